@@ -0,0 +1,96 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+)
+
+// PermissionDenied is the error code of responses to requests turned away by an Authorizer,
+// within the range reserved for implementation-defined server errors.
+const PermissionDenied = -32016
+
+// msgPermissionDenied is the message of PermissionDenied errors.
+const msgPermissionDenied = "Permission denied"
+
+// ErrPermissionDenied is the error sent for requests an Authorizer rejected.
+var ErrPermissionDenied = &Error{Code: PermissionDenied, Message: msgPermissionDenied}
+
+// AnyRole is the role an ACL grants to every authenticated caller, whatever their roles.
+const AnyRole = "*"
+
+// Authorizer decides whether authenticated callers may make requests. Implementations must be
+// safe for concurrent use.
+type Authorizer interface {
+	// Authorize returns nil if the caller, nil when unauthenticated, may make req, and the error
+	// to reject it with otherwise.
+	Authorize(ctx context.Context, principal *Principal, req *Request) error
+}
+
+// WithAuthorizer makes the server check every request with authz once it is authenticated, and
+// before dispatching it. Requests it rejects are answered with the *Error it returned, or with
+// ErrPermissionDenied for other errors, and over HTTP with 403 Forbidden.
+func WithAuthorizer(authz Authorizer) ServerOption {
+	return func(s *Server) {
+		s.authorizer = authz
+	}
+}
+
+// ACL is an Authorizer granting roles the methods matching patterns, such as "admin.*" or
+// "eth_get*", in the syntax of path.Match. Methods granted to no role of the caller are denied.
+// An ACL is safe for concurrent use, including granting while serving.
+type ACL struct {
+	mu     sync.RWMutex
+	grants map[string][]string // Patterns by role
+}
+
+// NewACL creates an ACL granting nothing.
+func NewACL() *ACL {
+	return &ACL{grants: make(map[string][]string)}
+}
+
+// Allow grants role the methods matching patterns. Granting AnyRole opens the methods to every
+// authenticated caller. It returns an error, granting nothing, if a pattern is malformed.
+func (a *ACL) Allow(role string, patterns ...string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid method pattern %q: %w", pattern, err)
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.grants[role] = append(a.grants[role], patterns...)
+	return nil
+}
+
+// Allowed reports whether a caller with the given roles may call method.
+func (a *ACL) Allowed(roles []string, method string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.matches(AnyRole, method) {
+		return true
+	}
+	return slices.ContainsFunc(roles, func(role string) bool {
+		return a.matches(role, method)
+	})
+}
+
+// Authorize implements Authorizer, denying unauthenticated callers.
+func (a *ACL) Authorize(_ context.Context, principal *Principal, req *Request) error {
+	if principal == nil || !a.Allowed(principal.Roles, req.Method) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// matches reports whether role was granted method. The caller must hold a.mu.
+func (a *ACL) matches(role, method string) bool {
+	for _, pattern := range a.grants[role] {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
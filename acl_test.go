@@ -0,0 +1,118 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACL(t *testing.T) {
+	acl := NewACL()
+	require.NoError(t, acl.Allow("admin", "admin.*", "user.*"))
+	require.NoError(t, acl.Allow("reader", "user.get", "user.list"))
+	require.NoError(t, acl.Allow(AnyRole, "ping"))
+
+	assert.True(t, acl.Allowed([]string{"admin"}, "admin.users.delete"))
+	assert.True(t, acl.Allowed([]string{"admin"}, "user.delete"))
+	assert.True(t, acl.Allowed([]string{"guest", "reader"}, "user.get"))
+	assert.False(t, acl.Allowed([]string{"reader"}, "user.delete"))
+	assert.False(t, acl.Allowed([]string{"reader"}, "admin.stats"))
+	assert.False(t, acl.Allowed(nil, "user.get"))
+	assert.True(t, acl.Allowed(nil, "ping"), "AnyRole opens methods to all callers")
+	assert.False(t, acl.Allowed([]string{"admin"}, "administer"))
+
+	assert.Error(t, acl.Allow("broken", "ok", "["))
+	assert.False(t, acl.Allowed([]string{"broken"}, "ok"), "nothing is granted on error")
+
+	ctx := context.Background()
+	assert.ErrorIs(t, acl.Authorize(ctx, nil, NewRequest("ping", nil)), ErrPermissionDenied,
+		"unauthenticated callers are denied")
+	assert.NoError(t, acl.Authorize(ctx, &Principal{}, NewRequest("ping", nil)))
+}
+
+func TestServer_Authorizer(t *testing.T) {
+	ctx := context.Background()
+	acl := NewACL()
+	require.NoError(t, acl.Allow("admin", "admin.*"))
+	require.NoError(t, acl.Allow(AnyRole, "whoami"))
+
+	var dispatched int
+	auth := AuthenticatorFunc(func(ctx context.Context, _ *Request) (*Principal, error) {
+		md, _ := IncomingMetadata(ctx)
+		return &Principal{ID: md.Get("x-user"), Roles: md.Values("x-role")}, nil
+	})
+	srv := NewServer(WithAuthenticator(auth), WithAuthorizer(acl))
+	srv.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+			dispatched++
+			return next.ServeRPC(ctx, req)
+		})
+	})
+	require.NoError(t, srv.Register("whoami", whoami))
+	require.NoError(t, srv.Register("admin.reset", whoami))
+
+	as := func(user string, roles ...string) context.Context {
+		md := MetadataPairs("x-user", user)
+		md.Set("x-role", roles...)
+		return NewIncomingContext(ctx, md)
+	}
+
+	t.Run("Granted methods are dispatched", func(t *testing.T) {
+		req := NewRequestWithID("admin.reset", nil, int64(1))
+		resp := srv.HandleRequest(as("root", "admin"), req)
+		assert.Nil(t, resp.Err())
+		resp = srv.HandleRequest(as("bob"), NewRequestWithID("whoami", nil, int64(2)))
+		assert.Nil(t, resp.Err())
+	})
+
+	t.Run("Denied methods never reach the middleware", func(t *testing.T) {
+		dispatched = 0
+		req := NewRequestWithID("admin.reset", nil, int64(3))
+		resp := srv.HandleRequest(as("bob", "user"), req)
+		require.ErrorIs(t, resp.Err(), ErrPermissionDenied)
+		assert.Equal(t, int64(3), resp.IDOrNil())
+		assert.Nil(t, srv.HandleRequest(as("bob"), NewNotification("admin.reset", nil)))
+		assert.Equal(t, 0, dispatched)
+	})
+
+	t.Run("Custom errors are sent as is", func(t *testing.T) {
+		deny := NewServer(WithAuthorizer(authorizerFunc(
+			func(context.Context, *Principal, *Request) error {
+				return &Error{Code: -32050, Message: "Quota plan"}
+			})))
+		require.NoError(t, deny.Register("whoami", whoami))
+		resp := deny.HandleRequest(ctx, NewRequestWithID("whoami", nil, int64(1)))
+		assert.Equal(t, -32050, resp.Err().Code)
+
+		opaque := NewServer(WithAuthorizer(authorizerFunc(
+			func(context.Context, *Principal, *Request) error {
+				return errors.New("internal detail")
+			})))
+		require.NoError(t, opaque.Register("whoami", whoami))
+		resp = opaque.HandleRequest(ctx, NewRequestWithID("whoami", nil, int64(1)))
+		require.ErrorIs(t, resp.Err(), ErrPermissionDenied)
+		assert.Nil(t, resp.Err().Data)
+	})
+
+	t.Run("Denied over HTTP is 403", func(t *testing.T) {
+		body := `{"jsonrpc":"2.0","method":"admin.reset","id":1}`
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentTypeJSON)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+// authorizerFunc adapts a function into an Authorizer.
+type authorizerFunc func(ctx context.Context, principal *Principal, req *Request) error
+
+func (f authorizerFunc) Authorize(ctx context.Context, principal *Principal, req *Request) error {
+	return f(ctx, principal, req)
+}
@@ -0,0 +1,424 @@
+package jsonrpc
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Method names under which the admin methods are served, in the admin namespace reserved by
+// WithAdmin and WithProxyAdmin.
+const (
+	AdminMethodsMethod     = "admin.methods"
+	AdminConnectionsMethod = "admin.connections"
+	AdminInFlightMethod    = "admin.inFlight"
+	AdminRateLimitsMethod  = "admin.rateLimits"
+	AdminDrainMethod       = "admin.drain"
+	AdminKickMethod        = "admin.kick"
+)
+
+// adminNamespace is the prefix of the admin methods.
+const adminNamespace = "admin."
+
+// defaultDrainTimeout is how long the drain started by AdminDrainMethod lasts at most by default.
+const defaultDrainTimeout = 30 * time.Second
+
+// ErrKicked is returned by ServeStream for connections closed with AdminKickMethod.
+var ErrKicked = errors.New("connection kicked")
+
+// AdminPolicy configures WithAdmin and WithProxyAdmin.
+type AdminPolicy struct {
+	// Separate serves the admin methods only on the server returned by AdminServer, for instance
+	// on a listener bound to a private address, rather than in the admin namespace of the server
+	// or proxy itself.
+	Separate bool
+
+	// Authorize, if set, is called before every admin call, and an error it returns is returned
+	// instead. Without it, admin.drain and admin.kick are denied with ErrPermissionDenied unless
+	// the server serving them has an authorizer set with WithAuthorizer. Admin methods served in
+	// the namespace of a server also go through its middleware and authorization, like the other
+	// methods; those served in the namespace of a proxy only go through Authorize.
+	Authorize func(ctx context.Context, req *Request) error
+
+	// DrainTimeout bounds the drain started by AdminDrainMethod, after which the remaining
+	// connections are closed even if they have requests in flight. Defaults to 30 seconds.
+	DrainTimeout time.Duration
+}
+
+// AdminConn describes a connection, as listed by AdminConnectionsMethod.
+type AdminConn struct {
+	// ID identifies the connection for AdminKickMethod.
+	ID string `json:"id"`
+
+	// Remote is the address of the peer, if known.
+	Remote string `json:"remote,omitempty"`
+
+	// Connected is when the connection was accepted.
+	Connected time.Time `json:"connected"`
+}
+
+// AdminCall describes a request in flight, as listed by AdminInFlightMethod.
+type AdminCall struct {
+	// ID is the ID of the request, nil for notifications.
+	ID any `json:"id,omitempty"`
+
+	// Method is the method called.
+	Method string `json:"method"`
+
+	// Conn is the ID of the connection the request arrived on, empty for HTTP requests.
+	Conn string `json:"conn,omitempty"`
+
+	// Started is when the request was dispatched.
+	Started time.Time `json:"started"`
+}
+
+// AdminRateLimit counts the requests to a method checked against rate limits, as reported by
+// AdminRateLimitsMethod.
+type AdminRateLimit struct {
+	// Allowed is the number of requests that passed the rate limits.
+	Allowed uint64 `json:"allowed"`
+
+	// Limited is the number of requests turned away with ErrRateLimited.
+	Limited uint64 `json:"limited"`
+}
+
+// kickParams are the params of AdminKickMethod, accepted by name or by position.
+type kickParams struct {
+	ID string `json:"id"`
+}
+
+// WithAdmin tracks the connections, requests in flight, and rate limit counters of the server
+// and serves the admin methods, for the operations of long-running servers. Unless
+// policy.Separate is set, they are served in the reserved admin namespace of the server:
+//
+//   - admin.methods returns the sorted names of the methods served.
+//   - admin.connections returns the AdminConn of each connection served by ServeStream.
+//   - admin.inFlight returns the AdminCall of each request in flight, oldest first.
+//   - admin.rateLimits returns the AdminRateLimit of each method by name, under the empty name
+//     for methods that are not registered.
+//   - admin.drain starts shutting the server down as Shutdown does, bounded by
+//     policy.DrainTimeout, and returns true without waiting for it.
+//   - admin.kick closes the connection of the ID given as params, ["id"] or {"id": "id"},
+//     failing its requests in flight, and returns true.
+//
+// Like the health report, the admin methods are exempt from rate and concurrency limits, so that
+// a saturated server can be operated. Protect them with policy.Authorize or an authorizer, or
+// serve them separately with AdminServer; admin.drain and admin.kick are denied unless one of
+// the former is set.
+func WithAdmin(policy AdminPolicy) ServerOption {
+	return func(s *Server) {
+		s.admin = newAdminTracker(policy)
+		if !policy.Separate {
+			registerAdmin(s, s, policy)
+		}
+	}
+}
+
+// AdminServer returns a new server, configured by opts, serving only the admin methods of s, to
+// be served on a listener of its own. Servers created without WithAdmin list no connections nor
+// requests in flight, and count no rate limits.
+func (s *Server) AdminServer(opts ...ServerOption) *Server {
+	admin := NewServer(opts...)
+	registerAdmin(admin, s, s.admin.adminPolicy())
+	return admin
+}
+
+// adminTarget is a server or proxy operated through the admin methods.
+type adminTarget interface {
+	// adminMethods returns the sorted names of the methods served.
+	adminMethods() []string
+
+	// adminDrain shuts the target down gracefully within ctx.
+	adminDrain(ctx context.Context) error
+
+	// adminTracker returns the tracker of the target, nil if it is not tracked.
+	adminTracker() *adminTracker
+}
+
+// registerAdmin serves the admin methods of target on srv.
+func registerAdmin(srv *Server, target adminTarget, policy AdminPolicy) {
+	timeout := policy.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	methods := map[string]func(ctx context.Context, req *Request) (any, error){
+		AdminMethodsMethod: func(context.Context, *Request) (any, error) {
+			return target.adminMethods(), nil
+		},
+		AdminConnectionsMethod: func(context.Context, *Request) (any, error) {
+			return target.adminTracker().conns(), nil
+		},
+		AdminInFlightMethod: func(context.Context, *Request) (any, error) {
+			return target.adminTracker().inFlight(), nil
+		},
+		AdminRateLimitsMethod: func(context.Context, *Request) (any, error) {
+			return target.adminTracker().rateLimits(), nil
+		},
+		AdminDrainMethod: func(context.Context, *Request) (any, error) {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				_ = target.adminDrain(ctx)
+			}()
+			return true, nil
+		},
+		AdminKickMethod: func(_ context.Context, req *Request) (any, error) {
+			params, err := DecodeParams[kickParams](req)
+			if err != nil {
+				return nil, err
+			}
+			if !target.adminTracker().kick(params.ID, ErrKicked) {
+				return nil, ErrInvalidParams.WithData(
+					fmt.Sprintf("connection %q is not served", params.ID))
+			}
+			return true, nil
+		},
+	}
+	for name, fn := range methods {
+		privileged := name == AdminDrainMethod || name == AdminKickMethod
+		srv.registerBuiltin(name, func(ctx context.Context, req *Request) (any, error) {
+			if policy.Authorize != nil {
+				if err := policy.Authorize(ctx, req); err != nil {
+					return nil, err
+				}
+			} else if privileged && srv.authorizer == nil {
+				// Operating the server is never open to anyone by default
+				return nil, ErrPermissionDenied.WithData(
+					"admin.drain and admin.kick require an authorizer or AdminPolicy.Authorize")
+			}
+			return fn(ctx, req)
+		})
+	}
+}
+
+// isAdminMethod reports whether method is an admin method served by the server itself.
+func (s *Server) isAdminMethod(method string) bool {
+	return s.admin != nil && !s.admin.policy.Separate && strings.HasPrefix(method, adminNamespace)
+}
+
+// adminMethods implements adminTarget.
+func (s *Server) adminMethods() []string {
+	names := s.Methods()
+	slices.Sort(names)
+	return names
+}
+
+// adminDrain implements adminTarget.
+func (s *Server) adminDrain(ctx context.Context) error {
+	return s.Shutdown(ctx)
+}
+
+// adminTracker implements adminTarget.
+func (s *Server) adminTracker() *adminTracker {
+	return s.admin
+}
+
+// adminTracker tracks the connections, requests in flight, and rate limit counters of a server
+// or proxy operated through the admin methods. A nil tracker tracks nothing.
+type adminTracker struct {
+	policy   AdminPolicy
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	byID   map[string]*adminConn
+	calls  map[uint64]AdminCall
+	next   uint64
+	idle   chan struct{} // Closed once no call is in flight while draining
+	limits map[string]*AdminRateLimit
+}
+
+// adminConn is a connection tracked by an adminTracker.
+type adminConn struct {
+	info  AdminConn
+	close func(cause error)
+}
+
+// newAdminTracker returns a tracker for policy.
+func newAdminTracker(policy AdminPolicy) *adminTracker {
+	return &adminTracker{
+		policy: policy,
+		byID:   make(map[string]*adminConn),
+		calls:  make(map[uint64]AdminCall),
+		limits: make(map[string]*AdminRateLimit),
+	}
+}
+
+// adminPolicy returns the policy of the tracker, the zero policy if there is none.
+func (t *adminTracker) adminPolicy() AdminPolicy {
+	if t == nil {
+		return AdminPolicy{}
+	}
+	return t.policy
+}
+
+// connect tracks a connection, closed by close when kicked.
+func (t *adminTracker) connect(info AdminConn, closeConn func(cause error)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.byID[info.ID] = &adminConn{info: info, close: closeConn}
+}
+
+// disconnect stops tracking the connection of id.
+func (t *adminTracker) disconnect(id string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.byID, id)
+}
+
+// begin tracks req, arrived on the connection of conn, and returns its sequence number for end.
+func (t *adminTracker) begin(conn string, req *Request) uint64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	t.calls[t.next] = AdminCall{ID: req.ID, Method: req.Method, Conn: conn, Started: time.Now()}
+	return t.next
+}
+
+// end stops tracking the requests numbered seqs.
+func (t *adminTracker) end(seqs ...uint64) {
+	if t == nil || len(seqs) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, seq := range seqs {
+		delete(t.calls, seq)
+	}
+	if len(t.calls) == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// countRateLimit counts a request to method checked against the rate limits, turned away if err
+// is not nil.
+func (t *adminTracker) countRateLimit(method string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counter, ok := t.limits[method]
+	if !ok {
+		counter = &AdminRateLimit{}
+		t.limits[method] = counter
+	}
+	if err == nil {
+		counter.Allowed++
+	} else {
+		counter.Limited++
+	}
+}
+
+// conns returns the connections tracked, oldest first.
+func (t *adminTracker) conns() []AdminConn {
+	conns := []AdminConn{}
+	if t == nil {
+		return conns
+	}
+	t.mu.Lock()
+	for _, conn := range t.byID {
+		conns = append(conns, conn.info)
+	}
+	t.mu.Unlock()
+	slices.SortFunc(conns, func(a, b AdminConn) int {
+		return cmp.Or(a.Connected.Compare(b.Connected), strings.Compare(a.ID, b.ID))
+	})
+	return conns
+}
+
+// inFlight returns the requests in flight, oldest first.
+func (t *adminTracker) inFlight() []AdminCall {
+	calls := []AdminCall{}
+	if t == nil {
+		return calls
+	}
+	t.mu.Lock()
+	seqs := slices.Sorted(maps.Keys(t.calls))
+	for _, seq := range seqs {
+		calls = append(calls, t.calls[seq])
+	}
+	t.mu.Unlock()
+	return calls
+}
+
+// rateLimits returns the rate limit counters by method.
+func (t *adminTracker) rateLimits() map[string]AdminRateLimit {
+	counters := make(map[string]AdminRateLimit)
+	if t == nil {
+		return counters
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for method, counter := range t.limits {
+		counters[method] = *counter
+	}
+	return counters
+}
+
+// kick closes the connection of id with cause, reporting whether it is tracked.
+func (t *adminTracker) kick(id string, cause error) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	conn, ok := t.byID[id]
+	t.mu.Unlock()
+	if ok {
+		conn.close(cause)
+	}
+	return ok
+}
+
+// isDraining reports whether drain has been called.
+func (t *adminTracker) isDraining() bool {
+	return t != nil && t.draining.Load()
+}
+
+// drain marks the tracker as draining, waits for the requests in flight to end or ctx to be
+// done, and then closes the connections with ErrServerClosed. It returns ctx.Err() if ctx was
+// done first.
+func (t *adminTracker) drain(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.draining.Store(true)
+	t.mu.Lock()
+	var idle <-chan struct{}
+	if len(t.calls) > 0 {
+		if t.idle == nil {
+			t.idle = make(chan struct{})
+		}
+		idle = t.idle
+	}
+	t.mu.Unlock()
+
+	var err error
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	for _, conn := range t.conns() {
+		t.kick(conn.ID, ErrServerClosed)
+	}
+	return err
+}
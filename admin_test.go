@@ -0,0 +1,225 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// messageHandler is a Server or Proxy.
+type messageHandler interface {
+	HandleMessage(ctx context.Context, data []byte) []byte
+}
+
+// adminCall calls method of srv with params and decodes its result into result.
+func adminCall(t *testing.T, srv messageHandler, method, params string, result any) *Error {
+	t.Helper()
+	msg := `{"jsonrpc":"2.0","method":"` + method + `","id":"admin"`
+	if params != "" {
+		msg += `,"params":` + params
+	}
+	resp, err := DecodeResponse(srv.HandleMessage(context.Background(), []byte(msg+"}")))
+	require.NoError(t, err)
+	if rpcErr := resp.Err(); rpcErr != nil {
+		return rpcErr
+	}
+	if result != nil {
+		require.NoError(t, resp.UnmarshalResult(result))
+	}
+	return nil
+}
+
+// allowAdmin is an AdminPolicy.Authorize allowing every admin call.
+func allowAdmin(context.Context, *Request) error {
+	return nil
+}
+
+// firstAllowed is a RateLimiter allowing the first call of each key only.
+type firstAllowed struct {
+	seen map[string]bool
+}
+
+func (l *firstAllowed) Allow(key string) bool {
+	allowed := !l.seen[key]
+	l.seen[key] = true
+	return allowed
+}
+
+func (l *firstAllowed) Wait(context.Context, string) error {
+	return nil
+}
+
+func TestServer_Admin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Methods and requests in flight", func(t *testing.T) {
+		srv := NewServer(WithAdmin(AdminPolicy{}))
+		release := make(chan struct{})
+		require.NoError(t, srv.RegisterFunc("slow", func(context.Context, *Request) (any, error) {
+			<-release
+			return true, nil
+		}))
+		done := make(chan []byte)
+		go func() {
+			done <- srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"slow","id":7}`))
+		}()
+
+		var methods []string
+		require.Nil(t, adminCall(t, srv, AdminMethodsMethod, "", &methods))
+		assert.Contains(t, methods, "slow")
+		assert.Contains(t, methods, AdminKickMethod)
+
+		require.Eventually(t, func() bool {
+			var calls []AdminCall
+			require.Nil(t, adminCall(t, srv, AdminInFlightMethod, "", &calls))
+			return len(calls) == 2 && calls[0].Method == "slow"
+		}, time.Second, time.Millisecond)
+		close(release)
+		<-done
+
+		var calls []AdminCall
+		require.Nil(t, adminCall(t, srv, AdminInFlightMethod, "", &calls))
+		require.Len(t, calls, 1, "only the admin call itself is left")
+		assert.Equal(t, AdminInFlightMethod, calls[0].Method)
+	})
+
+	t.Run("Connections and kick", func(t *testing.T) {
+		srv := NewServer(WithAdmin(AdminPolicy{Authorize: allowAdmin}))
+		clientEnd, serverEnd := newStreamPair()
+		defer func() { _ = clientEnd.Close() }()
+		connCtx := NewIncomingContext(ctx, MetadataPairs(PeerMetadataKey, "10.0.0.1:4000"))
+		served := make(chan error, 1)
+		go func() { served <- srv.ServeStream(connCtx, serverEnd) }()
+
+		var conns []AdminConn
+		require.Eventually(t, func() bool {
+			require.Nil(t, adminCall(t, srv, AdminConnectionsMethod, "", &conns))
+			return len(conns) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, "10.0.0.1:4000", conns[0].Remote)
+		assert.Equal(t, srv.Conns()[0].ID(), conns[0].ID)
+
+		rpcErr := adminCall(t, srv, AdminKickMethod, `["nope"]`, nil)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+
+		var kicked bool
+		require.Nil(t, adminCall(t, srv, AdminKickMethod, `{"id":"`+conns[0].ID+`"}`, &kicked))
+		assert.True(t, kicked)
+		assert.ErrorIs(t, <-served, ErrKicked)
+		require.Nil(t, adminCall(t, srv, AdminConnectionsMethod, "", &conns))
+		assert.Empty(t, conns)
+	})
+
+	t.Run("Rate limit counters", func(t *testing.T) {
+		srv := NewServer(WithAdmin(AdminPolicy{}),
+			WithRateLimit(RateLimit{Limiter: &firstAllowed{seen: map[string]bool{}},
+				Key: RateLimitByMethod}))
+		require.NoError(t, srv.RegisterFunc("m", func(context.Context, *Request) (any, error) {
+			return true, nil
+		}))
+		for range 3 {
+			_ = srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"m","id":1}`))
+		}
+		_ = srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"unknown","id":1}`))
+
+		var counters map[string]AdminRateLimit
+		require.Nil(t, adminCall(t, srv, AdminRateLimitsMethod, "", &counters))
+		assert.Equal(t, map[string]AdminRateLimit{
+			"m": {Allowed: 1, Limited: 2},
+			"":  {Allowed: 1},
+		}, counters, "admin calls are exempt")
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		srv := NewServer(WithAdmin(AdminPolicy{Authorize: allowAdmin, DrainTimeout: time.Second}))
+		require.NoError(t, srv.RegisterFunc("m", func(context.Context, *Request) (any, error) {
+			return true, nil
+		}))
+		var draining bool
+		require.Nil(t, adminCall(t, srv, AdminDrainMethod, "", &draining))
+		assert.True(t, draining)
+		require.Eventually(t, func() bool {
+			rpcErr := adminCall(t, srv, "m", "", nil)
+			return rpcErr != nil && rpcErr.Code == ShuttingDown
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Drain and kick are denied by default", func(t *testing.T) {
+		srv := NewServer(WithAdmin(AdminPolicy{}))
+		require.NoError(t, srv.RegisterFunc("m", func(context.Context, *Request) (any, error) {
+			return true, nil
+		}))
+		for _, method := range []string{AdminDrainMethod, AdminKickMethod} {
+			rpcErr := adminCall(t, srv, method, `{"id":"nope"}`, nil)
+			require.NotNil(t, rpcErr, method)
+			assert.Equal(t, PermissionDenied, rpcErr.Code, method)
+		}
+		require.Nil(t, adminCall(t, srv, "m", "", nil), "the server was not drained")
+		require.Nil(t, adminCall(t, srv.AdminServer(), AdminMethodsMethod, "", nil),
+			"other admin methods stay open")
+		rpcErr := adminCall(t, srv.AdminServer(), AdminDrainMethod, "", nil)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, PermissionDenied, rpcErr.Code, "separate servers are denied alike")
+
+		authz := authorizerFunc(func(context.Context, *Principal, *Request) error { return nil })
+		allowed := NewServer(WithAdmin(AdminPolicy{}), WithAuthorizer(authz))
+		rpcErr = adminCall(t, allowed, AdminKickMethod, `{"id":"nope"}`, nil)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code, "the authorizer decides")
+	})
+
+	t.Run("Separate and authorized", func(t *testing.T) {
+		var denied atomic.Bool
+		srv := NewServer(WithAdmin(AdminPolicy{
+			Separate: true,
+			Authorize: func(context.Context, *Request) error {
+				if denied.Load() {
+					return ErrPermissionDenied
+				}
+				return nil
+			},
+		}))
+		custom := func(context.Context, *Request) (any, error) { return true, nil }
+		require.NoError(t, srv.RegisterFunc("admin.custom", custom),
+			"the namespace is free on the server itself")
+
+		rpcErr := adminCall(t, srv, AdminMethodsMethod, "", nil)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+
+		admin := srv.AdminServer()
+		var methods []string
+		require.Nil(t, adminCall(t, admin, AdminMethodsMethod, "", &methods))
+		assert.Equal(t, []string{"admin.custom"}, methods)
+
+		denied.Store(true)
+		rpcErr = adminCall(t, admin, AdminMethodsMethod, "", nil)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, PermissionDenied, rpcErr.Code)
+	})
+
+	t.Run("Reserved namespace", func(t *testing.T) {
+		srv := NewServer(WithAdmin(AdminPolicy{}))
+		err := srv.RegisterFunc("admin.custom", func(context.Context, *Request) (any, error) {
+			return nil, nil
+		})
+		assert.ErrorContains(t, err, "reserved")
+	})
+
+	t.Run("Without tracking", func(t *testing.T) {
+		admin := NewServer().AdminServer()
+		var result json.RawMessage
+		require.Nil(t, adminCall(t, admin, AdminConnectionsMethod, "", &result))
+		assert.JSONEq(t, `[]`, string(result))
+		require.Nil(t, adminCall(t, admin, AdminRateLimitsMethod, "", &result))
+		assert.JSONEq(t, `{}`, string(result))
+		require.Nil(t, adminCall(t, admin, AdminInFlightMethod, "", &result))
+		assert.JSONEq(t, `[]`, string(result))
+	})
+}
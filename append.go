@@ -0,0 +1,243 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// revive:disable:modifies-parameter appending to dst is the point of these encoders
+
+// decimal is the base integers are encoded in.
+const decimal = 10
+
+// Bounds outside which float IDs are encoded in exponent notation, as encoding/json does.
+const (
+	minPlainFloat = 1e-6
+	maxPlainFloat = 1e21
+)
+
+// hexDigits are the digits of \u escapes.
+const hexDigits = "0123456789abcdef"
+
+// AppendJSON appends the JSON encoding of the request to dst and returns the extended buffer,
+// like MarshalJSON. IDs and the method are encoded directly, and params held as json.RawMessage
+// are appended as they are, so that encoding such a request into a buffer with enough capacity
+// does not allocate. Other params are marshaled.
+func (r *Request) AppendJSON(dst []byte) ([]byte, error) {
+	if err := r.Validate(); err != nil {
+		return dst, err
+	}
+
+	dst = append(dst, `{"jsonrpc":"2.0"`...)
+	var err error
+	// An absent ID marks a notification, as for MarshalJSON
+	if !r.IsNotification() {
+		dst = append(dst, `,"id":`...)
+		if dst, err = appendID(dst, r.ID); err != nil {
+			return dst, err
+		}
+	}
+	dst = append(dst, `,"method":`...)
+	dst = appendString(dst, r.Method)
+	if r.Params != nil {
+		dst = append(dst, `,"params":`...)
+		if dst, err = appendValue(dst, r.Params); err != nil {
+			return dst, fmt.Errorf("failed to marshal params: %w", err)
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// AppendJSON appends the JSON encoding of the response to dst and returns the extended buffer.
+// Raw IDs, results, and errors are appended as they are, so that encoding a decoded response, or
+// one created with a raw result, into a buffer with enough capacity does not allocate.
+func (r *Response) AppendJSON(dst []byte) ([]byte, error) {
+	if err := r.Validate(); err != nil {
+		return dst, err
+	}
+
+	dst = append(dst, `{"jsonrpc":"2.0","id":`...)
+	var err error
+	switch {
+	case len(r.rawID) > 0:
+		dst = append(dst, r.rawID...)
+	case r.id != nil:
+		if dst, err = appendID(dst, r.id); err != nil {
+			return dst, err
+		}
+	default:
+		dst = append(dst, "null"...)
+	}
+
+	switch {
+	case r.err != nil:
+		dst = append(dst, `,"error":`...)
+		if dst, err = r.err.AppendJSON(dst); err != nil {
+			return dst, err
+		}
+	case len(r.rawError) > 0:
+		dst = append(dst, `,"error":`...)
+		dst = append(dst, r.rawError...)
+	default:
+		dst = append(dst, `,"result":`...)
+		dst = append(dst, r.result...)
+	}
+	return append(dst, '}'), nil
+}
+
+// AppendJSON appends the JSON encoding of the error to dst and returns the extended buffer. Data
+// other than json.RawMessage is marshaled.
+func (e *Error) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	sep := false
+	if e.Code != 0 {
+		dst = append(dst, `"code":`...)
+		dst = strconv.AppendInt(dst, int64(e.Code), decimal)
+		sep = true
+	}
+	if e.Message != "" {
+		if sep {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"message":`...)
+		dst = appendString(dst, e.Message)
+		sep = true
+	}
+	if e.Data != nil {
+		if sep {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"data":`...)
+		var err error
+		if dst, err = appendValue(dst, e.Data); err != nil {
+			return dst, fmt.Errorf("failed to marshal error data: %w", err)
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// AppendBatchRequest appends the JSON encoding of a batch of requests to dst and returns the
+// extended buffer, like EncodeBatchRequest.
+func AppendBatchRequest(dst []byte, reqs []*Request) ([]byte, error) {
+	if len(reqs) == 0 {
+		return dst, errors.New("batch request must contain at least one request")
+	}
+
+	dst = append(dst, '[')
+	for i, req := range reqs {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		var err error
+		if dst, err = req.AppendJSON(dst); err != nil {
+			return dst, fmt.Errorf("invalid request at index %d: %w", i, err)
+		}
+	}
+	return append(dst, ']'), nil
+}
+
+// AppendBatchResponse appends the JSON encoding of a batch of responses to dst and returns the
+// extended buffer, like EncodeBatchResponse.
+func AppendBatchResponse(dst []byte, resps []*Response) ([]byte, error) {
+	if len(resps) == 0 {
+		return dst, errors.New("batch response must contain at least one response")
+	}
+
+	dst = append(dst, '[')
+	for i, resp := range resps {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		var err error
+		if dst, err = resp.AppendJSON(dst); err != nil {
+			return dst, fmt.Errorf("invalid response at index %d: %w", i, err)
+		}
+	}
+	return append(dst, ']'), nil
+}
+
+// appendValue appends the JSON encoding of v, as is for raw JSON.
+func appendValue(dst []byte, v any) ([]byte, error) {
+	if raw, ok := v.(json.RawMessage); ok {
+		return append(dst, raw...), nil
+	}
+	data, err := getCodec().Marshal(v)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, data...), nil
+}
+
+// appendID appends the JSON encoding of an ID.
+func appendID(dst []byte, id any) ([]byte, error) {
+	switch value := id.(type) {
+	case string:
+		return appendString(dst, value), nil
+	case int64:
+		return strconv.AppendInt(dst, value, decimal), nil
+	case int:
+		return strconv.AppendInt(dst, int64(value), decimal), nil
+	case float64:
+		return appendFloat(dst, value)
+	default:
+		data, err := getCodec().Marshal(id)
+		if err != nil {
+			return dst, fmt.Errorf("failed to marshal id: %w", err)
+		}
+		return append(dst, data...), nil
+	}
+}
+
+// appendFloat appends a float the way encoding/json formats it.
+func appendFloat(dst []byte, f float64) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return dst, fmt.Errorf("unsupported id value: %v", f)
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < minPlainFloat || abs >= maxPlainFloat) {
+		format = 'e'
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		if n := len(dst) - start; n >= 4 && dst[len(dst)-4] == 'e' && dst[len(dst)-3] == '-' &&
+			dst[len(dst)-2] == '0' {
+			dst[len(dst)-2] = dst[len(dst)-1]
+			dst = dst[:len(dst)-1]
+		}
+	}
+	return dst, nil
+}
+
+// appendString appends s as a JSON string, escaping quotes, backslashes, and control characters.
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= ' ' && c != '"' && c != '\\' {
+			continue
+		}
+		dst = append(dst, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		}
+		start = i + 1
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,219 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequest_AppendJSON(t *testing.T) {
+	t.Run("Matches MarshalJSON", func(t *testing.T) {
+		reqs := []*Request{
+			NewRequestWithID("sum", []any{1, 2}, int64(7)),
+			NewRequestWithID("get", map[string]any{"key": "a\"b\\c\n"}, "id-1"),
+			NewRequestWithID("float", nil, 1.5),
+			NewNotification("notify", json.RawMessage(`{"raw":true}`)),
+			NewRequestWithID("typed", nil, StringID("t-1")),
+			NewRequestWithID("null", nil, NullID()),
+			NewRequestWithID("absent", nil, ID{}),
+		}
+		for _, req := range reqs {
+			want, err := req.MarshalJSON()
+			require.NoError(t, err)
+			got, err := req.AppendJSON(nil)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+
+			decoded, err := DecodeRequest(got)
+			require.NoError(t, err)
+			assert.Equal(t, req.Method, decoded.Method)
+			assert.Equal(t, req.IsNotification(), decoded.IsNotification())
+		}
+	})
+
+	t.Run("Appends to dst", func(t *testing.T) {
+		req := NewRequestWithID("ping", nil, int64(1))
+		got, err := req.AppendJSON([]byte("prefix:"))
+		require.NoError(t, err)
+		assert.Equal(t, `prefix:{"jsonrpc":"2.0","id":1,"method":"ping"}`, string(got))
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		_, err := (&Request{JSONRPC: "2.0"}).AppendJSON(nil)
+		require.Error(t, err)
+	})
+
+	t.Run("Does not allocate with raw params", func(t *testing.T) {
+		req := NewRequestWithID("update", json.RawMessage(`{"a":1}`), int64(42))
+		buf := make([]byte, 0, 256)
+		allocs := testing.AllocsPerRun(100, func() {
+			buf, _ = req.AppendJSON(buf[:0])
+		})
+		assert.Zero(t, allocs)
+	})
+}
+
+func TestResponse_AppendJSON(t *testing.T) {
+	t.Run("Results and errors", func(t *testing.T) {
+		result, err := NewResponse(int64(1), map[string]any{"ok": true})
+		require.NoError(t, err)
+		resps := []*Response{
+			result,
+			NewErrorResponse("abc", &Error{Code: InvalidParams, Message: "bad", Data: []any{1}}),
+			NewErrorResponse(nil, ErrParse),
+		}
+		for _, resp := range resps {
+			want, err := resp.MarshalJSON()
+			require.NoError(t, err)
+			got, err := resp.AppendJSON(nil)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+		}
+	})
+
+	t.Run("Decoded responses are appended as they are", func(t *testing.T) {
+		data := `{"jsonrpc":"2.0","id":"x","error":{"code":-32000,"message":"boom"}}`
+		resp, err := DecodeResponse([]byte(data))
+		require.NoError(t, err)
+		got, err := resp.AppendJSON(nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, data, string(got))
+	})
+
+	t.Run("Does not allocate for decoded responses", func(t *testing.T) {
+		resp, err := DecodeResponse(mediumResponseJSON)
+		require.NoError(t, err)
+		buf := make([]byte, 0, 1024)
+		allocs := testing.AllocsPerRun(100, func() {
+			buf, _ = resp.AppendJSON(buf[:0])
+		})
+		assert.Zero(t, allocs)
+	})
+}
+
+func TestAppendBatch(t *testing.T) {
+	reqs := []*Request{
+		NewRequestWithID("a", nil, int64(1)),
+		NewNotification("b", []any{true}),
+	}
+	got, err := AppendBatchRequest(nil, reqs)
+	require.NoError(t, err)
+	decoded, err := DecodeBatchRequest(got)
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "b", decoded[1].Method)
+
+	_, err = AppendBatchRequest(nil, nil)
+	require.Error(t, err)
+	_, err = AppendBatchRequest(nil, []*Request{reqs[0], {Method: "x"}})
+	require.ErrorContains(t, err, "index 1")
+
+	resp, err := NewResponse(int64(1), "ok")
+	require.NoError(t, err)
+	got, err = AppendBatchResponse([]byte("x"), []*Response{resp, NewErrorResponse(2, ErrInternal)})
+	require.NoError(t, err)
+	assert.Equal(t, byte('x'), got[0])
+	resps, err := DecodeBatchResponse(got[1:])
+	require.NoError(t, err)
+	assert.Len(t, resps, 2)
+}
+
+func TestAppendString(t *testing.T) {
+	tests := map[string]string{
+		"plain":      `"plain"`,
+		`q"uote`:     `"q\"uote"`,
+		`back\slash`: `"back\\slash"`,
+		"ctl\n\r\t":  `"ctl\n\r\t"`,
+		"nul\x00":    `"nul\u0000"`,
+		"ünïcode":    `"ünïcode"`,
+	}
+	for in, want := range tests {
+		got := appendString(nil, in)
+		assert.Equal(t, want, string(got))
+
+		var decoded string
+		require.NoError(t, json.Unmarshal(got, &decoded))
+		assert.Equal(t, in, decoded)
+	}
+}
+
+func TestAppendFloat(t *testing.T) {
+	for _, f := range []float64{1.5, -0.25, 1e21, 1e-7, 123456.789, 0} {
+		got, err := appendFloat(nil, f)
+		require.NoError(t, err)
+		want, err := json.Marshal(f)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+
+	_, err := appendFloat(nil, math.NaN())
+	require.Error(t, err)
+}
+
+func TestParseSimpleID(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want any
+		ok   bool
+	}{
+		{raw: `1`, want: int64(1), ok: true},
+		{raw: `-42`, want: int64(-42), ok: true},
+		{raw: `123456789012345678`, want: int64(123456789012345678), ok: true},
+		{raw: `"abc"`, want: "abc", ok: true},
+		{raw: `""`, want: nil, ok: true},
+		{raw: `1234567890123456789`},
+		{raw: `1.5`},
+		{raw: `01`},
+		{raw: `-`},
+		{raw: `"a\"b"`},
+		{raw: `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := parseSimpleID([]byte(tt.raw))
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServer_AppendMessage(t *testing.T) {
+	srv := NewServer()
+	ping := HandlerFunc(func(context.Context, *Request) (any, error) {
+		return "pong", nil
+	})
+	require.NoError(t, srv.Register("ping", ping))
+	ctx := context.Background()
+
+	buf := []byte("keep")
+	buf = srv.AppendMessage(ctx, buf, []byte(`{"jsonrpc":"2.0","method":"ping","id":1}`))
+	assert.Equal(t, `keep{"jsonrpc":"2.0","id":1,"result":"pong"}`, string(buf))
+
+	buf = srv.AppendMessage(ctx, buf[:0], []byte(`{"jsonrpc":"2.0","method":"ping"}`))
+	assert.Empty(t, buf, "no reply is due for notifications")
+	assert.Nil(t, srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"ping"}`)))
+}
+
+func TestReset(t *testing.T) {
+	req, err := DecodeRequest([]byte(`{"jsonrpc":"2.0","method":"a","params":[1],"id":1}`))
+	require.NoError(t, err)
+	req.Reset()
+	assert.Equal(t, Request{}, *req)
+	require.NoError(t, req.UnmarshalJSON([]byte(`{"jsonrpc":"2.0","method":"b"}`)))
+	assert.Equal(t, "b", req.Method)
+	assert.Nil(t, req.Params)
+
+	resp, err := DecodeResponse([]byte(`{"jsonrpc":"2.0","id":1,"result":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.IDOrNil())
+	resp.Reset()
+	data := `{"jsonrpc":"2.0","id":"two","error":{"code":-32601,"message":"missing"}}`
+	require.NoError(t, resp.UnmarshalJSON([]byte(data)))
+	assert.Equal(t, "two", resp.IDOrNil())
+	assert.Equal(t, MethodNotFound, resp.Err().Code)
+	assert.Nil(t, resp.RawResult())
+}
@@ -0,0 +1,226 @@
+// Package attach carries binary blobs in the params and results of jsonrpc calls, such as the
+// files of file transfer methods, without blowing up the size of their messages.
+//
+// An Attachment is a JSON value standing for a blob: small blobs are inlined as base64, and
+// larger ones are uploaded out of band to a Store, the attachment only carrying a reference to
+// them, along with their size and SHA-256 digest. Senders create attachments with
+// Attacher.Attach, and receivers read them with Attacher.Open, which downloads the blobs passed
+// by reference from the same store and verifies them:
+//
+//	type UploadParams struct {
+//	    Name string            `json:"name"`
+//	    File attach.Attachment `json:"file"`
+//	}
+//
+// Stores are shared by the senders and receivers of attachments, such as a MemoryStore behind a
+// Handler, which an HTTPStore reaches over HTTP.
+package attach
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// defaultInlineLimit is the size up to which blobs are inlined when WithInlineLimit is not set.
+const defaultInlineLimit = 64 << 10
+
+var (
+	// ErrNotFound is returned by stores for references to blobs they do not hold.
+	ErrNotFound = errors.New("attach: blob not found")
+
+	// ErrCorrupt is returned when reading a blob passed by reference whose size or digest does
+	// not match its attachment.
+	ErrCorrupt = errors.New("attach: blob does not match its attachment")
+
+	// errNoStore is returned for blobs to pass by reference by an Attacher without a store.
+	errNoStore = errors.New("attach: no store for blobs passed by reference")
+)
+
+// Attachment is a binary blob within params or results, inline or passed by reference.
+type Attachment struct {
+	// Data is the content of inline blobs, encoded as base64.
+	Data []byte `json:"data,omitempty"`
+
+	// Ref is the reference of blobs passed out of band, in the store they were uploaded to.
+	Ref string `json:"ref,omitempty"`
+
+	// Size is the size of the blob in bytes.
+	Size int64 `json:"size"`
+
+	// SHA256 is the hex-encoded SHA-256 digest of blobs passed by reference.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// MediaType is the media type of the blob, if known, such as "image/png".
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// Inline reports whether the blob is carried in the attachment itself.
+func (a Attachment) Inline() bool {
+	return a.Ref == ""
+}
+
+// Store keeps the blobs passed by reference. Implementations must be safe for concurrent use;
+// removing blobs no longer needed, such as after some time, is left to them.
+type Store interface {
+	// Put stores the blob read from r and returns its reference.
+	Put(ctx context.Context, r io.Reader) (string, error)
+
+	// Get returns a reader of the blob with the given reference, or ErrNotFound.
+	Get(ctx context.Context, ref string) (io.ReadCloser, error)
+}
+
+// config holds the options of an Attacher.
+type config struct {
+	inlineLimit int
+}
+
+// Option configures New.
+type Option func(*config)
+
+// WithInlineLimit sets the size up to which blobs are inlined rather than passed by reference.
+// Defaults to 64 KiB.
+func WithInlineLimit(n int) Option {
+	return func(c *config) {
+		c.inlineLimit = n
+	}
+}
+
+// newConfig returns the configuration set by opts.
+func newConfig(opts []Option) *config {
+	cfg := &config{inlineLimit: defaultInlineLimit}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Attacher creates and opens attachments, passing large blobs by reference through a store. An
+// Attacher is safe for concurrent use.
+type Attacher struct {
+	store Store
+	cfg   *config
+}
+
+// New returns an Attacher passing blobs larger than the inline limit through store. With a nil
+// store, only inline blobs can be attached and opened.
+func New(store Store, opts ...Option) *Attacher {
+	return &Attacher{store: store, cfg: newConfig(opts)}
+}
+
+// Attach returns an attachment of data with the given media type, which may be empty.
+func (a *Attacher) Attach(ctx context.Context, data []byte, mediaType string) (Attachment, error) {
+	return a.AttachReader(ctx, bytes.NewReader(data), mediaType)
+}
+
+// AttachReader returns an attachment of the blob read from r with the given media type, which may
+// be empty. Blobs too large to be inlined are streamed to the store without being held in
+// memory.
+func (a *Attacher) AttachReader(
+	ctx context.Context,
+	r io.Reader,
+	mediaType string,
+) (Attachment, error) {
+	head, err := io.ReadAll(io.LimitReader(r, int64(a.cfg.inlineLimit)+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if len(head) <= a.cfg.inlineLimit {
+		return Attachment{Data: head, Size: int64(len(head)), MediaType: mediaType}, nil
+	}
+	if a.store == nil {
+		return Attachment{}, errNoStore
+	}
+
+	digest := &digestReader{r: io.MultiReader(bytes.NewReader(head), r), hash: sha256.New()}
+	ref, err := a.store.Put(ctx, digest)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{
+		Ref:       ref,
+		Size:      digest.size,
+		SHA256:    hex.EncodeToString(digest.hash.Sum(nil)),
+		MediaType: mediaType,
+	}, nil
+}
+
+// Open returns a reader of the blob of att, downloading it from the store if passed by reference.
+// Reading such a blob fails with ErrCorrupt at its end if it does not match its size or digest.
+func (a *Attacher) Open(ctx context.Context, att Attachment) (io.ReadCloser, error) {
+	if att.Inline() {
+		return io.NopCloser(bytes.NewReader(att.Data)), nil
+	}
+	if a.store == nil {
+		return nil, errNoStore
+	}
+	blob, err := a.store.Get(ctx, att.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &verifiedReader{
+		digestReader: digestReader{r: io.LimitReader(blob, att.Size+1), hash: sha256.New()},
+		closer:       blob,
+		att:          att,
+	}, nil
+}
+
+// Bytes returns the blob of att, read with Open.
+func (a *Attacher) Bytes(ctx context.Context, att Attachment) ([]byte, error) {
+	r, err := a.Open(ctx, att)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(r)
+}
+
+// digestReader hashes and counts the bytes read from r.
+type digestReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+}
+
+// Read reads from r, hashing and counting the bytes read.
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	_, _ = d.hash.Write(p[:n])
+	d.size += int64(n)
+	return n, err
+}
+
+// verifiedReader reads a blob passed by reference, checking it against its attachment at its
+// end.
+type verifiedReader struct {
+	digestReader
+	closer io.Closer
+	att    Attachment
+}
+
+// Read reads from the blob, replacing its end with ErrCorrupt if it does not match.
+func (v *verifiedReader) Read(p []byte) (int, error) {
+	n, err := v.digestReader.Read(p)
+	if v.size > v.att.Size {
+		return n, fmt.Errorf("%w: larger than %d bytes", ErrCorrupt, v.att.Size)
+	}
+	if errors.Is(err, io.EOF) {
+		if v.size != v.att.Size {
+			return n, fmt.Errorf("%w: %d bytes, want %d", ErrCorrupt, v.size, v.att.Size)
+		}
+		if sum := hex.EncodeToString(v.hash.Sum(nil)); sum != v.att.SHA256 {
+			return n, fmt.Errorf("%w: digest mismatch", ErrCorrupt)
+		}
+	}
+	return n, err
+}
+
+// Close closes the download of the blob.
+func (v *verifiedReader) Close() error {
+	return v.closer.Close()
+}
@@ -0,0 +1,161 @@
+package attach
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverTransport hands payloads to a server in process.
+type serverTransport struct {
+	srv *jsonrpc.Server
+}
+
+func (t serverTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	return t.srv.HandleMessage(ctx, payload), nil
+}
+
+func (serverTransport) Close() error {
+	return nil
+}
+
+// blob returns n bytes of recognizable data.
+func blob(n int) []byte {
+	return bytes.Repeat([]byte("attach"), n/6+1)[:n]
+}
+
+func TestAttacher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Small blobs are inlined", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := New(store, WithInlineLimit(16))
+
+		att, err := a.Attach(ctx, blob(16), "text/plain")
+		require.NoError(t, err)
+		assert.True(t, att.Inline())
+		assert.Equal(t, int64(16), att.Size)
+		assert.Zero(t, store.Len())
+
+		got, err := a.Bytes(ctx, att)
+		require.NoError(t, err)
+		assert.Equal(t, blob(16), got)
+	})
+
+	t.Run("Large blobs are passed by reference", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := New(store, WithInlineLimit(16))
+
+		att, err := a.Attach(ctx, blob(100), "")
+		require.NoError(t, err)
+		assert.False(t, att.Inline())
+		assert.Empty(t, att.Data)
+		assert.Equal(t, int64(100), att.Size)
+		assert.Len(t, att.SHA256, 64)
+		assert.Equal(t, 1, store.Len())
+
+		got, err := a.Bytes(ctx, att)
+		require.NoError(t, err)
+		assert.Equal(t, blob(100), got)
+	})
+
+	t.Run("Blobs are verified", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := New(store, WithInlineLimit(16))
+		att, err := a.Attach(ctx, blob(100), "")
+		require.NoError(t, err)
+
+		tampered := att
+		tampered.SHA256 = att.SHA256[1:] + "0"
+		_, err = a.Bytes(ctx, tampered)
+		require.ErrorIs(t, err, ErrCorrupt)
+
+		shorter := att
+		shorter.Size = 50
+		_, err = a.Bytes(ctx, shorter)
+		require.ErrorIs(t, err, ErrCorrupt)
+
+		longer := att
+		longer.Size = 150
+		_, err = a.Bytes(ctx, longer)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("Unknown reference", func(t *testing.T) {
+		a := New(NewMemoryStore())
+		_, err := a.Open(ctx, Attachment{Ref: "missing", Size: 1})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Without a store", func(t *testing.T) {
+		a := New(nil, WithInlineLimit(16))
+
+		_, err := a.Attach(ctx, blob(8), "")
+		require.NoError(t, err)
+		_, err = a.Attach(ctx, blob(17), "")
+		require.ErrorIs(t, err, errNoStore)
+		_, err = a.Open(ctx, Attachment{Ref: "ref"})
+		assert.ErrorIs(t, err, errNoStore)
+	})
+
+	t.Run("Readers are streamed to the store", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := New(store, WithInlineLimit(16))
+
+		att, err := a.AttachReader(ctx, io.MultiReader(bytes.NewReader(blob(10)),
+			bytes.NewReader(blob(90))), "application/octet-stream")
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), att.Size)
+		assert.Equal(t, "application/octet-stream", att.MediaType)
+	})
+
+	t.Run("Attachments travel in params", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := New(store, WithInlineLimit(16))
+
+		type uploadParams struct {
+			Name string     `json:"name"`
+			File Attachment `json:"file"`
+		}
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("upload", func(
+			ctx context.Context,
+			req *jsonrpc.Request,
+		) (any, error) {
+			params, err := jsonrpc.DecodeParams[uploadParams](req)
+			if err != nil {
+				return nil, err
+			}
+			data, err := a.Bytes(ctx, params.File)
+			if err != nil {
+				return nil, err
+			}
+			return len(data), nil
+		}))
+		client := jsonrpc.NewClient(serverTransport{srv: srv})
+
+		for _, size := range []int{4, 4096} {
+			att, err := a.Attach(ctx, blob(size), "")
+			require.NoError(t, err)
+			got, err := jsonrpc.Call[int](ctx, client, "upload", uploadParams{Name: "f", File: att})
+			require.NoError(t, err)
+			assert.Equal(t, size, got)
+		}
+	})
+
+	t.Run("Encoding", func(t *testing.T) {
+		data, err := json.Marshal(Attachment{Data: []byte("hi"), Size: 2})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"data":"aGk=","size":2}`, string(data))
+
+		data, err = json.Marshal(Attachment{Ref: "r", Size: 9, SHA256: "ab"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ref":"r","size":9,"sha256":"ab"}`, string(data))
+	})
+}
@@ -0,0 +1,112 @@
+package attach
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxRefLength bounds the references read from the responses of uploads.
+const maxRefLength = 1 << 10
+
+// Handler returns an HTTP handler exposing store to HTTPStore clients: a POST request uploads a
+// blob, answered with its reference, and a GET request to the reference appended to the path of
+// the handler downloads it. Mounted under a prefix, the handler is wrapped in http.StripPrefix:
+//
+//	mux.Handle("/blobs/", http.StripPrefix("/blobs", attach.Handler(store)))
+//
+// Authentication is left to middleware wrapping the handler.
+func Handler(store Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			ref, err := store.Put(r.Context(), r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = io.WriteString(w, ref)
+		case http.MethodGet:
+			blob, err := store.Get(r.Context(), strings.TrimPrefix(r.URL.Path, "/"))
+			if errors.Is(err, ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer func() { _ = blob.Close() }()
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = io.Copy(w, blob)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// HTTPStore is a Store reaching the store exposed by a Handler at a URL.
+type HTTPStore struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPStore returns a store uploading to and downloading from the Handler at baseURL, through
+// client, or http.DefaultClient if nil.
+func NewHTTPStore(baseURL string, client *http.Client) *HTTPStore {
+	s := &HTTPStore{url: strings.TrimSuffix(baseURL, "/"), client: client}
+	if s.client == nil {
+		s.client = http.DefaultClient
+	}
+	return s
+}
+
+// Put implements Store.
+func (s *HTTPStore) Put(ctx context.Context, r io.Reader) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/", r)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("attach: upload failed: %s", resp.Status)
+	}
+	ref, err := io.ReadAll(io.LimitReader(resp.Body, maxRefLength))
+	if err != nil {
+		return "", err
+	}
+	return string(ref), nil
+}
+
+// Get implements Store.
+func (s *HTTPStore) Get(ctx context.Context, ref string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/"+url.PathEscape(ref), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("attach: download failed: %s", resp.Status)
+	}
+}
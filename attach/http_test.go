@@ -0,0 +1,79 @@
+package attach
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPStore(t *testing.T) {
+	ctx := context.Background()
+
+	backing := NewMemoryStore()
+	mux := http.NewServeMux()
+	mux.Handle("/blobs/", http.StripPrefix("/blobs", Handler(backing)))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	store := NewHTTPStore(server.URL+"/blobs/", server.Client())
+
+	t.Run("Upload and download", func(t *testing.T) {
+		ref, err := store.Put(ctx, strings.NewReader("over http"))
+		require.NoError(t, err)
+
+		r, err := backing.Get(ctx, ref)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "over http", string(data))
+
+		r, err = store.Get(ctx, ref)
+		require.NoError(t, err)
+		defer func() { _ = r.Close() }()
+		data, err = io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "over http", string(data))
+	})
+
+	t.Run("Unknown reference", func(t *testing.T) {
+		_, err := store.Get(ctx, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Attacher over HTTP", func(t *testing.T) {
+		a := New(store, WithInlineLimit(4))
+		att, err := a.Attach(ctx, []byte("larger than four"), "")
+		require.NoError(t, err)
+		require.False(t, att.Inline())
+
+		data, err := New(store).Bytes(ctx, att)
+		require.NoError(t, err)
+		assert.Equal(t, "larger than four", string(data))
+	})
+
+	t.Run("Other methods", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, server.URL+"/blobs/x", nil)
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("Failed upload", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInsufficientStorage)
+			},
+		))
+		t.Cleanup(failing.Close)
+
+		_, err := NewHTTPStore(failing.URL, nil).Put(ctx, strings.NewReader("x"))
+		assert.ErrorContains(t, err, "507")
+	})
+}
@@ -0,0 +1,67 @@
+package attach
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sync"
+)
+
+// refBytes is the number of random bytes of the references of a MemoryStore.
+const refBytes = 16
+
+// MemoryStore is a Store keeping blobs in memory until deleted.
+type MemoryStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string][]byte)}
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(_ context.Context, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, refBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	ref := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[ref] = data
+	return ref, nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, ref string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[ref]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete removes the blob with the given reference, if any.
+func (s *MemoryStore) Delete(ref string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, ref)
+}
+
+// Len returns the number of blobs held.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.blobs)
+}
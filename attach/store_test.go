@@ -0,0 +1,49 @@
+package attach
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Put and get", func(t *testing.T) {
+		s := NewMemoryStore()
+		ref, err := s.Put(ctx, strings.NewReader("blob"))
+		require.NoError(t, err)
+		assert.NotEmpty(t, ref)
+
+		r, err := s.Get(ctx, ref)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "blob", string(data))
+	})
+
+	t.Run("References are unique", func(t *testing.T) {
+		s := NewMemoryStore()
+		first, err := s.Put(ctx, strings.NewReader("a"))
+		require.NoError(t, err)
+		second, err := s.Put(ctx, strings.NewReader("a"))
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+		assert.Equal(t, 2, s.Len())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s := NewMemoryStore()
+		ref, err := s.Put(ctx, strings.NewReader("blob"))
+		require.NoError(t, err)
+		s.Delete(ref)
+
+		_, err = s.Get(ctx, ref)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Zero(t, s.Len())
+	})
+}
@@ -0,0 +1,352 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAuditInterval is how often audited clients check their calls by default.
+	defaultAuditInterval = time.Second
+
+	// defaultLateWindow is how long audited stream clients remember the calls that gave up by
+	// default.
+	defaultLateWindow = time.Minute
+)
+
+// AuditKind is the kind of an AuditEvent.
+type AuditKind int
+
+const (
+	// AuditStuck reports a call in flight for longer than AuditPolicy.StuckAfter.
+	AuditStuck AuditKind = iota + 1
+
+	// AuditAbandoned reports a call still in flight although its context is done, as when the
+	// goroutine making it is blocked on a transport or interceptor ignoring its context.
+	AuditAbandoned
+
+	// AuditLate reports a response received by a stream client after its call gave up, as when
+	// the call timed out before the server answered.
+	AuditLate
+)
+
+// String returns the name of k.
+func (k AuditKind) String() string {
+	switch k {
+	case AuditStuck:
+		return "stuck"
+	case AuditAbandoned:
+		return "abandoned"
+	case AuditLate:
+		return "late"
+	default:
+		return fmt.Sprintf("AuditKind(%d)", int(k))
+	}
+}
+
+// InFlightCall describes a call, or notification, of an audited client.
+type InFlightCall struct {
+	// ID is the ID of the request, nil for notifications.
+	ID any
+
+	// Method is the method called.
+	Method string
+
+	// Batch is true if the call was sent in a batch.
+	Batch bool
+
+	// Started is when the call was sent.
+	Started time.Time
+
+	// Abandoned is true if the context of the call is done.
+	Abandoned bool
+
+	// Stack is the stack of the goroutine that made the call, recorded with AuditPolicy.Stacks.
+	Stack string
+}
+
+// AuditEvent reports a correlation leak detected by an audited client.
+type AuditEvent struct {
+	Kind AuditKind
+
+	// Call is the call concerned.
+	Call InFlightCall
+
+	// Response is the late response, for AuditLate events.
+	Response *Response
+
+	// Age is how long the call had been in flight, or, for AuditLate events, how long after the
+	// call gave up the response arrived.
+	Age time.Duration
+}
+
+// AuditPolicy configures WithClientAudit.
+type AuditPolicy struct {
+	// StuckAfter is how long a call may stay in flight before it is reported as AuditStuck. Zero
+	// reports no call as stuck.
+	StuckAfter time.Duration
+
+	// Interval is how often the calls in flight are checked. Calls are reported as abandoned once
+	// they stay in flight for an interval after their context is done. Defaults to one second.
+	Interval time.Duration
+
+	// LateWindow is how long a stream client remembers the calls that gave up, to report the
+	// responses arriving for them as AuditLate. Defaults to one minute.
+	LateWindow time.Duration
+
+	// Stacks records the stack of the goroutine making each call, for dumps and events. It
+	// costs a stack trace per exchange.
+	Stacks bool
+
+	// OnEvent is called with each event, from the goroutine of the audit for stuck and abandoned
+	// calls and from the read loop for late responses. It should not block. Each call is reported
+	// at most once per kind.
+	OnEvent func(event AuditEvent)
+}
+
+// WithClientAudit enables the audit mode of a client, a debug mode tracking every exchange to
+// detect leaks of the correlation of responses to calls: calls that never get a response, calls
+// whose goroutines stay blocked once their context is done, and, on stream clients, responses
+// arriving after their call gave up. The calls in flight can be listed at any time with
+// Client.InFlight and Client.DumpInFlight, to troubleshoot hangs in production.
+func WithClientAudit(policy AuditPolicy) ClientOption {
+	return func(c *Client) {
+		c.audit = newAuditor(policy)
+	}
+}
+
+// InFlight returns the calls and notifications sent and not yet completed, oldest first. It
+// returns nil unless the client was created with WithClientAudit.
+func (c *Client) InFlight() []InFlightCall {
+	if c.audit == nil {
+		return nil
+	}
+	return c.audit.inFlight()
+}
+
+// DumpInFlight writes the calls of InFlight as text to w, a line per call followed by the stack
+// of its goroutine when recorded.
+func (c *Client) DumpInFlight(w io.Writer) error {
+	calls := c.InFlight()
+	if _, err := fmt.Fprintf(w, "%d calls in flight\n", len(calls)); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, call := range calls {
+		state := "waiting"
+		if call.Abandoned {
+			state = "abandoned"
+		}
+		if call.Batch {
+			state += ", batch"
+		}
+		_, err := fmt.Fprintf(w, "id=%v method=%s age=%s (%s)\n",
+			call.ID, call.Method, now.Sub(call.Started).Round(time.Millisecond), state)
+		if err != nil {
+			return err
+		}
+		if call.Stack != "" {
+			if _, err := fmt.Fprintf(w, "%s\n", call.Stack); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// auditor tracks the exchanges of an audited client.
+type auditor struct {
+	policy AuditPolicy
+
+	mu        sync.Mutex
+	exchanges map[uint64]*auditedExchange
+	next      uint64
+	gaveUp    map[string]gaveUpCall
+}
+
+// auditedExchange is an exchange in flight.
+type auditedExchange struct {
+	ctx       context.Context
+	calls     []InFlightCall
+	doneSeen  time.Time
+	stuck     bool
+	abandoned bool
+}
+
+// gaveUpCall is a call of a stream client that gave up without a response.
+type gaveUpCall struct {
+	call InFlightCall
+	at   time.Time
+}
+
+// newAuditor returns an auditor applying the defaults of policy.
+func newAuditor(policy AuditPolicy) *auditor {
+	p := policy
+	if p.Interval <= 0 {
+		p.Interval = defaultAuditInterval
+	}
+	if p.LateWindow <= 0 {
+		p.LateWindow = defaultLateWindow
+	}
+	return &auditor{
+		policy:    p,
+		exchanges: make(map[uint64]*auditedExchange),
+		gaveUp:    make(map[string]gaveUpCall),
+	}
+}
+
+// begin tracks the exchange of payload and returns its sequence number for end.
+func (a *auditor) begin(ctx context.Context, payload []byte) uint64 {
+	ex := &auditedExchange{ctx: ctx}
+	started := time.Now()
+	var stack string
+	if a.policy.Stacks {
+		stack = string(debug.Stack())
+	}
+	reqs, isBatch, err := DecodeRequestOrBatch(payload)
+	if err != nil {
+		reqs = nil
+	}
+	for _, req := range reqs {
+		ex.calls = append(ex.calls, InFlightCall{
+			ID:      req.ID,
+			Method:  req.Method,
+			Batch:   isBatch,
+			Started: started,
+			Stack:   stack,
+		})
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.next++
+	a.exchanges[a.next] = ex
+	return a.next
+}
+
+// end stops tracking the exchange numbered seq.
+func (a *auditor) end(seq uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.exchanges, seq)
+}
+
+// abandon remembers that the call awaiting the response for key gave up without it.
+func (a *auditor) abandon(key string) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ex := range a.exchanges {
+		for _, call := range ex.calls {
+			if call.ID != nil && idKey(call.ID) == key {
+				call.Abandoned = true
+				a.gaveUp[key] = gaveUpCall{call: call, at: now}
+				return
+			}
+		}
+	}
+}
+
+// late reports resp, which matched no call, if it answers a call that gave up.
+func (a *auditor) late(key string, resp *Response) {
+	a.mu.Lock()
+	gone, ok := a.gaveUp[key]
+	delete(a.gaveUp, key)
+	a.mu.Unlock()
+
+	if ok && a.policy.OnEvent != nil {
+		a.policy.OnEvent(AuditEvent{
+			Kind:     AuditLate,
+			Call:     gone.call,
+			Response: resp,
+			Age:      time.Since(gone.at),
+		})
+	}
+}
+
+// inFlight returns the calls in flight, oldest first.
+func (a *auditor) inFlight() []InFlightCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var calls []InFlightCall
+	for _, ex := range a.exchanges {
+		abandoned := ex.ctx.Err() != nil
+		for _, call := range ex.calls {
+			call.Abandoned = abandoned
+			calls = append(calls, call)
+		}
+	}
+	slices.SortStableFunc(calls, func(x, y InFlightCall) int {
+		return x.Started.Compare(y.Started)
+	})
+	return calls
+}
+
+// watch checks the calls in flight every interval until done is closed.
+func (a *auditor) watch(done <-chan struct{}) {
+	ticker := time.NewTicker(a.policy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.check(time.Now())
+		case <-done:
+			return
+		}
+	}
+}
+
+// check reports the calls stuck or abandoned at now, and forgets the calls that gave up longer
+// than the late window ago.
+func (a *auditor) check(now time.Time) {
+	var events []AuditEvent
+	a.mu.Lock()
+	for _, ex := range a.exchanges {
+		events = append(events, a.checkExchange(ex, now)...)
+	}
+	for key, gone := range a.gaveUp {
+		if now.Sub(gone.at) > a.policy.LateWindow {
+			delete(a.gaveUp, key)
+		}
+	}
+	a.mu.Unlock()
+
+	if a.policy.OnEvent == nil {
+		return
+	}
+	for _, event := range events {
+		a.policy.OnEvent(event)
+	}
+}
+
+// checkExchange returns the events of an exchange at now.
+func (a *auditor) checkExchange(ex *auditedExchange, now time.Time) []AuditEvent {
+	var kinds []AuditKind
+	if !ex.stuck && a.policy.StuckAfter > 0 && len(ex.calls) > 0 &&
+		now.Sub(ex.calls[0].Started) >= a.policy.StuckAfter {
+		ex.stuck = true
+		kinds = append(kinds, AuditStuck)
+	}
+	if !ex.abandoned && ex.ctx.Err() != nil {
+		if ex.doneSeen.IsZero() {
+			ex.doneSeen = now
+		} else if now.Sub(ex.doneSeen) >= a.policy.Interval {
+			ex.abandoned = true
+			kinds = append(kinds, AuditAbandoned)
+		}
+	}
+
+	var events []AuditEvent
+	for _, kind := range kinds {
+		for _, call := range ex.calls {
+			call.Abandoned = ex.ctx.Err() != nil
+			events = append(events, AuditEvent{Kind: kind, Call: call, Age: now.Sub(call.Started)})
+		}
+	}
+	return events
+}
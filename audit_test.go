@@ -0,0 +1,184 @@
+package jsonrpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditEvents returns a policy checking calls every few milliseconds, along with the channel
+// receiving its events.
+func auditEvents(policy AuditPolicy) (AuditPolicy, <-chan AuditEvent) {
+	events := make(chan AuditEvent, 16)
+	policy.Interval = 5 * time.Millisecond
+	policy.OnEvent = func(event AuditEvent) {
+		events <- event
+	}
+	return policy, events
+}
+
+// nextAuditEvent returns the next event, failing t if none arrives in time.
+func nextAuditEvent(t *testing.T, events <-chan AuditEvent) AuditEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no audit event")
+		return AuditEvent{}
+	}
+}
+
+func TestClientAudit(t *testing.T) {
+	t.Run("Calls in flight are listed and dumped", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		client := NewStreamClient(clientEnd, WithClientAudit(AuditPolicy{Stacks: true}))
+		defer client.Close()
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- client.Call(context.Background(), "eth_getLogs", []any{1}, nil)
+		}()
+		msg, err := serverEnd.ReadMessage(context.Background())
+		require.NoError(t, err)
+
+		calls := client.InFlight()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "eth_getLogs", calls[0].Method)
+		assert.NotNil(t, calls[0].ID)
+		assert.False(t, calls[0].Abandoned)
+		assert.False(t, calls[0].Batch)
+		assert.Contains(t, calls[0].Stack, "TestClientAudit")
+
+		var dump strings.Builder
+		require.NoError(t, client.DumpInFlight(&dump))
+		assert.True(t, strings.HasPrefix(dump.String(), "1 calls in flight\nid="))
+		assert.Contains(t, dump.String(), "method=eth_getLogs")
+		assert.Contains(t, dump.String(), "(waiting)")
+
+		req, err := DecodeRequest(msg)
+		require.NoError(t, err)
+		resp, err := NewResponse(req.ID, "ok")
+		require.NoError(t, err)
+		reply, err := resp.MarshalJSON()
+		require.NoError(t, err)
+		require.NoError(t, serverEnd.WriteMessage(context.Background(), reply))
+		require.NoError(t, <-errs)
+		assert.Empty(t, client.InFlight())
+	})
+
+	t.Run("Late responses are reported", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		policy, events := auditEvents(AuditPolicy{})
+		client := NewStreamClient(clientEnd, WithClientAudit(policy))
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := client.Call(ctx, "eth_call", nil, nil, WithCallID("slow"))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		_, err = serverEnd.ReadMessage(context.Background())
+		require.NoError(t, err)
+		resp, err := NewResponse("slow", "0x1")
+		require.NoError(t, err)
+		reply, err := resp.MarshalJSON()
+		require.NoError(t, err)
+		require.NoError(t, serverEnd.WriteMessage(context.Background(), reply))
+
+		event := nextAuditEvent(t, events)
+		assert.Equal(t, AuditLate, event.Kind)
+		assert.Equal(t, "eth_call", event.Call.Method)
+		assert.True(t, event.Call.Abandoned)
+		assert.Equal(t, "slow", event.Response.IDString())
+		assert.Positive(t, event.Age)
+	})
+
+	t.Run("Stuck calls are reported once", func(t *testing.T) {
+		clientEnd, _ := newStreamPair()
+		policy, events := auditEvents(AuditPolicy{StuckAfter: 10 * time.Millisecond})
+		client := NewStreamClient(clientEnd, WithClientAudit(policy))
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- client.Call(context.Background(), "eth_subscribe", nil, nil)
+		}()
+		event := nextAuditEvent(t, events)
+		assert.Equal(t, AuditStuck, event.Kind)
+		assert.Equal(t, "eth_subscribe", event.Call.Method)
+		assert.GreaterOrEqual(t, event.Age, 10*time.Millisecond)
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Empty(t, events, "calls are reported once per kind")
+		require.NoError(t, client.Close())
+		require.ErrorIs(t, <-errs, ErrClientClosed)
+	})
+
+	t.Run("Goroutines blocked on abandoned calls are reported", func(t *testing.T) {
+		release := make(chan struct{})
+		blocking := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			<-release
+			return nil, context.Canceled
+		}}
+		policy, events := auditEvents(AuditPolicy{})
+		client := NewClient(blocking, WithClientAudit(policy))
+		defer client.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() {
+			errs <- client.Notify(ctx, "log", []any{"x"})
+		}()
+		require.Eventually(t, func() bool { return len(client.InFlight()) == 1 },
+			time.Second, time.Millisecond)
+		cancel()
+
+		event := nextAuditEvent(t, events)
+		assert.Equal(t, AuditAbandoned, event.Kind)
+		assert.Equal(t, "log", event.Call.Method)
+		assert.Nil(t, event.Call.ID)
+		assert.True(t, client.InFlight()[0].Abandoned)
+
+		close(release)
+		require.Error(t, <-errs)
+		assert.Empty(t, client.InFlight())
+	})
+
+	t.Run("Batches are tracked per call", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		client := NewStreamClient(clientEnd, WithClientAudit(AuditPolicy{}))
+		defer client.Close()
+
+		go func() {
+			_, _ = client.CallBatch(context.Background(), []*Request{
+				NewRequestWithID("a", nil, "1"),
+				NewRequestWithID("b", nil, "2"),
+			})
+		}()
+		_, err := serverEnd.ReadMessage(context.Background())
+		require.NoError(t, err)
+		calls := client.InFlight()
+		require.Len(t, calls, 2)
+		assert.ElementsMatch(t, []string{"a", "b"}, []string{calls[0].Method, calls[1].Method})
+		assert.True(t, calls[0].Batch)
+	})
+
+	t.Run("Clients without audit", func(t *testing.T) {
+		client := NewClient(&funcTransport{})
+		assert.Nil(t, client.InFlight())
+		var dump strings.Builder
+		require.NoError(t, client.DumpInFlight(&dump))
+		assert.Equal(t, "0 calls in flight\n", dump.String())
+	})
+}
+
+func TestAuditKind_String(t *testing.T) {
+	assert.Equal(t, "stuck", AuditStuck.String())
+	assert.Equal(t, "abandoned", AuditAbandoned.String())
+	assert.Equal(t, "late", AuditLate.String())
+	assert.Equal(t, "AuditKind(9)", AuditKind(9).String())
+}
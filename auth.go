@@ -0,0 +1,281 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unauthenticated is the error code of responses to requests turned away by an Authenticator,
+// within the range reserved for implementation-defined server errors.
+const Unauthenticated = -32015
+
+// msgUnauthenticated is the message of Unauthenticated errors.
+const msgUnauthenticated = "Unauthenticated"
+
+// ErrUnauthenticated is the error sent for requests an Authenticator rejected.
+var ErrUnauthenticated = &Error{Code: Unauthenticated, Message: msgUnauthenticated}
+
+// Metadata keys of the HMAC signatures made with HMACSigner.
+const (
+	// SignatureMetadataKey holds the hex-encoded HMAC-SHA256 of the timestamp, a dot, and the
+	// encoded message.
+	SignatureMetadataKey = "x-jsonrpc-signature"
+
+	// TimestampMetadataKey holds the Unix time in seconds at which the message was signed.
+	TimestampMetadataKey = "x-jsonrpc-timestamp"
+)
+
+// authorizationMetadataKey is the metadata key of HTTP Authorization headers.
+const authorizationMetadataKey = "authorization"
+
+// Credentials authenticate the messages a Client sends, through the metadata sent alongside
+// them. Only transports carrying metadata, such as the HTTP transport, send credentials.
+// Implementations must be safe for concurrent use.
+type Credentials interface {
+	// Metadata returns the metadata authenticating the encoded message payload.
+	Metadata(ctx context.Context, payload []byte) (Metadata, error)
+}
+
+// CredentialsFunc adapts an ordinary function into Credentials.
+type CredentialsFunc func(ctx context.Context, payload []byte) (Metadata, error)
+
+// Metadata calls f(ctx, payload).
+func (f CredentialsFunc) Metadata(ctx context.Context, payload []byte) (Metadata, error) {
+	return f(ctx, payload)
+}
+
+// WithCredentials makes the client authenticate every message it sends, including batches, with
+// creds. Several credentials may be added, and their metadata is joined.
+func WithCredentials(creds Credentials) ClientOption {
+	return func(c *Client) {
+		c.credentials = append(c.credentials, creds)
+	}
+}
+
+// BearerToken returns credentials sending token in an "Authorization: Bearer" header.
+func BearerToken(token string) Credentials {
+	md := MetadataPairs(authorizationMetadataKey, "Bearer "+token)
+	return CredentialsFunc(func(context.Context, []byte) (Metadata, error) {
+		return md, nil
+	})
+}
+
+// BasicAuth returns credentials sending username and password in an "Authorization: Basic"
+// header.
+func BasicAuth(username, password string) Credentials {
+	encoded := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	md := MetadataPairs(authorizationMetadataKey, "Basic "+encoded)
+	return CredentialsFunc(func(context.Context, []byte) (Metadata, error) {
+		return md, nil
+	})
+}
+
+// HMACSigner returns credentials signing every message with secret, under SignatureMetadataKey
+// and TimestampMetadataKey, for servers to verify with HMACAuthenticator. The signature covers
+// the JSON encoding of the message, so transports must send it as is.
+func HMACSigner(secret []byte) Credentials {
+	return CredentialsFunc(func(_ context.Context, payload []byte) (Metadata, error) {
+		timestamp := strconv.FormatInt(time.Now().Unix(), decimal)
+		return MetadataPairs(
+			SignatureMetadataKey, sign(secret, timestamp, payload),
+			TimestampMetadataKey, timestamp,
+		), nil
+	})
+}
+
+// authenticate returns ctx carrying, as outgoing metadata, the metadata of the client's
+// credentials for payload.
+func (c *Client) authenticate(ctx context.Context, payload []byte) (context.Context, error) {
+	if len(c.credentials) == 0 {
+		return ctx, nil
+	}
+	md, _ := OutgoingMetadata(ctx)
+	for _, creds := range c.credentials {
+		credsMD, err := creds.Metadata(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		md = md.Join(credsMD)
+	}
+	return NewOutgoingContext(ctx, md), nil
+}
+
+// Principal is the authenticated identity of a caller.
+type Principal struct {
+	// ID identifies the caller, such as a user or key name.
+	ID string
+
+	// Roles are the roles granted to the caller.
+	Roles []string
+}
+
+// principalContextKey is the context key under which the Principal of a request is stored.
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the Principal the server's Authenticator returned for the current
+// request.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Authenticator authenticates the requests a Server receives, typically from their
+// IncomingMetadata. Implementations must be safe for concurrent use.
+type Authenticator interface {
+	// Authenticate returns the Principal behind req, or an error to reject it.
+	Authenticate(ctx context.Context, req *Request) (*Principal, error)
+}
+
+// AuthenticatorFunc adapts an ordinary function into an Authenticator.
+type AuthenticatorFunc func(ctx context.Context, req *Request) (*Principal, error)
+
+// Authenticate calls f(ctx, req).
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, req *Request) (*Principal, error) {
+	return f(ctx, req)
+}
+
+// WithAuthenticator makes the server authenticate every request with auth before dispatching it,
+// including to middleware, timeouts, and limits. Requests it rejects are answered with the
+// *Error it returned, or with ErrUnauthenticated for other errors, and over HTTP with 401
+// Unauthorized. Handlers find the authenticated caller with PrincipalFromContext.
+func WithAuthenticator(auth Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = auth
+	}
+}
+
+// BearerAuthenticator returns an Authenticator accepting requests whose "Authorization: Bearer"
+// header holds a token for which verify returns a Principal.
+func BearerAuthenticator(
+	verify func(ctx context.Context, token string) (*Principal, error),
+) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, _ *Request) (*Principal, error) {
+		token, ok := authorization(ctx, "Bearer")
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		return verify(ctx, token)
+	})
+}
+
+// BasicAuthenticator returns an Authenticator accepting requests whose "Authorization: Basic"
+// header holds credentials for which verify returns a Principal.
+func BasicAuthenticator(
+	verify func(ctx context.Context, username, password string) (*Principal, error),
+) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, _ *Request) (*Principal, error) {
+		encoded, ok := authorization(ctx, "Basic")
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		return verify(ctx, username, password)
+	})
+}
+
+// HMACAuthenticator returns an Authenticator accepting requests in messages signed by
+// HMACSigner with secret no more than maxSkew ago or ahead, authenticated as principal.
+// Rejected signatures get ErrUnauthenticated.
+func HMACAuthenticator(secret []byte, maxSkew time.Duration, principal *Principal) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, _ *Request) (*Principal, error) {
+		md, _ := IncomingMetadata(ctx)
+		timestamp := md.Get(TimestampMetadataKey)
+		unix, err := strconv.ParseInt(timestamp, decimal, 64)
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		skew := time.Since(time.Unix(unix, 0))
+		if skew > maxSkew || skew < -maxSkew {
+			return nil, ErrUnauthenticated
+		}
+
+		payload, ok := ctx.Value(payloadContextKey{}).([]byte)
+		want := sign(secret, timestamp, payload)
+		got := md.Get(SignatureMetadataKey)
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			return nil, ErrUnauthenticated
+		}
+		return principal, nil
+	})
+}
+
+// payloadContextKey is the context key under which the message a request arrived in is stored
+// for authenticators.
+type payloadContextKey struct{}
+
+// withPayload returns ctx carrying the encoded message data if the server authenticates
+// requests.
+func (s *Server) withPayload(ctx context.Context, data []byte) context.Context {
+	if s.authenticator == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, payloadContextKey{}, data)
+}
+
+// admit authenticates and authorizes req, returning ctx carrying the Principal behind req, or the
+// error to reject req with.
+func (s *Server) admit(ctx context.Context, req *Request) (context.Context, *Error) {
+	admitted := ctx
+	if s.authenticator != nil {
+		principal, err := s.authenticator.Authenticate(ctx, req)
+		if err != nil {
+			return nil, rejection(err, ErrUnauthenticated)
+		}
+		admitted = context.WithValue(ctx, principalContextKey{}, principal)
+	}
+	if s.authorizer != nil {
+		principal, _ := PrincipalFromContext(admitted)
+		if err := s.authorizer.Authorize(admitted, principal, req); err != nil {
+			return nil, rejection(err, ErrPermissionDenied)
+		}
+	}
+	return admitted, nil
+}
+
+// rejection returns the error to reject a request with when an authenticator or authorizer fails
+// with err: err itself if it is an *Error, so that its code is kept, and fallback otherwise, so
+// that the cause is not disclosed.
+func rejection(err error, fallback *Error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		if rpcErr != nil {
+			return rpcErr
+		}
+	}
+	return fallback
+}
+
+// authorization returns the credentials of the Authorization header of the given scheme.
+func authorization(ctx context.Context, scheme string) (string, bool) {
+	md, _ := IncomingMetadata(ctx)
+	value := md.Get(authorizationMetadataKey)
+	prefix, credentials, ok := strings.Cut(value, " ")
+	if !ok || !strings.EqualFold(prefix, scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(credentials), true
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of timestamp, a dot, and payload.
+func sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte{'.'})
+	_, _ = mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,203 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// whoami returns the ID of the authenticated caller.
+var whoami = HandlerFunc(func(ctx context.Context, _ *Request) (any, error) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil, errors.New("no principal")
+	}
+	return p.ID, nil
+})
+
+// newAuthServer starts an HTTP server authenticating with auth and serving whoami.
+func newAuthServer(t *testing.T, auth Authenticator) *httptest.Server {
+	t.Helper()
+	srv := NewServer(WithAuthenticator(auth))
+	require.NoError(t, srv.Register("whoami", whoami))
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// callWhoami calls whoami on url with the given credentials.
+func callWhoami(t *testing.T, url string, creds ...Credentials) (string, error) {
+	t.Helper()
+	opts := make([]ClientOption, 0, len(creds))
+	for _, c := range creds {
+		opts = append(opts, WithCredentials(c))
+	}
+	client := NewClient(NewHTTPTransport(url), opts...)
+	defer func() { _ = client.Close() }()
+	var id string
+	err := client.Call(context.Background(), "whoami", nil, &id)
+	return id, err
+}
+
+func TestBearerAuth(t *testing.T) {
+	verify := func(_ context.Context, token string) (*Principal, error) {
+		if token != "s3cret" {
+			return nil, errors.New("bad token")
+		}
+		return &Principal{ID: "alice"}, nil
+	}
+	ts := newAuthServer(t, BearerAuthenticator(verify))
+
+	id, err := callWhoami(t, ts.URL, BearerToken("s3cret"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", id)
+
+	_, err = callWhoami(t, ts.URL, BearerToken("wrong"))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = callWhoami(t, ts.URL)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = callWhoami(t, ts.URL, BasicAuth("alice", "s3cret"))
+	require.ErrorIs(t, err, ErrUnauthenticated, "the scheme must match")
+
+	body := `{"jsonrpc":"2.0","method":"whoami","id":1}`
+	resp, err := http.Post(ts.URL, contentTypeJSON, strings.NewReader(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestBasicAuth(t *testing.T) {
+	verify := func(_ context.Context, username, password string) (*Principal, error) {
+		if username != "bob" || password != "p:w" {
+			return nil, &Error{Code: -32099, Message: "Go away"}
+		}
+		return &Principal{ID: username}, nil
+	}
+	ts := newAuthServer(t, BasicAuthenticator(verify))
+
+	id, err := callWhoami(t, ts.URL, BasicAuth("bob", "p:w"))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", id)
+
+	_, err = callWhoami(t, ts.URL, BasicAuth("bob", "nope"))
+	assert.True(t, IsCode(err, -32099), "errors of the authenticator are sent as is")
+}
+
+func TestHMACAuth(t *testing.T) {
+	secret := []byte("shared")
+	ts := newAuthServer(t, HMACAuthenticator(secret, time.Minute, &Principal{ID: "svc"}))
+
+	t.Run("Signed calls and batches are accepted", func(t *testing.T) {
+		id, err := callWhoami(t, ts.URL, HMACSigner(secret))
+		require.NoError(t, err)
+		assert.Equal(t, "svc", id)
+
+		client := NewClient(NewHTTPTransport(ts.URL), WithCredentials(HMACSigner(secret)))
+		defer func() { _ = client.Close() }()
+		resps, err := client.CallBatch(context.Background(), []*Request{
+			NewRequestWithID("whoami", nil, int64(1)),
+			NewRequestWithID("whoami", nil, int64(2)),
+		})
+		require.NoError(t, err)
+		for _, resp := range resps {
+			assert.Nil(t, resp.Err())
+		}
+	})
+
+	t.Run("Wrong secret is rejected", func(t *testing.T) {
+		_, err := callWhoami(t, ts.URL, HMACSigner([]byte("other")))
+		require.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("Tampered and stale messages are rejected", func(t *testing.T) {
+		post := func(body, timestamp, signature string) int {
+			req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentTypeJSON)
+			req.Header.Set(TimestampMetadataKey, timestamp)
+			req.Header.Set(SignatureMetadataKey, signature)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		body := `{"jsonrpc":"2.0","method":"whoami","id":1}`
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		assert.Equal(t, http.StatusOK, post(body, now, sign(secret, now, []byte(body))))
+
+		tampered := `{"jsonrpc":"2.0","method":"whoami","id":2}`
+		assert.Equal(t, http.StatusUnauthorized,
+			post(tampered, now, sign(secret, now, []byte(body))))
+
+		stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		assert.Equal(t, http.StatusUnauthorized,
+			post(body, stale, sign(secret, stale, []byte(body))))
+	})
+}
+
+func TestServer_Authenticator(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	srv := NewServer(WithAuthenticator(AuthenticatorFunc(
+		func(ctx context.Context, _ *Request) (*Principal, error) {
+			calls++
+			md, _ := IncomingMetadata(ctx)
+			if md.Get("x-key") != "k" {
+				return nil, errors.New("missing key")
+			}
+			return &Principal{ID: "k", Roles: []string{"reader"}}, nil
+		},
+	)))
+	require.NoError(t, srv.Register("whoami", whoami))
+
+	t.Run("Rejected requests are not dispatched", func(t *testing.T) {
+		resp := srv.HandleRequest(ctx, NewRequestWithID("whoami", nil, int64(1)))
+		require.ErrorIs(t, resp.Err(), ErrUnauthenticated)
+		assert.Nil(t, resp.Err().Data, "the cause is not disclosed")
+		assert.Nil(t, srv.HandleRequest(ctx, NewNotification("whoami", nil)))
+	})
+
+	t.Run("Each batch member is authenticated", func(t *testing.T) {
+		calls = 0
+		authCtx := NewIncomingContext(ctx, MetadataPairs("x-key", "k"))
+		msg := `[{"jsonrpc":"2.0","method":"whoami","id":1},` +
+			`{"jsonrpc":"2.0","method":"whoami","id":2}]`
+		resps, err := DecodeBatchResponse(srv.HandleMessage(authCtx, []byte(msg)))
+		require.NoError(t, err)
+		for _, resp := range resps {
+			var id string
+			require.NoError(t, resp.UnmarshalResult(&id))
+			assert.Equal(t, "k", id)
+		}
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestClient_CredentialsJoinMetadata(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	fail := CredentialsFunc(func(context.Context, []byte) (Metadata, error) {
+		return nil, errors.New("no token")
+	})
+	client := NewClient(NewHTTPTransport(ts.URL), WithCredentials(BearerToken("t")))
+	ctx := AppendToOutgoingContext(context.Background(), "x-trace", "1")
+	require.NoError(t, client.Notify(ctx, "ping", nil))
+	assert.Equal(t, "Bearer t", got.Get("Authorization"))
+	assert.Equal(t, "1", got.Get("X-Trace"))
+
+	failing := NewClient(NewHTTPTransport(ts.URL), WithCredentials(fail))
+	assert.EqualError(t, failing.Notify(context.Background(), "ping", nil), "no token")
+}
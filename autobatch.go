@@ -0,0 +1,191 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithAutoBatching makes the client gather the calls issued within window of the first one into
+// a single batch request, and hand each caller the response to its own call, so that traffic to
+// endpoints limiting requests rather than calls is cut down. A batch is sent early once it holds
+// maxSize calls, or the size limit set with WithClientMaxBatchSize; zero leaves only the latter.
+//
+// Calls pass through the interceptors one by one before being gathered, so that retries and rate
+// limits apply to each of them. Notifications and calls carrying outgoing metadata, which could
+// not share the headers of one request, are sent on their own, as are calls reusing the ID of a
+// gathered call. A caller giving up on its context stops waiting; the batch is canceled once all
+// of its callers have given up.
+func WithAutoBatching(window time.Duration, maxSize int) ClientOption {
+	return func(c *Client) {
+		c.batcher = &autoBatcher{client: c, window: window, maxSize: max(maxSize, 0)}
+	}
+}
+
+// autoBatcher gathers calls into batches.
+type autoBatcher struct {
+	client  *Client
+	window  time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	pending *pendingBatch
+}
+
+// pendingBatch is a batch gathering calls until its window closes.
+type pendingBatch struct {
+	calls []*batchedCall
+	keys  map[string]struct{}
+}
+
+// batchedCall is a call gathered into a batch.
+type batchedCall struct {
+	ctx  context.Context
+	req  *Request
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// send gathers req into the pending batch and waits for its response.
+func (b *autoBatcher) send(ctx context.Context, req *Request) (*Response, error) {
+	if _, ok := OutgoingMetadata(ctx); ok {
+		return b.client.sendOne(ctx, req)
+	}
+	call := &batchedCall{ctx: ctx, req: req, done: make(chan struct{})}
+	if !b.add(call) {
+		return b.client.sendOne(ctx, req)
+	}
+	select {
+	case <-call.done:
+		return call.resp, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// add appends call to the pending batch, starting one if there is none and sending it once full.
+// It reports false for calls whose ID is already in the pending batch.
+func (b *autoBatcher) add(call *batchedCall) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	batch := b.pending
+	if batch == nil {
+		batch = &pendingBatch{keys: make(map[string]struct{})}
+		b.pending = batch
+		time.AfterFunc(b.window, func() { b.flush(batch) })
+	}
+	key := idKey(call.req.ID)
+	if _, dup := batch.keys[key]; dup {
+		return false
+	}
+	batch.keys[key] = struct{}{}
+	batch.calls = append(batch.calls, call)
+
+	if size := b.size(); size > 0 && len(batch.calls) >= size {
+		b.pending = nil
+		go b.client.sendGathered(batch.calls)
+	}
+	return true
+}
+
+// size returns the number of calls filling a batch, or zero for no limit.
+func (b *autoBatcher) size() int {
+	limit := b.client.limits.maxBatchSize
+	if b.maxSize > 0 && (limit == 0 || b.maxSize < limit) {
+		return b.maxSize
+	}
+	return limit
+}
+
+// flush sends batch once its window has closed, unless it was sent for being full.
+func (b *autoBatcher) flush(batch *pendingBatch) {
+	b.mu.Lock()
+	if b.pending != batch {
+		b.mu.Unlock()
+		return
+	}
+	b.pending = nil
+	b.mu.Unlock()
+	b.client.sendGathered(batch.calls)
+}
+
+// sendGathered sends the calls still awaited as one batch, or a lone call as itself, and hands
+// their callers the outcome. The batch runs detached from the contexts of the calls and is
+// canceled once all of their callers have given up.
+func (c *Client) sendGathered(calls []*batchedCall) {
+	live := make([]*batchedCall, 0, len(calls))
+	for _, call := range calls {
+		if err := call.ctx.Err(); err != nil {
+			call.err = err
+			close(call.done)
+			continue
+		}
+		live = append(live, call)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(live[0].ctx))
+	defer cancel()
+	var waiting atomic.Int32
+	waiting.Store(int32(len(live)))
+	stops := make([]func() bool, len(live))
+	for i, call := range live {
+		stops[i] = context.AfterFunc(call.ctx, func() {
+			if waiting.Add(-1) == 0 {
+				cancel()
+			}
+		})
+	}
+	defer func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+
+	if len(live) == 1 {
+		live[0].resp, live[0].err = c.sendOne(ctx, live[0].req)
+		close(live[0].done)
+		return
+	}
+	reqs := make([]*Request, len(live))
+	for i, call := range live {
+		reqs[i] = call.req
+	}
+	replies, err := c.sendBatch(ctx, reqs)
+	for _, call := range live {
+		switch resp, ok := replies[idKey(call.req.ID)]; {
+		case err != nil:
+			call.err = err
+		case !ok:
+			call.err = fmt.Errorf("missing response for request id %v", call.req.ID)
+		default:
+			call.resp = resp
+		}
+		close(call.done)
+	}
+}
+
+// sendBatch marshals reqs, calls with distinct IDs, as a batch and exchanges it.
+func (c *Client) sendBatch(ctx context.Context, reqs []*Request) (map[string]*Response, error) {
+	payload, err := EncodeBatchRequest(reqs)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(reqs))
+	ids := make([]any, len(reqs))
+	for i, req := range reqs {
+		keys[i] = idKey(req.ID)
+		ids[i] = req.ID
+	}
+	replies, err := c.exchange(ctx, payload, keys)
+	if err != nil {
+		c.propagateCancel(ctx, err, ids...)
+		return nil, err
+	}
+	return replies, nil
+}
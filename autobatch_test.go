@@ -0,0 +1,92 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchWindow is the window of auto-batching clients under test, long enough for the goroutines
+// of a test to issue their calls within it.
+const batchWindow = 50 * time.Millisecond
+
+func TestClient_WithAutoBatching(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+
+	// callSums issues n concurrent sum calls, checking their results.
+	callSums := func(t *testing.T, client *Client, n int) {
+		t.Helper()
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Go(func() {
+				var total int
+				assert.NoError(t, client.Call(ctx, "sum", []int{i, 1}, &total))
+				assert.Equal(t, i+1, total)
+			})
+		}
+		wg.Wait()
+	}
+
+	t.Run("Gathers concurrent calls into one batch", func(t *testing.T) {
+		var sent atomic.Int32
+		client := NewClient(countingTransport(srv, &sent), WithAutoBatching(batchWindow, 0))
+		defer client.Close()
+
+		callSums(t, client, 5)
+		assert.Equal(t, int32(1), sent.Load())
+
+		require.NoError(t, client.Call(ctx, "sum", []int{1}, nil))
+		assert.Equal(t, int32(2), sent.Load(), "lone calls are sent as themselves")
+	})
+
+	t.Run("Sends full batches early", func(t *testing.T) {
+		var sent atomic.Int32
+		client := NewClient(countingTransport(srv, &sent),
+			WithAutoBatching(time.Hour, 2), WithClientMaxBatchSize(3))
+		defer client.Close()
+
+		callSums(t, client, 4)
+		assert.Equal(t, int32(2), sent.Load())
+	})
+
+	t.Run("Errors belong to their own call", func(t *testing.T) {
+		var sent atomic.Int32
+		client := NewClient(countingTransport(srv, &sent), WithAutoBatching(batchWindow, 0))
+		defer client.Close()
+
+		var wg sync.WaitGroup
+		var failErr, sumErr error
+		wg.Go(func() { failErr = client.Call(ctx, "fail", nil, nil) })
+		wg.Go(func() { sumErr = client.Call(ctx, "sum", []int{1}, nil) })
+		wg.Wait()
+		assert.Error(t, failErr)
+		assert.NoError(t, sumErr)
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("Sends some calls on their own", func(t *testing.T) {
+		var sent atomic.Int32
+		client := NewClient(countingTransport(srv, &sent), WithAutoBatching(time.Hour, 0))
+		defer client.Close()
+
+		require.NoError(t, client.Notify(ctx, "noop", nil))
+		require.NoError(t, client.Call(ctx, "sum", []int{1}, nil, WithCallHeader("X-Tenant", "a")))
+		assert.Equal(t, int32(2), sent.Load())
+	})
+
+	t.Run("Callers giving up stop waiting", func(t *testing.T) {
+		var sent atomic.Int32
+		client := NewClient(countingTransport(srv, &sent), WithAutoBatching(time.Hour, 0))
+		defer client.Close()
+
+		err := client.Call(ctx, "sum", []int{1}, nil, WithCallTimeout(10*time.Millisecond))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Zero(t, sent.Load())
+	})
+}
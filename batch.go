@@ -19,7 +19,7 @@ func isBatchJSON(data []byte) bool {
 // - For batch requests: returns slice with multiple elements
 func DecodeRequestOrBatch(data []byte) (reqs []*Request, isBatch bool, err error) {
 	if len(bytes.TrimSpace(data)) == 0 {
-		return nil, false, errors.New(errEmptyData)
+		return nil, false, emptyMessage()
 	}
 
 	if isBatchJSON(data) {
@@ -39,7 +39,7 @@ func DecodeRequestOrBatch(data []byte) (reqs []*Request, isBatch bool, err error
 // - For batch responses: returns slice with multiple elements
 func DecodeResponseOrBatch(data []byte) (resps []*Response, isBatch bool, err error) {
 	if len(bytes.TrimSpace(data)) == 0 {
-		return nil, false, errors.New(errEmptyData)
+		return nil, false, emptyMessage()
 	}
 
 	if isBatchJSON(data) {
@@ -61,12 +61,12 @@ func DecodeResponseOrBatch(data []byte) (resps []*Response, isBatch bool, err er
 // - Any element fails to parse as a valid Request
 func DecodeBatchRequest(data []byte) ([]*Request, error) {
 	if len(bytes.TrimSpace(data)) == 0 {
-		return nil, errors.New(errEmptyData)
+		return nil, emptyMessage()
 	}
 
 	// Unmarshal as array of raw messages
 	var rawMessages []json.RawMessage
-	if err := getSonicAPI().Unmarshal(data, &rawMessages); err != nil {
+	if err := getCodec().Unmarshal(data, &rawMessages); err != nil {
 		return nil, fmt.Errorf("invalid batch format: %w", err)
 	}
 
@@ -93,19 +93,11 @@ func DecodeBatchRequest(data []byte) ([]*Request, error) {
 // - Input slice is empty
 // - Any request fails validation
 func EncodeBatchRequest(reqs []*Request) ([]byte, error) {
-	if len(reqs) == 0 {
-		return nil, errors.New("batch request must contain at least one request")
-	}
-
-	// Validate all requests first
-	for i, req := range reqs {
-		if err := req.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid request at index %d: %w", i, err)
-		}
+	data, err := AppendBatchRequest(nil, reqs)
+	if err != nil {
+		return nil, err
 	}
-
-	// Marshal as array
-	return getSonicAPI().Marshal(reqs)
+	return data, nil
 }
 
 // DecodeBatchResponse parses a JSON-RPC batch response from a byte slice.
@@ -115,12 +107,12 @@ func EncodeBatchRequest(reqs []*Request) ([]byte, error) {
 // - Any element fails to parse as a valid Response
 func DecodeBatchResponse(data []byte) ([]*Response, error) {
 	if len(bytes.TrimSpace(data)) == 0 {
-		return nil, errors.New(errEmptyData)
+		return nil, emptyMessage()
 	}
 
 	// Unmarshal as array of raw messages
 	var rawMessages []json.RawMessage
-	if err := getSonicAPI().Unmarshal(data, &rawMessages); err != nil {
+	if err := getCodec().Unmarshal(data, &rawMessages); err != nil {
 		return nil, fmt.Errorf("invalid batch format: %w", err)
 	}
 
@@ -146,19 +138,11 @@ func DecodeBatchResponse(data []byte) ([]*Response, error) {
 // - Input slice is empty
 // - Any response fails validation
 func EncodeBatchResponse(resps []*Response) ([]byte, error) {
-	if len(resps) == 0 {
-		return nil, errors.New("batch response must contain at least one response")
-	}
-
-	// Validate all responses first
-	for i, resp := range resps {
-		if err := resp.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid response at index %d: %w", i, err)
-		}
+	data, err := AppendBatchResponse(nil, resps)
+	if err != nil {
+		return nil, err
 	}
-
-	// Marshal as array
-	return getSonicAPI().Marshal(resps)
+	return data, nil
 }
 
 // DecodeBatchRequestFromReader parses a JSON-RPC batch request from an io.Reader.
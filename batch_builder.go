@@ -0,0 +1,189 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errBatchNotSent is reported by calls of a batch whose responses have not been correlated.
+var errBatchNotSent = errors.New("batch has not been sent")
+
+// Batch accumulates requests to be sent as a single JSON-RPC batch, assigning each call a unique
+// ID, and correlates the responses back to the calls that produced them regardless of order.
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	calls  []*BatchCall
+	reqs   []*Request
+	lastID int64
+	newID  func() any
+}
+
+// BatchCall is a call added to a Batch. After the responses have been correlated it holds either
+// the response to its request or the error explaining why there is none.
+type BatchCall struct {
+	req  *Request
+	resp *Response
+	err  error
+}
+
+// NewBatch creates an empty Batch whose calls are numbered sequentially from 1.
+func NewBatch() *Batch {
+	b := &Batch{}
+	b.newID = func() any {
+		b.lastID++
+		return b.lastID
+	}
+	return b
+}
+
+// NewBatch creates an empty Batch whose call IDs are drawn from the client, so that they never
+// collide with other calls in flight on the same client.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{newID: c.newID}
+}
+
+// Add appends a call to the batch and returns a handle for reading its response.
+func (b *Batch) Add(method string, params any) *BatchCall {
+	call := &BatchCall{req: NewRequestWithID(method, params, b.newID())}
+	b.calls = append(b.calls, call)
+	b.reqs = append(b.reqs, call.req)
+	return call
+}
+
+// AddNotification appends a notification to the batch. Notifications receive no response.
+func (b *Batch) AddNotification(method string, params any) {
+	b.reqs = append(b.reqs, NewNotification(method, params))
+}
+
+// Len returns the number of requests in the batch, including notifications.
+func (b *Batch) Len() int {
+	return len(b.reqs)
+}
+
+// Requests returns the requests in the batch in the order they were added.
+func (b *Batch) Requests() []*Request {
+	return b.reqs
+}
+
+// Calls returns the calls in the batch in the order they were added, excluding notifications.
+func (b *Batch) Calls() []*BatchCall {
+	return b.calls
+}
+
+// MarshalJSON encodes the batch as a JSON array of requests.
+func (b *Batch) MarshalJSON() ([]byte, error) {
+	return EncodeBatchRequest(b.reqs)
+}
+
+// Correlate matches resps to the calls in the batch by ID. Calls without a matching response get
+// an error, available from BatchCall.Err. If the server rejected the whole batch with a single
+// error response carrying a null ID, that error is set on every call and returned.
+func (b *Batch) Correlate(resps []*Response) error {
+	if len(resps) == 1 && resps[0].IDOrNil() == nil {
+		if rpcErr := resps[0].Err(); rpcErr != nil {
+			b.fail(rpcErr)
+			return rpcErr
+		}
+	}
+
+	byID := make(map[string]*Response, len(resps))
+	for _, resp := range resps {
+		byID[idKey(resp.IDOrNil())] = resp
+	}
+	for _, call := range b.calls {
+		resp, ok := byID[idKey(call.req.ID)]
+		if !ok {
+			call.resp, call.err = nil, fmt.Errorf("missing response for request id %v", call.req.ID)
+			continue
+		}
+		call.resp, call.err = resp, nil
+	}
+	return nil
+}
+
+// fail sets err on every call in the batch.
+func (b *Batch) fail(err error) {
+	for _, call := range b.calls {
+		call.resp, call.err = nil, err
+	}
+}
+
+// Request returns the request sent for the call.
+func (c *BatchCall) Request() *Request {
+	return c.req
+}
+
+// Response returns the response correlated to the call, or nil if there is none.
+func (c *BatchCall) Response() *Response {
+	return c.resp
+}
+
+// Err returns the error for the call: the JSON-RPC error returned by the server as an *Error, an
+// error describing why no response was correlated, or nil on success.
+func (c *BatchCall) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.resp == nil {
+		return errBatchNotSent
+	}
+	// Avoid returning a typed nil *Error as a non-nil error
+	rpcErr := c.resp.Err()
+	if rpcErr == nil {
+		return nil
+	}
+	return rpcErr
+}
+
+// UnmarshalResult unmarshals the call's result into dst, returning the call's error instead if it
+// has one.
+func (c *BatchCall) UnmarshalResult(dst any) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return c.resp.UnmarshalResult(dst)
+}
+
+// SendBatch sends the batch and correlates the responses to its calls. The outcome of each call is
+// reported by BatchCall.Err; SendBatch itself only fails when the batch could not be exchanged or
+// was rejected as a whole, in which case every call reports the same error.
+func (c *Client) SendBatch(ctx context.Context, b *Batch) error {
+	if err := c.limits.checkBatch(b.Len()); err != nil {
+		b.fail(err)
+		return err
+	}
+	if err := c.throttleBatch(ctx, b.Requests()); err != nil {
+		b.fail(err)
+		return err
+	}
+	payload, err := EncodeBatchRequest(c.withEmptyParams(b.reqs))
+	if err != nil {
+		return err
+	}
+	if c.observer != nil {
+		c.observer.BatchStarted(ctx, b.Len())
+	}
+
+	keys := make([]string, len(b.calls))
+	for i, call := range b.calls {
+		keys[i] = idKey(call.req.ID)
+	}
+
+	replies, err := c.exchange(ctx, payload, keys)
+	if err != nil {
+		ids := make([]any, len(b.calls))
+		for i, call := range b.calls {
+			ids[i] = call.req.ID
+		}
+		c.propagateCancel(ctx, err, ids...)
+		b.fail(err)
+		return err
+	}
+
+	resps := make([]*Response, 0, len(replies))
+	for _, resp := range replies {
+		resps = append(resps, resp)
+	}
+	return b.Correlate(resps)
+}
@@ -0,0 +1,139 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	t.Run("Assigns sequential IDs", func(t *testing.T) {
+		b := NewBatch()
+		first := b.Add("a", nil)
+		b.AddNotification("log", nil)
+		second := b.Add("b", []any{1})
+
+		assert.Equal(t, int64(1), first.Request().ID)
+		assert.Equal(t, int64(2), second.Request().ID)
+		assert.Equal(t, 3, b.Len())
+		assert.Len(t, b.Calls(), 2)
+		assert.True(t, b.Requests()[1].IsNotification())
+	})
+
+	t.Run("Marshals as an array", func(t *testing.T) {
+		b := NewBatch()
+		b.Add("a", []any{1})
+		b.AddNotification("log", nil)
+
+		data, err := b.MarshalJSON()
+		require.NoError(t, err)
+		assert.JSONEq(t,
+			`[{"jsonrpc":"2.0","id":1,"method":"a","params":[1]},{"jsonrpc":"2.0","method":"log"}]`,
+			string(data))
+	})
+
+	t.Run("Empty batch fails to marshal", func(t *testing.T) {
+		_, err := NewBatch().MarshalJSON()
+		require.Error(t, err)
+	})
+
+	t.Run("Correlates out of order with per-call errors", func(t *testing.T) {
+		b := NewBatch()
+		sum := b.Add("sum", []any{1, 2})
+		fail := b.Add("fail", nil)
+		lost := b.Add("lost", nil)
+
+		data := `[{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found"}},
+			{"jsonrpc":"2.0","id":1,"result":3}]`
+		resps, err := DecodeBatchResponse([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, b.Correlate(resps))
+
+		var got int
+		require.NoError(t, sum.UnmarshalResult(&got))
+		assert.Equal(t, 3, got)
+		assert.NotNil(t, sum.Response())
+
+		var rpcErr *Error
+		require.ErrorAs(t, fail.Err(), &rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+
+		require.Error(t, lost.Err())
+		assert.Nil(t, lost.Response())
+		assert.ErrorContains(t, lost.UnmarshalResult(&got), "missing response")
+	})
+
+	t.Run("Whole batch rejected", func(t *testing.T) {
+		b := NewBatch()
+		a := b.Add("a", nil)
+		c := b.Add("c", nil)
+
+		resp := NewErrorResponse(nil, &Error{Code: InvalidRequest, Message: "Invalid Request"})
+		err := b.Correlate([]*Response{resp})
+		require.Error(t, err)
+		assert.Equal(t, err, a.Err())
+		assert.Equal(t, err, c.Err())
+	})
+
+	t.Run("Unsent call", func(t *testing.T) {
+		call := NewBatch().Add("a", nil)
+		require.ErrorIs(t, call.Err(), errBatchNotSent)
+	})
+}
+
+func TestClient_SendBatch(t *testing.T) {
+	t.Run("Transport", func(t *testing.T) {
+		srv := newTestServer(t)
+		transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, payload), nil
+		}}
+		client := NewClient(transport)
+
+		b := client.NewBatch()
+		sum := b.Add("sum", []any{1, 2, 4})
+		missing := b.Add("missing", nil)
+		b.AddNotification("notify_hello", []any{7})
+		require.NoError(t, client.SendBatch(context.Background(), b))
+
+		var got int
+		require.NoError(t, sum.UnmarshalResult(&got))
+		assert.Equal(t, 7, got)
+		var rpcErr *Error
+		require.ErrorAs(t, missing.Err(), &rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+	})
+
+	t.Run("Stream IDs do not collide with calls", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		echoResponder(t, serverEnd)
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		require.NoError(t, client.Call(context.Background(), "echo", []any{"x"}, nil))
+
+		b := client.NewBatch()
+		first := b.Add("echo", []any{"a"})
+		second := b.Add("echo", []any{"b"})
+		assert.NotEqual(t, int64(1), first.Request().ID)
+		require.NoError(t, client.SendBatch(context.Background(), b))
+
+		var got []string
+		require.NoError(t, first.UnmarshalResult(&got))
+		assert.Equal(t, []string{"a"}, got)
+		require.NoError(t, second.UnmarshalResult(&got))
+		assert.Equal(t, []string{"b"}, got)
+	})
+
+	t.Run("Exchange failure is set on every call", func(t *testing.T) {
+		client := NewClient(&funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return nil, assert.AnError
+		}})
+
+		b := client.NewBatch()
+		call := b.Add("a", nil)
+		require.ErrorIs(t, client.SendBatch(context.Background(), b), assert.AnError)
+		require.ErrorIs(t, call.Err(), assert.AnError)
+	})
+}
@@ -0,0 +1,81 @@
+package jsonrpc
+
+// BatchAborted is the error code of responses to batch members skipped under BatchAbort, within
+// the range reserved for implementation-defined server errors.
+const BatchAborted = -32018
+
+// msgBatchAborted is the message of BatchAborted errors.
+const msgBatchAborted = "Batch aborted"
+
+// ErrBatchAborted is the error sent for batch members skipped after an earlier member failed.
+var ErrBatchAborted = &Error{Code: BatchAborted, Message: msgBatchAborted}
+
+// BatchExecution selects how the members of a batch are dispatched.
+type BatchExecution int
+
+const (
+	// BatchParallel dispatches the members concurrently, bounded by the concurrency limit.
+	BatchParallel BatchExecution = iota
+
+	// BatchSequential dispatches the members one after another, in the order of the batch, for
+	// batches whose members depend on the effects of earlier ones.
+	BatchSequential
+)
+
+// BatchFailure selects what happens to the members of a batch once one of them failed.
+type BatchFailure int
+
+const (
+	// BatchContinue dispatches every member regardless of the failure of others, as the JSON-RPC
+	// 2.0 specification has it.
+	BatchContinue BatchFailure = iota
+
+	// BatchAbort answers the members not yet dispatched once a member was answered with an error
+	// with ErrBatchAborted, skipping their handlers. Members already executing run to completion,
+	// so under BatchParallel which members are skipped depends on timing; with BatchSequential,
+	// exactly the members after the first failure are.
+	BatchAbort
+)
+
+// BatchPolicy configures the execution of batch members by a server.
+type BatchPolicy struct {
+	// Execution selects whether members are dispatched concurrently or in order.
+	Execution BatchExecution
+
+	// OnError selects whether the failure of a member skips the remaining ones.
+	OnError BatchFailure
+}
+
+// WithBatchPolicy sets how the server executes the members of batches. The default policy
+// dispatches them concurrently and independently of each other's failures.
+func WithBatchPolicy(policy BatchPolicy) ServerOption {
+	return func(s *Server) {
+		s.batchPolicy = policy
+	}
+}
+
+// SplitResponses splits the responses to a batch into those carrying a result and those carrying
+// an error, keeping their order.
+func SplitResponses(resps []*Response) (succeeded, failed []*Response) {
+	for _, resp := range resps {
+		if resp.Err() != nil {
+			failed = append(failed, resp)
+			continue
+		}
+		succeeded = append(succeeded, resp)
+	}
+	return succeeded, failed
+}
+
+// Split splits the calls of a sent batch into those that succeeded and those whose Err is not
+// nil, keeping their order.
+func (b *Batch) Split() (succeeded, failed []*BatchCall) {
+	for _, call := range b.calls {
+		if call.Err() != nil {
+			failed = append(failed, call)
+			continue
+		}
+		succeeded = append(succeeded, call)
+	}
+	return succeeded, failed
+}
@@ -0,0 +1,117 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const policyBatch = `[
+	{"jsonrpc":"2.0","method":"step","params":[1],"id":1},
+	{"jsonrpc":"2.0","method":"fail","id":2},
+	{"jsonrpc":"2.0","method":"step","params":[3]},
+	{"jsonrpc":"2.0","method":"step","params":[4],"id":4}
+]`
+
+// newPolicyServer returns a server with the given batch policy whose step method records its
+// params in the order of execution.
+func newPolicyServer(t *testing.T, policy BatchPolicy) (*Server, *[]int) {
+	t.Helper()
+	var mu sync.Mutex
+	var steps []int
+	srv := NewServer(WithBatchPolicy(policy))
+	step := func(_ context.Context, req *Request) (any, error) {
+		var params []int
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, params[0])
+		return params[0], nil
+	}
+	require.NoError(t, srv.RegisterFunc("step", step))
+	require.NoError(t, srv.RegisterFunc("fail", func(context.Context, *Request) (any, error) {
+		return nil, errors.New("failed")
+	}))
+	return srv, &steps
+}
+
+func TestServer_BatchPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Continues on error by default", func(t *testing.T) {
+		srv, steps := newPolicyServer(t, BatchPolicy{})
+		resps, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(policyBatch)))
+		require.NoError(t, err)
+		require.Len(t, resps, 3)
+		assert.ElementsMatch(t, []int{1, 3, 4}, *steps)
+		assert.Nil(t, resps[2].Err())
+	})
+
+	t.Run("Sequential execution keeps batch order", func(t *testing.T) {
+		var running, overlaps atomic.Int32
+		srv, steps := newPolicyServer(t, BatchPolicy{Execution: BatchSequential})
+		srv.Use(func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+				if running.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				defer running.Add(-1)
+				return next.ServeRPC(ctx, req)
+			})
+		})
+		_, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(policyBatch)))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 3, 4}, *steps)
+		assert.Zero(t, overlaps.Load())
+	})
+
+	t.Run("Aborts the remaining members", func(t *testing.T) {
+		policy := BatchPolicy{Execution: BatchSequential, OnError: BatchAbort}
+		srv, steps := newPolicyServer(t, policy)
+		resps, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(policyBatch)))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1}, *steps)
+
+		require.Len(t, resps, 3, "skipped notifications are not answered")
+		assert.Nil(t, resps[0].Err())
+		assert.Equal(t, ServerSideException, resps[1].Err().Code)
+		assert.EqualValues(t, 4, resps[2].IDOrNil())
+		assert.ErrorIs(t, resps[2].Err(), ErrBatchAborted)
+	})
+}
+
+func TestSplitResponses(t *testing.T) {
+	ok, err := NewResponse(int64(1), 1)
+	require.NoError(t, err)
+	failed := NewErrorResponse(int64(2), ErrInternal)
+
+	succeeded, failures := SplitResponses([]*Response{failed, ok})
+	assert.Equal(t, []*Response{ok}, succeeded)
+	assert.Equal(t, []*Response{failed}, failures)
+}
+
+func TestBatch_Split(t *testing.T) {
+	srv := newTestServer(t)
+	roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}
+	client := NewClient(&funcTransport{fn: roundTrip})
+	defer client.Close()
+
+	b := client.NewBatch()
+	sum := b.Add("sum", []int{1, 2})
+	missing := b.Add("missing", nil)
+	b.AddNotification("noop", nil)
+	require.NoError(t, client.SendBatch(context.Background(), b))
+
+	succeeded, failed := b.Split()
+	assert.Equal(t, []*BatchCall{sum}, succeeded)
+	assert.Equal(t, []*BatchCall{missing}, failed)
+}
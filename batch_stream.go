@@ -0,0 +1,284 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BatchDecoder decodes the members of a batch one at a time from a reader, so that a huge batch
+// never has to be held in memory as a whole: only the member being decoded is buffered. A
+// message holding a single request or response rather than a batch is read as a batch of one.
+//
+// The decoder checks the framing of the array as it goes, so a malformed batch is only detected
+// when its malformed part is reached, after the members before it have been returned.
+type BatchDecoder struct {
+	r     *bufio.Reader
+	index int
+	state batchState
+	err   error
+}
+
+// batchState tracks the position of a BatchDecoder in its input.
+type batchState int
+
+const (
+	// batchStart is the state before the first byte of the message.
+	batchStart batchState = iota
+
+	// batchFirst is the state inside the array, before its first member.
+	batchFirst
+
+	// batchNext is the state after a member of the array.
+	batchNext
+
+	// batchSingle is the state before a message that is not an array.
+	batchSingle
+
+	// batchDone is the state once the message has been read.
+	batchDone
+)
+
+// NewBatchDecoder creates a BatchDecoder reading from r.
+func NewBatchDecoder(r io.Reader) *BatchDecoder {
+	return &BatchDecoder{r: bufio.NewReaderSize(r, defaultChunkSize)}
+}
+
+// NextRaw returns the next member of the batch, undecoded. It returns io.EOF once all members have
+// been read, and keeps returning the first other error encountered.
+func (d *BatchDecoder) NextRaw() (json.RawMessage, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	raw, err := d.next()
+	if err != nil {
+		d.err = err
+		return nil, err
+	}
+	d.index++
+	return raw, nil
+}
+
+// NextResponse returns the next member of the batch as a response, or io.EOF once all members
+// have been read.
+func (d *BatchDecoder) NextResponse() (*Response, error) {
+	raw, err := d.NextRaw()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := DecodeResponse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response at index %d: %w", d.index-1, err)
+	}
+	return resp, nil
+}
+
+// NextRequest returns the next member of the batch as a request, or io.EOF once all members have
+// been read.
+func (d *BatchDecoder) NextRequest() (*Request, error) {
+	raw, err := d.NextRaw()
+	if err != nil {
+		return nil, err
+	}
+	req, err := DecodeRequest(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request at index %d: %w", d.index-1, err)
+	}
+	return req, nil
+}
+
+// next reads the next member according to the state of the decoder.
+func (d *BatchDecoder) next() (json.RawMessage, error) {
+	switch d.state {
+	case batchStart:
+		c, err := d.skipSpace()
+		if errors.Is(err, io.EOF) {
+			return nil, emptyMessage()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c != '[' {
+			_ = d.r.UnreadByte()
+			d.state = batchSingle
+			return d.next()
+		}
+		d.state = batchFirst
+		return d.next()
+	case batchSingle:
+		d.state = batchDone
+		return d.readValue()
+	case batchFirst:
+		c, err := d.skipSpace()
+		if err != nil {
+			return nil, d.unexpected(err)
+		}
+		if c == ']' {
+			return nil, errors.New("batch must contain at least one member")
+		}
+		_ = d.r.UnreadByte()
+		d.state = batchNext
+		return d.readValue()
+	case batchNext:
+		c, err := d.skipSpace()
+		if err != nil {
+			return nil, d.unexpected(err)
+		}
+		switch c {
+		case ',':
+			return d.readValue()
+		case ']':
+			d.state = batchDone
+			return nil, io.EOF
+		default:
+			return nil, fmt.Errorf("invalid batch format: unexpected %q after member %d", c,
+				d.index-1)
+		}
+	default:
+		return nil, io.EOF
+	}
+}
+
+// readValue reads one JSON value.
+func (d *BatchDecoder) readValue() (json.RawMessage, error) {
+	if _, err := d.skipSpace(); err != nil {
+		return nil, d.unexpected(err)
+	}
+	_ = d.r.UnreadByte()
+
+	var value []byte
+	var scan valueScanner
+	for {
+		chunk, err := d.r.Peek(max(d.r.Buffered(), 1))
+		if len(chunk) == 0 {
+			if len(value) > 0 && scan.scalar() && errors.Is(err, io.EOF) {
+				// A scalar ending the input
+				return value, nil
+			}
+			return nil, d.unexpected(err)
+		}
+
+		if end := scan.scan(chunk); end >= 0 {
+			if end == 0 && len(value) == 0 {
+				return nil, errors.New("invalid batch format: missing member")
+			}
+			value = append(value, chunk[:end]...)
+			_, _ = d.r.Discard(end)
+			return value, nil
+		}
+		value = append(value, chunk...)
+		_, _ = d.r.Discard(len(chunk))
+	}
+}
+
+// valueScanner finds where a JSON value ends, tracking nesting and strings. Values that are not
+// objects, arrays, or strings end at the next delimiter, which is not part of the value.
+type valueScanner struct {
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// scan consumes chunk, returning the length of the part of it that completes the value, or -1 if
+// the value goes on past it.
+func (s *valueScanner) scan(chunk []byte) int {
+	for i, c := range chunk {
+		if s.inString {
+			closed := s.scanString(c)
+			if closed && s.depth == 0 {
+				return i + 1
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			if s.depth == 0 {
+				// The end of the enclosing batch, after a scalar
+				return i
+			}
+			s.depth--
+			if s.depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\n', '\r':
+			if s.depth == 0 {
+				return i
+			}
+		default:
+		}
+	}
+	return -1
+}
+
+// scanString consumes a byte of a string, reporting whether it closed the string.
+func (s *valueScanner) scanString(c byte) bool {
+	switch {
+	case s.escaped:
+		s.escaped = false
+	case c == '\\':
+		s.escaped = true
+	case c == '"':
+		s.inString = false
+		return true
+	default:
+	}
+	return false
+}
+
+// scalar reports whether the value scanned so far is outside any string, object, or array.
+func (s *valueScanner) scalar() bool {
+	return s.depth == 0 && !s.inString
+}
+
+// skipSpace reads past whitespace and returns the first other byte.
+func (d *BatchDecoder) skipSpace() (byte, error) {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !isJSONSpace(c) {
+			return c, nil
+		}
+	}
+}
+
+// unexpected turns the end of the input in the middle of a batch into an error.
+func (*BatchDecoder) unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid batch format: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// isJSONSpace reports whether c is JSON whitespace.
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// DecodeBatchResponseStream decodes the responses of a batch from r one at a time, calling fn
+// with each in order, and stops at the first error returned by fn. See BatchDecoder.
+func DecodeBatchResponseStream(r io.Reader, fn func(*Response) error) error {
+	if r == nil {
+		return errors.New("cannot read from nil reader")
+	}
+	dec := NewBatchDecoder(r)
+	for {
+		resp, err := dec.NextResponse()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(resp); err != nil {
+			return err
+		}
+	}
+}
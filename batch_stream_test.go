@@ -0,0 +1,161 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generatedBatch is a reader producing a batch of n responses on the fly, so that the whole
+// batch never exists in memory.
+type generatedBatch struct {
+	n, next int
+	pending []byte
+}
+
+func (g *generatedBatch) Read(p []byte) (int, error) {
+	for len(g.pending) == 0 {
+		switch {
+		case g.next == 0:
+			g.pending = []byte("[")
+		case g.next > g.n:
+			return 0, io.EOF
+		default:
+		}
+		if g.next > 0 {
+			sep := ","
+			if g.next == g.n {
+				sep = "]"
+			}
+			g.pending = fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"result":{"s":"a\"]}"}}%s`,
+				g.next, sep)
+		}
+		g.next++
+	}
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	return n, nil
+}
+
+func TestBatchDecoder(t *testing.T) {
+	batch := ` [ {"jsonrpc":"2.0","id":1,"result":[1,{"a":"]"}]},` +
+		"\n\t" + `{"jsonrpc":"2.0","id":"x","error":{"code":-32601,"message":"m \"q\""}} ] `
+
+	t.Run("Responses", func(t *testing.T) {
+		for name, r := range map[string]io.Reader{
+			"Whole":    strings.NewReader(batch),
+			"Bytewise": iotest.OneByteReader(strings.NewReader(batch)),
+		} {
+			t.Run(name, func(t *testing.T) {
+				dec := NewBatchDecoder(r)
+				first, err := dec.NextResponse()
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), first.IDOrNil())
+				assert.JSONEq(t, `[1,{"a":"]"}]`, string(first.RawResult()))
+
+				second, err := dec.NextResponse()
+				require.NoError(t, err)
+				assert.Equal(t, "x", second.IDOrNil())
+				assert.Equal(t, `m "q"`, second.Err().Message)
+
+				_, err = dec.NextResponse()
+				require.ErrorIs(t, err, io.EOF)
+				_, err = dec.NextResponse()
+				require.ErrorIs(t, err, io.EOF)
+			})
+		}
+	})
+
+	t.Run("Requests", func(t *testing.T) {
+		dec := NewBatchDecoder(strings.NewReader(
+			`[{"jsonrpc":"2.0","method":"a","id":1},{"jsonrpc":"2.0","method":"b"}]`))
+		var methods []string
+		for {
+			req, err := dec.NextRequest()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			methods = append(methods, req.Method)
+		}
+		assert.Equal(t, []string{"a", "b"}, methods)
+	})
+
+	t.Run("Single message", func(t *testing.T) {
+		dec := NewBatchDecoder(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":true}`))
+		resp, err := dec.NextResponse()
+		require.NoError(t, err)
+		assert.Equal(t, "true", string(resp.RawResult()))
+		_, err = dec.NextResponse()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("Scalar members", func(t *testing.T) {
+		dec := NewBatchDecoder(strings.NewReader(`[1, "two" ,null]`))
+		var raws []string
+		for {
+			raw, err := dec.NextRaw()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			raws = append(raws, string(raw))
+		}
+		assert.Equal(t, []string{"1", `"two"`, "null"}, raws)
+	})
+
+	t.Run("Malformed input", func(t *testing.T) {
+		for _, input := range []string{
+			``,
+			`   `,
+			`[]`,
+			`[{"jsonrpc":"2.0","id":1,"result":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":1} {"jsonrpc":"2.0","id":2,"result":2}]`,
+			`[{"jsonrpc":"2.0","id":1,"result":"unterminated`,
+			`[{"jsonrpc":"2.0","id":1,"result":1},]`,
+			`[,]`,
+		} {
+			dec := NewBatchDecoder(strings.NewReader(input))
+			var err error
+			for err == nil {
+				_, err = dec.NextResponse()
+			}
+			assert.False(t, errors.Is(err, io.EOF), "input %q", input)
+		}
+	})
+
+	t.Run("Invalid members", func(t *testing.T) {
+		dec := NewBatchDecoder(strings.NewReader(`[{"jsonrpc":"2.0","id":1,"result":1},{"a":1}]`))
+		_, err := dec.NextResponse()
+		require.NoError(t, err)
+		_, err = dec.NextResponse()
+		require.ErrorContains(t, err, "index 1")
+	})
+}
+
+func TestDecodeBatchResponseStream(t *testing.T) {
+	const n = 10000
+
+	count := 0
+	err := DecodeBatchResponseStream(&generatedBatch{n: n}, func(resp *Response) error {
+		count++
+		assert.Equal(t, int64(count), resp.IDOrNil())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, n, count)
+
+	errStop := errors.New("stop")
+	err = DecodeBatchResponseStream(&generatedBatch{n: n}, func(*Response) error {
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+
+	require.Error(t, DecodeBatchResponseStream(nil, nil))
+}
@@ -0,0 +1,274 @@
+// Package bench load-tests JSON-RPC endpoints: it fires a weighted mix of calls, optionally in
+// batches, from concurrent workers through a jsonrpc.Client and reports latency percentiles,
+// throughput, and a breakdown of errors. It backs the jsonrpc-bench command.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+var (
+	// errNoCalls is returned for a Config without calls.
+	errNoCalls = errors.New("bench: no calls to make")
+
+	// errNoMethod is returned for a Call without a method.
+	errNoMethod = errors.New("bench: call without a method")
+
+	// errUnbounded is returned for a Config bounded neither by requests nor by duration.
+	errUnbounded = errors.New("bench: either Requests or Duration must be set")
+)
+
+// Call is an entry of the mix of calls of a run.
+type Call struct {
+	// Method is the method called.
+	Method string `json:"method"`
+
+	// Params is a text/template producing the JSON params of each call, or empty for none. The
+	// template is executed with the fields Seq, the sequence number of the call in the run, and
+	// Worker, the index of the worker making it, and the method RandInt, returning an integer in
+	// [min, max). The function hex formats an integer as a 0x-prefixed hexadecimal string:
+	//
+	//	["{{hex .Seq}}", {{.RandInt 0 100}}]
+	Params string `json:"params,omitempty"`
+
+	// Weight is the share of the calls of the run made to this entry, relative to the weights of
+	// the other entries. Defaults to 1.
+	Weight int `json:"weight,omitempty"`
+}
+
+// Config configures a run.
+type Config struct {
+	// Calls is the mix of calls made, picked at random by weight.
+	Calls []Call
+
+	// Concurrency is the number of workers making calls in parallel. Defaults to 1.
+	Concurrency int
+
+	// Requests is the number of calls after which the run stops, counting each call of a batch.
+	Requests int
+
+	// Duration is how long the run lasts, if not stopped by Requests first.
+	Duration time.Duration
+
+	// BatchSize, above 1, sends the calls in batches of that many.
+	BatchSize int
+
+	// Rate, if positive, caps the calls, or batches, sent per second across all workers.
+	Rate float64
+
+	// Timeout bounds each call or batch. Calls exceeding it count as "timeout" errors.
+	Timeout time.Duration
+}
+
+// Run makes the calls of cfg through client until Requests calls were made, Duration elapsed,
+// or ctx is done, and reports the outcome. Calls interrupted by the end of the run are not
+// counted. Run fails only for an invalid configuration.
+func Run(ctx context.Context, client *jsonrpc.Client, cfg Config) (*Report, error) {
+	mix, err := newMix(cfg.Calls)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Requests <= 0 && cfg.Duration <= 0 {
+		return nil, errUnbounded
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	if cfg.Duration > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+	}
+	defer cancel()
+
+	r := &runner{client: client, cfg: cfg, mix: mix, ctx: runCtx}
+	if cfg.Rate > 0 {
+		r.pace = newPacer(runCtx, cfg.Rate)
+	}
+	workers := max(cfg.Concurrency, 1)
+	recorders := make([]*recorder, workers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range recorders {
+		recorders[i] = newRecorder()
+		wg.Go(func() { r.work(i, recorders[i]) })
+	}
+	wg.Wait()
+	return newReport(time.Since(start), recorders), nil
+}
+
+// runner holds the state shared by the workers of a run.
+type runner struct {
+	client *jsonrpc.Client
+	cfg    Config
+	mix    *mix
+	ctx    context.Context
+	pace   <-chan struct{}
+	seq    atomic.Int64
+}
+
+// work makes calls until the run ends, recording them in rec.
+func (r *runner) work(worker int, rec *recorder) {
+	rng := rand.New(rand.NewPCG(rand.Uint64(), uint64(worker)))
+	size := max(r.cfg.BatchSize, 1)
+	for {
+		first, ok := r.claim(size)
+		if !ok {
+			return
+		}
+		if r.pace != nil {
+			select {
+			case <-r.pace:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+		calls := make([]pendingCall, 0, size)
+		for seq := first; seq < first+int64(size); seq++ {
+			if r.cfg.Requests > 0 && seq >= int64(r.cfg.Requests) {
+				break
+			}
+			calls = append(calls, r.mix.pick(rng).prepare(seq, worker, rng))
+		}
+		r.send(calls, rec)
+	}
+}
+
+// claim reserves the sequence numbers of the next size calls, or returns false once the run is
+// over.
+func (r *runner) claim(size int) (int64, bool) {
+	if r.ctx.Err() != nil {
+		return 0, false
+	}
+	first := r.seq.Add(int64(size)) - int64(size)
+	if r.cfg.Requests > 0 && first >= int64(r.cfg.Requests) {
+		return 0, false
+	}
+	return first, true
+}
+
+// send makes calls, as a batch if there are several, and records their outcomes.
+func (r *runner) send(calls []pendingCall, rec *recorder) {
+	ctx, cancel := r.ctx, context.CancelFunc(func() {})
+	if r.cfg.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.ctx, r.cfg.Timeout)
+	}
+	defer cancel()
+
+	start := time.Now()
+	if len(calls) == 1 && r.cfg.BatchSize <= 1 {
+		call := calls[0]
+		err := call.err
+		if err == nil {
+			err = r.client.Call(ctx, call.method, call.params, nil)
+		}
+		r.record(rec, call.method, time.Since(start), err)
+		return
+	}
+
+	reqs := make([]*jsonrpc.Request, 0, len(calls))
+	for _, call := range calls {
+		if call.err != nil {
+			r.record(rec, call.method, 0, call.err)
+			continue
+		}
+		reqs = append(reqs, jsonrpc.NewRequest(call.method, call.params))
+	}
+	if len(reqs) == 0 {
+		return
+	}
+	resps, err := r.client.CallBatch(ctx, reqs)
+	latency := time.Since(start)
+	for i, req := range reqs {
+		callErr := err
+		if rpcErr := resps[i].Err(); callErr == nil && rpcErr != nil {
+			callErr = rpcErr
+		}
+		r.record(rec, req.Method, latency, callErr)
+	}
+}
+
+// record records a call unless it was interrupted by the end of the run.
+func (r *runner) record(rec *recorder, method string, latency time.Duration, err error) {
+	if err != nil && r.ctx.Err() != nil {
+		return
+	}
+	rec.record(method, latency, err)
+}
+
+// pendingCall is a call of the mix with its params rendered.
+type pendingCall struct {
+	method string
+	params any
+	err    error
+}
+
+// mix picks calls at random by weight.
+type mix struct {
+	calls []*templateCall
+	total int
+}
+
+// newMix parses the templates of calls.
+func newMix(calls []Call) (*mix, error) {
+	if len(calls) == 0 {
+		return nil, errNoCalls
+	}
+	m := &mix{}
+	for _, call := range calls {
+		parsed, err := parseCall(call)
+		if err != nil {
+			return nil, err
+		}
+		m.calls = append(m.calls, parsed)
+		m.total += parsed.weight
+	}
+	return m, nil
+}
+
+// pick returns a call at random by weight.
+func (m *mix) pick(rng *rand.Rand) *templateCall {
+	n := rng.IntN(m.total)
+	for _, call := range m.calls {
+		if n < call.weight {
+			return call
+		}
+		n -= call.weight
+	}
+	return m.calls[len(m.calls)-1]
+}
+
+// newPacer returns a channel receiving a value at rate per second until ctx is done.
+func newPacer(ctx context.Context, rate float64) <-chan struct{} {
+	ticks := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case ticks <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ticks
+}
+
+// rawParams returns rendered params as raw JSON, or nil for none.
+func rawParams(rendered []byte) any {
+	if len(rendered) == 0 {
+		return nil
+	}
+	return json.RawMessage(rendered)
+}
@@ -0,0 +1,149 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer returns a server answering "ok", failing "fail" with code -32005, and counting
+// the blocks asked of "block".
+func newTestServer(t *testing.T, blocks *atomic.Int64) *httptest.Server {
+	t.Helper()
+	srv := jsonrpc.NewServer()
+	require.NoError(t, srv.RegisterFunc("ok", func(context.Context, *jsonrpc.Request) (any, error) {
+		return true, nil
+	}))
+	require.NoError(t, srv.RegisterFunc("fail", func(
+		context.Context,
+		*jsonrpc.Request,
+	) (any, error) {
+		return nil, &jsonrpc.Error{Code: -32005, Message: "rate limited"}
+	}))
+	require.NoError(t, srv.RegisterFunc("block", func(
+		_ context.Context,
+		req *jsonrpc.Request,
+	) (any, error) {
+		var params []string
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		blocks.Add(1)
+		return params[0], nil
+	}))
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	var blocks atomic.Int64
+	server := newTestServer(t, &blocks)
+	client := jsonrpc.NewClient(jsonrpc.NewHTTPTransport(server.URL))
+
+	t.Run("Stops after the requests", func(t *testing.T) {
+		report, err := Run(ctx, client, Config{
+			Calls:       []Call{{Method: "ok", Weight: 3}, {Method: "fail"}},
+			Concurrency: 4,
+			Requests:    200,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 200, report.Calls)
+		ok, fail := report.Methods["ok"], report.Methods["fail"]
+		assert.Equal(t, 200, ok.Calls+fail.Calls)
+		assert.Greater(t, ok.Calls, fail.Calls, "calls are picked by weight")
+		assert.Zero(t, ok.Errors)
+		assert.Equal(t, fail.Calls, fail.Errors)
+		assert.Equal(t, fail.Calls, report.Errors)
+		assert.Equal(t, map[string]int{"code -32005": fail.Calls}, report.ErrorKinds)
+		assert.LessOrEqual(t, report.Latency.Min, report.Latency.P50)
+		assert.LessOrEqual(t, report.Latency.P50, report.Latency.P99)
+		assert.LessOrEqual(t, report.Latency.P99, report.Latency.Max)
+		assert.Positive(t, report.Throughput())
+	})
+
+	t.Run("Params templates", func(t *testing.T) {
+		blocks.Store(0)
+		report, err := Run(ctx, client, Config{
+			Calls:    []Call{{Method: "block", Params: `["{{hex .Seq}}"]`}},
+			Requests: 20,
+		})
+		require.NoError(t, err)
+		assert.Zero(t, report.Errors)
+		assert.Equal(t, int64(20), blocks.Load())
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		blocks.Store(0)
+		report, err := Run(ctx, client, Config{
+			Calls:       []Call{{Method: "block", Params: `["{{.Worker}}"]`}},
+			Concurrency: 2,
+			Requests:    25,
+			BatchSize:   10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 25, report.Calls, "the last batch is partial")
+		assert.Zero(t, report.Errors)
+		assert.Equal(t, int64(25), blocks.Load())
+	})
+
+	t.Run("Stops after the duration", func(t *testing.T) {
+		report, err := Run(ctx, client, Config{
+			Calls:    []Call{{Method: "ok"}},
+			Duration: 100 * time.Millisecond,
+			Rate:     100,
+		})
+		require.NoError(t, err)
+		assert.Positive(t, report.Calls)
+		assert.LessOrEqual(t, report.Calls, 15, "calls are paced")
+		assert.Zero(t, report.Errors, "calls interrupted by the end are not counted")
+	})
+
+	t.Run("Timeouts", func(t *testing.T) {
+		slow := jsonrpc.NewClient(&slowTransport{delay: 50 * time.Millisecond})
+		report, err := Run(ctx, slow, Config{
+			Calls:    []Call{{Method: "ok"}},
+			Requests: 2,
+			Timeout:  time.Millisecond,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"timeout": 2}, report.ErrorKinds)
+	})
+
+	t.Run("Invalid configurations", func(t *testing.T) {
+		_, err := Run(ctx, client, Config{Requests: 1})
+		require.ErrorIs(t, err, errNoCalls)
+		_, err = Run(ctx, client, Config{Calls: []Call{{Method: "ok"}}})
+		require.ErrorIs(t, err, errUnbounded)
+		_, err = Run(ctx, client, Config{Calls: []Call{{}}, Requests: 1})
+		require.ErrorIs(t, err, errNoMethod)
+		_, err = Run(ctx, client, Config{Calls: []Call{{Method: "ok", Params: "{{"}}, Requests: 1})
+		assert.Error(t, err)
+	})
+}
+
+// slowTransport answers nothing before its delay, or the end of the context.
+type slowTransport struct {
+	delay time.Duration
+}
+
+func (t *slowTransport) RoundTrip(ctx context.Context, _ []byte) ([]byte, error) {
+	select {
+	case <-time.After(t.delay):
+		return nil, errors.New("too late")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (*slowTransport) Close() error {
+	return nil
+}
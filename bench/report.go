@@ -0,0 +1,221 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Percentiles of the latency summaries.
+const (
+	p50 = 0.50
+	p90 = 0.90
+	p99 = 0.99
+)
+
+// tabPadding is the padding between the columns of a written report.
+const tabPadding = 2
+
+// Report is the outcome of a run.
+type Report struct {
+	// Calls is the number of calls made, and Errors the number of them that failed.
+	Calls  int
+	Errors int
+
+	// Elapsed is the duration of the run.
+	Elapsed time.Duration
+
+	// Latency summarizes the latencies of all calls. Calls of a batch share the latency of the
+	// batch.
+	Latency Latency
+
+	// Methods holds the statistics of the calls to each method.
+	Methods map[string]MethodReport
+
+	// ErrorKinds counts the failed calls by kind: "code N" for JSON-RPC errors with code N,
+	// "timeout" for calls exceeding their timeout, and the text of other errors.
+	ErrorKinds map[string]int
+}
+
+// MethodReport holds the statistics of the calls to one method.
+type MethodReport struct {
+	Calls   int
+	Errors  int
+	Latency Latency
+}
+
+// Latency summarizes latencies.
+type Latency struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// Throughput returns the calls made per second.
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Calls) / r.Elapsed.Seconds()
+}
+
+// Write writes the report as text to w.
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, tabPadding, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "calls\t%d\n", r.Calls)
+	_, _ = fmt.Fprintf(tw, "errors\t%d\n", r.Errors)
+	_, _ = fmt.Fprintf(tw, "elapsed\t%s\n", r.Elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(tw, "throughput\t%.1f calls/s\n\n", r.Throughput())
+
+	_, _ = fmt.Fprintln(tw, "method\tcalls\terrors\tmin\tmean\tp50\tp90\tp99\tmax")
+	writeLatencyRow(tw, "all", r.Calls, r.Errors, r.Latency)
+	for _, method := range slices.Sorted(maps.Keys(r.Methods)) {
+		m := r.Methods[method]
+		writeLatencyRow(tw, method, m.Calls, m.Errors, m.Latency)
+	}
+
+	if len(r.ErrorKinds) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nerror\tcalls")
+		kinds := slices.SortedFunc(maps.Keys(r.ErrorKinds), func(a, b string) int {
+			if byCount := r.ErrorKinds[b] - r.ErrorKinds[a]; byCount != 0 {
+				return byCount
+			}
+			return strings.Compare(a, b)
+		})
+		for _, kind := range kinds {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\n", kind, r.ErrorKinds[kind])
+		}
+	}
+	return tw.Flush()
+}
+
+// writeLatencyRow writes a row of the latency table.
+func writeLatencyRow(w io.Writer, name string, calls, errs int, l Latency) {
+	_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", name, calls, errs,
+		round(l.Min), round(l.Mean), round(l.P50), round(l.P90), round(l.P99), round(l.Max))
+}
+
+// round rounds d for display.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	default:
+		return d
+	}
+}
+
+// recorder records the calls of a worker, merged into the report at the end of the run.
+type recorder struct {
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	kinds     map[string]int
+}
+
+// newRecorder returns an empty recorder.
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		kinds:     make(map[string]int),
+	}
+}
+
+// record records a call to method.
+func (r *recorder) record(method string, latency time.Duration, err error) {
+	r.latencies[method] = append(r.latencies[method], latency)
+	if err != nil {
+		r.errors[method]++
+		r.kinds[errorKind(err)]++
+	}
+}
+
+// errorKind returns the kind of err reported in Report.ErrorKinds.
+func errorKind(err error) string {
+	var rpcErr *jsonrpc.Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return "code " + strconv.Itoa(rpcErr.Code)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return err.Error()
+	}
+}
+
+// newReport merges the recorders of a run lasting elapsed.
+func newReport(elapsed time.Duration, recorders []*recorder) *Report {
+	report := &Report{
+		Elapsed:    elapsed,
+		Methods:    make(map[string]MethodReport),
+		ErrorKinds: make(map[string]int),
+	}
+	byMethod := make(map[string][]time.Duration)
+	errs := make(map[string]int)
+	for _, rec := range recorders {
+		for method, latencies := range rec.latencies {
+			byMethod[method] = append(byMethod[method], latencies...)
+		}
+		for method, n := range rec.errors {
+			errs[method] += n
+		}
+		for kind, n := range rec.kinds {
+			report.ErrorKinds[kind] += n
+		}
+	}
+
+	var all []time.Duration
+	for method, latencies := range byMethod {
+		all = append(all, latencies...)
+		report.Methods[method] = MethodReport{
+			Calls:   len(latencies),
+			Errors:  errs[method],
+			Latency: summarize(latencies),
+		}
+		report.Calls += len(latencies)
+		report.Errors += errs[method]
+	}
+	report.Latency = summarize(all)
+	return report
+}
+
+// summarize returns the summary of latencies, which it sorts.
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	return Latency{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(latencies, p50),
+		P90:  percentile(latencies, p90),
+		P99:  percentile(latencies, p99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the p-th percentile of the sorted latencies, by the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(float64(len(sorted))*p)) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
@@ -0,0 +1,66 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	t.Run("Latencies are summarized", func(t *testing.T) {
+		latencies := make([]time.Duration, 100)
+		for i := range latencies {
+			latencies[i] = time.Duration(100-i) * time.Millisecond
+		}
+		l := summarize(latencies)
+		assert.Equal(t, time.Millisecond, l.Min)
+		assert.Equal(t, 50*time.Millisecond, l.P50)
+		assert.Equal(t, 90*time.Millisecond, l.P90)
+		assert.Equal(t, 99*time.Millisecond, l.P99)
+		assert.Equal(t, 100*time.Millisecond, l.Max)
+		assert.Equal(t, 50500*time.Microsecond, l.Mean)
+		assert.Equal(t, Latency{}, summarize(nil))
+	})
+
+	t.Run("Recorders are merged", func(t *testing.T) {
+		first, second := newRecorder(), newRecorder()
+		first.record("a", time.Millisecond, nil)
+		first.record("a", 3*time.Millisecond, &jsonrpc.Error{Code: -32000})
+		second.record("a", 2*time.Millisecond, nil)
+		second.record("b", 4*time.Millisecond, context.DeadlineExceeded)
+		second.record("b", 4*time.Millisecond, fmt.Errorf("call: %w", errors.New("refused")))
+
+		report := newReport(time.Second, []*recorder{first, second})
+		assert.Equal(t, 5, report.Calls)
+		assert.Equal(t, 3, report.Errors)
+		assert.Equal(t, MethodReport{Calls: 3, Errors: 1, Latency: Latency{
+			Min: time.Millisecond, Mean: 2 * time.Millisecond, P50: 2 * time.Millisecond,
+			P90: 3 * time.Millisecond, P99: 3 * time.Millisecond, Max: 3 * time.Millisecond,
+		}}, report.Methods["a"])
+		assert.Equal(t, map[string]int{
+			"code -32000":   1,
+			"timeout":       1,
+			"call: refused": 1,
+		}, report.ErrorKinds)
+		assert.InDelta(t, 5.0, report.Throughput(), 0.001)
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		rec := newRecorder()
+		rec.record("eth_call", 2*time.Millisecond, nil)
+		rec.record("eth_call", 2*time.Millisecond, &jsonrpc.Error{Code: -32005})
+		var out bytes.Buffer
+		require.NoError(t, newReport(time.Second, []*recorder{rec}).Write(&out))
+		assert.Contains(t, out.String(), "throughput  2.0 calls/s")
+		assert.Regexp(t, `eth_call\s+2\s+1\s+2ms`, out.String())
+		assert.Regexp(t, `code -32005\s+1`, out.String())
+		assert.Zero(t, (&Report{}).Throughput())
+	})
+}
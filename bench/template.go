@@ -0,0 +1,85 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"text/template"
+)
+
+// hexBase is the base of the integers formatted by the hex template function.
+const hexBase = 16
+
+// templateFuncs are the functions available to params templates.
+var templateFuncs = template.FuncMap{
+	"hex": func(n int64) string {
+		return "0x" + strconv.FormatInt(n, hexBase)
+	},
+}
+
+// templateCall is a Call with its params template parsed.
+type templateCall struct {
+	method string
+	params *template.Template
+	weight int
+}
+
+// parseCall parses the params template of call and checks that it renders valid JSON.
+func parseCall(call Call) (*templateCall, error) {
+	if call.Method == "" {
+		return nil, errNoMethod
+	}
+	if call.Weight < 0 {
+		return nil, fmt.Errorf("bench: negative weight for %s", call.Method)
+	}
+	parsed := &templateCall{method: call.Method, weight: max(call.Weight, 1)}
+	if call.Params == "" {
+		return parsed, nil
+	}
+	tmpl, err := template.New(call.Method).Funcs(templateFuncs).Parse(call.Params)
+	if err != nil {
+		return nil, fmt.Errorf("bench: params of %s: %w", call.Method, err)
+	}
+	parsed.params = tmpl
+	if sample := parsed.prepare(0, 0, rand.New(rand.NewPCG(0, 0))); sample.err != nil {
+		return nil, sample.err
+	}
+	return parsed, nil
+}
+
+// prepare renders the params of the call with sequence number seq made by worker.
+func (c *templateCall) prepare(seq int64, worker int, rng *rand.Rand) pendingCall {
+	call := pendingCall{method: c.method}
+	if c.params == nil {
+		return call
+	}
+	var buf bytes.Buffer
+	data := templateData{Seq: seq, Worker: worker, rng: rng}
+	if err := c.params.Execute(&buf, data); err != nil {
+		call.err = fmt.Errorf("bench: params of %s: %w", c.method, err)
+		return call
+	}
+	if !json.Valid(buf.Bytes()) {
+		call.err = fmt.Errorf("bench: params of %s are not valid JSON: %s", c.method, buf.Bytes())
+		return call
+	}
+	call.params = rawParams(buf.Bytes())
+	return call
+}
+
+// templateData is the data params templates are executed with.
+type templateData struct {
+	Seq    int64
+	Worker int
+	rng    *rand.Rand
+}
+
+// RandInt returns a random integer in [lo, hi), or lo if the interval is empty.
+func (d templateData) RandInt(lo, hi int) int {
+	if hi <= lo {
+		return lo
+	}
+	return lo + d.rng.IntN(hi-lo)
+}
@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCall(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	t.Run("Templates render per call", func(t *testing.T) {
+		call, err := parseCall(Call{
+			Method: "m",
+			Params: `["{{hex .Seq}}", {{.Worker}}, {{.RandInt 5 6}}]`,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, call.weight, "the weight defaults to 1")
+
+		pending := call.prepare(255, 3, rng)
+		require.NoError(t, pending.err)
+		assert.Equal(t, "m", pending.method)
+		assert.JSONEq(t, `["0xff", 3, 5]`, string(pending.params.(json.RawMessage)))
+	})
+
+	t.Run("Calls without params", func(t *testing.T) {
+		call, err := parseCall(Call{Method: "m", Weight: 4})
+		require.NoError(t, err)
+		assert.Equal(t, 4, call.weight)
+		assert.Nil(t, call.prepare(0, 0, rng).params)
+	})
+
+	t.Run("Invalid templates", func(t *testing.T) {
+		_, err := parseCall(Call{Method: "m", Params: `{{.Missing}}`})
+		require.Error(t, err)
+		_, err = parseCall(Call{Method: "m", Params: `[unquoted]`})
+		require.ErrorContains(t, err, "not valid JSON")
+		_, err = parseCall(Call{Method: "m", Weight: -1})
+		assert.Error(t, err)
+	})
+
+	t.Run("Empty random intervals", func(t *testing.T) {
+		assert.Equal(t, 7, templateData{rng: rng}.RandInt(7, 7))
+	})
+}
@@ -4,6 +4,8 @@ package jsonrpc
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -247,6 +249,75 @@ func BenchmarkResponseMarshal(b *testing.B) {
 	}
 }
 
+// BenchmarkAppendJSON benchmarks appending requests and responses to a reused buffer
+func BenchmarkAppendJSON(b *testing.B) {
+	req := &Request{
+		JSONRPC: "2.0",
+		ID:      int64(42),
+		Method:  "updateUser",
+		Params:  json.RawMessage(`{"userId":12345,"name":"Alice Johnson"}`),
+	}
+	resp := &Response{
+		jsonrpc: "2.0",
+		id:      int64(42),
+		result:  []byte(`{"userId":12345,"name":"Alice Johnson","status":"active"}`),
+	}
+	buf := make([]byte, 0, 1024)
+
+	b.Run("Request", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var err error
+			if buf, err = req.AppendJSON(buf[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Response", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var err error
+			if buf, err = resp.AppendJSON(buf[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Batch_Response", func(b *testing.B) {
+		resps := make([]*Response, 100)
+		for i := range resps {
+			resps[i] = resp
+		}
+		b.ReportAllocs()
+		for b.Loop() {
+			var err error
+			if buf, err = AppendBatchResponse(buf[:0], resps); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkServerAppendMessage benchmarks dispatching a request into a reused reply buffer
+func BenchmarkServerAppendMessage(b *testing.B) {
+	srv := NewServer()
+	result := json.RawMessage(`"pong"`)
+	ping := HandlerFunc(func(context.Context, *Request) (any, error) {
+		return result, nil
+	})
+	if err := srv.Register("ping", ping); err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	buf := make([]byte, 0, 1024)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		buf = srv.AppendMessage(ctx, buf[:0], smallRequestJSON)
+	}
+}
+
 // BenchmarkUnmarshalResult benchmarks lazy result unmarshaling
 // TODO: Add comparison benchmarks for alternative JSON parsers
 func BenchmarkUnmarshalResult(b *testing.B) {
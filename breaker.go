@@ -0,0 +1,240 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Defaults applied to the zero fields of a BreakerPolicy.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker for the calls it fails fast while open. It is
+// retryable, so that WithRetry and PoolTransport move on once the breaker lets calls through or
+// to another endpoint.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through, counting consecutive failures.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails calls fast with ErrCircuitOpen until the cooldown elapses.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single probe call through, whose outcome closes the breaker or
+	// opens it again. Other calls fail fast meanwhile.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerPolicy configures a CircuitBreaker.
+type BreakerPolicy struct {
+	// Threshold is the number of consecutive failures opening the breaker. Defaults to 5.
+	Threshold int
+
+	// Cooldown is how long the breaker stays open before letting a probe call through.
+	// Defaults to 30s.
+	Cooldown time.Duration
+
+	// TripCodes lists the JSON-RPC error codes counting as failures, such as the rate limit or
+	// overload codes of a provider. A reply counts as failed if any of its responses carries one.
+	TripCodes []int
+
+	// OnStateChange, if set, is called on every change of state. It must not block.
+	OnStateChange func(from, to BreakerState)
+}
+
+// CircuitBreaker is a Transport guarding an upstream endpoint: once calls to it fail Threshold
+// times in a row, it opens and fails calls fast with ErrCircuitOpen, sparing the endpoint and
+// the callers' time. After the cooldown, it lets one probe call through, which closes it again
+// if it succeeds. Wrapping each endpoint of a PoolTransport makes the pool move on from open
+// endpoints at once.
+//
+// Failures are the exchange errors of the wrapped transport, except those of canceled calls and
+// of HTTP statuses other than 5xx and 429, and replies holding an error code listed in
+// TripCodes. A CircuitBreaker is safe for concurrent use.
+type CircuitBreaker struct {
+	transport Transport
+	policy    BreakerPolicy
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker wraps transport in a circuit breaker configured by policy.
+func NewCircuitBreaker(transport Transport, policy BreakerPolicy) *CircuitBreaker {
+	if policy.Threshold <= 0 {
+		policy.Threshold = defaultBreakerThreshold
+	}
+	if policy.Cooldown <= 0 {
+		policy.Cooldown = defaultBreakerCooldown
+	}
+	return &CircuitBreaker{transport: transport, policy: policy}
+}
+
+// RoundTrip sends payload through the wrapped transport, unless the breaker is open.
+func (b *CircuitBreaker) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	call, err := b.admit()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := b.transport.RoundTrip(ctx, payload)
+	switch {
+	case err != nil && (ctx.Err() != nil || !isRetryableError(err)):
+		// Failures of the caller's context or request say nothing about the endpoint
+		b.finish(call, outcomeNeutral)
+	case err != nil || b.trips(reply):
+		b.finish(call, outcomeFailure)
+	default:
+		b.finish(call, outcomeSuccess)
+	}
+	return reply, err
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Close closes the wrapped transport.
+func (b *CircuitBreaker) Close() error {
+	return b.transport.Close()
+}
+
+// admission is how a call was let through a breaker.
+type admission int
+
+const (
+	// regularCall is a call let through a closed breaker.
+	regularCall admission = iota
+
+	// probeCall is the probe of a half-open breaker.
+	probeCall
+)
+
+// outcome is what the outcome of a call says about the endpoint.
+type outcome int
+
+const (
+	outcomeNeutral outcome = iota
+	outcomeSuccess
+	outcomeFailure
+)
+
+// admit lets a call through or fails it fast.
+func (b *CircuitBreaker) admit() (admission, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.policy.Cooldown {
+			return regularCall, ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return probeCall, nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return regularCall, ErrCircuitOpen
+		}
+		b.probing = true
+		return probeCall, nil
+	default:
+		return regularCall, nil
+	}
+}
+
+// finish records the outcome of a call: a success closes a half-open breaker, and a failure
+// opens the breaker once failures reach the threshold, or at once for a probe. A neutral outcome
+// of a probe lets another one through.
+func (b *CircuitBreaker) finish(call admission, result outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if call == probeCall {
+		b.probing = false
+	}
+	switch result {
+	case outcomeSuccess:
+		b.failures = 0
+		if b.state == BreakerHalfOpen {
+			b.setState(BreakerClosed)
+		}
+	case outcomeFailure:
+		b.failures++
+		if call == probeCall || (b.state == BreakerClosed && b.failures >= b.policy.Threshold) {
+			b.openedAt = time.Now()
+			b.setState(BreakerOpen)
+		}
+	default:
+	}
+}
+
+// setState moves the breaker to state, notifying the policy's hook.
+func (b *CircuitBreaker) setState(state BreakerState) {
+	from := b.state
+	if from == state {
+		return
+	}
+	b.state = state
+	if hook := b.policy.OnStateChange; hook != nil {
+		hook(from, state)
+	}
+}
+
+// codedReply is a response reduced to its error code, as decoded by trips.
+type codedReply struct {
+	Error *codeOnly `json:"error"`
+}
+
+// codeOnly is the code of an error.
+type codeOnly struct {
+	Code int `json:"code"`
+}
+
+// trips reports whether reply holds a response with an error code listed in TripCodes.
+func (b *CircuitBreaker) trips(reply []byte) bool {
+	if len(b.policy.TripCodes) == 0 || len(reply) == 0 {
+		return false
+	}
+	var resps []codedReply
+	if jsonKind(reply) == '[' {
+		if err := getCodec().Unmarshal(reply, &resps); err != nil {
+			return false
+		}
+	} else {
+		var resp codedReply
+		if err := getCodec().Unmarshal(reply, &resp); err != nil {
+			return false
+		}
+		resps = append(resps, resp)
+	}
+	return slices.ContainsFunc(resps, func(resp codedReply) bool {
+		return resp.Error != nil && slices.Contains(b.policy.TripCodes, resp.Error.Code)
+	})
+}
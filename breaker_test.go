@@ -0,0 +1,199 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// switchTransport fails with err while it is set, and answers with reply otherwise.
+type switchTransport struct {
+	calls atomic.Int32
+	err   atomic.Pointer[error]
+	reply []byte
+}
+
+func (t *switchTransport) RoundTrip(ctx context.Context, _ []byte) ([]byte, error) {
+	t.calls.Add(1)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := t.err.Load(); err != nil {
+		return nil, *err
+	}
+	return t.reply, nil
+}
+
+func (*switchTransport) Close() error { return nil }
+
+// setErr makes the transport fail with err, or succeed for nil.
+func (t *switchTransport) setErr(err error) {
+	if err == nil {
+		t.err.Store(nil)
+		return
+	}
+	t.err.Store(&err)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("connection refused")
+	ok := []byte(`{"jsonrpc":"2.0","id":1,"result":true}`)
+
+	t.Run("Opens after consecutive failures and fails fast", func(t *testing.T) {
+		upstream := &switchTransport{reply: ok}
+		upstream.setErr(errDown)
+		var changes []BreakerState
+		b := NewCircuitBreaker(upstream, BreakerPolicy{
+			Threshold:     3,
+			Cooldown:      time.Hour,
+			OnStateChange: func(_, to BreakerState) { changes = append(changes, to) },
+		})
+
+		for range 3 {
+			_, err := b.RoundTrip(ctx, nil)
+			require.ErrorIs(t, err, errDown)
+		}
+		assert.Equal(t, BreakerOpen, b.State())
+		_, err := b.RoundTrip(ctx, nil)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, int32(3), upstream.calls.Load())
+		assert.Equal(t, []BreakerState{BreakerOpen}, changes)
+	})
+
+	t.Run("A success resets the count", func(t *testing.T) {
+		upstream := &switchTransport{reply: ok}
+		b := NewCircuitBreaker(upstream, BreakerPolicy{Threshold: 2})
+		for range 3 {
+			upstream.setErr(errDown)
+			_, _ = b.RoundTrip(ctx, nil)
+			upstream.setErr(nil)
+			_, err := b.RoundTrip(ctx, nil)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, BreakerClosed, b.State())
+	})
+
+	t.Run("Probes after the cooldown", func(t *testing.T) {
+		upstream := &switchTransport{reply: ok}
+		upstream.setErr(errDown)
+		var changes []BreakerState
+		b := NewCircuitBreaker(upstream, BreakerPolicy{
+			Threshold:     1,
+			Cooldown:      20 * time.Millisecond,
+			OnStateChange: func(_, to BreakerState) { changes = append(changes, to) },
+		})
+		_, _ = b.RoundTrip(ctx, nil)
+		require.Equal(t, BreakerOpen, b.State())
+
+		time.Sleep(30 * time.Millisecond)
+		_, err := b.RoundTrip(ctx, nil)
+		require.ErrorIs(t, err, errDown, "the failed probe reaches the endpoint")
+		require.Equal(t, BreakerOpen, b.State())
+		_, err = b.RoundTrip(ctx, nil)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+
+		upstream.setErr(nil)
+		time.Sleep(30 * time.Millisecond)
+		reply, err := b.RoundTrip(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ok, reply)
+		assert.Equal(t, BreakerClosed, b.State())
+		assert.Equal(t, []BreakerState{
+			BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed,
+		}, changes)
+	})
+
+	t.Run("Lets a single probe through", func(t *testing.T) {
+		gate := make(chan struct{})
+		started := make(chan struct{}, 1)
+		var calls atomic.Int32
+		upstream := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			if calls.Add(1) == 1 {
+				return nil, errDown
+			}
+			started <- struct{}{}
+			<-gate
+			return ok, nil
+		}}
+		b := NewCircuitBreaker(upstream, BreakerPolicy{Threshold: 1, Cooldown: time.Millisecond})
+		_, _ = b.RoundTrip(ctx, nil)
+		time.Sleep(5 * time.Millisecond)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := b.RoundTrip(ctx, nil)
+			done <- err
+		}()
+		<-started
+		assert.Equal(t, BreakerHalfOpen, b.State())
+		_, err := b.RoundTrip(ctx, nil)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+
+		close(gate)
+		require.NoError(t, <-done)
+		assert.Equal(t, BreakerClosed, b.State())
+	})
+
+	t.Run("Counts replies with trip codes", func(t *testing.T) {
+		limited := []byte(`[{"jsonrpc":"2.0","id":1,"result":1},` +
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32005,"message":"limited"}}]`)
+		upstream := &switchTransport{reply: limited}
+		b := NewCircuitBreaker(upstream, BreakerPolicy{Threshold: 2, TripCodes: []int{-32005}})
+		for range 2 {
+			reply, err := b.RoundTrip(ctx, nil)
+			require.NoError(t, err)
+			assert.Equal(t, limited, reply, "the reply is returned as-is")
+		}
+		assert.Equal(t, BreakerOpen, b.State())
+
+		other := NewCircuitBreaker(&switchTransport{
+			reply: []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"nope"}}`),
+		}, BreakerPolicy{Threshold: 1, TripCodes: []int{-32005}})
+		_, _ = other.RoundTrip(ctx, nil)
+		assert.Equal(t, BreakerClosed, other.State())
+	})
+
+	t.Run("Ignores canceled calls and client errors", func(t *testing.T) {
+		upstream := &switchTransport{reply: ok}
+		b := NewCircuitBreaker(upstream, BreakerPolicy{Threshold: 1})
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := b.RoundTrip(canceled, nil)
+		require.ErrorIs(t, err, context.Canceled)
+
+		upstream.setErr(&HTTPError{StatusCode: 400})
+		_, _ = b.RoundTrip(ctx, nil)
+		assert.Equal(t, BreakerClosed, b.State())
+	})
+
+	t.Run("Pools move on from open endpoints", func(t *testing.T) {
+		down := &switchTransport{}
+		down.setErr(errDown)
+		up := &switchTransport{reply: ok}
+		pool := NewPoolTransport([]PoolEndpoint{
+			{Name: "down", Transport: NewCircuitBreaker(down, BreakerPolicy{Threshold: 1})},
+			{Name: "up", Transport: up},
+		}, WithEjection(100, time.Hour))
+		defer pool.Close()
+
+		for range 3 {
+			reply, err := pool.RoundTrip(ctx, nil)
+			require.NoError(t, err)
+			assert.Equal(t, ok, reply)
+		}
+		assert.Equal(t, int32(1), down.calls.Load())
+	})
+
+	t.Run("State names", func(t *testing.T) {
+		assert.Equal(t, "closed", BreakerClosed.String())
+		assert.Equal(t, "open", BreakerOpen.String())
+		assert.Equal(t, "half-open", BreakerHalfOpen.String())
+		assert.Equal(t, "unknown", BreakerState(-1).String())
+	})
+}
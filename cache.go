@@ -0,0 +1,238 @@
+package jsonrpc
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// defaultCacheSize is the capacity of the LRUCache used by policies setting no Cache.
+const defaultCacheSize = 1024
+
+// Cache stores the responses of cached calls under the keys returned by CacheKey. Implementations
+// must be safe for concurrent use.
+type Cache interface {
+	// Get returns the response stored under key, unless there is none or it has expired.
+	Get(key string) (*Response, bool)
+
+	// Set stores resp under key for ttl, or until evicted when ttl is zero.
+	Set(key string, resp *Response, ttl time.Duration)
+}
+
+// CachePolicy configures the response caching enabled with WithCache and WithProxyCache. Only
+// successful responses to the listed methods are cached, so it suits read-heavy idempotent
+// methods such as eth_chainId.
+type CachePolicy struct {
+	// Cache stores the responses. Defaults to an LRUCache of 1024 entries.
+	Cache Cache
+
+	// TTLs maps the cached methods to how long their responses are kept, zero keeping them
+	// until evicted.
+	TTLs map[string]time.Duration
+}
+
+// WithCache answers calls to the methods of policy from its cache when it holds a response to the
+// same method and params, caching the responses to the other calls. The cache runs as an
+// interceptor placed after those added by earlier WithInterceptors options, so that earlier
+// interceptors also see the calls answered from the cache. Batches are not cached.
+func WithCache(policy CachePolicy) ClientOption {
+	return WithInterceptors(newResponseCache(policy).interceptor)
+}
+
+// WithProxyCache answers the calls to the methods of policy from its cache, including batch
+// members, rather than forwarding them, and caches the responses the upstreams send to the other
+// calls.
+func WithProxyCache(policy CachePolicy) ProxyOption {
+	return func(p *Proxy) {
+		p.cache = newResponseCache(policy)
+	}
+}
+
+// CacheKey returns the cache key of a call to method with params. Params are canonicalized
+// before being hashed, so that named params given in a different order share a key.
+func CacheKey(method string, params any) (string, error) {
+	data, err := getCodec().Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	value, err := jsonvalue.Parse(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(value.SortKeys().AppendJSON(nil))
+	return method + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// responseCache applies a CachePolicy.
+type responseCache struct {
+	cache Cache
+	ttls  map[string]time.Duration
+}
+
+// cacheSlot is where the response to a cached call is stored.
+type cacheSlot struct {
+	key string
+	ttl time.Duration
+}
+
+// newResponseCache returns the cache applying policy.
+func newResponseCache(policy CachePolicy) *responseCache {
+	rc := &responseCache{cache: policy.Cache, ttls: make(map[string]time.Duration)}
+	if rc.cache == nil {
+		rc.cache = NewLRUCache(defaultCacheSize)
+	}
+	for method, ttl := range policy.TTLs {
+		rc.ttls[method] = ttl
+	}
+	return rc
+}
+
+// slot returns where the response to req is cached, and false if it is not cacheable.
+func (rc *responseCache) slot(req *Request) (cacheSlot, bool) {
+	ttl, ok := rc.ttls[req.Method]
+	if !ok || req.IsNotification() {
+		return cacheSlot{}, false
+	}
+	key, err := CacheKey(req.Method, req.Params)
+	if err != nil {
+		return cacheSlot{}, false
+	}
+	return cacheSlot{key: key, ttl: ttl}, true
+}
+
+// get returns the response cached in slot with the given ID, or nil if there is none.
+func (rc *responseCache) get(slot cacheSlot, id any) *Response {
+	cached, ok := rc.cache.Get(slot.key)
+	if !ok {
+		return nil
+	}
+	resp, err := cached.WithID(id)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+// set caches a copy of resp in slot if it is a successful response.
+func (rc *responseCache) set(slot cacheSlot, resp *Response) {
+	if resp == nil || resp.Err() != nil {
+		return
+	}
+	clone, err := resp.Clone()
+	if err != nil {
+		return
+	}
+	rc.cache.Set(slot.key, clone, slot.ttl)
+}
+
+// interceptor answers cacheable calls to next from the cache.
+func (rc *responseCache) interceptor(next Invoker) Invoker {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		slot, ok := rc.slot(req)
+		if !ok {
+			return next(ctx, req)
+		}
+		if resp := rc.get(slot, req.ID); resp != nil {
+			return resp, nil
+		}
+		resp, err := next(ctx, req)
+		if err == nil {
+			rc.set(slot, resp)
+		}
+		return resp, err
+	}
+}
+
+// LRUCache is an in-memory Cache holding a bounded number of responses, evicting the least
+// recently used one to make room. Expired responses are dropped when looked up or evicted.
+// An LRUCache is safe for concurrent use.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Most recently used first
+}
+
+// lruEntry is an element of an LRUCache.
+type lruEntry struct {
+	key     string
+	resp    *Response
+	expires time.Time // Zero for no expiry
+}
+
+// NewLRUCache creates an LRUCache holding up to capacity responses. A capacity below 1 means
+// 1024.
+func NewLRUCache(capacity int) *LRUCache {
+	size := capacity
+	if size < 1 {
+		size = defaultCacheSize
+	}
+	return &LRUCache{
+		capacity: size,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(key string) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry, ok := elem.Value.(*lruEntry)
+	if !ok || entry.expired(time.Now()) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.resp, true
+}
+
+// Set implements Cache.
+func (c *LRUCache) Set(key string, resp *Response, ttl time.Duration) {
+	entry := &lruEntry{key: key, resp: resp}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of responses held, including expired ones not yet dropped.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops an element. The caller must hold c.mu.
+func (c *LRUCache) remove(elem *list.Element) {
+	if entry, ok := elem.Value.(*lruEntry); ok {
+		delete(c.entries, entry.key)
+	}
+	c.order.Remove(elem)
+}
+
+// expired reports whether the entry has expired at now.
+func (e *lruEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
@@ -0,0 +1,173 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheKey(t *testing.T) {
+	a, err := CacheKey("get", map[string]any{"a": 1, "b": []any{"x", map[string]any{"c": true}}})
+	require.NoError(t, err)
+	b, err := CacheKey("get", json.RawMessage(`{ "b": ["x", {"c": true}], "a": 1 }`))
+	require.NoError(t, err)
+	assert.Equal(t, a, b, "member order and whitespace do not matter")
+
+	c, err := CacheKey("other", json.RawMessage(`{"a":1,"b":["x",{"c":true}]}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, a, c, "the method is part of the key")
+
+	d, err := CacheKey("get", []any{1, 2})
+	require.NoError(t, err)
+	e, err := CacheKey("get", []any{2, 1})
+	require.NoError(t, err)
+	assert.NotEqual(t, d, e, "positional params keep their order")
+
+	_, err = CacheKey("get", func() {})
+	assert.Error(t, err)
+}
+
+func TestLRUCache(t *testing.T) {
+	resp := func(result string) *Response {
+		r, err := NewResponse(int64(1), result)
+		require.NoError(t, err)
+		return r
+	}
+
+	t.Run("Evicts the least recently used", func(t *testing.T) {
+		cache := NewLRUCache(2)
+		cache.Set("a", resp("a"), 0)
+		cache.Set("b", resp("b"), 0)
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+		cache.Set("c", resp("c"), 0)
+
+		_, ok = cache.Get("b")
+		assert.False(t, ok)
+		_, ok = cache.Get("a")
+		assert.True(t, ok)
+		_, ok = cache.Get("c")
+		assert.True(t, ok)
+		assert.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("Replaces existing keys", func(t *testing.T) {
+		cache := NewLRUCache(2)
+		cache.Set("a", resp("old"), 0)
+		cache.Set("a", resp("new"), 0)
+		got, ok := cache.Get("a")
+		require.True(t, ok)
+		var result string
+		require.NoError(t, got.UnmarshalResult(&result))
+		assert.Equal(t, "new", result)
+		assert.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("Expires entries", func(t *testing.T) {
+		cache := NewLRUCache(0)
+		cache.Set("a", resp("a"), 10*time.Millisecond)
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+		time.Sleep(20 * time.Millisecond)
+		_, ok = cache.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestClient_WithCache(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+
+	var calls int
+	transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		calls++
+		return srv.HandleMessage(ctx, payload), nil
+	}}
+	client := NewClient(transport, WithCache(CachePolicy{
+		TTLs: map[string]time.Duration{"sum": 0, "fail": 0},
+	}))
+
+	t.Run("Repeated calls are answered from the cache", func(t *testing.T) {
+		calls = 0
+		for range 3 {
+			var total int
+			require.NoError(t, client.Call(ctx, "sum", []int{1, 2}, &total))
+			assert.Equal(t, 3, total)
+		}
+		assert.Equal(t, 1, calls)
+
+		var total int
+		require.NoError(t, client.Call(ctx, "sum", []int{2, 2}, &total))
+		assert.Equal(t, 4, total)
+		assert.Equal(t, 2, calls, "other params are a miss")
+	})
+
+	t.Run("Only listed methods are cached", func(t *testing.T) {
+		calls = 0
+		for range 2 {
+			require.NoError(t, client.Call(ctx, "subtract", []int{3, 1}, nil))
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		calls = 0
+		for range 2 {
+			assert.Error(t, client.Call(ctx, "fail", nil, nil))
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Cached responses carry the caller's ID", func(t *testing.T) {
+		cache := NewLRUCache(1)
+		cached := NewClient(transport, WithCache(CachePolicy{
+			Cache: cache,
+			TTLs:  map[string]time.Duration{"sum": time.Minute},
+		}))
+		for _, id := range []int64{7, 8} {
+			req := NewRequestWithID("sum", []int{1}, id)
+			resp, err := cached.invoke(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, id, resp.IDOrNil())
+		}
+		assert.Equal(t, 1, cache.Len())
+	})
+}
+
+func TestProxy_WithCache(t *testing.T) {
+	ctx := context.Background()
+	client, rec := newUpstream(newTestServer(t))
+	proxy := NewProxy(client, WithProxyCache(CachePolicy{
+		TTLs: map[string]time.Duration{"sum": time.Minute},
+	}))
+
+	msg := `[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},` +
+		`{"jsonrpc":"2.0","method":"subtract","params":[5,3],"id":2}]`
+	for range 2 {
+		resps, err := DecodeBatchResponse(proxy.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		assert.Equal(t, int64(1), resps[0].IDOrNil())
+		assert.Equal(t, int64(2), resps[1].IDOrNil())
+	}
+
+	sent := rec.sent()
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[0], "sum")
+	assert.NotContains(t, sent[1], "sum", "the cached member is not forwarded")
+
+	failing := NewProxy(NewClient(&funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+		return nil, errors.New("down")
+	}}), WithProxyCache(CachePolicy{TTLs: map[string]time.Duration{"sum": 0}}))
+	reply := failing.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"sum","id":1}`))
+	resp, err := DecodeResponse(reply)
+	require.NoError(t, err)
+	require.ErrorIs(t, resp.Err(), ErrUpstreamUnavailable)
+	assert.Equal(t, 0, failing.cache.cache.(*LRUCache).Len())
+}
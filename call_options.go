@@ -0,0 +1,129 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CallOption configures a single call or notification, overriding the client's defaults for it
+// without constructing a second client.
+type CallOption func(*callOptions)
+
+// callOptions holds the settings of a call.
+type callOptions struct {
+	timeout time.Duration
+	header  []string
+	id      any
+	codec   Codec
+}
+
+// WithCallTimeout bounds the call by timeout, in addition to the deadline of its context.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithCallHeader adds key with value to the outgoing metadata of the call, which HTTP transports
+// send as a request header.
+func WithCallHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		o.header = append(o.header, key, value)
+	}
+}
+
+// WithCallID sends the call with id instead of one of the client's ID generator, such as for
+// replaying requests or correlating them with external logs. The ID must be unique among the
+// calls in flight on a stream client. It has no effect on notifications.
+func WithCallID(id any) CallOption {
+	return func(o *callOptions) {
+		o.id = id
+	}
+}
+
+// WithCallCodec marshals the params and unmarshals the result of the call with codec instead
+// of the package codec set with SetCodec, such as for params needing encoding/json's behavior
+// for one method.
+func WithCallCodec(codec Codec) CallOption {
+	return func(o *callOptions) {
+		o.codec = codec
+	}
+}
+
+// defaultCallOptions are the settings of calls without options, shared to spare an allocation.
+var defaultCallOptions = &callOptions{}
+
+// newCallOptions applies opts.
+func newCallOptions(opts []CallOption) *callOptions {
+	if len(opts) == 0 {
+		return defaultCallOptions
+	}
+	o := &callOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// callOptions applies opts over the defaults of the client.
+func (c *Client) callOptions(opts []CallOption) *callOptions {
+	if c.codec == nil {
+		return newCallOptions(opts)
+	}
+	o := &callOptions{codec: c.codec}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// jsonCodec returns the codec of the call, or the package codec if it has none.
+func (o *callOptions) jsonCodec() Codec {
+	if o.codec != nil {
+		return o.codec
+	}
+	return getCodec()
+}
+
+// context returns ctx bounded by the timeout and carrying the headers of the call.
+func (o *callOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	callCtx := ctx
+	if len(o.header) > 0 {
+		callCtx = AppendToOutgoingContext(callCtx, o.header...)
+	}
+	if o.timeout > 0 {
+		return context.WithTimeout(callCtx, o.timeout)
+	}
+	return callCtx, func() {}
+}
+
+// params returns params, marshaled with the codec of the call if it has one.
+func (o *callOptions) params(params any) (any, error) {
+	if o.codec == nil || params == nil {
+		return params, nil
+	}
+	if _, raw := params.(json.RawMessage); raw {
+		return params, nil
+	}
+	data, err := o.codec.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
+	}
+	return json.RawMessage(data), nil
+}
+
+// unmarshalResult unmarshals the result of resp into result, with the codec of the call if it
+// has one.
+func (o *callOptions) unmarshalResult(resp *Response, result any) error {
+	if o.codec == nil {
+		return resp.UnmarshalResult(result)
+	}
+	raw := resp.RawResult()
+	if len(raw) == 0 {
+		return errors.New("response has no result field")
+	}
+	return o.codec.Unmarshal(raw, result)
+}
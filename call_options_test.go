@@ -0,0 +1,85 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CallOptions(t *testing.T) {
+	ctx := context.Background()
+	var sent []*Request
+	var headers []Metadata
+	roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+		req, err := DecodeRequest(payload)
+		require.NoError(t, err)
+		sent = append(sent, req)
+		md, _ := OutgoingMetadata(ctx)
+		headers = append(headers, md)
+		if req.Method == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		resp, err := NewResponse(req.ID, map[string]any{"n": 1})
+		require.NoError(t, err)
+		return resp.MarshalJSON()
+	}
+	client := NewClient(&funcTransport{fn: roundTrip})
+	defer client.Close()
+
+	t.Run("Timeout", func(t *testing.T) {
+		start := time.Now()
+		err := client.Call(ctx, "slow", nil, nil, WithCallTimeout(20*time.Millisecond))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		require.NoError(t, client.Call(ctx, "m", nil, nil,
+			WithCallHeader("X-Trace", "a"), WithCallHeader("X-Trace", "b")))
+		assert.Equal(t, []string{"a", "b"}, headers[len(headers)-1].Values("X-Trace"))
+
+		require.NoError(t, client.Notify(ctx, "n", nil, WithCallHeader("X-Tenant", "t")))
+		assert.Equal(t, "t", headers[len(headers)-1].Get("X-Tenant"))
+
+		require.NoError(t, client.Call(ctx, "m", nil, nil))
+		assert.Empty(t, headers[len(headers)-1], "options apply to one call only")
+	})
+
+	t.Run("ID override", func(t *testing.T) {
+		require.NoError(t, client.Call(ctx, "m", nil, nil, WithCallID("replay-7")))
+		assert.Equal(t, "replay-7", sent[len(sent)-1].ID)
+
+		require.NoError(t, client.Notify(ctx, "n", nil, WithCallID("ignored")))
+		assert.True(t, sent[len(sent)-1].IsNotification())
+	})
+
+	t.Run("Codec", func(t *testing.T) {
+		codec := &countingCodec{}
+		var result struct{ N int }
+		require.NoError(t, client.Call(ctx, "m", []int{1}, &result, WithCallCodec(codec)))
+		assert.Equal(t, 1, result.N)
+		assert.Equal(t, int32(1), codec.marshals.Load())
+		assert.Equal(t, int32(1), codec.unmarshals.Load())
+
+		require.NoError(t, client.Call(ctx, "m", []int{1}, &result))
+		assert.Equal(t, int32(1), codec.marshals.Load(), "options apply to one call only")
+
+		failing := WithCallCodec(StdCodec)
+		err := client.Call(ctx, "m", func() {}, nil, failing)
+		assert.ErrorContains(t, err, "failed to marshal params")
+	})
+
+	t.Run("Generic calls", func(t *testing.T) {
+		got, err := Call[map[string]int](ctx, client, "m", nil, WithCallID(int64(99)))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"n": 1}, got)
+		assert.EqualValues(t, 99, sent[len(sent)-1].ID)
+
+		_, err = Call[int](ctx, client, "slow", nil, WithCallTimeout(time.Millisecond))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,145 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// CancelRequestMethod is the cancellation notification used by the Language Server Protocol. Its
+// params are an object holding the ID of the request to cancel, e.g. {"id": 7}.
+const CancelRequestMethod = "$/cancelRequest"
+
+// WithCancelNotification makes a stream client send a notification named method, with params
+// {"id": <request id>}, for every in-flight call whose context is done before the response
+// arrives, so that the server can stop working on it. CancelRequestMethod is the common choice.
+//
+// Request/response transports need no notification: canceling the context aborts the exchange,
+// which the server observes directly (e.g. as the HTTP request's context being canceled).
+func WithCancelNotification(method string) ClientOption {
+	return func(c *Client) {
+		c.cancelMethod = method
+	}
+}
+
+// WithCancelMethod makes the server treat notifications named method, with params
+// {"id": <request id>}, as cancellation of the identified in-flight request on the same stream:
+// the context passed to its handler is canceled, so long-running handlers can abort. The
+// notification is consumed by the server and never reaches a registered handler.
+//
+// Cancellation is scoped to a stream connection, as request IDs are only unique per connection.
+// Requests served through HandleMessage or HandleRequest outside of ServeStream or a stream
+// client cannot be canceled this way.
+func WithCancelMethod(method string) ServerOption {
+	return func(s *Server) {
+		s.cancelMethod = method
+	}
+}
+
+// inflightContextKey is the context key under which a connection's inflightRequests is stored.
+type inflightContextKey struct{}
+
+// inflightRequests tracks the cancelable requests being served on one connection.
+type inflightRequests struct {
+	mu      sync.Mutex
+	cancels map[string]*context.CancelFunc
+}
+
+// withInflight returns a copy of ctx carrying an empty in-flight request registry.
+func withInflight(ctx context.Context) context.Context {
+	reg := &inflightRequests{cancels: make(map[string]*context.CancelFunc)}
+	return context.WithValue(ctx, inflightContextKey{}, reg)
+}
+
+// trackInflight derives a cancelable context for a request and registers it under the request ID
+// in the connection registry carried by ctx, if any. The returned function must be called once
+// the request is done.
+func trackInflight(ctx context.Context, id any) (context.Context, func()) {
+	reg, ok := ctx.Value(inflightContextKey{}).(*inflightRequests)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	key := idKey(id)
+	entry := &cancel
+
+	reg.mu.Lock()
+	reg.cancels[key] = entry
+	reg.mu.Unlock()
+
+	return reqCtx, func() {
+		reg.mu.Lock()
+		// A peer reusing an ID may have replaced the entry
+		if reg.cancels[key] == entry {
+			delete(reg.cancels, key)
+		}
+		reg.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancelInflight cancels the in-flight request identified by a cancellation notification.
+func cancelInflight(ctx context.Context, req *Request) {
+	reg, ok := ctx.Value(inflightContextKey{}).(*inflightRequests)
+	if !ok {
+		return
+	}
+	id, ok := cancelID(req)
+	if !ok {
+		return
+	}
+
+	reg.mu.Lock()
+	entry, ok := reg.cancels[idKey(id)]
+	reg.mu.Unlock()
+
+	if ok {
+		(*entry)()
+	}
+}
+
+// cancelID returns the ID named by the params of a cancellation notification, decoded or left
+// raw by WithLazyParams.
+func cancelID(req *Request) (any, bool) {
+	switch params := req.Params.(type) {
+	case map[string]any:
+		id, ok := params["id"]
+		return id, ok
+	case json.RawMessage:
+		var members map[string]json.RawMessage
+		if err := getCodec().Unmarshal(params, &members); err != nil {
+			return nil, false
+		}
+		id, err := unmarshalRawID(members["id"])
+		return id, err == nil && id != nil
+	default:
+		return nil, false
+	}
+}
+
+// isCancelRequest reports whether req is a cancellation notification the server handles itself.
+func (s *Server) isCancelRequest(req *Request) bool {
+	return s.cancelMethod != "" && req.Method == s.cancelMethod && req.IsNotification()
+}
+
+// propagateCancel notifies the peer of calls abandoned because ctx is done, when the client is
+// configured to do so.
+func (c *Client) propagateCancel(ctx context.Context, err error, ids ...any) {
+	if c.cancelMethod == "" || !c.isStream() {
+		return
+	}
+	if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+		return
+	}
+
+	for _, id := range ids {
+		note := NewNotification(c.cancelMethod, map[string]any{"id": id})
+		payload, marshalErr := note.MarshalJSON()
+		if marshalErr != nil {
+			continue
+		}
+		_ = c.write(c.ctx, payload)
+	}
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowServer returns a server whose "slow" method signals on started and then blocks until its
+// context is done, reporting the context error on canceled.
+func newSlowServer(t *testing.T, opts ...ServerOption) (*Server, <-chan struct{}, <-chan error) {
+	t.Helper()
+	started := make(chan struct{}, 2)
+	canceled := make(chan error, 2)
+	srv := NewServer(opts...)
+	slow := func(ctx context.Context, _ *Request) (any, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return nil, ctx.Err()
+	}
+	require.NoError(t, srv.RegisterFunc("slow", slow))
+	return srv, started, canceled
+}
+
+// cancelAfter cancels the returned context once n handlers have started.
+func cancelAfter(t *testing.T, started <-chan struct{}, n int) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		for range n {
+			<-started
+		}
+		cancel()
+	}()
+	return ctx
+}
+
+func TestCancellation(t *testing.T) {
+	cancelable := map[string][]ServerOption{
+		"Client cancel reaches the handler": {WithCancelMethod(CancelRequestMethod)},
+		"Lazy params are read": {
+			WithCancelMethod(CancelRequestMethod), WithLazyParams(),
+		},
+	}
+	for name, opts := range cancelable {
+		t.Run(name, func(t *testing.T) {
+			srv, started, canceled := newSlowServer(t, opts...)
+			clientEnd, serverEnd := newStreamPair()
+			go func() { _ = srv.ServeStream(context.Background(), serverEnd) }()
+
+			client := NewStreamClient(clientEnd, WithCancelNotification(CancelRequestMethod))
+			defer client.Close()
+
+			err := client.Call(cancelAfter(t, started, 1), "slow", nil, nil)
+			require.ErrorIs(t, err, context.Canceled)
+
+			select {
+			case err := <-canceled:
+				require.ErrorIs(t, err, context.Canceled)
+			case <-time.After(time.Second):
+				t.Fatal("handler context was not canceled")
+			}
+		})
+	}
+
+	t.Run("Batch members are canceled", func(t *testing.T) {
+		srv, started, canceled := newSlowServer(t, WithCancelMethod(CancelRequestMethod))
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(context.Background(), serverEnd) }()
+
+		client := NewStreamClient(clientEnd, WithCancelNotification(CancelRequestMethod))
+		defer client.Close()
+
+		reqs := []*Request{NewRequestWithID("slow", nil, "a"), NewRequestWithID("slow", nil, "b")}
+		_, err := client.CallBatch(cancelAfter(t, started, len(reqs)), reqs)
+		require.ErrorIs(t, err, context.Canceled)
+
+		for range reqs {
+			select {
+			case err := <-canceled:
+				require.ErrorIs(t, err, context.Canceled)
+			case <-time.After(time.Second):
+				t.Fatal("batch member context was not canceled")
+			}
+		}
+	})
+
+	t.Run("Cancel notification is consumed by the server", func(t *testing.T) {
+		srv := NewServer(WithCancelMethod(CancelRequestMethod))
+		called := false
+		handle := func(context.Context, *Request) (any, error) {
+			called = true
+			return nil, nil
+		}
+		require.NoError(t, srv.RegisterFunc(CancelRequestMethod, handle))
+
+		note := NewNotification(CancelRequestMethod, map[string]any{"id": 1})
+		assert.Nil(t, srv.HandleRequest(withInflight(context.Background()), note))
+		assert.False(t, called)
+	})
+
+	t.Run("Servers without a cancel method ignore the notification", func(t *testing.T) {
+		srv, started, canceled := newSlowServer(t)
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(context.Background(), serverEnd) }()
+
+		client := NewStreamClient(clientEnd, WithCancelNotification(CancelRequestMethod))
+
+		err := client.Call(cancelAfter(t, started, 1), "slow", nil, nil)
+		require.ErrorIs(t, err, context.Canceled)
+
+		select {
+		case <-canceled:
+			t.Fatal("handler should not have been canceled")
+		case <-time.After(50 * time.Millisecond):
+		}
+		require.NoError(t, client.Close())
+	})
+}
+
+func TestTrackInflight(t *testing.T) {
+	t.Run("No registry", func(t *testing.T) {
+		ctx := context.Background()
+		reqCtx, done := trackInflight(ctx, 1)
+		defer done()
+		assert.Equal(t, ctx, reqCtx)
+	})
+
+	t.Run("Cancel by ID", func(t *testing.T) {
+		ctx := withInflight(context.Background())
+		reqCtx, done := trackInflight(ctx, int64(7))
+		defer done()
+
+		// Decoded params carry numbers as float64
+		cancelInflight(ctx, NewNotification(CancelRequestMethod, map[string]any{"id": float64(7)}))
+		require.ErrorIs(t, reqCtx.Err(), context.Canceled)
+	})
+
+	t.Run("Done unregisters", func(t *testing.T) {
+		ctx := withInflight(context.Background())
+		_, done := trackInflight(ctx, "x")
+		done()
+
+		reg, ok := ctx.Value(inflightContextKey{}).(*inflightRequests)
+		require.True(t, ok)
+		assert.Empty(t, reg.cancels)
+	})
+}
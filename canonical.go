@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// CanonicalJSON returns the canonical form of the JSON data, as defined by the JSON
+// Canonicalization Scheme of RFC 8785: object members sorted by key, no insignificant
+// whitespace, minimal string escapes, and numbers in the shortest form of their IEEE 754 double.
+// Equal values have identical canonical forms, so that messages can be hashed and signed
+// deterministically, and a received message verified by canonicalizing it again. Numbers beyond
+// the range of doubles cannot be canonicalized, and integers beyond 2^53 lose precision, so such
+// values are best sent as strings.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	return appendCanonical(nil, data)
+}
+
+// appendCanonical appends the canonical form of the JSON data to dst.
+func appendCanonical(dst, data []byte) ([]byte, error) {
+	value, err := jsonvalue.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return value.AppendCanonical(dst)
+}
+
+// WithCanonicalJSON makes the server write its replies and pushed notifications as canonical
+// JSON, as described by CanonicalJSON, such as for middleware signing response bodies or for
+// audit logs hashing them. Messages holding numbers that cannot be canonicalized are written as
+// they are.
+func WithCanonicalJSON() ServerOption {
+	return func(s *Server) {
+		s.canonical = true
+	}
+}
+
+// WithClientCanonicalJSON makes the client send its requests, notifications, and batches as
+// canonical JSON, as described by CanonicalJSON, so that transports, authenticators, and
+// interceptors of the wire format can sign or hash the exact bytes sent. Calls whose params
+// cannot be canonicalized fail.
+func WithClientCanonicalJSON() ClientOption {
+	return func(c *Client) {
+		c.canonical = true
+	}
+}
+
+// canonicalReply replaces the reply appended to dst with its canonical form, leaving it as is if
+// it cannot be canonicalized.
+func canonicalReply(dst, reply []byte) []byte {
+	if len(reply) == len(dst) {
+		return reply
+	}
+	canonical, err := appendCanonical(dst, reply[len(dst):])
+	if err != nil {
+		return reply
+	}
+	return canonical
+}
@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	a, err := CanonicalJSON([]byte(`{ "method": "m", "params": {"b": 1.50, "a": [1e3, "é"]},
+		"jsonrpc": "2.0", "id": 1 }`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"jsonrpc":"2.0","method":"m","params":{"a":[1000,"é"],"b":1.5}}`,
+		string(a))
+
+	b, err := CanonicalJSON([]byte(`{"jsonrpc":"2.0","id":1.0,"params":{"a":[1000,"é"],"b":1.5},` +
+		`"method":"m"}`))
+	require.NoError(t, err)
+	assert.Equal(t, a, b, "equal messages canonicalize identically")
+
+	_, err = CanonicalJSON([]byte(`{`))
+	assert.Error(t, err)
+	_, err = CanonicalJSON([]byte(`[1e999]`))
+	assert.Error(t, err)
+}
+
+func TestServer_CanonicalJSON(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(WithCanonicalJSON())
+	require.NoError(t, srv.RegisterFunc("user", func(context.Context, *Request) (any, error) {
+		return map[string]any{"name": "a", "age": 3.0, "tags": []string{}}, nil
+	}))
+	require.NoError(t, srv.RegisterFunc("huge", func(context.Context, *Request) (any, error) {
+		return json.RawMessage(`1e999`), nil
+	}))
+
+	t.Run("Replies", func(t *testing.T) {
+		reply := srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"user","id":1}`))
+		assert.Equal(t, `{"id":1,"jsonrpc":"2.0","result":{"age":3,"name":"a","tags":[]}}`,
+			string(reply))
+
+		reply = srv.HandleMessage(ctx, []byte(
+			`[{"jsonrpc":"2.0","method":"user","id":1},{"jsonrpc":"2.0","method":"x","id":2}]`))
+		assert.Equal(t, `[{"id":1,"jsonrpc":"2.0","result":{"age":3,"name":"a","tags":[]}},`+
+			`{"error":{"code":-32601,"message":"Method not found"},"id":2,"jsonrpc":"2.0"}]`,
+			string(reply))
+	})
+
+	t.Run("Appended to a buffer", func(t *testing.T) {
+		buf := []byte("prefix")
+		reply := srv.AppendMessage(ctx, buf, []byte(`{"jsonrpc":"2.0","method":"user","id":1}`))
+		assert.Equal(t, `prefix{"id":1,"jsonrpc":"2.0","result":{"age":3,"name":"a","tags":[]}}`,
+			string(reply))
+		assert.Equal(t, buf, srv.AppendMessage(ctx, buf, []byte(`{"jsonrpc":"2.0","method":"x"}`)))
+	})
+
+	t.Run("Uncanonicalizable replies are written as they are", func(t *testing.T) {
+		reply := srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"huge","id":1}`))
+		assert.Contains(t, string(reply), `1e999`)
+	})
+
+	t.Run("Pushed notifications", func(t *testing.T) {
+		conns := make(chan *Conn, 1)
+		pushSrv := NewServer(WithCanonicalJSON())
+		watch := func(ctx context.Context, _ *Request) (any, error) {
+			conn, _ := ConnFromContext(ctx)
+			conns <- conn
+			return true, nil
+		}
+		require.NoError(t, pushSrv.RegisterFunc("watch", watch))
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = pushSrv.ServeStream(ctx, serverEnd) }()
+		require.NoError(t, clientEnd.WriteMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","method":"watch","id":1}`)))
+		reply, err := clientEnd.ReadMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":1,"jsonrpc":"2.0","result":true}`, string(reply))
+
+		require.NoError(t, (<-conns).Notify("event", map[string]any{"z": 1, "a": 2}))
+		msg, err := clientEnd.ReadMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, `{"jsonrpc":"2.0","method":"event","params":{"a":2,"z":1}}`, string(msg))
+	})
+}
+
+func TestClient_CanonicalJSON(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var sent []string
+	roundTrip := func(_ context.Context, payload []byte) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, string(payload))
+		return []byte(`{"jsonrpc":"2.0","result":true,"id":1}`), nil
+	}
+	client := NewClient(&funcTransport{fn: roundTrip}, WithClientCanonicalJSON(),
+		WithIDGenerator(IDGeneratorFunc(func() any { return int64(1) })))
+	defer client.Close()
+
+	require.NoError(t, client.Call(ctx, "m", map[string]any{"b": 2.50, "a": 1}, nil))
+	require.NoError(t, client.Notify(ctx, "n", []any{1e21}))
+	assert.Equal(t, []string{
+		`{"id":1,"jsonrpc":"2.0","method":"m","params":{"a":1,"b":2.5}}`,
+		`{"jsonrpc":"2.0","method":"n","params":[1e+21]}`,
+	}, sent)
+
+	err := client.Call(ctx, "m", json.RawMessage(`[1e999]`), nil)
+	assert.ErrorContains(t, err, "canonicalize request")
+
+	t.Run("Streams", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		stream := NewStreamClient(clientEnd, WithClientCanonicalJSON())
+		defer stream.Close()
+		require.NoError(t, stream.Notify(ctx, "n", map[string]any{"b": true, "a": nil}))
+		readCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		msg, err := serverEnd.ReadMessage(readCtx)
+		require.NoError(t, err)
+		assert.Equal(t, `{"jsonrpc":"2.0","method":"n","params":{"a":null,"b":true}}`, string(msg))
+	})
+}
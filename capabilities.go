@@ -0,0 +1,201 @@
+package jsonrpc
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+)
+
+// CapabilitiesMethod is the method under which WithCapabilities serves the capability handshake.
+// Its params and result are the Capabilities of the calling and the called end.
+const CapabilitiesMethod = "rpc.capabilities"
+
+// Capabilities describes the protocol extensions and limits an end of a connection supports, as
+// advertised in the capability handshake. Zero fields advertise nothing, or no limit.
+type Capabilities struct {
+	// Extensions names further extensions supported, by convention as "vendor.feature", for
+	// applications to agree on.
+	Extensions []string `json:"extensions,omitempty"`
+
+	// CancelMethod is the cancellation notification accepted, as set with WithCancelMethod, or
+	// sent, as set with WithCancelNotification.
+	CancelMethod string `json:"cancelMethod,omitempty"`
+
+	// Compression lists the content codings accepted, in order of preference.
+	Compression []string `json:"compression,omitempty"`
+
+	// MaxBatchSize is the number of members a batch may hold.
+	MaxBatchSize int `json:"maxBatchSize,omitempty"`
+
+	// MaxMessageSize is the size in bytes a message may have.
+	MaxMessageSize int `json:"maxMessageSize,omitempty"`
+}
+
+// Has reports whether extension is among the extensions of c.
+func (c Capabilities) Has(extension string) bool {
+	return slices.Contains(c.Extensions, extension)
+}
+
+// Negotiate returns the capabilities both c and other support: the extensions and codings of c
+// that other also lists, in the order of c, the cancellation method if both use the same, and
+// the lower of each limit.
+func (c Capabilities) Negotiate(other Capabilities) Capabilities {
+	agreed := Capabilities{
+		Extensions:     common(c.Extensions, other.Extensions),
+		Compression:    common(c.Compression, other.Compression),
+		MaxBatchSize:   minLimit(c.MaxBatchSize, other.MaxBatchSize),
+		MaxMessageSize: minLimit(c.MaxMessageSize, other.MaxMessageSize),
+	}
+	if c.CancelMethod == other.CancelMethod {
+		agreed.CancelMethod = c.CancelMethod
+	}
+	return agreed
+}
+
+// common returns the elements of ours also in theirs, in the order of ours.
+func common(ours, theirs []string) []string {
+	var both []string
+	for _, v := range ours {
+		if slices.Contains(theirs, v) {
+			both = append(both, v)
+		}
+	}
+	return both
+}
+
+// minLimit returns the lower of two limits, zero meaning no limit.
+func minLimit(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// WithCapabilities serves the capability handshake under CapabilitiesMethod, answering caps. The
+// cancellation method and limits left zero in caps are those the server is configured with, and
+// the codings of WithCompression are listed if caps lists none. On a stream connection, the
+// capabilities agreed with the calling end are kept for handlers to query with
+// CapabilitiesFromContext, until the end repeats the handshake. The method goes through
+// middleware and authorization like the registered ones.
+func WithCapabilities(caps Capabilities) ServerOption {
+	return func(s *Server) {
+		s.registerBuiltin(CapabilitiesMethod, func(ctx context.Context, req *Request) (any, error) {
+			var remote Capabilities
+			if req.Params != nil {
+				if err := req.UnmarshalParams(&remote); err != nil {
+					return nil, ErrInvalidParams.WithData(err.Error())
+				}
+			}
+			local := s.capabilities(caps)
+			if conn, ok := ConnFromContext(ctx); ok {
+				agreed := local.Negotiate(remote)
+				conn.capabilities.Store(&agreed)
+			}
+			return local, nil
+		})
+	}
+}
+
+// capabilities returns caps completed with the configuration of the server.
+func (s *Server) capabilities(caps Capabilities) Capabilities {
+	if caps.CancelMethod == "" {
+		caps.CancelMethod = s.cancelMethod
+	}
+	if caps.Compression == nil && s.compression != nil {
+		caps.Compression = []string{codingGzip, codingDeflate}
+	}
+	if caps.MaxBatchSize == 0 {
+		caps.MaxBatchSize = s.limits.maxBatchSize
+	}
+	if caps.MaxMessageSize == 0 {
+		caps.MaxMessageSize = s.limits.maxMessageSize
+	}
+	return caps
+}
+
+// CapabilitiesFromContext returns the capabilities agreed on the stream connection of the current
+// request, from the context of a handler. It returns false outside handlers of stream requests
+// and until the remote end has performed the handshake.
+func CapabilitiesFromContext(ctx context.Context) (Capabilities, bool) {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return Capabilities{}, false
+	}
+	return conn.Capabilities()
+}
+
+// Capabilities returns the capabilities agreed on the connection, or false until the remote end
+// has performed the handshake.
+func (c *Conn) Capabilities() (Capabilities, bool) {
+	agreed := c.capabilities.Load()
+	if agreed == nil {
+		return Capabilities{}, false
+	}
+	return *agreed, true
+}
+
+// negotiation holds the capabilities a client offered in the handshake and those agreed.
+type negotiation struct {
+	offered Capabilities
+	agreed  atomic.Pointer[Capabilities]
+}
+
+// Negotiate performs the capability handshake with the server, offering caps, and returns the
+// capabilities both ends support, also returned by Capabilities from then on. The cancellation
+// method and limits left zero in caps are those the client is configured with. A stream client
+// repeats the handshake on every reconnection, as the new connection may reach another server;
+// until it completes, Capabilities reports none. Servers without WithCapabilities answer
+// ErrMethodNotFound.
+func (c *Client) Negotiate(ctx context.Context, caps Capabilities) (Capabilities, error) {
+	offered := caps
+	if offered.CancelMethod == "" {
+		offered.CancelMethod = c.cancelMethod
+	}
+	if offered.MaxBatchSize == 0 {
+		offered.MaxBatchSize = c.limits.maxBatchSize
+	}
+	if offered.MaxMessageSize == 0 {
+		offered.MaxMessageSize = c.limits.maxMessageSize
+	}
+	n := &negotiation{offered: offered}
+	c.negotiation.Store(n)
+	return c.handshake(ctx, n)
+}
+
+// handshake performs the capability handshake of n.
+func (c *Client) handshake(ctx context.Context, n *negotiation) (Capabilities, error) {
+	var remote Capabilities
+	if err := c.Call(ctx, CapabilitiesMethod, n.offered, &remote); err != nil {
+		return Capabilities{}, err
+	}
+	agreed := n.offered.Negotiate(remote)
+	n.agreed.Store(&agreed)
+	return agreed, nil
+}
+
+// Capabilities returns the capabilities agreed by the last handshake of Negotiate, or false if
+// there was none or it failed.
+func (c *Client) Capabilities() (Capabilities, bool) {
+	n := c.negotiation.Load()
+	if n == nil {
+		return Capabilities{}, false
+	}
+	agreed := n.agreed.Load()
+	if agreed == nil {
+		return Capabilities{}, false
+	}
+	return *agreed, true
+}
+
+// renegotiate forgets the capabilities agreed on a dropped connection and repeats the handshake
+// in the background, if Negotiate was called.
+func (c *Client) renegotiate() {
+	n := c.negotiation.Load()
+	if n == nil {
+		return
+	}
+	n.agreed.Store(nil)
+	go func() {
+		_, _ = c.handshake(c.ctx, n)
+	}()
+}
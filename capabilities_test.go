@@ -0,0 +1,163 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities_Negotiate(t *testing.T) {
+	tests := []struct {
+		name        string
+		ours, their Capabilities
+		want        Capabilities
+	}{
+		{
+			name:  "Common extensions in our order",
+			ours:  Capabilities{Extensions: []string{"a.x", "b.y", "c.z"}},
+			their: Capabilities{Extensions: []string{"c.z", "a.x"}},
+			want:  Capabilities{Extensions: []string{"a.x", "c.z"}},
+		},
+		{
+			name:  "Lower limits, zero meaning none",
+			ours:  Capabilities{MaxBatchSize: 10, MaxMessageSize: 0},
+			their: Capabilities{MaxBatchSize: 20, MaxMessageSize: 4096},
+			want:  Capabilities{MaxBatchSize: 10, MaxMessageSize: 4096},
+		},
+		{
+			name:  "Same cancellation method",
+			ours:  Capabilities{CancelMethod: CancelRequestMethod, Compression: []string{"gzip"}},
+			their: Capabilities{CancelMethod: CancelRequestMethod},
+			want:  Capabilities{CancelMethod: CancelRequestMethod},
+		},
+		{
+			name:  "Different cancellation methods",
+			ours:  Capabilities{CancelMethod: CancelRequestMethod},
+			their: Capabilities{CancelMethod: "cancel"},
+			want:  Capabilities{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.ours.Negotiate(tt.their))
+		})
+	}
+
+	t.Run("Has", func(t *testing.T) {
+		caps := Capabilities{Extensions: []string{"a.x"}}
+		assert.True(t, caps.Has("a.x"))
+		assert.False(t, caps.Has("b.y"))
+	})
+}
+
+func TestServer_WithCapabilities(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(
+		WithCapabilities(Capabilities{Extensions: []string{"acme.trace", "acme.stats"}}),
+		WithCancelMethod(CancelRequestMethod),
+		WithMaxBatchSize(50),
+		WithCompression(Compression{}),
+	)
+	agreed := func(ctx context.Context, _ *Request) (any, error) {
+		caps, ok := CapabilitiesFromContext(ctx)
+		if !ok {
+			return nil, nil
+		}
+		return caps, nil
+	}
+	require.NoError(t, srv.RegisterFunc("agreed", agreed))
+
+	// dial serves a new connection with srv.
+	dial := func(context.Context) (Stream, error) {
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		return clientEnd, nil
+	}
+
+	t.Run("Agrees on capabilities with handlers", func(t *testing.T) {
+		stream, err := dial(ctx)
+		require.NoError(t, err)
+		client := NewStreamClient(stream,
+			WithCancelNotification(CancelRequestMethod),
+			WithClientMaxBatchSize(100))
+		defer client.Close()
+
+		var before *Capabilities
+		require.NoError(t, client.Call(ctx, "agreed", nil, &before))
+		assert.Nil(t, before)
+		_, ok := client.Capabilities()
+		assert.False(t, ok)
+
+		agreed, err := client.Negotiate(ctx, Capabilities{Extensions: []string{"acme.stats"}})
+		require.NoError(t, err)
+		want := Capabilities{
+			Extensions:   []string{"acme.stats"},
+			CancelMethod: CancelRequestMethod,
+			MaxBatchSize: 50,
+		}
+		assert.Equal(t, want, agreed)
+		got, ok := client.Capabilities()
+		require.True(t, ok)
+		assert.Equal(t, want, got)
+
+		var served Capabilities
+		require.NoError(t, client.Call(ctx, "agreed", nil, &served))
+		assert.Equal(t, want, served)
+	})
+
+	t.Run("Advertises the server's configuration", func(t *testing.T) {
+		client := NewClient(&funcTransport{fn: func(_ context.Context, msg []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, msg), nil
+		}})
+		var advertised Capabilities
+		require.NoError(t, client.Call(ctx, CapabilitiesMethod, nil, &advertised))
+		assert.Equal(t, Capabilities{
+			Extensions:   []string{"acme.trace", "acme.stats"},
+			CancelMethod: CancelRequestMethod,
+			Compression:  []string{codingGzip, codingDeflate},
+			MaxBatchSize: 50,
+		}, advertised)
+	})
+
+	t.Run("Negotiates again after reconnecting", func(t *testing.T) {
+		stream, err := dial(ctx)
+		require.NoError(t, err)
+		reconnected := make(chan struct{}, 1)
+		client := NewStreamClient(stream, WithReconnect(dial, ReconnectPolicy{
+			InitialBackoff: time.Millisecond,
+			OnReconnect:    func(ReconnectEvent) { reconnected <- struct{}{} },
+		}))
+		defer client.Close()
+		_, err = client.Negotiate(ctx, Capabilities{Extensions: []string{"acme.trace"}})
+		require.NoError(t, err)
+
+		_ = stream.Close()
+		select {
+		case <-reconnected:
+		case <-time.After(time.Second):
+			t.Fatal("client did not reconnect")
+		}
+		require.Eventually(t, func() bool {
+			var served *Capabilities
+			err := client.Call(ctx, "agreed", nil, &served)
+			got, ok := client.Capabilities()
+			return err == nil && served != nil && served.Has("acme.trace") && ok &&
+				got.Has("acme.trace")
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("Fails against a server without the handshake", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = NewServer().ServeStream(ctx, serverEnd) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		_, err := client.Negotiate(ctx, Capabilities{})
+		require.ErrorIs(t, err, ErrMethodNotFound)
+		_, ok := client.Capabilities()
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,450 @@
+// Package cbor provides a CBOR (RFC 8949) wire encoding for the jsonrpc package. Messages keep
+// the JSON-RPC envelope and are transcoded between JSON and CBOR at the transport, which cuts
+// their size on high-volume links without changing handlers or clients:
+//
+//	srv := jsonrpc.NewServer(jsonrpc.WithEncodings(cbor.Encoding{}))
+//	transport := jsonrpc.NewHTTPTransport(url, jsonrpc.WithHTTPEncoding(cbor.Encoding{}))
+//
+// Encoding uses definite lengths, the shortest integer heads, and float32 when lossless.
+// Decoding accepts indefinite lengths and half-precision floats; tags are skipped in favor of
+// their content, byte strings become base64 strings, undefined becomes null, and map keys must
+// be text strings.
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// revive:disable:modifies-parameter encoders append to dst
+
+// ContentType is the media type of CBOR messages.
+const ContentType = "application/cbor"
+
+// Encoding is the CBOR jsonrpc.Encoding.
+type Encoding struct{}
+
+var _ jsonrpc.Encoding = Encoding{}
+
+// ContentType implements jsonrpc.Encoding.
+func (Encoding) ContentType() string {
+	return ContentType
+}
+
+// Encode implements jsonrpc.Encoding.
+func (Encoding) Encode(msg []byte) ([]byte, error) {
+	return FromJSON(msg)
+}
+
+// Decode implements jsonrpc.Encoding.
+func (Encoding) Decode(data []byte) ([]byte, error) {
+	return ToJSON(data)
+}
+
+// Major types, per RFC 8949.
+const (
+	majorUint   byte = 0
+	majorNegInt byte = 1
+	majorBytes  byte = 2
+	majorText   byte = 3
+	majorArray  byte = 4
+	majorMap    byte = 5
+	majorTag    byte = 6
+	majorSimple byte = 7
+)
+
+// Additional information values of the initial byte.
+const (
+	infoUint8      = 24
+	infoUint16     = 25
+	infoUint32     = 26
+	infoUint64     = 27
+	infoIndefinite = 31
+	infoMask       = 0x1f
+	majorShift     = 5
+
+	// byteBits is the width of a byte, for assembling arguments.
+	byteBits = 8
+)
+
+// Simple values, as additional information of majorSimple.
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	simpleFloat16   = infoUint16
+	simpleFloat32   = infoUint32
+	simpleFloat64   = infoUint64
+)
+
+// breakByte ends items of indefinite length.
+const breakByte = 0xff
+
+// FromJSON transcodes a JSON message into CBOR.
+func FromJSON(msg []byte) ([]byte, error) {
+	v, err := jsonvalue.Parse(msg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	return appendValue(make([]byte, 0, len(msg)), v)
+}
+
+// appendValue appends the CBOR encoding of v to dst.
+func appendValue(dst []byte, v jsonvalue.Value) ([]byte, error) {
+	var err error
+	switch v.Kind {
+	case jsonvalue.Bool:
+		if v.Bool {
+			return append(dst, majorSimple<<majorShift|simpleTrue), nil
+		}
+		return append(dst, majorSimple<<majorShift|simpleFalse), nil
+	case jsonvalue.Number:
+		return appendNumber(dst, v)
+	case jsonvalue.String:
+		return append(appendHead(dst, majorText, uint64(len(v.Text))), v.Text...), nil
+	case jsonvalue.Array:
+		dst = appendHead(dst, majorArray, uint64(len(v.Items)))
+		for _, item := range v.Items {
+			if dst, err = appendValue(dst, item); err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	case jsonvalue.Object:
+		dst = appendHead(dst, majorMap, uint64(len(v.Members)))
+		for _, member := range v.Members {
+			dst = append(appendHead(dst, majorText, uint64(len(member.Key))), member.Key...)
+			if dst, err = appendValue(dst, member.Value); err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	default:
+		return append(dst, majorSimple<<majorShift|simpleNull), nil
+	}
+}
+
+// appendNumber appends a number as an integer, or as a float if it is not one.
+func appendNumber(dst []byte, v jsonvalue.Value) ([]byte, error) {
+	if n, ok := v.AsInt(); ok {
+		if n < 0 {
+			return appendHead(dst, majorNegInt, uint64(-(n + 1))), nil
+		}
+		return appendHead(dst, majorUint, uint64(n)), nil
+	}
+	if n, ok := v.AsUint(); ok {
+		return appendHead(dst, majorUint, n), nil
+	}
+	f, err := v.AsFloat()
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q: %w", v.Text, err)
+	}
+	if f32 := float32(f); float64(f32) == f {
+		dst = append(dst, majorSimple<<majorShift|simpleFloat32)
+		return binary.BigEndian.AppendUint32(dst, math.Float32bits(f32)), nil
+	}
+	dst = append(dst, majorSimple<<majorShift|simpleFloat64)
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(f)), nil
+}
+
+// appendHead appends the initial byte of major type major with argument n, in its shortest form.
+func appendHead(dst []byte, major byte, n uint64) []byte {
+	initial := major << majorShift
+	switch {
+	case n < infoUint8:
+		return append(dst, initial|byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, initial|infoUint8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, initial|infoUint16), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, initial|infoUint32), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, initial|infoUint64), n)
+	}
+}
+
+// ToJSON transcodes a CBOR message into JSON.
+func ToJSON(data []byte) ([]byte, error) {
+	d := &decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("trailing data after message")
+	}
+	return v.AppendJSON(make([]byte, 0, len(data)+len(data)/2)), nil
+}
+
+var (
+	// errTruncated is returned for messages ending in the middle of a value.
+	errTruncated = errors.New("unexpected end of message")
+
+	// errBreak is returned for a break outside of an item of indefinite length.
+	errBreak = errors.New("unexpected break")
+)
+
+// decoder reads CBOR values from data.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// next consumes n bytes.
+func (d *decoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errTruncated
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// header is the initial byte of a data item and its argument.
+type header struct {
+	major byte
+	info  byte
+	arg   uint64
+}
+
+// indefinite reports whether the item has an indefinite length.
+func (h header) indefinite() bool {
+	return h.info == infoIndefinite
+}
+
+// count returns the number of elements of the item, for strings, arrays, and maps.
+func (h header) count() *count {
+	return &count{left: h.arg, indefinite: h.indefinite()}
+}
+
+// count tracks the elements left to read of a string, array, or map.
+type count struct {
+	left       uint64
+	indefinite bool
+}
+
+// head reads an initial byte and its argument.
+func (d *decoder) head() (header, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return header{}, err
+	}
+	h := header{major: b[0] >> majorShift, info: b[0] & infoMask}
+	switch {
+	case h.info < infoUint8:
+		h.arg = uint64(h.info)
+		return h, nil
+	case h.info <= infoUint64:
+		raw, err := d.next(1 << (h.info - infoUint8))
+		if err != nil {
+			return header{}, err
+		}
+		for _, c := range raw {
+			h.arg = h.arg<<byteBits | uint64(c)
+		}
+		return h, nil
+	case h.indefinite():
+		return h, nil
+	default:
+		return header{}, fmt.Errorf("invalid cbor additional information %d", h.info)
+	}
+}
+
+// value decodes the next value.
+func (d *decoder) value(depth int) (jsonvalue.Value, error) {
+	if depth >= jsonvalue.MaxDepth {
+		return jsonvalue.Value{}, jsonvalue.ErrTooDeep
+	}
+	h, err := d.head()
+	if err != nil {
+		return jsonvalue.Value{}, err
+	}
+	if (h.major < majorBytes || h.major == majorTag) && h.indefinite() {
+		return jsonvalue.Value{}, fmt.Errorf("invalid indefinite length for major type %d", h.major)
+	}
+
+	switch h.major {
+	case majorUint:
+		return jsonvalue.Uint(h.arg), nil
+	case majorNegInt:
+		return jsonvalue.NegativeUint(h.arg), nil
+	case majorBytes:
+		raw, err := d.str(majorBytes, h.count())
+		return jsonvalue.Bytes(raw), err
+	case majorText:
+		raw, err := d.str(majorText, h.count())
+		if err == nil && !utf8.Valid(raw) {
+			err = errors.New("invalid utf-8 in text string")
+		}
+		return jsonvalue.Str(string(raw)), err
+	case majorArray:
+		return d.array(h.count(), depth)
+	case majorMap:
+		return d.object(h.count(), depth)
+	case majorTag:
+		return d.value(depth + 1)
+	default:
+		return simple(h)
+	}
+}
+
+// simple decodes a simple value or float.
+func simple(h header) (jsonvalue.Value, error) {
+	switch h.info {
+	case simpleFalse, simpleTrue:
+		return jsonvalue.Boolean(h.info == simpleTrue), nil
+	case simpleNull, simpleUndefined:
+		return jsonvalue.Value{}, nil
+	case simpleFloat16:
+		return jsonvalue.Float(halfToFloat(uint16(h.arg)))
+	case simpleFloat32:
+		return jsonvalue.Float(float64(math.Float32frombits(uint32(h.arg))))
+	case simpleFloat64:
+		return jsonvalue.Float(math.Float64frombits(h.arg))
+	case infoIndefinite:
+		return jsonvalue.Value{}, errBreak
+	default:
+		return jsonvalue.Value{}, fmt.Errorf("unsupported cbor simple value %d", h.arg)
+	}
+}
+
+// str decodes the content of a byte or text string, joining the chunks of indefinite ones.
+func (d *decoder) str(major byte, c *count) ([]byte, error) {
+	if !c.indefinite {
+		return d.next(c.left)
+	}
+	var joined []byte
+	for {
+		more, err := d.more(c)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return joined, nil
+		}
+		chunk, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunk.major != major || chunk.indefinite() {
+			return nil, errors.New("invalid chunk in indefinite length string")
+		}
+		raw, err := d.next(chunk.arg)
+		if err != nil {
+			return nil, err
+		}
+		joined = append(joined, raw...)
+	}
+}
+
+// more reports whether another element follows, counting it. The break ending an indefinite
+// length item is consumed.
+func (d *decoder) more(c *count) (bool, error) {
+	if !c.indefinite {
+		if c.left == 0 {
+			return false, nil
+		}
+		c.left--
+		return true, nil
+	}
+	if d.pos >= len(d.data) {
+		return false, errTruncated
+	}
+	if d.data[d.pos] != breakByte {
+		return true, nil
+	}
+	d.pos++
+	return false, nil
+}
+
+// capacity bounds the capacity allocated for the elements of c by the bytes left, as each
+// element takes at least one.
+func (d *decoder) capacity(c *count) int {
+	return int(min(c.left, uint64(len(d.data)-d.pos)))
+}
+
+// array decodes the elements of an array.
+func (d *decoder) array(c *count, depth int) (jsonvalue.Value, error) {
+	v := jsonvalue.Value{Kind: jsonvalue.Array, Items: make([]jsonvalue.Value, 0, d.capacity(c))}
+	for {
+		more, err := d.more(c)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		if !more {
+			return v, nil
+		}
+		item, err := d.value(depth + 1)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		v.Items = append(v.Items, item)
+	}
+}
+
+// object decodes the members of a map, whose keys must be text strings.
+func (d *decoder) object(c *count, depth int) (jsonvalue.Value, error) {
+	members := make([]jsonvalue.Member, 0, d.capacity(c))
+	for {
+		more, err := d.more(c)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		if !more {
+			return jsonvalue.Value{Kind: jsonvalue.Object, Members: members}, nil
+		}
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		if key.Kind != jsonvalue.String {
+			return jsonvalue.Value{}, errors.New("map key is not a text string")
+		}
+		member, err := d.value(depth + 1)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		members = append(members, jsonvalue.Member{Key: key.Text, Value: member})
+	}
+}
+
+// Layout of half-precision floats.
+const (
+	halfSignMask     = 0x8000
+	halfExponentBits = 10
+	halfExponentMask = 0x1f
+	halfMantissaMask = 0x3ff
+	halfImplicitBit  = 0x400
+	halfExponentBias = 25
+	halfSubnormalExp = -24
+)
+
+// halfToFloat converts a half-precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>halfExponentBits) & halfExponentMask
+	mant := float64(h & halfMantissaMask)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, halfSubnormalExp)
+	case halfExponentMask:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+halfImplicitBit, exp-halfExponentBias)
+	}
+	if h&halfSignMask != 0 {
+		return -f
+	}
+	return f
+}
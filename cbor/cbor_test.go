@@ -0,0 +1,146 @@
+package cbor
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// decodeHex decodes a hex string, failing the test if it is invalid.
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return data
+}
+
+func TestFromJSON(t *testing.T) {
+	// Examples from RFC 8949, Appendix A
+	cases := map[string]string{
+		`0`:                    "00",
+		`23`:                   "17",
+		`24`:                   "1818",
+		`1000`:                 "1903e8",
+		`-1000`:                "3903e7",
+		`18446744073709551615`: "1bffffffffffffffff",
+		`1.5`:                  "fa3fc00000",
+		`1.1`:                  "fb3ff199999999999a",
+		`[true,false,null]`:    "83f5f4f6",
+		`[1,[2,3],[4,5]]`:      "8301820203820405",
+		`{"a":1,"b":[2,3]}`:    "a26161016162820203",
+		`"IETF"`:               "6449455446",
+	}
+	for input, want := range cases {
+		got, err := FromJSON([]byte(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, want, hex.EncodeToString(got), input)
+	}
+
+	_, err := FromJSON([]byte(`[1,`))
+	assert.Error(t, err)
+}
+
+func TestToJSON(t *testing.T) {
+	t.Run("Decodes RFC 8949 examples", func(t *testing.T) {
+		cases := map[string]string{
+			"3bffffffffffffffff":         `-18446744073709551616`,
+			"f93c00":                     `1`,
+			"f97bff":                     `65504`,
+			"f98001":                     `-5.960464477539063e-08`,
+			"f7":                         `null`,
+			"c11a514b67b0":               `1363896240`,
+			"4401020304":                 `"AQIDBA=="`,
+			"9f018202039f0405ffff":       `[1,[2,3],[4,5]]`,
+			"bf61610161629f0203ffff":     `{"a":1,"b":[2,3]}`,
+			"7f657374726561646d696e67ff": `"streaming"`,
+		}
+		for input, want := range cases {
+			got, err := ToJSON(decodeHex(t, input))
+			require.NoError(t, err, input)
+			assert.Equal(t, want, string(got), input)
+		}
+	})
+
+	t.Run("Round trips messages", func(t *testing.T) {
+		messages := []string{
+			`{"jsonrpc":"2.0","method":"sum","params":[1,-2,3.25],"id":1}`,
+			`{"jsonrpc":"2.0","result":{"name":"x","tags":["a","b"]},"id":"abc"}`,
+			`[{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":null}]`,
+		}
+		for _, msg := range messages {
+			encoded, err := FromJSON([]byte(msg))
+			require.NoError(t, err)
+			assert.Less(t, len(encoded), len(msg))
+
+			decoded, err := ToJSON(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, msg, string(decoded))
+		}
+	})
+
+	t.Run("Rejects invalid messages", func(t *testing.T) {
+		invalid := map[string]string{
+			"empty":                  "",
+			"truncated":              "8201",
+			"trailing data":          "0101",
+			"unterminated":           "9f01",
+			"stray break":            "ff",
+			"non-text key":           "a10102",
+			"infinity":               "f97c00",
+			"invalid utf-8":          "61ff",
+			"reserved information":   "1c",
+			"indefinite integer":     "1f",
+			"mixed string chunks":    "7f4101ff",
+			"dishonest array length": "9affffffff",
+		}
+		for name, data := range invalid {
+			_, err := ToJSON(decodeHex(t, data))
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	srv := jsonrpc.NewServer()
+	echo := func(_ context.Context, req *jsonrpc.Request) (any, error) {
+		return req.Params, nil
+	}
+	require.NoError(t, srv.RegisterFunc("echo", echo))
+	t.Run("Framed stream", func(t *testing.T) {
+		serverConn, clientConn := net.Pipe()
+		serverStream := jsonrpc.NewFramedStream(serverConn, serverConn, jsonrpc.HeaderFraming)
+		go func() {
+			encoded := jsonrpc.NewEncodedStream(serverStream, Encoding{})
+			_ = srv.ServeStream(context.Background(), encoded)
+		}()
+
+		clientStream := jsonrpc.NewFramedStream(clientConn, clientConn, jsonrpc.HeaderFraming)
+		client := jsonrpc.NewStreamClient(jsonrpc.NewEncodedStream(clientStream, Encoding{}))
+		defer client.Close()
+
+		var got map[string]any
+		params := map[string]any{"a": []any{1.0, "b"}, "c": nil}
+		require.NoError(t, client.Call(context.Background(), "echo", params, &got))
+		assert.Equal(t, params, got)
+	})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	t.Run("HTTP without server support falls back to an error", func(t *testing.T) {
+		transport := jsonrpc.NewHTTPTransport(ts.URL, jsonrpc.WithHTTPEncoding(Encoding{}))
+		client := jsonrpc.NewClient(transport)
+		defer client.Close()
+
+		var httpErr *jsonrpc.HTTPError
+		require.ErrorAs(t, client.Call(context.Background(), "echo", nil, nil), &httpErr)
+		assert.Equal(t, 415, httpErr.StatusCode)
+	})
+}
@@ -0,0 +1,350 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ChunkMethod is the method of the notifications carrying the chunks of chunked results.
+const ChunkMethod = "$/chunk"
+
+// resultChunkSize is the number of payload bytes sent per chunk of chunked results.
+const resultChunkSize = 64 << 10
+
+var (
+	// errChunksUnsupported is returned for chunked results outside stream connections.
+	errChunksUnsupported = errors.New("chunked results require a stream connection")
+
+	// errNotChunked is returned for methods answering a chunked call with a plain result.
+	errNotChunked = errors.New("method did not stream its result in chunks")
+
+	// errChunkSequence is returned for chunks arriving out of sequence.
+	errChunkSequence = errors.New("chunk out of sequence")
+)
+
+// chunkFrame is the params of a chunk notification: a piece of the payload of the call with the
+// ID stream, numbered by seq from zero, the final one closing the payload.
+type chunkFrame struct {
+	Stream any    `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Data   []byte `json:"data,omitempty"`
+	Final  bool   `json:"final,omitempty"`
+}
+
+// chunkNotification is a chunk notification as decoded by clients.
+type chunkNotification struct {
+	Method string     `json:"method"`
+	Params chunkFrame `json:"params"`
+}
+
+// chunkSummary is the result of chunked calls, counting the chunks sent, the final one included,
+// so that clients tell them from calls answered with a plain result.
+type chunkSummary struct {
+	Chunks uint64 `json:"chunks"`
+}
+
+// ChunkedHandler returns a handler streaming the payload fn writes to w, such as a multi-GB
+// trace or dump, as a sequence of ChunkMethod notifications, for clients reading it with
+// CallChunked. The response of the call follows once fn returns, after the final chunk unless
+// fn fails.
+//
+// Chunks are written to the stream as they fill up, ahead of the response, so that fn is slowed
+// down to the pace of the peer. Chunked results are only available on stream connections; other
+// requests are answered with an invalid request error.
+func ChunkedHandler(fn func(ctx context.Context, req *Request, w io.Writer) error) Handler {
+	return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+		peer, ok := ClientFromContext(ctx)
+		if !ok || req.IsNotification() {
+			return nil, &Error{
+				Code:    InvalidRequest,
+				Message: msgInvalidRequest,
+				Data:    errChunksUnsupported.Error(),
+			}
+		}
+		w := &chunkWriter{
+			ctx:    ctx,
+			peer:   peer,
+			stream: req.ID,
+			buf:    make([]byte, 0, resultChunkSize),
+		}
+		if err := fn(ctx, req, w); err != nil {
+			return nil, err
+		}
+		if err := w.send(true); err != nil {
+			return nil, err
+		}
+		return chunkSummary{Chunks: w.seq}, nil
+	})
+}
+
+// chunkWriter is the io.Writer of a ChunkedHandler, sending what is written in chunks of up to
+// resultChunkSize bytes.
+type chunkWriter struct {
+	ctx    context.Context
+	peer   *Client
+	stream any
+	seq    uint64
+	buf    []byte
+}
+
+// Write buffers p, sending every chunk filled.
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n := min(len(p)-written, cap(w.buf)-len(w.buf))
+		w.buf = append(w.buf, p[written:written+n]...)
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.send(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// send sends the buffered bytes as the next chunk.
+func (w *chunkWriter) send(final bool) error {
+	frame := chunkFrame{Stream: w.stream, Seq: w.seq, Data: w.buf, Final: final}
+	msg, err := NewNotification(ChunkMethod, frame).MarshalJSON()
+	if err != nil {
+		return err
+	}
+	if err := w.peer.write(w.ctx, msg); err != nil {
+		return err
+	}
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// CallChunked calls a method served by a ChunkedHandler and returns a reader of its payload,
+// reassembled from the chunks as they arrive. The reader returns io.EOF after the final chunk,
+// or the error of the call, such as a JSON-RPC error of the method, once the chunks received
+// before it are read. Closing the reader early drops the chunks still to come and cancels the
+// call, which stops the handler on servers honoring WithCancelNotification.
+//
+// CallChunked returns without waiting for the response, so that the payload can be read while it
+// is being sent. Chunks are queued without bound, like the notifications of subscriptions, so
+// that a slow reader never stalls the connection. The call lasts until ctx is done, and options
+// apply to the call as for Call. CallChunked is only supported by stream clients.
+func (c *Client) CallChunked(
+	ctx context.Context,
+	method string,
+	params any,
+	opts ...CallOption,
+) (io.ReadCloser, error) {
+	if !c.isStream() {
+		return nil, errChunksUnsupported
+	}
+	id := newCallOptions(opts).id
+	if id == nil {
+		id = c.newID()
+	}
+	key := idKey(id)
+	if key == "" {
+		return nil, fmt.Errorf("invalid request id: %v", id)
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	s := &chunkStream{
+		client: c,
+		key:    key,
+		ctx:    callCtx,
+		cancel: cancel,
+		wake:   make(chan struct{}, 1),
+	}
+	if err := c.addChunkStream(s); err != nil {
+		cancel()
+		return nil, err
+	}
+	go s.call(method, params, append(opts, WithCallID(id)))
+	return s, nil
+}
+
+// chunkStream is the reader of a chunked result.
+type chunkStream struct {
+	client *Client
+	key    string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+
+	mu     sync.Mutex
+	chunks [][]byte
+	next   uint64 // Sequence number of the next chunk
+	err    error  // Returned once the chunks are read: io.EOF after the final chunk
+}
+
+// call makes the chunked call, failing the stream with its error or the lack of chunks.
+func (s *chunkStream) call(method string, params any, opts []CallOption) {
+	var summary chunkSummary
+	err := s.client.Call(s.ctx, method, params, &summary, opts...)
+	if err == nil && summary.Chunks == 0 {
+		err = errNotChunked
+	}
+	if err != nil {
+		s.fail(err)
+	}
+}
+
+// Read reads from the chunks received, waiting for more as needed.
+func (s *chunkStream) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		s.mu.Lock()
+		if len(s.chunks) > 0 {
+			n := copy(p, s.chunks[0])
+			if s.chunks[0] = s.chunks[0][n:]; len(s.chunks[0]) == 0 {
+				s.chunks[0] = nil
+				s.chunks = s.chunks[1:]
+			}
+			s.mu.Unlock()
+			return n, nil
+		}
+		err := s.err
+		s.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.ctx.Done():
+			s.fail(s.ctx.Err())
+		case <-s.client.done:
+			s.fail(s.client.terminalErr())
+		}
+	}
+}
+
+// Close stops reading, cancelling the call if it is still in flight.
+func (s *chunkStream) Close() error {
+	s.mu.Lock()
+	s.chunks = nil
+	if s.err == nil || errors.Is(s.err, io.EOF) {
+		s.err = io.ErrClosedPipe
+	}
+	s.mu.Unlock()
+	s.client.removeChunkStream(s)
+	s.cancel()
+	return nil
+}
+
+// push appends the payload of frame, ending the stream after the final chunk.
+func (s *chunkStream) push(frame chunkFrame) {
+	s.mu.Lock()
+	switch {
+	case s.err != nil:
+	case frame.Seq != s.next:
+		s.err = fmt.Errorf("%w: got %d, want %d", errChunkSequence, frame.Seq, s.next)
+	default:
+		s.next++
+		if len(frame.Data) > 0 {
+			s.chunks = append(s.chunks, frame.Data)
+		}
+		if frame.Final {
+			s.err = io.EOF
+		}
+	}
+	ended := s.err != nil
+	s.mu.Unlock()
+
+	if ended {
+		s.client.removeChunkStream(s)
+	}
+	s.signal()
+}
+
+// fail ends the stream with err, unless it has ended already.
+func (s *chunkStream) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.client.removeChunkStream(s)
+	s.signal()
+}
+
+// signal wakes up a waiting Read.
+func (s *chunkStream) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// addChunkStream routes the chunks of the call with the key of s to it.
+func (c *Client) addChunkStream(s *chunkStream) error {
+	c.chunkMu.Lock()
+	defer c.chunkMu.Unlock()
+
+	if _, ok := c.chunks[s.key]; ok {
+		return fmt.Errorf("duplicate chunked call id: %s", s.key)
+	}
+	if c.chunks == nil {
+		c.chunks = make(map[string]*chunkStream)
+	}
+	c.chunks[s.key] = s
+	return nil
+}
+
+// removeChunkStream stops routing chunks to s.
+func (c *Client) removeChunkStream(s *chunkStream) {
+	c.chunkMu.Lock()
+	defer c.chunkMu.Unlock()
+
+	if c.chunks[s.key] == s {
+		delete(c.chunks, s.key)
+	}
+}
+
+// abortChunkStreams ends the chunked results in progress with err, as their chunks are lost
+// with the connection.
+func (c *Client) abortChunkStreams(err error) {
+	c.chunkMu.Lock()
+	streams := make([]*chunkStream, 0, len(c.chunks))
+	for _, s := range c.chunks {
+		streams = append(streams, s)
+	}
+	c.chunkMu.Unlock()
+
+	for _, s := range streams {
+		s.fail(err)
+	}
+}
+
+// routeChunk delivers a chunk notification to its chunked result. It reports false for messages
+// that are not chunk notifications, which are only looked for while chunked calls are in
+// progress.
+func (c *Client) routeChunk(msg []byte) bool {
+	c.chunkMu.Lock()
+	active := len(c.chunks) > 0
+	c.chunkMu.Unlock()
+	if !active || isBatchJSON(msg) {
+		return false
+	}
+
+	var notification chunkNotification
+	if err := getCodec().Unmarshal(msg, &notification); err != nil {
+		return false
+	}
+	if notification.Method != ChunkMethod {
+		return false
+	}
+
+	c.chunkMu.Lock()
+	s, ok := c.chunks[idKey(notification.Params.Stream)]
+	c.chunkMu.Unlock()
+	if ok {
+		s.push(notification.Params)
+	}
+	// Chunks of closed streams are dropped
+	return true
+}
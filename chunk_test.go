@@ -0,0 +1,224 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newChunkPeer serves srv over a stream pair and returns a stream client on the other end.
+func newChunkPeer(t *testing.T, srv *Server, opts ...ClientOption) *Client {
+	t.Helper()
+	clientEnd, serverEnd := newStreamPair()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+
+	client := NewStreamClient(clientEnd, opts...)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// payload returns n bytes of recognizable data.
+func payload(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestCallChunked(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Payload is reassembled", func(t *testing.T) {
+		// Several chunks, written in uneven pieces
+		want := payload(3*resultChunkSize + 1234)
+		srv := NewServer()
+		require.NoError(t, srv.Register("dump", ChunkedHandler(
+			func(_ context.Context, _ *Request, w io.Writer) error {
+				for rest := want; len(rest) > 0; {
+					n := min(len(rest), 1000)
+					if _, err := w.Write(rest[:n]); err != nil {
+						return err
+					}
+					rest = rest[n:]
+				}
+				return nil
+			},
+		)))
+		client := newChunkPeer(t, srv)
+
+		r, err := client.CallChunked(ctx, "dump", nil)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(want, got))
+		require.NoError(t, r.Close())
+	})
+
+	t.Run("Empty payload", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("empty", ChunkedHandler(
+			func(context.Context, *Request, io.Writer) error { return nil },
+		)))
+		client := newChunkPeer(t, srv)
+
+		r, err := client.CallChunked(ctx, "empty", nil)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Handler error follows the chunks sent", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("broken", ChunkedHandler(
+			func(_ context.Context, _ *Request, w io.Writer) error {
+				if _, err := w.Write(payload(2 * resultChunkSize)); err != nil {
+					return err
+				}
+				return &Error{Code: ServerSideException, Message: "disk failure"}
+			},
+		)))
+		client := newChunkPeer(t, srv)
+
+		r, err := client.CallChunked(ctx, "broken", nil)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, "disk failure", rpcErr.Message)
+		assert.Len(t, got, 2*resultChunkSize)
+	})
+
+	t.Run("Plain result", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterFunc("plain", func(context.Context, *Request) (any, error) {
+			return "not chunked", nil
+		}))
+		client := newChunkPeer(t, srv)
+
+		r, err := client.CallChunked(ctx, "plain", nil)
+		require.NoError(t, err)
+		_, err = io.ReadAll(r)
+		assert.Error(t, err)
+	})
+
+	t.Run("Close cancels the call", func(t *testing.T) {
+		stopped := make(chan error, 1)
+		srv := NewServer(WithCancelMethod(CancelRequestMethod))
+		require.NoError(t, srv.Register("endless", ChunkedHandler(
+			func(_ context.Context, _ *Request, w io.Writer) error {
+				for {
+					if _, err := w.Write(payload(resultChunkSize)); err != nil {
+						stopped <- err
+						return err
+					}
+				}
+			},
+		)))
+		client := newChunkPeer(t, srv, WithCancelNotification(CancelRequestMethod))
+
+		r, err := client.CallChunked(ctx, "endless", nil)
+		require.NoError(t, err)
+		_, err = io.ReadFull(r, make([]byte, 10))
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		_, err = r.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+
+		select {
+		case err := <-stopped:
+			assert.Error(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("handler not stopped")
+		}
+	})
+
+	t.Run("Context ends the read", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		srv := NewServer()
+		require.NoError(t, srv.Register("stuck", ChunkedHandler(
+			func(context.Context, *Request, io.Writer) error {
+				<-release
+				return nil
+			},
+		)))
+		client := newChunkPeer(t, srv)
+
+		callCtx, cancel := context.WithCancel(ctx)
+		r, err := client.CallChunked(callCtx, "stuck", nil)
+		require.NoError(t, err)
+		cancel()
+		_, err = r.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Requires a stream connection", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("dump", ChunkedHandler(
+			func(context.Context, *Request, io.Writer) error { return nil },
+		)))
+		client := NewClient(&funcTransport{fn: func(ctx context.Context, p []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, p), nil
+		}})
+
+		_, err := client.CallChunked(ctx, "dump", nil)
+		require.ErrorIs(t, err, errChunksUnsupported)
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("dump", nil, 1))
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, InvalidRequest, resp.Err().Code)
+	})
+}
+
+func TestChunkStream_Push(t *testing.T) {
+	newStream := func() *chunkStream {
+		ctx, cancel := context.WithCancel(context.Background())
+		client := NewClient(&funcTransport{})
+		return &chunkStream{
+			client: client,
+			key:    "n:1",
+			ctx:    ctx,
+			cancel: cancel,
+			wake:   make(chan struct{}, 1),
+		}
+	}
+
+	t.Run("In sequence", func(t *testing.T) {
+		s := newStream()
+		s.push(chunkFrame{Seq: 0, Data: []byte("ab")})
+		s.push(chunkFrame{Seq: 1, Data: []byte("c"), Final: true})
+		got, err := io.ReadAll(s)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", string(got))
+	})
+
+	t.Run("Out of sequence", func(t *testing.T) {
+		s := newStream()
+		s.push(chunkFrame{Seq: 0, Data: []byte("ab")})
+		s.push(chunkFrame{Seq: 2, Data: []byte("c")})
+		got, err := io.ReadAll(s)
+		require.ErrorIs(t, err, errChunkSequence)
+		assert.Equal(t, "ab", string(got))
+	})
+
+	t.Run("Errors after the data received", func(t *testing.T) {
+		s := newStream()
+		s.push(chunkFrame{Seq: 0, Data: []byte("ab")})
+		s.fail(fmt.Errorf("wrapped: %w", errors.ErrUnsupported))
+		got, err := io.ReadAll(s)
+		assert.ErrorIs(t, err, errors.ErrUnsupported)
+		assert.Equal(t, "ab", string(got))
+	})
+}
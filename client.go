@@ -0,0 +1,707 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic/ast"
+)
+
+var (
+	// ErrClientClosed is returned by calls made on, or interrupted by, a closed Client.
+	ErrClientClosed = errors.New("client is closed")
+
+	// errDuplicateID is returned when a call reuses the ID of a call that is still in flight.
+	errDuplicateID = errors.New("request id is already in flight")
+)
+
+// Client issues JSON-RPC calls, notifications, and batches over a Transport or a Stream.
+// A Client is safe for concurrent use by multiple goroutines.
+//
+// Clients created with NewClient perform one request/response exchange per call. Clients created
+// with NewStreamClient keep a single persistent connection, run a background read loop, and
+// correlate responses to in-flight calls by ID.
+type Client struct {
+	transport Transport
+	conn      atomic.Pointer[streamConn]
+
+	idGen        IDGenerator
+	interceptors []Interceptor
+	invoke       Invoker
+	batcher      *autoBatcher
+	strict       bool
+	v1Compat     bool
+	canonical    bool
+	emptyParams  EmptyParams
+	errors       *ErrorRegistry
+	observer     Observer
+	limits       messageLimits
+	rateLimits   []RateLimit
+	credentials  []Credentials
+	codec        Codec
+	audit        *auditor
+
+	// Unmatched response handling
+	unmatchedHandler func(resp *Response)
+	unmatchedErr     func(err error)
+	answered         recentIDs
+
+	// Stream state
+	server       *Server
+	baseCtx      context.Context
+	cancelMethod string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	writeMu      sync.Mutex
+	mu           sync.Mutex
+	pending      map[string]chan *Response
+	closed       bool
+	closeErr     error
+	done         chan struct{}
+	closeOnce    sync.Once
+
+	// Reconnection state
+	dial            Dialer
+	reconnectPolicy *ReconnectPolicy
+	keepalive       *KeepalivePolicy
+
+	// Deadline propagation
+	propagateDeadline bool
+
+	// Subscription state
+	subMu       sync.Mutex
+	subs        map[string]*Subscription
+	early       map[string][]json.RawMessage
+	subscribing int
+
+	// Chunked result state
+	chunkMu sync.Mutex
+	chunks  map[string]*chunkStream
+
+	// Capability handshake state
+	negotiation atomic.Pointer[negotiation]
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// Invoker sends a single request or notification and returns its response, or nil for
+// notifications.
+type Invoker func(ctx context.Context, req *Request) (*Response, error)
+
+// Interceptor wraps the sending of calls and notifications with behavior such as signing,
+// injecting credentials, or latency tracking. It receives the next invoker in the chain and
+// returns the invoker to call in its place; it may inspect or modify the request before calling
+// next, and the response after.
+type Interceptor func(next Invoker) Invoker
+
+// WithInterceptors appends interceptors to the chain applied around every Call and Notify,
+// including the calls made by Subscribe. Interceptors run in the order given: the first is the
+// outermost. Batches are sent as-is, without passing through the interceptors.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithServer makes a stream client serve requests and notifications initiated by the remote peer
+// using srv, enabling bidirectional calls over one connection. Without it, inbound requests are
+// answered with a method not found error and inbound notifications are dropped. It has no effect
+// on clients using a request/response Transport.
+func WithServer(srv *Server) ClientOption {
+	return func(c *Client) {
+		c.server = srv
+	}
+}
+
+// withBaseContext sets the context from which the contexts of inbound request handlers derive.
+func withBaseContext(ctx context.Context) ClientOption {
+	return func(c *Client) {
+		c.baseCtx = ctx
+	}
+}
+
+// NewClient creates a Client that sends every call through the given request/response Transport.
+func NewClient(transport Transport, opts ...ClientOption) *Client {
+	c := &Client{
+		transport: transport,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.idGen == nil {
+		c.idGen = NewSequentialIDGenerator()
+	}
+	c.buildInvoker()
+	c.startAudit()
+	return c
+}
+
+// NewStreamClient creates a Client multiplexing calls over a persistent Stream. The Client starts
+// a background goroutine reading from the stream, which runs until Close is called or reading
+// fails, unless WithReconnect is used.
+func NewStreamClient(stream Stream, opts ...ClientOption) *Client {
+	c := &Client{
+		baseCtx: context.Background(),
+		pending: make(map[string]chan *Response),
+		done:    make(chan struct{}),
+		subs:    make(map[string]*Subscription),
+		early:   make(map[string][]json.RawMessage),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.idGen == nil {
+		c.idGen = NewSequentialIDGenerator()
+	}
+	c.buildInvoker()
+	boundStream(stream, c.limits.maxMessageSize)
+	conn := newStreamConn(stream)
+	c.conn.Store(conn)
+	c.ctx, c.cancel = context.WithCancel(contextWithClient(withInflight(c.baseCtx), c))
+	c.startKeepalive(conn)
+	c.startAudit()
+
+	go c.readLoop()
+	return c
+}
+
+// Done returns a channel that is closed once the client has shut down, either through Close or,
+// for stream clients, because reading from the stream failed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Call invokes method with params and waits for the response. If the server returns a JSON-RPC
+// error, Call returns it as an *Error, or as an error also matching the registered Go error when
+// WithClientErrorRegistry is used. Otherwise the result is unmarshaled into result, unless result
+// is nil. Options override the client's defaults for this call only.
+func (c *Client) Call(
+	ctx context.Context,
+	method string,
+	params any,
+	result any,
+	opts ...CallOption,
+) error {
+	options := c.callOptions(opts)
+	resp, err := c.call(ctx, method, params, options)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return options.unmarshalResult(resp, result)
+}
+
+// call invokes method with params under options and returns the response, or its JSON-RPC error.
+func (c *Client) call(
+	ctx context.Context,
+	method string,
+	params any,
+	options *callOptions,
+) (*Response, error) {
+	callCtx, cancel := options.context(ctx)
+	defer cancel()
+	callParams, err := options.params(params)
+	if err != nil {
+		return nil, err
+	}
+	id := options.id
+	if id == nil {
+		id = c.newID()
+	}
+	req := NewRequestWithID(method, c.emptyParams.apply(callParams), id)
+
+	resp, err := c.invoke(callCtx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("missing response for request id %v", req.ID)
+	}
+	if rpcErr := resp.Err(); rpcErr != nil {
+		if c.errors != nil {
+			return nil, c.errors.FromError(rpcErr)
+		}
+		return nil, rpcErr
+	}
+	return resp, nil
+}
+
+// Notify sends a notification, which by definition receives no response. Options override the
+// client's defaults for this notification only.
+func (c *Client) Notify(ctx context.Context, method string, params any, opts ...CallOption) error {
+	options := c.callOptions(opts)
+	callCtx, cancel := options.context(ctx)
+	defer cancel()
+	callParams, err := options.params(params)
+	if err != nil {
+		return err
+	}
+	_, err = c.invoke(callCtx, NewNotification(method, c.emptyParams.apply(callParams)))
+	return err
+}
+
+// CallBatch sends the requests as a single batch and waits for all responses. The returned slice
+// holds one response per non-notification request, in the same order as the requests regardless
+// of the order in which the server replied.
+func (c *Client) CallBatch(ctx context.Context, reqs []*Request) ([]*Response, error) {
+	if err := c.limits.checkBatch(len(reqs)); err != nil {
+		return nil, err
+	}
+	if err := c.throttleBatch(ctx, reqs); err != nil {
+		return nil, err
+	}
+	payload, err := EncodeBatchRequest(c.withEmptyParams(reqs))
+	if err != nil {
+		return nil, err
+	}
+	if c.observer != nil {
+		c.observer.BatchStarted(ctx, len(reqs))
+	}
+
+	calls := make([]*Request, 0, len(reqs))
+	keys := make([]string, 0, len(reqs))
+	seen := make(map[string]struct{}, len(reqs))
+	for _, req := range reqs {
+		if req.IsNotification() {
+			continue
+		}
+		key := idKey(req.ID)
+		if _, ok := seen[key]; ok {
+			return nil, fmt.Errorf("duplicate request id in batch: %v", req.ID)
+		}
+		seen[key] = struct{}{}
+		calls = append(calls, req)
+		keys = append(keys, key)
+	}
+
+	replies, err := c.exchange(ctx, payload, keys)
+	if err != nil {
+		ids := make([]any, len(calls))
+		for i, req := range calls {
+			ids[i] = req.ID
+		}
+		c.propagateCancel(ctx, err, ids...)
+		return nil, err
+	}
+
+	resps := make([]*Response, 0, len(keys))
+	for i, key := range keys {
+		resp, ok := replies[key]
+		if !ok {
+			return nil, fmt.Errorf("missing response for request id %v", calls[i].ID)
+		}
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+// Close closes the underlying transport or stream. In-flight calls on a stream client fail with
+// ErrClientClosed.
+func (c *Client) Close() error {
+	return c.closeWith(ErrClientClosed)
+}
+
+// closeWith closes the client like Close, failing in-flight calls on a stream client with cause.
+func (c *Client) closeWith(cause error) error {
+	var err error
+	c.closeOnce.Do(func() {
+		if c.isStream() {
+			c.shutdown(cause)
+			err = c.conn.Load().stream.Close()
+			return
+		}
+		err = c.transport.Close()
+		close(c.done)
+	})
+	return err
+}
+
+// newID returns the next ID for a client-generated request.
+func (c *Client) newID() any {
+	return c.idGen.NextID()
+}
+
+// buildInvoker wraps send in the configured interceptors and observer, and the propagation of
+// deadlines, innermost so that the time left is measured once the interceptors are done.
+func (c *Client) buildInvoker() {
+	c.invoke = c.send
+	if c.propagateDeadline {
+		c.invoke = c.withDeadline(c.invoke)
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		c.invoke = c.interceptors[i](c.invoke)
+	}
+	if c.observer != nil {
+		c.invoke = observe(c.observer, c.invoke)
+	}
+}
+
+// send exchanges a single request or notification, gathering calls into batches with
+// WithAutoBatching, and returns the matching response, or nil for notifications.
+func (c *Client) send(ctx context.Context, req *Request) (*Response, error) {
+	if c.batcher != nil && !req.IsNotification() {
+		return c.batcher.send(ctx, req)
+	}
+	return c.sendOne(ctx, req)
+}
+
+// sendOne marshals a single request or notification, exchanges it, and returns the matching
+// response, or nil for notifications.
+func (c *Client) sendOne(ctx context.Context, req *Request) (*Response, error) {
+	payload, err := req.AppendJSON(nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.IsNotification() {
+		_, err = c.exchange(ctx, payload, nil)
+		return nil, err
+	}
+
+	key := idKey(req.ID)
+	replies, err := c.exchange(ctx, payload, []string{key})
+	if err != nil {
+		c.propagateCancel(ctx, err, req.ID)
+		return nil, err
+	}
+
+	resp, ok := replies[key]
+	if !ok {
+		return nil, fmt.Errorf("missing response for request id %v", req.ID)
+	}
+	return resp, nil
+}
+
+// exchange writes payload and collects the responses for the given correlation keys. An empty
+// keys slice means no response is expected.
+func (c *Client) exchange(
+	ctx context.Context,
+	payload []byte,
+	keys []string,
+) (map[string]*Response, error) {
+	select {
+	case <-c.done:
+		return nil, c.terminalErr()
+	default:
+	}
+
+	msg := payload
+	if c.canonical {
+		canonical, err := CanonicalJSON(payload)
+		if err != nil {
+			return nil, fmt.Errorf("canonicalize request: %w", err)
+		}
+		msg = canonical
+	}
+	if c.audit != nil {
+		defer c.audit.end(c.audit.begin(ctx, msg))
+	}
+	if c.isStream() {
+		return c.exchangeStream(ctx, msg, keys)
+	}
+	authCtx, err := c.authenticate(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.exchangeTransport(authCtx, msg, keys)
+}
+
+// exchangeTransport performs a request/response round trip on the transport.
+func (c *Client) exchangeTransport(
+	ctx context.Context,
+	payload []byte,
+	keys []string,
+) (map[string]*Response, error) {
+	reply, err := c.transport.RoundTrip(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if err := c.limits.checkMessage(len(reply)); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	resps, isBatch, err := c.decodeReply(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	// A server that cannot parse a batch replies with a single error response
+	if len(keys) > 1 && !isBatch && resps[0].IDOrNil() == nil {
+		if rpcErr := resps[0].Err(); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	return c.matchReplies(resps, keys), nil
+}
+
+// exchangeStream writes payload to the stream and waits for the read loop to deliver responses.
+// Calls made on, or interrupted by, a dropped connection fail with ErrConnectionLost.
+func (c *Client) exchangeStream(
+	ctx context.Context,
+	payload []byte,
+	keys []string,
+) (map[string]*Response, error) {
+	conn := c.conn.Load()
+	waits, err := c.register(keys)
+	if err != nil {
+		return nil, err
+	}
+	defer c.unregister(keys)
+
+	select {
+	case <-conn.lost:
+		return nil, ErrConnectionLost
+	default:
+	}
+	if err := c.writeTo(ctx, conn, payload); err != nil {
+		return nil, err
+	}
+
+	replies := make(map[string]*Response, len(keys))
+	for i, ch := range waits {
+		select {
+		case resp := <-ch:
+			replies[keys[i]] = resp
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, c.terminalErr()
+		case <-conn.lost:
+			return nil, ErrConnectionLost
+		}
+	}
+	return replies, nil
+}
+
+// write writes msg to the current connection.
+func (c *Client) write(ctx context.Context, msg []byte) error {
+	return c.writeTo(ctx, c.conn.Load(), msg)
+}
+
+// writeTo serializes writes to the stream of conn.
+func (c *Client) writeTo(ctx context.Context, conn *streamConn, msg []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return conn.stream.WriteMessage(ctx, msg)
+}
+
+// register creates a pending response slot for each key.
+func (c *Client) register(keys []string) ([]chan *Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, c.closeErr
+	}
+	for _, key := range keys {
+		if _, ok := c.pending[key]; ok {
+			return nil, errDuplicateID
+		}
+	}
+
+	waits := make([]chan *Response, len(keys))
+	for i, key := range keys {
+		ch := make(chan *Response, 1)
+		c.pending[key] = ch
+		waits[i] = ch
+	}
+	return waits, nil
+}
+
+// unregister removes the pending response slots for keys.
+func (c *Client) unregister(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := c.pending[key]; ok && c.audit != nil {
+			c.audit.abandon(key)
+		}
+		delete(c.pending, key)
+	}
+}
+
+// readLoop reads messages from the stream until it fails or the client is closed. Reconnecting
+// clients carry on with a new connection when reading fails.
+func (c *Client) readLoop() {
+	for {
+		conn := c.conn.Load()
+		msg, err := conn.stream.ReadMessage(c.ctx)
+		if err == nil {
+			conn.touch()
+			c.dispatch(conn, msg)
+			continue
+		}
+		err = conn.readErr(err)
+		if c.reconnectPolicy == nil || c.ctx.Err() != nil {
+			c.shutdown(fmt.Errorf("%w: %w", ErrClientClosed, err))
+			return
+		}
+		if !c.reconnect(conn, err) {
+			return
+		}
+	}
+}
+
+// dispatch routes an incoming message: responses go to the calls waiting for them, subscription
+// notifications to their subscriptions, chunks to their chunked results, and other requests and
+// notifications to the client's server. Malformed responses are dropped, and unmatched ones
+// reported.
+func (c *Client) dispatch(conn *streamConn, msg []byte) {
+	if isRequestMessage(msg) {
+		if c.routeNotification(msg) || c.routeChunk(msg) {
+			return
+		}
+		var seq uint64
+		if c.server != nil && c.server.orderedReplies {
+			// Only the read loop reserves turns, so it creates the queue without a lock
+			if conn.replies == nil {
+				conn.replies = newReplyQueue(func(reply []byte) {
+					_ = c.writeTo(c.ctx, conn, reply)
+				})
+			}
+			seq = conn.replies.reserve()
+		}
+		go c.serveInbound(conn, msg, seq)
+		return
+	}
+
+	if c.limits.checkMessage(len(msg)) != nil {
+		return
+	}
+	resps, _, err := c.decodeReply(msg)
+	if err != nil {
+		return
+	}
+	for _, resp := range resps {
+		c.deliver(resp)
+	}
+}
+
+// decodeReply decodes an encoded response or batch, applying the client's compatibility and
+// strictness settings first.
+func (c *Client) decodeReply(reply []byte) ([]*Response, bool, error) {
+	data := reply
+	if c.v1Compat {
+		normalized, err := NormalizeV1(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid response: %w", err)
+		}
+		data = normalized
+	}
+	if c.strict {
+		if err := ValidateStrict(data); err != nil {
+			return nil, false, fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return DecodeResponseOrBatch(data)
+}
+
+// serveInbound handles a request or notification initiated by the remote peer and writes the
+// reply, if any, back to the connection it came from. With ordered replies, the reply takes its
+// turn as number seq.
+func (c *Client) serveInbound(conn *streamConn, msg []byte, seq uint64) {
+	srv := c.server
+	if srv == nil {
+		srv = emptyServer
+	}
+	srv.drain.enter()
+	defer srv.drain.leave()
+
+	reply := srv.HandleMessage(c.ctx, msg)
+	if conn.replies != nil {
+		conn.replies.complete(seq, reply)
+		return
+	}
+	if reply == nil {
+		return
+	}
+	_ = c.writeTo(c.ctx, conn, reply)
+}
+
+// isRequestMessage reports whether an encoded message (or the first member of a batch) is a
+// request or notification rather than a response.
+func isRequestMessage(msg []byte) bool {
+	path := []any{"method"}
+	if isBatchJSON(msg) {
+		path = []any{0, "method"}
+	}
+	node, err := ast.NewSearcher(string(msg)).GetByPath(path...)
+	return err == nil && node.Exists()
+}
+
+// deliver hands a response to the pending call with the matching ID, if any, and reports it as
+// unmatched otherwise.
+func (c *Client) deliver(resp *Response) {
+	key := idKey(resp.IDOrNil())
+	tracked := c.tracksUnmatched()
+
+	c.mu.Lock()
+	ch, ok := c.pending[key]
+	if ok {
+		delete(c.pending, key)
+		if tracked {
+			c.answered.add(key)
+		}
+	}
+	duplicate := !ok && tracked && c.answered.contains(key)
+	c.mu.Unlock()
+
+	if ok {
+		ch <- resp
+		return
+	}
+	if c.audit != nil {
+		c.audit.late(key, resp)
+	}
+	if tracked {
+		c.reportUnmatched(resp, duplicate)
+	}
+}
+
+// startAudit starts checking the calls in flight of an audited client until it shuts down.
+func (c *Client) startAudit() {
+	if c.audit != nil {
+		go c.audit.watch(c.done)
+	}
+}
+
+// shutdown marks a stream client as closed with the given cause and releases waiting calls.
+func (c *Client) shutdown(cause error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeErr = cause
+	c.cancel()
+	close(c.done)
+}
+
+// isStream reports whether the client multiplexes calls over a stream rather than a Transport.
+func (c *Client) isStream() bool {
+	return c.conn.Load() != nil
+}
+
+// terminalErr returns the error describing why the client stopped.
+func (c *Client) terminalErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeErr != nil {
+		return c.closeErr
+	}
+	return ErrClientClosed
+}
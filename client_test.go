@@ -0,0 +1,435 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// funcTransport is a Transport backed by a function, for tests.
+type funcTransport struct {
+	fn     func(ctx context.Context, payload []byte) ([]byte, error)
+	closed bool
+}
+
+func (t *funcTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	return t.fn(ctx, payload)
+}
+
+func (t *funcTransport) Close() error {
+	t.closed = true
+	return nil
+}
+
+// pipeStream is one end of an in-memory Stream pair, for tests.
+type pipeStream struct {
+	in        chan []byte
+	out       chan []byte
+	done      chan struct{}
+	closeOnce *sync.Once
+}
+
+// newStreamPair returns two connected in-memory streams.
+func newStreamPair() (*pipeStream, *pipeStream) {
+	a2b := make(chan []byte, 16)
+	b2a := make(chan []byte, 16)
+	done := make(chan struct{})
+	once := &sync.Once{}
+	return &pipeStream{in: b2a, out: a2b, done: done, closeOnce: once},
+		&pipeStream{in: a2b, out: b2a, done: done, closeOnce: once}
+}
+
+func (s *pipeStream) ReadMessage(ctx context.Context) ([]byte, error) {
+	select {
+	case msg := <-s.in:
+		return msg, nil
+	case <-s.done:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *pipeStream) WriteMessage(ctx context.Context, msg []byte) error {
+	select {
+	case s.out <- msg:
+		return nil
+	case <-s.done:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *pipeStream) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+// echoResponder answers every request read from the stream by echoing its params as the result.
+func echoResponder(t *testing.T, s *pipeStream) {
+	t.Helper()
+	go func() {
+		for {
+			msg, err := s.ReadMessage(context.Background())
+			if err != nil {
+				return
+			}
+			reqs, isBatch, err := DecodeRequestOrBatch(msg)
+			if err != nil {
+				return
+			}
+			resps := make([]*Response, 0, len(reqs))
+			for _, req := range reqs {
+				if req.IsNotification() {
+					continue
+				}
+				resp, _ := NewResponse(req.ID, req.Params)
+				resps = append(resps, resp)
+			}
+			if len(resps) == 0 {
+				continue
+			}
+			var out []byte
+			if isBatch {
+				// Reverse to check that the client does not rely on ordering
+				for i, j := 0, len(resps)-1; i < j; i, j = i+1, j-1 {
+					resps[i], resps[j] = resps[j], resps[i]
+				}
+				out, _ = EncodeBatchResponse(resps)
+			} else {
+				out, _ = resps[0].MarshalJSON()
+			}
+			_ = s.WriteMessage(context.Background(), out)
+		}
+	}()
+}
+
+func TestClient_Call(t *testing.T) {
+	t.Run("Result is unmarshaled", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			req, err := DecodeRequest(payload)
+			require.NoError(t, err)
+			assert.Equal(t, "sum", req.Method)
+			resp, _ := NewResponse(req.ID, 3)
+			return resp.MarshalJSON()
+		}}
+		client := NewClient(transport)
+
+		var result int
+		err := client.Call(context.Background(), "sum", []any{1, 2}, &result)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result)
+	})
+
+	t.Run("Nil result discards the response", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			req, _ := DecodeRequest(payload)
+			resp, _ := NewResponse(req.ID, "ok")
+			return resp.MarshalJSON()
+		}}
+		client := NewClient(transport)
+		require.NoError(t, client.Call(context.Background(), "ping", nil, nil))
+	})
+
+	t.Run("JSON-RPC error is returned as *Error", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			req, _ := DecodeRequest(payload)
+			return NewErrorResponse(req.ID, &Error{Code: MethodNotFound, Message: "nope"}).
+				MarshalJSON()
+		}}
+		client := NewClient(transport)
+
+		err := client.Call(context.Background(), "missing", nil, nil)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+	})
+
+	t.Run("Null ID error reply belongs to the call", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"x"}}`), nil
+		}}
+		client := NewClient(transport)
+
+		err := client.Call(context.Background(), "sum", nil, nil)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, ParseError, rpcErr.Code)
+	})
+
+	t.Run("Transport error is returned", func(t *testing.T) {
+		boom := errors.New("boom")
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return nil, boom
+		}}
+		client := NewClient(transport)
+		require.ErrorIs(t, client.Call(context.Background(), "sum", nil, nil), boom)
+	})
+
+	t.Run("Mismatched ID is an error", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(`{"jsonrpc":"2.0","id":"other","result":1}`), nil
+		}}
+		client := NewClient(transport)
+
+		err := client.Call(context.Background(), "sum", nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing response")
+	})
+
+	t.Run("IDs are unique per call", func(t *testing.T) {
+		var mu sync.Mutex
+		seen := make(map[any]bool)
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			req, _ := DecodeRequest(payload)
+			mu.Lock()
+			assert.False(t, seen[req.ID], "duplicate id %v", req.ID)
+			seen[req.ID] = true
+			mu.Unlock()
+			resp, _ := NewResponse(req.ID, nil)
+			return resp.MarshalJSON()
+		}}
+		client := NewClient(transport)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, client.Call(context.Background(), "m", nil, nil))
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, seen, 50)
+	})
+}
+
+func TestClient_Notify(t *testing.T) {
+	var got *Request
+	transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+		got, _ = DecodeRequest(payload)
+		return nil, nil
+	}}
+	client := NewClient(transport)
+
+	require.NoError(t, client.Notify(context.Background(), "log", map[string]any{"a": 1}))
+	require.NotNil(t, got)
+	assert.True(t, got.IsNotification())
+	assert.Equal(t, "log", got.Method)
+}
+
+func TestClient_CallBatch(t *testing.T) {
+	t.Run("Responses follow request order", func(t *testing.T) {
+		client, server := newStreamPair()
+		echoResponder(t, server)
+		c := NewStreamClient(client)
+		defer c.Close()
+
+		reqs := []*Request{
+			NewRequestWithID("a", []any{"a"}, int64(1)),
+			NewNotification("log", nil),
+			NewRequestWithID("b", []any{"b"}, "two"),
+		}
+		resps, err := c.CallBatch(context.Background(), reqs)
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		assert.Equal(t, int64(1), resps[0].IDOrNil())
+		assert.Equal(t, "two", resps[1].IDOrNil())
+	})
+
+	t.Run("Duplicate IDs are rejected", func(t *testing.T) {
+		client := NewClient(&funcTransport{})
+		reqs := []*Request{
+			NewRequestWithID("a", nil, int64(1)),
+			NewRequestWithID("b", nil, int64(1)),
+		}
+		_, err := client.CallBatch(context.Background(), reqs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate")
+	})
+
+	t.Run("Single error reply fails the batch", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"x"}}`), nil
+		}}
+		client := NewClient(transport)
+		reqs := []*Request{
+			NewRequestWithID("a", nil, int64(1)),
+			NewRequestWithID("b", nil, int64(2)),
+		}
+		_, err := client.CallBatch(context.Background(), reqs)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, InvalidRequest, rpcErr.Code)
+	})
+
+	t.Run("Missing response is an error", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(`[{"jsonrpc":"2.0","id":1,"result":true}]`), nil
+		}}
+		client := NewClient(transport)
+		reqs := []*Request{
+			NewRequestWithID("a", nil, int64(1)),
+			NewRequestWithID("b", nil, int64(2)),
+		}
+		_, err := client.CallBatch(context.Background(), reqs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing response for request id 2")
+	})
+}
+
+func TestClient_Stream(t *testing.T) {
+	t.Run("Concurrent calls are correlated", func(t *testing.T) {
+		client, server := newStreamPair()
+		echoResponder(t, server)
+		c := NewStreamClient(client)
+		defer c.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var result []int
+				err := c.Call(context.Background(), "echo", []any{i}, &result)
+				assert.NoError(t, err)
+				assert.Equal(t, []int{i}, result)
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("Context cancellation abandons the call", func(t *testing.T) {
+		client, _ := newStreamPair()
+		c := NewStreamClient(client)
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, c.Call(ctx, "never", nil, nil), context.DeadlineExceeded)
+	})
+
+	t.Run("Close releases in-flight calls", func(t *testing.T) {
+		client, _ := newStreamPair()
+		c := NewStreamClient(client)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- c.Call(context.Background(), "never", nil, nil)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, c.Close())
+
+		select {
+		case err := <-errCh:
+			require.ErrorIs(t, err, ErrClientClosed)
+		case <-time.After(time.Second):
+			t.Fatal("call was not released by Close")
+		}
+		require.ErrorIs(t, c.Call(context.Background(), "after", nil, nil), ErrClientClosed)
+	})
+
+	t.Run("Read failure closes the client", func(t *testing.T) {
+		client, server := newStreamPair()
+		c := NewStreamClient(client)
+		require.NoError(t, server.Close())
+
+		require.Eventually(t, func() bool {
+			err := c.Call(context.Background(), "x", nil, nil)
+			return errors.Is(err, ErrClientClosed) && errors.Is(err, io.EOF)
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestClient_Close(t *testing.T) {
+	transport := &funcTransport{}
+	client := NewClient(transport)
+	require.NoError(t, client.Close())
+	assert.True(t, transport.closed)
+	require.ErrorIs(t, client.Call(context.Background(), "x", nil, nil), ErrClientClosed)
+}
+
+func TestClient_Interceptors(t *testing.T) {
+	// tag returns an interceptor recording its name before and after calling next
+	tag := func(name string, trace *[]string) Interceptor {
+		return func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				*trace = append(*trace, name+">")
+				resp, err := next(ctx, req)
+				*trace = append(*trace, "<"+name)
+				return resp, err
+			}
+		}
+	}
+	echo := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+		req, err := DecodeRequest(payload)
+		if err != nil || req.IsNotification() {
+			return nil, err
+		}
+		resp, err := NewResponse(req.ID, req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return resp.MarshalJSON()
+	}}
+
+	t.Run("Runs in order around calls and notifications", func(t *testing.T) {
+		var trace []string
+		client := NewClient(echo, WithInterceptors(tag("a", &trace), tag("b", &trace)))
+
+		require.NoError(t, client.Call(context.Background(), "m", nil, nil))
+		assert.Equal(t, []string{"a>", "b>", "<b", "<a"}, trace)
+
+		trace = nil
+		require.NoError(t, client.Notify(context.Background(), "n", nil))
+		assert.Equal(t, []string{"a>", "b>", "<b", "<a"}, trace)
+	})
+
+	t.Run("Mutates requests and responses", func(t *testing.T) {
+		sign := func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				req.Params = map[string]any{"token": "secret"}
+				resp, err := next(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				return NewResponse(resp.IDOrNil(), "rewritten:"+string(resp.RawResult()))
+			}
+		}
+		client := NewClient(echo, WithInterceptors(sign))
+
+		var got string
+		require.NoError(t, client.Call(context.Background(), "m", []any{1}, &got))
+		assert.Equal(t, `rewritten:{"token":"secret"}`, got)
+	})
+
+	t.Run("Short-circuit without a response", func(t *testing.T) {
+		drop := func(Invoker) Invoker {
+			return func(context.Context, *Request) (*Response, error) { return nil, nil }
+		}
+		client := NewClient(echo, WithInterceptors(drop))
+		err := client.Call(context.Background(), "m", nil, nil)
+		require.ErrorContains(t, err, "missing response")
+	})
+
+	t.Run("Errors short-circuit the call", func(t *testing.T) {
+		called := false
+		transport := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			called = true
+			return nil, nil
+		}}
+		deny := func(Invoker) Invoker {
+			return func(context.Context, *Request) (*Response, error) { return nil, assert.AnError }
+		}
+		client := NewClient(transport, WithInterceptors(deny))
+		require.ErrorIs(t, client.Call(context.Background(), "m", nil, nil), assert.AnError)
+		assert.False(t, called)
+	})
+}
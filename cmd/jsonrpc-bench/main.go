@@ -0,0 +1,156 @@
+// Command jsonrpc-bench load-tests a JSON-RPC endpoint: it fires a mix of calls at it from
+// concurrent workers, optionally in batches, and reports latency percentiles, throughput, and a
+// breakdown of errors. Endpoints are given by URL, with the schemes http, https, ws, wss, tcp,
+// and unix:
+//
+//	jsonrpc-bench -url http://localhost:8545 -c 16 -d 30s \
+//		-call eth_blockNumber -call 'eth_getBlockByNumber=["{{hex .Seq}}",false]'
+//
+// Weighted mixes are read from a JSON file of calls with a method, params, and weight, where
+// params are a JSON value, or a string holding a params template:
+//
+//	[{"method":"eth_call","params":[{"to":"0x00"},"latest"],"weight":3},
+//	 {"method":"eth_getBalance","params":"[\"0x{{.RandInt 1 9}}\",\"latest\"]"}]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/bench"
+	"github.com/jkbrsn/jsonrpc/ws"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "jsonrpc-bench:", err)
+		os.Exit(1)
+	}
+}
+
+// callFlags collects the calls given with repeated -call flags.
+type callFlags []bench.Call
+
+// String implements flag.Value.
+func (c *callFlags) String() string {
+	methods := make([]string, len(*c))
+	for i, call := range *c {
+		methods[i] = call.Method
+	}
+	return strings.Join(methods, ",")
+}
+
+// Set implements flag.Value, parsing a method optionally followed by "=" and params.
+func (c *callFlags) Set(value string) error {
+	method, params, _ := strings.Cut(value, "=")
+	*c = append(*c, bench.Call{Method: method, Params: params})
+	return nil
+}
+
+// run benchmarks the endpoint as directed by args and writes the report to stdout.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("jsonrpc-bench", flag.ContinueOnError)
+	endpoint := flags.String("url", "", "URL of the endpoint")
+	var calls callFlags
+	flags.Var(&calls, "call", `call to make, as METHOD or METHOD=PARAMS, repeatable`)
+	mixFile := flags.String("mix", "", "path of a JSON file of weighted calls")
+	var cfg bench.Config
+	flags.IntVar(&cfg.Concurrency, "c", 1, "number of concurrent workers")
+	flags.IntVar(&cfg.Requests, "n", 0, "number of calls to make")
+	flags.DurationVar(&cfg.Duration, "d", 0, "duration of the run")
+	flags.IntVar(&cfg.BatchSize, "batch", 1, "number of calls per batch")
+	flags.Float64Var(&cfg.Rate, "rate", 0, "maximum calls or batches per second, 0 for no limit")
+	flags.DurationVar(&cfg.Timeout, "timeout", 0, "timeout of each call or batch")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *endpoint == "" {
+		return errors.New("-url is required")
+	}
+
+	cfg.Calls = calls
+	if *mixFile != "" {
+		mix, err := readMix(*mixFile)
+		if err != nil {
+			return err
+		}
+		cfg.Calls = append(cfg.Calls, mix...)
+	}
+
+	client, err := dial(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	report, err := bench.Run(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+	return report.Write(stdout)
+}
+
+// mixEntry is an entry of a mix file.
+type mixEntry struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Weight int             `json:"weight"`
+}
+
+// readMix reads the calls of the mix file at path.
+func readMix(path string) ([]bench.Call, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []mixEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	calls := make([]bench.Call, len(entries))
+	for i, entry := range entries {
+		params := string(entry.Params)
+		var template string
+		if json.Unmarshal(entry.Params, &template) == nil {
+			params = template
+		}
+		calls[i] = bench.Call{Method: entry.Method, Params: params, Weight: entry.Weight}
+	}
+	return calls, nil
+}
+
+// dial returns a client for the endpoint at rawURL.
+func dial(ctx context.Context, rawURL string) (*jsonrpc.Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var stream jsonrpc.Stream
+	switch u.Scheme {
+	case "http", "https":
+		return jsonrpc.NewClient(jsonrpc.NewHTTPTransport(rawURL)), nil
+	case "ws", "wss":
+		stream, err = ws.Dial(ctx, rawURL)
+	case "tcp":
+		stream, err = jsonrpc.DialConn(ctx, "tcp", u.Host)
+	case "unix":
+		stream, err = jsonrpc.DialConn(ctx, "unix", u.Path)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonrpc.NewStreamClient(stream), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	srv := jsonrpc.NewServer()
+	require.NoError(t, srv.RegisterFunc("echo", func(
+		_ context.Context,
+		req *jsonrpc.Request,
+	) (any, error) {
+		return req.Params, nil
+	}))
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+
+	t.Run("Calls given by flags", func(t *testing.T) {
+		var out bytes.Buffer
+		args := []string{"-url", server.URL, "-n", "10", "-c", "2",
+			"-call", "echo", "-call", `echo=["{{hex .Seq}}"]`}
+		require.NoError(t, run(ctx, args, &out))
+		assert.Regexp(t, `calls\s+10`, out.String())
+		assert.Regexp(t, `errors\s+0`, out.String())
+	})
+
+	t.Run("Calls read from a mix file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mix.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[
+			{"method": "echo", "params": [1, 2], "weight": 2},
+			{"method": "echo", "params": "[{{.Seq}}]"},
+			{"method": "missing"}
+		]`), 0o600))
+
+		calls, err := readMix(path)
+		require.NoError(t, err)
+		require.Len(t, calls, 3)
+		assert.Equal(t, "[1, 2]", calls[0].Params)
+		assert.Equal(t, 2, calls[0].Weight)
+		assert.Equal(t, "[{{.Seq}}]", calls[1].Params)
+		assert.Empty(t, calls[2].Params)
+
+		var out bytes.Buffer
+		require.NoError(t, run(ctx, []string{"-url", server.URL, "-n", "100", "-mix", path}, &out))
+		assert.Contains(t, out.String(), "code -32601")
+	})
+
+	t.Run("Invalid arguments", func(t *testing.T) {
+		require.Error(t, run(ctx, []string{"-n", "1", "-call", "echo"}, &bytes.Buffer{}))
+		args := []string{"-url", "ftp://host", "-n", "1", "-call", "echo"}
+		require.ErrorContains(t, run(ctx, args, &bytes.Buffer{}), "unsupported scheme")
+		assert.Error(t, run(ctx, []string{"-url", server.URL, "-call", "echo"}, &bytes.Buffer{}))
+	})
+}
@@ -0,0 +1,77 @@
+// Command jsonrpc-gen generates Go bindings from an OpenRPC document: the types of its schemas, a
+// typed client wrapping a jsonrpc.Client, and a server interface with a function registering its
+// implementations on a jsonrpc.Server. Added as a tool of a module with
+//
+//	go get -tool github.com/jkbrsn/jsonrpc/cmd/jsonrpc-gen
+//
+// it fits go:generate directives such as the following, which default the package name to that of
+// the file holding the directive:
+//
+//	//go:generate go tool jsonrpc-gen -spec api.json -out api.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jkbrsn/jsonrpc/openrpc"
+	"github.com/jkbrsn/jsonrpc/openrpc/codegen"
+)
+
+// sourcePerm is the permission of the generated file.
+const sourcePerm = 0o644
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "jsonrpc-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the bindings as directed by args, reading the document from stdin if the spec is
+// "-" and writing the output to stdout unless an output file is given.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("jsonrpc-gen", flag.ContinueOnError)
+	spec := flags.String("spec", "", `path of the OpenRPC document, or "-" for stdin`)
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"),
+		"name of the generated package, defaults to the package running go:generate")
+	name := flags.String("name", codegen.DefaultName, "prefix of the generated client and server")
+	out := flags.String("out", "", "path of the generated file, stdout if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *spec == "" || *pkg == "" {
+		return errors.New("-spec and -package are required")
+	}
+
+	data, err := readSpec(*spec, stdin)
+	if err != nil {
+		return err
+	}
+	var doc openrpc.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("decode %s: %w", *spec, err)
+	}
+	src, err := codegen.Generate(&doc, codegen.Options{Package: *pkg, Name: *name})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, sourcePerm)
+}
+
+// readSpec reads the document at path, or from stdin if path is "-".
+func readSpec(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
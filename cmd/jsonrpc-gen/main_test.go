@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spec is a minimal OpenRPC document.
+const spec = `{"openrpc":"1.3.2","info":{"title":"API","version":"1"},"methods":[{"name":"ping"}]}`
+
+func TestRun(t *testing.T) {
+	t.Run("Reads stdin and writes stdout", func(t *testing.T) {
+		var out bytes.Buffer
+		args := []string{"-spec", "-", "-package", "api", "-name", "API"}
+		require.NoError(t, run(args, strings.NewReader(spec), &out))
+		assert.Contains(t, out.String(), "package api")
+		assert.Contains(t, out.String(), "type APIClient struct")
+	})
+
+	t.Run("Writes the output file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "api.go")
+		args := []string{"-spec", "-", "-package", "api", "-out", path}
+		require.NoError(t, run(args, strings.NewReader(spec), &bytes.Buffer{}))
+
+		src, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(src), "type ServiceClient struct")
+	})
+
+	t.Run("Package defaults to GOPACKAGE", func(t *testing.T) {
+		t.Setenv("GOPACKAGE", "generated")
+		var out bytes.Buffer
+		require.NoError(t, run([]string{"-spec", "-"}, strings.NewReader(spec), &out))
+		assert.Contains(t, out.String(), "package generated")
+	})
+
+	t.Run("Missing spec fails", func(t *testing.T) {
+		err := run([]string{"-package", "api"}, strings.NewReader(spec), &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid document fails", func(t *testing.T) {
+		args := []string{"-spec", "-", "-package", "api"}
+		assert.Error(t, run(args, strings.NewReader("{"), &bytes.Buffer{}))
+	})
+}
@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// WithCoalescing makes concurrent identical calls share one request on the wire: a call matched
+// by match, to the same method with the same params as a call in flight, waits for the response
+// to that call rather than being sent, and gets a copy of it carrying its own ID. Failures to
+// send the call are shared too. Only idempotent methods should be matched, for instance with
+// IdempotentMethods.
+//
+// A caller giving up on its context leaves the others waiting; the shared request is canceled
+// once all of them have given up. Coalescing runs as an interceptor placed after those added by
+// earlier WithInterceptors options, so that earlier interceptors see every call and later ones
+// the shared requests. Batches are not coalesced.
+func WithCoalescing(match func(req *Request) bool) ClientOption {
+	return WithInterceptors(newCoalescer(match).interceptor)
+}
+
+// coalescer shares the outcome of identical calls in flight.
+type coalescer struct {
+	match   func(req *Request) bool
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// newCoalescer returns a coalescer of the calls matched by match.
+func newCoalescer(match func(req *Request) bool) *coalescer {
+	return &coalescer{match: match, flights: make(map[string]*flight)}
+}
+
+// flight is a shared call in flight.
+type flight struct {
+	done    chan struct{}
+	resp    *Response
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// interceptor coalesces matching calls to next.
+func (co *coalescer) interceptor(next Invoker) Invoker {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		if req.IsNotification() || !co.match(req) {
+			return next(ctx, req)
+		}
+		key, err := CacheKey(req.Method, req.Params)
+		if err != nil {
+			return next(ctx, req)
+		}
+
+		f := co.join(ctx, key, req, next)
+		select {
+		case <-f.done:
+			if f.err != nil || f.resp == nil {
+				return f.resp, f.err
+			}
+			return f.resp.WithID(req.ID)
+		case <-ctx.Done():
+			co.leave(key, f)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// join returns the flight of the calls with the given key, starting it with req if there is none.
+// The flight runs detached from ctx, so that it outlives callers giving up.
+func (co *coalescer) join(ctx context.Context, key string, req *Request, next Invoker) *flight {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if f, ok := co.flights[key]; ok {
+		f.waiters++
+		return f
+	}
+
+	flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f := &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	co.flights[key] = f
+	go func() {
+		defer cancel()
+		f.resp, f.err = next(flightCtx, req)
+		co.forget(key, f)
+		close(f.done)
+	}()
+	return f
+}
+
+// leave removes a caller giving up from the flight, canceling it if it was the last one.
+func (co *coalescer) leave(key string, f *flight) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	f.cancel()
+	if co.flights[key] == f {
+		delete(co.flights, key)
+	}
+}
+
+// forget removes a completed flight, so that later calls are sent anew.
+func (co *coalescer) forget(key string, f *flight) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if co.flights[key] == f {
+		delete(co.flights, key)
+	}
+}
@@ -0,0 +1,167 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTransport forwards to srv, counting the payloads sent.
+func countingTransport(srv *Server, sent *atomic.Int32) *funcTransport {
+	return &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		sent.Add(1)
+		return srv.HandleMessage(ctx, payload), nil
+	}}
+}
+
+func TestClient_WithCoalescing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Concurrent identical calls share one request", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer()
+		require.NoError(t, srv.Register("block", g))
+		var sent atomic.Int32
+		co := newCoalescer(IdempotentMethods("block"))
+		client := NewClient(countingTransport(srv, &sent), WithInterceptors(co.interceptor))
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Go(func() {
+				var result string
+				assert.NoError(t, client.Call(ctx, "block", nil, &result))
+				assert.Equal(t, "done", result)
+			})
+		}
+		g.waitStarted(t, 1)
+		require.Eventually(t, func() bool {
+			return co.waiters(t, "block") == 5
+		}, time.Second, time.Millisecond)
+		g.open()
+		wg.Wait()
+		assert.Equal(t, int32(1), sent.Load())
+
+		require.NoError(t, client.Call(ctx, "block", nil, nil))
+		assert.Equal(t, int32(2), sent.Load(), "completed calls are not reused")
+	})
+
+	t.Run("Responses carry each caller's ID", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer()
+		require.NoError(t, srv.Register("block", g))
+		var sent atomic.Int32
+		co := newCoalescer(IdempotentMethods("block"))
+		client := NewClient(countingTransport(srv, &sent), WithInterceptors(co.interceptor))
+
+		resps := make([]*Response, 3)
+		var wg sync.WaitGroup
+		for i := range resps {
+			wg.Go(func() {
+				resp, err := client.invoke(ctx, NewRequestWithID("block", nil, int64(i+1)))
+				assert.NoError(t, err)
+				resps[i] = resp
+			})
+		}
+		g.waitStarted(t, 1)
+		require.Eventually(t, func() bool {
+			return co.waiters(t, "block") == 3
+		}, time.Second, time.Millisecond)
+		g.open()
+		wg.Wait()
+		for i, resp := range resps {
+			assert.Equal(t, int64(i+1), resp.IDOrNil())
+		}
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("Different params and unmatched methods are not coalesced", func(t *testing.T) {
+		var sent atomic.Int32
+		client := NewClient(countingTransport(newTestServer(t), &sent),
+			WithCoalescing(IdempotentMethods("sum")))
+		require.NoError(t, client.Call(ctx, "sum", []int{1}, nil))
+		require.NoError(t, client.Call(ctx, "sum", []int{2}, nil))
+		require.NoError(t, client.Call(ctx, "subtract", []int{2, 1}, nil))
+		require.NoError(t, client.Notify(ctx, "sum", []int{1}))
+		assert.Equal(t, int32(4), sent.Load())
+	})
+
+	t.Run("Failures are shared", func(t *testing.T) {
+		release := make(chan struct{})
+		var sent atomic.Int32
+		co := newCoalescer(IdempotentMethods("get"))
+		client := NewClient(&funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			sent.Add(1)
+			<-release
+			return nil, errors.New("connection refused")
+		}}, WithInterceptors(co.interceptor))
+
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Go(func() {
+				assert.EqualError(t, client.Call(ctx, "get", nil, nil), "connection refused")
+			})
+		}
+		require.Eventually(t, func() bool {
+			return co.waiters(t, "get") == 3
+		}, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("Callers giving up leave the others waiting", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer()
+		require.NoError(t, srv.Register("block", g))
+		var sent atomic.Int32
+		co := newCoalescer(IdempotentMethods("block"))
+		client := NewClient(countingTransport(srv, &sent), WithInterceptors(co.interceptor))
+
+		done := make(chan error, 1)
+		go func() { done <- client.Call(ctx, "block", nil, nil) }()
+		g.waitStarted(t, 1)
+
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, client.Call(short, "block", nil, nil), context.DeadlineExceeded)
+
+		g.open()
+		require.NoError(t, <-done)
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("Last caller giving up cancels the request", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer()
+		require.NoError(t, srv.Register("block", g))
+		var sent atomic.Int32
+		co := newCoalescer(IdempotentMethods("block"))
+		client := NewClient(countingTransport(srv, &sent), WithInterceptors(co.interceptor))
+
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, client.Call(short, "block", nil, nil), context.DeadlineExceeded)
+		require.Eventually(t, func() bool {
+			return g.running.Load() == 0
+		}, time.Second, time.Millisecond, "the handler saw the cancellation")
+	})
+}
+
+// waiters returns the number of callers waiting on the flight of method with no params.
+func (co *coalescer) waiters(t *testing.T, method string) int {
+	t.Helper()
+	key, err := CacheKey(method, nil)
+	require.NoError(t, err)
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if f, ok := co.flights[key]; ok {
+		return f.waiters
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+)
+
+// Codec marshals and unmarshals JSON. All encoding and decoding in the package goes through the
+// active codec, which is sonic configured by the performance profile unless replaced with
+// SetCodec. Alternative libraries plug in through small adapters, and some satisfy the interface
+// as they are, such as jsoniter's configurations:
+//
+//	jsonrpc.SetCodec(jsoniter.ConfigCompatibleWithStandardLibrary)
+//
+// Field lookups in raw messages, such as routing responses by ID, use sonic's parser regardless
+// of the codec.
+type Codec interface {
+	// Marshal returns the JSON encoding of v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes the JSON data into the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+
+	// Valid reports whether data is a valid JSON encoding.
+	Valid(data []byte) bool
+}
+
+// StdCodec is a Codec backed by encoding/json, for identical behavior to the standard library.
+var StdCodec Codec = stdCodec{}
+
+// stdCodec implements Codec with encoding/json.
+type stdCodec struct{}
+
+// Marshal implements Codec.
+func (stdCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (stdCodec) Unmarshal(data []byte, v any) error {
+	return unmarshalStd(data, v)
+}
+
+// Valid implements Codec.
+func (stdCodec) Valid(data []byte) bool {
+	return json.Valid(data)
+}
+
+// customCodec is the codec set with SetCodec, or nil to use sonic.
+var customCodec Codec
+
+// SetCodec replaces the codec used for all JSON operations in the package. A nil codec restores
+// sonic, configured by the performance profile. Like SetPerformanceProfile, it is meant to be
+// called once at startup, before messages are exchanged.
+func SetCodec(codec Codec) {
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	customCodec = codec
+}
+
+// GetCodec returns the codec currently used for JSON operations.
+func GetCodec() Codec {
+	return getCodec()
+}
+
+// getCodec returns the active codec.
+func getCodec() Codec {
+	profileMutex.RLock()
+	defer profileMutex.RUnlock()
+	if customCodec != nil {
+		return customCodec
+	}
+	return sonicAPI
+}
+
+// WithClientCodec marshals the params and unmarshals the results of the client's calls with
+// codec instead of the package codec set with SetCodec, such as a codec from NewTimeCodec for a
+// server with its own time conventions. WithCallCodec overrides it for a single call.
+func WithClientCodec(codec Codec) ClientOption {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// WithServerCodec unmarshals the params of requests, through Request.UnmarshalParams,
+// BindParams and services, and marshals the results of handlers with codec instead of the
+// package codec set with SetCodec. Raw results passed through with WithRawPassthrough
+// are sent as they are.
+func WithServerCodec(codec Codec) ServerOption {
+	return func(s *Server) {
+		s.codec = codec
+	}
+}
@@ -0,0 +1,86 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCodec is a Codec delegating to encoding/json and counting its calls.
+type countingCodec struct {
+	marshals   atomic.Int32
+	unmarshals atomic.Int32
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshals.Add(1)
+	return StdCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	c.unmarshals.Add(1)
+	return StdCodec.Unmarshal(data, v)
+}
+
+func (*countingCodec) Valid(data []byte) bool {
+	return StdCodec.Valid(data)
+}
+
+func TestSetCodec(t *testing.T) {
+	t.Run("Defaults to sonic", func(t *testing.T) {
+		assert.Equal(t, sonicAPI, GetCodec())
+	})
+
+	t.Run("Custom codec handles all operations", func(t *testing.T) {
+		codec := &countingCodec{}
+		SetCodec(codec)
+		defer SetCodec(nil)
+		assert.Equal(t, codec, GetCodec())
+
+		srv := NewServer()
+		sum := HandlerFunc(func(_ context.Context, req *Request) (any, error) {
+			var nums []int
+			if err := req.UnmarshalParams(&nums); err != nil {
+				return nil, err
+			}
+			return nums[0] + nums[1], nil
+		})
+		require.NoError(t, srv.Register("sum", sum))
+		transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, payload), nil
+		}}
+		client := NewClient(transport)
+		defer client.Close()
+
+		var result int
+		require.NoError(t, client.Call(context.Background(), "sum", []int{2, 3}, &result))
+		assert.Equal(t, 5, result)
+		assert.Positive(t, codec.marshals.Load())
+		assert.Positive(t, codec.unmarshals.Load())
+	})
+
+	t.Run("Nil restores the profile codec", func(t *testing.T) {
+		SetCodec(StdCodec)
+		SetCodec(nil)
+		assert.Equal(t, sonicAPI, GetCodec())
+	})
+}
+
+func TestStdCodec(t *testing.T) {
+	SetCodec(StdCodec)
+	defer SetCodec(nil)
+
+	req := NewRequestWithID("echo", map[string]any{"a": []any{1, "x"}}, "abc")
+	data, err := req.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"echo","params":{"a":[1,"x"]},"id":"abc"}`,
+		string(data))
+
+	decoded, err := DecodeRequest(data)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", decoded.ID)
+	assert.False(t, StdCodec.Valid([]byte(`{"a":`)))
+}
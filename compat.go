@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// v1Version is the version some JSON-RPC 1.0 peers put in the jsonrpc member.
+const v1Version = "1.0"
+
+// jsonRPCVersionJSON is the encoded jsonrpc member of JSON-RPC 2.0 messages.
+var jsonRPCVersionJSON = json.RawMessage(`"` + jsonRPCVersion + `"`)
+
+// WithV1Compat makes the server accept JSON-RPC 1.0 style requests by normalizing them with
+// NormalizeV1 before decoding. Replies are still encoded as JSON-RPC 2.0 responses.
+func WithV1Compat() ServerOption {
+	return func(s *Server) {
+		s.v1Compat = true
+	}
+}
+
+// WithV1CompatResponses makes the client accept JSON-RPC 1.0 style replies, such as those of
+// legacy daemons, by normalizing them with NormalizeV1 before decoding.
+func WithV1CompatResponses() ClientOption {
+	return func(c *Client) {
+		c.v1Compat = true
+	}
+}
+
+// NormalizeV1 rewrites an encoded JSON-RPC 1.0 style message or batch into its JSON-RPC 2.0
+// equivalent, so that it can be decoded with DecodeRequest or DecodeResponse:
+//
+//   - a missing jsonrpc member, or one set to "1.0", is set to "2.0"
+//   - in responses holding both members, a null error is dropped, and so is a null result
+//     alongside a non-null error
+//
+// In JSON-RPC 1.0 a request with a null id is a notification, which the 2.0 types already treat
+// the same way. Messages that need no rewriting are returned as-is.
+func NormalizeV1(msg []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(msg)
+	if !isBatchJSON(trimmed) {
+		return normalizeV1Message(trimmed)
+	}
+
+	var members []json.RawMessage
+	if err := getCodec().Unmarshal(trimmed, &members); err != nil {
+		return nil, fmt.Errorf("invalid batch: %w", err)
+	}
+	for i, member := range members {
+		normalized, err := normalizeV1Message(member)
+		if err != nil {
+			return nil, fmt.Errorf("batch member %d: %w", i, err)
+		}
+		members[i] = normalized
+	}
+	return getCodec().Marshal(members)
+}
+
+// normalizeV1Message rewrites a single JSON-RPC 1.0 style message.
+func normalizeV1Message(msg []byte) ([]byte, error) {
+	var members map[string]json.RawMessage
+	if err := getCodec().Unmarshal(msg, &members); err != nil || members == nil {
+		return nil, errors.New("message must be an object")
+	}
+
+	changed := false
+	if version, ok := members["jsonrpc"]; !ok || isV1Version(version) {
+		members["jsonrpc"] = jsonRPCVersionJSON
+		changed = true
+
+		// JSON-RPC 1.0 notifications are requests with a null id
+		if _, isRequest := members["method"]; isRequest && isJSONNull(members["id"]) {
+			delete(members, "id")
+		}
+	}
+
+	if _, isRequest := members["method"]; !isRequest {
+		rawResult, hasResult := members["result"]
+		rawError, hasError := members["error"]
+		switch {
+		case hasResult && hasError && isJSONNull(rawError):
+			delete(members, "error")
+			changed = true
+		case hasResult && hasError && isJSONNull(rawResult):
+			delete(members, "result")
+			changed = true
+		default:
+		}
+	}
+
+	if !changed {
+		return msg, nil
+	}
+	return getCodec().Marshal(members)
+}
+
+// isV1Version reports whether an encoded jsonrpc member declares version 1.0.
+func isV1Version(raw json.RawMessage) bool {
+	var version string
+	if err := getCodec().Unmarshal(raw, &version); err != nil {
+		return false
+	}
+	return version == v1Version
+}
+
+// isJSONNull reports whether raw holds the JSON null literal.
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
@@ -0,0 +1,135 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeV1(t *testing.T) {
+	cases := []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{
+			"Request without version",
+			`{"id":1,"method":"getblockcount","params":[]}`,
+			`{"jsonrpc":"2.0","id":1,"method":"getblockcount","params":[]}`,
+		},
+		{
+			"Version 1.0",
+			`{"jsonrpc":"1.0","id":1,"method":"getblockcount"}`,
+			`{"jsonrpc":"2.0","id":1,"method":"getblockcount"}`,
+		},
+		{
+			"Result with null error",
+			`{"result":3,"error":null,"id":1}`,
+			`{"jsonrpc":"2.0","id":1,"result":3}`,
+		},
+		{
+			"Null result with error",
+			`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":1}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`,
+		},
+		{
+			"Null result and null error",
+			`{"result":null,"error":null,"id":1}`,
+			`{"jsonrpc":"2.0","id":1,"result":null}`,
+		},
+		{
+			"Batch",
+			`[{"result":1,"error":null,"id":1},{"jsonrpc":"2.0","result":2,"id":2}]`,
+			`[{"jsonrpc":"2.0","id":1,"result":1},{"jsonrpc":"2.0","id":2,"result":2}]`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, err := NormalizeV1([]byte(tc.msg))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(normalized))
+		})
+	}
+
+	t.Run("2.0 message is returned as-is", func(t *testing.T) {
+		msg := []byte(`{"jsonrpc":"2.0","id":1,"result":3}`)
+		normalized, err := NormalizeV1(msg)
+		require.NoError(t, err)
+		assert.Equal(t, msg, normalized)
+	})
+
+	t.Run("Non-object", func(t *testing.T) {
+		_, err := NormalizeV1([]byte(`[1]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Decodes as 2.0 response", func(t *testing.T) {
+		normalized, err := NormalizeV1([]byte(`{"result":"ok","error":null,"id":"a"}`))
+		require.NoError(t, err)
+		resp, err := DecodeResponse(normalized)
+		require.NoError(t, err)
+		assert.Equal(t, "a", resp.IDOrNil())
+		assert.Nil(t, resp.Err())
+	})
+}
+
+func TestServer_V1Compat(t *testing.T) {
+	msg := []byte(`{"method":"sum","params":[1,2],"id":1}`)
+
+	t.Run("Rejected by default", func(t *testing.T) {
+		reply := newTestServer(t).HandleMessage(context.Background(), msg)
+		expected := `{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null}`
+		assert.JSONEq(t, expected, string(reply))
+	})
+
+	t.Run("Accepted in compat mode", func(t *testing.T) {
+		srv := NewServer(WithV1Compat())
+		require.NoError(t, srv.RegisterFunc("sum", func(context.Context, *Request) (any, error) {
+			return 3, nil
+		}))
+		reply := srv.HandleMessage(context.Background(), msg)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":3}`, string(reply))
+	})
+
+	t.Run("Null id is a notification", func(t *testing.T) {
+		srv := NewServer(WithV1Compat())
+		called := make(chan struct{}, 1)
+		require.NoError(t, srv.RegisterFunc("hello", func(context.Context, *Request) (any, error) {
+			called <- struct{}{}
+			return nil, nil
+		}))
+		reply := srv.HandleMessage(context.Background(), []byte(`{"method":"hello","id":null}`))
+		assert.Nil(t, reply)
+		assert.Len(t, called, 1)
+	})
+}
+
+func TestClient_V1CompatResponses(t *testing.T) {
+	transport := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+		return []byte(`{"result":3,"error":null,"id":1}`), nil
+	}}
+
+	t.Run("Rejected by default", func(t *testing.T) {
+		assert.Error(t, NewClient(transport).Call(context.Background(), "m", nil, nil))
+	})
+
+	t.Run("Accepted in compat mode", func(t *testing.T) {
+		var result int
+		client := NewClient(transport, WithV1CompatResponses())
+		require.NoError(t, client.Call(context.Background(), "m", nil, &result))
+		assert.Equal(t, 3, result)
+	})
+
+	t.Run("Error response", func(t *testing.T) {
+		transport := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return []byte(`{"result":null,"error":{"code":-5,"message":"not found"},"id":1}`), nil
+		}}
+		client := NewClient(transport, WithV1CompatResponses())
+		err := client.Call(context.Background(), "m", nil, nil)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, -5, rpcErr.Code)
+	})
+}
@@ -0,0 +1,199 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content codings supported for HTTP message bodies.
+const (
+	codingGzip    = "gzip"
+	codingDeflate = "deflate"
+)
+
+// defaultCompressionMinSize is the size below which bodies are sent uncompressed when
+// Compression.MinSize is zero, small enough that compression rarely pays off below it.
+const defaultCompressionMinSize = 1024
+
+// errUnsupportedEncoding is returned for bodies in a content coding other than gzip or deflate.
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// Compression configures the compression of HTTP message bodies.
+type Compression struct {
+	// MinSize is the size in bytes from which bodies are compressed, as compressing small
+	// messages costs more than it saves. Zero means 1024; a negative value compresses all bodies.
+	MinSize int
+
+	// Level is the compression level, from gzip.BestSpeed to gzip.BestCompression. Zero, like
+	// any invalid level, means gzip.DefaultCompression.
+	Level int
+}
+
+// WithCompression makes ServeHTTP compress replies of at least c.MinSize bytes with gzip or
+// deflate, as negotiated with the Accept-Encoding header of each request. Compressed requests
+// are accepted regardless of this option.
+func WithCompression(c Compression) ServerOption {
+	return func(s *Server) {
+		s.compression = &compressors{
+			minSize: c.minSize(),
+			gzip:    newCompressor(codingGzip, c.Level),
+			deflate: newCompressor(codingDeflate, c.Level),
+		}
+	}
+}
+
+// WithHTTPCompression makes the transport compress request bodies of at least c.MinSize bytes
+// with gzip, and accept replies compressed with gzip or deflate. The server must accept
+// compressed requests, as Server.ServeHTTP does.
+func WithHTTPCompression(c Compression) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.compression = &compressors{minSize: c.minSize(), gzip: newCompressor(codingGzip, c.Level)}
+	}
+}
+
+// minSize returns the effective minimum size of compressed bodies.
+func (c Compression) minSize() int {
+	if c.MinSize == 0 {
+		return defaultCompressionMinSize
+	}
+	return max(c.MinSize, 0)
+}
+
+// compressors holds the compressors of a server or transport.
+type compressors struct {
+	minSize int
+	gzip    *compressor
+	deflate *compressor
+}
+
+// negotiate returns the compressor to use for a body of size bytes given the Accept-Encoding
+// header value, or nil to send the body uncompressed.
+func (c *compressors) negotiate(size int, acceptEncoding string) *compressor {
+	if size < c.minSize || acceptEncoding == "" {
+		return nil
+	}
+	var gzipQ, deflateQ float64
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		coding, q := parseCoding(part)
+		switch coding {
+		case codingGzip, "x-gzip":
+			gzipQ = q
+		case codingDeflate:
+			deflateQ = q
+		case "*":
+			gzipQ = max(gzipQ, q)
+		default:
+		}
+	}
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return c.gzip
+	case deflateQ > 0 && c.deflate != nil:
+		return c.deflate
+	default:
+		return nil
+	}
+}
+
+// parseCoding parses an element of an Accept-Encoding header into its coding and quality.
+func parseCoding(part string) (string, float64) {
+	coding, params, _ := strings.Cut(part, ";")
+	q := 1.0
+	if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			q = parsed
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(coding)), q
+}
+
+// resetWriter is a compressing writer that can be reused.
+type resetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressor compresses bodies in a content coding, reusing its writers.
+type compressor struct {
+	coding string
+	pool   sync.Pool
+}
+
+// newCompressor creates a compressor for the coding at the given level.
+func newCompressor(coding string, level int) *compressor {
+	effective := level
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression || level == gzip.NoCompression {
+		effective = gzip.DefaultCompression
+	}
+	c := &compressor{coding: coding}
+	c.pool.New = func() any {
+		if coding == codingDeflate {
+			w, _ := zlib.NewWriterLevel(io.Discard, effective)
+			return w
+		}
+		w, _ := gzip.NewWriterLevel(io.Discard, effective)
+		return w
+	}
+	return c
+}
+
+// compress returns data compressed.
+func (c *compressor) compress(data []byte) ([]byte, error) {
+	w, ok := c.pool.Get().(resetWriter)
+	if !ok {
+		return nil, errors.New("invalid pooled compressor")
+	}
+	defer c.pool.Put(w)
+
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns a reader of body decoded from the content coding named by the
+// Content-Encoding header value.
+func decompress(contentEncoding string, body io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return body, nil
+	case codingGzip, "x-gzip":
+		return gzip.NewReader(body)
+	case codingDeflate:
+		return zlib.NewReader(body)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedEncoding, contentEncoding)
+	}
+}
+
+// compressReply returns a reply body compressed as negotiated with the request, setting the
+// response headers accordingly.
+func (c *compressors) compressReply(w http.ResponseWriter, r *http.Request, body []byte) []byte {
+	if c == nil {
+		return body
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	comp := c.negotiate(len(body), r.Header.Get("Accept-Encoding"))
+	if comp == nil {
+		return body
+	}
+	compressed, err := comp.compress(body)
+	if err != nil {
+		return body
+	}
+	w.Header().Set("Content-Encoding", comp.coding)
+	return compressed
+}
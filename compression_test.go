@@ -0,0 +1,161 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gzipped returns data compressed with gzip.
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCompressors_Negotiate(t *testing.T) {
+	c := &compressors{
+		minSize: 10,
+		gzip:    newCompressor(codingGzip, 0),
+		deflate: newCompressor(codingDeflate, 0),
+	}
+	cases := map[string]string{
+		"":                       "",
+		"gzip":                   codingGzip,
+		"deflate":                codingDeflate,
+		"br, deflate":            codingDeflate,
+		"gzip;q=0.5, deflate":    codingDeflate,
+		"GZIP, deflate;q=0.9":    codingGzip,
+		"gzip;q=0":               "",
+		"*":                      codingGzip,
+		"identity":               "",
+		"x-gzip; q=0.8, br; q=1": codingGzip,
+	}
+	for header, want := range cases {
+		comp := c.negotiate(100, header)
+		if want == "" {
+			assert.Nil(t, comp, header)
+			continue
+		}
+		require.NotNil(t, comp, header)
+		assert.Equal(t, want, comp.coding, header)
+	}
+	assert.Nil(t, c.negotiate(9, "gzip"), "below the minimum size")
+}
+
+func TestServer_WithCompression(t *testing.T) {
+	srv := NewServer(WithCompression(Compression{MinSize: 64}))
+	require.NoError(t, srv.RegisterFunc("echo", func(_ context.Context, req *Request) (any, error) {
+		return req.Params, nil
+	}))
+	post := func(body []byte, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(body))
+		req.Header = header
+		req.Header.Set("Content-Type", contentTypeJSON)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	large := fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo","params":["%s"],"id":1}`,
+		strings.Repeat("x", 1000))
+
+	t.Run("Compresses large replies as negotiated", func(t *testing.T) {
+		rec := post([]byte(large), http.Header{"Accept-Encoding": {"gzip"}})
+		assert.Equal(t, codingGzip, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		assert.Less(t, rec.Body.Len(), 200)
+
+		r, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		reply, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Contains(t, string(reply), strings.Repeat("x", 1000))
+
+		rec = post([]byte(large), http.Header{"Accept-Encoding": {"deflate"}})
+		assert.Equal(t, codingDeflate, rec.Header().Get("Content-Encoding"))
+		zr, err := zlib.NewReader(rec.Body)
+		require.NoError(t, err)
+		_, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	})
+
+	t.Run("Leaves small or unnegotiated replies alone", func(t *testing.T) {
+		small := `{"jsonrpc":"2.0","method":"echo","id":1}`
+		rec := post([]byte(small), http.Header{"Accept-Encoding": {"gzip"}})
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+
+		rec = post([]byte(large), http.Header{})
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("Decompresses requests", func(t *testing.T) {
+		rec := post(gzipped(t, large), http.Header{"Content-Encoding": {"gzip"}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"result"`)
+	})
+
+	t.Run("Rejects unsupported codings", func(t *testing.T) {
+		rec := post([]byte(large), http.Header{"Content-Encoding": {"br"}})
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+		rec = post([]byte("not gzip"), http.Header{"Content-Encoding": {"gzip"}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Size limit applies to the decompressed body", func(t *testing.T) {
+		limited := NewServer(WithMaxMessageSize(512))
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(gzipped(t, large)))
+		req.Header.Set("Content-Type", contentTypeJSON)
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		limited.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+func TestHTTPTransport_WithCompression(t *testing.T) {
+	srv := NewServer(WithCompression(Compression{MinSize: 64}))
+	require.NoError(t, srv.RegisterFunc("echo", func(_ context.Context, req *Request) (any, error) {
+		return req.Params, nil
+	}))
+	var requestEncoding, replyEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestEncoding = r.Header.Get("Content-Encoding")
+		srv.ServeHTTP(w, r)
+		replyEncoding = w.Header().Get("Content-Encoding")
+	}))
+	defer ts.Close()
+
+	transport := NewHTTPTransport(ts.URL, WithHTTPCompression(Compression{MinSize: 64}))
+	client := NewClient(transport)
+	defer client.Close()
+
+	t.Run("Large messages are compressed both ways", func(t *testing.T) {
+		var got []string
+		params := []string{strings.Repeat("y", 500)}
+		require.NoError(t, client.Call(context.Background(), "echo", params, &got))
+		assert.Equal(t, params, got)
+		assert.Equal(t, codingGzip, requestEncoding)
+		assert.Equal(t, codingGzip, replyEncoding)
+	})
+
+	t.Run("Small messages are not", func(t *testing.T) {
+		require.NoError(t, client.Call(context.Background(), "echo", nil, nil))
+		assert.Empty(t, requestEncoding)
+		assert.Empty(t, replyEncoding)
+	})
+}
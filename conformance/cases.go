@@ -0,0 +1,309 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Sections of the cases.
+const (
+	// SectionSpec holds the examples of the JSON-RPC 2.0 specification.
+	SectionSpec = "spec"
+
+	// SectionEdge holds edge cases beyond the examples of the specification.
+	SectionEdge = "edge"
+)
+
+// Replies shared by several cases.
+const (
+	wantParseError     = `{"jsonrpc": "2.0", "error": {"code": -32700}, "id": null}`
+	wantInvalidRequest = `{"jsonrpc": "2.0", "error": {"code": -32600}, "id": 1}`
+)
+
+// Cases returns the examples of section 7 of the JSON-RPC 2.0 specification, followed by edge
+// cases of IDs, versions, params, and batches. They call the methods registered by Register.
+func Cases() []Case {
+	spec := append(specCases(), specBatchCases()...)
+	edge := append(edgeCases(), edgeBatchCases()...)
+	for i := range spec {
+		spec[i].Section = SectionSpec
+	}
+	for i := range edge {
+		edge[i].Section = SectionEdge
+	}
+	return append(spec, edge...)
+}
+
+// specCases returns the examples of the specification.
+func specCases() []Case {
+	return []Case{
+		{
+			Name:    "positional params",
+			Request: `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+			Want:    `{"jsonrpc": "2.0", "result": 19, "id": 1}`,
+		},
+		{
+			Name:    "positional params in another order",
+			Request: `{"jsonrpc": "2.0", "method": "subtract", "params": [23, 42], "id": 2}`,
+			Want:    `{"jsonrpc": "2.0", "result": -19, "id": 2}`,
+		},
+		{
+			Name: "named params",
+			Request: `{"jsonrpc": "2.0", "method": "subtract",
+				"params": {"subtrahend": 23, "minuend": 42}, "id": 3}`,
+			Want: `{"jsonrpc": "2.0", "result": 19, "id": 3}`,
+		},
+		{
+			Name: "named params in another order",
+			Request: `{"jsonrpc": "2.0", "method": "subtract",
+				"params": {"minuend": 42, "subtrahend": 23}, "id": 4}`,
+			Want: `{"jsonrpc": "2.0", "result": 19, "id": 4}`,
+		},
+		{
+			Name:    "notification",
+			Request: `{"jsonrpc": "2.0", "method": "update", "params": [1, 2, 3, 4, 5]}`,
+		},
+		{
+			Name:    "notification of a missing method",
+			Request: `{"jsonrpc": "2.0", "method": "foobar"}`,
+		},
+		{
+			Name:    "missing method",
+			Request: `{"jsonrpc": "2.0", "method": "foobar", "id": "1"}`,
+			Want:    `{"jsonrpc": "2.0", "error": {"code": -32601}, "id": "1"}`,
+		},
+		{
+			Name:    "invalid JSON",
+			Request: `{"jsonrpc": "2.0", "method": "foobar, "params": "bar", "baz]`,
+			Want:    wantParseError,
+		},
+		{
+			Name:    "invalid request object",
+			Request: `{"jsonrpc": "2.0", "method": 1, "params": "bar"}`,
+			Want:    `{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}`,
+		},
+	}
+}
+
+// specBatchCases returns the examples of the specification sending batches.
+func specBatchCases() []Case {
+	return []Case{
+		{
+			Name: "batch of invalid JSON",
+			Request: `[{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+				{"jsonrpc": "2.0", "method"]`,
+			Want: wantParseError,
+		},
+		{
+			Name:    "empty batch",
+			Request: `[]`,
+			Want:    `{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}`,
+		},
+		{
+			Name:    "batch of an invalid request",
+			Request: `[1]`,
+			Want:    `[{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}]`,
+		},
+		{
+			Name:    "batch of invalid requests",
+			Request: `[1, 2, 3]`,
+			Want: `[{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}]`,
+		},
+		{
+			Name: "batch with notifications and errors",
+			Request: `[
+				{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+				{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+				{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "2"},
+				{"foo": "boo"},
+				{"jsonrpc": "2.0", "method": "foo.get", "params": {"name": "myself"}, "id": "5"},
+				{"jsonrpc": "2.0", "method": "get_data", "id": "9"}
+			]`,
+			Want: `[
+				{"jsonrpc": "2.0", "result": 7, "id": "1"},
+				{"jsonrpc": "2.0", "result": 19, "id": "2"},
+				{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32601}, "id": "5"},
+				{"jsonrpc": "2.0", "result": ["hello", 5], "id": "9"}
+			]`,
+		},
+		{
+			Name: "batch of notifications",
+			Request: `[
+				{"jsonrpc": "2.0", "method": "notify_sum", "params": [1, 2, 4]},
+				{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]}
+			]`,
+		},
+	}
+}
+
+// edgeCases returns the edge cases of single requests beyond the examples of the specification.
+func edgeCases() []Case {
+	return []Case{
+		{
+			Name:    "string ID",
+			Request: `{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": "a-b"}`,
+			Want:    `{"jsonrpc": "2.0", "result": 3, "id": "a-b"}`,
+		},
+		{
+			Name:    "zero ID",
+			Request: `{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": 0}`,
+			Want:    `{"jsonrpc": "2.0", "result": 1, "id": 0}`,
+		},
+		{
+			Name:    "negative ID",
+			Request: `{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": -7}`,
+			Want:    `{"jsonrpc": "2.0", "result": 1, "id": -7}`,
+		},
+		{
+			Name:    "ID beyond float64 precision",
+			Request: `{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": 9007199254740993}`,
+			Want:    `{"jsonrpc": "2.0", "result": 1, "id": 9007199254740993}`,
+		},
+		{
+			Name:    "missing params",
+			Request: `{"jsonrpc": "2.0", "method": "get_data", "id": 1}`,
+			Want:    `{"jsonrpc": "2.0", "result": ["hello", 5], "id": 1}`,
+		},
+		{
+			Name:    "invalid params",
+			Request: `{"jsonrpc": "2.0", "method": "subtract", "params": ["a", "b"], "id": 1}`,
+			Want:    `{"jsonrpc": "2.0", "error": {"code": -32602}, "id": 1}`,
+		},
+		{
+			Name:    "params of a primitive type",
+			Request: `{"jsonrpc": "2.0", "method": "sum", "params": 3, "id": 1}`,
+			Want:    wantInvalidRequest,
+			Codes:   []int{jsonrpc.InvalidParams},
+		},
+		{
+			Name:    "missing version",
+			Request: `{"method": "sum", "params": [1], "id": 1}`,
+			Want:    wantInvalidRequest,
+		},
+		{
+			Name:    "wrong version",
+			Request: `{"jsonrpc": "1.0", "method": "sum", "params": [1], "id": 1}`,
+			Want:    wantInvalidRequest,
+		},
+		{
+			Name:    "missing method member",
+			Request: `{"jsonrpc": "2.0", "params": [1], "id": 1}`,
+			Want:    wantInvalidRequest,
+		},
+	}
+}
+
+// edgeBatchCases returns the edge cases sending batches or empty messages.
+func edgeBatchCases() []Case {
+	return []Case{
+		{
+			Name:    "invalid JSON in a batch member",
+			Request: `[{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": 1}, {"jsonrpc": ]`,
+			Want:    wantParseError,
+		},
+		{
+			Name: "batch with a single notification",
+			Request: `[{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": 1},
+				{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]}]`,
+			Want: `[{"jsonrpc": "2.0", "result": 1, "id": 1}]`,
+		},
+		{
+			Name: "batch with a missing method notification",
+			Request: `[{"jsonrpc": "2.0", "method": "foobar"},
+				{"jsonrpc": "2.0", "method": "sum", "params": [2], "id": 2}]`,
+			Want: `[{"jsonrpc": "2.0", "result": 2, "id": 2}]`,
+		},
+		{
+			Name: "whitespace around a batch",
+			Request: " \n\t" +
+				`[{"jsonrpc": "2.0", "method": "sum", "params": [3], "id": 3}]` + "\n ",
+			Want: `[{"jsonrpc": "2.0", "result": 3, "id": 3}]`,
+		},
+		{
+			Name:    "empty message",
+			Request: ``,
+			Want:    wantParseError,
+			Codes:   []int{jsonrpc.InvalidRequest},
+		},
+	}
+}
+
+// errNotNumbers is returned by the methods of Register for params that are not numbers.
+var errNotNumbers = errors.New("params must be numbers")
+
+// subtractParams are the named params of subtract.
+type subtractParams struct {
+	Minuend    *float64 `json:"minuend"`
+	Subtrahend *float64 `json:"subtrahend"`
+}
+
+// Register registers on srv the methods called by the cases, as in the examples of the
+// specification: subtract, of positional or named params, sum, get_data, and the notifications
+// update, notify_hello, and notify_sum. Implementations other than this package implement them
+// alike.
+func Register(srv *jsonrpc.Server) error {
+	methods := map[string]jsonrpc.HandlerFunc{
+		"subtract": subtract,
+		"sum":      sum,
+		"get_data": func(context.Context, *jsonrpc.Request) (any, error) {
+			return json.RawMessage(`["hello", 5]`), nil
+		},
+		"update":       discard,
+		"notify_hello": discard,
+		"notify_sum":   discard,
+	}
+	for name, handler := range methods {
+		if err := srv.RegisterFunc(name, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subtract returns the difference of its positional or named params.
+func subtract(_ context.Context, req *jsonrpc.Request) (any, error) {
+	var positional []float64
+	if req.UnmarshalParams(&positional) == nil {
+		if len(positional) != 2 {
+			return nil, invalidParams(errNotNumbers)
+		}
+		return positional[0] - positional[1], nil
+	}
+	var named subtractParams
+	if err := req.UnmarshalParams(&named); err != nil {
+		return nil, invalidParams(err)
+	}
+	if named.Minuend == nil || named.Subtrahend == nil {
+		return nil, invalidParams(errNotNumbers)
+	}
+	return *named.Minuend - *named.Subtrahend, nil
+}
+
+// sum returns the sum of its positional params.
+func sum(_ context.Context, req *jsonrpc.Request) (any, error) {
+	var numbers []float64
+	if err := req.UnmarshalParams(&numbers); err != nil {
+		return nil, invalidParams(err)
+	}
+	var total float64
+	for _, n := range numbers {
+		total += n
+	}
+	return total, nil
+}
+
+// discard handles notifications.
+func discard(context.Context, *jsonrpc.Request) (any, error) {
+	return nil, nil
+}
+
+// invalidParams returns the InvalidParams error for err.
+func invalidParams(err error) error {
+	return &jsonrpc.Error{Code: jsonrpc.InvalidParams, Message: "Invalid params", Data: err.Error()}
+}
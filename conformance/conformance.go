@@ -0,0 +1,141 @@
+// Package conformance checks JSON-RPC 2.0 servers against the specification: it sends the
+// examples of the specification and edge cases of IDs, versions, params, and batches to a server
+// under test through any jsonrpc.Transport, and reports which replies conform. The server is
+// expected to implement the methods of the examples, which Register adds to a jsonrpc.Server:
+//
+//	report := conformance.Run(ctx, jsonrpc.NewHTTPTransport(gatewayURL))
+//	if report.Failed() > 0 {
+//		_ = report.Write(os.Stderr)
+//	}
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// tabPadding is the padding between the columns of a written report.
+const tabPadding = 2
+
+// Case is a message sent to the server under test along with the reply it must get.
+type Case struct {
+	// Name describes the case.
+	Name string
+
+	// Section is the section the case belongs to, SectionSpec or SectionEdge, set by Cases.
+	Section string
+
+	// Request is the message sent, as it is.
+	Request string
+
+	// Want is the reply expected, or empty if the message must get none. Responses must hold
+	// the same version, IDs, and results, compared as JSON values, and errors with the same
+	// codes, whatever their messages and data. The responses of a batch may come in any order.
+	// Responses to Parse error and Invalid Request errors may have a null id, as the
+	// specification allows when the id of the request could not be detected.
+	Want string
+
+	// Codes lists the error codes accepted in place of those of Want, for cases where the
+	// specification allows several.
+	Codes []int
+}
+
+// Result is the outcome of a case.
+type Result struct {
+	Case Case
+
+	// Reply is the reply received, empty if there was none.
+	Reply string
+
+	// Err describes why the reply does not conform, or is nil if it does.
+	Err error
+}
+
+// Passed reports whether the reply conforms.
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Report holds the results of a run, in the order of the cases.
+type Report struct {
+	Results []Result
+}
+
+// Passed returns the number of cases passed.
+func (r *Report) Passed() int {
+	passed := 0
+	for _, result := range r.Results {
+		if result.Passed() {
+			passed++
+		}
+	}
+	return passed
+}
+
+// Failed returns the number of cases failed.
+func (r *Report) Failed() int {
+	return len(r.Results) - r.Passed()
+}
+
+// Write writes the report as text to w, with a line per case and the reasons of failures.
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, tabPadding, ' ', 0)
+	for _, result := range r.Results {
+		status := "PASS"
+		if !result.Passed() {
+			status = "FAIL"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", status, result.Case.Section, result.Case.Name)
+		if !result.Passed() {
+			_, _ = fmt.Fprintf(tw, "\t\t  %v\n", result.Err)
+		}
+	}
+	_, _ = fmt.Fprintf(tw, "\n%d passed, %d failed\n", r.Passed(), r.Failed())
+	return tw.Flush()
+}
+
+// Run sends the cases, or those of Cases if none are given, through transport one at a time
+// and checks the replies. Transports failing with an *jsonrpc.HTTPError holding a body, as
+// HTTP transports do for the error statuses of JSON-RPC over HTTP, are taken to have replied
+// with the body.
+func Run(ctx context.Context, transport jsonrpc.Transport, cases ...Case) *Report {
+	run := cases
+	if len(run) == 0 {
+		run = Cases()
+	}
+	report := &Report{Results: make([]Result, 0, len(run))}
+	for _, c := range run {
+		report.Results = append(report.Results, runCase(ctx, transport, c))
+	}
+	return report
+}
+
+// runCase sends the message of c and checks the reply.
+func runCase(ctx context.Context, transport jsonrpc.Transport, c Case) Result {
+	reply, err := transport.RoundTrip(ctx, []byte(c.Request))
+	if body := httpErrorBody(err); len(body) > 0 {
+		reply, err = body, nil
+	}
+	result := Result{Case: c, Reply: string(reply)}
+	if err != nil {
+		result.Err = fmt.Errorf("exchange failed: %w", err)
+		return result
+	}
+	result.Err = checkReply(c, strings.TrimSpace(string(reply)))
+	return result
+}
+
+// httpErrorBody returns the body of an *jsonrpc.HTTPError in the chain of err, or nil.
+func httpErrorBody(err error) []byte {
+	var httpErr *jsonrpc.HTTPError
+	if !errors.As(err, &httpErr) {
+		return nil
+	}
+	return httpErr.Body
+}
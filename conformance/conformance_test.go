@@ -0,0 +1,104 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/jsonrpctest"
+)
+
+// newServer returns a server implementing the methods of the cases.
+func newServer(t *testing.T) *jsonrpc.Server {
+	t.Helper()
+	srv := jsonrpc.NewServer()
+	require.NoError(t, Register(srv))
+	return srv
+}
+
+// requirePassed fails t with the report unless every case passed.
+func requirePassed(t *testing.T, report *Report) {
+	t.Helper()
+	if report.Failed() > 0 {
+		var out bytes.Buffer
+		require.NoError(t, report.Write(&out))
+		t.Fatalf("cases failed:\n%s", out.String())
+	}
+}
+
+// fixedTransport replies to every message with itself.
+type fixedTransport string
+
+// RoundTrip implements jsonrpc.Transport.
+func (t fixedTransport) RoundTrip(context.Context, []byte) ([]byte, error) {
+	return []byte(t), nil
+}
+
+// Close implements jsonrpc.Transport.
+func (fixedTransport) Close() error {
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Server conforms in process", func(t *testing.T) {
+		report := Run(ctx, jsonrpctest.NewTransport(newServer(t)))
+		requirePassed(t, report)
+		assert.Len(t, report.Results, len(Cases()))
+	})
+
+	t.Run("Server conforms over HTTP", func(t *testing.T) {
+		httpSrv := httptest.NewServer(newServer(t))
+		defer httpSrv.Close()
+		requirePassed(t, Run(ctx, jsonrpc.NewHTTPTransport(httpSrv.URL)))
+	})
+
+	t.Run("Nonconforming replies fail", func(t *testing.T) {
+		reply := `{"jsonrpc":"2.0","result":19,"id":1}`
+		report := Run(ctx, fixedTransport(reply))
+		assert.Equal(t, 1, report.Passed(), "only the first case expects this reply")
+		assert.True(t, report.Results[0].Passed())
+		assert.Equal(t, reply, report.Results[1].Reply)
+		assert.EqualError(t, report.Results[1].Err, "id 1, want 2")
+	})
+
+	t.Run("Cases are given sections", func(t *testing.T) {
+		for _, c := range Cases() {
+			assert.Contains(t, []string{SectionSpec, SectionEdge}, c.Section, c.Name)
+		}
+	})
+}
+
+func TestReportWrite(t *testing.T) {
+	report := Run(context.Background(), jsonrpctest.NewTransport(newServer(t)),
+		Case{
+			Name:    "sum",
+			Section: SectionEdge,
+			Request: `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`,
+			Want:    `{"jsonrpc":"2.0","result":1,"id":1}`,
+		},
+		Case{
+			Name:    "wrong sum",
+			Section: SectionEdge,
+			Request: `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`,
+			Want:    `{"jsonrpc":"2.0","result":2,"id":1}`,
+		},
+	)
+	var out strings.Builder
+	require.NoError(t, report.Write(&out))
+	assert.Equal(t, strings.Join([]string{
+		"PASS  edge  sum",
+		"FAIL  edge  wrong sum",
+		"              result 1, want 2",
+		"",
+		"1 passed, 1 failed",
+		"",
+	}, "\n"), out.String())
+}
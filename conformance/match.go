@@ -0,0 +1,230 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// version is the version responses must hold.
+const version = "2.0"
+
+var (
+	errMissingReply = errors.New("missing reply")
+	errNotObject    = errors.New("response is not an object")
+	errMissingID    = errors.New("response without an id")
+	errResultOrErr  = errors.New("response must hold exactly one of result and error")
+	errNoMessage    = errors.New("error without a message")
+)
+
+// checkReply checks reply, trimmed of whitespace, against the reply wanted by c.
+func checkReply(c Case, reply string) error {
+	if c.Want == "" {
+		if reply != "" {
+			return fmt.Errorf("unexpected reply %s", reply)
+		}
+		return nil
+	}
+	if reply == "" {
+		return errMissingReply
+	}
+	want, err := decode(c.Want)
+	if err != nil {
+		return fmt.Errorf("invalid case: %w", err)
+	}
+	got, err := decode(reply)
+	if err != nil {
+		return fmt.Errorf("reply is not valid JSON: %s", reply)
+	}
+
+	wantBatch, isBatch := want.([]any)
+	gotBatch, gotIsBatch := got.([]any)
+	switch {
+	case isBatch && gotIsBatch:
+		return matchBatch(wantBatch, gotBatch, c.Codes)
+	case isBatch:
+		return fmt.Errorf("reply %s is not a batch", reply)
+	case gotIsBatch:
+		return fmt.Errorf("reply %s is a batch, want a single response", reply)
+	default:
+		return matchResponse(want, got, c.Codes)
+	}
+}
+
+// matchBatch checks that the responses of a batch match those wanted, in any order.
+func matchBatch(want, got []any, codes []int) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("%d responses, want %d", len(got), len(want))
+	}
+	used := make([]bool, len(got))
+	for _, w := range want {
+		var mismatch error
+		matched := slices.ContainsFunc(indices(got), func(i int) bool {
+			if used[i] {
+				return false
+			}
+			err := matchResponse(w, got[i], codes)
+			if err != nil {
+				mismatch = err
+				return false
+			}
+			used[i] = true
+			return true
+		})
+		if !matched {
+			return fmt.Errorf("no response matching %s: %w", encode(w), mismatch)
+		}
+	}
+	return nil
+}
+
+// indices returns the indices of values.
+func indices(values []any) []int {
+	indices := make([]int, len(values))
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+// matchResponse checks a response against the one wanted.
+func matchResponse(want, got any, codes []int) error {
+	w, ok := want.(map[string]any)
+	if !ok {
+		return fmt.Errorf("invalid case: %s is not a response", encode(want))
+	}
+	g, ok := got.(map[string]any)
+	if !ok {
+		return errNotObject
+	}
+	if g["jsonrpc"] != version {
+		return fmt.Errorf("version %s, want %q", encode(g["jsonrpc"]), version)
+	}
+	id, ok := g["id"]
+	if !ok {
+		return errMissingID
+	}
+	wantErr, wantsErr := w["error"].(map[string]any)
+	if !equalJSON(w["id"], id) && (id != nil || !undetectedID(wantErr)) {
+		return fmt.Errorf("id %s, want %s", encode(id), encode(w["id"]))
+	}
+	result, hasResult := g["result"]
+	rpcErr, hasError := g["error"]
+	if hasResult == hasError {
+		return errResultOrErr
+	}
+
+	if wantsErr {
+		if !hasError {
+			return fmt.Errorf("result %s, want an error", encode(result))
+		}
+		return matchError(wantErr, rpcErr, codes)
+	}
+	if !hasResult {
+		return fmt.Errorf("error %s, want a result", encode(rpcErr))
+	}
+	if !equalJSON(w["result"], result) {
+		return fmt.Errorf("result %s, want %s", encode(result), encode(w["result"]))
+	}
+	return nil
+}
+
+// undetectedID reports whether the wanted error is one for which the specification lets the id
+// of the response be null, having failed to detect the id of the request.
+func undetectedID(wantErr map[string]any) bool {
+	code, ok := wantErr["code"].(json.Number)
+	if !ok {
+		return false
+	}
+	return equalJSON(code, number(jsonrpc.ParseError)) ||
+		equalJSON(code, number(jsonrpc.InvalidRequest))
+}
+
+// number returns code as a decoded JSON number.
+func number(code int) json.Number {
+	return json.Number(strconv.Itoa(code))
+}
+
+// matchError checks that an error object has the code wanted, or one of codes, and a message.
+func matchError(want map[string]any, got any, codes []int) error {
+	obj, ok := got.(map[string]any)
+	if !ok {
+		return fmt.Errorf("error %s is not an object", encode(got))
+	}
+	code := obj["code"]
+	accepted := equalJSON(want["code"], code) || slices.ContainsFunc(codes, func(c int) bool {
+		return equalJSON(number(c), code)
+	})
+	if !accepted {
+		return fmt.Errorf("error code %s, want %s", encode(code), encode(want["code"]))
+	}
+	if _, ok := obj["message"].(string); !ok {
+		return errNoMessage
+	}
+	return nil
+}
+
+// decode decodes a JSON document, keeping numbers exact.
+func decode(data string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data")
+	}
+	return v, nil
+}
+
+// encode returns the JSON encoding of v for error messages.
+func encode(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// equalJSON reports whether two decoded JSON values are equal, comparing numbers by value.
+func equalJSON(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		return ok && equalNumbers(x, y)
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, equalJSON)
+	default:
+		return a == b
+	}
+}
+
+// equalNumbers reports whether two JSON numbers have the same value, such as 19 and 19.0.
+func equalNumbers(a, b json.Number) bool {
+	x, okX := new(big.Rat).SetString(string(a))
+	y, okY := new(big.Rat).SetString(string(b))
+	if !okX || !okY {
+		return a == b
+	}
+	return x.Cmp(y) == 0
+}
@@ -0,0 +1,119 @@
+package conformance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// mismatch is a reply along with the reason it does not conform.
+type mismatch struct {
+	reply string
+	want  string
+}
+
+func TestCheckReply(t *testing.T) {
+	result := Case{Want: `{"jsonrpc":"2.0","result":{"a":[1,"b"]},"id":1}`}
+	failure := Case{Want: `{"jsonrpc":"2.0","error":{"code":-32601},"id":"x"}`}
+
+	t.Run("Replies conform as JSON values", func(t *testing.T) {
+		for _, reply := range []string{
+			`{"jsonrpc":"2.0","result":{"a":[1,"b"]},"id":1}`,
+			`{"id":1.0,"result":{"a":[1e0,"b"]},"jsonrpc":"2.0"}`,
+		} {
+			assert.NoError(t, checkReply(result, reply), reply)
+		}
+		reply := `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Not found","data":1},"id":"x"}`
+		assert.NoError(t, checkReply(failure, reply), "messages and data are not compared")
+	})
+
+	t.Run("Nonconforming results", func(t *testing.T) {
+		for _, m := range []mismatch{
+			{``, "missing reply"},
+			{`{"jsonrpc":`, `reply is not valid JSON: {"jsonrpc":`},
+			{`[]`, "reply [] is a batch, want a single response"},
+			{`"2.0"`, "response is not an object"},
+			{`{"result":1,"id":1}`, `version null, want "2.0"`},
+			{`{"jsonrpc":"2.0","result":1}`, "response without an id"},
+			{`{"jsonrpc":"2.0","result":1,"id":"1"}`, `id "1", want 1`},
+			{`{"jsonrpc":"2.0","result":{"a":[1]},"id":1}`, `result {"a":[1]}, want {"a":[1,"b"]}`},
+			{`{"jsonrpc":"2.0","id":1}`, errResultOrErr.Error()},
+			{
+				`{"jsonrpc":"2.0","error":{"code":-32603,"message":"x"},"id":1}`,
+				`error {"code":-32603,"message":"x"}, want a result`,
+			},
+		} {
+			assert.EqualError(t, checkReply(result, m.reply), m.want, m.reply)
+		}
+	})
+
+	t.Run("Nonconforming errors", func(t *testing.T) {
+		for _, m := range []mismatch{
+			{`{"jsonrpc":"2.0","result":1,"id":"x"}`, "result 1, want an error"},
+			{
+				`{"jsonrpc":"2.0","error":"Method not found","id":"x"}`,
+				`error "Method not found" is not an object`,
+			},
+			{
+				`{"jsonrpc":"2.0","error":{"code":-32600,"message":"x"},"id":"x"}`,
+				"error code -32600, want -32601",
+			},
+			{`{"jsonrpc":"2.0","error":{"code":-32601},"id":"x"}`, "error without a message"},
+			{
+				`{"jsonrpc":"2.0","error":{"code":-32601,"message":"x"},"id":null}`,
+				`id null, want "x"`,
+			},
+		} {
+			assert.EqualError(t, checkReply(failure, m.reply), m.want, m.reply)
+		}
+	})
+
+	t.Run("Cases without a reply", func(t *testing.T) {
+		assert.NoError(t, checkReply(Case{}, ""))
+		assert.EqualError(t, checkReply(Case{}, "[]"), "unexpected reply []")
+	})
+
+	t.Run("Alternative codes and null IDs", func(t *testing.T) {
+		c := Case{
+			Want:  `{"jsonrpc":"2.0","error":{"code":-32600},"id":1}`,
+			Codes: []int{jsonrpc.InvalidParams},
+		}
+		reply := func(code, id string) string {
+			return `{"jsonrpc":"2.0","error":{"code":` + code + `,"message":"x"},"id":` + id + `}`
+		}
+		assert.NoError(t, checkReply(c, reply("-32602", "1")))
+		assert.NoError(t, checkReply(c, reply("-32600", "null")), "invalid requests may get null")
+		assert.Error(t, checkReply(c, reply("-32600", "2")))
+	})
+
+	t.Run("Batches match in any order", func(t *testing.T) {
+		one, two := `{"jsonrpc":"2.0","result":1,"id":1}`, `{"jsonrpc":"2.0","result":2,"id":2}`
+		batch := Case{Want: "[" + one + "," + two + "]"}
+		require.NoError(t, checkReply(batch, "["+two+","+one+"]"))
+
+		assert.EqualError(t, checkReply(batch, "["+one+"]"), "1 responses, want 2")
+		assert.EqualError(t, checkReply(batch, "["+one+","+one+"]"),
+			`no response matching {"id":2,"jsonrpc":"2.0","result":2}: id 1, want 2`)
+		assert.EqualError(t, checkReply(batch, one), "reply "+one+" is not a batch")
+	})
+}
+
+func TestEqualJSON(t *testing.T) {
+	decoded := func(data string) any {
+		v, err := decode(data)
+		require.NoError(t, err)
+		return v
+	}
+	assert.True(t, equalJSON(decoded(`9007199254740993`), decoded(`9007199254740993.0`)))
+	assert.False(t, equalJSON(decoded(`9007199254740993`), decoded(`9007199254740992`)),
+		"numbers are compared exactly")
+	assert.False(t, equalJSON(decoded(`{"a":1}`), decoded(`{"a":1,"b":null}`)))
+	assert.False(t, equalJSON(decoded(`[1,2]`), decoded(`[2,1]`)))
+	assert.False(t, equalJSON(decoded(`"1"`), decoded(`1`)))
+
+	_, err := decode(`1 2`)
+	assert.Error(t, err)
+}
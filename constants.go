@@ -3,5 +3,12 @@ package jsonrpc
 const (
 	defaultChunkSize = 16 * 1024
 	errEmptyData     = "empty data"
+	errParamsType    = "params field must be either an array, an object, or nil"
+	errVersion       = "jsonrpc field is required to be exactly \"2.0\""
+	errMethodMissing = "method field is required"
 	jsonRPCVersion   = "2.0"
+
+	// maxSimpleIDDigits bounds the integer IDs decoded without the generic decoder, so that they
+	// cannot overflow an int64.
+	maxSimpleIDDigits = 18
 )
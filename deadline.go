@@ -0,0 +1,197 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strconv"
+	"time"
+)
+
+// TimeoutMetadataKey is the metadata key, sent as an HTTP header, carrying the time left to the
+// deadline of a call in whole milliseconds, as propagated by WithClientDeadlinePropagation.
+const TimeoutMetadataKey = "jsonrpc-timeout"
+
+// TimeoutMember is the params member carrying the time left to the deadline of a call in whole
+// milliseconds over stream transports, which carry no metadata, as propagated by
+// WithClientDeadlinePropagation.
+const TimeoutMember = "$timeout"
+
+// errCallerDeadline is the cause of handler contexts canceled by a propagated deadline.
+var errCallerDeadline = errors.New("caller deadline exceeded")
+
+// WithClientDeadlinePropagation makes the client send the time left to the deadline of the
+// context of every call, for a server with WithDeadlinePropagation to bound the handler, and the
+// calls it makes in turn, by the same deadline. The time travels as TimeoutMetadataKey on
+// request/response transports, and as the TimeoutMember params member on streams; calls with
+// positional params over a stream are sent without it. Calls whose context has no deadline are
+// sent unchanged. As metadata, the time sets apart the calls gathered by WithAutoBatching.
+func WithClientDeadlinePropagation() ClientOption {
+	return func(c *Client) {
+		c.propagateDeadline = true
+	}
+}
+
+// WithDeadlinePropagation makes the server bound the handling of every request by the deadline
+// its caller propagated with WithClientDeadlinePropagation, as received in TimeoutMetadataKey or
+// the TimeoutMember params member, which is removed from the params before dispatch. Once the
+// deadline passes, the handler's context is canceled and the request is answered with
+// ErrRequestTimeout. Propagated times that do not parse are ignored.
+func WithDeadlinePropagation() ServerOption {
+	return func(s *Server) {
+		s.propagateDeadline = true
+	}
+}
+
+// withDeadline wraps next, sending the time left to the deadline of each call.
+func (c *Client) withDeadline(next Invoker) Invoker {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return next(ctx, req)
+		}
+		ms := timeoutMillis(time.Until(deadline))
+		if !c.isStream() {
+			return next(AppendToOutgoingContext(ctx, TimeoutMetadataKey, ms), req)
+		}
+		params, ok := withMember(req.Params, TimeoutMember, ms)
+		if !ok {
+			return next(ctx, req)
+		}
+		timed := *req
+		timed.Params = params
+		return next(ctx, &timed)
+	}
+}
+
+// timeoutMillis formats d as whole milliseconds, rounded up so that a deadline about to pass is
+// still sent as one.
+func timeoutMillis(d time.Duration) string {
+	return strconv.FormatInt(int64(max((d+time.Millisecond-1)/time.Millisecond, 1)), decimal)
+}
+
+// withMember returns params, which must be nil or encode as an object, as an encoded object also
+// holding the member name with the raw JSON value.
+func withMember(params any, name, value string) (json.RawMessage, bool) {
+	members := make(map[string]json.RawMessage)
+	if params != nil {
+		raw, err := getCodec().Marshal(params)
+		if err != nil || !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			return nil, false
+		}
+		if err := getCodec().Unmarshal(raw, &members); err != nil {
+			return nil, false
+		}
+	}
+	members[name] = json.RawMessage(value)
+	encoded, err := getCodec().Marshal(members)
+	if err != nil {
+		return nil, false
+	}
+	return encoded, true
+}
+
+// invokeWithDeadline dispatches the request under the deadline propagated by its caller, if any,
+// answering with ErrRequestTimeout once it passes.
+func (s *Server) invokeWithDeadline(ctx context.Context, req *Request) (any, error) {
+	if !s.propagateDeadline {
+		return s.invokeWithTimeout(ctx, req)
+	}
+	timeout, stripped, ok := callerTimeout(ctx, req)
+	if !ok {
+		return s.invokeWithTimeout(ctx, stripped)
+	}
+
+	deadlineCtx, cancel := context.WithTimeoutCause(ctx, timeout, errCallerDeadline)
+	defer cancel()
+	result, err := s.invokeWithTimeout(deadlineCtx, stripped)
+	if err != nil && context.Cause(deadlineCtx) == errCallerDeadline {
+		return nil, ErrRequestTimeout
+	}
+	return result, err
+}
+
+// callerTimeout returns the time left to the deadline propagated with req, and req without the
+// TimeoutMember params member, if it held one.
+func callerTimeout(ctx context.Context, req *Request) (time.Duration, *Request, bool) {
+	value, stripped := takeMember(req, TimeoutMember)
+	if md, ok := IncomingMetadata(ctx); ok && md.Get(TimeoutMetadataKey) != "" {
+		value = md.Get(TimeoutMetadataKey)
+	}
+	ms, err := strconv.ParseInt(value, decimal, 64)
+	if err != nil || ms <= 0 {
+		return 0, stripped, false
+	}
+	return time.Duration(ms) * time.Millisecond, stripped, true
+}
+
+// takeMember returns the raw value of the named member of the object params of req, and a copy
+// of req without it. Requests without the member are returned as they are.
+func takeMember(req *Request, name string) (string, *Request) {
+	var value string
+	var rest any
+	switch params := req.Params.(type) {
+	case map[string]any:
+		v, ok := params[name]
+		if !ok {
+			return "", req
+		}
+		value = memberString(v)
+		others := maps.Clone(params)
+		delete(others, name)
+		if len(others) > 0 {
+			rest = others
+		}
+	case json.RawMessage:
+		v, others, ok := takeRawMember(params, name)
+		if !ok {
+			return "", req
+		}
+		value, rest = v, others
+	default:
+		return "", req
+	}
+
+	stripped := *req
+	stripped.Params = rest
+	return value, &stripped
+}
+
+// takeRawMember returns the raw value of the named member of the encoded object params, and the
+// other members encoded as an object, or nil if there are none.
+func takeRawMember(params json.RawMessage, name string) (string, any, bool) {
+	if !bytes.Contains(params, []byte(strconv.Quote(name))) {
+		return "", nil, false
+	}
+	var members map[string]json.RawMessage
+	if err := getCodec().Unmarshal(params, &members); err != nil {
+		return "", nil, false
+	}
+	value, ok := members[name]
+	if !ok {
+		return "", nil, false
+	}
+	delete(members, name)
+	if len(members) == 0 {
+		return string(value), nil, true
+	}
+	rest, err := getCodec().Marshal(members)
+	if err != nil {
+		return "", nil, false
+	}
+	return string(value), json.RawMessage(rest), true
+}
+
+// memberString returns a decoded member value holding a number as a string.
+func memberString(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineReport is the result of the deadline method under test.
+type deadlineReport struct {
+	Left   *int64         `json:"left"`
+	Params map[string]any `json:"params"`
+}
+
+// newDeadlineServer returns a server reporting the time left to the deadline of its handler in
+// milliseconds, and its object params, under the deadline method.
+func newDeadlineServer(t *testing.T, opts ...ServerOption) *Server {
+	t.Helper()
+	srv := NewServer(opts...)
+	report := func(ctx context.Context, req *Request) (any, error) {
+		var out deadlineReport
+		if deadline, ok := ctx.Deadline(); ok {
+			left := time.Until(deadline).Milliseconds()
+			out.Left = &left
+		}
+		if req.Params != nil {
+			_ = req.UnmarshalParams(&out.Params)
+		}
+		return out, nil
+	}
+	wait := func(ctx context.Context, _ *Request) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	require.NoError(t, srv.RegisterFunc("deadline", report))
+	require.NoError(t, srv.RegisterFunc("wait", wait))
+	return srv
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	srv := newDeadlineServer(t, WithDeadlinePropagation())
+
+	t.Run("Over HTTP as metadata", func(t *testing.T) {
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		client := NewClient(NewHTTPTransport(ts.URL), WithClientDeadlinePropagation())
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var got deadlineReport
+		require.NoError(t, client.Call(ctx, "deadline", map[string]any{"a": 1}, &got))
+		require.NotNil(t, got.Left)
+		assert.Greater(t, *got.Left, int64(1000))
+		assert.LessOrEqual(t, *got.Left, int64(2000))
+		assert.Equal(t, map[string]any{"a": float64(1)}, got.Params)
+
+		got = deadlineReport{}
+		require.NoError(t, client.Call(context.Background(), "deadline", nil, &got))
+		assert.Nil(t, got.Left)
+	})
+
+	t.Run("Over streams as a params member", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(context.Background(), serverEnd) }()
+		client := NewStreamClient(clientEnd, WithClientDeadlinePropagation())
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		for _, params := range []any{nil, map[string]any{"a": 1}} {
+			var got deadlineReport
+			require.NoError(t, client.Call(ctx, "deadline", params, &got))
+			require.NotNil(t, got.Left)
+			assert.Greater(t, *got.Left, int64(1000))
+			if params == nil {
+				assert.Nil(t, got.Params)
+			} else {
+				assert.Equal(t, map[string]any{"a": float64(1)}, got.Params)
+			}
+		}
+
+		var got deadlineReport
+		require.NoError(t, client.Call(ctx, "deadline", []any{1}, &got))
+		assert.Nil(t, got.Left, "positional params carry no deadline")
+	})
+
+	t.Run("Answers with a timeout once the deadline passes", func(t *testing.T) {
+		ctx := context.Background()
+		start := time.Now()
+		reply := srv.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"wait","params":{"$timeout":20}}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, RequestTimeout, resp.Err().Code)
+		assert.Less(t, time.Since(start), time.Second)
+
+		mdCtx := NewIncomingContext(ctx, MetadataPairs(TimeoutMetadataKey, "20"))
+		reply = srv.HandleMessage(mdCtx, []byte(`{"jsonrpc":"2.0","id":2,"method":"wait"}`))
+		resp, err = DecodeResponse(reply)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, RequestTimeout, resp.Err().Code)
+	})
+
+	t.Run("Ignores malformed times", func(t *testing.T) {
+		msg := `{"jsonrpc":"2.0","id":1,"method":"deadline","params":{"$timeout":"soon","b":2}}`
+		reply := srv.HandleMessage(context.Background(), []byte(msg))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		var got deadlineReport
+		require.NoError(t, resp.UnmarshalResult(&got))
+		assert.Nil(t, got.Left)
+		assert.Equal(t, map[string]any{"b": float64(2)}, got.Params)
+	})
+
+	t.Run("Strips the member from lazy params", func(t *testing.T) {
+		lazy := newDeadlineServer(t, WithDeadlinePropagation(), WithLazyParams())
+		reply := lazy.HandleMessage(context.Background(),
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"deadline","params":{"a":1,"$timeout":900}}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		var got deadlineReport
+		require.NoError(t, resp.UnmarshalResult(&got))
+		require.NotNil(t, got.Left)
+		assert.LessOrEqual(t, *got.Left, int64(900))
+		assert.Equal(t, map[string]any{"a": float64(1)}, got.Params)
+	})
+
+	t.Run("Leaves the member to servers without the option", func(t *testing.T) {
+		plain := newDeadlineServer(t)
+		reply := plain.HandleMessage(context.Background(),
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"deadline","params":{"$timeout":500}}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		var got deadlineReport
+		require.NoError(t, resp.UnmarshalResult(&got))
+		assert.Nil(t, got.Left)
+		assert.Equal(t, map[string]any{TimeoutMember: float64(500)}, got.Params)
+	})
+}
+
+func TestTimeoutMillis(t *testing.T) {
+	assert.Equal(t, "1", timeoutMillis(0))
+	assert.Equal(t, "1", timeoutMillis(-time.Second))
+	assert.Equal(t, "2", timeoutMillis(1500*time.Microsecond))
+	assert.Equal(t, "1500", timeoutMillis(1500*time.Millisecond))
+}
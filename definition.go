@@ -0,0 +1,109 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+)
+
+// MethodDef is the typed definition of a method taking params of type P and returning a result
+// of type R. The same value serves both sides: servers register its handler, and clients call
+// the method through it, so that a package shared by both is the one source of truth of the
+// method's name and types, checked at compile time:
+//
+//	var GetUser = jsonrpc.Method[GetUserParams, *User]("user.get", nil)
+//
+//	// Server side
+//	srv.RegisterMethods(GetUser.WithHandler(users.Get))
+//
+//	// Client side
+//	user, err := GetUser.Call(ctx, client, GetUserParams{ID: 7})
+//
+// Unlike RegisterService, dispatch involves no reflection beyond decoding the params.
+type MethodDef[P, R any] struct {
+	name    string
+	handler func(ctx context.Context, params P) (R, error)
+}
+
+// MethodDefinition is a method a server can register with RegisterMethods, such as a MethodDef.
+type MethodDefinition interface {
+	// Name returns the name of the method.
+	Name() string
+
+	// Handler returns the handler of the method, or nil if it has none.
+	Handler() Handler
+}
+
+// Method defines the method name taking params of type P and returning a result of type R,
+// served by handler. P must encode as a JSON array or object, such as a struct or a slice, as
+// params are sent as they are. Definitions shared with clients, which need no handler, can leave
+// it nil and have it set with WithHandler on the server.
+func Method[P, R any](
+	name string,
+	handler func(ctx context.Context, params P) (R, error),
+) MethodDef[P, R] {
+	return MethodDef[P, R]{name: name, handler: handler}
+}
+
+// Name returns the name of the method.
+func (m MethodDef[P, R]) Name() string {
+	return m.name
+}
+
+// WithHandler returns a copy of the definition served by handler.
+func (m MethodDef[P, R]) WithHandler(
+	handler func(ctx context.Context, params P) (R, error),
+) MethodDef[P, R] {
+	m.handler = handler
+	return m
+}
+
+// Handler returns a handler decoding the params of requests with DecodeParams and calling the
+// handler of the definition with them, or nil if the definition has none.
+func (m MethodDef[P, R]) Handler() Handler {
+	if m.handler == nil {
+		return nil
+	}
+	handler := m.handler
+	return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+		params, err := DecodeParams[P](req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, params)
+	})
+}
+
+// Call calls the method through client with params and returns its result. Errors and options
+// are as for Client.Call.
+func (m MethodDef[P, R]) Call(
+	ctx context.Context,
+	client *Client,
+	params P,
+	opts ...CallOption,
+) (R, error) {
+	return Call[R](ctx, client, m.name, params, opts...)
+}
+
+// Notify sends params to the method through client as a notification, as Client.Notify does.
+func (m MethodDef[P, R]) Notify(
+	ctx context.Context,
+	client *Client,
+	params P,
+	opts ...CallOption,
+) error {
+	return client.Notify(ctx, m.name, params, opts...)
+}
+
+// RegisterMethods registers the handlers of defs under their names, all of them or none if any
+// registration is invalid, such as for a definition without a handler or a name defined
+// twice. See Register.
+func (s *Server) RegisterMethods(defs ...MethodDefinition) error {
+	handlers := make(map[string]Handler, len(defs))
+	for _, def := range defs {
+		if _, ok := handlers[def.Name()]; ok {
+			return fmt.Errorf("method %q is defined twice", def.Name())
+		}
+		handlers[def.Name()] = def.Handler()
+	}
+	return s.registerAll(handlers)
+}
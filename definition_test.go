@@ -0,0 +1,107 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type defUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type defGetUserParams struct {
+	ID int `json:"id"`
+}
+
+// getUserDef is a definition shared by the server and client of the tests, without a handler.
+var getUserDef = Method[defGetUserParams, *defUser]("user.get", nil)
+
+func TestMethodDef(t *testing.T) {
+	ctx := context.Background()
+
+	getUser := func(_ context.Context, params defGetUserParams) (*defUser, error) {
+		if params.ID != 7 {
+			return nil, &Error{Code: InvalidParams, Message: "no such user"}
+		}
+		return &defUser{ID: 7, Name: "ann"}, nil
+	}
+
+	newPair := func(t *testing.T, defs ...MethodDefinition) *Client {
+		t.Helper()
+		srv := NewServer()
+		require.NoError(t, srv.RegisterMethods(defs...))
+		return NewClient(&funcTransport{
+			fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+				return srv.HandleMessage(ctx, payload), nil
+			},
+		})
+	}
+
+	t.Run("Shared definition serves both sides", func(t *testing.T) {
+		client := newPair(t, getUserDef.WithHandler(getUser))
+
+		user, err := getUserDef.Call(ctx, client, defGetUserParams{ID: 7})
+		require.NoError(t, err)
+		assert.Equal(t, &defUser{ID: 7, Name: "ann"}, user)
+	})
+
+	t.Run("Handler errors reach the caller", func(t *testing.T) {
+		client := newPair(t, getUserDef.WithHandler(getUser))
+
+		_, err := getUserDef.Call(ctx, client, defGetUserParams{ID: 1})
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, "no such user", rpcErr.Message)
+	})
+
+	t.Run("Params are bound like DecodeParams", func(t *testing.T) {
+		client := newPair(t, getUserDef.WithHandler(getUser))
+
+		var user defUser
+		require.NoError(t, client.Call(ctx, "user.get", []any{7}, &user))
+		assert.Equal(t, "ann", user.Name)
+
+		err := client.Call(ctx, "user.get", []any{"seven"}, &user)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+	})
+
+	t.Run("Notify", func(t *testing.T) {
+		got := make(chan string, 1)
+		logDef := Method("log", func(_ context.Context, lines []string) (struct{}, error) {
+			got <- lines[0]
+			return struct{}{}, nil
+		})
+		client := newPair(t, logDef)
+
+		require.NoError(t, logDef.Notify(ctx, client, []string{"hello"}))
+		assert.Equal(t, "hello", <-got)
+	})
+
+	t.Run("Definition without handler is not registered", func(t *testing.T) {
+		assert.Nil(t, getUserDef.Handler())
+		assert.Error(t, NewServer().RegisterMethods(getUserDef))
+	})
+
+	t.Run("Name defined twice", func(t *testing.T) {
+		srv := NewServer()
+		err := srv.RegisterMethods(getUserDef.WithHandler(getUser), getUserDef.WithHandler(getUser))
+		require.Error(t, err)
+		assert.Empty(t, srv.Methods())
+	})
+
+	t.Run("WithHandler leaves the shared definition as is", func(t *testing.T) {
+		def := getUserDef.WithHandler(func(context.Context, defGetUserParams) (*defUser, error) {
+			return nil, errors.New("unused")
+		})
+		assert.NotNil(t, def.Handler())
+		assert.Nil(t, getUserDef.Handler())
+		assert.Equal(t, "user.get", def.Name())
+	})
+}
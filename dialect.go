@@ -0,0 +1,162 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+)
+
+// ErrorRule maps upstream errors to a canonical error. A rule matches an error holding its Code,
+// unless zero, and whose message matches its Message, unless nil.
+type ErrorRule struct {
+	// Code is the upstream code matched, or zero for any code.
+	Code int
+
+	// Message, if set, must match the upstream message, such as `(?i)rate limit` for providers
+	// sharing a code between unrelated errors.
+	Message *regexp.Regexp
+
+	// Target is the canonical error matched errors are translated to, typically one of the
+	// package errors such as ErrRateLimited, or one registered in an ErrorRegistry.
+	Target *Error
+}
+
+// matches reports whether the rule matches rpcErr.
+func (r ErrorRule) matches(rpcErr *Error) bool {
+	if r.Code != 0 && r.Code != rpcErr.Code {
+		return false
+	}
+	return r.Message == nil || r.Message.MatchString(rpcErr.Message)
+}
+
+// ErrorDialect is the translation table of the errors of one kind of upstream, such as the
+// custom codes of a node implementation or an RPC provider, into canonical errors, so that
+// application logic handles them alike whichever endpoint answered. Rules are tried in order,
+// so specific rules precede catch-alls for the same code.
+type ErrorDialect struct {
+	rules []ErrorRule
+}
+
+// NewErrorDialect returns a dialect translating errors by rules:
+//
+//	infura := jsonrpc.NewErrorDialect(
+//		jsonrpc.ErrorRule{Code: -32005, Target: jsonrpc.ErrRateLimited},
+//		jsonrpc.ErrorRule{Code: -32000, Message: regexp.MustCompile(`(?i)busy|overloaded`),
+//			Target: jsonrpc.ErrServerBusy},
+//	)
+func NewErrorDialect(rules ...ErrorRule) *ErrorDialect {
+	return &ErrorDialect{rules: rules}
+}
+
+// Translate returns the canonical error for rpcErr, or false if no rule matches. The canonical
+// error holds the code and message of the target, or the upstream message if the target has
+// none, and the upstream error as its data, which UnmarshalData decodes into an Error.
+func (d *ErrorDialect) Translate(rpcErr *Error) (*Error, bool) {
+	if rpcErr == nil {
+		return nil, false
+	}
+	for _, rule := range d.rules {
+		if !rule.matches(rpcErr) {
+			continue
+		}
+		message := rule.Target.Message
+		if message == "" {
+			message = rpcErr.Message
+		}
+		return &Error{Code: rule.Target.Code, Message: message, Data: rpcErr}, true
+	}
+	return nil, false
+}
+
+// DialectTransport is a Transport translating the errors in the replies of an upstream endpoint
+// through its ErrorDialect. Wrapping each endpoint of a PoolTransport or QuorumTransport with the
+// dialect of its provider gives callers canonical errors regardless of the endpoint answering.
+// Replies without errors matching the dialect are passed through unchanged.
+type DialectTransport struct {
+	transport Transport
+	dialect   *ErrorDialect
+}
+
+// NewDialectTransport wraps transport, translating the errors of its replies with dialect.
+func NewDialectTransport(transport Transport, dialect *ErrorDialect) *DialectTransport {
+	return &DialectTransport{transport: transport, dialect: dialect}
+}
+
+// RoundTrip sends payload through the wrapped transport and translates the errors of the reply.
+func (t *DialectTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	reply, err := t.transport.RoundTrip(ctx, payload)
+	if err != nil {
+		return reply, err
+	}
+	return t.dialect.translateReply(reply), nil
+}
+
+// Close closes the wrapped transport.
+func (t *DialectTransport) Close() error {
+	return t.transport.Close()
+}
+
+// translateReply returns reply, a response or a batch of responses, with its errors translated.
+// Replies that cannot be decoded are returned as they are, for the client to report.
+func (d *ErrorDialect) translateReply(reply []byte) []byte {
+	if !bytes.Contains(reply, []byte(`"`+fieldError+`"`)) {
+		return reply
+	}
+	if jsonKind(reply) != '[' {
+		if translated, ok := d.translateResponse(reply); ok {
+			return translated
+		}
+		return reply
+	}
+
+	var resps []json.RawMessage
+	if err := getCodec().Unmarshal(reply, &resps); err != nil {
+		return reply
+	}
+	changed := false
+	for i, resp := range resps {
+		if translated, ok := d.translateResponse(resp); ok {
+			resps[i], changed = translated, true
+		}
+	}
+	if !changed {
+		return reply
+	}
+	translated, err := getCodec().Marshal(resps)
+	if err != nil {
+		return reply
+	}
+	return translated
+}
+
+// translateResponse returns resp with its error translated, or false if it has none matching the
+// dialect.
+func (d *ErrorDialect) translateResponse(resp json.RawMessage) (json.RawMessage, bool) {
+	var members map[string]json.RawMessage
+	if err := getCodec().Unmarshal(resp, &members); err != nil {
+		return nil, false
+	}
+	raw, ok := members[fieldError]
+	if !ok {
+		return nil, false
+	}
+	upstream := &Error{}
+	if err := getCodec().Unmarshal(raw, upstream); err != nil {
+		return nil, false
+	}
+	canonical, ok := d.Translate(upstream)
+	if !ok {
+		return nil, false
+	}
+	encoded, err := getCodec().Marshal(canonical)
+	if err != nil {
+		return nil, false
+	}
+	members[fieldError] = encoded
+	translated, err := getCodec().Marshal(members)
+	if err != nil {
+		return nil, false
+	}
+	return translated, true
+}
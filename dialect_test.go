@@ -0,0 +1,133 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errBlockNotFound is an application error a dialect translates to.
+var errBlockNotFound = &Error{Code: -32001, Message: "Block not found"}
+
+// testDialect translates the errors of a fictional provider.
+var testDialect = NewErrorDialect(
+	ErrorRule{Code: -32005, Target: ErrRateLimited},
+	ErrorRule{Code: -32000, Message: regexp.MustCompile(`^header not found`),
+		Target: errBlockNotFound},
+	ErrorRule{Code: -32000, Message: regexp.MustCompile(`(?i)too many requests`),
+		Target: ErrRateLimited},
+	ErrorRule{Code: 429, Target: &Error{Code: RateLimited}},
+)
+
+func TestErrorDialect(t *testing.T) {
+	t.Run("Rules match codes and messages in order", func(t *testing.T) {
+		translated, ok := testDialect.Translate(&Error{Code: -32005, Message: "daily limit"})
+		require.True(t, ok)
+		assert.Equal(t, RateLimited, translated.Code)
+		assert.Equal(t, msgRateLimited, translated.Message)
+
+		translated, ok = testDialect.Translate(&Error{Code: -32000, Message: "header not found"})
+		require.True(t, ok)
+		assert.ErrorIs(t, translated, errBlockNotFound)
+
+		translated, ok = testDialect.Translate(&Error{Code: -32000, Message: "Too Many Requests"})
+		require.True(t, ok)
+		assert.ErrorIs(t, translated, ErrRateLimited)
+
+		_, ok = testDialect.Translate(&Error{Code: -32000, Message: "execution reverted"})
+		assert.False(t, ok)
+		_, ok = testDialect.Translate(nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("Upstream errors are kept as data", func(t *testing.T) {
+		upstream := &Error{Code: 429, Message: "slow down", Data: "retry in 1s"}
+		translated, ok := testDialect.Translate(upstream)
+		require.True(t, ok)
+		assert.Equal(t, "slow down", translated.Message, "the target has no message")
+
+		var original Error
+		require.NoError(t, translated.UnmarshalData(&original))
+		assert.Equal(t, 429, original.Code)
+		assert.Equal(t, "retry in 1s", original.Data)
+	})
+
+	t.Run("Rules without a code match any code", func(t *testing.T) {
+		dialect := NewErrorDialect(ErrorRule{
+			Message: regexp.MustCompile(`capacity`),
+			Target:  ErrServerBusy,
+		})
+		translated, ok := dialect.Translate(&Error{Code: 7, Message: "over capacity"})
+		require.True(t, ok)
+		assert.Equal(t, ServerBusy, translated.Code)
+	})
+}
+
+func TestDialectTransport(t *testing.T) {
+	ctx := context.Background()
+
+	upstream := func(reply string) Transport {
+		return &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return []byte(reply), nil
+		}}
+	}
+
+	t.Run("Errors of calls are translated", func(t *testing.T) {
+		client := NewClient(NewDialectTransport(upstream(
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"request rate exceeded"}}`,
+		), testDialect))
+
+		err := client.Call(ctx, "eth_blockNumber", nil, nil)
+		require.ErrorIs(t, err, ErrRateLimited)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, msgRateLimited, rpcErr.Message)
+	})
+
+	t.Run("Batches are translated per response", func(t *testing.T) {
+		client := NewClient(NewDialectTransport(upstream(`[
+			{"jsonrpc":"2.0","id":"a","result":"0x1"},
+			{"jsonrpc":"2.0","id":"b","error":{"code":-32000,"message":"header not found"}},
+			{"jsonrpc":"2.0","id":"c","error":{"code":-32000,"message":"execution reverted"}}
+		]`), testDialect))
+
+		resps, err := client.CallBatch(ctx, []*Request{
+			NewRequestWithID("eth_blockNumber", nil, "a"),
+			NewRequestWithID("eth_getBlockByNumber", []any{"0x9"}, "b"),
+			NewRequestWithID("eth_call", []any{}, "c"),
+		})
+		require.NoError(t, err)
+		require.Len(t, resps, 3)
+		assert.Nil(t, resps[0].Err())
+		assert.ErrorIs(t, resps[1].Err(), errBlockNotFound)
+		assert.Equal(t, -32000, resps[2].Err().Code, "unmatched errors are kept")
+	})
+
+	t.Run("Replies without matching errors are unchanged", func(t *testing.T) {
+		for _, reply := range []string{
+			`{"jsonrpc":"2.0","id":1,"result":{"error":"not an error member"}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`,
+			`[{"jsonrpc":"2.0","id":1,"result":1}]`,
+			`not json "error"`,
+		} {
+			got, err := NewDialectTransport(upstream(reply), testDialect).RoundTrip(ctx, nil)
+			require.NoError(t, err)
+			assert.Equal(t, reply, string(got))
+		}
+	})
+
+	t.Run("Transport errors and close", func(t *testing.T) {
+		failing := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return nil, errors.New("unreachable")
+		}}
+		transport := NewDialectTransport(failing, testDialect)
+		_, err := transport.RoundTrip(ctx, nil)
+		require.EqualError(t, err, "unreachable")
+		require.NoError(t, transport.Close())
+		assert.True(t, failing.closed)
+	})
+}
@@ -0,0 +1,179 @@
+package jsonrpc
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jkbrsn/jsonrpc/openrpc"
+)
+
+// DiscoverMethod is the method name under which WithDiscovery serves the server's OpenRPC
+// document.
+const DiscoverMethod = "rpc.discover"
+
+// reservedPrefix is the prefix of the method names reserved for methods built into the server.
+const reservedPrefix = "rpc."
+
+// MethodInfo documents a method in the server's OpenRPC document.
+type MethodInfo struct {
+	// Summary and Description describe the method.
+	Summary     string
+	Description string
+
+	// Params is a value of the method's params type, such as a zero struct whose fields are
+	// documented as named params, as bound by DecodeParams. Methods registered with
+	// RegisterService default to their argument types.
+	Params any
+
+	// Result is a value of the method's result type. Methods registered with RegisterService
+	// default to their result type.
+	Result any
+
+	// Errors are the errors the method may return.
+	Errors []*Error
+
+	// Deprecated marks the method as deprecated.
+	Deprecated bool
+}
+
+// Describe sets the documentation of method in the server's OpenRPC document. Methods that are
+// not described are documented from their signature if registered with RegisterService, and as
+// taking and returning any value otherwise.
+func (s *Server) Describe(method string, info MethodInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.infos == nil {
+		s.infos = make(map[string]MethodInfo)
+	}
+	s.infos[method] = info
+}
+
+// WithDiscovery serves the server's OpenRPC document, as returned by OpenRPC, under the
+// rpc.discover method reserved for it by the OpenRPC specification. The method goes through
+// middleware and authorization like the registered ones.
+func WithDiscovery(info openrpc.Info) ServerOption {
+	return func(s *Server) {
+		s.registerBuiltin(DiscoverMethod, func(context.Context, *Request) (any, error) {
+			return s.OpenRPC(info), nil
+		})
+	}
+}
+
+// OpenRPC returns the OpenRPC document describing the registered methods, in name order, with
+// the schemas of their params and results derived from their Go types through their json and
+// description struct tags.
+func (s *Server) OpenRPC(info openrpc.Info) *openrpc.Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	methods := s.registry.Load().methods
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		if !strings.HasPrefix(name, reservedPrefix) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	reflector := openrpc.NewReflector()
+	doc := &openrpc.Document{
+		OpenRPC: openrpc.Version,
+		Info:    info,
+		Methods: make([]openrpc.Method, 0, len(names)),
+	}
+	for _, name := range names {
+		doc.Methods = append(doc.Methods, s.describeMethod(reflector, name))
+	}
+	doc.Components = reflector.Components()
+	return doc
+}
+
+// describeMethod returns the OpenRPC description of a registered method. The caller must hold
+// s.mu.
+func (s *Server) describeMethod(reflector *openrpc.Reflector, name string) openrpc.Method {
+	info, described := s.infos[name]
+	method := openrpc.Method{
+		Name:        name,
+		Summary:     info.Summary,
+		Description: info.Description,
+		Params:      []openrpc.ContentDescriptor{},
+		Deprecated:  info.Deprecated,
+	}
+	for _, e := range info.Errors {
+		method.Errors = append(method.Errors, openrpc.Error{
+			Code:    e.Code,
+			Message: e.Message,
+			Data:    e.Data,
+		})
+	}
+
+	var resultType reflect.Type
+	sm, isService := s.registry.Load().methods[name].(*serviceMethod)
+	switch {
+	case described && info.Params != nil:
+		method.Params = describeParams(reflector, reflect.TypeOf(info.Params))
+		method.ParamStructure = openrpc.Either
+	case isService:
+		method.Params, method.ParamStructure = sm.describeArgs(reflector)
+	default:
+	}
+	switch {
+	case described && info.Result != nil:
+		resultType = reflect.TypeOf(info.Result)
+	case isService && sm.hasResult:
+		resultType = sm.fn.Type.Out(0)
+	default:
+	}
+	if resultType != nil || !isService {
+		method.Result = &openrpc.ContentDescriptor{
+			Name:   "result",
+			Schema: reflector.Reflect(resultType),
+		}
+	}
+	return method
+}
+
+// describeParams returns the params of a params type: the fields of a struct, or a single param
+// otherwise.
+func describeParams(reflector *openrpc.Reflector, t reflect.Type) []openrpc.ContentDescriptor {
+	fields := openrpc.Fields(t)
+	if fields == nil {
+		return []openrpc.ContentDescriptor{{
+			Name:     "params",
+			Required: true,
+			Schema:   reflector.Reflect(t),
+		}}
+	}
+
+	params := make([]openrpc.ContentDescriptor, 0, len(fields))
+	for _, field := range fields {
+		params = append(params, openrpc.ContentDescriptor{
+			Name:        field.Name,
+			Description: field.Description,
+			Required:    field.Required,
+			Schema:      reflector.Reflect(field.Type),
+		})
+	}
+	return params
+}
+
+// describeArgs returns the params of a service method and how they are passed: by name for the
+// fields of a single struct argument, and by position otherwise.
+func (m *serviceMethod) describeArgs(
+	reflector *openrpc.Reflector,
+) ([]openrpc.ContentDescriptor, string) {
+	if len(m.argTypes) == 1 && openrpc.Fields(m.argTypes[0]) != nil {
+		return describeParams(reflector, m.argTypes[0]), openrpc.ByName
+	}
+
+	params := make([]openrpc.ContentDescriptor, 0, len(m.argTypes))
+	for i, t := range m.argTypes {
+		params = append(params, openrpc.ContentDescriptor{
+			Name:   "arg" + strconv.Itoa(i+1),
+			Schema: reflector.Reflect(t),
+		})
+	}
+	return params, openrpc.ByPosition
+}
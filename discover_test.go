@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc/openrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transferArgs are documented params.
+type transferArgs struct {
+	From   string `json:"from" description:"Sending account"`
+	To     string `json:"to"`
+	Amount int64  `json:"amount,omitempty"`
+}
+
+// receipt is a documented result.
+type receipt struct {
+	Hash string `json:"hash"`
+}
+
+// methodByName returns the method of doc with the given name.
+func methodByName(t *testing.T, doc *openrpc.Document, name string) openrpc.Method {
+	t.Helper()
+	for _, m := range doc.Methods {
+		if m.Name == name {
+			return m
+		}
+	}
+	t.Fatalf("method %s not documented", name)
+	return openrpc.Method{}
+}
+
+func TestServer_OpenRPC(t *testing.T) {
+	srv := newTestServer(t)
+	require.NoError(t, srv.RegisterService("user", &userService{}))
+	require.NoError(t, srv.Register("transfer", HandlerFunc(
+		func(context.Context, *Request) (any, error) { return receipt{}, nil })))
+	srv.Describe("transfer", MethodInfo{
+		Summary: "Moves funds",
+		Params:  transferArgs{},
+		Result:  receipt{},
+		Errors:  []*Error{{Code: -32050, Message: "Insufficient funds"}},
+	})
+
+	doc := srv.OpenRPC(openrpc.Info{Title: "Test", Version: "1.0.0"})
+	assert.Equal(t, openrpc.Version, doc.OpenRPC)
+	assert.Equal(t, "Test", doc.Info.Title)
+
+	t.Run("Methods are sorted", func(t *testing.T) {
+		names := make([]string, len(doc.Methods))
+		for i, m := range doc.Methods {
+			names[i] = m.Name
+		}
+		assert.IsNonDecreasing(t, names)
+		assert.Contains(t, names, "sum")
+	})
+
+	t.Run("Described methods", func(t *testing.T) {
+		m := methodByName(t, doc, "transfer")
+		assert.Equal(t, "Moves funds", m.Summary)
+		assert.Equal(t, openrpc.Either, m.ParamStructure)
+		require.Len(t, m.Params, 3)
+		assert.Equal(t, "from", m.Params[0].Name)
+		assert.Equal(t, "Sending account", m.Params[0].Description)
+		assert.True(t, m.Params[0].Required)
+		assert.False(t, m.Params[2].Required)
+		assert.Equal(t, "integer", m.Params[2].Schema.Type)
+		assert.Equal(t, "#/components/schemas/receipt", m.Result.Schema.Ref)
+		assert.Equal(t, []openrpc.Error{{Code: -32050, Message: "Insufficient funds"}}, m.Errors)
+
+		require.NotNil(t, doc.Components)
+		assert.Contains(t, doc.Components.Schemas["receipt"].Properties, "hash")
+	})
+
+	t.Run("Service methods", func(t *testing.T) {
+		get := methodByName(t, doc, "user.get")
+		assert.Equal(t, openrpc.ByName, get.ParamStructure)
+		require.Len(t, get.Params, 2)
+		assert.Equal(t, "id", get.Params[0].Name)
+		assert.Equal(t, "object", get.Result.Schema.Type)
+
+		add := methodByName(t, doc, "user.add")
+		assert.Equal(t, openrpc.ByPosition, add.ParamStructure)
+		require.Len(t, add.Params, 2)
+		assert.Equal(t, "arg2", add.Params[1].Name)
+		assert.Equal(t, "integer", add.Result.Schema.Type)
+
+		reset := methodByName(t, doc, "user.reset")
+		assert.Empty(t, reset.Params)
+		assert.Nil(t, reset.Result, "methods without a result have none")
+	})
+
+	t.Run("Undescribed handlers accept anything", func(t *testing.T) {
+		m := methodByName(t, doc, "sum")
+		assert.Empty(t, m.Params)
+		require.NotNil(t, m.Result)
+		assert.Equal(t, &openrpc.Schema{}, m.Result.Schema)
+	})
+}
+
+func TestServer_WithDiscovery(t *testing.T) {
+	srv := newTestServer(t)
+	WithDiscovery(openrpc.Info{Title: "Test", Version: "1.0.0"})(srv)
+
+	roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}
+	client := NewClient(&funcTransport{fn: roundTrip})
+	var doc openrpc.Document
+	require.NoError(t, client.Call(context.Background(), DiscoverMethod, nil, &doc))
+	assert.Equal(t, "Test", doc.Info.Title)
+	for _, m := range doc.Methods {
+		assert.NotEqual(t, DiscoverMethod, m.Name, "rpc.discover documents the other methods")
+	}
+
+	encoded, err := json.Marshal(srv.OpenRPC(doc.Info))
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"openrpc":"1.3.2"`)
+
+	assert.ErrorContains(t, srv.Register("rpc.other", HandlerFunc(
+		func(context.Context, *Request) (any, error) { return nil, nil })), "reserved")
+}
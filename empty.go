@@ -0,0 +1,105 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// EmptyParams selects how a client encodes the params of calls that have none, for servers
+// picky about their form: some reject a missing params member, others an empty array or object.
+type EmptyParams int
+
+const (
+	// EmptyParamsAsIs sends params as given: nil params are omitted, and empty arrays and
+	// objects are sent as they are.
+	EmptyParamsAsIs EmptyParams = iota
+
+	// EmptyParamsOmit omits the params member of calls with empty params.
+	EmptyParamsOmit
+
+	// EmptyParamsArray sends empty params as [].
+	EmptyParamsArray
+
+	// EmptyParamsObject sends empty params as {}.
+	EmptyParamsObject
+)
+
+// WithClientEmptyParams sets how the client encodes empty params: nil params, and params
+// encoding as null or as an empty array or object, of every call, notification, and batch
+// member it sends. Defaults to EmptyParamsAsIs.
+func WithClientEmptyParams(mode EmptyParams) ClientOption {
+	return func(c *Client) {
+		c.emptyParams = mode
+	}
+}
+
+// WithVoidResult makes the server answer void successes, calls whose handler returned neither a
+// result nor an error, with result instead of null, such as true or an empty object, for
+// clients that reject null results.
+func WithVoidResult(result any) ServerOption {
+	return func(s *Server) {
+		s.voidResult = result
+	}
+}
+
+// apply returns params in the form selected by the mode if they are empty, and as they are
+// otherwise.
+func (m EmptyParams) apply(params any) any {
+	if m == EmptyParamsAsIs || !isEmptyParams(params) {
+		return params
+	}
+	switch m {
+	case EmptyParamsOmit:
+		return nil
+	case EmptyParamsArray:
+		return json.RawMessage("[]")
+	case EmptyParamsObject:
+		return json.RawMessage("{}")
+	default:
+		return params
+	}
+}
+
+// isEmptyParams reports whether params are nil or encode as null or as an empty array or object.
+func isEmptyParams(params any) bool {
+	if raw, ok := params.(json.RawMessage); ok {
+		trimmed := bytes.TrimSpace(raw)
+		if len(trimmed) == 0 || isJSONNull(trimmed) {
+			return true
+		}
+		kind := jsonKind(trimmed)
+		return (kind == '[' || kind == '{') && len(bytes.TrimSpace(trimmed[1:len(trimmed)-1])) == 0
+	}
+	if params == nil {
+		return true
+	}
+	v := reflect.ValueOf(params)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// withEmptyParams returns reqs with their empty params in the form selected for the client,
+// copying the requests it changes.
+func (c *Client) withEmptyParams(reqs []*Request) []*Request {
+	if c.emptyParams == EmptyParamsAsIs {
+		return reqs
+	}
+	shaped := make([]*Request, len(reqs))
+	for i, req := range reqs {
+		shaped[i] = req
+		if !isEmptyParams(req.Params) {
+			continue
+		}
+		shapedReq := *req
+		shapedReq.Params = c.emptyParams.apply(req.Params)
+		shaped[i] = &shapedReq
+	}
+	return shaped
+}
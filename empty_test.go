@@ -0,0 +1,116 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTransport records the payloads it is sent, answering them with srv.
+type recordingTransport struct {
+	srv      *Server
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (t *recordingTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	t.mu.Lock()
+	t.payloads = append(t.payloads, string(payload))
+	t.mu.Unlock()
+	return t.srv.HandleMessage(ctx, payload), nil
+}
+
+func (*recordingTransport) Close() error {
+	return nil
+}
+
+func TestWithClientEmptyParams(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+	cases := []struct {
+		mode EmptyParams
+		want string
+	}{
+		{EmptyParamsOmit, `{"jsonrpc":"2.0","id":1,"method":"sum"}`},
+		{EmptyParamsArray, `{"jsonrpc":"2.0","id":1,"method":"sum","params":[]}`},
+		{EmptyParamsObject, `{"jsonrpc":"2.0","id":1,"method":"sum","params":{}}`},
+	}
+	for _, tc := range cases {
+		for _, params := range []any{nil, []int{}, []int(nil), map[string]any{},
+			json.RawMessage(" [ ] "), json.RawMessage("null")} {
+			transport := &recordingTransport{srv: srv}
+			client := NewClient(transport, WithClientEmptyParams(tc.mode),
+				WithIDGenerator(IDGeneratorFunc(func() any { return int64(1) })))
+			_ = client.Call(ctx, "sum", params, nil)
+			require.Len(t, transport.payloads, 1)
+			assert.JSONEq(t, tc.want, transport.payloads[0], "%v", params)
+		}
+	}
+
+	t.Run("Leaves other params as they are", func(t *testing.T) {
+		transport := &recordingTransport{srv: srv}
+		client := NewClient(transport, WithClientEmptyParams(EmptyParamsArray),
+			WithIDGenerator(IDGeneratorFunc(func() any { return int64(1) })))
+		var got int
+		require.NoError(t, client.Call(ctx, "sum", []int{1, 2}, &got))
+		assert.Equal(t, 3, got)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2]}`,
+			transport.payloads[0])
+	})
+
+	t.Run("Sends params as given by default", func(t *testing.T) {
+		transport := &recordingTransport{srv: srv}
+		client := NewClient(transport,
+			WithIDGenerator(IDGeneratorFunc(func() any { return int64(1) })))
+		_ = client.Call(ctx, "sum", []int{}, nil)
+		_ = client.Call(ctx, "sum", nil, nil)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"sum","params":[]}`,
+			transport.payloads[0])
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"sum"}`, transport.payloads[1])
+	})
+
+	t.Run("Notifications and batches", func(t *testing.T) {
+		transport := &recordingTransport{srv: srv}
+		client := NewClient(transport, WithClientEmptyParams(EmptyParamsArray))
+		require.NoError(t, client.Notify(ctx, "notify_hello", nil))
+		_, err := client.CallBatch(ctx, []*Request{
+			NewRequestWithID("sum", nil, int64(1)),
+			NewRequestWithID("sum", []int{1}, int64(2)),
+		})
+		require.NoError(t, err)
+		batch := client.NewBatch()
+		batch.Add("sum", nil)
+		require.NoError(t, client.SendBatch(ctx, batch))
+
+		require.Len(t, transport.payloads, 3)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","method":"notify_hello","params":[]}`,
+			transport.payloads[0])
+		assert.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"method":"sum","params":[]},`+
+			`{"jsonrpc":"2.0","id":2,"method":"sum","params":[1]}]`, transport.payloads[1])
+		assert.Contains(t, transport.payloads[2], `"params":[]`)
+	})
+}
+
+func TestWithVoidResult(t *testing.T) {
+	srv := NewServer(WithVoidResult(true))
+	void := func(context.Context, *Request) (any, error) { return nil, nil }
+	sum := func(context.Context, *Request) (any, error) { return 3, nil }
+	require.NoError(t, srv.RegisterFunc("notify_hello", void))
+	require.NoError(t, srv.RegisterFunc("sum", sum))
+	reply := srv.HandleMessage(context.Background(),
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"notify_hello"}`))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":true}`, string(reply))
+
+	reply = srv.HandleMessage(context.Background(),
+		[]byte(`{"jsonrpc":"2.0","id":2,"method":"sum","params":[1,2]}`))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":3}`, string(reply))
+
+	plain := newTestServer(t)
+	reply = plain.HandleMessage(context.Background(),
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"notify_hello"}`))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":null}`, string(reply))
+}
@@ -0,0 +1,91 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"mime"
+)
+
+// Encoding is a wire encoding other than JSON for the JSON-RPC envelope, such as MessagePack or
+// CBOR, to cut the size of messages on high-volume links. Messages are transcoded between JSON
+// and the encoding at the transport, so handlers, interceptors, and codecs see JSON as usual.
+// The msgpack and cbor packages provide implementations.
+type Encoding interface {
+	// ContentType returns the media type of encoded messages, used to negotiate the encoding
+	// over HTTP.
+	ContentType() string
+
+	// Encode transcodes a JSON message into the encoding.
+	Encode(msg []byte) ([]byte, error)
+
+	// Decode transcodes an encoded message into JSON.
+	Decode(data []byte) ([]byte, error)
+}
+
+// WithEncodings makes ServeHTTP accept requests in the given encodings besides JSON, selected by
+// their Content-Type header, and answer them in the same encoding. For streams, wrap the stream
+// with NewEncodedStream instead.
+func WithEncodings(encs ...Encoding) ServerOption {
+	return func(s *Server) {
+		s.encodings = append(s.encodings, encs...)
+	}
+}
+
+// encodingFor returns the server encoding whose media type is named by the Content-Type header
+// value, or nil if there is none.
+func (s *Server) encodingFor(value string) Encoding {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return nil
+	}
+	for _, enc := range s.encodings {
+		if enc.ContentType() == mediaType {
+			return enc
+		}
+	}
+	return nil
+}
+
+// WithHTTPEncoding makes the transport send requests in enc and ask for replies in it. Replies
+// in JSON, as sent by servers rejecting the encoding, are accepted as well.
+func WithHTTPEncoding(enc Encoding) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.encoding = enc
+	}
+}
+
+// encodedStream is a Stream exchanging messages in another encoding than JSON.
+type encodedStream struct {
+	Stream
+	enc Encoding
+}
+
+// NewEncodedStream wraps stream so that messages are written in enc and read from it, for both
+// peers of a connection configured with the same encoding. The stream must carry binary data
+// safely, such as one using HeaderFraming. Messages that fail to decode are passed on as they
+// are, so that the receiving server answers them with a parse error.
+func NewEncodedStream(stream Stream, enc Encoding) Stream {
+	return &encodedStream{Stream: stream, enc: enc}
+}
+
+// ReadMessage reads the next message and transcodes it into JSON.
+func (s *encodedStream) ReadMessage(ctx context.Context) ([]byte, error) {
+	data, err := s.Stream.ReadMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.enc.Decode(data)
+	if err != nil {
+		return data, nil
+	}
+	return msg, nil
+}
+
+// WriteMessage transcodes msg into the stream's encoding and writes it.
+func (s *encodedStream) WriteMessage(ctx context.Context, msg []byte) error {
+	data, err := s.enc.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return s.Stream.WriteMessage(ctx, data)
+}
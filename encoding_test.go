@@ -0,0 +1,134 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hexEncoding is an Encoding carrying messages in hex, for tests.
+type hexEncoding struct{}
+
+func (hexEncoding) ContentType() string {
+	return "application/x-hex"
+}
+
+func (hexEncoding) Encode(msg []byte) ([]byte, error) {
+	return []byte(hex.EncodeToString(msg)), nil
+}
+
+func (hexEncoding) Decode(data []byte) ([]byte, error) {
+	return hex.DecodeString(string(data))
+}
+
+func TestServer_WithEncodings(t *testing.T) {
+	srv := newTestServer(t)
+	WithEncodings(hexEncoding{})(srv)
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	encode := func(msg string) string {
+		return hex.EncodeToString([]byte(msg))
+	}
+
+	t.Run("Answers in the request encoding", func(t *testing.T) {
+		rec := post("application/x-hex; charset=utf-8",
+			encode(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-hex", rec.Header().Get("Content-Type"))
+
+		reply, err := hex.DecodeString(rec.Body.String())
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":3,"id":1}`, string(reply))
+	})
+
+	t.Run("Status follows the decoded reply", func(t *testing.T) {
+		rec := post("application/x-hex", encode(`{"jsonrpc":"2.0","method":"missing","id":1}`))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Undecodable body is a parse error", func(t *testing.T) {
+		rec := post("application/x-hex", "not hex")
+		reply, err := hex.DecodeString(rec.Body.String())
+		require.NoError(t, err)
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Equal(t, ParseError, resp.Err().Code)
+	})
+
+	t.Run("JSON is still accepted", func(t *testing.T) {
+		rec := post(contentTypeJSON, `{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`)
+		assert.Equal(t, contentTypeJSON, rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("Other media types are unsupported", func(t *testing.T) {
+		rec := post("application/msgpack", "")
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("HTTP transport", func(t *testing.T) {
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		client := NewClient(NewHTTPTransport(ts.URL, WithHTTPEncoding(hexEncoding{})))
+		defer client.Close()
+
+		var sum int
+		require.NoError(t, client.Call(context.Background(), "sum", []int{4, 5}, &sum))
+		assert.Equal(t, 9, sum)
+	})
+}
+
+func TestHTTPTransport_EncodingFallback(t *testing.T) {
+	var contentType string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"json","id":1}`))
+	}))
+	defer ts.Close()
+
+	transport := NewHTTPTransport(ts.URL, WithHTTPEncoding(hexEncoding{}))
+	reply, err := transport.RoundTrip(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-hex", contentType)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":"json","id":1}`, string(reply))
+}
+
+func TestNewEncodedStream(t *testing.T) {
+	local, remote := newStreamPair()
+	stream := NewEncodedStream(local, hexEncoding{})
+	defer stream.Close()
+	ctx := context.Background()
+
+	t.Run("Writes encoded messages", func(t *testing.T) {
+		require.NoError(t, stream.WriteMessage(ctx, []byte(`{"a":1}`)))
+		data, err := remote.ReadMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, hex.EncodeToString([]byte(`{"a":1}`)), string(data))
+	})
+
+	t.Run("Reads decoded messages", func(t *testing.T) {
+		require.NoError(t, remote.WriteMessage(ctx, []byte(hex.EncodeToString([]byte(`[1]`)))))
+		msg, err := stream.ReadMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, `[1]`, string(msg))
+	})
+
+	t.Run("Passes undecodable messages on", func(t *testing.T) {
+		require.NoError(t, remote.WriteMessage(ctx, []byte("zz")))
+		msg, err := stream.ReadMessage(ctx)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal([]byte("zz"), msg))
+	})
+}
@@ -0,0 +1,220 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errEmptyBatch is returned by Engine.SendBatch for empty batches.
+var errEmptyBatch = errors.New("batch is empty")
+
+// Event is something an Engine found in the data fed to it: a ResponseEvent or a RequestEvent.
+type Event interface {
+	event()
+}
+
+// ResponseEvent is a response received by the engine.
+type ResponseEvent struct {
+	// Response is the response.
+	Response *Response
+
+	// Matched is true if the response answers a call the engine sent and still had pending. It
+	// is false for responses to forgotten calls and to no call at all, such as the error a server
+	// sends with a null ID for a batch it cannot parse.
+	Matched bool
+}
+
+// RequestEvent is a request, notification, or batch of them sent by the peer. Requests are
+// answered with Engine.Respond or, for batches, Engine.RespondBatch.
+type RequestEvent struct {
+	// Requests holds the requests and notifications of the message.
+	Requests []*Request
+
+	// Batch is true if the message is a batch, even of a single member.
+	Batch bool
+}
+
+// event implements Event.
+func (ResponseEvent) event() {}
+
+// event implements Event.
+func (RequestEvent) event() {}
+
+// Engine is the JSON-RPC protocol of a peer without any I/O: it encodes the messages to send,
+// assigns request IDs, and correlates the responses fed to it with the calls in flight, leaving
+// the reading and writing of bytes, and the waiting, to its caller. It suits event loops and
+// schedulers of their own, such as io_uring, with which the bundled transports do not fit.
+//
+// Messages to send are queued and taken with NextOutgoing; data received is passed to Feed, which
+// returns what it holds as events. An Engine keeps no time, so calls the caller gives up on are
+// released with Forget. An Engine is not safe for concurrent use: it is meant to be driven from
+// a single loop.
+type Engine struct {
+	idGen    IDGenerator
+	pending  map[string]struct{}
+	outgoing [][]byte
+}
+
+// EngineOption configures an Engine.
+type EngineOption func(*Engine)
+
+// WithEngineIDGenerator sets how the engine generates the IDs of the requests created by Call.
+// Defaults to NewSequentialIDGenerator.
+func WithEngineIDGenerator(gen IDGenerator) EngineOption {
+	return func(e *Engine) {
+		e.idGen = gen
+	}
+}
+
+// NewEngine returns an Engine with no calls in flight.
+func NewEngine(opts ...EngineOption) *Engine {
+	e := &Engine{
+		idGen:   NewSequentialIDGenerator(),
+		pending: make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Call queues a request for method with params under a new ID, which it returns. The response
+// comes out of Feed as a matched ResponseEvent carrying that ID.
+func (e *Engine) Call(method string, params any) (any, error) {
+	req := NewRequestWithID(method, params, e.idGen.NextID())
+	if err := e.Send(req); err != nil {
+		return nil, err
+	}
+	return req.ID, nil
+}
+
+// Notify queues a notification for method with params.
+func (e *Engine) Notify(method string, params any) error {
+	return e.Send(NewNotification(method, params))
+}
+
+// Send queues a request or notification built by the caller. A request whose ID is already in
+// flight is rejected.
+func (e *Engine) Send(req *Request) error {
+	if !req.IsNotification() {
+		if _, ok := e.pending[idKey(req.ID)]; ok {
+			return errDuplicateID
+		}
+	}
+	msg, err := req.AppendJSON(nil)
+	if err != nil {
+		return err
+	}
+	if !req.IsNotification() {
+		e.pending[idKey(req.ID)] = struct{}{}
+	}
+	e.outgoing = append(e.outgoing, msg)
+	return nil
+}
+
+// SendBatch queues reqs as a single batch. Its responses come out of Feed one ResponseEvent per
+// request, in the order the peer sent them. Batches holding the same ID twice, or an ID already
+// in flight, are rejected.
+func (e *Engine) SendBatch(reqs []*Request) error {
+	if len(reqs) == 0 {
+		return errEmptyBatch
+	}
+	keys := make(map[string]struct{}, len(reqs))
+	for _, req := range reqs {
+		if req.IsNotification() {
+			continue
+		}
+		key := idKey(req.ID)
+		if _, ok := keys[key]; ok {
+			return fmt.Errorf("duplicate request id in batch: %v", req.ID)
+		}
+		if _, ok := e.pending[key]; ok {
+			return errDuplicateID
+		}
+		keys[key] = struct{}{}
+	}
+	msg, err := EncodeBatchRequest(reqs)
+	if err != nil {
+		return err
+	}
+	for key := range keys {
+		e.pending[key] = struct{}{}
+	}
+	e.outgoing = append(e.outgoing, msg)
+	return nil
+}
+
+// Respond queues the response to a request of a RequestEvent.
+func (e *Engine) Respond(resp *Response) error {
+	msg, err := resp.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	e.outgoing = append(e.outgoing, msg)
+	return nil
+}
+
+// RespondBatch queues the responses to the requests of a batch RequestEvent as a single batch.
+// Batches of notifications only get no reply, so nothing is queued for an empty resps.
+func (e *Engine) RespondBatch(resps []*Response) error {
+	if len(resps) == 0 {
+		return nil
+	}
+	msg, err := EncodeBatchResponse(resps)
+	if err != nil {
+		return err
+	}
+	e.outgoing = append(e.outgoing, msg)
+	return nil
+}
+
+// NextOutgoing takes the next message to send from the queue, returning false once it is empty.
+func (e *Engine) NextOutgoing() ([]byte, bool) {
+	if len(e.outgoing) == 0 {
+		return nil, false
+	}
+	msg := e.outgoing[0]
+	e.outgoing[0] = nil
+	e.outgoing = e.outgoing[1:]
+	return msg, true
+}
+
+// Feed parses a message received from the peer and returns the events it holds: one event per
+// response, or a single RequestEvent for requests and notifications. Malformed messages return
+// a *MessageError, as from ParseMessage, and change nothing.
+func (e *Engine) Feed(data []byte) ([]Event, error) {
+	msg, err := ParseMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Requests) > 0 {
+		return []Event{RequestEvent{Requests: msg.Requests, Batch: msg.Batch}}, nil
+	}
+
+	events := make([]Event, 0, len(msg.Responses))
+	for _, resp := range msg.Responses {
+		key := idKey(resp.IDOrNil())
+		_, matched := e.pending[key]
+		matched = matched && resp.IDOrNil() != nil
+		if matched {
+			delete(e.pending, key)
+		}
+		events = append(events, ResponseEvent{Response: resp, Matched: matched})
+	}
+	return events, nil
+}
+
+// Forget releases the call with the given ID, such as after the caller gave up waiting for it,
+// and reports whether it was in flight. A response arriving for it later comes out of Feed as an
+// unmatched ResponseEvent.
+func (e *Engine) Forget(id any) bool {
+	key := idKey(id)
+	_, ok := e.pending[key]
+	delete(e.pending, key)
+	return ok
+}
+
+// Pending returns the number of calls in flight.
+func (e *Engine) Pending() int {
+	return len(e.pending)
+}
@@ -0,0 +1,140 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drive sends every queued message of the engine to srv and feeds the replies back, returning
+// the events they hold.
+func drive(t *testing.T, e *Engine, srv *Server) []Event {
+	t.Helper()
+	var events []Event
+	for {
+		msg, ok := e.NextOutgoing()
+		if !ok {
+			return events
+		}
+		reply := srv.HandleMessage(context.Background(), msg)
+		if reply == nil {
+			continue
+		}
+		fed, err := e.Feed(reply)
+		require.NoError(t, err)
+		events = append(events, fed...)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	srv := newTestServer(t)
+
+	t.Run("Correlates calls", func(t *testing.T) {
+		e := NewEngine()
+		first, err := e.Call("sum", []int{1, 2})
+		require.NoError(t, err)
+		second, err := e.Call("sum", []int{1, 2})
+		require.NoError(t, err)
+		require.NoError(t, e.Notify("sum", []int{1, 2}))
+		assert.Equal(t, 2, e.Pending())
+
+		events := drive(t, e, srv)
+		require.Len(t, events, 2)
+		for i, id := range []any{first, second} {
+			event, ok := events[i].(ResponseEvent)
+			require.True(t, ok)
+			assert.True(t, event.Matched)
+			assert.Equal(t, idKey(id), idKey(event.Response.IDOrNil()))
+		}
+		assert.Zero(t, e.Pending())
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		e := NewEngine()
+		require.NoError(t, e.SendBatch([]*Request{
+			NewRequestWithID("sum", []int{1, 2}, int64(1)),
+			NewNotification("sum", []int{1, 2}),
+			NewRequestWithID("sum", []int{1, 2}, "two"),
+		}))
+		assert.Equal(t, 2, e.Pending())
+
+		events := drive(t, e, srv)
+		require.Len(t, events, 2)
+		for _, event := range events {
+			assert.True(t, event.(ResponseEvent).Matched)
+		}
+		assert.Zero(t, e.Pending())
+	})
+
+	t.Run("Rejects IDs in flight", func(t *testing.T) {
+		e := NewEngine()
+		req := NewRequestWithID("sum", nil, int64(7))
+		require.NoError(t, e.Send(req))
+		require.ErrorIs(t, e.Send(req), errDuplicateID)
+		require.ErrorIs(t, e.SendBatch([]*Request{req}), errDuplicateID)
+
+		other := NewRequestWithID("sum", nil, int64(8))
+		require.ErrorContains(t, e.SendBatch([]*Request{other, other}), "duplicate request id")
+		require.ErrorIs(t, e.SendBatch(nil), errEmptyBatch)
+		assert.Equal(t, 1, e.Pending())
+	})
+
+	t.Run("Forgotten calls are unmatched", func(t *testing.T) {
+		e := NewEngine(WithEngineIDGenerator(IDGeneratorFunc(func() any { return "fixed" })))
+		id, err := e.Call("sum", []int{1, 2})
+		require.NoError(t, err)
+		assert.Equal(t, "fixed", id)
+		assert.True(t, e.Forget(id))
+		assert.False(t, e.Forget(id))
+
+		events := drive(t, e, srv)
+		require.Len(t, events, 1)
+		assert.False(t, events[0].(ResponseEvent).Matched)
+
+		parseError := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"x"}}`
+		events, err = e.Feed([]byte(parseError))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.False(t, events[0].(ResponseEvent).Matched)
+	})
+
+	t.Run("Serves requests from the peer", func(t *testing.T) {
+		e := NewEngine()
+		events, err := e.Feed([]byte(`[{"jsonrpc":"2.0","id":1,"method":"ping"},` +
+			`{"jsonrpc":"2.0","method":"tick"}]`))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		event, ok := events[0].(RequestEvent)
+		require.True(t, ok)
+		assert.True(t, event.Batch)
+		require.Len(t, event.Requests, 2)
+		assert.Equal(t, "ping", event.Requests[0].Method)
+
+		resp, err := NewResponse(event.Requests[0].ID, "pong")
+		require.NoError(t, err)
+		require.NoError(t, e.RespondBatch([]*Response{resp}))
+		require.NoError(t, e.RespondBatch(nil))
+		msg, ok := e.NextOutgoing()
+		require.True(t, ok)
+		assert.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"result":"pong"}]`, string(msg))
+		_, ok = e.NextOutgoing()
+		assert.False(t, ok)
+
+		require.NoError(t, e.Respond(resp))
+		msg, ok = e.NextOutgoing()
+		require.True(t, ok)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"pong"}`, string(msg))
+	})
+
+	t.Run("Malformed data changes nothing", func(t *testing.T) {
+		e := NewEngine()
+		_, err := e.Call("sum", nil)
+		require.NoError(t, err)
+		_, err = e.Feed([]byte(`{"jsonrpc":"2.0","id":1,`))
+		var msgErr *MessageError
+		require.ErrorAs(t, err, &msgErr)
+		assert.Equal(t, 1, e.Pending())
+	})
+}
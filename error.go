@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -15,13 +16,94 @@ const (
 	ParseError          = -32700
 )
 
-// Error represents a JSON-RPC error.
+// msgServerError is the message ErrorFromCode uses for the implementation-defined server errors.
+const msgServerError = "Server error"
+
+// Errors for the codes predefined by the JSON-RPC 2.0 specification, for use with errors.Is and as
+// handler return values.
+var (
+	ErrParse          = &Error{Code: ParseError, Message: msgParseError}
+	ErrInvalidRequest = &Error{Code: InvalidRequest, Message: msgInvalidRequest}
+	ErrMethodNotFound = &Error{Code: MethodNotFound, Message: msgMethodNotFound}
+	ErrInvalidParams  = &Error{Code: InvalidParams, Message: msgInvalidParams}
+	ErrInternal       = &Error{Code: ServerSideException, Message: msgInternalError}
+)
+
+// Error represents a JSON-RPC error. Data may hold any value that can be marshaled; errors
+// decoded from a response hold it in its generic decoded form, which UnmarshalData converts into
+// a typed value.
 type Error struct {
 	Code    int    `json:"code,omitempty"`
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"` // Optional data field
 }
 
+// ErrorFromCode returns a new error with the given code and the standard message for it: the
+// message defined by the specification for predefined codes, "Server error" for the server error
+// range -32099 to -32000, and an empty message otherwise.
+func ErrorFromCode(code int) *Error {
+	var message string
+	switch {
+	case code == ParseError:
+		message = msgParseError
+	case code == InvalidRequest:
+		message = msgInvalidRequest
+	case code == MethodNotFound:
+		message = msgMethodNotFound
+	case code == InvalidParams:
+		message = msgInvalidParams
+	case code == ServerSideException:
+		message = msgInternalError
+	case code >= minServerErrorCode && code <= maxServerErrorCode:
+		message = msgServerError
+	default:
+	}
+	return &Error{Code: code, Message: message}
+}
+
+// IsCode reports whether err is, or wraps, an *Error with the given code.
+func IsCode(err error, code int) bool {
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr != nil && rpcErr.Code == code
+}
+
+// Is reports whether target is an *Error with the same code, so that errors.Is matches errors by
+// code regardless of their message and data:
+//
+//	if errors.Is(err, jsonrpc.ErrMethodNotFound) { ... }
+func (e *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	if !ok || e == nil || other == nil {
+		return false
+	}
+	return e.Code == other.Code
+}
+
+// WithData returns a copy of the error carrying the given data, leaving the receiver unchanged.
+func (e *Error) WithData(data any) *Error {
+	return &Error{Code: e.Code, Message: e.Message, Data: data}
+}
+
+// UnmarshalData decodes the error's data into the provided destination pointer. It returns an
+// error if the error carries no data.
+func (e *Error) UnmarshalData(dst any) error {
+	if e == nil || e.Data == nil {
+		return errors.New("error has no data")
+	}
+
+	data, ok := e.Data.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = getCodec().Marshal(e.Data); err != nil {
+			return fmt.Errorf("failed to marshal error data: %w", err)
+		}
+	}
+	return getCodec().Unmarshal(data, dst)
+}
+
 // Equals compares the contents of two JSON-RPC errors for equality.
 // Returns true if both errors have the same Code and Message.
 //
@@ -47,6 +129,11 @@ func (e *Error) Equals(other *Error) bool {
 	return true
 }
 
+// Error implements the error interface, allowing a JSON-RPC error to be returned as a Go error.
+func (e *Error) Error() string {
+	return e.String()
+}
+
 // IsEmpty returns true if the error is empty, which is if the error is nil or both code and message
 // are empty.
 //
@@ -78,7 +165,7 @@ func (e *Error) UnmarshalJSON(data []byte) error {
 
 	// 1. Unmarshal the error as a standard JSON-RPC error
 	type alias Error // Avoid infinite recursion by using an alias
-	if err := getSonicAPI().Unmarshal(data, (*alias)(e)); err == nil {
+	if err := getCodec().Unmarshal(data, (*alias)(e)); err == nil {
 		// If Code and Message are set, consider a valid error
 		if e.Code != 0 {
 			return nil
@@ -89,7 +176,7 @@ func (e *Error) UnmarshalJSON(data []byte) error {
 	errorStrWrapper := struct {
 		Error string `json:"error"`
 	}{}
-	err := getSonicAPI().Unmarshal(data, &errorStrWrapper)
+	err := getCodec().Unmarshal(data, &errorStrWrapper)
 	if err == nil && errorStrWrapper.Error != "" {
 		e.Code = ServerSideException
 		e.Message = errorStrWrapper.Error
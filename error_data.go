@@ -0,0 +1,81 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrorData is a standard payload for the data of errors, after the problem details of RFC 9457,
+// so that clients and servers agree on machine-readable error details instead of ad hoc maps.
+type ErrorData struct {
+	// Type identifies the kind of problem, such as a URI or a dotted name like
+	// "validation.failed", more specific than the error code.
+	Type string `json:"type,omitempty"`
+
+	// Detail explains this occurrence of the problem, for humans.
+	Detail string `json:"detail,omitempty"`
+
+	// Fields lists the offending params, for validation problems.
+	Fields []FieldError `json:"fields,omitempty"`
+
+	// Retryable reports whether the same call may succeed if sent again, as after a transient
+	// failure. WithRetry retries the calls drawing such errors.
+	Retryable bool `json:"retryable,omitempty"`
+
+	// CorrelationID identifies the failed call in the logs of the server, for support requests.
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// FieldError is a problem with one of the params of a call.
+type FieldError struct {
+	// Field is the path of the param, such as "user.email" or "items[2]".
+	Field string `json:"field"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// NewErrorData returns an error with the given code and its standard message, as returned by
+// ErrorFromCode, carrying data.
+func NewErrorData(code int, data ErrorData) *Error {
+	return ErrorFromCode(code).WithErrorData(data)
+}
+
+// WithErrorData returns a copy of the error carrying data, leaving the receiver unchanged.
+func (e *Error) WithErrorData(data ErrorData) *Error {
+	return e.WithData(&data)
+}
+
+// ErrorData returns the data of the error decoded as ErrorData. It returns false if the error
+// carries no data, or data that is not an object.
+func (e *Error) ErrorData() (*ErrorData, bool) {
+	if e == nil {
+		return nil, false
+	}
+	switch data := e.Data.(type) {
+	case *ErrorData:
+		return data, data != nil
+	case ErrorData:
+		return &data, true
+	default:
+	}
+	var raw json.RawMessage
+	if err := e.UnmarshalData(&raw); err != nil || jsonKind(raw) != '{' {
+		return nil, false
+	}
+	var data ErrorData
+	if err := getCodec().Unmarshal(raw, &data); err != nil {
+		return nil, false
+	}
+	return &data, true
+}
+
+// ErrorDataOf returns the ErrorData of err, if it is or wraps an *Error carrying one, as returned
+// by Call for error responses.
+func ErrorDataOf(err error) (*ErrorData, bool) {
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		return nil, false
+	}
+	return rpcErr.ErrorData()
+}
@@ -0,0 +1,72 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorData(t *testing.T) {
+	data := ErrorData{
+		Type:          "validation.failed",
+		Detail:        "the request has invalid fields",
+		Fields:        []FieldError{{Field: "user.email", Message: "must be an email address"}},
+		CorrelationID: "req-42",
+	}
+
+	t.Run("Travels in the data of error responses", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterFunc("signup", func(context.Context, *Request) (any, error) {
+			return nil, NewErrorData(InvalidParams, data)
+		}))
+		handle := func(ctx context.Context, msg []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, msg), nil
+		}
+		client := NewClient(&funcTransport{fn: handle})
+
+		err := client.Call(context.Background(), "signup", nil, nil)
+		require.True(t, IsCode(err, InvalidParams))
+		got, ok := ErrorDataOf(fmt.Errorf("signing up: %w", err))
+		require.True(t, ok)
+		assert.Equal(t, data, *got)
+	})
+
+	t.Run("Encodes with camel-case members, omitting zero ones", func(t *testing.T) {
+		encoded, err := getCodec().Marshal(NewErrorData(ServerSideException, ErrorData{
+			Type:      "upstream.unavailable",
+			Retryable: true,
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"code":-32603,"message":"Internal error",`+
+			`"data":{"type":"upstream.unavailable","retryable":true}}`, string(encoded))
+	})
+
+	t.Run("Leaves the receiver unchanged", func(t *testing.T) {
+		withData := ErrInternal.WithErrorData(data)
+		assert.Nil(t, ErrInternal.Data)
+		got, ok := withData.ErrorData()
+		require.True(t, ok)
+		assert.Equal(t, data, *got)
+	})
+
+	t.Run("Missing or foreign data", func(t *testing.T) {
+		for _, rpcErr := range []*Error{
+			nil,
+			ErrInternal,
+			ErrInternal.WithData("not an object"),
+			ErrInternal.WithData([]int{1}),
+		} {
+			_, ok := rpcErr.ErrorData()
+			assert.False(t, ok)
+		}
+		_, ok := ErrorDataOf(assert.AnError)
+		assert.False(t, ok)
+
+		got, ok := ErrInternal.WithData(map[string]any{"detail": "x", "other": 1}).ErrorData()
+		require.True(t, ok)
+		assert.Equal(t, "x", got.Detail)
+	})
+}
@@ -0,0 +1,122 @@
+package jsonrpc
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrorRegistry maps application errors to JSON-RPC errors, so that handlers can return plain Go
+// errors such as ErrNotFound and have them sent with an application-defined code, and clients can
+// map those codes back to the same Go errors. An ErrorRegistry is safe for concurrent use.
+type ErrorRegistry struct {
+	mu      sync.RWMutex
+	entries []errorEntry
+	byCode  map[int]error
+}
+
+// errorEntry is a registered mapping from matching errors to a code and message.
+type errorEntry struct {
+	matches func(err error) bool
+	code    int
+	message string
+}
+
+// NewErrorRegistry creates an empty ErrorRegistry.
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{byCode: make(map[int]error)}
+}
+
+// Register maps target, and every error wrapping it, to the given code and message. An empty
+// message uses the text of the returned error. Clients map the code back to target.
+func (r *ErrorRegistry) Register(target error, code int, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, errorEntry{
+		matches: func(err error) bool { return errors.Is(err, target) },
+		code:    code,
+		message: message,
+	})
+	r.byCode[code] = target
+}
+
+// RegisterErrorType maps every error of type T, or wrapping one, to the given code and message.
+// An empty message uses the text of the returned error. As a type cannot be rebuilt from a code,
+// the mapping only applies to errors returned by handlers.
+func RegisterErrorType[T error](r *ErrorRegistry, code int, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, errorEntry{
+		matches: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+		code:    code,
+		message: message,
+	})
+}
+
+// ToError returns the JSON-RPC error for err if it matches a registration, trying registrations
+// in the order they were made.
+func (r *ErrorRegistry) ToError(err error) (*Error, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, entry := range r.entries {
+		if !entry.matches(err) {
+			continue
+		}
+		message := entry.message
+		if message == "" {
+			message = err.Error()
+		}
+		return &Error{Code: entry.code, Message: message}, true
+	}
+	return nil, false
+}
+
+// FromError returns an error that wraps both rpcErr and the Go error registered for its code, so
+// that errors.Is matches the registered error and errors.As still extracts the *Error. Without a
+// registration for the code, rpcErr is returned as-is.
+func (r *ErrorRegistry) FromError(rpcErr *Error) error {
+	r.mu.RLock()
+	target, ok := r.byCode[rpcErr.Code]
+	r.mu.RUnlock()
+
+	if !ok {
+		return rpcErr
+	}
+	return &mappedError{rpcErr: rpcErr, target: target}
+}
+
+// WithErrorRegistry makes the server map handler errors through reg before falling back to an
+// internal error. Handler errors that are, or wrap, an *Error are still sent as-is.
+func WithErrorRegistry(reg *ErrorRegistry) ServerOption {
+	return func(s *Server) {
+		s.errors = reg
+	}
+}
+
+// WithClientErrorRegistry makes Call map JSON-RPC errors back to the Go errors registered in reg.
+func WithClientErrorRegistry(reg *ErrorRegistry) ClientOption {
+	return func(c *Client) {
+		c.errors = reg
+	}
+}
+
+// mappedError is a JSON-RPC error mapped back to a registered Go error.
+type mappedError struct {
+	rpcErr *Error
+	target error
+}
+
+// Error returns the text of the JSON-RPC error.
+func (e *mappedError) Error() string {
+	return e.rpcErr.Error()
+}
+
+// Unwrap returns both the JSON-RPC error and the registered error.
+func (e *mappedError) Unwrap() []error {
+	return []error{e.rpcErr, e.target}
+}
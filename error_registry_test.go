@@ -0,0 +1,103 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNotFound = errors.New("not found")
+
+// quotaError is an application error type for registry tests.
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", e.limit)
+}
+
+func newTestRegistry() *ErrorRegistry {
+	reg := NewErrorRegistry()
+	reg.Register(errNotFound, -32001, "Resource not found")
+	RegisterErrorType[*quotaError](reg, -32002, "")
+	return reg
+}
+
+func TestErrorRegistry_ToError(t *testing.T) {
+	reg := newTestRegistry()
+
+	t.Run("Registered value", func(t *testing.T) {
+		rpcErr, ok := reg.ToError(fmt.Errorf("user 7: %w", errNotFound))
+		require.True(t, ok)
+		assert.Equal(t, &Error{Code: -32001, Message: "Resource not found"}, rpcErr)
+	})
+
+	t.Run("Registered type uses the error text", func(t *testing.T) {
+		rpcErr, ok := reg.ToError(&quotaError{limit: 10})
+		require.True(t, ok)
+		assert.Equal(t, &Error{Code: -32002, Message: "quota of 10 exceeded"}, rpcErr)
+	})
+
+	t.Run("Unregistered", func(t *testing.T) {
+		_, ok := reg.ToError(errors.New("other"))
+		assert.False(t, ok)
+	})
+}
+
+func TestErrorRegistry_FromError(t *testing.T) {
+	reg := newTestRegistry()
+
+	t.Run("Registered code", func(t *testing.T) {
+		err := reg.FromError(&Error{Code: -32001, Message: "Resource not found"})
+		assert.ErrorIs(t, err, errNotFound)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, -32001, rpcErr.Code)
+		assert.Equal(t, rpcErr.Error(), err.Error())
+	})
+
+	t.Run("Unregistered code", func(t *testing.T) {
+		rpcErr := &Error{Code: -32002, Message: "quota"}
+		assert.Same(t, rpcErr, reg.FromError(rpcErr))
+	})
+}
+
+func TestErrorRegistry_RoundTrip(t *testing.T) {
+	reg := newTestRegistry()
+	srv := NewServer(WithErrorRegistry(reg))
+	require.NoError(t, srv.RegisterFunc("get", func(context.Context, *Request) (any, error) {
+		return nil, errNotFound
+	}))
+	require.NoError(t, srv.RegisterFunc("explicit", func(context.Context, *Request) (any, error) {
+		return nil, &Error{Code: -32050, Message: "explicit"}
+	}))
+	require.NoError(t, srv.RegisterFunc("other", func(context.Context, *Request) (any, error) {
+		return nil, errors.New("boom")
+	}))
+
+	transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}}
+	client := NewClient(transport, WithClientErrorRegistry(reg))
+
+	t.Run("Registered error", func(t *testing.T) {
+		err := client.Call(context.Background(), "get", nil, nil)
+		assert.ErrorIs(t, err, errNotFound)
+		assert.True(t, IsCode(err, -32001))
+	})
+
+	t.Run("Errors of type *Error are sent as-is", func(t *testing.T) {
+		err := client.Call(context.Background(), "explicit", nil, nil)
+		assert.True(t, IsCode(err, -32050))
+	})
+
+	t.Run("Unregistered error is internal", func(t *testing.T) {
+		err := client.Call(context.Background(), "other", nil, nil)
+		assert.True(t, IsCode(err, ServerSideException))
+	})
+}
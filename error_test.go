@@ -3,6 +3,9 @@
 package jsonrpc
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -207,3 +210,90 @@ func TestError_Validate(t *testing.T) {
 		assert.Contains(t, err.Error(), "nil")
 	})
 }
+
+func TestErrorFromCode(t *testing.T) {
+	cases := []struct {
+		code    int
+		message string
+	}{
+		{ParseError, "Parse error"},
+		{InvalidRequest, "Invalid Request"},
+		{MethodNotFound, "Method not found"},
+		{InvalidParams, "Invalid params"},
+		{ServerSideException, "Internal error"},
+		{-32001, "Server error"},
+		{42, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.message, func(t *testing.T) {
+			assert.Equal(t, &Error{Code: tc.code, Message: tc.message}, ErrorFromCode(tc.code))
+		})
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	t.Run("Matches by code", func(t *testing.T) {
+		err := fmt.Errorf("call: %w", &Error{Code: MethodNotFound, Message: "no such method"})
+		assert.ErrorIs(t, err, ErrMethodNotFound)
+		assert.NotErrorIs(t, err, ErrInvalidParams)
+	})
+
+	t.Run("Non-JSON-RPC target", func(t *testing.T) {
+		assert.NotErrorIs(t, ErrInternal, errors.New("Internal error"))
+	})
+
+	t.Run("As extracts the error", func(t *testing.T) {
+		var rpcErr *Error
+		require.ErrorAs(t, fmt.Errorf("wrapped: %w", ErrInvalidParams), &rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+	})
+}
+
+func TestIsCode(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &Error{Code: -32001, Message: "not found"})
+	assert.True(t, IsCode(err, -32001))
+	assert.False(t, IsCode(err, -32002))
+	assert.False(t, IsCode(errors.New("plain"), -32001))
+	assert.False(t, IsCode(nil, -32001))
+}
+
+func TestError_WithData(t *testing.T) {
+	err := ErrInvalidParams.WithData("missing field")
+	assert.Equal(t, "missing field", err.Data)
+	assert.Equal(t, InvalidParams, err.Code)
+	assert.Nil(t, ErrInvalidParams.Data)
+}
+
+func TestError_UnmarshalData(t *testing.T) {
+	type details struct {
+		Field  string `json:"field"`
+		Reason string `json:"reason"`
+	}
+
+	t.Run("Decoded error", func(t *testing.T) {
+		var rpcErr Error
+		data := `{"code":-32602,"message":"Invalid params",` +
+			`"data":{"field":"age","reason":"negative"}}`
+		require.NoError(t, rpcErr.UnmarshalJSON([]byte(data)))
+
+		var got details
+		require.NoError(t, rpcErr.UnmarshalData(&got))
+		assert.Equal(t, details{Field: "age", Reason: "negative"}, got)
+	})
+
+	t.Run("Struct and raw data", func(t *testing.T) {
+		for _, data := range []any{
+			details{Field: "age", Reason: "negative"},
+			json.RawMessage(`{"field":"age","reason":"negative"}`),
+		} {
+			var got details
+			require.NoError(t, ErrInvalidParams.WithData(data).UnmarshalData(&got))
+			assert.Equal(t, details{Field: "age", Reason: "negative"}, got)
+		}
+	})
+
+	t.Run("No data", func(t *testing.T) {
+		var got details
+		assert.Error(t, ErrInvalidParams.UnmarshalData(&got))
+	})
+}
@@ -0,0 +1,68 @@
+package jsonrpc
+
+import "context"
+
+// Future is the pending outcome of a call issued with Client.Go. Its Done channel is closed once
+// the call completes, so that many calls can be issued concurrently and awaited selectively,
+// such as in a select statement. A Future is safe for concurrent use.
+type Future struct {
+	done    chan struct{}
+	options *callOptions
+	resp    *Response
+	err     error
+}
+
+// Go invokes method with params asynchronously and returns a Future for its outcome, in the
+// style of net/rpc. The call runs until it completes or ctx is done; errors and options are as
+// for Call:
+//
+//	balance := client.Go(ctx, "getBalance", []any{"alice"})
+//	height := client.Go(ctx, "getHeight", nil)
+//	var h int64
+//	if err := height.Result(&h); err != nil {
+//		return err
+//	}
+func (c *Client) Go(ctx context.Context, method string, params any, opts ...CallOption) *Future {
+	f := &Future{done: make(chan struct{}), options: c.callOptions(opts)}
+	go func() {
+		defer close(f.done)
+		f.resp, f.err = c.call(ctx, method, params, f.options)
+	}()
+	return f
+}
+
+// Done returns a channel that is closed once the call has completed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for the call to complete and returns its error, or unmarshals its result into
+// into unless into is nil. It can be called any number of times.
+func (f *Future) Result(into any) error {
+	<-f.done
+	if f.err != nil {
+		return f.err
+	}
+	if into == nil {
+		return nil
+	}
+	return f.options.unmarshalResult(f.resp, into)
+}
+
+// Response waits for the call to complete and returns its response, or nil if the call failed.
+func (f *Future) Response() *Response {
+	<-f.done
+	return f.resp
+}
+
+// Await waits for the call of f to complete and returns its result unmarshaled into a value of
+// type R:
+//
+//	balance, err := jsonrpc.Await[int64](client.Go(ctx, "getBalance", []any{"alice"}))
+func Await[R any](f *Future) (R, error) {
+	var result R
+	if err := f.Result(&result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
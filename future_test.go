@@ -0,0 +1,65 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Go(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	srv := newTestServer(t)
+	gated := func(ctx context.Context, _ *Request) (any, error) {
+		select {
+		case <-release:
+			return "released", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	require.NoError(t, srv.RegisterFunc("gated", gated))
+	clientEnd, serverEnd := newStreamPair()
+	go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+	client := NewStreamClient(clientEnd)
+	defer client.Close()
+
+	t.Run("Awaits calls selectively", func(t *testing.T) {
+		gated := client.Go(ctx, "gated", nil)
+		sum := client.Go(ctx, "sum", []int{1, 2})
+
+		select {
+		case <-sum.Done():
+		case <-gated.Done():
+			t.Fatal("gated call completed before its release")
+		case <-time.After(time.Second):
+			t.Fatal("call not completed")
+		}
+		var total int
+		require.NoError(t, sum.Result(&total))
+		assert.Equal(t, 3, total)
+		require.NotNil(t, sum.Response())
+
+		close(release)
+		got, err := Await[string](gated)
+		require.NoError(t, err)
+		assert.Equal(t, "released", got)
+		assert.NoError(t, gated.Result(nil), "results can be read again")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		f := client.Go(ctx, "missing", nil)
+		assert.ErrorIs(t, f.Result(nil), ErrMethodNotFound)
+		assert.Nil(t, f.Response())
+
+		_, err := Await[int](client.Go(ctx, "sum", []int{1}, WithCallTimeout(time.Nanosecond)))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(t, client.Go(canceled, "sum", nil).Result(nil), context.Canceled)
+	})
+}
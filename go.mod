@@ -4,6 +4,7 @@ go 1.25.3
 
 require (
 	github.com/bytedance/sonic v1.14.2
+	github.com/coder/websocket v1.8.14
 	github.com/stretchr/testify v1.10.0
 )
 
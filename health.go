@@ -0,0 +1,244 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HealthMethod is the method name under which WithHealth serves the server's HealthReport.
+const HealthMethod = "system.health"
+
+// Health statuses of a HealthReport.
+const (
+	// HealthOK is the status of a server ready to serve.
+	HealthOK = "ok"
+
+	// HealthUnavailable is the status of a server shutting down or failing a health check.
+	HealthUnavailable = "unavailable"
+)
+
+// HealthCheck checks a dependency of the server, such as an upstream or a database, returning
+// an error if it is unusable.
+type HealthCheck func(ctx context.Context) error
+
+// HealthReport describes the state of a server, as served by HealthMethod and ReadinessHandler.
+type HealthReport struct {
+	// Status is HealthOK, or HealthUnavailable if the server is shutting down or a check failed.
+	Status string `json:"status"`
+
+	// Uptime is the number of seconds since the server was created.
+	Uptime float64 `json:"uptime"`
+
+	// InFlight is the number of requests and notifications being handled, batch members counted
+	// one by one, from their dispatch until their response is built. It includes those queued
+	// under concurrency limits and the request for the report itself.
+	InFlight int64 `json:"inFlight"`
+
+	// Executing is the number of handlers executing under the concurrency limit set with
+	// WithConcurrencyLimit, zero without one.
+	Executing int `json:"executing"`
+
+	// Queued is the number of requests waiting for a slot under any concurrency limit, global or
+	// per method: the depth of the dispatch queues.
+	Queued int `json:"queued"`
+
+	// Methods holds the load of the limits set with WithMethodConcurrencyLimit, by method.
+	Methods map[string]LimiterLoad `json:"methods,omitempty"`
+
+	// Connections is the number of connections served by ServeStream.
+	Connections int `json:"connections"`
+
+	// ShuttingDown reports whether Shutdown has been called.
+	ShuttingDown bool `json:"shuttingDown,omitempty"`
+
+	// Checks holds the outcome of each health check by name: HealthOK, or its error message.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LimiterLoad is the load of a concurrency limit.
+type LimiterLoad struct {
+	// Executing is the number of handlers holding a slot.
+	Executing int `json:"executing"`
+
+	// Queued is the number of requests waiting for a slot.
+	Queued int `json:"queued"`
+}
+
+// WithHealth serves HealthMethod, answering the server's HealthReport for monitoring. Unlike the
+// readiness handler, the method reports an unavailable server with a result rather than an
+// error. Like rpc.discover, it goes through middleware and authorization, but it is exempt from
+// rate and concurrency limits, so that it is answered by a saturated server too.
+func WithHealth() ServerOption {
+	return func(s *Server) {
+		s.health = true
+		s.registerBuiltin(HealthMethod, func(ctx context.Context, _ *Request) (any, error) {
+			return s.Health(ctx), nil
+		})
+	}
+}
+
+// WithReadinessCheck adds check under name to the health checks of the server, run by Health for
+// every report, such as UpstreamHealthCheck for the upstream of a gateway. A failing check makes
+// the server unavailable.
+func WithReadinessCheck(name string, check HealthCheck) ServerOption {
+	return func(s *Server) {
+		if s.healthChecks == nil {
+			s.healthChecks = make(map[string]HealthCheck)
+		}
+		s.healthChecks[name] = check
+	}
+}
+
+// Health returns the report on the state of the server, running its health checks concurrently.
+func (s *Server) Health(ctx context.Context) *HealthReport {
+	report := &HealthReport{
+		Status:       HealthOK,
+		Uptime:       time.Since(s.started).Seconds(),
+		InFlight:     s.inFlight.Load(),
+		ShuttingDown: s.isShuttingDown(),
+	}
+	if s.limiter != nil {
+		report.Executing, report.Queued = s.limiter.load()
+	}
+	if len(s.methodLimiters) > 0 {
+		report.Methods = make(map[string]LimiterLoad, len(s.methodLimiters))
+		for method, l := range s.methodLimiters {
+			executing, queued := l.load()
+			report.Methods[method] = LimiterLoad{Executing: executing, Queued: queued}
+			report.Queued += queued
+		}
+	}
+	s.connMu.Lock()
+	report.Connections = len(s.conns)
+	s.connMu.Unlock()
+
+	if len(s.healthChecks) > 0 {
+		report.Checks = runHealthChecks(ctx, s.healthChecks)
+	}
+	if report.ShuttingDown {
+		report.Status = HealthUnavailable
+	}
+	for _, outcome := range report.Checks {
+		if outcome != HealthOK {
+			report.Status = HealthUnavailable
+		}
+	}
+	return report
+}
+
+// runHealthChecks runs checks concurrently and returns their outcomes by name.
+func runHealthChecks(ctx context.Context, checks map[string]HealthCheck) map[string]string {
+	outcomes := make(map[string]string, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Go(func() {
+			outcome := HealthOK
+			if err := check(ctx); err != nil {
+				outcome = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			outcomes[name] = outcome
+		})
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// LivenessHandler returns an HTTP handler for liveness probes such as /healthz, answering 200
+// for as long as the server is able to answer at all.
+func (*Server) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(HealthOK + "\n"))
+	})
+}
+
+// ReadinessHandler returns an HTTP handler for readiness probes such as /readyz, answering the
+// server's HealthReport with status 200, or 503 while the server is unavailable, so that load
+// balancers stop routing to it during shutdown or when a dependency fails.
+func (s *Server) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := s.Health(r.Context())
+		body, err := getCodec().Marshal(report)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status := http.StatusOK
+		if report.Status != HealthOK {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	})
+}
+
+// UpstreamHealthCheck returns a check of an upstream client: it fails once the client is closed
+// or, for a stream client, while its connection is down. With a probe method, such as
+// "eth_blockNumber", the check also calls it, failing if the upstream cannot be reached; an
+// error response still proves the upstream reachable.
+func UpstreamHealthCheck(client *Client, probe string) HealthCheck {
+	return func(ctx context.Context) error {
+		if err := client.connected(); err != nil {
+			return err
+		}
+		if probe == "" {
+			return nil
+		}
+		err := client.Call(ctx, probe, nil, nil)
+		var rpcErr *Error
+		if err != nil && !errors.As(err, &rpcErr) {
+			return err
+		}
+		return nil
+	}
+}
+
+// HealthCheck returns a check of the upstreams of the proxy, the default one and those of
+// WithProxyRoute, each checked concurrently as by UpstreamHealthCheck. Upstreams chosen by a
+// router are not known in advance and are not checked.
+func (p *Proxy) HealthCheck(probe string) HealthCheck {
+	upstreams := []*Client{p.upstream}
+	for _, upstream := range p.routes {
+		if !slices.Contains(upstreams, upstream) {
+			upstreams = append(upstreams, upstream)
+		}
+	}
+	return func(ctx context.Context) error {
+		errs := make([]error, len(upstreams))
+		var wg sync.WaitGroup
+		for i, upstream := range upstreams {
+			wg.Go(func() {
+				errs[i] = UpstreamHealthCheck(upstream, probe)(ctx)
+			})
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+}
+
+// connected returns an error if the client is closed or its stream connection is down.
+func (c *Client) connected() error {
+	select {
+	case <-c.done:
+		return c.terminalErr()
+	default:
+	}
+	conn := c.conn.Load()
+	if conn == nil {
+		return nil
+	}
+	select {
+	case <-conn.lost:
+		return ErrConnectionLost
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errDatabaseDown is the error of a failing health check under test.
+var errDatabaseDown = errors.New("database down")
+
+func TestServer_Health(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reports load and connections", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(
+			WithHealth(),
+			WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1}),
+			WithMethodConcurrencyLimit("block", ConcurrencyLimit{MaxConcurrent: 1}),
+			WithRateLimit(RateLimit{Limiter: NewTokenBucket(1, 2)}),
+		)
+		require.NoError(t, srv.Register("block", g))
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Go(func() { assert.NoError(t, client.Call(ctx, "block", nil, nil)) })
+		}
+		g.waitStarted(t, 1)
+		require.Eventually(t, func() bool {
+			return srv.Health(ctx).Queued == 1
+		}, time.Second, time.Millisecond)
+
+		var report HealthReport
+		require.NoError(t, client.Call(ctx, HealthMethod, nil, &report),
+			"health reports are exempt from limits")
+		g.open()
+		wg.Wait()
+		assert.Equal(t, HealthOK, report.Status)
+		assert.Equal(t, 1, report.Executing)
+		assert.Equal(t, 1, report.Queued)
+		assert.Equal(t, map[string]LimiterLoad{"block": {Executing: 1}}, report.Methods)
+		assert.Equal(t, int64(3), report.InFlight)
+		assert.Equal(t, 1, report.Connections)
+		assert.Positive(t, report.Uptime)
+		assert.Empty(t, report.Checks)
+	})
+
+	t.Run("Counts each request once", func(t *testing.T) {
+		srv := NewServer()
+		inFlight := func(ctx context.Context, _ *Request) (any, error) {
+			return srv.Health(ctx).InFlight, nil
+		}
+		require.NoError(t, srv.RegisterFunc("inFlight", inFlight))
+		reply := srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"inFlight","id":1}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":1,"id":1}`, string(reply))
+
+		roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, payload), nil
+		}
+		var got int64
+		client := NewClient(&funcTransport{fn: roundTrip})
+		require.NoError(t, client.Call(ctx, "inFlight", nil, &got))
+		assert.Equal(t, int64(1), got)
+		assert.Zero(t, srv.Health(ctx).InFlight)
+	})
+
+	t.Run("Failing checks make the server unavailable", func(t *testing.T) {
+		srv := NewServer(
+			WithReadinessCheck("cache", func(context.Context) error { return nil }),
+			WithReadinessCheck("db", func(context.Context) error { return errDatabaseDown }),
+		)
+		report := srv.Health(ctx)
+		assert.Equal(t, HealthUnavailable, report.Status)
+		assert.Equal(t, map[string]string{"cache": HealthOK, "db": "database down"}, report.Checks)
+	})
+}
+
+func TestServer_HealthHandlers(t *testing.T) {
+	// get serves a GET request with h, returning the status and body.
+	get := func(h http.Handler) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	t.Run("Liveness", func(t *testing.T) {
+		code, body := get(NewServer().LivenessHandler())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok\n", body)
+	})
+
+	t.Run("Readiness", func(t *testing.T) {
+		healthy := true
+		check := func(context.Context) error {
+			if healthy {
+				return nil
+			}
+			return errDatabaseDown
+		}
+		srv := NewServer(WithReadinessCheck("db", check))
+		code, body := get(srv.ReadinessHandler())
+		assert.Equal(t, http.StatusOK, code)
+		var report HealthReport
+		require.NoError(t, json.Unmarshal([]byte(body), &report))
+		assert.Equal(t, HealthOK, report.Status)
+
+		healthy = false
+		code, _ = get(srv.ReadinessHandler())
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+
+		healthy = true
+		require.NoError(t, srv.Shutdown(context.Background()))
+		code, body = get(srv.ReadinessHandler())
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Contains(t, body, `"shuttingDown":true`)
+	})
+}
+
+func TestUpstreamHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	upstream, _ := newUpstream(newTestServer(t))
+	defer upstream.Close()
+
+	t.Run("Probes the upstream", func(t *testing.T) {
+		assert.NoError(t, UpstreamHealthCheck(upstream, "")(ctx))
+		assert.NoError(t, UpstreamHealthCheck(upstream, "noop")(ctx))
+		assert.NoError(t, UpstreamHealthCheck(upstream, "missing")(ctx),
+			"error responses prove the upstream reachable")
+
+		unreachable := NewClient(&funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return nil, errDatabaseDown
+		}})
+		assert.ErrorIs(t, UpstreamHealthCheck(unreachable, "noop")(ctx), errDatabaseDown)
+	})
+
+	t.Run("Checks stream connections", func(t *testing.T) {
+		clientEnd, _ := newStreamPair()
+		client := NewStreamClient(clientEnd)
+		assert.NoError(t, UpstreamHealthCheck(client, "")(ctx))
+		require.NoError(t, client.Close())
+		assert.ErrorIs(t, UpstreamHealthCheck(client, "")(ctx), ErrClientClosed)
+	})
+
+	t.Run("Proxies check every upstream", func(t *testing.T) {
+		routed, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(upstream, WithProxyRoute("sum", routed))
+		check := proxy.HealthCheck("noop")
+		assert.NoError(t, check(ctx))
+
+		require.NoError(t, routed.Close())
+		assert.ErrorIs(t, check(ctx), ErrClientClosed)
+	})
+}
@@ -0,0 +1,144 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultHedgeAttempts is the default number of endpoints a hedged call is sent to.
+const defaultHedgeAttempts = 2
+
+// HedgePolicy configures the hedged calls of a PoolTransport enabled with WithHedging.
+type HedgePolicy struct {
+	// Delay is the wait for an answer before sending the call to one more endpoint. A zero Delay
+	// sends the call to MaxAttempts endpoints at once.
+	Delay time.Duration
+
+	// MaxAttempts caps the number of endpoints a call is in flight on at once, including the
+	// first. Defaults to 2.
+	MaxAttempts int
+
+	// Hedgeable reports whether a request may safely be sent to several endpoints, typically a
+	// read; with a nil Hedgeable, nothing is hedged. IdempotentMethods builds one from a list of
+	// methods.
+	Hedgeable func(req *Request) bool
+}
+
+// WithHedging makes the pool hedge the calls matched by the policy, trading upstream load for
+// tail latency: when an endpoint has not answered a call after the policy's delay, the call is
+// sent to the next endpoint too, up to MaxAttempts at once, and the first successful reply wins,
+// canceling the others. Calls failing on an endpoint move on to the next one at once, as without
+// hedging. Notifications and batches are not hedged.
+func WithHedging(policy HedgePolicy) PoolOption {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultHedgeAttempts
+	}
+	if policy.Delay < 0 {
+		policy.Delay = 0
+	}
+	return func(p *PoolTransport) {
+		p.hedging = policy
+	}
+}
+
+// hedgeResult is the outcome of one attempt of a hedged call.
+type hedgeResult struct {
+	reply []byte
+	err   error
+}
+
+// hedgedCall is a call sent to several endpoints of a pool.
+type hedgedCall struct {
+	pool       *PoolTransport
+	ctx        context.Context
+	payload    []byte
+	candidates []*poolEndpoint
+	results    chan hedgeResult
+	sent       int
+	pending    int
+	errs       []error
+}
+
+// shouldHedge reports whether payload holds a single request matched by the hedging policy.
+func (p *PoolTransport) shouldHedge(payload []byte) bool {
+	if p.hedging.Hedgeable == nil || len(p.endpoints) < 2 || jsonKind(payload) != '{' {
+		return false
+	}
+	req, err := DecodeRequest(payload)
+	return err == nil && !req.IsNotification() && p.hedging.Hedgeable(req)
+}
+
+// hedge sends payload to the endpoints chosen by the balancing policy, one more each time the
+// hedging delay elapses without an answer, returning the first successful reply.
+func (p *PoolTransport) hedge(ctx context.Context, payload []byte) ([]byte, error) {
+	hedgeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	candidates := p.candidates()
+	call := &hedgedCall{
+		pool:       p,
+		ctx:        hedgeCtx,
+		payload:    payload,
+		candidates: candidates,
+		results:    make(chan hedgeResult, len(candidates)),
+	}
+	call.launch()
+	if p.hedging.Delay == 0 {
+		for call.canHedge() {
+			call.launch()
+		}
+	}
+
+	timer := time.NewTimer(p.hedging.Delay)
+	defer timer.Stop()
+	for call.pending > 0 {
+		select {
+		case <-timer.C:
+			if call.canHedge() {
+				call.launch()
+			}
+			timer.Reset(p.hedging.Delay)
+		case res := <-call.results:
+			if reply, done := call.settle(ctx, res); done {
+				return reply, nil
+			}
+		}
+	}
+	return nil, errors.Join(call.errs...)
+}
+
+// canHedge reports whether the call may be sent to one more endpoint.
+func (h *hedgedCall) canHedge() bool {
+	return h.sent < len(h.candidates) && h.pending < h.pool.hedging.MaxAttempts
+}
+
+// launch sends the call to the next candidate endpoint.
+func (h *hedgedCall) launch() {
+	ep := h.candidates[h.sent]
+	h.sent++
+	h.pending++
+	go func() {
+		reply, err := h.pool.attempt(h.ctx, ep, h.payload)
+		h.results <- hedgeResult{reply: reply, err: err}
+	}()
+}
+
+// settle records the outcome of an attempt, reporting whether it ends the call with a reply.
+// A failed attempt moves on to the next endpoint, unless the failure is not worth failing over.
+func (h *hedgedCall) settle(ctx context.Context, res hedgeResult) ([]byte, bool) {
+	h.pending--
+	if res.err == nil {
+		return res.reply, true
+	}
+	h.errs = append(h.errs, res.err)
+	if ctx.Err() != nil || !isRetryableError(res.err) {
+		// Stop sending; the attempts still in flight may yet succeed
+		h.sent = len(h.candidates)
+		return nil, false
+	}
+	if h.sent < len(h.candidates) {
+		h.launch()
+	}
+	return nil, false
+}
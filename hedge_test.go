@@ -0,0 +1,140 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowEndpoint returns a pool endpoint answering with its name after delay, or failing with err,
+// counting its calls and the calls canceled while waiting.
+func slowEndpoint(
+	name string,
+	delay time.Duration,
+	err error,
+	calls, canceled *atomic.Int32,
+) PoolEndpoint {
+	return PoolEndpoint{Name: name, Transport: &funcTransport{
+		fn: func(ctx context.Context, _ []byte) ([]byte, error) {
+			calls.Add(1)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				canceled.Add(1)
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return []byte(name), nil
+		},
+	}}
+}
+
+func TestPoolTransport_Hedging(t *testing.T) {
+	ctx := context.Background()
+	read := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call"}`)
+	hedging := WithHedging(HedgePolicy{
+		Delay:     20 * time.Millisecond,
+		Hedgeable: IdempotentMethods("eth_call"),
+	})
+
+	t.Run("Slow endpoints are hedged", func(t *testing.T) {
+		var aCalls, aCanceled, bCalls, bCanceled atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			slowEndpoint("a", time.Second, nil, &aCalls, &aCanceled),
+			slowEndpoint("b", 0, nil, &bCalls, &bCanceled),
+		}, hedging)
+		defer pool.Close()
+
+		start := time.Now()
+		reply, err := pool.RoundTrip(ctx, read)
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(reply))
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Eventually(t, func() bool { return aCanceled.Load() == 1 },
+			time.Second, 5*time.Millisecond, "the losing attempt is canceled")
+		assert.Zero(t, pool.Endpoints()[0].Failures, "canceled attempts are no failures")
+	})
+
+	t.Run("Fast endpoints are not hedged", func(t *testing.T) {
+		var aCalls, aCanceled, bCalls, bCanceled atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			slowEndpoint("a", 0, nil, &aCalls, &aCanceled),
+			slowEndpoint("b", 0, nil, &bCalls, &bCanceled),
+		}, hedging)
+		defer pool.Close()
+
+		reply, err := pool.RoundTrip(ctx, read)
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(reply))
+		assert.Zero(t, bCalls.Load())
+	})
+
+	t.Run("Failures move on without waiting", func(t *testing.T) {
+		var aCalls, aCanceled, bCalls, bCanceled atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			slowEndpoint("a", 0, assert.AnError, &aCalls, &aCanceled),
+			slowEndpoint("b", 0, nil, &bCalls, &bCanceled),
+		}, WithHedging(HedgePolicy{Delay: time.Hour, Hedgeable: IdempotentMethods("eth_call")}))
+		defer pool.Close()
+
+		reply, err := pool.RoundTrip(ctx, read)
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(reply))
+		assert.Equal(t, 1, pool.Endpoints()[0].Failures)
+	})
+
+	t.Run("All failing joins the errors", func(t *testing.T) {
+		var aCalls, aCanceled, bCalls, bCanceled atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			slowEndpoint("a", 0, assert.AnError, &aCalls, &aCanceled),
+			slowEndpoint("b", 0, ErrCircuitOpen, &bCalls, &bCanceled),
+		}, hedging)
+		defer pool.Close()
+
+		_, err := pool.RoundTrip(ctx, read)
+		require.ErrorIs(t, err, assert.AnError)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("Zero delay sends to MaxAttempts endpoints at once", func(t *testing.T) {
+		var aCalls, aCanceled, bCalls, bCanceled, cCalls, cCanceled atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			slowEndpoint("a", time.Second, nil, &aCalls, &aCanceled),
+			slowEndpoint("b", 10*time.Millisecond, nil, &bCalls, &bCanceled),
+			slowEndpoint("c", 0, nil, &cCalls, &cCanceled),
+		}, WithHedging(HedgePolicy{MaxAttempts: 2, Hedgeable: IdempotentMethods("eth_call")}))
+		defer pool.Close()
+
+		reply, err := pool.RoundTrip(ctx, read)
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(reply))
+		assert.Zero(t, cCalls.Load())
+	})
+
+	t.Run("Unmatched calls are not hedged", func(t *testing.T) {
+		var aCalls, aCanceled, bCalls, bCanceled atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			slowEndpoint("a", 50*time.Millisecond, nil, &aCalls, &aCanceled),
+			slowEndpoint("b", 0, nil, &bCalls, &bCanceled),
+		}, hedging)
+		defer pool.Close()
+
+		payloads := []string{
+			`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction"}`,
+			`{"jsonrpc":"2.0","method":"eth_call"}`,
+			`[{"jsonrpc":"2.0","id":1,"method":"eth_call"}]`,
+		}
+		for _, payload := range payloads {
+			reply, err := pool.RoundTrip(ctx, []byte(payload))
+			require.NoError(t, err)
+			assert.Equal(t, "a", string(reply), payload)
+		}
+		assert.Zero(t, bCalls.Load())
+	})
+}
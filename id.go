@@ -0,0 +1,102 @@
+package jsonrpc
+
+import (
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand/v2"
+	"sync/atomic"
+)
+
+// UUID version 4 layout, per RFC 9562.
+const (
+	uuidVersionByte = 6
+	uuidVariantByte = 8
+	uuidVersionMask = 0x0f
+	uuidVersion4    = 0x40
+	uuidVariantMask = 0x3f
+	uuidVariantRFC  = 0x80
+)
+
+// maxSafeInteger is the largest integer that JavaScript peers can represent exactly, 2^53 - 1.
+const maxSafeInteger = 1<<53 - 1
+
+// IDGenerator produces the IDs of the requests a Client creates in Call, Subscribe, and NewBatch.
+// Implementations must be safe for concurrent use and return either a string or an int64.
+//
+// IDs only need to be unique among the calls in flight on one client. Stream clients reject a
+// call whose ID is already in flight rather than misrouting its response.
+type IDGenerator interface {
+	NextID() any
+}
+
+// IDGeneratorFunc adapts an ordinary function into an IDGenerator.
+type IDGeneratorFunc func() any
+
+// NextID calls f().
+func (f IDGeneratorFunc) NextID() any {
+	return f()
+}
+
+// WithIDGenerator sets how the client generates request IDs. Defaults to
+// NewSequentialIDGenerator.
+func WithIDGenerator(gen IDGenerator) ClientOption {
+	return func(c *Client) {
+		c.idGen = gen
+	}
+}
+
+// sequentialIDGenerator counts up from 1.
+type sequentialIDGenerator struct {
+	last atomic.Int64
+}
+
+// NewSequentialIDGenerator returns a generator of the int64 IDs 1, 2, 3, and so on. The IDs are
+// unique across concurrent calls for as long as the generator is used.
+func NewSequentialIDGenerator() IDGenerator {
+	return &sequentialIDGenerator{}
+}
+
+// NextID returns the next number in the sequence.
+func (g *sequentialIDGenerator) NextID() any {
+	return g.last.Add(1)
+}
+
+// randomIDGenerator draws random integers.
+type randomIDGenerator struct{}
+
+// NewRandomIDGenerator returns a generator of random non-negative int64 IDs below 2^53, so that
+// JavaScript peers represent them exactly. Such IDs do not reveal how many calls were made;
+// collisions between concurrent calls are possible but unlikely.
+func NewRandomIDGenerator() IDGenerator {
+	return randomIDGenerator{}
+}
+
+// NextID returns a random ID.
+func (randomIDGenerator) NextID() any {
+	return mathrand.Int64N(maxSafeInteger)
+}
+
+// uuidGenerator draws random version 4 UUIDs.
+type uuidGenerator struct{}
+
+// NewUUIDGenerator returns a generator of random version 4 UUID strings, such as
+// "f47ac10b-58cc-4372-a567-0e02b2c3d479", which are unique across clients and processes for all
+// practical purposes.
+func NewUUIDGenerator() IDGenerator {
+	return uuidGenerator{}
+}
+
+// NextID returns a new UUID.
+func (uuidGenerator) NextID() any {
+	return newUUID()
+}
+
+// newUUID returns a random version 4 UUID string.
+func newUUID() string {
+	var b [16]byte
+	// Read never fails, crashing the program irrecoverably instead
+	_, _ = rand.Read(b[:])
+	b[uuidVersionByte] = b[uuidVersionByte]&uuidVersionMask | uuidVersion4
+	b[uuidVariantByte] = b[uuidVariantByte]&uuidVariantMask | uuidVariantRFC
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,114 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectIDs draws n IDs from gen across concurrent goroutines.
+func collectIDs(gen IDGenerator, n int) []any {
+	ids := make([]any, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			ids[i] = gen.NextID()
+		})
+	}
+	wg.Wait()
+	return ids
+}
+
+func TestIDGenerators(t *testing.T) {
+	const n = 1000
+
+	t.Run("Sequential", func(t *testing.T) {
+		gen := NewSequentialIDGenerator()
+		assert.Equal(t, int64(1), gen.NextID())
+		assert.Equal(t, int64(2), gen.NextID())
+
+		seen := make(map[any]bool)
+		for _, id := range collectIDs(gen, n) {
+			require.False(t, seen[id], "duplicate id %v", id)
+			seen[id] = true
+		}
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		for _, id := range collectIDs(NewRandomIDGenerator(), n) {
+			v, ok := id.(int64)
+			require.True(t, ok)
+			assert.GreaterOrEqual(t, v, int64(0))
+			assert.Less(t, v, int64(maxSafeInteger))
+		}
+	})
+
+	t.Run("UUID", func(t *testing.T) {
+		pattern := regexp.MustCompile(
+			`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		seen := make(map[any]bool)
+		for _, id := range collectIDs(NewUUIDGenerator(), n) {
+			s, ok := id.(string)
+			require.True(t, ok)
+			assert.Regexp(t, pattern, s)
+			require.False(t, seen[id], "duplicate id %v", id)
+			seen[id] = true
+		}
+	})
+
+	t.Run("Func", func(t *testing.T) {
+		gen := IDGeneratorFunc(func() any { return "fixed" })
+		assert.Equal(t, "fixed", gen.NextID())
+	})
+}
+
+func TestClient_WithIDGenerator(t *testing.T) {
+	t.Run("Transport client uses the generator", func(t *testing.T) {
+		var mu sync.Mutex
+		var ids []any
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			req, err := DecodeRequest(payload)
+			require.NoError(t, err)
+			mu.Lock()
+			ids = append(ids, req.ID)
+			mu.Unlock()
+			resp, _ := NewResponse(req.ID, true)
+			return resp.MarshalJSON()
+		}}
+
+		next := 0
+		gen := IDGeneratorFunc(func() any {
+			next++
+			return fmt.Sprintf("req-%d", next)
+		})
+		client := NewClient(transport, WithIDGenerator(gen))
+		require.NoError(t, client.Call(context.Background(), "a", nil, nil))
+		require.NoError(t, client.Call(context.Background(), "b", nil, nil))
+		assert.Equal(t, []any{"req-1", "req-2"}, ids)
+
+		batch := client.NewBatch()
+		assert.Equal(t, "req-3", batch.Add("c", nil).Request().ID)
+	})
+
+	t.Run("Concurrent stream calls with UUIDs", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		echoResponder(t, serverEnd)
+		client := NewStreamClient(clientEnd, WithIDGenerator(NewUUIDGenerator()))
+		defer client.Close()
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Go(func() {
+				var got []int
+				assert.NoError(t, client.Call(context.Background(), "echo", []any{i}, &got))
+				assert.Equal(t, []int{i}, got)
+			})
+		}
+		wg.Wait()
+	})
+}
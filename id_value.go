@@ -0,0 +1,234 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// IDKind tells apart the forms the id member of a message can take.
+type IDKind uint8
+
+const (
+	// IDAbsent is the kind of a missing id member, which makes a request a notification.
+	IDAbsent IDKind = iota
+
+	// IDNull is the kind of an id member holding null. A request with a null ID is answered,
+	// unlike a notification, and servers answer messages whose ID they cannot tell with it.
+	IDNull
+
+	// IDString is the kind of a string ID.
+	IDString
+
+	// IDNumber is the kind of a numeric ID.
+	IDNumber
+)
+
+// jsonNull is the JSON null literal.
+const jsonNull = "null"
+
+// ID is the id member of a message, distinguishing an absent member from null, strings, and
+// numbers, and keeping numbers as their literal so that they round-trip exactly. The zero ID is
+// absent. IDs are comparable with ==, which tells apart the numbers 1 and 1.0; Equal does not.
+type ID struct {
+	kind IDKind
+	text string
+}
+
+// NullID returns the null ID.
+func NullID() ID {
+	return ID{kind: IDNull}
+}
+
+// StringID returns the ID holding s.
+func StringID(s string) ID {
+	return ID{kind: IDString, text: s}
+}
+
+// NumberID returns the ID holding n.
+func NumberID(n int64) ID {
+	return ID{kind: IDNumber, text: strconv.FormatInt(n, decimal)}
+}
+
+// ParseID parses an encoded id member. Empty input is the absent ID.
+func ParseID(raw json.RawMessage) (ID, error) {
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case len(trimmed) == 0:
+		return ID{}, nil
+	case string(trimmed) == jsonNull:
+		return NullID(), nil
+	case trimmed[0] == '"':
+		var s string
+		if err := json.Unmarshal(trimmed, &s); err != nil {
+			return ID{}, invalidField(fieldID, fmt.Sprintf("invalid id field: %v", err))
+		}
+		return StringID(s), nil
+	default:
+		var n json.Number
+		if err := json.Unmarshal(trimmed, &n); err != nil {
+			return ID{}, invalidField(fieldID, "id field must be a string or a number")
+		}
+		return ID{kind: IDNumber, text: string(trimmed)}, nil
+	}
+}
+
+// IDFrom returns the ID of the value held by Request.ID, which is nil, a string, an int64, a
+// float64, a json.Number, or an ID. Other integer types and *big.Int are accepted too.
+func IDFrom(v any) (ID, error) {
+	switch id := v.(type) {
+	case nil:
+		return ID{}, nil
+	case ID:
+		return id, nil
+	case string:
+		return StringID(id), nil
+	case int:
+		return NumberID(int64(id)), nil
+	case int32:
+		return NumberID(int64(id)), nil
+	case int64:
+		return NumberID(id), nil
+	case uint64:
+		return ID{kind: IDNumber, text: strconv.FormatUint(id, decimal)}, nil
+	case float64:
+		if math.IsInf(id, 0) || math.IsNaN(id) {
+			return ID{}, fmt.Errorf("unsupported id %v", id)
+		}
+		return ID{kind: IDNumber, text: formatFloat64ID(id)}, nil
+	case json.Number:
+		return ParseID(json.RawMessage(id))
+	case *big.Int:
+		if id == nil {
+			return ID{}, errors.New("id must not be a nil *big.Int")
+		}
+		return ID{kind: IDNumber, text: id.String()}, nil
+	default:
+		return ID{}, fmt.Errorf("id of type %T must be a string or a number", v)
+	}
+}
+
+// Kind returns the kind of the ID.
+func (id ID) Kind() IDKind {
+	return id.kind
+}
+
+// IsAbsent reports whether the ID is absent.
+func (id ID) IsAbsent() bool {
+	return id.kind == IDAbsent
+}
+
+// IsNull reports whether the ID is null.
+func (id ID) IsNull() bool {
+	return id.kind == IDNull
+}
+
+// String returns the content of a string ID, the literal of a numeric ID, "null" for the null
+// ID, and "" for the absent ID.
+func (id ID) String() string {
+	if id.kind == IDNull {
+		return jsonNull
+	}
+	return id.text
+}
+
+// Int64 returns a numeric ID as an int64, reporting false if the ID is not an integer within
+// range.
+func (id ID) Int64() (int64, bool) {
+	if id.kind != IDNumber {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(id.text, decimal, 64)
+	return n, err == nil
+}
+
+// BigInt returns an integer ID as a *big.Int, with no limit on its size, reporting false if the
+// ID is not an integer literal.
+func (id ID) BigInt() (*big.Int, bool) {
+	if id.kind != IDNumber {
+		return nil, false
+	}
+	return new(big.Int).SetString(id.text, decimal)
+}
+
+// Value returns the ID in the form Request.ID holds decoded IDs: nil for absent IDs, the null ID
+// itself, a string, an int64 for integers within range, a float64 for other numbers a float64
+// holds exactly, and a json.Number otherwise.
+func (id ID) Value() any {
+	switch id.kind {
+	case IDNull:
+		return id
+	case IDString:
+		return id.text
+	case IDNumber:
+		return numberValue(id.text)
+	default:
+		return nil
+	}
+}
+
+// Equal reports whether id and other are the same ID. Unlike ==, it compares numbers by value,
+// so that 1 equals 1.0.
+func (id ID) Equal(other ID) bool {
+	if id == other {
+		return true
+	}
+	if id.kind != IDNumber || other.kind != IDNumber {
+		return false
+	}
+	a, errA := strconv.ParseFloat(id.text, 64)
+	b, errB := strconv.ParseFloat(other.text, 64)
+	return errA == nil && errB == nil && a == b
+}
+
+// MarshalJSON encodes the ID. Absent IDs have no encoding: requests holding one are encoded
+// without an id member, and other messages with null.
+func (id ID) MarshalJSON() ([]byte, error) {
+	switch id.kind {
+	case IDString:
+		return json.Marshal(id.text)
+	case IDNumber:
+		return []byte(id.text), nil
+	default:
+		return []byte(jsonNull), nil
+	}
+}
+
+// UnmarshalJSON decodes an ID with ParseID.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseID(data)
+	if err != nil {
+		return err
+	}
+	if parsed.IsAbsent() {
+		return errors.New("empty id")
+	}
+	*id = parsed
+	return nil
+}
+
+// TypedID returns the request's ID as an ID, absent for notifications.
+func (r *Request) TypedID() ID {
+	id, _ := IDFrom(r.ID)
+	return id
+}
+
+// TypedID returns the response's ID as an ID. Decoded responses keep the literal of their id
+// member. Responses are always encoded with an id member, so responses without an ID report the
+// null ID.
+func (r *Response) TypedID() ID {
+	if len(r.rawID) > 0 {
+		if id, err := ParseID(r.rawID); err == nil {
+			return id
+		}
+	}
+	id, _ := IDFrom(r.IDOrNil())
+	if id.IsAbsent() {
+		return NullID()
+	}
+	return id
+}
@@ -0,0 +1,160 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseID(t *testing.T) {
+	t.Run("Kinds", func(t *testing.T) {
+		tests := []struct {
+			raw  string
+			kind IDKind
+			str  string
+		}{
+			{"", IDAbsent, ""},
+			{"null", IDNull, "null"},
+			{`"abc"`, IDString, "abc"},
+			{`""`, IDString, ""},
+			{"42", IDNumber, "42"},
+			{"-1.50", IDNumber, "-1.50"},
+			{"12345678901234567890", IDNumber, "12345678901234567890"},
+		}
+		for _, tt := range tests {
+			id, err := ParseID(json.RawMessage(tt.raw))
+			require.NoError(t, err, tt.raw)
+			assert.Equal(t, tt.kind, id.Kind(), tt.raw)
+			assert.Equal(t, tt.str, id.String(), tt.raw)
+		}
+	})
+
+	t.Run("Invalid IDs", func(t *testing.T) {
+		for _, raw := range []string{"{}", "[1]", "true", `"unterminated`} {
+			_, err := ParseID(json.RawMessage(raw))
+			assert.ErrorIs(t, err, ErrInvalidField, raw)
+		}
+	})
+
+	t.Run("Round trips exactly", func(t *testing.T) {
+		for _, raw := range []string{"null", `"a\"b"`, "1.0", "12345678901234567890", "-0"} {
+			id, err := ParseID(json.RawMessage(raw))
+			require.NoError(t, err)
+			encoded, err := json.Marshal(id)
+			require.NoError(t, err)
+			var decoded ID
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			assert.Equal(t, id, decoded, raw)
+			if raw != `"a\"b"` {
+				assert.Equal(t, raw, string(encoded))
+			}
+		}
+	})
+}
+
+func TestID(t *testing.T) {
+	t.Run("Equal compares numbers by value", func(t *testing.T) {
+		one, err := ParseID(json.RawMessage("1.0"))
+		require.NoError(t, err)
+		assert.True(t, one.Equal(NumberID(1)))
+		assert.NotEqual(t, one, NumberID(1))
+		assert.False(t, StringID("1").Equal(NumberID(1)))
+		assert.False(t, NullID().Equal(ID{}))
+		assert.True(t, NullID().Equal(NullID()))
+	})
+
+	t.Run("Value matches decoded request IDs", func(t *testing.T) {
+		assert.Nil(t, ID{}.Value())
+		assert.Equal(t, NullID(), NullID().Value())
+		assert.Equal(t, "x", StringID("x").Value())
+		assert.Equal(t, int64(7), NumberID(7).Value())
+		fraction, err := ParseID(json.RawMessage("1.5"))
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, fraction.Value())
+	})
+
+	t.Run("IDFrom", func(t *testing.T) {
+		tests := map[string]struct {
+			value any
+			want  ID
+		}{
+			"nil":    {nil, ID{}},
+			"string": {"a", StringID("a")},
+			"int":    {7, NumberID(7)},
+			"int64":  {int64(7), NumberID(7)},
+			"float":  {1.5, ID{kind: IDNumber, text: "1.5"}},
+			"ID":     {NullID(), NullID()},
+			"number": {json.Number("8"), NumberID(8)},
+		}
+		for name, tt := range tests {
+			id, err := IDFrom(tt.value)
+			require.NoError(t, err, name)
+			assert.Equal(t, tt.want, id, name)
+		}
+		_, err := IDFrom(true)
+		assert.Error(t, err)
+	})
+
+	t.Run("Int64", func(t *testing.T) {
+		n, ok := NumberID(-3).Int64()
+		assert.True(t, ok)
+		assert.Equal(t, int64(-3), n)
+		_, ok = StringID("3").Int64()
+		assert.False(t, ok)
+	})
+}
+
+func TestRequest_NullID(t *testing.T) {
+	t.Run("Null ID is not a notification", func(t *testing.T) {
+		req, err := DecodeRequest([]byte(`{"jsonrpc":"2.0","method":"a","id":null}`))
+		require.NoError(t, err)
+		assert.False(t, req.IsNotification())
+		assert.Equal(t, NullID(), req.TypedID())
+
+		lazy, err := DecodeRequestLazy([]byte(`{"jsonrpc":"2.0","method":"a","id":null}`))
+		require.NoError(t, err)
+		assert.False(t, lazy.IsNotification())
+
+		encoded, err := json.Marshal(req)
+		require.NoError(t, err)
+		assert.Contains(t, string(encoded), `"id":null`)
+	})
+
+	t.Run("Absent ID is a notification", func(t *testing.T) {
+		req, err := DecodeRequest([]byte(`{"jsonrpc":"2.0","method":"a"}`))
+		require.NoError(t, err)
+		assert.True(t, req.IsNotification())
+		assert.True(t, req.TypedID().IsAbsent())
+
+		typed := NewRequestWithID("a", nil, ID{})
+		assert.True(t, typed.IsNotification())
+		encoded, err := json.Marshal(typed)
+		require.NoError(t, err)
+		assert.NotContains(t, string(encoded), `"id"`)
+	})
+
+	t.Run("Server answers requests with a null ID", func(t *testing.T) {
+		srv := newTestServer(t)
+		reply := srv.HandleMessage(context.Background(),
+			[]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":null}`))
+		require.NotNil(t, reply)
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Equal(t, NullID(), resp.TypedID())
+		var sum int
+		require.NoError(t, resp.UnmarshalResult(&sum))
+		assert.Equal(t, 3, sum)
+	})
+}
+
+func TestResponse_TypedID(t *testing.T) {
+	resp, err := DecodeResponse([]byte(`{"jsonrpc":"2.0","result":1,"id":1.0}`))
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", resp.TypedID().String())
+
+	assert.Equal(t, NullID(), NewErrorResponse(nil, ErrParse).TypedID())
+	assert.Equal(t, StringID("x"), NewErrorResponse("x", ErrParse).TypedID())
+}
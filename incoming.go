@@ -0,0 +1,62 @@
+package jsonrpc
+
+// Incoming is a message received from a peer, as classified by ParseIncoming: a *Request
+// expecting a response, a *Notification, a *Response, or an IncomingBatch of these. Callers tell
+// them apart with a type switch rather than by checking IDs:
+//
+//	switch msg := in.(type) {
+//	case *jsonrpc.Request:
+//	    reply(handle(msg))
+//	case *jsonrpc.Notification:
+//	    handle(msg.Request)
+//	case *jsonrpc.Response:
+//	    deliver(msg)
+//	case jsonrpc.IncomingBatch:
+//	    // handle each member
+//	}
+type Incoming interface {
+	isIncoming()
+}
+
+// Notification is a request without an ID, to which no response is sent.
+type Notification struct {
+	*Request
+}
+
+// IncomingBatch is a batch of requests and notifications, or of responses.
+type IncomingBatch []Incoming
+
+func (*Request) isIncoming()      {}
+func (*Notification) isIncoming() {}
+func (*Response) isIncoming()     {}
+func (IncomingBatch) isIncoming() {}
+
+// ParseIncoming parses data like ParseMessage and classifies it. Requests without an ID are
+// returned as a *Notification, other requests as a *Request, and batches as an IncomingBatch
+// whose members are classified likewise, in order.
+func ParseIncoming(data []byte) (Incoming, error) {
+	msg, err := ParseMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(IncomingBatch, 0, len(msg.Requests)+len(msg.Responses))
+	for _, req := range msg.Requests {
+		members = append(members, classifyRequest(req))
+	}
+	for _, resp := range msg.Responses {
+		members = append(members, resp)
+	}
+	if msg.Batch {
+		return members, nil
+	}
+	return members[0], nil
+}
+
+// classifyRequest returns req as a *Notification if it has no ID, and as is otherwise.
+func classifyRequest(req *Request) Incoming {
+	if req.IsNotification() {
+		return &Notification{Request: req}
+	}
+	return req
+}
@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIncoming(t *testing.T) {
+	t.Run("Single messages", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data string
+			want Incoming
+		}{
+			{"request", `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`, &Request{}},
+			{"notification", `{"jsonrpc":"2.0","method":"log"}`, &Notification{}},
+			{"response", `{"jsonrpc":"2.0","result":1,"id":1}`, &Response{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				in, err := ParseIncoming([]byte(tt.data))
+				require.NoError(t, err)
+				assert.IsType(t, tt.want, in)
+			})
+		}
+	})
+
+	t.Run("Notification exposes its request", func(t *testing.T) {
+		in, err := ParseIncoming([]byte(`{"jsonrpc":"2.0","method":"log","params":{"a":1}}`))
+		require.NoError(t, err)
+		notification, ok := in.(*Notification)
+		require.True(t, ok)
+		assert.Equal(t, "log", notification.Method)
+		assert.Equal(t, map[string]any{"a": float64(1)}, notification.Params)
+	})
+
+	t.Run("Batch members are classified in order", func(t *testing.T) {
+		in, err := ParseIncoming([]byte(`[
+			{"jsonrpc":"2.0","method":"a","id":1},
+			{"jsonrpc":"2.0","method":"b"},
+			{"jsonrpc":"2.0","method":"c","id":"x"}
+		]`))
+		require.NoError(t, err)
+		batch, ok := in.(IncomingBatch)
+		require.True(t, ok)
+		require.Len(t, batch, 3)
+		assert.IsType(t, &Request{}, batch[0])
+		assert.IsType(t, &Notification{}, batch[1])
+		assert.IsType(t, &Request{}, batch[2])
+	})
+
+	t.Run("Errors are those of ParseMessage", func(t *testing.T) {
+		_, err := ParseIncoming([]byte(`{"jsonrpc":"2.0","id":1}`))
+		require.ErrorIs(t, err, ErrUnexpectedShape)
+	})
+}
@@ -0,0 +1,144 @@
+package jsonvalue
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// AppendCanonical appends the canonical JSON of v to dst, as defined by the JSON Canonicalization
+// Scheme of RFC 8785: without insignificant whitespace, with object members sorted by the UTF-16
+// code units of their keys, strings escaped minimally, and numbers formatted like ECMAScript's
+// Number.prototype.toString. As numbers are taken as IEEE 754 doubles, integers beyond 2^53 lose
+// precision, and numbers outside the range of doubles are an error.
+func (v Value) AppendCanonical(dst []byte) ([]byte, error) {
+	switch v.Kind {
+	case Bool:
+		return strconv.AppendBool(dst, v.Bool), nil
+	case Number:
+		f, err := strconv.ParseFloat(v.Text, 64)
+		if err != nil || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("number %s cannot be canonicalized", v.Text)
+		}
+		return appendES6Number(dst, f), nil
+	case String:
+		return appendCanonicalString(dst, v.Text), nil
+	case Array:
+		return appendCanonicalArray(dst, v.Items)
+	case Object:
+		return appendCanonicalObject(dst, v.Members)
+	default:
+		return append(dst, "null"...), nil
+	}
+}
+
+// appendCanonicalArray appends the canonical JSON of an array of items to dst.
+func appendCanonicalArray(dst []byte, items []Value) ([]byte, error) {
+	out := append(dst, '[')
+	for i, item := range items {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		var err error
+		if out, err = item.AppendCanonical(out); err != nil {
+			return nil, err
+		}
+	}
+	return append(out, ']'), nil
+}
+
+// appendCanonicalObject appends the canonical JSON of an object of members to dst.
+func appendCanonicalObject(dst []byte, members []Member) ([]byte, error) {
+	sorted := slices.Clone(members)
+	slices.SortStableFunc(sorted, func(a, b Member) int {
+		return slices.Compare(utf16.Encode([]rune(a.Key)), utf16.Encode([]rune(b.Key)))
+	})
+	out := append(dst, '{')
+	for i, member := range sorted {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = appendCanonicalString(out, member.Key)
+		out = append(out, ':')
+		var err error
+		if out, err = member.Value.AppendCanonical(out); err != nil {
+			return nil, err
+		}
+	}
+	return append(out, '}'), nil
+}
+
+// shortEscapes are the two-character escapes of control characters used by canonical JSON.
+var shortEscapes = map[byte]byte{'\b': 'b', '\t': 't', '\n': 'n', '\f': 'f', '\r': 'r'}
+
+// appendCanonicalString appends s as a canonical JSON string, replacing invalid UTF-8 with U+FFFD.
+func appendCanonicalString(dst []byte, s string) []byte {
+	out := append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			out = utf8.AppendRune(out, r)
+			i += size
+			continue
+		}
+		switch short, ok := shortEscapes[c]; {
+		case c == '"' || c == '\\':
+			out = append(out, '\\', c)
+		case ok:
+			out = append(out, '\\', short)
+		case c < ' ':
+			out = append(out, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			out = append(out, c)
+		}
+		i++
+	}
+	return append(out, '"')
+}
+
+// Limits of the positions of the decimal point within which ECMAScript formats numbers without
+// an exponent.
+const (
+	maxPlainPoint = 21
+	minPlainPoint = -6
+)
+
+// appendES6Number appends the finite f as formatted by ECMAScript's Number.prototype.toString.
+func appendES6Number(dst []byte, f float64) []byte {
+	if f == 0 {
+		return append(dst, '0')
+	}
+	out := dst
+	if f < 0 {
+		out = append(out, '-')
+	}
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(math.Abs(f), 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exp)
+	point := e + 1
+	switch {
+	case len(digits) <= point && point <= maxPlainPoint:
+		out = append(out, digits...)
+		return append(out, strings.Repeat("0", point-len(digits))...)
+	case 0 < point && point <= maxPlainPoint:
+		return append(append(append(out, digits[:point]...), '.'), digits[point:]...)
+	case minPlainPoint < point && point <= 0:
+		out = append(out, "0."...)
+		return append(append(out, strings.Repeat("0", -point)...), digits...)
+	default:
+		out = append(out, digits[0])
+		if len(digits) > 1 {
+			out = append(append(out, '.'), digits[1:]...)
+		}
+		out = append(out, 'e')
+		if point > 0 {
+			out = append(out, '+')
+		}
+		return strconv.AppendInt(out, int64(point-1), decimal)
+	}
+}
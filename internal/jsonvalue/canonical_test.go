@@ -0,0 +1,68 @@
+package jsonvalue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// canonical returns the canonical JSON of input.
+func canonical(t *testing.T, input string) string {
+	t.Helper()
+	v, err := Parse([]byte(input))
+	require.NoError(t, err)
+	out, err := v.AppendCanonical(nil)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestAppendCanonical(t *testing.T) {
+	t.Run("RFC 8785 example", func(t *testing.T) {
+		input := `{
+			"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			"literals": [null, true, false]
+		}`
+		want := `{"literals":[null,true,false],` +
+			`"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],` +
+			`"string":"€$\u000f\nA'B\"\\\\\"/"}`
+		assert.Equal(t, want, canonical(t, input))
+	})
+
+	t.Run("Sorts keys by UTF-16 code units", func(t *testing.T) {
+		input := `{"\u20ac":1,"\r":2,"\ufb33":3,"1":4,"\ud83d\ude00":5,"\u0080":6,"\u00f6":7}`
+		want := "{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001F600\":5," +
+			"\"\ufb33\":3}"
+		assert.Equal(t, want, canonical(t, input))
+	})
+
+	t.Run("Formats numbers like ECMAScript", func(t *testing.T) {
+		tests := map[string]string{
+			`0`:                      `0`,
+			`-0`:                     `0`,
+			`-1.50`:                  `-1.5`,
+			`1e20`:                   `100000000000000000000`,
+			`1e21`:                   `1e+21`,
+			`123e18`:                 `123000000000000000000`,
+			`1.5e300`:                `1.5e+300`,
+			`0.000001`:               `0.000001`,
+			`1e-7`:                   `1e-7`,
+			`-1.25e-8`:               `-1.25e-8`,
+			`9007199254740993`:       `9007199254740992`,
+			`12.34e1`:                `123.4`,
+			`5e-324`:                 `5e-324`,
+			`1.7976931348623157e308`: `1.7976931348623157e+308`,
+		}
+		for input, want := range tests {
+			assert.Equal(t, want, canonical(t, input), input)
+		}
+	})
+
+	t.Run("Rejects numbers beyond doubles", func(t *testing.T) {
+		v, err := Parse([]byte(`[1e400]`))
+		require.NoError(t, err)
+		_, err = v.AppendCanonical(nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,317 @@
+// Package jsonvalue holds an ordered, lossless tree form of JSON values, shared by the binary
+// wire encodings to transcode messages to and from JSON, by the response cache to canonicalize
+// params, and by the JSON Schema validator.
+package jsonvalue
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// revive:disable:modifies-parameter encoders append to dst
+
+// MaxDepth bounds the nesting of the values parsed and decoded, so that hostile input cannot
+// exhaust the stack.
+const MaxDepth = 10000
+
+// ErrTooDeep is returned for values nested deeper than MaxDepth.
+var ErrTooDeep = errors.New("value nested too deeply")
+
+// Kind is the type of a JSON value.
+type Kind uint8
+
+const (
+	// Null is the JSON null.
+	Null Kind = iota
+
+	// Bool is true or false.
+	Bool
+
+	// Number is a number, held as its JSON literal so that integers of any size survive.
+	Number
+
+	// String is a string.
+	String
+
+	// Array is an array.
+	Array
+
+	// Object is an object, whose members keep their order.
+	Object
+)
+
+// Value is a JSON value.
+type Value struct {
+	Kind Kind
+
+	// Bool is the value of a Bool.
+	Bool bool
+
+	// Text is the literal of a Number or the content of a String.
+	Text string
+
+	// Items are the elements of an Array.
+	Items []Value
+
+	// Members are the members of an Object.
+	Members []Member
+}
+
+// Member is a member of an object.
+type Member struct {
+	Key   string
+	Value Value
+}
+
+// Boolean returns a Bool value.
+func Boolean(b bool) Value {
+	return Value{Kind: Bool, Bool: b}
+}
+
+// Int returns a Number value holding n.
+func Int(n int64) Value {
+	return Value{Kind: Number, Text: strconv.FormatInt(n, decimal)}
+}
+
+// Uint returns a Number value holding n.
+func Uint(n uint64) Value {
+	return Value{Kind: Number, Text: strconv.FormatUint(n, decimal)}
+}
+
+// NegativeUint returns a Number value holding -1-n, which may not fit an int64.
+func NegativeUint(n uint64) Value {
+	if n <= math.MaxInt64 {
+		return Int(-1 - int64(n))
+	}
+	neg := new(big.Int).SetUint64(n)
+	neg.Add(neg, big.NewInt(1)).Neg(neg)
+	return Value{Kind: Number, Text: neg.String()}
+}
+
+// Float returns a Number value holding f, which must be finite as JSON has no representation
+// for infinities and NaN.
+func Float(f float64) (Value, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return Value{}, fmt.Errorf("unsupported number %v", f)
+	}
+	return Value{Kind: Number, Text: string(appendFloat(nil, f))}, nil
+}
+
+// Str returns a String value.
+func Str(s string) Value {
+	return Value{Kind: String, Text: s}
+}
+
+// Bytes returns a String value holding b in base64, as encoding/json encodes byte slices.
+func Bytes(b []byte) Value {
+	return Str(base64.StdEncoding.EncodeToString(b))
+}
+
+// AsInt returns a Number as an int64, reporting false if it is not an integer within range.
+func (v Value) AsInt() (int64, bool) {
+	n, err := strconv.ParseInt(v.Text, decimal, 64)
+	return n, err == nil
+}
+
+// AsUint returns a Number as a uint64, reporting false if it is not an integer within range.
+func (v Value) AsUint() (uint64, bool) {
+	n, err := strconv.ParseUint(v.Text, decimal, 64)
+	return n, err == nil
+}
+
+// AsFloat returns a Number as a float64.
+func (v Value) AsFloat() (float64, error) {
+	return strconv.ParseFloat(v.Text, 64)
+}
+
+// decimal is the base of number literals.
+const decimal = 10
+
+// Parse parses a single JSON value.
+func Parse(data []byte) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := parseValue(dec, 0)
+	if err != nil {
+		return Value{}, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return Value{}, errors.New("invalid character after top-level value")
+	}
+	return v, nil
+}
+
+// parseValue parses the value starting at the next token of dec.
+func parseValue(dec *json.Decoder, depth int) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return Value{}, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if depth >= MaxDepth {
+			return Value{}, ErrTooDeep
+		}
+		if t == '[' {
+			return parseArray(dec, depth+1)
+		}
+		return parseObject(dec, depth+1)
+	case json.Number:
+		return Value{Kind: Number, Text: string(t)}, nil
+	case string:
+		return Str(t), nil
+	case bool:
+		return Boolean(t), nil
+	default:
+		return Value{}, nil
+	}
+}
+
+// parseArray parses the elements of an array whose opening bracket was consumed.
+func parseArray(dec *json.Decoder, depth int) (Value, error) {
+	v := Value{Kind: Array, Items: []Value{}}
+	for dec.More() {
+		item, err := parseValue(dec, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		v.Items = append(v.Items, item)
+	}
+	_, err := dec.Token()
+	return v, err
+}
+
+// parseObject parses the members of an object whose opening brace was consumed.
+func parseObject(dec *json.Decoder, depth int) (Value, error) {
+	v := Value{Kind: Object, Members: []Member{}}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return Value{}, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return Value{}, errors.New("object key is not a string")
+		}
+		member, err := parseValue(dec, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		v.Members = append(v.Members, Member{Key: key, Value: member})
+	}
+	_, err := dec.Token()
+	return v, err
+}
+
+// SortKeys returns a copy of v whose objects, at any depth, have their members sorted by key,
+// so that equal values encode to the same JSON regardless of member order.
+func (v Value) SortKeys() Value {
+	sorted := v
+	switch v.Kind {
+	case Array:
+		sorted.Items = make([]Value, len(v.Items))
+		for i, item := range v.Items {
+			sorted.Items[i] = item.SortKeys()
+		}
+	case Object:
+		sorted.Members = make([]Member, len(v.Members))
+		for i, member := range v.Members {
+			sorted.Members[i] = Member{Key: member.Key, Value: member.Value.SortKeys()}
+		}
+		slices.SortStableFunc(sorted.Members, func(a, b Member) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+	default:
+	}
+	return sorted
+}
+
+// AppendJSON appends the JSON encoding of v to dst.
+func (v Value) AppendJSON(dst []byte) []byte {
+	switch v.Kind {
+	case Bool:
+		return strconv.AppendBool(dst, v.Bool)
+	case Number:
+		return append(dst, v.Text...)
+	case String:
+		return appendString(dst, v.Text)
+	case Array:
+		dst = append(dst, '[')
+		for i, item := range v.Items {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = item.AppendJSON(dst)
+		}
+		return append(dst, ']')
+	case Object:
+		dst = append(dst, '{')
+		for i, member := range v.Members {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendString(dst, member.Key)
+			dst = append(dst, ':')
+			dst = member.Value.AppendJSON(dst)
+		}
+		return append(dst, '}')
+	default:
+		return append(dst, "null"...)
+	}
+}
+
+// hexDigits are the digits of \u escapes.
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a JSON string, replacing invalid UTF-8 with U+FFFD.
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				dst = append(dst, "\ufffd"...)
+			} else {
+				dst = append(dst, s[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < ' ':
+			dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+		i++
+	}
+	return append(dst, '"')
+}
+
+// Bounds outside of which floats are formatted with an exponent, as in encoding/json.
+const (
+	minPlainFloat = 1e-6
+	maxPlainFloat = 1e21
+)
+
+// appendFloat appends the shortest literal of a finite f.
+func appendFloat(dst []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < minPlainFloat || abs >= maxPlainFloat) {
+		format = 'e'
+	}
+	return strconv.AppendFloat(dst, f, format, -1, 64)
+}
@@ -0,0 +1,78 @@
+package jsonvalue
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Round trips through JSON", func(t *testing.T) {
+		inputs := []string{
+			`null`,
+			`true`,
+			`{"jsonrpc":"2.0","method":"m","params":[1,-2.5,"x",null,false],"id":7}`,
+			`{"b":1,"a":{"z":[],"y":{}}}`,
+			`[18446744073709551615,-9223372036854775809,1e400]`,
+		}
+		for _, input := range inputs {
+			v, err := Parse([]byte(input))
+			require.NoError(t, err, input)
+			assert.Equal(t, input, string(v.AppendJSON(nil)), "keeps member order and numbers")
+		}
+	})
+
+	t.Run("Escapes strings", func(t *testing.T) {
+		v := Str("a\"b\\c\n\x01\xffé")
+		assert.Equal(t, `"a\"b\\c\u000a\u0001`+"\ufffdé\"", string(v.AppendJSON(nil)))
+
+		parsed, err := Parse(v.AppendJSON(nil))
+		require.NoError(t, err)
+		assert.Equal(t, "a\"b\\c\n\x01\ufffdé", parsed.Text)
+	})
+
+	t.Run("Rejects invalid input", func(t *testing.T) {
+		for _, input := range []string{``, `{`, `[1,]`, `{"a":1} 2`, `nul`} {
+			_, err := Parse([]byte(input))
+			assert.Error(t, err, input)
+		}
+	})
+
+	t.Run("Rejects deep nesting", func(t *testing.T) {
+		deep := strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1)
+		_, err := Parse([]byte(deep))
+		assert.Error(t, err)
+	})
+}
+
+func TestNumbers(t *testing.T) {
+	assert.Equal(t, "-42", Int(-42).Text)
+	assert.Equal(t, "18446744073709551615", Uint(18446744073709551615).Text)
+	assert.Equal(t, "-10", NegativeUint(9).Text)
+	assert.Equal(t, "-18446744073709551616", NegativeUint(18446744073709551615).Text)
+
+	for f, want := range map[float64]string{1.5: "1.5", 100: "100", 1e21: "1e+21", 1e-7: "1e-07"} {
+		v, err := Float(f)
+		require.NoError(t, err)
+		assert.Equal(t, want, v.Text)
+	}
+
+	v := Value{Kind: Number, Text: "12"}
+	n, ok := v.AsInt()
+	assert.True(t, ok)
+	assert.Equal(t, int64(12), n)
+	_, ok = Value{Kind: Number, Text: "1.5"}.AsUint()
+	assert.False(t, ok)
+
+	assert.Equal(t, "aGk=", Bytes([]byte("hi")).Text)
+}
+
+func TestSortKeys(t *testing.T) {
+	v, err := Parse([]byte(`{"b":[{"y":1,"x":2}],"a":null}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":null,"b":[{"x":2,"y":1}]}`, string(v.SortKeys().AppendJSON(nil)))
+	assert.Equal(t, `{"b":[{"y":1,"x":2}],"a":null}`, string(v.AppendJSON(nil)),
+		"the original keeps its order")
+}
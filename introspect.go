@@ -0,0 +1,85 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jkbrsn/jsonrpc/openrpc"
+)
+
+// Method names under which the built-in introspection methods are served.
+const (
+	ListMethodsMethod = "rpc.listMethods"
+	DescribeMethod    = "rpc.describe"
+	PingMethod        = "rpc.ping"
+)
+
+// pong is the result of PingMethod.
+const pong = "pong"
+
+// MethodDescription is the result of DescribeMethod: the method as documented in the server's
+// OpenRPC document, with the components its schemas refer to.
+type MethodDescription struct {
+	openrpc.Method
+	Components *openrpc.Components `json:"components,omitempty"`
+}
+
+// introspectParams are the params of DescribeMethod, accepted by name or by position.
+type introspectParams struct {
+	Method string `json:"method"`
+}
+
+// WithIntrospection serves the introspection methods: rpc.listMethods returns the sorted names of
+// all the methods the server serves, including built-in ones, and rpc.describe returns the
+// MethodDescription of the method named by its params, ["name"] or {"method": "name"}. Like
+// rpc.discover, these methods go through middleware and authorization.
+func WithIntrospection() ServerOption {
+	return func(s *Server) {
+		s.registerBuiltin(ListMethodsMethod, func(context.Context, *Request) (any, error) {
+			names := s.Methods()
+			slices.Sort(names)
+			return names, nil
+		})
+		s.registerBuiltin(DescribeMethod, func(_ context.Context, req *Request) (any, error) {
+			params, err := DecodeParams[introspectParams](req)
+			if err != nil {
+				return nil, err
+			}
+			desc, ok := s.describeOne(params.Method)
+			if !ok {
+				return nil, ErrInvalidParams.WithData(
+					fmt.Sprintf("method %q is not registered", params.Method))
+			}
+			return desc, nil
+		})
+	}
+}
+
+// WithPing serves rpc.ping, which answers "pong" for liveness checks. If check is not nil, it is
+// called on every ping, and an error it returns is returned instead.
+func WithPing(check func(ctx context.Context) error) ServerOption {
+	return func(s *Server) {
+		s.registerBuiltin(PingMethod, func(ctx context.Context, _ *Request) (any, error) {
+			if check != nil {
+				if err := check(ctx); err != nil {
+					return nil, err
+				}
+			}
+			return pong, nil
+		})
+	}
+}
+
+// describeOne returns the description of a registered method, and false if there is none.
+func (s *Server) describeOne(name string) (*MethodDescription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.registry.Load().methods[name]; !ok {
+		return nil, false
+	}
+	reflector := openrpc.NewReflector()
+	method := s.describeMethod(reflector, name)
+	return &MethodDescription{Method: method, Components: reflector.Components()}, true
+}
@@ -0,0 +1,87 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInlineClient returns a client handing its messages to srv.
+func newInlineClient(srv *Server) *Client {
+	roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}
+	return NewClient(&funcTransport{fn: roundTrip})
+}
+
+func TestServer_WithIntrospection(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+	WithIntrospection()(srv)
+	require.NoError(t, srv.Register("transfer", HandlerFunc(
+		func(context.Context, *Request) (any, error) { return receipt{}, nil })))
+	srv.Describe("transfer", MethodInfo{Summary: "Moves funds", Result: receipt{}})
+	client := newInlineClient(srv)
+
+	t.Run("rpc.listMethods lists all methods sorted", func(t *testing.T) {
+		var names []string
+		require.NoError(t, client.Call(ctx, ListMethodsMethod, nil, &names))
+		assert.IsNonDecreasing(t, names)
+		assert.Contains(t, names, "sum")
+		assert.Contains(t, names, "transfer")
+		assert.Contains(t, names, DescribeMethod)
+	})
+
+	t.Run("rpc.describe by position and by name", func(t *testing.T) {
+		var byPosition, byName MethodDescription
+		require.NoError(t, client.Call(ctx, DescribeMethod, Positional("transfer"), &byPosition))
+		params := map[string]string{"method": "transfer"}
+		require.NoError(t, client.Call(ctx, DescribeMethod, params, &byName))
+		assert.Equal(t, byPosition, byName)
+
+		assert.Equal(t, "transfer", byName.Name)
+		assert.Equal(t, "Moves funds", byName.Summary)
+		require.NotNil(t, byName.Result)
+		assert.Equal(t, "#/components/schemas/receipt", byName.Result.Schema.Ref)
+		require.NotNil(t, byName.Components)
+		assert.Contains(t, byName.Components.Schemas, "receipt")
+	})
+
+	t.Run("rpc.describe of an unknown method", func(t *testing.T) {
+		err := client.Call(ctx, DescribeMethod, Positional("missing"), nil)
+		require.ErrorIs(t, err, ErrInvalidParams)
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		plain := newInlineClient(newTestServer(t))
+		err := plain.Call(ctx, ListMethodsMethod, nil, nil)
+		require.ErrorIs(t, err, ErrMethodNotFound)
+	})
+}
+
+func TestServer_WithPing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Answers pong", func(t *testing.T) {
+		srv := newTestServer(t)
+		WithPing(nil)(srv)
+
+		var result string
+		require.NoError(t, newInlineClient(srv).Call(ctx, PingMethod, nil, &result))
+		assert.Equal(t, "pong", result)
+	})
+
+	t.Run("Failing check is returned", func(t *testing.T) {
+		srv := newTestServer(t)
+		unhealthy := &Error{Code: -32050, Message: "database unreachable"}
+		WithPing(func(context.Context) error { return unhealthy })(srv)
+
+		err := newInlineClient(srv).Call(ctx, PingMethod, nil, nil)
+		var rpcErr *Error
+		require.True(t, errors.As(err, &rpcErr))
+		assert.Equal(t, unhealthy.Code, rpcErr.Code)
+	})
+}
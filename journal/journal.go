@@ -0,0 +1,231 @@
+// Package journal records the calls of jsonrpc servers and clients to a journal of NDJSON
+// entries, for audits, and replays journals against live endpoints, for regression testing.
+//
+// Every request is recorded before it is handled or sent, as a write-ahead log, and its outcome
+// once the call completes, with the time it took. Recording plugs into jsonrpc.Observer, so it
+// sees calls with their final outcome. Entries go to a Sink: WriterSink writes them to any
+// io.Writer, and OpenFile appends them to a file.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// filePerm is the permission of journal files created by OpenFile.
+const filePerm = 0o600
+
+// Kind tells what an Entry records.
+type Kind string
+
+// Kinds of entries.
+const (
+	// KindRequest entries record a request or notification as it starts.
+	KindRequest Kind = "request"
+
+	// KindResponse entries record the outcome of a call as it completes.
+	KindResponse Kind = "response"
+)
+
+// Entry is a line of a journal.
+type Entry struct {
+	// Seq numbers the calls of a journal, relating each response entry to its request entry.
+	Seq uint64 `json:"seq"`
+
+	// Kind tells whether the entry records a request or its outcome.
+	Kind Kind `json:"kind"`
+
+	// Time is when the entry was recorded.
+	Time time.Time `json:"time"`
+
+	// Request is the request or notification, on request entries.
+	Request json.RawMessage `json:"request,omitempty"`
+
+	// Response is the response, on response entries of calls that got one.
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Error is the error of the call, on response entries: on servers, the error returned by the
+	// handler; on clients, the failure to send the call or receive its response.
+	Error string `json:"error,omitempty"`
+
+	// Duration is the time the call took in nanoseconds, on response entries.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// Sink stores journal entries. Implementations must be safe for concurrent use.
+type Sink interface {
+	// Append stores an entry. It is called synchronously with the call, so a slow sink slows
+	// the calls down.
+	Append(entry *Entry) error
+}
+
+// config holds the options of the journal and of Replay.
+type config struct {
+	onError func(error)
+	equal   func(want, got []byte) bool
+}
+
+// Option configures Server, Client, and Replay.
+type Option func(*config)
+
+// WithErrorHandler sets a function called with the errors of the sink, which are otherwise
+// dropped. Calls are not failed by errors of the journal.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+// newConfig returns the configuration set by opts.
+func newConfig(opts []Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Server returns an option recording every request a server handles, including the members of
+// batches, to sink.
+func Server(sink Sink, opts ...Option) jsonrpc.ServerOption {
+	return jsonrpc.WithObserver(newRecorder(sink, opts))
+}
+
+// Client returns an option recording every Call and Notify of a client to sink. Batches are not
+// recorded.
+func Client(sink Sink, opts ...Option) jsonrpc.ClientOption {
+	return jsonrpc.WithClientObserver(newRecorder(sink, opts))
+}
+
+// recorder adapts a Sink to jsonrpc.Observer.
+type recorder struct {
+	sink Sink
+	cfg  *config
+	seq  atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[*jsonrpc.Request]uint64
+}
+
+// newRecorder returns a recorder writing to sink.
+func newRecorder(sink Sink, opts []Option) *recorder {
+	return &recorder{
+		sink:    sink,
+		cfg:     newConfig(opts),
+		pending: make(map[*jsonrpc.Request]uint64),
+	}
+}
+
+// CallStarted implements jsonrpc.Observer.
+func (r *recorder) CallStarted(_ context.Context, req *jsonrpc.Request) {
+	seq := r.seq.Add(1)
+	r.mu.Lock()
+	r.pending[req] = seq
+	r.mu.Unlock()
+
+	raw, err := req.MarshalJSON()
+	if err != nil {
+		r.report(err)
+		return
+	}
+	r.append(&Entry{Seq: seq, Kind: KindRequest, Time: time.Now(), Request: raw})
+}
+
+// CallFinished implements jsonrpc.Observer.
+func (r *recorder) CallFinished(_ context.Context, event jsonrpc.CallEvent) {
+	r.mu.Lock()
+	seq, ok := r.pending[event.Request]
+	delete(r.pending, event.Request)
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	entry := &Entry{Seq: seq, Kind: KindResponse, Time: time.Now(), Duration: event.Duration}
+	if event.Response != nil {
+		raw, err := event.Response.MarshalJSON()
+		if err != nil {
+			r.report(err)
+		}
+		entry.Response = raw
+	}
+	if event.Err != nil {
+		entry.Error = event.Err.Error()
+	}
+	r.append(entry)
+}
+
+// BatchStarted implements jsonrpc.Observer.
+func (*recorder) BatchStarted(context.Context, int) {}
+
+// append stores entry, reporting a failure to do so.
+func (r *recorder) append(entry *Entry) {
+	if err := r.sink.Append(entry); err != nil {
+		r.report(err)
+	}
+}
+
+// report passes err to the error handler, if any.
+func (r *recorder) report(err error) {
+	if r.cfg.onError != nil {
+		r.cfg.onError(err)
+	}
+}
+
+// writerSink writes entries to an io.Writer as NDJSON.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WriterSink returns a Sink writing entries to w as NDJSON, one line per entry, each in a
+// single Write call.
+func WriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// Append implements Sink.
+func (s *writerSink) Append(entry *Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// FileSink is a Sink appending entries to a file as NDJSON.
+type FileSink struct {
+	Sink
+	file *os.File
+}
+
+// OpenFile returns a FileSink appending to the file at path, which is created if needed.
+func OpenFile(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, filePerm)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{Sink: WriterSink(file), file: file}, nil
+}
+
+// Sync commits the entries written so far to stable storage.
+func (s *FileSink) Sync() error {
+	return s.file.Sync()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,171 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink collects entries in memory.
+type memorySink struct {
+	mu      sync.Mutex
+	entries []Entry
+	err     error
+}
+
+func (s *memorySink) Append(entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+// serverTransport exchanges payloads with a server in process.
+type serverTransport struct {
+	srv *jsonrpc.Server
+	err error
+}
+
+func (t *serverTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.srv.HandleMessage(ctx, payload), nil
+}
+
+func (*serverTransport) Close() error {
+	return nil
+}
+
+// newServer creates a server with an "echo" and a "fail" method.
+func newServer(t *testing.T, opts ...jsonrpc.ServerOption) *jsonrpc.Server {
+	t.Helper()
+	srv := jsonrpc.NewServer(opts...)
+	echo := jsonrpc.HandlerFunc(func(_ context.Context, req *jsonrpc.Request) (any, error) {
+		return req.Params, nil
+	})
+	fail := jsonrpc.HandlerFunc(func(context.Context, *jsonrpc.Request) (any, error) {
+		return nil, jsonrpc.ErrInvalidParams
+	})
+	require.NoError(t, srv.Register("echo", echo))
+	require.NoError(t, srv.Register("fail", fail))
+	return srv
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	sink := &memorySink{}
+	srv := newServer(t, Server(sink))
+
+	srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`))
+	srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":2,"method":"fail"}`))
+	srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"echo","params":[2]}`))
+
+	require.Len(t, sink.entries, 6)
+	for i, entry := range sink.entries {
+		assert.Equal(t, uint64(i/2+1), entry.Seq)
+		assert.False(t, entry.Time.IsZero())
+	}
+
+	request, response := sink.entries[0], sink.entries[1]
+	assert.Equal(t, KindRequest, request.Kind)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`,
+		string(request.Request))
+	assert.Equal(t, KindResponse, response.Kind)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":[1]}`, string(response.Response))
+	assert.Positive(t, response.Duration)
+
+	failed := sink.entries[3]
+	assert.Contains(t, string(failed.Response), `"code":-32602`)
+	assert.NotEmpty(t, failed.Error)
+
+	notification := sink.entries[5]
+	assert.Equal(t, KindResponse, notification.Kind)
+	assert.Nil(t, notification.Response)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	sink := &memorySink{}
+	transport := &serverTransport{srv: newServer(t)}
+	client := jsonrpc.NewClient(transport, Client(sink))
+	defer client.Close()
+
+	var got []int
+	require.NoError(t, client.Call(ctx, "echo", []int{1}, &got))
+	transport.err = assert.AnError
+	require.Error(t, client.Call(ctx, "echo", []int{2}, &got))
+
+	require.Len(t, sink.entries, 4)
+	assert.Contains(t, string(sink.entries[1].Response), `"result":[1]`)
+	assert.Equal(t, KindResponse, sink.entries[3].Kind)
+	assert.Nil(t, sink.entries[3].Response)
+	assert.Contains(t, sink.entries[3].Error, assert.AnError.Error())
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	var errs []error
+	sink := &memorySink{err: assert.AnError}
+	srv := newServer(t, Server(sink, WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})))
+
+	reply := srv.HandleMessage(context.Background(),
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`))
+	assert.Contains(t, string(reply), `"result":[1]`, "calls are not failed by the journal")
+	require.Len(t, errs, 2)
+	assert.ErrorIs(t, errors.Join(errs...), assert.AnError)
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := WriterSink(&buf)
+	require.NoError(t, sink.Append(&Entry{Seq: 1, Kind: KindRequest, Request: []byte(`{}`)}))
+	require.NoError(t, sink.Append(&Entry{Seq: 1, Kind: KindResponse}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var entry Entry
+	require.NoError(t, json.Unmarshal(lines[0], &entry))
+	assert.Equal(t, KindRequest, entry.Kind)
+	assert.JSONEq(t, `{}`, string(entry.Request))
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.ndjson")
+	for seq := range uint64(2) {
+		sink, err := OpenFile(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Append(&Entry{Seq: seq, Kind: KindRequest}))
+		require.NoError(t, sink.Sync())
+		require.NoError(t, sink.Close())
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	r := NewReader(file)
+	for seq := range uint64(2) {
+		entry, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, seq, entry.Seq, "entries are appended")
+	}
+	_, err = r.Next()
+	assert.ErrorIs(t, err, io.EOF)
+
+	_, err = OpenFile(filepath.Join(t.TempDir(), "missing", "calls.ndjson"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// WithEqual sets how Replay compares a recorded response with the live one, such as by comparing
+// only their results. Defaults to comparing their canonical JSON, as returned by
+// jsonrpc.CanonicalJSON.
+func WithEqual(equal func(want, got []byte) bool) Option {
+	return func(c *config) {
+		c.equal = equal
+	}
+}
+
+// Reader reads the entries of a journal.
+type Reader struct {
+	dec *json.Decoder
+}
+
+// NewReader returns a Reader of the journal read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(r)}
+}
+
+// Next returns the next entry of the journal, or io.EOF at its end.
+func (r *Reader) Next() (*Entry, error) {
+	var entry Entry
+	if err := r.dec.Decode(&entry); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("journal: reading entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Mismatch is a replayed call whose live outcome differs from the recorded one.
+type Mismatch struct {
+	// Seq is the number of the call in the journal.
+	Seq uint64
+
+	// Request is the replayed request.
+	Request json.RawMessage
+
+	// Want is the recorded response.
+	Want json.RawMessage
+
+	// Got is the live response, nil if the exchange failed.
+	Got json.RawMessage
+
+	// Err is the failure to exchange the request, if any.
+	Err error
+}
+
+// Report is the outcome of a Replay.
+type Report struct {
+	// Replayed is the number of requests sent.
+	Replayed int
+
+	// Mismatches lists the calls whose live response differs from the recorded one, in the
+	// order of the journal.
+	Mismatches []Mismatch
+}
+
+// recorded is a call read from a journal.
+type recorded struct {
+	seq      uint64
+	request  json.RawMessage
+	response json.RawMessage
+}
+
+// Replay sends every request of the journal read from r through transport, one at a time in
+// the order recorded, and compares each live response with the recorded one. Requests, including
+// their IDs, are sent as recorded. Calls recorded without a response, such as notifications or
+// calls that failed or were cut short, are sent without being compared.
+//
+// Replay returns an error if the journal cannot be read or ctx is done; mismatching responses
+// and failures to exchange the requests are reported in the Report instead.
+func Replay(
+	ctx context.Context,
+	r io.Reader,
+	transport jsonrpc.Transport,
+	opts ...Option,
+) (*Report, error) {
+	calls, err := readCalls(NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	equal := newConfig(opts).equal
+	if equal == nil {
+		equal = canonicalEqual
+	}
+	report := &Report{}
+	for _, call := range calls {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		got, err := transport.RoundTrip(ctx, call.request)
+		report.Replayed++
+		switch {
+		case err != nil:
+			report.Mismatches = append(report.Mismatches, call.mismatch(nil, err))
+		case call.response != nil && !equal(call.response, got):
+			report.Mismatches = append(report.Mismatches, call.mismatch(got, nil))
+		default:
+		}
+	}
+	return report, nil
+}
+
+// readCalls reads the calls of a journal, in the order of their requests.
+func readCalls(r *Reader) ([]*recorded, error) {
+	var calls []*recorded
+	bySeq := make(map[uint64]*recorded)
+	for {
+		entry, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return calls, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch entry.Kind {
+		case KindRequest:
+			call := &recorded{seq: entry.Seq, request: entry.Request}
+			calls = append(calls, call)
+			bySeq[entry.Seq] = call
+		case KindResponse:
+			if call, ok := bySeq[entry.Seq]; ok {
+				call.response = entry.Response
+			}
+		default:
+		}
+	}
+}
+
+// mismatch returns the mismatch of the call with the live response got or the error err.
+func (c *recorded) mismatch(got []byte, err error) Mismatch {
+	return Mismatch{Seq: c.seq, Request: c.request, Want: c.response, Got: got, Err: err}
+}
+
+// canonicalEqual reports whether want and got have the same canonical JSON, or are identical if
+// either cannot be canonicalized.
+func canonicalEqual(want, got []byte) bool {
+	canonicalWant, errWant := jsonrpc.CanonicalJSON(want)
+	canonicalGot, errGot := jsonrpc.CanonicalJSON(got)
+	if errWant != nil || errGot != nil {
+		return bytes.Equal(want, got)
+	}
+	return bytes.Equal(canonicalWant, canonicalGot)
+}
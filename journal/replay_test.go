@@ -0,0 +1,98 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// record returns the journal of the given messages handled by a server with an "echo" and a
+// "fail" method.
+func record(t *testing.T, messages ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	srv := newServer(t, Server(WriterSink(&buf)))
+	for _, msg := range messages {
+		srv.HandleMessage(context.Background(), []byte(msg))
+	}
+	return buf.Bytes()
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	journal := record(t,
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`,
+		`{"jsonrpc":"2.0","id":2,"method":"fail"}`,
+		`{"jsonrpc":"2.0","method":"echo","params":[2]}`,
+	)
+
+	t.Run("Matching endpoint", func(t *testing.T) {
+		transport := &serverTransport{srv: newServer(t)}
+		report, err := Replay(ctx, bytes.NewReader(journal), transport)
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Replayed)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("Regressed endpoint", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("echo",
+			func(context.Context, *jsonrpc.Request) (any, error) { return "changed", nil }))
+		report, err := Replay(ctx, bytes.NewReader(journal), &serverTransport{srv: srv})
+		require.NoError(t, err)
+		require.Len(t, report.Mismatches, 2)
+
+		mismatch := report.Mismatches[0]
+		assert.Equal(t, uint64(1), mismatch.Seq)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`,
+			string(mismatch.Request))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":[1]}`, string(mismatch.Want))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"changed"}`, string(mismatch.Got))
+		assert.Contains(t, string(report.Mismatches[1].Got), `"code":-32601`)
+	})
+
+	t.Run("Exchange failures", func(t *testing.T) {
+		transport := &serverTransport{err: assert.AnError}
+		report, err := Replay(ctx, bytes.NewReader(journal), transport)
+		require.NoError(t, err)
+		require.Len(t, report.Mismatches, 3)
+		assert.ErrorIs(t, report.Mismatches[2].Err, assert.AnError)
+	})
+
+	t.Run("Custom comparison", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		anything := func(_, _ []byte) bool { return true }
+		report, err := Replay(ctx, bytes.NewReader(journal), &serverTransport{srv: srv},
+			WithEqual(anything))
+		require.NoError(t, err)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("Calls cut short are sent without comparison", func(t *testing.T) {
+		cut := `{"seq":1,"kind":"request","time":"2024-01-02T03:04:05Z",` +
+			`"request":{"jsonrpc":"2.0","id":1,"method":"missing"}}`
+		transport := &serverTransport{srv: newServer(t)}
+		report, err := Replay(ctx, strings.NewReader(cut), transport)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Replayed)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("Malformed journal", func(t *testing.T) {
+		_, err := Replay(ctx, strings.NewReader("{not json"), &serverTransport{})
+		assert.ErrorContains(t, err, "journal: reading entry")
+	})
+
+	t.Run("Canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		report, err := Replay(canceled, bytes.NewReader(journal), &serverTransport{})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, report.Replayed)
+	})
+}
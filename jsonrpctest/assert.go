@@ -0,0 +1,59 @@
+package jsonrpctest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// AssertErrorCode fails the test unless err is or wraps a *jsonrpc.Error with code, and reports
+// whether it does.
+func AssertErrorCode(t testing.TB, err error, code int) bool {
+	t.Helper()
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) {
+		t.Errorf("jsonrpctest: got error %v, want a JSON-RPC error with code %d", err, code)
+		return false
+	}
+	if rpcErr.Code != code {
+		t.Errorf("jsonrpctest: got error code %d (%s), want %d", rpcErr.Code, rpcErr.Message, code)
+		return false
+	}
+	return true
+}
+
+// AssertResult fails the test unless resp is a successful response whose result equals want once
+// encoded as JSON, regardless of the order of object members, and reports whether it is.
+func AssertResult(t testing.TB, resp *jsonrpc.Response, want any) bool {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("jsonrpctest: got no response, want result %v", want)
+		return false
+	}
+	if rpcErr := resp.Err(); rpcErr != nil {
+		t.Errorf("jsonrpctest: got error %v, want result %v", rpcErr, want)
+		return false
+	}
+
+	var got any
+	if err := resp.UnmarshalResult(&got); err != nil {
+		t.Errorf("jsonrpctest: invalid result: %v", err)
+		return false
+	}
+	gotJSON, err := canonicalJSON(got)
+	if err != nil {
+		t.Errorf("jsonrpctest: invalid result: %v", err)
+		return false
+	}
+	wantJSON, err := canonicalJSON(want)
+	if err != nil {
+		t.Errorf("jsonrpctest: invalid expected result: %v", err)
+		return false
+	}
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("jsonrpctest: got result %s, want %s", gotJSON, wantJSON)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package jsonrpctest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+func TestAssertErrorCode(t *testing.T) {
+	wrapped := fmt.Errorf("call failed: %w", jsonrpc.ErrInvalidParams)
+	assert.True(t, AssertErrorCode(t, wrapped, jsonrpc.InvalidParams))
+
+	ft := &fakeT{TB: t}
+	assert.False(t, AssertErrorCode(ft, wrapped, jsonrpc.MethodNotFound))
+	assert.False(t, AssertErrorCode(ft, errors.New("plain"), jsonrpc.MethodNotFound))
+	assert.Len(t, ft.finish(), 2)
+}
+
+func TestAssertResult(t *testing.T) {
+	resp, err := jsonrpc.NewResponse(int64(1), map[string]any{"a": 1, "b": []string{"x"}})
+	require.NoError(t, err)
+	assert.True(t, AssertResult(t, resp, map[string]any{"b": []string{"x"}, "a": 1}))
+
+	ft := &fakeT{TB: t}
+	assert.False(t, AssertResult(ft, resp, map[string]any{"a": 2}))
+	assert.False(t, AssertResult(ft, nil, 1))
+	failed := jsonrpc.NewErrorResponse(int64(1), jsonrpc.ErrInternal)
+	assert.False(t, AssertResult(ft, failed, 1))
+	assert.Len(t, ft.finish(), 3)
+}
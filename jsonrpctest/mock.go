@@ -0,0 +1,221 @@
+// Package jsonrpctest provides test doubles for code using the jsonrpc package: an in-memory
+// transport and stream pipe, a scriptable mock server, and assertion helpers, so that unit tests
+// need no network listeners.
+package jsonrpctest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// Mock is a JSON-RPC server answering calls from scripted expectations:
+//
+//	mock := jsonrpctest.NewMock(t)
+//	mock.Expect("user.get").WithParams([]any{42}).Return(User{Name: "alice"})
+//	client := mock.Client()
+//
+// Each call is answered by the first expectation matching its method and params that is not
+// used up. Calls matching no expectation fail the test and are answered with a method not found
+// error. When the test ends, NewMock's cleanup fails it if an expectation was not met.
+type Mock struct {
+	t   testing.TB
+	srv *jsonrpc.Server
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []*jsonrpc.Request
+}
+
+// Expectation is an expected call of a Mock, created by Mock.Expect. By default it expects one
+// call with any params and answers it with a null result.
+type Expectation struct {
+	mock    *Mock
+	method  string
+	params  []byte
+	handler jsonrpc.HandlerFunc
+	times   int
+	calls   int
+}
+
+// anyTimes is the times of expectations set with AnyTimes.
+const anyTimes = -1
+
+// NewMock creates a mock server for the test t. The server options apply to its underlying
+// server, so that mocks also exercise middleware, limits, or authentication.
+func NewMock(t testing.TB, opts ...jsonrpc.ServerOption) *Mock {
+	t.Helper()
+	m := &Mock{t: t, srv: jsonrpc.NewServer(opts...)}
+	m.srv.Use(func(jsonrpc.Handler) jsonrpc.Handler {
+		return jsonrpc.HandlerFunc(m.serve)
+	})
+	t.Cleanup(func() { m.AssertExpectations() })
+	return m
+}
+
+// Server returns the server the mock answers through, to serve it over HTTP or a stream.
+func (m *Mock) Server() *jsonrpc.Server {
+	return m.srv
+}
+
+// HandleMessage implements MessageHandler.
+func (m *Mock) HandleMessage(ctx context.Context, data []byte) []byte {
+	return m.srv.HandleMessage(ctx, data)
+}
+
+// Client returns a client calling the mock through an in-memory transport, closed when the test
+// ends.
+func (m *Mock) Client(opts ...jsonrpc.ClientOption) *jsonrpc.Client {
+	client := jsonrpc.NewClient(NewTransport(m.srv), opts...)
+	m.t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// Expect adds an expected call to method.
+func (m *Mock) Expect(method string) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &Expectation{mock: m, method: method, times: 1}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Calls returns the requests and notifications the mock received, in order.
+func (m *Mock) Calls() []*jsonrpc.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*jsonrpc.Request(nil), m.calls...)
+}
+
+// AssertExpectations fails the test for every expectation that did not receive its calls, and
+// reports whether all did.
+func (m *Mock) AssertExpectations() bool {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	for _, e := range m.expectations {
+		if e.times != anyTimes && e.calls != e.times {
+			m.t.Errorf("jsonrpctest: %s: got %d calls, want %d", e, e.calls, e.times)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// serve answers a call from the first matching expectation.
+func (m *Mock) serve(ctx context.Context, req *jsonrpc.Request) (any, error) {
+	params, err := canonicalJSON(req.Params)
+	if err != nil {
+		return nil, jsonrpc.ErrInvalidParams.WithData(err.Error())
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, req)
+	e := m.match(req.Method, params)
+	if e != nil {
+		e.calls++
+	}
+	m.mu.Unlock()
+
+	if e == nil {
+		m.t.Errorf("jsonrpctest: unexpected call to %s with params %s", req.Method, params)
+		return nil, jsonrpc.ErrMethodNotFound.WithData("unexpected call")
+	}
+	if e.handler == nil {
+		return nil, nil
+	}
+	return e.handler(ctx, req)
+}
+
+// match returns the first expectation matching a call, or nil. The caller must hold m.mu.
+func (m *Mock) match(method string, params []byte) *Expectation {
+	for _, e := range m.expectations {
+		if e.method != method || (e.times != anyTimes && e.calls >= e.times) {
+			continue
+		}
+		if e.params == nil || string(e.params) == string(params) {
+			return e
+		}
+	}
+	return nil
+}
+
+// WithParams restricts the expectation to calls with params equal to params once encoded as
+// JSON, regardless of the order of object members.
+func (e *Expectation) WithParams(params any) *Expectation {
+	e.mock.t.Helper()
+	canonical, err := canonicalJSON(params)
+	if err != nil {
+		e.mock.t.Fatalf("jsonrpctest: invalid params for %s: %v", e.method, err)
+	}
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.params = canonical
+	return e
+}
+
+// Return answers the expected calls with result.
+func (e *Expectation) Return(result any) *Expectation {
+	return e.Do(func(context.Context, *jsonrpc.Request) (any, error) {
+		return result, nil
+	})
+}
+
+// ReturnError answers the expected calls with err, sent as is if it is a *jsonrpc.Error.
+func (e *Expectation) ReturnError(err error) *Expectation {
+	return e.Do(func(context.Context, *jsonrpc.Request) (any, error) {
+		return nil, err
+	})
+}
+
+// Do answers the expected calls with handler.
+func (e *Expectation) Do(handler jsonrpc.HandlerFunc) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.handler = handler
+	return e
+}
+
+// Times sets the number of calls the expectation expects, after which it no longer matches.
+func (e *Expectation) Times(n int) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.times = n
+	return e
+}
+
+// AnyTimes makes the expectation match any number of calls, including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	return e.Times(anyTimes)
+}
+
+// String describes the expectation in failure messages.
+func (e *Expectation) String() string {
+	if e.params == nil {
+		return e.method
+	}
+	return fmt.Sprintf("%s with params %s", e.method, e.params)
+}
+
+// canonicalJSON encodes v as JSON with sorted object members, or returns nil for nil.
+func canonicalJSON(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	value, err := jsonvalue.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return value.SortKeys().AppendJSON(nil), nil
+}
@@ -0,0 +1,131 @@
+package jsonrpctest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// fakeT records the failures of the code under test instead of failing the test.
+type fakeT struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// finish runs the cleanups, as at the end of a test, and returns the recorded failures.
+func (f *fakeT) finish() []string {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.errors
+}
+
+func TestMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Answers from expectations", func(t *testing.T) {
+		mock := NewMock(t)
+		mock.Expect("user.get").WithParams([]any{42}).Return(map[string]string{"name": "alice"})
+		mock.Expect("user.get").WithParams([]any{7}).
+			ReturnError(&jsonrpc.Error{Code: 404, Message: "no such user"})
+
+		client := mock.Client()
+		var user map[string]string
+		require.NoError(t, client.Call(ctx, "user.get", jsonrpc.Positional(42), &user))
+		assert.Equal(t, "alice", user["name"])
+
+		err := client.Call(ctx, "user.get", jsonrpc.Positional(7), nil)
+		AssertErrorCode(t, err, 404)
+		assert.Len(t, mock.Calls(), 2)
+	})
+
+	t.Run("Named params match regardless of member order", func(t *testing.T) {
+		mock := NewMock(t)
+		mock.Expect("transfer").WithParams(map[string]any{"from": "a", "to": "b"}).Return(true)
+
+		payload := `{"jsonrpc":"2.0","method":"transfer","params":{"to":"b","from":"a"},"id":1}`
+		resp, err := jsonrpc.DecodeResponse(mock.HandleMessage(ctx, []byte(payload)))
+		require.NoError(t, err)
+		AssertResult(t, resp, true)
+	})
+
+	t.Run("Times and AnyTimes", func(t *testing.T) {
+		mock := NewMock(t)
+		mock.Expect("tick").Times(2).Return(1)
+		mock.Expect("tick").Return(2)
+		mock.Expect("idle").AnyTimes()
+
+		client := mock.Client()
+		for _, want := range []int{1, 1, 2} {
+			got, err := jsonrpc.Call[int](ctx, client, "tick", nil)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+		require.NoError(t, client.Notify(ctx, "idle", nil))
+	})
+
+	t.Run("Do handles calls", func(t *testing.T) {
+		mock := NewMock(t)
+		mock.Expect("echo").Do(func(_ context.Context, req *jsonrpc.Request) (any, error) {
+			return req.Params, nil
+		})
+
+		var got []string
+		require.NoError(t, mock.Client().Call(ctx, "echo", []string{"hi"}, &got))
+		assert.Equal(t, []string{"hi"}, got)
+	})
+
+	t.Run("Unexpected calls fail the test", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		mock := NewMock(ft)
+		mock.Expect("user.get").WithParams([]any{1})
+
+		err := mock.Client().Call(ctx, "user.get", jsonrpc.Positional(2), nil)
+		require.ErrorIs(t, err, jsonrpc.ErrMethodNotFound)
+
+		failures := ft.finish()
+		require.Len(t, failures, 2)
+		assert.Contains(t, failures[0], "unexpected call to user.get with params [2]")
+		assert.Contains(t, failures[1], "user.get with params [1]: got 0 calls, want 1")
+	})
+
+	t.Run("Server options apply", func(t *testing.T) {
+		mock := NewMock(t, jsonrpc.WithMaxBatchSize(1))
+		mock.Expect("a").AnyTimes()
+
+		reqs := []*jsonrpc.Request{
+			jsonrpc.NewRequestWithID("a", nil, int64(1)),
+			jsonrpc.NewRequestWithID("a", nil, int64(2)),
+		}
+		_, err := mock.Client().CallBatch(ctx, reqs)
+		require.Error(t, err)
+		assert.NotNil(t, mock.Server())
+	})
+}
@@ -0,0 +1,263 @@
+package jsonrpctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// Permissions of the recording files and of the directories created for them.
+const (
+	recordingPerm    = 0o644
+	recordingDirPerm = 0o755
+)
+
+// recording is the content of a recording file.
+type recording struct {
+	Exchanges []exchange `json:"exchanges"`
+}
+
+// exchange is a recorded payload and its reply, with normalized IDs. Reply is omitted for
+// payloads of notifications only.
+type exchange struct {
+	Payload json.RawMessage `json:"payload"`
+	Reply   json.RawMessage `json:"reply,omitempty"`
+}
+
+// RecordingTransport is a transport recording the exchanges of the transport it wraps, to be
+// saved as a file that NewReplayTransport replays. Recorded request IDs are replaced by their
+// order of appearance in each payload, 1 for the first, so that recordings do not depend on
+// generated IDs, and object members are sorted so that they do not depend on encoding order.
+type RecordingTransport struct {
+	next jsonrpc.Transport
+	path string
+
+	mu        sync.Mutex
+	exchanges []exchange
+}
+
+// NewRecordingTransport returns a transport sending payloads through next and recording them
+// with their replies, saved to path by Save or Close:
+//
+//	var transport jsonrpc.Transport
+//	if *update {
+//	    transport = jsonrpctest.NewRecordingTransport(jsonrpc.NewHTTPTransport(url), golden)
+//	} else {
+//	    transport, err = jsonrpctest.NewReplayTransport(golden)
+//	}
+func NewRecordingTransport(next jsonrpc.Transport, path string) *RecordingTransport {
+	return &RecordingTransport{next: next, path: path}
+}
+
+// RoundTrip implements jsonrpc.Transport. Exchanges that fail are not recorded.
+func (r *RecordingTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	reply, err := r.next.RoundTrip(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := new(idMap)
+	recorded, err := rewriteIDs(payload, ids.placeholder)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpctest: record payload: %w", err)
+	}
+	ex := exchange{Payload: recorded}
+	if len(bytes.TrimSpace(reply)) > 0 {
+		if ex.Reply, err = rewriteIDs(reply, ids.toPlaceholder); err != nil {
+			return nil, fmt.Errorf("jsonrpctest: record reply: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, ex)
+	r.mu.Unlock()
+	return reply, nil
+}
+
+// Save writes the exchanges recorded so far to the file, creating its directory if needed.
+func (r *RecordingTransport) Save() error {
+	r.mu.Lock()
+	rec := recording{Exchanges: slices.Clone(r.exchanges)}
+	r.mu.Unlock()
+	if rec.Exchanges == nil {
+		rec.Exchanges = []exchange{}
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), recordingDirPerm); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), recordingPerm)
+}
+
+// Close saves the recording and closes the wrapped transport.
+func (r *RecordingTransport) Close() error {
+	return errors.Join(r.Save(), r.next.Close())
+}
+
+// ReplayTransport is a transport answering payloads from a recording.
+type ReplayTransport struct {
+	mu        sync.Mutex
+	exchanges []exchange
+	used      []bool
+}
+
+// NewReplayTransport returns a transport replaying the recording saved at path by a
+// RecordingTransport. Each payload is answered with the reply of the first unused recorded
+// exchange with the same payload once IDs are normalized, so that concurrent calls may replay in a
+// different order than recorded, and the reply's IDs are restored to those of the payload.
+// Payloads without a recorded exchange fail.
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("jsonrpctest: invalid recording %s: %w", path, err)
+	}
+	for i := range rec.Exchanges {
+		payload, err := canonicalJSON(rec.Exchanges[i].Payload)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpctest: invalid recording %s: %w", path, err)
+		}
+		rec.Exchanges[i].Payload = payload
+	}
+	return &ReplayTransport{exchanges: rec.Exchanges, used: make([]bool, len(rec.Exchanges))}, nil
+}
+
+// RoundTrip implements jsonrpc.Transport.
+func (r *ReplayTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ids := new(idMap)
+	normalized, err := rewriteIDs(payload, ids.placeholder)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpctest: replay payload: %w", err)
+	}
+
+	r.mu.Lock()
+	i := r.find(normalized)
+	if i >= 0 {
+		r.used[i] = true
+	}
+	r.mu.Unlock()
+	if i < 0 {
+		return nil, fmt.Errorf("jsonrpctest: no recorded exchange for %s", normalized)
+	}
+
+	reply := r.exchanges[i].Reply
+	if reply == nil {
+		return nil, nil
+	}
+	return rewriteIDs(reply, ids.fromPlaceholder)
+}
+
+// find returns the index of the first unused exchange with payload, or -1. The caller must hold
+// r.mu.
+func (r *ReplayTransport) find(payload []byte) int {
+	for i, ex := range r.exchanges {
+		if !r.used[i] && bytes.Equal(ex.Payload, payload) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remaining returns the number of recorded exchanges not replayed yet.
+func (r *ReplayTransport) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// Close implements jsonrpc.Transport.
+func (*ReplayTransport) Close() error {
+	return nil
+}
+
+// idMap maps the IDs of a payload to the placeholders they are recorded as.
+type idMap struct {
+	placeholders map[string]jsonvalue.Value
+	ids          []jsonvalue.Value
+}
+
+// placeholder returns the placeholder of a payload ID, assigning the next one to new IDs.
+func (m *idMap) placeholder(id jsonvalue.Value) jsonvalue.Value {
+	if p, ok := m.toPlaceholderOK(id); ok {
+		return p
+	}
+	if m.placeholders == nil {
+		m.placeholders = make(map[string]jsonvalue.Value)
+	}
+	m.ids = append(m.ids, id)
+	p := jsonvalue.Int(int64(len(m.ids)))
+	m.placeholders[string(id.AppendJSON(nil))] = p
+	return p
+}
+
+// toPlaceholder returns the placeholder of a reply ID, or the ID itself if it is not one of the
+// payload's.
+func (m *idMap) toPlaceholder(id jsonvalue.Value) jsonvalue.Value {
+	p, _ := m.toPlaceholderOK(id)
+	return p
+}
+
+// toPlaceholderOK returns the placeholder of id, reporting whether it has one.
+func (m *idMap) toPlaceholderOK(id jsonvalue.Value) (jsonvalue.Value, bool) {
+	if p, ok := m.placeholders[string(id.AppendJSON(nil))]; ok {
+		return p, true
+	}
+	return id, false
+}
+
+// fromPlaceholder returns the payload ID a recorded placeholder stands for, or the placeholder
+// itself if it stands for none.
+func (m *idMap) fromPlaceholder(p jsonvalue.Value) jsonvalue.Value {
+	n, err := strconv.Atoi(p.Text)
+	if p.Kind != jsonvalue.Number || err != nil || n < 1 || n > len(m.ids) {
+		return p
+	}
+	return m.ids[n-1]
+}
+
+// rewriteIDs returns the message, single or batch, with the non-null IDs of its members replaced
+// by rewrite and with object members sorted.
+func rewriteIDs(data []byte, rewrite func(jsonvalue.Value) jsonvalue.Value) ([]byte, error) {
+	msg, err := jsonvalue.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	members := []jsonvalue.Value{msg}
+	if msg.Kind == jsonvalue.Array {
+		members = msg.Items
+	}
+	for _, member := range members {
+		for i, field := range member.Members {
+			if field.Key == "id" && field.Value.Kind != jsonvalue.Null {
+				member.Members[i].Value = rewrite(field.Value)
+			}
+		}
+	}
+	return msg.SortKeys().AppendJSON(nil), nil
+}
@@ -0,0 +1,106 @@
+package jsonrpctest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// record runs calls against an echo server through a RecordingTransport saved to a temporary
+// file, and returns the file's path.
+func record(t *testing.T, calls func(client *jsonrpc.Client)) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "testdata", "echo.json")
+	recorder := NewRecordingTransport(NewTransport(newEchoServer(t)), path)
+	client := jsonrpc.NewClient(recorder, jsonrpc.WithIDGenerator(jsonrpc.NewUUIDGenerator()))
+	calls(client)
+	require.NoError(t, client.Close())
+	return path
+}
+
+// replay returns a client replaying the recording at path, with its transport.
+func replay(t *testing.T, path string) (*jsonrpc.Client, *ReplayTransport) {
+	t.Helper()
+	transport, err := NewReplayTransport(path)
+	require.NoError(t, err)
+	client := jsonrpc.NewClient(transport, jsonrpc.WithIDGenerator(jsonrpc.NewRandomIDGenerator()))
+	t.Cleanup(func() { _ = client.Close() })
+	return client, transport
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Replays calls with other IDs", func(t *testing.T) {
+		path := record(t, func(client *jsonrpc.Client) {
+			require.NoError(t, client.Call(ctx, "echo", []string{"a"}, nil))
+			require.NoError(t, client.Call(ctx, "echo", map[string]int{"y": 2, "x": 1}, nil))
+			require.NoError(t, client.Notify(ctx, "echo", nil))
+		})
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"id": 1`, "IDs are normalized")
+
+		client, transport := replay(t, path)
+		var got map[string]int
+		require.NoError(t, client.Call(ctx, "echo", map[string]int{"x": 1, "y": 2}, &got))
+		assert.Equal(t, map[string]int{"x": 1, "y": 2}, got)
+		var echoed []string
+		require.NoError(t, client.Call(ctx, "echo", []string{"a"}, &echoed))
+		assert.Equal(t, []string{"a"}, echoed)
+		require.NoError(t, client.Notify(ctx, "echo", nil))
+		assert.Equal(t, 0, transport.Remaining())
+	})
+
+	t.Run("Replays batches", func(t *testing.T) {
+		batch := func() []*jsonrpc.Request {
+			return []*jsonrpc.Request{
+				jsonrpc.NewRequest("echo", []int{1}),
+				jsonrpc.NewRequest("echo", []int{2}),
+			}
+		}
+		path := record(t, func(client *jsonrpc.Client) {
+			_, err := client.CallBatch(ctx, batch())
+			require.NoError(t, err)
+		})
+
+		client, _ := replay(t, path)
+		reqs := batch()
+		resps, err := client.CallBatch(ctx, reqs)
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		for i, resp := range resps {
+			assert.Equal(t, reqs[i].ID, resp.IDOrNil())
+			AssertResult(t, resp, []int{i + 1})
+		}
+	})
+
+	t.Run("Unrecorded and exhausted payloads fail", func(t *testing.T) {
+		path := record(t, func(client *jsonrpc.Client) {
+			require.NoError(t, client.Call(ctx, "echo", []int{1}, nil))
+		})
+
+		client, transport := replay(t, path)
+		require.ErrorContains(t, client.Call(ctx, "echo", []int{2}, nil), "no recorded exchange")
+		require.NoError(t, client.Call(ctx, "echo", []int{1}, nil))
+		require.Error(t, client.Call(ctx, "echo", []int{1}, nil))
+		assert.Equal(t, 0, transport.Remaining())
+	})
+
+	t.Run("Invalid recordings fail", func(t *testing.T) {
+		_, err := NewReplayTransport(filepath.Join(t.TempDir(), "missing.json"))
+		require.Error(t, err)
+
+		path := filepath.Join(t.TempDir(), "invalid.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+		_, err = NewReplayTransport(path)
+		require.Error(t, err)
+	})
+}
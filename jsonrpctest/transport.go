@@ -0,0 +1,100 @@
+package jsonrpctest
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// MessageHandler handles encoded JSON-RPC messages, as jsonrpc.Server and jsonrpc.Proxy do.
+type MessageHandler interface {
+	HandleMessage(ctx context.Context, data []byte) []byte
+}
+
+// transport hands the payloads of a client to a MessageHandler in the same process.
+type transport struct {
+	handler MessageHandler
+}
+
+// NewTransport returns a transport delivering each payload to handler, such as a jsonrpc.Server,
+// without a network listener:
+//
+//	client := jsonrpc.NewClient(jsonrpctest.NewTransport(srv))
+func NewTransport(handler MessageHandler) jsonrpc.Transport {
+	return &transport{handler: handler}
+}
+
+// RoundTrip implements jsonrpc.Transport.
+func (t *transport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.handler.HandleMessage(ctx, slices.Clone(payload)), nil
+}
+
+// Close implements jsonrpc.Transport.
+func (*transport) Close() error {
+	return nil
+}
+
+// pipeEnd is one end of a pipe created by NewPipe.
+type pipeEnd struct {
+	in     <-chan []byte
+	out    chan<- []byte
+	closed chan struct{}
+	once   *sync.Once
+}
+
+// NewPipe returns the two ends of an in-memory, unbuffered stream, so that a client created with
+// jsonrpc.NewStreamClient on one end talks to a server serving the other with ServeStream:
+//
+//	clientEnd, serverEnd := jsonrpctest.NewPipe()
+//	go srv.ServeStream(ctx, serverEnd)
+//	client := jsonrpc.NewStreamClient(clientEnd)
+//
+// Closing either end closes both: reads then return io.EOF and writes io.ErrClosedPipe.
+func NewPipe() (a, b jsonrpc.Stream) {
+	left, right := make(chan []byte), make(chan []byte)
+	closed := make(chan struct{})
+	once := new(sync.Once)
+	return &pipeEnd{in: left, out: right, closed: closed, once: once},
+		&pipeEnd{in: right, out: left, closed: closed, once: once}
+}
+
+// ReadMessage implements jsonrpc.Stream.
+func (p *pipeEnd) ReadMessage(ctx context.Context) ([]byte, error) {
+	select {
+	case msg := <-p.in:
+		return msg, nil
+	case <-p.closed:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WriteMessage implements jsonrpc.Stream.
+func (p *pipeEnd) WriteMessage(ctx context.Context, msg []byte) error {
+	select {
+	case <-p.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	select {
+	case p.out <- slices.Clone(msg):
+		return nil
+	case <-p.closed:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close implements jsonrpc.Stream.
+func (p *pipeEnd) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return nil
+}
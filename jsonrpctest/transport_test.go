@@ -0,0 +1,79 @@
+package jsonrpctest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// newEchoServer returns a server with an echo method.
+func newEchoServer(t *testing.T) *jsonrpc.Server {
+	t.Helper()
+	srv := jsonrpc.NewServer()
+	require.NoError(t, srv.RegisterFunc("echo",
+		func(_ context.Context, req *jsonrpc.Request) (any, error) { return req.Params, nil }))
+	return srv
+}
+
+func TestNewTransport(t *testing.T) {
+	ctx := context.Background()
+	client := jsonrpc.NewClient(NewTransport(newEchoServer(t)))
+	defer func() { _ = client.Close() }()
+
+	var got []int
+	require.NoError(t, client.Call(ctx, "echo", []int{1, 2}, &got))
+	assert.Equal(t, []int{1, 2}, got)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, client.Call(canceled, "echo", nil, nil), context.Canceled)
+}
+
+func TestNewPipe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Client and server talk over the pipe", func(t *testing.T) {
+		clientEnd, serverEnd := NewPipe()
+		served := make(chan error, 1)
+		go func() { served <- newEchoServer(t).ServeStream(ctx, serverEnd) }()
+
+		client := jsonrpc.NewStreamClient(clientEnd)
+		var got []string
+		require.NoError(t, client.Call(ctx, "echo", []string{"hi"}, &got))
+		assert.Equal(t, []string{"hi"}, got)
+		require.NoError(t, client.Close())
+
+		select {
+		case err := <-served:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("server did not stop")
+		}
+	})
+
+	t.Run("Closing one end closes both", func(t *testing.T) {
+		a, b := NewPipe()
+		require.NoError(t, a.Close())
+		require.NoError(t, a.Close())
+
+		_, err := b.ReadMessage(ctx)
+		require.ErrorIs(t, err, io.EOF)
+		require.ErrorIs(t, b.WriteMessage(ctx, []byte("{}")), io.ErrClosedPipe)
+	})
+
+	t.Run("Operations honor their context", func(t *testing.T) {
+		a, _ := NewPipe()
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := a.ReadMessage(short)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorIs(t, a.WriteMessage(short, []byte("{}")), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,488 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// node is a compiled schema.
+type node struct {
+	// boolean is set for the schemas true and false, which accept every value and none.
+	boolean *bool
+
+	// ref is the JSON pointer of the schema referenced with $ref, and target its compiled form.
+	ref    string
+	target *node
+
+	types []string
+
+	// enum and constant hold the canonical forms of the values allowed, and enumText and
+	// constText the JSON they were given as.
+	enum      []string
+	enumText  string
+	constant  *string
+	constText string
+
+	minimum          *bound
+	maximum          *bound
+	exclusiveMinimum *bound
+	exclusiveMaximum *bound
+	multipleOf       *bound
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+
+	prefixItems []*node
+	items       *node
+	minItems    int
+	maxItems    int
+	uniqueItems bool
+
+	properties           map[string]*node
+	patternProperties    []patternSchema
+	additionalProperties *node
+	required             []string
+	minProperties        int
+	maxProperties        int
+
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+}
+
+// unbounded is the value of the maximum counts of a node without one.
+const unbounded = -1
+
+// bound is a number given by a numeric keyword.
+type bound struct {
+	value *big.Rat
+	text  string
+}
+
+// patternSchema is a schema of patternProperties.
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *node
+}
+
+// rejectsAll reports whether n is the schema false.
+func (n *node) rejectsAll() bool {
+	return n.boolean != nil && !*n.boolean
+}
+
+// compileFunc compiles the value v of a keyword, found at ptr, into n.
+type compileFunc func(c *compiler, n *node, v jsonvalue.Value, ptr string) error
+
+// keywords holds the compilers of the supported keywords; others, such as annotations and
+// format, are ignored. It is filled by init, as its compilers recurse into compiler.compile.
+var keywords map[string]compileFunc
+
+func init() {
+	keywords = map[string]compileFunc{
+		"$ref":                 compileRef,
+		"type":                 compileType,
+		"enum":                 compileEnum,
+		"const":                compileConst,
+		"minimum":              numberKeyword(func(n *node) **bound { return &n.minimum }),
+		"maximum":              numberKeyword(func(n *node) **bound { return &n.maximum }),
+		"exclusiveMinimum":     numberKeyword(func(n *node) **bound { return &n.exclusiveMinimum }),
+		"exclusiveMaximum":     numberKeyword(func(n *node) **bound { return &n.exclusiveMaximum }),
+		"multipleOf":           compileMultipleOf,
+		"minLength":            countKeyword(func(n *node) *int { return &n.minLength }),
+		"maxLength":            countKeyword(func(n *node) *int { return &n.maxLength }),
+		"pattern":              compilePattern,
+		"prefixItems":          schemaListKeyword(func(n *node) *[]*node { return &n.prefixItems }),
+		"items":                compileItems,
+		"minItems":             countKeyword(func(n *node) *int { return &n.minItems }),
+		"maxItems":             countKeyword(func(n *node) *int { return &n.maxItems }),
+		"uniqueItems":          compileUniqueItems,
+		"properties":           compileProperties,
+		"patternProperties":    compilePatternProperties,
+		"additionalProperties": compileAdditionalProperties,
+		"required":             compileRequired,
+		"minProperties":        countKeyword(func(n *node) *int { return &n.minProperties }),
+		"maxProperties":        countKeyword(func(n *node) *int { return &n.maxProperties }),
+		"allOf":                schemaListKeyword(func(n *node) *[]*node { return &n.allOf }),
+		"anyOf":                schemaListKeyword(func(n *node) *[]*node { return &n.anyOf }),
+		"oneOf":                schemaListKeyword(func(n *node) *[]*node { return &n.oneOf }),
+		"not":                  schemaKeyword(func(n *node) **node { return &n.not }),
+	}
+}
+
+// compiler compiles the schemas of a document.
+type compiler struct {
+	doc jsonvalue.Value
+
+	// nodes holds the schemas compiled, by JSON pointer, and refs those whose $ref is unresolved.
+	nodes map[string]*node
+	refs  []*node
+}
+
+// compile compiles the schema v found at ptr.
+func (c *compiler) compile(v jsonvalue.Value, ptr string) (*node, error) {
+	if n, ok := c.nodes[ptr]; ok {
+		return n, nil
+	}
+	n := &node{maxLength: unbounded, maxItems: unbounded, maxProperties: unbounded}
+	c.nodes[ptr] = n
+	switch v.Kind {
+	case jsonvalue.Bool:
+		accept := v.Bool
+		n.boolean = &accept
+		return n, nil
+	case jsonvalue.Object:
+	default:
+		return nil, invalid(ptr, "an object or a boolean")
+	}
+	for _, m := range v.Members {
+		if compile, ok := keywords[m.Key]; ok {
+			if err := compile(c, n, m.Value, ptr+"/"+escape(m.Key)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// resolve compiles the targets of the unresolved references, which may add more.
+func (c *compiler) resolve() error {
+	for len(c.refs) > 0 {
+		n := c.refs[len(c.refs)-1]
+		c.refs = c.refs[:len(c.refs)-1]
+		v, ok := lookup(c.doc, n.ref)
+		if !ok {
+			return fmt.Errorf("jsonschema: $ref to missing schema %q", "#"+n.ref)
+		}
+		target, err := c.compile(v, n.ref)
+		if err != nil {
+			return err
+		}
+		n.target = target
+	}
+	return nil
+}
+
+// checkCycles reports schemas applying themselves to the same value through $ref and the
+// in-place applicators, which would make validation recurse forever.
+func (c *compiler) checkCycles() error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[*node]int, len(c.nodes))
+	var visit func(n *node) bool
+	visit = func(n *node) bool {
+		switch state[n] {
+		case visiting:
+			return false
+		case visited:
+			return true
+		default:
+		}
+		state[n] = visiting
+		for _, next := range n.inPlace() {
+			if !visit(next) {
+				return false
+			}
+		}
+		state[n] = visited
+		return true
+	}
+	for ptr, n := range c.nodes {
+		if !visit(n) {
+			return fmt.Errorf("jsonschema: schema %q references itself without consuming the value",
+				"#"+ptr)
+		}
+	}
+	return nil
+}
+
+// inPlace returns the schemas n applies to the value it validates itself.
+func (n *node) inPlace() []*node {
+	var schemas []*node
+	schemas = append(schemas, n.allOf...)
+	schemas = append(schemas, n.anyOf...)
+	schemas = append(schemas, n.oneOf...)
+	for _, schema := range []*node{n.target, n.not} {
+		if schema != nil {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+func compileRef(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.String {
+		return invalid(ptr, "a string")
+	}
+	fragment, ok := strings.CutPrefix(v.Text, "#")
+	if !ok {
+		return fmt.Errorf("jsonschema: unsupported $ref %q: only references within the schema, "+
+			"starting with #, are resolved", v.Text)
+	}
+	ref, err := url.PathUnescape(fragment)
+	if err != nil || (ref != "" && !strings.HasPrefix(ref, "/")) {
+		return fmt.Errorf("jsonschema: unsupported $ref %q: not a JSON pointer", v.Text)
+	}
+	n.ref = ref
+	c.refs = append(c.refs, n)
+	return nil
+}
+
+// typeNames are the names of the types of JSON Schema.
+var typeNames = []string{"null", "boolean", "object", "array", "number", "string", "integer"}
+
+func compileType(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	items := []jsonvalue.Value{v}
+	if v.Kind == jsonvalue.Array {
+		items = v.Items
+	}
+	for _, item := range items {
+		if item.Kind != jsonvalue.String || !contains(typeNames, item.Text) {
+			return invalid(ptr, "a type name or an array of type names")
+		}
+		n.types = append(n.types, item.Text)
+	}
+	return nil
+}
+
+func compileEnum(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.Array {
+		return invalid(ptr, "an array")
+	}
+	n.enum = make([]string, len(v.Items))
+	texts := make([]string, len(v.Items))
+	for i, item := range v.Items {
+		n.enum[i] = canonical(item)
+		texts[i] = string(item.AppendJSON(nil))
+	}
+	n.enumText = strings.Join(texts, ", ")
+	return nil
+}
+
+func compileConst(_ *compiler, n *node, v jsonvalue.Value, _ string) error {
+	constant := canonical(v)
+	n.constant = &constant
+	n.constText = string(v.AppendJSON(nil))
+	return nil
+}
+
+// numberKeyword returns the compiler of a numeric keyword setting the bound of n at field.
+func numberKeyword(field func(n *node) **bound) compileFunc {
+	return func(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+		if v.Kind != jsonvalue.Number {
+			return invalid(ptr, "a number")
+		}
+		*field(n) = &bound{value: toRat(v.Text), text: v.Text}
+		return nil
+	}
+}
+
+func compileMultipleOf(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.Number || toRat(v.Text).Sign() <= 0 {
+		return invalid(ptr, "a number greater than 0")
+	}
+	return numberKeyword(func(n *node) **bound { return &n.multipleOf })(c, n, v, ptr)
+}
+
+// countKeyword returns the compiler of a keyword setting the count of n at field.
+func countKeyword(field func(n *node) *int) compileFunc {
+	return func(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+		count, ok := v.AsInt()
+		if v.Kind != jsonvalue.Number || !ok || count < 0 || count > maxCount {
+			return invalid(ptr, "a non-negative integer")
+		}
+		*field(n) = int(count)
+		return nil
+	}
+}
+
+// maxCount bounds the counts of the count keywords, so that they fit an int on any platform.
+const maxCount = 1<<31 - 1
+
+func compilePattern(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	re, err := compileRegexp(v, ptr)
+	n.pattern = re
+	return err
+}
+
+// compileRegexp compiles the regular expression v found at ptr.
+func compileRegexp(v jsonvalue.Value, ptr string) (*regexp.Regexp, error) {
+	if v.Kind != jsonvalue.String {
+		return nil, invalid(ptr, "a regular expression")
+	}
+	re, err := regexp.Compile(v.Text)
+	if err != nil {
+		return nil, fmt.Errorf("jsonschema: %q: %w", "#"+ptr, err)
+	}
+	return re, nil
+}
+
+func compileItems(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind == jsonvalue.Array {
+		// The array form of earlier drafts is what prefixItems became.
+		return schemaListKeyword(func(n *node) *[]*node { return &n.prefixItems })(c, n, v, ptr)
+	}
+	return schemaKeyword(func(n *node) **node { return &n.items })(c, n, v, ptr)
+}
+
+func compileUniqueItems(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.Bool {
+		return invalid(ptr, "a boolean")
+	}
+	n.uniqueItems = v.Bool
+	return nil
+}
+
+func compileProperties(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.Object {
+		return invalid(ptr, "an object")
+	}
+	n.properties = make(map[string]*node, len(v.Members))
+	for _, m := range v.Members {
+		schema, err := c.compile(m.Value, ptr+"/"+escape(m.Key))
+		if err != nil {
+			return err
+		}
+		n.properties[m.Key] = schema
+	}
+	return nil
+}
+
+func compilePatternProperties(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.Object {
+		return invalid(ptr, "an object")
+	}
+	for _, m := range v.Members {
+		re, err := compileRegexp(jsonvalue.Str(m.Key), ptr)
+		if err != nil {
+			return err
+		}
+		schema, err := c.compile(m.Value, ptr+"/"+escape(m.Key))
+		if err != nil {
+			return err
+		}
+		n.patternProperties = append(n.patternProperties,
+			patternSchema{pattern: re, schema: schema})
+	}
+	return nil
+}
+
+func compileAdditionalProperties(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	return schemaKeyword(func(n *node) **node { return &n.additionalProperties })(c, n, v, ptr)
+}
+
+func compileRequired(_ *compiler, n *node, v jsonvalue.Value, ptr string) error {
+	if v.Kind != jsonvalue.Array {
+		return invalid(ptr, "an array of strings")
+	}
+	for _, item := range v.Items {
+		if item.Kind != jsonvalue.String {
+			return invalid(ptr, "an array of strings")
+		}
+		n.required = append(n.required, item.Text)
+	}
+	return nil
+}
+
+// schemaKeyword returns the compiler of a keyword setting the subschema of n at field.
+func schemaKeyword(field func(n *node) **node) compileFunc {
+	return func(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+		schema, err := c.compile(v, ptr)
+		*field(n) = schema
+		return err
+	}
+}
+
+// schemaListKeyword returns the compiler of a keyword setting the subschemas of n at field.
+func schemaListKeyword(field func(n *node) *[]*node) compileFunc {
+	return func(c *compiler, n *node, v jsonvalue.Value, ptr string) error {
+		if v.Kind != jsonvalue.Array || len(v.Items) == 0 {
+			return invalid(ptr, "a non-empty array of schemas")
+		}
+		schemas := make([]*node, len(v.Items))
+		for i, item := range v.Items {
+			schema, err := c.compile(item, ptr+"/"+strconv.Itoa(i))
+			if err != nil {
+				return err
+			}
+			schemas[i] = schema
+		}
+		*field(n) = schemas
+		return nil
+	}
+}
+
+// invalid returns the error of the keyword at ptr not being what it must be.
+func invalid(ptr, must string) error {
+	return fmt.Errorf("jsonschema: %q must be %s", "#"+ptr, must)
+}
+
+// lookup returns the value at the JSON pointer ptr within doc.
+func lookup(doc jsonvalue.Value, ptr string) (jsonvalue.Value, bool) {
+	if ptr == "" {
+		return doc, true
+	}
+	v := doc
+	for token := range strings.SplitSeq(ptr[1:], "/") {
+		next, ok := child(v, unescape(token))
+		if !ok {
+			return jsonvalue.Value{}, false
+		}
+		v = next
+	}
+	return v, true
+}
+
+// child returns the member or element of v named by the unescaped pointer token.
+func child(v jsonvalue.Value, token string) (jsonvalue.Value, bool) {
+	switch v.Kind {
+	case jsonvalue.Object:
+		for _, m := range v.Members {
+			if m.Key == token {
+				return m.Value, true
+			}
+		}
+	case jsonvalue.Array:
+		i, err := strconv.Atoi(token)
+		if err == nil && i >= 0 && i < len(v.Items) {
+			return v.Items[i], true
+		}
+	default:
+	}
+	return jsonvalue.Value{}, false
+}
+
+// pointerEscaper and pointerUnescaper convert between keys and JSON pointer tokens.
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// escape returns the JSON pointer token of key.
+func escape(key string) string {
+	return pointerEscaper.Replace(key)
+}
+
+// unescape returns the key of a JSON pointer token.
+func unescape(token string) string {
+	return pointerUnescaper.Replace(token)
+}
+
+// contains reports whether names holds name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	t.Run("Invalid schemas", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			schema string
+			want   string
+		}{
+			{"Not JSON", `{`, "jsonschema:"},
+			{"Not a schema", `1`, `"#" must be an object or a boolean`},
+			{"Unknown type", `{"type":"float"}`, `"#/type" must be a type name`},
+			{"Negative count", `{"properties":{"a":{"minLength":-1}}}`,
+				`"#/properties/a/minLength" must be a non-negative integer`},
+			{"Non-integral count", `{"maxItems":1.5}`, `"#/maxItems" must be a non-negative`},
+			{"Zero multiple", `{"multipleOf":0}`, `"#/multipleOf" must be a number greater than 0`},
+			{"Bad pattern", `{"pattern":"("}`, `"#/pattern": error parsing regexp`},
+			{"Bad pattern property", `{"patternProperties":{"(":{}}}`, `"#/patternProperties"`},
+			{"Empty allOf", `{"allOf":[]}`, `"#/allOf" must be a non-empty array of schemas`},
+			{"Bad subschema", `{"items":{"anyOf":[1]}}`, `"#/items/anyOf/0" must be an object`},
+			{"Remote reference", `{"$ref":"other.json#/a"}`, `unsupported $ref "other.json#/a"`},
+			{"Missing reference", `{"$ref":"#/$defs/a"}`, `$ref to missing schema "#/$defs/a"`},
+			{"Reference cycle",
+				`{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"not":{"$ref":"#"}}},"$ref":"#/$defs/a"}`,
+				"references itself"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := Compile([]byte(tt.schema))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.want)
+			})
+		}
+	})
+
+	t.Run("Unreferenced definitions are not compiled", func(t *testing.T) {
+		_, err := Compile([]byte(`{"$defs":{"unused":{"type":"float"}},"type":"object"}`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Array form of items", func(t *testing.T) {
+		s, err := Compile([]byte(`{"items":[{"type":"string"},{"type":"integer"}]}`))
+		require.NoError(t, err)
+		assert.NoError(t, s.Validate([]byte(`["a",1,null]`)))
+		assert.Error(t, s.Validate([]byte(`[1,"a"]`)))
+	})
+
+	t.Run("MustCompile panics", func(t *testing.T) {
+		assert.Panics(t, func() { MustCompile(`{"type":1}`) })
+	})
+}
@@ -0,0 +1,391 @@
+// Package jsonschema validates JSON documents against JSON Schemas, such as the params of the
+// methods of a jsonrpc.Server set with jsonrpc.WithMethodSchema:
+//
+//	schema := jsonschema.MustCompile(`{
+//		"type": "object",
+//		"properties": {"age": {"type": "integer", "minimum": 0}},
+//		"required": ["age"]
+//	}`)
+//	srv := jsonrpc.NewServer(jsonrpc.WithMethodSchema("user.update", schema))
+//
+// The validation keywords of draft 2020-12 are supported: type, enum, const, the numeric, string,
+// array, and object keywords, the allOf, anyOf, oneOf, and not applicators, and $ref to schemas
+// within the document, such as under $defs. Annotations, format, and other keywords are ignored.
+// Numbers are compared exactly, whatever their size, and patterns use the syntax of the regexp
+// package.
+package jsonschema
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// Schema is a compiled JSON Schema, safe for concurrent use.
+type Schema struct {
+	root *node
+}
+
+// Compile compiles the JSON Schema data, reporting keywords with invalid values and references
+// to schemas that are missing or outside the document.
+func Compile(data []byte) (*Schema, error) {
+	doc, err := jsonvalue.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	c := &compiler{doc: doc, nodes: make(map[string]*node)}
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.resolve(); err != nil {
+		return nil, err
+	}
+	if err := c.checkCycles(); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the schema cannot be compiled. It simplifies the
+// initialization of variables holding schemas.
+func MustCompile(schema string) *Schema {
+	s, err := Compile([]byte(schema))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Violation is a way in which a document violates a schema.
+type Violation struct {
+	// Path is the JSON pointer of the offending value within the document, empty for the
+	// document itself.
+	Path string `json:"path"`
+
+	// Keyword is the schema keyword violated.
+	Keyword string `json:"keyword"`
+
+	// Message describes the violation.
+	Message string `json:"message"`
+}
+
+// ValidationError is the error of a document violating a schema.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements error, describing the first violation.
+func (e *ValidationError) Error() string {
+	first := e.Violations[0]
+	msg := fmt.Sprintf("jsonschema: %q %s", "#"+first.Path, first.Message)
+	if more := len(e.Violations) - 1; more > 0 {
+		msg += fmt.Sprintf(" (and %d more violations)", more)
+	}
+	return msg
+}
+
+// Validate validates the JSON document data against s, returning a *ValidationError listing
+// every violation, or an error if data is not valid JSON.
+func (s *Schema) Validate(data []byte) error {
+	v, err := jsonvalue.Parse(data)
+	if err != nil {
+		return fmt.Errorf("jsonschema: %w", err)
+	}
+	var r report
+	s.root.validate(v, "", &r)
+	if len(r) > 0 {
+		return &ValidationError{Violations: r}
+	}
+	return nil
+}
+
+// report collects the violations of a document.
+type report []Violation
+
+// add adds the violation of keyword by the value at path.
+func (r *report) add(path, keyword, format string, args ...any) {
+	*r = append(*r, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate adds the violations of n by the value v at path to r.
+func (n *node) validate(v jsonvalue.Value, path string, r *report) {
+	if n.boolean != nil {
+		if !*n.boolean {
+			r.add(path, "false", "is not allowed")
+		}
+		return
+	}
+	if n.target != nil {
+		n.target.validate(v, path, r)
+	}
+	n.validateValue(v, path, r)
+	switch v.Kind {
+	case jsonvalue.Number:
+		n.validateNumber(v, path, r)
+	case jsonvalue.String:
+		n.validateString(v, path, r)
+	case jsonvalue.Array:
+		n.validateArray(v, path, r)
+	case jsonvalue.Object:
+		n.validateObject(v, path, r)
+	default:
+	}
+	n.validateApplicators(v, path, r)
+}
+
+// valid reports whether v satisfies n.
+func (n *node) valid(v jsonvalue.Value, path string) bool {
+	var r report
+	n.validate(v, path, &r)
+	return len(r) == 0
+}
+
+// validateValue applies the keywords applying to values of any type.
+func (n *node) validateValue(v jsonvalue.Value, path string, r *report) {
+	if len(n.types) > 0 && !n.hasType(v) {
+		r.add(path, "type", "must be of type %s, not %s", strings.Join(n.types, " or "), typeOf(v))
+	}
+	if n.enum != nil || n.constant != nil {
+		value := canonical(v)
+		if n.enum != nil && !contains(n.enum, value) {
+			r.add(path, "enum", "must be one of %s", n.enumText)
+		}
+		if n.constant != nil && value != *n.constant {
+			r.add(path, "const", "must be %s", n.constText)
+		}
+	}
+}
+
+// hasType reports whether v is of one of the types of n.
+func (n *node) hasType(v jsonvalue.Value) bool {
+	kind := typeOf(v)
+	for _, t := range n.types {
+		if t == kind || (t == "integer" && kind == "number" && toRat(v.Text).IsInt()) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the name of the type of v, number for any number.
+func typeOf(v jsonvalue.Value) string {
+	switch v.Kind {
+	case jsonvalue.Bool:
+		return "boolean"
+	case jsonvalue.Number:
+		return "number"
+	case jsonvalue.String:
+		return "string"
+	case jsonvalue.Array:
+		return "array"
+	case jsonvalue.Object:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// validateNumber applies the numeric keywords.
+func (n *node) validateNumber(v jsonvalue.Value, path string, r *report) {
+	x := toRat(v.Text)
+	checks := []struct {
+		keyword string
+		bound   *bound
+		ok      func(cmp int) bool
+		format  string
+	}{
+		{"minimum", n.minimum, func(cmp int) bool { return cmp >= 0 }, "must be at least %s"},
+		{"maximum", n.maximum, func(cmp int) bool { return cmp <= 0 }, "must be at most %s"},
+		{"exclusiveMinimum", n.exclusiveMinimum, func(cmp int) bool { return cmp > 0 },
+			"must be greater than %s"},
+		{"exclusiveMaximum", n.exclusiveMaximum, func(cmp int) bool { return cmp < 0 },
+			"must be less than %s"},
+	}
+	for _, check := range checks {
+		if check.bound != nil && !check.ok(x.Cmp(check.bound.value)) {
+			r.add(path, check.keyword, check.format, check.bound.text)
+		}
+	}
+	if n.multipleOf != nil && !new(big.Rat).Quo(x, n.multipleOf.value).IsInt() {
+		r.add(path, "multipleOf", "must be a multiple of %s", n.multipleOf.text)
+	}
+}
+
+// validateString applies the string keywords.
+func (n *node) validateString(v jsonvalue.Value, path string, r *report) {
+	length := utf8.RuneCountInString(v.Text)
+	if length < n.minLength {
+		r.add(path, "minLength", "must be at least %d characters long", n.minLength)
+	}
+	if n.maxLength != unbounded && length > n.maxLength {
+		r.add(path, "maxLength", "must be at most %d characters long", n.maxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(v.Text) {
+		r.add(path, "pattern", "must match the pattern %q", n.pattern.String())
+	}
+}
+
+// validateArray applies the array keywords.
+func (n *node) validateArray(v jsonvalue.Value, path string, r *report) {
+	for i, item := range v.Items {
+		itemPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case i < len(n.prefixItems):
+			n.prefixItems[i].validate(item, itemPath, r)
+		case n.items == nil:
+		case n.items.rejectsAll():
+			r.add(itemPath, "items", "is beyond the %d items allowed", len(n.prefixItems))
+		default:
+			n.items.validate(item, itemPath, r)
+		}
+	}
+	if len(v.Items) < n.minItems {
+		r.add(path, "minItems", "must have at least %d items", n.minItems)
+	}
+	if n.maxItems != unbounded && len(v.Items) > n.maxItems {
+		r.add(path, "maxItems", "must have at most %d items", n.maxItems)
+	}
+	if n.uniqueItems {
+		seen := make(map[string]int, len(v.Items))
+		for i, item := range v.Items {
+			value := canonical(item)
+			if first, ok := seen[value]; ok {
+				r.add(path+"/"+strconv.Itoa(i), "uniqueItems", "duplicates item %d", first)
+				continue
+			}
+			seen[value] = i
+		}
+	}
+}
+
+// validateObject applies the object keywords.
+func (n *node) validateObject(v jsonvalue.Value, path string, r *report) {
+	present := make(map[string]bool, len(v.Members))
+	for _, m := range v.Members {
+		present[m.Key] = true
+		n.validateMember(m, path+"/"+escape(m.Key), r)
+	}
+	for _, name := range n.required {
+		if !present[name] {
+			r.add(path, "required", "must have the property %q", name)
+		}
+	}
+	if len(present) < n.minProperties {
+		r.add(path, "minProperties", "must have at least %d properties", n.minProperties)
+	}
+	if n.maxProperties != unbounded && len(present) > n.maxProperties {
+		r.add(path, "maxProperties", "must have at most %d properties", n.maxProperties)
+	}
+}
+
+// validateMember applies the schemas of the object keywords to the member m at path.
+func (n *node) validateMember(m jsonvalue.Member, path string, r *report) {
+	matched := false
+	if schema, ok := n.properties[m.Key]; ok {
+		schema.validate(m.Value, path, r)
+		matched = true
+	}
+	for _, p := range n.patternProperties {
+		if p.pattern.MatchString(m.Key) {
+			p.schema.validate(m.Value, path, r)
+			matched = true
+		}
+	}
+	switch {
+	case matched, n.additionalProperties == nil:
+	case n.additionalProperties.rejectsAll():
+		r.add(path, "additionalProperties", "is not an allowed property")
+	default:
+		n.additionalProperties.validate(m.Value, path, r)
+	}
+}
+
+// validateApplicators applies the schemas combined by allOf, anyOf, oneOf, and not.
+func (n *node) validateApplicators(v jsonvalue.Value, path string, r *report) {
+	for _, schema := range n.allOf {
+		schema.validate(v, path, r)
+	}
+	if n.anyOf != nil && countValid(n.anyOf, v, path) == 0 {
+		r.add(path, "anyOf", "must match at least one of the anyOf schemas")
+	}
+	if n.oneOf != nil {
+		if matches := countValid(n.oneOf, v, path); matches != 1 {
+			r.add(path, "oneOf", "must match exactly one of the oneOf schemas, not %d", matches)
+		}
+	}
+	if n.not != nil && n.not.valid(v, path) {
+		r.add(path, "not", "must not match the not schema")
+	}
+}
+
+// countValid returns the number of schemas satisfied by v.
+func countValid(schemas []*node, v jsonvalue.Value, path string) int {
+	count := 0
+	for _, schema := range schemas {
+		if schema.valid(v, path) {
+			count++
+		}
+	}
+	return count
+}
+
+// canonical returns the canonical JSON of v, with sorted object members and normalized numbers,
+// so that equal values, such as 1 and 1.0, have equal canonical forms.
+func canonical(v jsonvalue.Value) string {
+	return string(normalize(v).SortKeys().AppendJSON(nil))
+}
+
+// normalize returns a copy of v whose numbers, at any depth, are in normal form.
+func normalize(v jsonvalue.Value) jsonvalue.Value {
+	normal := v
+	switch v.Kind {
+	case jsonvalue.Number:
+		normal.Text = toRat(v.Text).RatString()
+	case jsonvalue.Array:
+		normal.Items = make([]jsonvalue.Value, len(v.Items))
+		for i, item := range v.Items {
+			normal.Items[i] = normalize(item)
+		}
+	case jsonvalue.Object:
+		normal.Members = make([]jsonvalue.Member, len(v.Members))
+		for i, m := range v.Members {
+			normal.Members[i] = jsonvalue.Member{Key: m.Key, Value: normalize(m.Value)}
+		}
+	default:
+	}
+	return normal
+}
+
+// maxExponent bounds the decimal exponents of the numbers evaluated, so that a literal such as
+// 1e999999999 cannot make the validator compute a huge power of ten. Larger exponents are
+// clamped, which keeps such numbers beyond any bound a schema would realistically set.
+const maxExponent = 10000
+
+// numberText matches the parts of a JSON number literal.
+var numberText = regexp.MustCompile(`^(-?[0-9.]+)[eE]([+-]?[0-9]+)$`)
+
+// toRat returns the value of the JSON number literal text.
+func toRat(text string) *big.Rat {
+	literal := text
+	if parts := numberText.FindStringSubmatch(text); parts != nil {
+		exp, err := strconv.Atoi(parts[2])
+		if err != nil || exp > maxExponent || exp < -maxExponent {
+			exp = maxExponent
+			if strings.HasPrefix(parts[2], "-") {
+				exp = -maxExponent
+			}
+		}
+		literal = parts[1] + "e" + strconv.Itoa(exp)
+	}
+	x, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return new(big.Rat)
+	}
+	return x
+}
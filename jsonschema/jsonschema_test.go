@@ -0,0 +1,143 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// violations returns the violations of doc against schema.
+func violations(t *testing.T, schema, doc string) []Violation {
+	t.Helper()
+	err := MustCompile(schema).Validate([]byte(doc))
+	if err == nil {
+		return nil
+	}
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	return invalid.Violations
+}
+
+// violated returns the keywords violated by doc against schema.
+func violated(t *testing.T, schema, doc string) []string {
+	t.Helper()
+	var keywords []string
+	for _, v := range violations(t, schema, doc) {
+		keywords = append(keywords, v.Keyword)
+	}
+	return keywords
+}
+
+func TestSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		want   []string
+	}{
+		{"Type", `{"type":"string"}`, `1`, []string{"type"}},
+		{"Type list", `{"type":["string","null"]}`, `null`, nil},
+		{"Integer accepts integral numbers", `{"type":"integer"}`, `1.0`, nil},
+		{"Integer rejects fractions", `{"type":"integer"}`, `1.5`, []string{"type"}},
+		{"Enum", `{"enum":["a",1,{"b":[2]}]}`, `{"b":[2.0]}`, nil},
+		{"Enum mismatch", `{"enum":["a",1]}`, `"b"`, []string{"enum"}},
+		{"Const", `{"const":{"a":1,"b":2}}`, `{"b":2,"a":1}`, nil},
+		{"Const mismatch", `{"const":1}`, `2`, []string{"const"}},
+		{"Minimum", `{"minimum":0}`, `-1`, []string{"minimum"}},
+		{"Maximum", `{"maximum":10}`, `10`, nil},
+		{"Exclusive bounds", `{"exclusiveMinimum":0,"exclusiveMaximum":1}`, `1`,
+			[]string{"exclusiveMaximum"}},
+		{"Numbers compare exactly", `{"maximum":9007199254740993}`, `9007199254740994`,
+			[]string{"maximum"}},
+		{"Huge exponents", `{"maximum":1e308}`, `1e999999999`, []string{"maximum"}},
+		{"Multiple of decimals", `{"multipleOf":0.1}`, `0.3`, nil},
+		{"Not a multiple", `{"multipleOf":2}`, `3`, []string{"multipleOf"}},
+		{"String length counts characters", `{"minLength":2,"maxLength":2}`, `"héé"`,
+			[]string{"maxLength"}},
+		{"Pattern", `{"pattern":"^[a-z]+$"}`, `"abc1"`, []string{"pattern"}},
+		{"Numeric keywords ignore other types", `{"minimum":5,"minLength":5}`, `true`, nil},
+		{"Items", `{"items":{"type":"integer"}}`, `[1,"2",3]`, []string{"type"}},
+		{"Prefix items", `{"prefixItems":[{"type":"string"}],"items":false}`, `["a",1]`,
+			[]string{"items"}},
+		{"Item counts", `{"minItems":2,"maxItems":3}`, `[1]`, []string{"minItems"}},
+		{"Unique items", `{"uniqueItems":true}`, `[1,{"a":1},1.0]`, []string{"uniqueItems"}},
+		{"Properties", `{"properties":{"a":{"type":"string"}}}`, `{"a":1,"b":1}`,
+			[]string{"type"}},
+		{"Required", `{"required":["a","b"]}`, `{"a":1}`, []string{"required"}},
+		{"No additional properties",
+			`{"properties":{"a":{}},"patternProperties":{"^x-":{}},"additionalProperties":false}`,
+			`{"a":1,"x-b":2,"c":3}`, []string{"additionalProperties"}},
+		{"Additional property schema", `{"additionalProperties":{"type":"integer"}}`,
+			`{"a":1,"b":"2"}`, []string{"type"}},
+		{"Property counts", `{"minProperties":1,"maxProperties":1}`, `{"a":1,"b":2}`,
+			[]string{"maxProperties"}},
+		{"All of", `{"allOf":[{"minimum":0},{"maximum":1}]}`, `2`, []string{"maximum"}},
+		{"Any of", `{"anyOf":[{"type":"string"},{"minimum":5}]}`, `1`, []string{"anyOf"}},
+		{"One of", `{"oneOf":[{"type":"integer"},{"minimum":0}]}`, `1`, []string{"oneOf"}},
+		{"Not", `{"not":{"type":"null"}}`, `null`, []string{"not"}},
+		{"Boolean schemas", `{"properties":{"a":true,"b":false}}`, `{"a":1,"b":1}`,
+			[]string{"false"}},
+		{"Unknown keywords", `{"format":"email","title":"x"}`, `"not an email"`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, violated(t, tt.schema, tt.doc))
+		})
+	}
+}
+
+func TestSchema_References(t *testing.T) {
+	schema := `{
+		"$defs": {
+			"node": {
+				"type": "object",
+				"properties": {
+					"value": {"type": "integer"},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+				},
+				"required": ["value"]
+			}
+		},
+		"$ref": "#/$defs/node"
+	}`
+
+	t.Run("Recursive", func(t *testing.T) {
+		doc := `{"value":1,"children":[{"value":2},{"value":3,"children":[{"value":"4"}]}]}`
+		got := violations(t, schema, doc)
+		require.Len(t, got, 1)
+		assert.Equal(t, "/children/1/children/0/value", got[0].Path)
+	})
+
+	t.Run("Root and escaped pointers", func(t *testing.T) {
+		tree := `{"properties":{"next":{"$ref":"#"},"a/b":{}},"type":"object"}`
+		assert.Equal(t, []string{"type"}, violated(t, tree, `{"next":{"next":1}}`))
+
+		escaped := `{"$defs":{"a/b":{"type":"null"}},"$ref":"#/$defs/a~1b"}`
+		assert.Equal(t, []string{"type"}, violated(t, escaped, `1`))
+	})
+}
+
+func TestViolation(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}}
+		},
+		"required": ["name", "id"]
+	}`
+	got := violations(t, schema, `{"name":"","tags":["a","c"],"a~b":1}`)
+	assert.Equal(t, []Violation{
+		{Path: "/name", Keyword: "minLength", Message: "must be at least 1 characters long"},
+		{Path: "/tags/1", Keyword: "enum", Message: `must be one of "a", "b"`},
+		{Path: "", Keyword: "required", Message: `must have the property "id"`},
+	}, got)
+
+	err := MustCompile(schema).Validate([]byte(`{"name":1}`))
+	assert.EqualError(t, err,
+		`jsonschema: "#/name" must be of type string, not number (and 1 more violations)`)
+
+	assert.Error(t, MustCompile(`{}`).Validate([]byte(`{`)))
+	assert.NoError(t, MustCompile(`{}`).Validate([]byte(`[1,2]`)))
+}
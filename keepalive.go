@@ -0,0 +1,193 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPeerUnresponsive is the cause of connections closed because the peer stopped answering
+// pings or sending messages, as configured with WithKeepalive or WithConnKeepalive.
+var ErrPeerUnresponsive = errors.New("peer is unresponsive")
+
+// Pinger is implemented by streams able to check that the peer is alive at the transport level,
+// such as WebSocket streams with ping and pong frames. Ping returns once the peer has answered, or
+// with an error if it did not before ctx is done.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// KeepalivePolicy configures the keepalives of the stream connections enabled with WithKeepalive
+// or WithConnKeepalive, which detect peers gone without closing the connection, such as behind a
+// half-open TCP connection, and reap idle ones.
+type KeepalivePolicy struct {
+	// Interval is the time without reading a message after which the peer is pinged. Pings go
+	// through the stream's Ping method if it implements Pinger, and are otherwise calls of Method.
+	// Zero disables pings.
+	Interval time.Duration
+
+	// Timeout is how long a ping may go unanswered before the peer is declared dead. Defaults to
+	// Interval.
+	Timeout time.Duration
+
+	// Method is the method called to ping peers over streams not implementing Pinger, such as
+	// TCP connections. Any response counts as an answer, including an error such as method not
+	// found. When empty, such streams are not pinged.
+	Method string
+
+	// IdleTimeout is the time without reading a message after which the peer is declared dead,
+	// whether it answers transport-level pings or not. Responses to pings made with Method count
+	// as messages. Zero disables it.
+	IdleTimeout time.Duration
+
+	// OnDead, if set, is called with the cause, matching ErrPeerUnresponsive, once the peer is
+	// declared dead and its connection is being closed. It runs on the keepalive goroutine of the
+	// connection, so it must not block.
+	OnDead func(err error)
+}
+
+// timeout returns how long a ping may go unanswered.
+func (p *KeepalivePolicy) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return p.Interval
+}
+
+// WithKeepalive makes a stream client check that its peer is alive according to policy. Once
+// the peer is declared dead, its connection is closed: calls fail with an error matching
+// ErrPeerUnresponsive and subscriptions end, unless WithReconnect is used, in which case the
+// client redials as if reading had failed. It has no effect on clients using a request/response
+// Transport.
+func WithKeepalive(policy KeepalivePolicy) ClientOption {
+	return func(c *Client) {
+		c.keepalive = &policy
+	}
+}
+
+// WithConnKeepalive applies policy to every connection served by ServeStream and every Peer of
+// the server, closing the connections whose peer is declared dead. When set, onDead is called
+// with the connection and the cause, such as to release what the server holds for the peer, in
+// addition to the OnDead function of the policy.
+func WithConnKeepalive(policy KeepalivePolicy, onDead func(conn *Conn, err error)) ServerOption {
+	return func(s *Server) {
+		s.keepalive = &policy
+		s.onDeadConn = onDead
+	}
+}
+
+// connKeepalive returns the keepalive option for a connection of the server, if any.
+func (s *Server) connKeepalive(conn *Conn) []ClientOption {
+	if s.keepalive == nil {
+		return nil
+	}
+	policy := *s.keepalive
+	onDead, onDeadConn := policy.OnDead, s.onDeadConn
+	policy.OnDead = func(err error) {
+		if onDead != nil {
+			onDead(err)
+		}
+		if onDeadConn != nil {
+			onDeadConn(conn, err)
+		}
+	}
+	return []ClientOption{WithKeepalive(policy)}
+}
+
+// startKeepalive starts checking the peer of conn, if the client has a keepalive policy.
+func (c *Client) startKeepalive(conn *streamConn) {
+	if c.keepalive == nil || (c.keepalive.Interval <= 0 && c.keepalive.IdleTimeout <= 0) {
+		return
+	}
+	go c.keepAlive(conn)
+}
+
+// keepAlive checks that the peer of conn is alive until the connection drops or the client shuts
+// down, declaring the peer dead when it stops answering pings or sending messages.
+func (c *Client) keepAlive(conn *streamConn) {
+	policy := c.keepalive
+	var pinged time.Time
+	for {
+		now := time.Now()
+		lastRead := conn.lastRead()
+		if policy.IdleTimeout > 0 && now.Sub(lastRead) >= policy.IdleTimeout {
+			c.declareDead(conn, fmt.Errorf("%w: no message for %s", ErrPeerUnresponsive,
+				policy.IdleTimeout))
+			return
+		}
+
+		// Ping once per interval of silence
+		last := lastRead
+		if pinged.After(last) {
+			last = pinged
+		}
+		if policy.Interval > 0 && now.Sub(last) >= policy.Interval {
+			if err := c.ping(conn); err != nil {
+				c.declareDead(conn, fmt.Errorf("%w: ping failed: %w", ErrPeerUnresponsive, err))
+				return
+			}
+			pinged = time.Now()
+			last = pinged
+		}
+
+		timer := time.NewTimer(policy.nextCheck(lastRead, last))
+		select {
+		case <-timer.C:
+		case <-conn.lost:
+			timer.Stop()
+			return
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// nextCheck returns the wait until the next ping or idle deadline, given the time of the last
+// read and of the last read or ping.
+func (p *KeepalivePolicy) nextCheck(lastRead, last time.Time) time.Duration {
+	next := last.Add(p.Interval)
+	idle := lastRead.Add(p.IdleTimeout)
+	if p.Interval <= 0 || (p.IdleTimeout > 0 && idle.Before(next)) {
+		next = idle
+	}
+	return time.Until(next)
+}
+
+// ping checks that the peer of conn answers within the policy's timeout. Streams not
+// implementing Pinger are pinged with a call of the policy's method, if any.
+func (c *Client) ping(conn *streamConn) error {
+	ctx, cancel := context.WithTimeout(c.ctx, c.keepalive.timeout())
+	defer cancel()
+
+	if pinger, ok := conn.stream.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	if c.keepalive.Method == "" {
+		return nil
+	}
+	_, err := c.send(ctx, NewRequestWithID(c.keepalive.Method, nil, c.newID()))
+	return err
+}
+
+// declareDead closes the connection of a dead peer, failing its calls with cause, and reports it
+// to the policy.
+func (c *Client) declareDead(conn *streamConn, cause error) {
+	select {
+	case <-conn.lost:
+		return
+	case <-c.ctx.Done():
+		return
+	default:
+	}
+	conn.dead.Store(&cause)
+	if c.reconnectPolicy != nil {
+		_ = conn.stream.Close()
+	} else {
+		_ = c.closeWith(fmt.Errorf("%w: %w", ErrClientClosed, cause))
+	}
+	if c.keepalive.OnDead != nil {
+		c.keepalive.OnDead(cause)
+	}
+}
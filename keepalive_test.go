@@ -0,0 +1,197 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pingStream is a pipeStream implementing Pinger, failing pings with err.
+type pingStream struct {
+	*pipeStream
+	pings atomic.Int32
+	err   atomic.Pointer[error]
+}
+
+func (s *pingStream) Ping(context.Context) error {
+	s.pings.Add(1)
+	if err := s.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// deadLog collects the causes reported by OnDead.
+type deadLog chan error
+
+// await returns the next cause reported.
+func (l deadLog) await(t *testing.T) error {
+	t.Helper()
+	select {
+	case err := <-l:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("peer not declared dead")
+		return nil
+	}
+}
+
+func TestClient_Keepalive(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Method pings keep a responsive peer alive", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = NewServer().ServeStream(ctx, serverEnd) }()
+		dead := make(deadLog, 1)
+		client := NewStreamClient(clientEnd, WithKeepalive(KeepalivePolicy{
+			Interval: 5 * time.Millisecond,
+			Timeout:  time.Second,
+			Method:   "ping",
+			OnDead:   func(err error) { dead <- err },
+		}))
+		defer client.Close()
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, dead)
+		assert.ErrorIs(t, client.Call(ctx, "any", nil, nil), ErrMethodNotFound)
+	})
+
+	t.Run("Unanswered pings declare the peer dead", func(t *testing.T) {
+		clientEnd, _ := newStreamPair()
+		dead := make(deadLog, 1)
+		client := NewStreamClient(clientEnd, WithKeepalive(KeepalivePolicy{
+			Interval: 5 * time.Millisecond,
+			Timeout:  10 * time.Millisecond,
+			Method:   "ping",
+			OnDead:   func(err error) { dead <- err },
+		}))
+		defer client.Close()
+
+		require.ErrorIs(t, dead.await(t), ErrPeerUnresponsive)
+		<-client.Done()
+		err := client.Call(ctx, "any", nil, nil)
+		assert.ErrorIs(t, err, ErrClientClosed)
+		assert.ErrorIs(t, err, ErrPeerUnresponsive)
+	})
+
+	t.Run("Streams implementing Pinger are pinged", func(t *testing.T) {
+		clientEnd, _ := newStreamPair()
+		stream := &pingStream{pipeStream: clientEnd}
+		dead := make(deadLog, 1)
+		client := NewStreamClient(stream, WithKeepalive(KeepalivePolicy{
+			Interval: 5 * time.Millisecond,
+			OnDead:   func(err error) { dead <- err },
+		}))
+		defer client.Close()
+
+		require.Eventually(t, func() bool {
+			return stream.pings.Load() >= 2
+		}, time.Second, time.Millisecond)
+		assert.Empty(t, dead)
+
+		failure := error(assert.AnError)
+		stream.err.Store(&failure)
+		err := dead.await(t)
+		assert.ErrorIs(t, err, ErrPeerUnresponsive)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("Messages defer pings", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		stream := &pingStream{pipeStream: clientEnd}
+		client := NewStreamClient(stream, WithKeepalive(KeepalivePolicy{
+			Interval: 50 * time.Millisecond,
+		}))
+		defer client.Close()
+
+		for range 10 {
+			_ = serverEnd.WriteMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"tick"}`))
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Zero(t, stream.pings.Load())
+	})
+
+	t.Run("Idle timeout reconnects", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		events := make(chan ReconnectEvent, 16)
+		stream, err := p.dial(ctx)
+		require.NoError(t, err)
+		client := NewStreamClient(stream,
+			WithReconnect(p.dial, ReconnectPolicy{
+				InitialBackoff: time.Millisecond,
+				OnReconnect:    func(event ReconnectEvent) { events <- event },
+			}),
+			WithKeepalive(KeepalivePolicy{IdleTimeout: 20 * time.Millisecond}))
+		defer client.Close()
+
+		event := nextEvent(t, events)
+		require.ErrorIs(t, event.Cause, ErrPeerUnresponsive)
+		require.NoError(t, event.Err)
+		select {
+		case <-client.Done():
+			t.Fatal("client shut down")
+		default:
+		}
+	})
+}
+
+func TestServer_ConnKeepalive(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reaps idle connections", func(t *testing.T) {
+		reaped := make(chan *Conn, 1)
+		causes := make(deadLog, 1)
+		srv := NewServer(WithConnKeepalive(KeepalivePolicy{
+			IdleTimeout: 20 * time.Millisecond,
+			OnDead:      func(err error) { causes <- err },
+		}, func(conn *Conn, _ error) { reaped <- conn }))
+
+		_, serverEnd := newStreamPair()
+		served := make(chan error, 1)
+		go func() { served <- srv.ServeStream(ctx, serverEnd) }()
+
+		require.ErrorIs(t, causes.await(t), ErrPeerUnresponsive)
+		select {
+		case conn := <-reaped:
+			<-conn.Done()
+		case <-time.After(time.Second):
+			t.Fatal("connection not reported")
+		}
+		assert.ErrorIs(t, awaitErr(t, served), ErrPeerUnresponsive)
+		assert.Empty(t, srv.Conns())
+	})
+
+	t.Run("Keeps active connections", func(t *testing.T) {
+		policy := KeepalivePolicy{IdleTimeout: 100 * time.Millisecond}
+		srv := NewServer(WithConnKeepalive(policy, nil))
+		echo := func(_ context.Context, req *Request) (any, error) { return req.Params, nil }
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		for range 8 {
+			require.NoError(t, client.Call(ctx, "echo", nil, nil))
+			time.Sleep(20 * time.Millisecond)
+		}
+		assert.Len(t, srv.Conns(), 1)
+	})
+}
+
+func TestKeepalivePolicy_nextCheck(t *testing.T) {
+	now := time.Now()
+	policy := KeepalivePolicy{Interval: time.Minute, IdleTimeout: time.Hour}
+	assert.InDelta(t, time.Minute, policy.nextCheck(now, now), float64(time.Second))
+
+	policy = KeepalivePolicy{Interval: time.Hour, IdleTimeout: time.Minute}
+	assert.InDelta(t, time.Minute, policy.nextCheck(now, now), float64(time.Second))
+
+	policy = KeepalivePolicy{IdleTimeout: time.Minute}
+	assert.InDelta(t, time.Minute, policy.nextCheck(now, now), float64(time.Second))
+}
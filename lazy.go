@@ -0,0 +1,94 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// WithLazyParams makes the server decode only the envelope of incoming requests, its version, ID,
+// and method, leaving their params as a json.RawMessage view of the message. Params are then
+// decoded once, straight into their destination, by UnmarshalParams, BindParams, DecodeParams,
+// and service methods, or forwarded as they are by handlers that only route messages. Handlers
+// and middleware reading Params directly must expect a json.RawMessage rather than []any or
+// map[string]any.
+func WithLazyParams() ServerOption {
+	return func(s *Server) {
+		s.lazyParams = true
+	}
+}
+
+// DecodeRequestLazy parses a JSON-RPC request like DecodeRequest, but without decoding its
+// params, which it leaves in Params as a json.RawMessage view of data. The params are only
+// checked to be an array or an object. data must not be modified while the request is in use.
+//
+// Responses need no lazy counterpart: DecodeResponse always keeps the result raw until
+// UnmarshalResult is called.
+func DecodeRequestLazy(data []byte) (*Request, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, emptyMessage()
+	}
+
+	var aux struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+	}
+	if err := getCodec().Unmarshal(data, &aux); err != nil {
+		return nil, err
+	}
+	if aux.JSONRPC != jsonRPCVersion {
+		return nil, invalidField(fieldJSONRPC, errVersion)
+	}
+	if aux.Method == "" {
+		return nil, invalidField(fieldMethod, errMethodMissing)
+	}
+
+	id, err := unmarshalRequestID(aux.ID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := rawRequestParams(aux.Params)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{JSONRPC: aux.JSONRPC, ID: id, Method: aux.Method, Params: params}, nil
+}
+
+// rawRequestParams validates raw params like unmarshalRequestParams, without decoding them.
+func rawRequestParams(raw json.RawMessage) (any, error) {
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case len(trimmed) == 0, string(trimmed) == "null", string(trimmed) == `""`:
+		return nil, nil
+	case trimmed[0] == '[' || trimmed[0] == '{':
+		return json.RawMessage(trimmed), nil
+	default:
+		return nil, invalidField(fieldParams, errParamsType)
+	}
+}
+
+// expandRawParams turns raw array params into a slice of their raw elements, so that positional
+// params bind to their destinations without being decoded generically first. Other params are
+// returned as they are.
+func expandRawParams(params any) (any, error) {
+	raw, ok := params.(json.RawMessage)
+	if !ok {
+		return params, nil
+	}
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return params, nil
+	}
+
+	var elems []json.RawMessage
+	if err := getCodec().Unmarshal(trimmed, &elems); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	values := make([]any, len(elems))
+	for i, elem := range elems {
+		values[i] = elem
+	}
+	return values, nil
+}
@@ -0,0 +1,130 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRequestLazy(t *testing.T) {
+	t.Run("Keeps params raw", func(t *testing.T) {
+		data := []byte(`{"jsonrpc":"2.0","id":7,"method":"sum","params": [1, {"a":2}] }`)
+		req, err := DecodeRequestLazy(data)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), req.ID)
+		assert.Equal(t, "sum", req.Method)
+		assert.Equal(t, json.RawMessage(`[1, {"a":2}]`), req.Params)
+
+		var params []any
+		require.NoError(t, req.UnmarshalParams(&params))
+		assert.Equal(t, []any{float64(1), map[string]any{"a": float64(2)}}, params)
+	})
+
+	t.Run("Absent and null params", func(t *testing.T) {
+		for _, data := range []string{
+			`{"jsonrpc":"2.0","method":"a"}`,
+			`{"jsonrpc":"2.0","method":"a","params":null}`,
+			`{"jsonrpc":"2.0","method":"a","params":""}`,
+		} {
+			req, err := DecodeRequestLazy([]byte(data))
+			require.NoError(t, err, data)
+			assert.Nil(t, req.Params, data)
+			assert.True(t, req.IsNotification())
+		}
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		for _, data := range []string{
+			``,
+			`{"jsonrpc":"1.0","method":"a"}`,
+			`{"jsonrpc":"2.0"}`,
+			`{"jsonrpc":"2.0","method":"a","params":42}`,
+			`{"jsonrpc":"2.0","method":"a","id":true}`,
+			`{"jsonrpc":"2.0","method":"a","params":[1,}`,
+		} {
+			_, err := DecodeRequestLazy([]byte(data))
+			require.Error(t, err, data)
+		}
+	})
+
+	t.Run("Forwards params as they are", func(t *testing.T) {
+		data := `{"jsonrpc":"2.0","id":1,"method":"route","params":{"big":[1,2,3]}}`
+		req, err := DecodeRequestLazy([]byte(data))
+		require.NoError(t, err)
+		encoded, err := req.AppendJSON(nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, data, string(encoded))
+	})
+}
+
+func TestRequest_BindParamsRaw(t *testing.T) {
+	type args struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+
+	t.Run("Positional", func(t *testing.T) {
+		req := &Request{Params: json.RawMessage(`[1, "x"]`)}
+		var got args
+		require.NoError(t, req.BindParams(&got))
+		assert.Equal(t, args{A: 1, B: "x"}, got)
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		req := &Request{Params: json.RawMessage(`{"a":2,"b":"y"}`)}
+		got, err := DecodeParams[args](req)
+		require.NoError(t, err)
+		assert.Equal(t, args{A: 2, B: "y"}, got)
+	})
+
+	t.Run("Too many positional params", func(t *testing.T) {
+		req := &Request{Params: json.RawMessage(`[1, "x", true]`)}
+		var got args
+		require.ErrorContains(t, req.BindParams(&got), "too many params")
+	})
+}
+
+func TestServer_LazyParams(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(WithLazyParams())
+	require.NoError(t, srv.RegisterService("user", &userService{}))
+
+	var seen any
+	echo := HandlerFunc(func(_ context.Context, req *Request) (any, error) {
+		seen = req.Params
+		return req.Params, nil
+	})
+	require.NoError(t, srv.Register("echo", echo))
+
+	t.Run("Handlers see raw params", func(t *testing.T) {
+		msg := `{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`
+		reply := srv.HandleMessage(ctx, []byte(msg))
+		assert.IsType(t, json.RawMessage{}, seen)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":[1]}`, string(reply))
+	})
+
+	t.Run("Service methods bind raw params", func(t *testing.T) {
+		msg := `[{"jsonrpc":"2.0","id":1,"method":"user.add","params":[2,3]},` +
+			`{"jsonrpc":"2.0","id":2,"method":"user.get","params":{"id":4,"name":"a"}}]`
+		resps, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+
+		var sum int
+		require.NoError(t, resps[0].UnmarshalResult(&sum))
+		assert.Equal(t, 5, sum)
+		var user map[string]any
+		require.NoError(t, resps[1].UnmarshalResult(&user))
+		assert.Equal(t, "a", user["name"])
+	})
+
+	t.Run("Invalid params are rejected", func(t *testing.T) {
+		msg := `{"jsonrpc":"2.0","id":1,"method":"echo","params":"nope"}`
+		resp, err := DecodeResponse(srv.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		assert.Equal(t, InvalidRequest, resp.Err().Code)
+	})
+}
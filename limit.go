@@ -0,0 +1,247 @@
+package jsonrpc
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// ServerBusy is the error code of responses to requests turned away by a concurrency limit,
+// within the range reserved for implementation-defined server errors.
+const ServerBusy = -32011
+
+// msgServerBusy is the message of ServerBusy errors.
+const msgServerBusy = "Server busy"
+
+// ErrServerBusy is the error sent for requests turned away by a concurrency limit.
+var ErrServerBusy = &Error{Code: ServerBusy, Message: msgServerBusy}
+
+// OverloadPolicy selects what happens to requests arriving while a concurrency limit is reached.
+type OverloadPolicy int
+
+const (
+	// OverloadQueue makes requests wait for a free slot, in order of priority and then of
+	// arrival, until their context is done. Requests arriving while the queue is full are
+	// rejected, unless of higher priority than a queued request, which is rejected instead.
+	OverloadQueue OverloadPolicy = iota
+
+	// OverloadReject answers requests with ErrServerBusy right away.
+	OverloadReject
+
+	// OverloadShed queues requests like OverloadQueue, but a request arriving while the queue is
+	// full takes the place of the oldest queued request of the lowest priority, unless all are of
+	// higher priority, which is rejected instead. This favors fresh requests, whose callers are
+	// the most likely to still be waiting.
+	OverloadShed
+)
+
+// ConcurrencyLimit bounds the number of handlers executing at once.
+type ConcurrencyLimit struct {
+	// MaxConcurrent is the maximum number of handlers executing at once. Zero disables the limit.
+	MaxConcurrent int
+
+	// MaxQueue caps the requests waiting for a slot under OverloadQueue and OverloadShed. Zero
+	// means no cap, in which case OverloadShed behaves as OverloadQueue.
+	MaxQueue int
+
+	// Overload is the policy for requests arriving while MaxConcurrent handlers are executing.
+	Overload OverloadPolicy
+}
+
+// WithConcurrencyLimit bounds the handlers executing at once across all methods. Requests turned
+// away are answered with ErrServerBusy. Batch members are dispatched by at most MaxConcurrent
+// workers, so that a large batch does not spawn a goroutine per member.
+//
+// A slot is held until the handler returns, even if the request timed out meanwhile, and time
+// spent queued counts towards the request timeout.
+func WithConcurrencyLimit(limit ConcurrencyLimit) ServerOption {
+	return func(s *Server) {
+		s.limiter = newLimiter(limit)
+	}
+}
+
+// WithMethodConcurrencyLimit bounds the handlers of method executing at once, in addition to the
+// limit set with WithConcurrencyLimit.
+func WithMethodConcurrencyLimit(method string, limit ConcurrencyLimit) ServerOption {
+	return func(s *Server) {
+		if s.methodLimiters == nil {
+			s.methodLimiters = make(map[string]*limiter)
+		}
+		s.methodLimiters[method] = newLimiter(limit)
+	}
+}
+
+// WithProxyConcurrencyLimit bounds the forwards a proxy has in flight at once, a batch headed for
+// one upstream counting as one, so that under load the forwards of higher priority go first; see
+// WithProxyMethodPriority. Calls turned away are answered with ErrServerBusy.
+func WithProxyConcurrencyLimit(limit ConcurrencyLimit) ProxyOption {
+	return func(p *Proxy) {
+		p.limiter = newLimiter(limit)
+	}
+}
+
+// invokeLimited calls invoke once the request has passed the rate limits and has a slot of its
+// worker pool, or under the global concurrency limit, and under its method concurrency limit.
+// Health reports served by WithHealth and admin methods served by WithAdmin are exempt, so that
+// probes and operators get an answer from a saturated server.
+func (s *Server) invokeLimited(ctx context.Context, req *Request) (any, error) {
+	if (s.health && req.Method == HealthMethod) || s.isAdminMethod(req.Method) {
+		return s.invoke(ctx, req)
+	}
+	if err := s.checkRateLimits(ctx, req); err != nil {
+		return nil, err
+	}
+	priority := priorityOf(ctx, s.methodPriorities, req)
+	if pool, size := s.workerPool(req); pool != nil {
+		if err := pool.acquire(ctx, priority, size); err != nil {
+			return nil, err
+		}
+		defer pool.release(size)
+	} else if s.limiter != nil {
+		if err := s.limiter.acquire(ctx, priority); err != nil {
+			return nil, err
+		}
+		defer s.limiter.release()
+	}
+	if l := s.methodLimiters[req.Method]; l != nil {
+		if err := l.acquire(ctx, priority); err != nil {
+			return nil, err
+		}
+		defer l.release()
+	}
+	return s.invoke(ctx, req)
+}
+
+// batchWorkers returns the number of goroutines dispatching a batch of n members.
+func (s *Server) batchWorkers(n int) int {
+	if s.limiter == nil {
+		return n
+	}
+	return min(n, s.limiter.limit.MaxConcurrent)
+}
+
+// limiter enforces a ConcurrencyLimit.
+type limiter struct {
+	limit ConcurrencyLimit
+
+	mu     sync.Mutex
+	active int
+
+	// waiters is ordered by priority, highest first, and by arrival within a priority
+	waiters []*waiter
+}
+
+// waiter is a request queued for a slot.
+type waiter struct {
+	ch       chan error
+	priority Priority
+}
+
+// newLimiter creates a limiter, or returns nil if limit is disabled.
+func newLimiter(limit ConcurrencyLimit) *limiter {
+	if limit.MaxConcurrent <= 0 {
+		return nil
+	}
+	return &limiter{limit: limit}
+}
+
+// load returns the number of slots taken and of requests queued.
+func (l *limiter) load() (active, queued int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active, len(l.waiters)
+}
+
+// acquire takes a slot, waiting for one as the overload policy allows. A nil error means the
+// slot must be given back with release.
+func (l *limiter) acquire(ctx context.Context, priority Priority) error {
+	l.mu.Lock()
+	if l.active < l.limit.MaxConcurrent {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	w, err := l.enqueue(priority)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-w.ch:
+		return err
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	if i := slices.Index(l.waiters, w); i >= 0 {
+		l.waiters = slices.Delete(l.waiters, i, i+1)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+	l.mu.Unlock()
+
+	// A slot or rejection was handed over meanwhile
+	if err := <-w.ch; err == nil {
+		l.release()
+	}
+	return ctx.Err()
+}
+
+// enqueue adds a waiter of the given priority to the queue, applying the overload policy. When
+// the queue is full, a waiter of lower priority is rejected to make room, the oldest of the
+// lowest priority under OverloadShed, and the newest otherwise; a waiter of equal priority only
+// makes room under OverloadShed. It must be called with mu held.
+func (l *limiter) enqueue(priority Priority) (*waiter, error) {
+	if l.limit.Overload == OverloadReject {
+		return nil, ErrServerBusy
+	}
+	if l.limit.MaxQueue > 0 && len(l.waiters) >= l.limit.MaxQueue {
+		victim, ok := l.victim(priority)
+		if !ok {
+			return nil, ErrServerBusy
+		}
+		l.waiters[victim].ch <- ErrServerBusy
+		l.waiters = slices.Delete(l.waiters, victim, victim+1)
+	}
+
+	w := &waiter{ch: make(chan error, 1), priority: priority}
+	i := len(l.waiters)
+	for i > 0 && l.waiters[i-1].priority < priority {
+		i--
+	}
+	l.waiters = slices.Insert(l.waiters, i, w)
+	return w, nil
+}
+
+// victim returns the index of the queued waiter to reject to make room for a waiter of the given
+// priority, and false if the new waiter is to be rejected instead. It must be called with mu
+// held.
+func (l *limiter) victim(priority Priority) (int, bool) {
+	last := len(l.waiters) - 1
+	lowest := l.waiters[last].priority
+	if l.limit.Overload != OverloadShed {
+		return last, lowest < priority
+	}
+	if lowest > priority {
+		return 0, false
+	}
+	oldest := last
+	for oldest > 0 && l.waiters[oldest-1].priority == lowest {
+		oldest--
+	}
+	return oldest, true
+}
+
+// release gives a slot back, handing it over to the first waiter, if any.
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.waiters) == 0 {
+		l.active--
+		return
+	}
+	l.waiters[0].ch <- nil
+	l.waiters = l.waiters[1:]
+}
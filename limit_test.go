@@ -0,0 +1,279 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gate is a handler blocking until released, tracking how many calls run at once.
+type gate struct {
+	started chan struct{}
+	release chan struct{}
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+// newGate creates a gate closed until release is called.
+func newGate() *gate {
+	return &gate{started: make(chan struct{}, 64), release: make(chan struct{})}
+}
+
+func (g *gate) ServeRPC(ctx context.Context, _ *Request) (any, error) {
+	n := g.running.Add(1)
+	defer g.running.Add(-1)
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	g.started <- struct{}{}
+
+	select {
+	case <-g.release:
+		return "done", nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// open releases all blocked and future calls.
+func (g *gate) open() {
+	close(g.release)
+}
+
+// waitStarted waits for n calls to have started.
+func (g *gate) waitStarted(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-g.started:
+		case <-time.After(time.Second):
+			t.Fatal("call not started")
+		}
+	}
+}
+
+// handleAsync dispatches a request in the background, returning the channel its response is
+// sent on.
+func handleAsync(ctx context.Context, srv *Server, method string, id int) <-chan *Response {
+	out := make(chan *Response, 1)
+	go func() { out <- srv.HandleRequest(ctx, NewRequestWithID(method, nil, id)) }()
+	return out
+}
+
+// awaitResponse returns the response sent on ch.
+func awaitResponse(t *testing.T, ch <-chan *Response) *Response {
+	t.Helper()
+	select {
+	case resp := <-ch:
+		return resp
+	case <-time.After(time.Second):
+		t.Fatal("response not received")
+		return nil
+	}
+}
+
+func TestServer_ConcurrencyLimit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reject answers with ErrServerBusy", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithConcurrencyLimit(ConcurrencyLimit{
+			MaxConcurrent: 1,
+			Overload:      OverloadReject,
+		}))
+		require.NoError(t, srv.Register("block", g))
+
+		first := handleAsync(ctx, srv, "block", 1)
+		g.waitStarted(t, 1)
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("block", nil, 2))
+		require.ErrorIs(t, resp.Err(), ErrServerBusy)
+		assert.Equal(t, ServerBusy, resp.Err().Code)
+
+		g.open()
+		assert.Nil(t, awaitResponse(t, first).Err())
+	})
+
+	t.Run("Queue waits for a free slot", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 2}))
+		require.NoError(t, srv.Register("block", g))
+
+		var resps []<-chan *Response
+		for i := range 5 {
+			resps = append(resps, handleAsync(ctx, srv, "block", i))
+		}
+		g.waitStarted(t, 2)
+		assert.Equal(t, int32(2), g.running.Load())
+
+		g.open()
+		for _, ch := range resps {
+			assert.Nil(t, awaitResponse(t, ch).Err())
+		}
+		assert.Equal(t, int32(2), g.peak.Load())
+	})
+
+	t.Run("Full queue rejects", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1, MaxQueue: 1}))
+		require.NoError(t, srv.Register("block", g))
+
+		first := handleAsync(ctx, srv, "block", 1)
+		g.waitStarted(t, 1)
+		queued := handleAsync(ctx, srv, "block", 2)
+		require.Eventually(t, func() bool {
+			return queueLen(srv.limiter) == 1
+		}, time.Second, time.Millisecond)
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("block", nil, 3))
+		require.ErrorIs(t, resp.Err(), ErrServerBusy)
+
+		g.open()
+		assert.Nil(t, awaitResponse(t, first).Err())
+		assert.Nil(t, awaitResponse(t, queued).Err())
+	})
+
+	t.Run("Shed rejects the oldest queued request", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithConcurrencyLimit(ConcurrencyLimit{
+			MaxConcurrent: 1,
+			MaxQueue:      1,
+			Overload:      OverloadShed,
+		}))
+		require.NoError(t, srv.Register("block", g))
+
+		first := handleAsync(ctx, srv, "block", 1)
+		g.waitStarted(t, 1)
+		oldest := handleAsync(ctx, srv, "block", 2)
+		require.Eventually(t, func() bool {
+			return queueLen(srv.limiter) == 1
+		}, time.Second, time.Millisecond)
+		newest := handleAsync(ctx, srv, "block", 3)
+
+		require.ErrorIs(t, awaitResponse(t, oldest).Err(), ErrServerBusy)
+		g.open()
+		assert.Nil(t, awaitResponse(t, first).Err())
+		assert.Nil(t, awaitResponse(t, newest).Err())
+	})
+
+	t.Run("Queued request gives up with its context", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1}))
+		require.NoError(t, srv.Register("block", g))
+
+		first := handleAsync(ctx, srv, "block", 1)
+		g.waitStarted(t, 1)
+
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		resp := srv.HandleRequest(short, NewRequestWithID("block", nil, 2))
+		require.Error(t, resp.Err())
+		assert.Equal(t, 0, queueLen(srv.limiter))
+
+		g.open()
+		assert.Nil(t, awaitResponse(t, first).Err())
+		resp = srv.HandleRequest(ctx, NewRequestWithID("block", nil, 3))
+		assert.Nil(t, resp.Err(), "the slot was released")
+	})
+
+	t.Run("Method limit applies to its method only", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithMethodConcurrencyLimit("block", ConcurrencyLimit{
+			MaxConcurrent: 1,
+			Overload:      OverloadReject,
+		}))
+		require.NoError(t, srv.Register("block", g))
+		require.NoError(t, srv.Register("other", g))
+
+		first := handleAsync(ctx, srv, "block", 1)
+		g.waitStarted(t, 1)
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("block", nil, 2))
+		require.ErrorIs(t, resp.Err(), ErrServerBusy)
+		other := handleAsync(ctx, srv, "other", 3)
+		g.waitStarted(t, 1)
+
+		g.open()
+		assert.Nil(t, awaitResponse(t, first).Err())
+		assert.Nil(t, awaitResponse(t, other).Err())
+	})
+
+	t.Run("Timed out handler keeps its slot until it returns", func(t *testing.T) {
+		release := make(chan struct{})
+		stuck := HandlerFunc(func(context.Context, *Request) (any, error) {
+			<-release
+			return nil, nil
+		})
+		srv := NewServer(
+			WithRequestTimeout(10*time.Millisecond),
+			WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1, Overload: OverloadReject}),
+		)
+		require.NoError(t, srv.Register("stuck", stuck))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("stuck", nil, 1))
+		require.ErrorIs(t, resp.Err(), ErrRequestTimeout)
+		resp = srv.HandleRequest(ctx, NewRequestWithID("stuck", nil, 2))
+		require.ErrorIs(t, resp.Err(), ErrServerBusy)
+
+		close(release)
+		require.Eventually(t, func() bool {
+			return activeLen(srv.limiter) == 0
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func TestServer_ConcurrencyLimitBatch(t *testing.T) {
+	g := newGate()
+	srv := NewServer(WithConcurrencyLimit(ConcurrencyLimit{
+		MaxConcurrent: 3,
+		Overload:      OverloadReject,
+	}))
+	require.NoError(t, srv.Register("block", g))
+
+	members := make([]string, 20)
+	for i := range members {
+		members[i] = fmt.Sprintf(`{"jsonrpc":"2.0","method":"block","id":%d}`, i)
+	}
+	msg := "[" + strings.Join(members, ",") + "]"
+
+	var wg sync.WaitGroup
+	var reply []byte
+	wg.Go(func() {
+		reply = srv.HandleMessage(context.Background(), []byte(msg))
+	})
+	g.waitStarted(t, 3)
+	g.open()
+	wg.Wait()
+
+	resps, err := DecodeBatchResponse(reply)
+	require.NoError(t, err)
+	require.Len(t, resps, len(members))
+	for _, resp := range resps {
+		assert.Nil(t, resp.Err(), "batch members wait for a worker rather than being rejected")
+	}
+	assert.LessOrEqual(t, g.peak.Load(), int32(3))
+}
+
+// queueLen returns the number of requests waiting on l.
+func queueLen(l *limiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiters)
+}
+
+// activeLen returns the number of slots taken on l.
+func activeLen(l *limiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active
+}
@@ -0,0 +1,236 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// redacted replaces the values scrubbed by RedactKeys.
+const redacted = "[REDACTED]"
+
+// LogKind tells what kind of call a log entry describes.
+type LogKind string
+
+// Kinds of logged calls.
+const (
+	LogRequest      LogKind = "request"
+	LogNotification LogKind = "notification"
+)
+
+// LogEntry describes a completed call, with its request and outcome.
+type LogEntry struct {
+	Kind   LogKind
+	Method string
+
+	// ID is the request ID, nil for notifications.
+	ID any
+
+	// Params holds the params of the call, as returned by the redactor if one is set.
+	Params any
+
+	Duration time.Duration
+
+	// Code is the JSON-RPC error code of the call, zero if it succeeded or failed without a
+	// response.
+	Code int
+
+	// Err is the error of the call, as reported to observers, nil if it succeeded.
+	Err error
+}
+
+// Logger receives an entry for every call a Server handles or a Client sends. It is called
+// synchronously and concurrently, so it should return quickly and must be safe for concurrent
+// use.
+type Logger interface {
+	LogCall(ctx context.Context, entry LogEntry)
+}
+
+// Redactor returns the params of a call of method as they should be logged, for instance with
+// secrets scrubbed. It must not modify params, which the call still uses.
+type Redactor func(method string, params any) any
+
+// LogOption configures call logging.
+type LogOption func(*logObserver)
+
+// WithLogRedactor passes the params of every logged call through redact. Returning nil leaves the
+// params out of the entry.
+func WithLogRedactor(redact Redactor) LogOption {
+	return func(o *logObserver) {
+		o.redact = redact
+	}
+}
+
+// WithLogger makes the server log every request and notification it handles to logger. It builds
+// on WithObserver, so it also logs requests turned away by timeouts and concurrency limits.
+func WithLogger(logger Logger, opts ...LogOption) ServerOption {
+	return WithObserver(newLogObserver(logger, opts))
+}
+
+// WithClientLogger makes the client log every Call and Notify it sends to logger. Batch members
+// are not logged. It builds on WithClientObserver.
+func WithClientLogger(logger Logger, opts ...LogOption) ClientOption {
+	return WithClientObserver(newLogObserver(logger, opts))
+}
+
+// RedactKeys returns a Redactor replacing the values of object members named after one of keys,
+// compared case-insensitively and at any depth, with "[REDACTED]". Params that are not decoded
+// JSON, such as structs passed to a client, are first converted to their JSON form.
+func RedactKeys(keys ...string) Redactor {
+	return func(_ string, params any) any {
+		switch params.(type) {
+		case nil, map[string]any, []any:
+		default:
+			data, err := getCodec().Marshal(params)
+			if err != nil {
+				return nil
+			}
+			params = nil
+			if err := getCodec().Unmarshal(data, &params); err != nil {
+				return nil
+			}
+		}
+		return redactValue(params, keys)
+	}
+}
+
+// redactValue returns a copy of v with the members named after keys replaced.
+func redactValue(v any, keys []string) any {
+	switch value := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for name, member := range value {
+			if containsFold(keys, name) {
+				out[name] = redacted
+				continue
+			}
+			out[name] = redactValue(member, keys)
+		}
+		return out
+	case []any:
+		out := make([]any, len(value))
+		for i, elem := range value {
+			out[i] = redactValue(elem, keys)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// containsFold reports whether keys holds name, ignoring case.
+func containsFold(keys []string, name string) bool {
+	for _, key := range keys {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSlogLogger returns a Logger writing entries to logger, at the info level for successful
+// calls, the warn level for failed ones, and the error level, with the stack, for panics.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+// slogLogger writes log entries to a slog.Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// LogCall implements Logger.
+func (l slogLogger) LogCall(ctx context.Context, entry LogEntry) {
+	attrs := []slog.Attr{
+		slog.String("kind", string(entry.Kind)),
+		slog.String("method", entry.Method),
+		slog.Duration("duration", entry.Duration),
+	}
+	if entry.ID != nil {
+		attrs = append(attrs, slog.Any("id", entry.ID))
+	}
+	if entry.Params != nil {
+		attrs = append(attrs, slog.Any("params", entry.Params))
+	}
+
+	level := slog.LevelInfo
+	if entry.Code != 0 {
+		attrs = append(attrs, slog.Int("code", entry.Code))
+	}
+	if entry.Err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", entry.Err.Error()))
+	}
+	var panicErr *PanicError
+	if errors.As(entry.Err, &panicErr) {
+		level = slog.LevelError
+		attrs = append(attrs,
+			slog.String("correlation_id", panicErr.CorrelationID),
+			slog.String("stack", string(panicErr.Stack)))
+	}
+	l.logger.LogAttrs(ctx, level, "jsonrpc call", attrs...)
+}
+
+// logObserver turns observed calls into log entries.
+type logObserver struct {
+	logger Logger
+	redact Redactor
+}
+
+// newLogObserver creates a logObserver.
+func newLogObserver(logger Logger, opts []LogOption) *logObserver {
+	o := &logObserver{logger: logger}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// CallStarted implements Observer.
+func (*logObserver) CallStarted(context.Context, *Request) {}
+
+// CallFinished implements Observer.
+func (o *logObserver) CallFinished(ctx context.Context, event CallEvent) {
+	req := event.Request
+	entry := LogEntry{
+		Kind:     LogRequest,
+		Method:   req.Method,
+		ID:       req.ID,
+		Params:   req.Params,
+		Duration: event.Duration,
+		Err:      event.Err,
+	}
+	if req.IsNotification() {
+		entry.Kind = LogNotification
+	}
+	if o.redact != nil {
+		entry.Params = o.redact(req.Method, req.Params)
+	}
+
+	if event.Response != nil && event.Response.Err() != nil {
+		entry.Code = event.Response.Err().Code
+		if entry.Err == nil {
+			entry.Err = event.Response.Err()
+		}
+	} else {
+		entry.Code = errorCode(event.Err)
+	}
+	o.logger.LogCall(ctx, entry)
+}
+
+// errorCode returns the code of the JSON-RPC error err is, or wraps, zero if none.
+func errorCode(err error) int {
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		return 0
+	}
+	if rpcErr == nil {
+		return 0
+	}
+	return rpcErr.Code
+}
+
+// BatchStarted implements Observer.
+func (*logObserver) BatchStarted(context.Context, int) {}
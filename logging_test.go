@@ -0,0 +1,137 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger records the entries it receives.
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+func (l *recordingLogger) LogCall(_ context.Context, entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// newLoggedServer creates a server logging to logger, with an "ok" and a "fail" method.
+func newLoggedServer(t *testing.T, logger Logger, opts ...LogOption) *Server {
+	t.Helper()
+	srv := NewServer(WithLogger(logger, opts...))
+	ok := HandlerFunc(func(context.Context, *Request) (any, error) {
+		return "fine", nil
+	})
+	fail := HandlerFunc(func(context.Context, *Request) (any, error) {
+		return nil, errors.New("boom")
+	})
+	require.NoError(t, srv.Register("ok", ok))
+	require.NoError(t, srv.Register("fail", fail))
+	return srv
+}
+
+func TestServer_Logger(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Requests and notifications", func(t *testing.T) {
+		logger := &recordingLogger{}
+		srv := newLoggedServer(t, logger)
+
+		srv.HandleRequest(ctx, NewRequestWithID("ok", []any{"a"}, 1))
+		srv.HandleRequest(ctx, NewRequestWithID("fail", nil, 2))
+		srv.HandleRequest(ctx, NewNotification("ok", nil))
+
+		require.Len(t, logger.entries, 3)
+		ok := logger.entries[0]
+		assert.Equal(t, LogRequest, ok.Kind)
+		assert.Equal(t, "ok", ok.Method)
+		assert.Equal(t, 1, ok.ID)
+		assert.Equal(t, []any{"a"}, ok.Params)
+		assert.Zero(t, ok.Code)
+		assert.NoError(t, ok.Err)
+
+		fail := logger.entries[1]
+		assert.Equal(t, ServerSideException, fail.Code)
+		assert.EqualError(t, fail.Err, "boom")
+
+		notif := logger.entries[2]
+		assert.Equal(t, LogNotification, notif.Kind)
+		assert.Nil(t, notif.ID)
+	})
+
+	t.Run("Redaction", func(t *testing.T) {
+		logger := &recordingLogger{}
+		srv := newLoggedServer(t, logger, WithLogRedactor(RedactKeys("password", "token")))
+
+		params := map[string]any{
+			"user":     "alice",
+			"Password": "hunter2",
+			"nested":   []any{map[string]any{"token": "abc", "keep": 1}},
+		}
+		srv.HandleRequest(ctx, NewRequestWithID("ok", params, 1))
+
+		require.Len(t, logger.entries, 1)
+		assert.Equal(t, map[string]any{
+			"user":     "alice",
+			"Password": redacted,
+			"nested":   []any{map[string]any{"token": redacted, "keep": 1}},
+		}, logger.entries[0].Params)
+		assert.Equal(t, "hunter2", params["Password"], "params are not modified")
+	})
+}
+
+func TestClient_Logger(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer()
+	require.NoError(t, srv.Register("ok", HandlerFunc(func(context.Context, *Request) (any, error) {
+		return "fine", nil
+	})))
+	type login struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+
+	logger := &recordingLogger{}
+	client := NewClient(&funcTransport{fn: func(ctx context.Context, payload []byte) (
+		[]byte,
+		error,
+	) {
+		return srv.HandleMessage(ctx, payload), nil
+	}}, WithClientLogger(logger, WithLogRedactor(RedactKeys("password"))))
+
+	require.NoError(t, client.Call(ctx, "ok", login{User: "alice", Password: "hunter2"}, nil))
+	require.Error(t, client.Call(ctx, "missing", nil, nil))
+
+	require.Len(t, logger.entries, 2)
+	assert.Equal(t, map[string]any{"user": "alice", "password": redacted}, logger.entries[0].Params)
+	assert.Equal(t, MethodNotFound, logger.entries[1].Code)
+	assert.Error(t, logger.entries[1].Err)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	srv := newLoggedServer(t, logger)
+
+	srv.HandleRequest(context.Background(), NewRequestWithID("ok", nil, 1))
+	srv.HandleRequest(context.Background(), NewRequestWithID("fail", nil, 2))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "level=INFO")
+	assert.Contains(t, lines[0], "method=ok")
+	assert.Contains(t, lines[0], "id=1")
+	assert.Contains(t, lines[1], "level=WARN")
+	assert.Contains(t, lines[1], "code=-32603")
+	assert.Contains(t, lines[1], "error=boom")
+}
@@ -0,0 +1,148 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bytedance/sonic/ast"
+)
+
+// LimitExceeded is the error code of responses to messages exceeding a size limit, within the
+// range reserved for implementation-defined server errors.
+const LimitExceeded = -32012
+
+// msgLimitExceeded is the message of LimitExceeded errors.
+const msgLimitExceeded = "Limit exceeded"
+
+// ErrLimitExceeded is the error sent for messages exceeding a size limit. The errors actually
+// sent carry data naming the limit; match them with errors.Is.
+var ErrLimitExceeded = &Error{Code: LimitExceeded, Message: msgLimitExceeded}
+
+// messageLimits bounds the size of the messages a peer accepts. Zero fields disable their limit.
+type messageLimits struct {
+	maxMessageSize int
+	maxBatchSize   int
+	maxParamsDepth int
+}
+
+// WithMaxMessageSize rejects request messages, including whole batches, larger than n bytes
+// with ErrLimitExceeded. ServeHTTP stops reading request bodies past the limit and answers them
+// with 413 Request Entity Too Large. Framed streams served by Serve or ServeStream, such as those
+// of NewConnStream and NewStdioStream, fail the read of a larger message before buffering it,
+// ending the stream. Zero disables the limit.
+func WithMaxMessageSize(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.maxMessageSize = max(n, 0)
+	}
+}
+
+// WithMaxBatchSize rejects batches of more than n members with a single ErrLimitExceeded
+// response, before dispatching any of them. Zero disables the limit.
+func WithMaxBatchSize(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.maxBatchSize = max(n, 0)
+	}
+}
+
+// WithMaxParamsDepth rejects requests whose params nest deeper than n levels with
+// ErrLimitExceeded, before decoding them. Flat positional or named params have a depth of 1.
+// Zero disables the limit.
+func WithMaxParamsDepth(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.maxParamsDepth = max(n, 0)
+	}
+}
+
+// WithClientMaxMessageSize makes the client reject response messages larger than n bytes. Calls
+// answered with one fail with an error matching ErrLimitExceeded. On framed streams, such as
+// those of NewStdioStream and DialConn, the read of a larger message fails before buffering it,
+// ending the stream; on other streams, the message is dropped and its calls wait for their
+// context. Zero disables the limit.
+func WithClientMaxMessageSize(n int) ClientOption {
+	return func(c *Client) {
+		c.limits.maxMessageSize = max(n, 0)
+	}
+}
+
+// WithClientMaxBatchSize makes CallBatch and SendBatch fail with an error matching
+// ErrLimitExceeded for batches of more than n members, without sending them. Zero disables the
+// limit.
+func WithClientMaxBatchSize(n int) ClientOption {
+	return func(c *Client) {
+		c.limits.maxBatchSize = max(n, 0)
+	}
+}
+
+// checkMessage returns an error if a message of size bytes exceeds the size limit.
+func (l messageLimits) checkMessage(size int) *Error {
+	if l.maxMessageSize > 0 && size > l.maxMessageSize {
+		return limitError("message size exceeds %d bytes", l.maxMessageSize)
+	}
+	return nil
+}
+
+// checkBatch returns an error if a batch of size members exceeds the batch limit.
+func (l messageLimits) checkBatch(size int) *Error {
+	if l.maxBatchSize > 0 && size > l.maxBatchSize {
+		return limitError("batch size exceeds %d members", l.maxBatchSize)
+	}
+	return nil
+}
+
+// checkParamsDepth returns an error if the params of the encoded request nest deeper than the
+// depth limit. The depth of the request object itself is not counted, as its other members are
+// scalars in any valid request.
+func (l messageLimits) checkParamsDepth(msg []byte) *Error {
+	if l.maxParamsDepth > 0 && exceedsDepth(msg, l.maxParamsDepth+1) {
+		return limitError("params depth exceeds %d", l.maxParamsDepth)
+	}
+	return nil
+}
+
+// rejectResponse returns the response for a request message exceeding a limit, addressed to its
+// ID if it has a readable one. Notifications are not answered.
+func rejectResponse(msg []byte, err *Error) *Response {
+	node, searchErr := ast.NewSearcher(string(msg)).GetByPath("id")
+	if searchErr != nil || !node.Exists() {
+		return nil
+	}
+	raw, rawErr := node.Raw()
+	if rawErr != nil {
+		return NewErrorResponse(nil, err)
+	}
+	id, idErr := unmarshalRawID(json.RawMessage(raw))
+	if idErr != nil {
+		return NewErrorResponse(nil, err)
+	}
+	return NewErrorResponse(id, err)
+}
+
+// limitError returns a LimitExceeded error with data describing the limit.
+func limitError(format string, limit int) *Error {
+	return ErrLimitExceeded.WithData(fmt.Sprintf(format, limit))
+}
+
+// exceedsDepth reports whether the encoded JSON value nests objects and arrays deeper than
+// limit. It stops scanning as soon as the limit is exceeded.
+func exceedsDepth(data []byte, limit int) bool {
+	var scan valueScanner
+	for _, c := range data {
+		if scan.inString {
+			scan.scanString(c)
+			continue
+		}
+		switch c {
+		case '"':
+			scan.inString = true
+		case '{', '[':
+			scan.depth++
+			if scan.depth > limit {
+				return true
+			}
+		case '}', ']':
+			scan.depth--
+		default:
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nestedArray returns a JSON array nested depth levels deep.
+func nestedArray(depth int) string {
+	return strings.Repeat("[", depth) + strings.Repeat("]", depth)
+}
+
+// limitedServer returns a server with an "echo" method and the given options.
+func limitedServer(t *testing.T, opts ...ServerOption) *Server {
+	t.Helper()
+	srv := NewServer(opts...)
+	echo := HandlerFunc(func(_ context.Context, req *Request) (any, error) {
+		return req.Params, nil
+	})
+	require.NoError(t, srv.Register("echo", echo))
+	return srv
+}
+
+func TestServer_MaxMessageSize(t *testing.T) {
+	ctx := context.Background()
+	srv := limitedServer(t, WithMaxMessageSize(64))
+
+	t.Run("Message within the limit is served", func(t *testing.T) {
+		reply := srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"echo","id":1}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Nil(t, resp.Err())
+	})
+
+	t.Run("Larger message is rejected", func(t *testing.T) {
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo","params":["%s"],"id":1}`,
+			strings.Repeat("x", 64))
+		resp, err := DecodeResponse(srv.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.ErrorIs(t, resp.Err(), ErrLimitExceeded)
+		assert.Equal(t, "message size exceeds 64 bytes", resp.Err().Data)
+		assert.Nil(t, resp.IDOrNil())
+	})
+
+	t.Run("HTTP answers 413", func(t *testing.T) {
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo","params":["%s"],"id":1}`,
+			strings.Repeat("x", 64))
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentTypeJSON)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		resp, err := DecodeResponse(rec.Body.Bytes())
+		require.NoError(t, err)
+		require.ErrorIs(t, resp.Err(), ErrLimitExceeded)
+	})
+
+	t.Run("Framed stream rejects the message before reading it", func(t *testing.T) {
+		clientEnd, serverEnd := net.Pipe()
+		defer clientEnd.Close()
+		served := make(chan error, 1)
+		go func() { served <- srv.ServeStream(ctx, NewConnStream(serverEnd)) }()
+
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo","params":["%s"],"id":1}`+"\n",
+			strings.Repeat("x", 64))
+		go func() { _, _ = clientEnd.Write([]byte(msg)) }()
+		select {
+		case err := <-served:
+			require.ErrorIs(t, err, ErrLimitExceeded)
+		case <-time.After(time.Second):
+			t.Fatal("stream was not ended")
+		}
+	})
+
+	t.Run("HTTP body without a length is read up to the limit", func(t *testing.T) {
+		body := strings.Repeat(" ", 1024) + `{"jsonrpc":"2.0","method":"echo","id":1}`
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentTypeJSON)
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+func TestServer_MaxBatchSize(t *testing.T) {
+	ctx := context.Background()
+	srv := limitedServer(t, WithMaxBatchSize(2))
+	member := `{"jsonrpc":"2.0","method":"echo","id":%d}`
+
+	t.Run("Batch within the limit is served", func(t *testing.T) {
+		msg := "[" + fmt.Sprintf(member, 1) + "," + fmt.Sprintf(member, 2) + "]"
+		resps, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		assert.Len(t, resps, 2)
+	})
+
+	t.Run("Larger batch is rejected as a whole", func(t *testing.T) {
+		members := make([]string, 3)
+		for i := range members {
+			members[i] = fmt.Sprintf(member, i)
+		}
+		reply := srv.HandleMessage(ctx, []byte("["+strings.Join(members, ",")+"]"))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err, "a single response rather than a batch")
+		require.ErrorIs(t, resp.Err(), ErrLimitExceeded)
+		assert.Equal(t, "batch size exceeds 2 members", resp.Err().Data)
+	})
+}
+
+func TestServer_MaxParamsDepth(t *testing.T) {
+	ctx := context.Background()
+	srv := limitedServer(t, WithMaxParamsDepth(3))
+	request := func(params string, id int) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo","params":%s,"id":%d}`, params, id)
+	}
+
+	t.Run("Params within the limit are served", func(t *testing.T) {
+		resp, err := DecodeResponse(srv.HandleMessage(ctx, []byte(request(nestedArray(3), 1))))
+		require.NoError(t, err)
+		assert.Nil(t, resp.Err())
+	})
+
+	t.Run("Deeper params are rejected with the request ID", func(t *testing.T) {
+		resp, err := DecodeResponse(srv.HandleMessage(ctx, []byte(request(nestedArray(4), 7))))
+		require.NoError(t, err)
+		require.ErrorIs(t, resp.Err(), ErrLimitExceeded)
+		assert.Equal(t, "params depth exceeds 3", resp.Err().Data)
+		assert.Equal(t, int64(7), resp.IDOrNil())
+	})
+
+	t.Run("Brackets in strings do not count", func(t *testing.T) {
+		params := `["[[[[\"[[[["]`
+		resp, err := DecodeResponse(srv.HandleMessage(ctx, []byte(request(params, 1))))
+		require.NoError(t, err)
+		assert.Nil(t, resp.Err())
+	})
+
+	t.Run("Batch members are checked individually", func(t *testing.T) {
+		msg := "[" + request(nestedArray(4), 1) + "," + request(nestedArray(1), 2) + "]"
+		resps, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		require.ErrorIs(t, resps[0].Err(), ErrLimitExceeded)
+		assert.Nil(t, resps[1].Err())
+	})
+
+	t.Run("Deep notification is dropped", func(t *testing.T) {
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo","params":%s}`, nestedArray(4))
+		assert.Nil(t, srv.HandleMessage(ctx, []byte(msg)))
+	})
+}
+
+func TestClient_MessageLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Larger response is rejected", func(t *testing.T) {
+		transport := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			resp, _ := NewResponse(int64(1), strings.Repeat("x", 64))
+			return resp.MarshalJSON()
+		}}
+		client := NewClient(transport, WithClientMaxMessageSize(64))
+		defer client.Close()
+
+		err := client.Call(ctx, "echo", nil, nil)
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	})
+
+	t.Run("Larger batch is not sent", func(t *testing.T) {
+		sent := false
+		transport := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			sent = true
+			return nil, nil
+		}}
+		client := NewClient(transport, WithClientMaxBatchSize(1))
+		defer client.Close()
+
+		reqs := []*Request{NewRequestWithID("echo", nil, 1), NewRequestWithID("echo", nil, 2)}
+		_, err := client.CallBatch(ctx, reqs)
+		require.ErrorIs(t, err, ErrLimitExceeded)
+
+		batch := client.NewBatch()
+		call := batch.Add("echo", nil)
+		batch.Add("echo", nil)
+		require.ErrorIs(t, client.SendBatch(ctx, batch), ErrLimitExceeded)
+		require.ErrorIs(t, call.Err(), ErrLimitExceeded)
+		assert.False(t, sent)
+	})
+
+	t.Run("Larger framed response ends the stream", func(t *testing.T) {
+		big, err := NewResponse(int64(1), strings.Repeat("x", 64))
+		require.NoError(t, err)
+		out, err := big.MarshalJSON()
+		require.NoError(t, err)
+		r, w := io.Pipe()
+		defer w.Close()
+		client := NewStreamClient(NewFramedStream(r, io.Discard, LineFraming),
+			WithClientMaxMessageSize(64))
+		defer client.Close()
+		go func() { _, _ = w.Write(append(out, '\n')) }()
+
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("client was not shut down")
+		}
+	})
+
+	t.Run("Larger stream response is dropped", func(t *testing.T) {
+		local, remote := newStreamPair()
+		client := NewStreamClient(local, WithClientMaxMessageSize(64))
+		defer client.Close()
+
+		go func() {
+			msg, err := remote.ReadMessage(ctx)
+			if err != nil {
+				return
+			}
+			req, err := DecodeRequest(msg)
+			if err != nil {
+				return
+			}
+			big, _ := NewResponse(req.ID, strings.Repeat("x", 64))
+			out, _ := big.MarshalJSON()
+			_ = remote.WriteMessage(ctx, out)
+		}()
+
+		short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := client.Call(short, "echo", nil, nil)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,148 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// PeerMetadataKey is the incoming metadata key holding the remote address of the peer, where
+// the transport knows it. Keys starting with a colon are never sent as HTTP headers.
+const PeerMetadataKey = ":peer"
+
+// Metadata carries transport-level key/value pairs alongside calls, such as HTTP headers, auth
+// tokens, and tracing context, independently of the transport in use. Keys are case-insensitive
+// and stored in lower case; each key may hold several values.
+//
+// Servers attach the metadata of incoming calls to handler contexts, read with IncomingMetadata.
+// Clients send the metadata attached with NewOutgoingContext, as HTTP headers on the HTTP
+// transport; stream transports carry no per-call metadata and ignore it.
+type Metadata map[string][]string
+
+// MetadataPairs creates Metadata from alternating keys and values. A trailing key without a
+// value is ignored.
+func MetadataPairs(kv ...string) Metadata {
+	md := make(Metadata, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		md.Append(kv[i], kv[i+1])
+	}
+	return md
+}
+
+// MetadataFromHTTP returns the metadata of an HTTP request: its headers, and the remote address
+// under PeerMetadataKey.
+func MetadataFromHTTP(r *http.Request) Metadata {
+	md := make(Metadata, len(r.Header)+1)
+	for key, values := range r.Header {
+		md.Append(key, values...)
+	}
+	if r.RemoteAddr != "" {
+		md.Set(PeerMetadataKey, r.RemoteAddr)
+	}
+	return md
+}
+
+// Get returns the first value for key, or "" if there is none.
+func (md Metadata) Get(key string) string {
+	values := md[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Values returns all values for key.
+func (md Metadata) Values(key string) []string {
+	return md[strings.ToLower(key)]
+}
+
+// Set replaces the values for key.
+func (md Metadata) Set(key string, values ...string) {
+	md[strings.ToLower(key)] = values
+}
+
+// Append adds values to those already held for key.
+func (md Metadata) Append(key string, values ...string) {
+	lower := strings.ToLower(key)
+	md[lower] = append(md[lower], values...)
+}
+
+// Delete removes key.
+func (md Metadata) Delete(key string) {
+	delete(md, strings.ToLower(key))
+}
+
+// Copy returns a deep copy of md.
+func (md Metadata) Copy() Metadata {
+	out := make(Metadata, len(md))
+	for key, values := range md {
+		out[key] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// Join returns a copy of md merged with others, appending the values of keys present in several.
+func (md Metadata) Join(others ...Metadata) Metadata {
+	out := md.Copy()
+	for _, other := range others {
+		for key, values := range other {
+			out.Append(key, values...)
+		}
+	}
+	return out
+}
+
+// Context keys for incoming and outgoing metadata.
+type (
+	incomingMetadataKey struct{}
+	outgoingMetadataKey struct{}
+)
+
+// NewIncomingContext returns a copy of ctx carrying md as the metadata of an incoming call.
+// Transports serving calls use it; handlers and middleware read it with IncomingMetadata.
+func NewIncomingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, incomingMetadataKey{}, md)
+}
+
+// IncomingMetadata returns the metadata of the call being handled. It returns false if the
+// transport provided none.
+func IncomingMetadata(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(incomingMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// NewOutgoingContext returns a copy of ctx carrying md as the metadata sent with calls made
+// using it, replacing any outgoing metadata already attached. Incoming metadata is kept apart,
+// so that servers never forward it by accident.
+func NewOutgoingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, outgoingMetadataKey{}, md)
+}
+
+// AppendToOutgoingContext returns a copy of ctx whose outgoing metadata also holds the given
+// alternating keys and values.
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := OutgoingMetadata(ctx)
+	return NewOutgoingContext(ctx, md.Join(MetadataPairs(kv...)))
+}
+
+// OutgoingMetadata returns the metadata attached to ctx for outgoing calls.
+func OutgoingMetadata(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(outgoingMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// setHTTPHeaders adds the outgoing metadata of ctx to an HTTP header, skipping pseudo keys.
+func setHTTPHeaders(ctx context.Context, header http.Header) {
+	md, ok := OutgoingMetadata(ctx)
+	if !ok {
+		return
+	}
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Run("Keys are case-insensitive", func(t *testing.T) {
+		md := MetadataPairs("Authorization", "Bearer x", "X-Trace", "a", "x-trace", "b")
+		assert.Equal(t, "Bearer x", md.Get("authorization"))
+		assert.Equal(t, []string{"a", "b"}, md.Values("X-TRACE"))
+		assert.Equal(t, "", md.Get("missing"))
+
+		md.Set("X-Trace", "c")
+		assert.Equal(t, []string{"c"}, md.Values("x-trace"))
+		md.Delete("X-Trace")
+		assert.Empty(t, md.Values("x-trace"))
+	})
+
+	t.Run("Odd pairs drop the trailing key", func(t *testing.T) {
+		assert.Equal(t, Metadata{"a": {"1"}}, MetadataPairs("a", "1", "b"))
+	})
+
+	t.Run("Copy and Join do not alias", func(t *testing.T) {
+		md := MetadataPairs("a", "1")
+		joined := md.Join(MetadataPairs("a", "2", "b", "3"))
+		assert.Equal(t, Metadata{"a": {"1", "2"}, "b": {"3"}}, joined)
+		assert.Equal(t, Metadata{"a": {"1"}}, md)
+
+		cp := md.Copy()
+		cp.Append("a", "x")
+		assert.Equal(t, []string{"1"}, md.Values("a"))
+	})
+}
+
+func TestMetadataContexts(t *testing.T) {
+	t.Run("Incoming and outgoing are kept apart", func(t *testing.T) {
+		ctx := NewIncomingContext(context.Background(), MetadataPairs("a", "in"))
+		_, ok := OutgoingMetadata(ctx)
+		assert.False(t, ok)
+
+		md, ok := IncomingMetadata(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "in", md.Get("a"))
+	})
+
+	t.Run("Append to outgoing", func(t *testing.T) {
+		ctx := AppendToOutgoingContext(context.Background(), "a", "1")
+		ctx = AppendToOutgoingContext(ctx, "a", "2")
+		md, ok := OutgoingMetadata(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []string{"1", "2"}, md.Values("a"))
+	})
+}
+
+func TestMetadata_HTTP(t *testing.T) {
+	srv := NewServer()
+	whoami := func(ctx context.Context, _ *Request) (any, error) {
+		md, ok := IncomingMetadata(ctx)
+		if !ok {
+			return nil, ErrInternal
+		}
+		return []string{md.Get("Authorization"), md.Get(PeerMetadataKey)}, nil
+	}
+	require.NoError(t, srv.RegisterFunc("whoami", whoami))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := NewClient(NewHTTPTransport(ts.URL))
+	defer client.Close()
+
+	ctx := AppendToOutgoingContext(context.Background(),
+		"Authorization", "Bearer token", PeerMetadataKey, "spoofed")
+	var got []string
+	require.NoError(t, client.Call(ctx, "whoami", nil, &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, "Bearer token", got[0])
+	assert.Contains(t, got[1], "127.0.0.1:")
+}
+
+func TestMetadata_Serve(t *testing.T) {
+	srv := NewServer()
+	require.NoError(t, srv.RegisterFunc("peer", func(ctx context.Context, _ *Request) (any, error) {
+		md, _ := IncomingMetadata(ctx)
+		return md.Get(PeerMetadataKey), nil
+	}))
+	l, err := Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveListener(t, srv, l)
+
+	stream, err := DialConn(context.Background(), "tcp", l.Addr().String())
+	require.NoError(t, err)
+	client := NewStreamClient(stream)
+	defer client.Close()
+
+	var peer string
+	require.NoError(t, client.Call(context.Background(), "peer", nil, &peer))
+	assert.Contains(t, peer, "127.0.0.1:")
+}
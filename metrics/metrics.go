@@ -0,0 +1,115 @@
+// Package metrics records metrics of jsonrpc servers and clients: calls by method and outcome
+// with their latency, calls in flight, and batch sizes. Measurements go to a Recorder, so that
+// any metrics system, such as Prometheus or OpenTelemetry, can be plugged in without becoming a
+// dependency of this module; Stats is an in-memory Recorder.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Role tells whether measurements come from a server or a client.
+type Role string
+
+// Roles reported to a Recorder.
+const (
+	RoleServer Role = "server"
+	RoleClient Role = "client"
+)
+
+// Outcomes reported for calls that did not end with a JSON-RPC error, whose outcome is its code.
+const (
+	// OutcomeOK is the outcome of successful calls.
+	OutcomeOK = "ok"
+
+	// OutcomeError is the outcome of client calls that failed without a response from the
+	// server, such as calls failing to connect.
+	OutcomeError = "error"
+)
+
+// UnknownMethod is reported in place of the method of calls answered with a method not found
+// error, so that arbitrary method names sent by peers do not grow the set of method labels.
+const UnknownMethod = "unknown"
+
+// Recorder receives measurements. Implementations must be safe for concurrent use. A Prometheus
+// adapter would typically back InFlight with a gauge, Call with a counter and a histogram, and
+// Batch with a histogram.
+type Recorder interface {
+	// InFlight adjusts the number of calls in progress by delta.
+	InFlight(role Role, delta int)
+
+	// Call records a completed call. The outcome is OutcomeOK, OutcomeError, or the JSON-RPC
+	// error code of the response in decimal.
+	Call(role Role, method, outcome string, duration time.Duration)
+
+	// Batch records a batch with its number of members.
+	Batch(role Role, size int)
+}
+
+// Server returns an option recording the calls and batches handled by a server to rec.
+func Server(rec Recorder) jsonrpc.ServerOption {
+	return jsonrpc.WithObserver(&observer{rec: rec, role: RoleServer})
+}
+
+// Client returns an option recording the calls and batches sent by a client to rec. Batch
+// members are not recorded as calls.
+func Client(rec Recorder) jsonrpc.ClientOption {
+	return jsonrpc.WithClientObserver(&observer{rec: rec, role: RoleClient})
+}
+
+// observer adapts a Recorder to jsonrpc.Observer.
+type observer struct {
+	rec  Recorder
+	role Role
+}
+
+// CallStarted implements jsonrpc.Observer.
+func (o *observer) CallStarted(context.Context, *jsonrpc.Request) {
+	o.rec.InFlight(o.role, 1)
+}
+
+// CallFinished implements jsonrpc.Observer.
+func (o *observer) CallFinished(_ context.Context, event jsonrpc.CallEvent) {
+	o.rec.InFlight(o.role, -1)
+
+	method := event.Request.Method
+	outcome := o.outcome(event)
+	if outcome == strconv.Itoa(jsonrpc.MethodNotFound) {
+		method = UnknownMethod
+	}
+	o.rec.Call(o.role, method, outcome, event.Duration)
+}
+
+// BatchStarted implements jsonrpc.Observer.
+func (o *observer) BatchStarted(_ context.Context, size int) {
+	o.rec.Batch(o.role, size)
+}
+
+// outcome returns the outcome label of a completed call.
+func (o *observer) outcome(event jsonrpc.CallEvent) string {
+	if event.Response != nil {
+		if rpcErr := event.Response.Err(); rpcErr != nil {
+			return strconv.Itoa(rpcErr.Code)
+		}
+		return OutcomeOK
+	}
+	if event.Err == nil {
+		return OutcomeOK
+	}
+
+	var rpcErr *jsonrpc.Error
+	switch {
+	case errors.As(event.Err, &rpcErr):
+		return strconv.Itoa(rpcErr.Code)
+	case o.role == RoleServer:
+		// Handlers of notifications failing with plain errors, reported as internal errors
+		return strconv.Itoa(jsonrpc.ServerSideException)
+	default:
+		return OutcomeError
+	}
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverTransport exchanges payloads with a server in process.
+type serverTransport struct {
+	srv *jsonrpc.Server
+	err error
+}
+
+func (t *serverTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.srv.HandleMessage(ctx, payload), nil
+}
+
+func (*serverTransport) Close() error {
+	return nil
+}
+
+// newServer creates a server with an "ok" and a "fail" method, recording to stats.
+func newServer(t *testing.T, stats *Stats) *jsonrpc.Server {
+	t.Helper()
+	srv := jsonrpc.NewServer(Server(stats))
+	ok := jsonrpc.HandlerFunc(func(context.Context, *jsonrpc.Request) (any, error) {
+		return "fine", nil
+	})
+	fail := jsonrpc.HandlerFunc(func(context.Context, *jsonrpc.Request) (any, error) {
+		return nil, jsonrpc.ErrInvalidParams
+	})
+	require.NoError(t, srv.Register("ok", ok))
+	require.NoError(t, srv.Register("fail", fail))
+	return srv
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	stats := NewStats()
+	srv := newServer(t, stats)
+
+	srv.HandleRequest(ctx, jsonrpc.NewRequestWithID("ok", nil, 1))
+	srv.HandleRequest(ctx, jsonrpc.NewRequestWithID("ok", nil, 2))
+	srv.HandleRequest(ctx, jsonrpc.NewRequestWithID("fail", nil, 3))
+	srv.HandleRequest(ctx, jsonrpc.NewRequestWithID("random.name", nil, 4))
+	srv.HandleMessage(ctx, []byte(`[{"jsonrpc":"2.0","method":"ok","id":5},`+
+		`{"jsonrpc":"2.0","method":"ok"}]`))
+
+	snap := stats.Snapshot()
+	assert.Equal(t, int64(0), snap.InFlight[RoleServer])
+	assert.Equal(t, int64(4), snap.Call(RoleServer, "ok", OutcomeOK).Count)
+
+	invalid := strconv.Itoa(jsonrpc.InvalidParams)
+	assert.Equal(t, int64(1), snap.Call(RoleServer, "fail", invalid).Count)
+
+	notFound := strconv.Itoa(jsonrpc.MethodNotFound)
+	assert.Equal(t, int64(1), snap.Call(RoleServer, UnknownMethod, notFound).Count)
+	assert.Zero(t, snap.Call(RoleServer, "random.name", notFound).Count)
+
+	assert.Equal(t, BatchStats{Count: 1, Members: 2, MaxSize: 2}, snap.Batches[RoleServer])
+	require.Len(t, snap.Calls, 3)
+	assert.Equal(t, "fail", snap.Calls[0].Method, "calls are sorted")
+}
+
+func TestServer_NotificationErrors(t *testing.T) {
+	stats := NewStats()
+	srv := jsonrpc.NewServer(Server(stats))
+	failing := jsonrpc.HandlerFunc(func(context.Context, *jsonrpc.Request) (any, error) {
+		return nil, errors.New("boom")
+	})
+	require.NoError(t, srv.Register("fail", failing))
+
+	srv.HandleRequest(context.Background(), jsonrpc.NewNotification("fail", nil))
+
+	internal := strconv.Itoa(jsonrpc.ServerSideException)
+	assert.Equal(t, int64(1), stats.Snapshot().Call(RoleServer, "fail", internal).Count)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Calls and batches", func(t *testing.T) {
+		stats := NewStats()
+		transport := &serverTransport{srv: newServer(t, NewStats())}
+		client := jsonrpc.NewClient(transport, Client(stats))
+
+		require.NoError(t, client.Call(ctx, "ok", nil, nil))
+		require.Error(t, client.Call(ctx, "fail", nil, nil))
+		batch := client.NewBatch()
+		batch.Add("ok", nil)
+		batch.Add("fail", nil)
+		require.NoError(t, client.SendBatch(ctx, batch))
+
+		snap := stats.Snapshot()
+		assert.Equal(t, int64(1), snap.Call(RoleClient, "ok", OutcomeOK).Count)
+		invalid := strconv.Itoa(jsonrpc.InvalidParams)
+		assert.Equal(t, int64(1), snap.Call(RoleClient, "fail", invalid).Count)
+		assert.Equal(t, BatchStats{Count: 1, Members: 2, MaxSize: 2}, snap.Batches[RoleClient])
+	})
+
+	t.Run("Transport failures", func(t *testing.T) {
+		stats := NewStats()
+		transport := &serverTransport{err: errors.New("down")}
+		client := jsonrpc.NewClient(transport, Client(stats))
+
+		require.Error(t, client.Call(ctx, "ok", nil, nil))
+		snap := stats.Snapshot()
+		assert.Equal(t, int64(1), snap.Call(RoleClient, "ok", OutcomeError).Count)
+		assert.Equal(t, int64(0), snap.InFlight[RoleClient])
+	})
+}
+
+func TestStats(t *testing.T) {
+	stats := NewStats()
+
+	stats.InFlight(RoleServer, 1)
+	stats.InFlight(RoleServer, 1)
+	stats.InFlight(RoleServer, -1)
+	stats.Call(RoleServer, "m", OutcomeOK, time.Millisecond)
+	stats.Call(RoleServer, "m", OutcomeOK, 3*time.Millisecond)
+	stats.Batch(RoleClient, 4)
+	stats.Batch(RoleClient, 2)
+
+	snap := stats.Snapshot()
+	assert.Equal(t, int64(1), snap.InFlight[RoleServer])
+	assert.Equal(t, CallStats{
+		Role:          RoleServer,
+		Method:        "m",
+		Outcome:       OutcomeOK,
+		Count:         2,
+		TotalDuration: 4 * time.Millisecond,
+		MaxDuration:   3 * time.Millisecond,
+	}, snap.Call(RoleServer, "m", OutcomeOK))
+	assert.Equal(t, BatchStats{Count: 2, Members: 6, MaxSize: 4}, snap.Batches[RoleClient])
+
+	stats.Call(RoleServer, "m", OutcomeOK, time.Millisecond)
+	assert.Equal(t, int64(2), snap.Call(RoleServer, "m", OutcomeOK).Count, "snapshots are copies")
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Stats is a Recorder aggregating measurements in memory, for tests, debugging endpoints, and
+// small deployments without a metrics system. A Stats is safe for concurrent use.
+type Stats struct {
+	mu       sync.Mutex
+	inFlight map[Role]int64
+	calls    map[callKey]*CallStats
+	batches  map[Role]*BatchStats
+}
+
+// callKey identifies the calls aggregated together.
+type callKey struct {
+	role    Role
+	method  string
+	outcome string
+}
+
+// CallStats aggregates the calls of one role, method, and outcome.
+type CallStats struct {
+	Role          Role
+	Method        string
+	Outcome       string
+	Count         int64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// BatchStats aggregates the batches of one role.
+type BatchStats struct {
+	Count   int64
+	Members int64
+	MaxSize int
+}
+
+// Snapshot is a copy of the measurements aggregated by a Stats.
+type Snapshot struct {
+	// InFlight holds the number of calls in progress by role.
+	InFlight map[Role]int64
+
+	// Calls holds the call statistics, sorted by role, method, and outcome.
+	Calls []CallStats
+
+	// Batches holds the batch statistics by role.
+	Batches map[Role]BatchStats
+}
+
+// NewStats creates an empty Stats.
+func NewStats() *Stats {
+	return &Stats{
+		inFlight: make(map[Role]int64),
+		calls:    make(map[callKey]*CallStats),
+		batches:  make(map[Role]*BatchStats),
+	}
+}
+
+// InFlight implements Recorder.
+func (s *Stats) InFlight(role Role, delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight[role] += int64(delta)
+}
+
+// Call implements Recorder.
+func (s *Stats) Call(role Role, method, outcome string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := callKey{role: role, method: method, outcome: outcome}
+	stats, ok := s.calls[key]
+	if !ok {
+		stats = &CallStats{Role: role, Method: method, Outcome: outcome}
+		s.calls[key] = stats
+	}
+	stats.Count++
+	stats.TotalDuration += duration
+	stats.MaxDuration = max(stats.MaxDuration, duration)
+}
+
+// Batch implements Recorder.
+func (s *Stats) Batch(role Role, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.batches[role]
+	if !ok {
+		stats = &BatchStats{}
+		s.batches[role] = stats
+	}
+	stats.Count++
+	stats.Members += int64(size)
+	stats.MaxSize = max(stats.MaxSize, size)
+}
+
+// Snapshot returns a copy of the current measurements.
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := Snapshot{
+		InFlight: make(map[Role]int64, len(s.inFlight)),
+		Calls:    make([]CallStats, 0, len(s.calls)),
+		Batches:  make(map[Role]BatchStats, len(s.batches)),
+	}
+	for role, n := range s.inFlight {
+		snap.InFlight[role] = n
+	}
+	for _, stats := range s.calls {
+		snap.Calls = append(snap.Calls, *stats)
+	}
+	for role, stats := range s.batches {
+		snap.Batches[role] = *stats
+	}
+	slices.SortFunc(snap.Calls, func(a, b CallStats) int {
+		return cmp.Or(
+			cmp.Compare(a.Role, b.Role),
+			cmp.Compare(a.Method, b.Method),
+			cmp.Compare(a.Outcome, b.Outcome),
+		)
+	})
+	return snap
+}
+
+// Call returns the statistics of the calls of role, method, and outcome, zero if there are none.
+func (snap Snapshot) Call(role Role, method, outcome string) CallStats {
+	for _, stats := range snap.Calls {
+		if stats.Role == role && stats.Method == method && stats.Outcome == outcome {
+			return stats
+		}
+	}
+	return CallStats{Role: role, Method: method, Outcome: outcome}
+}
@@ -0,0 +1,110 @@
+package jsonrpc
+
+import (
+	"context"
+	mathrand "math/rand/v2"
+	"time"
+)
+
+const (
+	// defaultMirrorTimeout bounds mirrored calls when MirrorPolicy.Timeout is not set.
+	defaultMirrorTimeout = 10 * time.Second
+
+	// defaultMirrorInFlight is the number of mirrored calls in flight when
+	// MirrorPolicy.MaxInFlight is not set.
+	defaultMirrorInFlight = 64
+
+	// fullPercent is the percentage of requests mirrored by a policy without one.
+	fullPercent = 100
+)
+
+// MirrorPolicy configures WithProxyMirror.
+type MirrorPolicy struct {
+	// Upstream receives the mirrored requests, such as a new version of the backend.
+	Upstream *Client
+
+	// Percent is the percentage of requests mirrored, from 0 to 100, each request being sampled
+	// on its own. Zero mirrors every request.
+	Percent float64
+
+	// Match, if set, selects the requests eligible for mirroring, such as read-only methods, so
+	// that the shadow upstream is spared calls with side effects.
+	Match func(req *Request) bool
+
+	// Timeout bounds every mirrored call. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// MaxInFlight is the number of mirrored calls in flight beyond which further requests are
+	// not mirrored, so that a slow shadow upstream cannot pile up calls. Defaults to 64.
+	MaxInFlight int
+}
+
+// WithProxyMirror asynchronously sends a copy of a sample of the requests and notifications the
+// proxy receives, batch members and calls answered from the cache included, to the upstream of
+// policy, for trying out a backend under real traffic. Mirrored requests are sent one by one with
+// IDs of their own, and their responses and errors are discarded: mirroring never delays nor
+// alters the replies of the proxy. Mirrored calls keep the values of the downstream context, but
+// not its cancellation.
+func WithProxyMirror(policy MirrorPolicy) ProxyOption {
+	return func(p *Proxy) {
+		p.mirror = newMirror(policy)
+	}
+}
+
+// mirror applies a MirrorPolicy.
+type mirror struct {
+	upstream *Client
+	percent  float64
+	match    func(req *Request) bool
+	timeout  time.Duration
+	slots    chan struct{}
+}
+
+// newMirror returns a mirror applying policy, with the defaults of its unset fields.
+func newMirror(policy MirrorPolicy) *mirror {
+	m := &mirror{
+		upstream: policy.Upstream,
+		percent:  policy.Percent,
+		match:    policy.Match,
+		timeout:  policy.Timeout,
+	}
+	if m.percent <= 0 {
+		m.percent = fullPercent
+	}
+	if m.timeout <= 0 {
+		m.timeout = defaultMirrorTimeout
+	}
+	inFlight := policy.MaxInFlight
+	if inFlight <= 0 {
+		inFlight = defaultMirrorInFlight
+	}
+	m.slots = make(chan struct{}, inFlight)
+	return m
+}
+
+// send mirrors a copy of req under an ID from idGen if it is sampled and a slot is free, without
+// waiting for the mirrored call to complete. It does nothing on a nil mirror.
+func (m *mirror) send(ctx context.Context, req *Request, idGen IDGenerator) {
+	if m == nil || m.match != nil && !m.match(req) {
+		return
+	}
+	if m.percent < fullPercent && mathrand.Float64()*fullPercent >= m.percent {
+		return
+	}
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		return
+	}
+
+	mirrored := *req
+	if !mirrored.IsNotification() {
+		mirrored.ID = idGen.NextID()
+	}
+	go func() {
+		defer func() { <-m.slots }()
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.timeout)
+		defer cancel()
+		_, _ = m.upstream.invoke(callCtx, &mirrored)
+	}()
+}
@@ -0,0 +1,129 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProxyMirror(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Mirrors requests and batch members", func(t *testing.T) {
+		shadowClient, shadow := newUpstream(newTestServer(t))
+		primary, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(primary, WithProxyMirror(MirrorPolicy{Upstream: shadowClient}))
+
+		reply := proxy.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","id":"a","method":"sum","params":[1,2]}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"a","result":3}`, string(reply))
+		reply = proxy.HandleMessage(ctx, []byte(`[`+
+			`{"jsonrpc":"2.0","id":1,"method":"subtract","params":[5,2]},`+
+			`{"jsonrpc":"2.0","method":"notify_hello"}]`))
+		assert.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"result":3}]`, string(reply))
+
+		require.Eventually(t, func() bool { return len(shadow.sent()) == 3 },
+			time.Second, time.Millisecond)
+		joined := strings.Join(shadow.sent(), "")
+		assert.Contains(t, joined, `"method":"sum","params":[1,2]`)
+		assert.Contains(t, joined, `"method":"subtract","params":[5,2]`)
+		assert.Contains(t, joined, `"method":"notify_hello"`)
+		assert.NotContains(t, joined, `"id":"a"`, "mirrored calls get IDs of their own")
+	})
+
+	t.Run("Failing shadow changes nothing", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		shadow := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			<-release
+			return nil, errors.New("shadow down")
+		}}
+		primary, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(primary, WithProxyMirror(MirrorPolicy{Upstream: NewClient(shadow)}))
+
+		done := make(chan []byte)
+		go func() {
+			done <- proxy.HandleMessage(ctx,
+				[]byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2]}`))
+		}()
+		select {
+		case reply := <-done:
+			assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":3}`, string(reply))
+		case <-time.After(time.Second):
+			t.Fatal("reply waited for the shadow upstream")
+		}
+	})
+
+	t.Run("Match and percent select requests", func(t *testing.T) {
+		shadowClient, shadow := newUpstream(newTestServer(t))
+		primary, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(primary,
+			WithProxyMirror(MirrorPolicy{
+				Upstream: shadowClient,
+				Match:    func(req *Request) bool { return req.Method == "sum" },
+			}),
+		)
+		_ = proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"subtract"}`))
+		_ = proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":2,"method":"sum"}`))
+		require.Eventually(t, func() bool { return len(shadow.sent()) == 1 },
+			time.Second, time.Millisecond)
+		assert.Contains(t, shadow.sent()[0], `"method":"sum"`)
+
+		var mirrored atomic.Int32
+		counting := &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			mirrored.Add(1)
+			return nil, nil
+		}}
+		proxy = NewProxy(primary, WithProxyMirror(MirrorPolicy{
+			Upstream: NewClient(counting),
+			Percent:  1e-9,
+		}))
+		for range 100 {
+			_ = proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"notify_hello"}`))
+		}
+		time.Sleep(10 * time.Millisecond)
+		assert.Zero(t, mirrored.Load())
+	})
+
+	t.Run("Calls beyond MaxInFlight are dropped", func(t *testing.T) {
+		release := make(chan struct{})
+		var mirrored atomic.Int32
+		shadow := &funcTransport{fn: func(ctx context.Context, _ []byte) ([]byte, error) {
+			mirrored.Add(1)
+			<-release
+			return nil, ctx.Err()
+		}}
+		primary, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(primary, WithProxyMirror(MirrorPolicy{
+			Upstream:    NewClient(shadow),
+			MaxInFlight: 1,
+		}))
+		for range 3 {
+			_ = proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"notify_hello"}`))
+		}
+		close(release)
+		assert.Eventually(t, func() bool { return mirrored.Load() == 1 },
+			time.Second, time.Millisecond)
+	})
+
+	t.Run("Mirrored calls outlive the downstream context", func(t *testing.T) {
+		errs := make(chan error, 1)
+		shadow := &funcTransport{fn: func(ctx context.Context, _ []byte) ([]byte, error) {
+			time.Sleep(5 * time.Millisecond)
+			errs <- ctx.Err()
+			return nil, nil
+		}}
+		primary, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(primary, WithProxyMirror(MirrorPolicy{Upstream: NewClient(shadow)}))
+		callCtx, cancel := context.WithCancel(ctx)
+		_ = proxy.HandleMessage(callCtx, []byte(`{"jsonrpc":"2.0","method":"notify_hello"}`))
+		cancel()
+		assert.NoError(t, <-errs)
+	})
+}
@@ -0,0 +1,428 @@
+// Package msgpack provides a MessagePack wire encoding for the jsonrpc package. Messages keep
+// the JSON-RPC envelope and are transcoded between JSON and MessagePack at the transport, which
+// cuts their size on high-volume links without changing handlers or clients:
+//
+//	srv := jsonrpc.NewServer(jsonrpc.WithEncodings(msgpack.Encoding{}))
+//	transport := jsonrpc.NewHTTPTransport(url, jsonrpc.WithHTTPEncoding(msgpack.Encoding{}))
+//
+// Integers are encoded in their smallest form and floats as float32 when lossless. Decoding
+// accepts any MessagePack value with string map keys except extension types; binary values
+// become base64 strings.
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/internal/jsonvalue"
+)
+
+// revive:disable:modifies-parameter encoders append to dst
+
+// ContentType is the media type of MessagePack messages.
+const ContentType = "application/msgpack"
+
+// Encoding is the MessagePack jsonrpc.Encoding.
+type Encoding struct{}
+
+var _ jsonrpc.Encoding = Encoding{}
+
+// ContentType implements jsonrpc.Encoding.
+func (Encoding) ContentType() string {
+	return ContentType
+}
+
+// Encode implements jsonrpc.Encoding.
+func (Encoding) Encode(msg []byte) ([]byte, error) {
+	return FromJSON(msg)
+}
+
+// Decode implements jsonrpc.Encoding.
+func (Encoding) Decode(data []byte) ([]byte, error) {
+	return ToJSON(data)
+}
+
+// Format bytes, per the MessagePack specification.
+const (
+	fixMapMin   = 0x80
+	fixMapMax   = 0x8f
+	fixArrayMin = 0x90
+	fixArrayMax = 0x9f
+	fixStrMin   = 0xa0
+	fixStrMax   = 0xbf
+	nilByte     = 0xc0
+	falseByte   = 0xc2
+	trueByte    = 0xc3
+	bin8        = 0xc4
+	bin32       = 0xc6
+	float32Byte = 0xca
+	float64Byte = 0xcb
+	uint8Byte   = 0xcc
+	uint64Byte  = 0xcf
+	int8Byte    = 0xd0
+	int64Byte   = 0xd3
+	str8        = 0xd9
+	str32       = 0xdb
+	array16     = 0xdc
+	array32     = 0xdd
+	map16       = 0xde
+	map32       = 0xdf
+	negFixMin   = 0xe0
+
+	// byteBits is the width of a byte, for assembling integers.
+	byteBits = 8
+
+	// Sizes in bytes of floats.
+	float32Size = 4
+	float64Size = 8
+
+	// fixLenMask extracts the length of fixed-size maps and arrays; fixStrMask that of strings.
+	fixLenMask = 0x0f
+	fixStrMask = 0x1f
+)
+
+// FromJSON transcodes a JSON message into MessagePack.
+func FromJSON(msg []byte) ([]byte, error) {
+	v, err := jsonvalue.Parse(msg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	return appendValue(make([]byte, 0, len(msg)), v)
+}
+
+// appendValue appends the MessagePack encoding of v to dst.
+func appendValue(dst []byte, v jsonvalue.Value) ([]byte, error) {
+	var err error
+	switch v.Kind {
+	case jsonvalue.Bool:
+		if v.Bool {
+			return append(dst, trueByte), nil
+		}
+		return append(dst, falseByte), nil
+	case jsonvalue.Number:
+		return appendNumber(dst, v)
+	case jsonvalue.String:
+		return appendString(dst, v.Text)
+	case jsonvalue.Array:
+		if dst, err = appendHeader(dst, len(v.Items), fixArrayMin, array16); err != nil {
+			return nil, err
+		}
+		for _, item := range v.Items {
+			if dst, err = appendValue(dst, item); err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	case jsonvalue.Object:
+		if dst, err = appendHeader(dst, len(v.Members), fixMapMin, map16); err != nil {
+			return nil, err
+		}
+		for _, member := range v.Members {
+			if dst, err = appendString(dst, member.Key); err != nil {
+				return nil, err
+			}
+			if dst, err = appendValue(dst, member.Value); err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	default:
+		return append(dst, nilByte), nil
+	}
+}
+
+// appendNumber appends a number as the smallest integer holding it, or as a float.
+func appendNumber(dst []byte, v jsonvalue.Value) ([]byte, error) {
+	if n, ok := v.AsInt(); ok {
+		return appendInt(dst, n), nil
+	}
+	if n, ok := v.AsUint(); ok {
+		return appendUint(dst, n), nil
+	}
+	f, err := v.AsFloat()
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q: %w", v.Text, err)
+	}
+	if f32 := float32(f); float64(f32) == f {
+		return binary.BigEndian.AppendUint32(append(dst, float32Byte), math.Float32bits(f32)), nil
+	}
+	return binary.BigEndian.AppendUint64(append(dst, float64Byte), math.Float64bits(f)), nil
+}
+
+// appendInt appends n in its smallest encoding.
+func appendInt(dst []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < fixMapMin:
+		return append(dst, byte(n))
+	case n >= 0:
+		return appendUint(dst, uint64(n))
+	case n >= -32:
+		return append(dst, byte(n))
+	case n >= math.MinInt8:
+		return append(dst, int8Byte, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(dst, int8Byte+1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(dst, int8Byte+2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, int64Byte), uint64(n))
+	}
+}
+
+// appendUint appends n in the smallest unsigned format holding it.
+func appendUint(dst []byte, n uint64) []byte {
+	switch {
+	case n <= math.MaxUint8:
+		return append(dst, uint8Byte, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, uint8Byte+1), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, uint8Byte+2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, uint64Byte), n)
+	}
+}
+
+// appendString appends a string.
+func appendString(dst []byte, s string) ([]byte, error) {
+	switch n := len(s); {
+	case n <= fixStrMask:
+		dst = append(dst, fixStrMin|byte(n))
+	case n <= math.MaxUint8:
+		dst = append(dst, str8, byte(n))
+	case n <= math.MaxUint16:
+		dst = binary.BigEndian.AppendUint16(append(dst, str8+1), uint16(n))
+	case n <= math.MaxUint32:
+		dst = binary.BigEndian.AppendUint32(append(dst, str32), uint32(n))
+	default:
+		return nil, errors.New("string too long")
+	}
+	return append(dst, s...), nil
+}
+
+// appendHeader appends the header of an array or map of n elements, given the format byte of
+// its fixed-size form and of its 16-bit form; the 32-bit form follows the 16-bit one.
+func appendHeader(dst []byte, n int, fixed, wide byte) ([]byte, error) {
+	switch {
+	case n <= fixLenMask:
+		return append(dst, fixed|byte(n)), nil
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, wide), uint16(n)), nil
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, wide+1), uint32(n)), nil
+	default:
+		return nil, errors.New("collection too large")
+	}
+}
+
+// ToJSON transcodes a MessagePack message into JSON.
+func ToJSON(data []byte) ([]byte, error) {
+	d := &decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("trailing data after message")
+	}
+	return v.AppendJSON(make([]byte, 0, len(data)+len(data)/2)), nil
+}
+
+// errTruncated is returned for messages ending in the middle of a value.
+var errTruncated = errors.New("unexpected end of message")
+
+// decoder reads MessagePack values from data.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// next consumes n bytes.
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<byteBits | uint64(c)
+	}
+	return n, nil
+}
+
+// length reads a length of size bytes.
+func (d *decoder) length(size int) (int, error) {
+	n, err := d.uint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos) {
+		// Every element takes at least a byte, so longer lengths cannot be honest
+		return 0, errTruncated
+	}
+	return int(n), nil
+}
+
+// value decodes the next value.
+func (d *decoder) value(depth int) (jsonvalue.Value, error) {
+	head, err := d.next(1)
+	if err != nil {
+		return jsonvalue.Value{}, err
+	}
+	b := head[0]
+	switch {
+	case b < fixMapMin:
+		return jsonvalue.Int(int64(b)), nil
+	case b >= negFixMin:
+		return jsonvalue.Int(int64(int8(b))), nil
+	case b <= fixMapMax:
+		return d.object(int(b&fixLenMask), depth)
+	case b <= fixArrayMax:
+		return d.array(int(b&fixLenMask), depth)
+	case b <= fixStrMax:
+		return d.str(int(b & fixStrMask))
+	default:
+		return d.typed(b, depth)
+	}
+}
+
+// typed decodes a value whose format byte is followed by its size or content.
+func (d *decoder) typed(b byte, depth int) (jsonvalue.Value, error) {
+	switch {
+	case b == nilByte:
+		return jsonvalue.Value{}, nil
+	case b == falseByte || b == trueByte:
+		return jsonvalue.Boolean(b == trueByte), nil
+	case b >= bin8 && b <= bin32:
+		n, err := d.length(1 << (b - bin8))
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		raw, err := d.next(n)
+		return jsonvalue.Bytes(raw), err
+	case b == float32Byte || b == float64Byte:
+		return d.float(b)
+	case b >= uint8Byte && b <= uint64Byte:
+		n, err := d.uint(1 << (b - uint8Byte))
+		return jsonvalue.Uint(n), err
+	case b >= int8Byte && b <= int64Byte:
+		return d.int(1 << (b - int8Byte))
+	case b >= str8 && b <= str32:
+		n, err := d.length(1 << (b - str8))
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		return d.str(n)
+	case b == array16 || b == array32:
+		return d.collection(b-array16, depth, d.array)
+	case b == map16 || b == map32:
+		return d.collection(b-map16, depth, d.object)
+	default:
+		return jsonvalue.Value{}, fmt.Errorf("unsupported msgpack format 0x%02x", b)
+	}
+}
+
+// collection decodes an array or map with a 16-bit length, or a 32-bit one if wide is set.
+func (d *decoder) collection(
+	wide byte,
+	depth int,
+	decode func(int, int) (jsonvalue.Value, error),
+) (jsonvalue.Value, error) {
+	n, err := d.length(2 << wide)
+	if err != nil {
+		return jsonvalue.Value{}, err
+	}
+	return decode(n, depth)
+}
+
+// int decodes a signed integer of size bytes.
+func (d *decoder) int(size int) (jsonvalue.Value, error) {
+	n, err := d.uint(size)
+	if err != nil {
+		return jsonvalue.Value{}, err
+	}
+	// Sign-extend from the width read
+	shift := 64 - byteBits*size
+	return jsonvalue.Int(int64(n<<shift) >> shift), nil
+}
+
+// float decodes a float32 or float64.
+func (d *decoder) float(b byte) (jsonvalue.Value, error) {
+	if b == float32Byte {
+		n, err := d.uint(float32Size)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		return jsonvalue.Float(float64(math.Float32frombits(uint32(n))))
+	}
+	n, err := d.uint(float64Size)
+	if err != nil {
+		return jsonvalue.Value{}, err
+	}
+	return jsonvalue.Float(math.Float64frombits(n))
+}
+
+// str decodes a string of n bytes.
+func (d *decoder) str(n int) (jsonvalue.Value, error) {
+	raw, err := d.next(n)
+	if err != nil {
+		return jsonvalue.Value{}, err
+	}
+	if !utf8.Valid(raw) {
+		return jsonvalue.Value{}, errors.New("invalid utf-8 in string")
+	}
+	return jsonvalue.Str(string(raw)), nil
+}
+
+// array decodes an array of n elements.
+func (d *decoder) array(n, depth int) (jsonvalue.Value, error) {
+	if depth >= jsonvalue.MaxDepth {
+		return jsonvalue.Value{}, jsonvalue.ErrTooDeep
+	}
+	v := jsonvalue.Value{Kind: jsonvalue.Array, Items: make([]jsonvalue.Value, 0, n)}
+	for range n {
+		item, err := d.value(depth + 1)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		v.Items = append(v.Items, item)
+	}
+	return v, nil
+}
+
+// object decodes a map of n members, which must have string keys.
+func (d *decoder) object(n, depth int) (jsonvalue.Value, error) {
+	if depth >= jsonvalue.MaxDepth {
+		return jsonvalue.Value{}, jsonvalue.ErrTooDeep
+	}
+	v := jsonvalue.Value{Kind: jsonvalue.Object, Members: make([]jsonvalue.Member, 0, n)}
+	for range n {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		if key.Kind != jsonvalue.String {
+			return jsonvalue.Value{}, errors.New("map key is not a string")
+		}
+		member, err := d.value(depth + 1)
+		if err != nil {
+			return jsonvalue.Value{}, err
+		}
+		v.Members = append(v.Members, jsonvalue.Member{Key: key.Text, Value: member})
+	}
+	return v, nil
+}
@@ -0,0 +1,119 @@
+package msgpack
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+func TestFromJSON(t *testing.T) {
+	t.Run("Uses the smallest formats", func(t *testing.T) {
+		cases := map[string]string{
+			`null`:                 "c0",
+			`[true,false]`:         "92c3c2",
+			`[0,127,128,-1,-32]`:   "95007fcc80ffe0",
+			`[-33,-129,256,65536]`: "94d0dfd1ff7fcd0100ce00010000",
+			`-4294967296`:          "d3ffffffff00000000",
+			`18446744073709551615`: "cfffffffffffffffff",
+			`[1.5,0.1]`:            "92ca3fc00000cb3fb999999999999a",
+			`{"a":"bc"}`:           "81a161a26263",
+		}
+		for input, want := range cases {
+			got, err := FromJSON([]byte(input))
+			require.NoError(t, err, input)
+			assert.Equal(t, want, hex.EncodeToString(got), input)
+		}
+	})
+
+	t.Run("Rejects invalid json", func(t *testing.T) {
+		_, err := FromJSON([]byte(`{"a":`))
+		assert.Error(t, err)
+	})
+}
+
+func TestToJSON(t *testing.T) {
+	t.Run("Round trips messages", func(t *testing.T) {
+		messages := []string{
+			`{"jsonrpc":"2.0","method":"sum","params":[1,2,3],"id":1}`,
+			`{"jsonrpc":"2.0","result":{"name":"x","tags":["a","b"],"ratio":0.25},"id":"abc"}`,
+			`[{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":null}]`,
+			`"` + strings.Repeat("x", 70000) + `"`,
+		}
+		for _, msg := range messages {
+			encoded, err := FromJSON([]byte(msg))
+			require.NoError(t, err)
+
+			decoded, err := ToJSON(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, msg, string(decoded))
+		}
+	})
+
+	t.Run("Shrinks messages", func(t *testing.T) {
+		msg := `{"jsonrpc":"2.0","method":"sum","params":[1,2,3],"id":1}`
+		encoded, err := FromJSON([]byte(msg))
+		require.NoError(t, err)
+		assert.Less(t, len(encoded), len(msg)*3/4)
+	})
+
+	t.Run("Decodes binary as base64", func(t *testing.T) {
+		decoded, err := ToJSON([]byte{bin8, 2, 'h', 'i'})
+		require.NoError(t, err)
+		assert.Equal(t, `"aGk="`, string(decoded))
+	})
+
+	t.Run("Rejects invalid messages", func(t *testing.T) {
+		invalid := map[string][]byte{
+			"empty":           {},
+			"truncated":       {0x92, 0x01},
+			"trailing data":   {0x01, 0x02},
+			"non-string key":  {0x81, 0x01, 0x02},
+			"extension type":  {0xd4, 0x01, 0x00},
+			"invalid utf-8":   {0xa1, 0xff},
+			"dishonest count": {array32, 0xff, 0xff, 0xff, 0xff},
+		}
+		for name, data := range invalid {
+			_, err := ToJSON(data)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestEncoding_HTTP(t *testing.T) {
+	srv := jsonrpc.NewServer(jsonrpc.WithEncodings(Encoding{}))
+	echo := func(_ context.Context, req *jsonrpc.Request) (any, error) {
+		return req.Params, nil
+	}
+	require.NoError(t, srv.RegisterFunc("echo", echo))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	t.Run("Encoded client", func(t *testing.T) {
+		transport := jsonrpc.NewHTTPTransport(ts.URL, jsonrpc.WithHTTPEncoding(Encoding{}))
+		client := jsonrpc.NewClient(transport)
+		defer client.Close()
+
+		var got []any
+		require.NoError(t, client.Call(context.Background(), "echo", []any{"a", 1.5}, &got))
+		assert.Equal(t, []any{"a", 1.5}, got)
+
+		err := client.Call(context.Background(), "missing", nil, nil)
+		require.ErrorIs(t, err, &jsonrpc.Error{Code: jsonrpc.MethodNotFound})
+	})
+
+	t.Run("JSON clients are still served", func(t *testing.T) {
+		client := jsonrpc.NewClient(jsonrpc.NewHTTPTransport(ts.URL))
+		defer client.Close()
+
+		var got []any
+		require.NoError(t, client.Call(context.Background(), "echo", []any{"a"}, &got))
+		assert.Equal(t, []any{"a"}, got)
+	})
+}
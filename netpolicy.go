@@ -0,0 +1,267 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// defaultProxyHeaderTimeout bounds the wait for the PROXY protocol header of a connection when
+// ListenerPolicy.HeaderTimeout is not set.
+const defaultProxyHeaderTimeout = 5 * time.Second
+
+// ListenerPolicy is the network policy of a listener wrapped by NewPolicyListener or created by
+// Listen with WithListenerPolicy. Connections it rejects are closed before they are accepted.
+type ListenerPolicy struct {
+	// Allow, if not empty, only accepts connections from clients within one of its prefixes.
+	Allow []netip.Prefix
+
+	// Deny rejects connections from clients within one of its prefixes, taking precedence over
+	// Allow.
+	Deny []netip.Prefix
+
+	// MaxConnsPerIP caps the connections open at once from each client address. Zero means no
+	// limit.
+	MaxConnsPerIP int
+
+	// ProxyProtocol selects whether connections start with a PROXY protocol header, of version 1
+	// or 2, and the client address it carries then replaces the address of the load balancer in
+	// RemoteAddr, so that Allow, Deny, MaxConnsPerIP, and handlers, through PeerMetadataKey,
+	// all see the real client. Defaults to ProxyProtocolOff.
+	ProxyProtocol ProxyProtocol
+
+	// TrustedProxies, if not empty, only accepts PROXY protocol headers from peers within one of
+	// its prefixes, rejecting the connections of other peers sending one. Without it, headers are
+	// trusted from any peer, which is only safe when the listener is reachable through the load
+	// balancer alone.
+	TrustedProxies []netip.Prefix
+
+	// HeaderTimeout bounds the wait for the PROXY protocol header. Defaults to 5 seconds.
+	HeaderTimeout time.Duration
+}
+
+// WithListenerPolicy makes Listen enforce policy on the connections it accepts, before any TLS
+// handshake.
+func WithListenerPolicy(policy ListenerPolicy) ConnOption {
+	return func(c *connConfig) {
+		c.policy = &policy
+	}
+}
+
+// policyListener is a net.Listener applying a ListenerPolicy.
+type policyListener struct {
+	net.Listener
+	policy ListenerPolicy
+
+	accepted chan net.Conn
+	failed   chan struct{} // Closed once accepting failed with err
+	err      error
+	closed   chan struct{}
+	once     sync.Once
+
+	mu    sync.Mutex
+	conns map[netip.Addr]int
+}
+
+// NewPolicyListener wraps l so that it enforces policy on the connections it accepts, for use
+// with Server.Serve or an http.Server. Connections are vetted in the background, so that clients
+// slow to send their PROXY protocol header do not hold up the others.
+func NewPolicyListener(l net.Listener, policy ListenerPolicy) net.Listener {
+	pl := &policyListener{
+		Listener: l,
+		policy:   policy,
+		accepted: make(chan net.Conn),
+		failed:   make(chan struct{}),
+		closed:   make(chan struct{}),
+		conns:    make(map[netip.Addr]int),
+	}
+	if pl.policy.HeaderTimeout <= 0 {
+		pl.policy.HeaderTimeout = defaultProxyHeaderTimeout
+	}
+	go pl.acceptLoop()
+	return pl
+}
+
+// Accept returns the next connection admitted by the policy.
+func (l *policyListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.accepted:
+		return conn, nil
+	case <-l.failed:
+		return nil, l.err
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes the listener. Connections already accepted stay open.
+func (l *policyListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// acceptLoop accepts connections from the wrapped listener until it fails, vetting each one
+// in its own goroutine.
+func (l *policyListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			close(l.failed)
+			return
+		}
+		go l.admit(conn)
+	}
+}
+
+// admit hands conn to Accept if the policy admits it, and closes it otherwise.
+func (l *policyListener) admit(conn net.Conn) {
+	admitted, ok := l.vet(conn)
+	if !ok {
+		_ = conn.Close()
+		return
+	}
+	select {
+	case l.accepted <- admitted:
+	case <-l.closed:
+		_ = admitted.Close()
+	}
+}
+
+// vet reads the PROXY protocol header of conn, if expected, and checks the client against the
+// policy, returning the connection to accept and whether it is admitted.
+func (l *policyListener) vet(conn net.Conn) (net.Conn, bool) {
+	pc := &policyConn{Conn: conn, remote: conn.RemoteAddr()}
+	if l.policy.ProxyProtocol != ProxyProtocolOff {
+		remote, ok := l.readHeader(conn, pc)
+		if !ok {
+			return nil, false
+		}
+		if remote != nil {
+			pc.remote = remote
+		}
+	}
+
+	client, _ := addrIP(pc.remote)
+	if !l.allows(client) {
+		return nil, false
+	}
+	if l.policy.MaxConnsPerIP > 0 && client.IsValid() {
+		if !l.acquire(client) {
+			return nil, false
+		}
+		pc.release = func() { l.release(client) }
+	}
+	return pc, true
+}
+
+// readHeader reads the PROXY protocol header of conn within the header timeout, returning the
+// client address it carries, if any, and whether the connection may go on. The bytes read past
+// the header stay buffered in pc.
+func (l *policyListener) readHeader(conn net.Conn, pc *policyConn) (net.Addr, bool) {
+	pc.reader = bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(l.policy.HeaderTimeout))
+	remote, err := readProxyHeader(pc.reader, l.policy.ProxyProtocol)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, false
+	}
+	if remote != nil && len(l.policy.TrustedProxies) > 0 {
+		peer, ok := addrIP(conn.RemoteAddr())
+		if !ok || !containsAddr(l.policy.TrustedProxies, peer) {
+			return nil, false
+		}
+	}
+	return remote, true
+}
+
+// allows reports whether the policy admits a client with the given address, invalid for clients
+// without one.
+func (l *policyListener) allows(client netip.Addr) bool {
+	if !client.IsValid() {
+		// Such as Unix sockets, which only the Allow list rules out
+		return len(l.policy.Allow) == 0
+	}
+	if containsAddr(l.policy.Deny, client) {
+		return false
+	}
+	return len(l.policy.Allow) == 0 || containsAddr(l.policy.Allow, client)
+}
+
+// acquire takes a connection slot of client, reporting false if it has none left.
+func (l *policyListener) acquire(client netip.Addr) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[client] >= l.policy.MaxConnsPerIP {
+		return false
+	}
+	l.conns[client]++
+	return true
+}
+
+// release returns a connection slot of client.
+func (l *policyListener) release(client netip.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[client]--; l.conns[client] <= 0 {
+		delete(l.conns, client)
+	}
+}
+
+// policyConn is a connection admitted by a policyListener, reporting the client address of its
+// PROXY protocol header as RemoteAddr.
+type policyConn struct {
+	net.Conn
+	remote  net.Addr
+	reader  *bufio.Reader // Holds the bytes read past the header, if one was looked for
+	release func()
+	once    sync.Once
+}
+
+// Read reads from the bytes buffered while looking for the header first.
+func (c *policyConn) Read(p []byte) (int, error) {
+	if c.reader != nil {
+		return c.reader.Read(p)
+	}
+	return c.Conn.Read(p)
+}
+
+// RemoteAddr returns the address of the client.
+func (c *policyConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// Close closes the connection and frees its slot under MaxConnsPerIP.
+func (c *policyConn) Close() error {
+	if c.release != nil {
+		c.once.Do(c.release)
+	}
+	return c.Conn.Close()
+}
+
+// addrIP returns the IP address of addr, if it has one.
+func addrIP(addr net.Addr) (netip.Addr, bool) {
+	if addr == nil {
+		return netip.Addr{}, false
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		ip := tcp.AddrPort().Addr().Unmap()
+		return ip, ip.IsValid()
+	}
+	if addrPort, err := netip.ParseAddrPort(addr.String()); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// containsAddr reports whether one of prefixes contains addr.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
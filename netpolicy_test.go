@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servePeer serves on a loopback listener with policy a server whose "peer" method returns the
+// address of the calling peer, returning the address of the listener.
+func servePeer(t *testing.T, policy ListenerPolicy) string {
+	t.Helper()
+	srv := NewServer()
+	peer := func(ctx context.Context, _ *Request) (any, error) {
+		md, _ := IncomingMetadata(ctx)
+		return md.Get(PeerMetadataKey), nil
+	}
+	require.NoError(t, srv.RegisterFunc("peer", peer))
+	l, err := Listen("tcp", "127.0.0.1:0", WithListenerPolicy(policy))
+	require.NoError(t, err)
+	serveListener(t, srv, l)
+	return l.Addr().String()
+}
+
+// callPeer connects to addr, sends header and a call to "peer", and returns the reply, or an
+// error if the connection was closed.
+func callPeer(t *testing.T, addr, header string) (string, error) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	return callOn(conn, header)
+}
+
+// callOn sends header and a call to "peer" on conn and returns the reply.
+func callOn(conn net.Conn, header string) (string, error) {
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	msg := header + `{"jsonrpc":"2.0","id":1,"method":"peer"}` + "\n"
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		return "", err
+	}
+	return bufio.NewReader(conn).ReadString('\n')
+}
+
+func TestListenerPolicy(t *testing.T) {
+	const header = "PROXY TCP4 203.0.113.7 192.0.2.1 4242 80\r\n"
+
+	t.Run("PROXY header sets the peer address", func(t *testing.T) {
+		addr := servePeer(t, ListenerPolicy{ProxyProtocol: ProxyProtocolOptional})
+		reply, err := callPeer(t, addr, header)
+		require.NoError(t, err)
+		assert.Contains(t, reply, `"result":"203.0.113.7:4242"`)
+
+		reply, err = callPeer(t, addr, "")
+		require.NoError(t, err)
+		assert.Contains(t, reply, `"result":"127.0.0.1:`)
+	})
+
+	t.Run("Required header", func(t *testing.T) {
+		addr := servePeer(t, ListenerPolicy{ProxyProtocol: ProxyProtocolRequired})
+		_, err := callPeer(t, addr, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("Allow and deny lists", func(t *testing.T) {
+		loopback := netip.MustParsePrefix("127.0.0.0/8")
+		addr := servePeer(t, ListenerPolicy{Deny: []netip.Prefix{loopback}})
+		_, err := callPeer(t, addr, "")
+		assert.Error(t, err)
+
+		addr = servePeer(t, ListenerPolicy{
+			ProxyProtocol: ProxyProtocolRequired,
+			Allow:         []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")},
+			Deny:          []netip.Prefix{netip.MustParsePrefix("203.0.113.8/32")},
+		})
+		_, err = callPeer(t, addr, header)
+		assert.NoError(t, err)
+		_, err = callPeer(t, addr, "PROXY TCP4 203.0.113.8 192.0.2.1 4242 80\r\n")
+		assert.Error(t, err)
+		_, err = callPeer(t, addr, "PROXY TCP4 198.51.100.1 192.0.2.1 4242 80\r\n")
+		assert.Error(t, err)
+	})
+
+	t.Run("Untrusted proxies", func(t *testing.T) {
+		addr := servePeer(t, ListenerPolicy{
+			ProxyProtocol:  ProxyProtocolOptional,
+			TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		})
+		_, err := callPeer(t, addr, header)
+		assert.Error(t, err)
+		_, err = callPeer(t, addr, "")
+		assert.NoError(t, err, "connections without a header need no trust")
+	})
+
+	t.Run("Connections per IP", func(t *testing.T) {
+		addr := servePeer(t, ListenerPolicy{MaxConnsPerIP: 1})
+		first, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		_, err = callOn(first, "")
+		require.NoError(t, err)
+
+		_, err = callPeer(t, addr, "")
+		assert.Error(t, err)
+
+		require.NoError(t, first.Close())
+		assert.Eventually(t, func() bool {
+			_, err := callPeer(t, addr, "")
+			return err == nil
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Close stops accepting", func(t *testing.T) {
+		inner, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		l := NewPolicyListener(inner, ListenerPolicy{})
+		require.NoError(t, l.Close())
+		_, err = l.Accept()
+		assert.ErrorIs(t, err, net.ErrClosed)
+	})
+}
@@ -0,0 +1,121 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// NumberDecoding sets how numbers are decoded into interface values, such as the params of
+// decoded requests and results unmarshaled into an any or a map[string]any.
+type NumberDecoding int
+
+const (
+	// NumbersAsFloat64 decodes numbers as float64, like encoding/json. Integers beyond 2^53 lose
+	// precision. This is the default.
+	NumbersAsFloat64 NumberDecoding = iota
+
+	// NumbersAsJSONNumber decodes numbers as json.Number, keeping their literal so that no
+	// precision is lost, and so that they are re-encoded exactly as received.
+	NumbersAsJSONNumber
+)
+
+// maxExactDigits is the number of significant decimal digits that survive a round trip through
+// a float64.
+const maxExactDigits = 15
+
+// numberDecoding is the active number decoding, protected by profileMutex.
+var numberDecoding = NumbersAsFloat64
+
+// SetNumberDecoding sets how numbers are decoded into interface values by the sonic profiles and
+// StdCodec. Other codecs set with SetCodec are configured on their own. Like
+// SetPerformanceProfile, it is meant to be called once at startup:
+//
+//	jsonrpc.SetNumberDecoding(jsonrpc.NumbersAsJSONNumber)
+//
+// IDs are not affected: numeric IDs that a float64 cannot hold exactly are always decoded as
+// json.Number.
+func SetNumberDecoding(mode NumberDecoding) {
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+
+	if api, ok := profileAPI(currentProfile, mode); ok {
+		sonicAPI = api
+		numberDecoding = mode
+	}
+}
+
+// GetNumberDecoding returns the active number decoding.
+func GetNumberDecoding() NumberDecoding {
+	profileMutex.RLock()
+	defer profileMutex.RUnlock()
+	return numberDecoding
+}
+
+// unmarshalStd decodes data with encoding/json, decoding numbers as set by SetNumberDecoding.
+func unmarshalStd(data []byte, v any) error {
+	if GetNumberDecoding() != NumbersAsJSONNumber {
+		return json.Unmarshal(data, v)
+	}
+	if !json.Valid(data) {
+		// Let json.Unmarshal describe the syntax error
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// numberValue returns the value of a numeric ID literal: an int64 for integers within range, a
+// float64 for other numbers a float64 holds exactly, and the literal as a json.Number otherwise.
+func numberValue(text string) any {
+	if n, err := strconv.ParseInt(text, decimal, 64); err == nil {
+		return n
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || !fitsFloat64(text, f) {
+		return json.Number(text)
+	}
+	if f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+		return int64(f)
+	}
+	return f
+}
+
+// fitsFloat64 reports whether the number literal text, parsed into f, is held exactly enough by
+// f to be re-encoded without losing precision.
+func fitsFloat64(text string, f float64) bool {
+	digits := significantDigits(text)
+	if f == 0 {
+		return digits == 0
+	}
+	return digits <= maxExactDigits
+}
+
+// significantDigits returns the number of significant digits of the mantissa of the number
+// literal text.
+func significantDigits(text string) int {
+	first, last := -1, -1
+	position := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == 'e' || c == 'E' {
+			break
+		}
+		if c < '0' || c > '9' {
+			continue
+		}
+		if c != '0' {
+			if first < 0 {
+				first = position
+			}
+			last = position
+		}
+		position++
+	}
+	if first < 0 {
+		return 0
+	}
+	return last - first + 1
+}
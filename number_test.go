@@ -0,0 +1,140 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bigIntLiteral = "12345678901234567890"
+
+func TestNumberValue(t *testing.T) {
+	tests := map[string]any{
+		"42":                    int64(42),
+		"-7":                    int64(-7),
+		"1.0":                   int64(1),
+		"1e3":                   int64(1000),
+		"1.5":                   1.5,
+		"0.1":                   0.1,
+		"0":                     int64(0),
+		bigIntLiteral:           json.Number(bigIntLiteral),
+		"9223372036854775808":   json.Number("9223372036854775808"),
+		"1.0000000000000000001": json.Number("1.0000000000000000001"),
+		"1e-400":                json.Number("1e-400"),
+		"1e400":                 json.Number("1e400"),
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, numberValue(text), text)
+	}
+}
+
+func TestBigIntIDs(t *testing.T) {
+	t.Run("Decoded without losing precision", func(t *testing.T) {
+		msg := `{"jsonrpc":"2.0","method":"a","id":` + bigIntLiteral + `}`
+		req, err := DecodeRequest([]byte(msg))
+		require.NoError(t, err)
+		assert.Equal(t, json.Number(bigIntLiteral), req.ID)
+		assert.Equal(t, bigIntLiteral, req.IDString())
+
+		n, ok := req.TypedID().BigInt()
+		require.True(t, ok)
+		assert.Equal(t, bigIntLiteral, n.String())
+	})
+
+	t.Run("Echoed exactly by servers", func(t *testing.T) {
+		srv := newTestServer(t)
+		msg := `{"jsonrpc":"2.0","method":"sum","params":[1],"id":` + bigIntLiteral + `}`
+		reply := srv.HandleMessage(context.Background(), []byte(msg))
+		assert.Contains(t, string(reply), `"id":`+bigIntLiteral)
+	})
+
+	t.Run("Sent and matched by clients", func(t *testing.T) {
+		srv := newTestServer(t)
+		transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+			assert.Contains(t, string(payload), `"id":`+bigIntLiteral)
+			return srv.HandleMessage(ctx, payload), nil
+		}}
+		client := NewClient(transport)
+		defer client.Close()
+
+		id, ok := new(big.Int).SetString(bigIntLiteral, decimal)
+		require.True(t, ok)
+		resps, err := client.CallBatch(context.Background(), []*Request{
+			NewRequestWithID("sum", []int{1, 2}, id),
+		})
+		require.NoError(t, err)
+		require.Len(t, resps, 1)
+		assert.Equal(t, json.Number(bigIntLiteral), resps[0].IDOrNil())
+		assert.Equal(t, idKey(id), idKey(resps[0].IDOrNil()))
+	})
+
+	t.Run("IDFrom accepts big integers", func(t *testing.T) {
+		id, err := IDFrom(new(big.Int).Lsh(big.NewInt(1), 70))
+		require.NoError(t, err)
+		assert.Equal(t, "1180591620717411303424", id.String())
+		_, err = IDFrom((*big.Int)(nil))
+		assert.Error(t, err)
+	})
+}
+
+func TestSetNumberDecoding(t *testing.T) {
+	msg := []byte(`{"jsonrpc":"2.0","method":"a","params":{"n":` + bigIntLiteral + `},"id":1}`)
+
+	t.Run("Defaults to float64", func(t *testing.T) {
+		assert.Equal(t, NumbersAsFloat64, GetNumberDecoding())
+		req, err := DecodeRequest(msg)
+		require.NoError(t, err)
+		params, ok := req.Params.(map[string]any)
+		require.True(t, ok)
+		assert.IsType(t, float64(0), params["n"])
+	})
+
+	for name, codec := range map[string]Codec{"Sonic": nil, "Std": StdCodec} {
+		t.Run(name+" keeps the literal of numbers", func(t *testing.T) {
+			SetCodec(codec)
+			defer SetCodec(nil)
+			SetNumberDecoding(NumbersAsJSONNumber)
+			defer SetNumberDecoding(NumbersAsFloat64)
+
+			req, err := DecodeRequest(msg)
+			require.NoError(t, err)
+			params, ok := req.Params.(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, json.Number(bigIntLiteral), params["n"])
+			assert.Equal(t, int64(1), req.ID)
+
+			var dst struct{ N *big.Int }
+			require.NoError(t, req.UnmarshalParams(&dst))
+			assert.Equal(t, bigIntLiteral, dst.N.String())
+
+			reply := `{"jsonrpc":"2.0","result":[` + bigIntLiteral + `],"id":1}`
+			resp, err := DecodeResponse([]byte(reply))
+			require.NoError(t, err)
+			var result any
+			require.NoError(t, resp.UnmarshalResult(&result))
+			assert.Equal(t, []any{json.Number(bigIntLiteral)}, result)
+		})
+	}
+
+	t.Run("Kept across profile changes", func(t *testing.T) {
+		SetNumberDecoding(NumbersAsJSONNumber)
+		defer SetNumberDecoding(NumbersAsFloat64)
+		SetPerformanceProfile(ProfileBalanced)
+		defer SetPerformanceProfile(ProfileDefault)
+
+		var v any
+		require.NoError(t, GetCodec().Unmarshal([]byte(bigIntLiteral), &v))
+		assert.Equal(t, json.Number(bigIntLiteral), v)
+	})
+
+	t.Run("Std codec rejects invalid data", func(t *testing.T) {
+		SetNumberDecoding(NumbersAsJSONNumber)
+		defer SetNumberDecoding(NumbersAsFloat64)
+		var v any
+		assert.Error(t, StdCodec.Unmarshal([]byte(`[1] [2]`), &v))
+	})
+}
@@ -0,0 +1,109 @@
+package jsonrpc
+
+import (
+	"context"
+	"time"
+)
+
+// Observer is notified of the calls and batches a Server handles or a Client sends, for
+// instrumentation such as metrics. Unlike middleware and interceptors, an observer sees calls
+// with their final outcome, including requests turned away by timeouts or concurrency limits.
+// Methods are called synchronously, so they should return quickly, and concurrently, so they
+// must be safe for concurrent use.
+type Observer interface {
+	// CallStarted is called when a request or notification is dispatched or sent.
+	CallStarted(ctx context.Context, req *Request)
+
+	// CallFinished is called once the call has completed.
+	CallFinished(ctx context.Context, event CallEvent)
+
+	// BatchStarted is called when a batch is dispatched or sent, with its number of members.
+	// On the server, its members are then observed individually.
+	BatchStarted(ctx context.Context, size int)
+}
+
+// CallEvent describes a completed call.
+type CallEvent struct {
+	// Request is the request or notification.
+	Request *Request
+
+	// Response is the response to the request, nil for notifications and for client calls that
+	// failed before receiving one.
+	Response *Response
+
+	// Err is the error of the call: on servers, the error returned by the handler, which is also
+	// set for notifications; on clients, the failure to send the call or receive its response.
+	Err error
+
+	// Duration is the time the call took.
+	Duration time.Duration
+}
+
+// WithObserver makes the server report every request it handles and every batch it receives to
+// obs. Several observers may be added, and are notified in the order added.
+func WithObserver(obs Observer) ServerOption {
+	return func(s *Server) {
+		s.observer = joinObservers(s.observer, obs)
+	}
+}
+
+// WithClientObserver makes the client report every Call, Notify, and batch it sends to obs. Calls
+// are observed once around all interceptors, so that retried calls are observed once. Several
+// observers may be added, and are notified in the order added.
+func WithClientObserver(obs Observer) ClientOption {
+	return func(c *Client) {
+		c.observer = joinObservers(c.observer, obs)
+	}
+}
+
+// observers notifies several observers in turn.
+type observers []Observer
+
+// joinObservers returns an observer notifying current, if any, then obs.
+func joinObservers(current, obs Observer) Observer {
+	switch joined := current.(type) {
+	case nil:
+		return obs
+	case observers:
+		return append(joined[:len(joined):len(joined)], obs)
+	default:
+		return observers{current, obs}
+	}
+}
+
+// CallStarted implements Observer.
+func (o observers) CallStarted(ctx context.Context, req *Request) {
+	for _, obs := range o {
+		obs.CallStarted(ctx, req)
+	}
+}
+
+// CallFinished implements Observer.
+func (o observers) CallFinished(ctx context.Context, event CallEvent) {
+	for _, obs := range o {
+		obs.CallFinished(ctx, event)
+	}
+}
+
+// BatchStarted implements Observer.
+func (o observers) BatchStarted(ctx context.Context, size int) {
+	for _, obs := range o {
+		obs.BatchStarted(ctx, size)
+	}
+}
+
+// observe wraps an invoker so that the observer sees every call made through it.
+func observe(obs Observer, next Invoker) Invoker {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		start := time.Now()
+		obs.CallStarted(ctx, req)
+		resp, err := next(ctx, req)
+		obs.CallFinished(ctx, CallEvent{
+			Request:  req,
+			Response: resp,
+			Err:      err,
+			Duration: time.Since(start),
+		})
+		return resp, err
+	}
+}
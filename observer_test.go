@@ -0,0 +1,208 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records the events it is notified of.
+type recordingObserver struct {
+	mu       sync.Mutex
+	started  []string
+	finished []CallEvent
+	batches  []int
+}
+
+func (o *recordingObserver) CallStarted(_ context.Context, req *Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started = append(o.started, req.Method)
+}
+
+func (o *recordingObserver) CallFinished(_ context.Context, event CallEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.finished = append(o.finished, event)
+}
+
+func (o *recordingObserver) BatchStarted(_ context.Context, size int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.batches = append(o.batches, size)
+}
+
+// event returns the finished event of method.
+func (o *recordingObserver) event(t *testing.T, method string) CallEvent {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, event := range o.finished {
+		if event.Request.Method == method {
+			return event
+		}
+	}
+	t.Fatalf("no event for %s", method)
+	return CallEvent{}
+}
+
+func TestServer_Observer(t *testing.T) {
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+
+	newServer := func(obs Observer) *Server {
+		srv := NewServer(WithObserver(obs))
+		ok := HandlerFunc(func(context.Context, *Request) (any, error) {
+			return "fine", nil
+		})
+		fail := HandlerFunc(func(context.Context, *Request) (any, error) {
+			return nil, errBoom
+		})
+		require.NoError(t, srv.Register("ok", ok))
+		require.NoError(t, srv.Register("fail", fail))
+		return srv
+	}
+
+	t.Run("Requests", func(t *testing.T) {
+		obs := &recordingObserver{}
+		srv := newServer(obs)
+
+		srv.HandleRequest(ctx, NewRequestWithID("ok", nil, 1))
+		srv.HandleRequest(ctx, NewRequestWithID("fail", nil, 2))
+
+		assert.Equal(t, []string{"ok", "fail"}, obs.started)
+		ok := obs.event(t, "ok")
+		require.NotNil(t, ok.Response)
+		assert.Nil(t, ok.Response.Err())
+		assert.NoError(t, ok.Err)
+		assert.Positive(t, ok.Duration)
+
+		fail := obs.event(t, "fail")
+		require.NotNil(t, fail.Response)
+		assert.Equal(t, ServerSideException, fail.Response.Err().Code)
+		assert.ErrorIs(t, fail.Err, errBoom)
+	})
+
+	t.Run("Notifications report handler errors", func(t *testing.T) {
+		obs := &recordingObserver{}
+		srv := newServer(obs)
+
+		assert.Nil(t, srv.HandleRequest(ctx, NewNotification("fail", nil)))
+
+		event := obs.event(t, "fail")
+		assert.Nil(t, event.Response)
+		assert.ErrorIs(t, event.Err, errBoom)
+	})
+
+	t.Run("Unknown methods", func(t *testing.T) {
+		obs := &recordingObserver{}
+		srv := newServer(obs)
+
+		srv.HandleRequest(ctx, NewRequestWithID("missing", nil, 1))
+		event := obs.event(t, "missing")
+		assert.Equal(t, MethodNotFound, event.Response.Err().Code)
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		obs := &recordingObserver{}
+		srv := newServer(obs)
+
+		msg := `[{"jsonrpc":"2.0","method":"ok","id":1},{"jsonrpc":"2.0","method":"fail","id":2}]`
+		srv.HandleMessage(ctx, []byte(msg))
+		assert.Equal(t, []int{2}, obs.batches)
+		assert.Len(t, obs.finished, 2)
+	})
+}
+
+func TestClient_Observer(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer()
+	require.NoError(t, srv.Register("ok", HandlerFunc(func(context.Context, *Request) (any, error) {
+		return "fine", nil
+	})))
+	errDown := errors.New("down")
+
+	t.Run("Calls", func(t *testing.T) {
+		obs := &recordingObserver{}
+		client := NewClient(&funcTransport{fn: func(ctx context.Context, payload []byte) (
+			[]byte,
+			error,
+		) {
+			return srv.HandleMessage(ctx, payload), nil
+		}}, WithClientObserver(obs))
+
+		require.NoError(t, client.Call(ctx, "ok", nil, nil))
+		require.Error(t, client.Call(ctx, "missing", nil, nil))
+
+		ok := obs.event(t, "ok")
+		require.NotNil(t, ok.Response)
+		assert.Nil(t, ok.Response.Err())
+		missing := obs.event(t, "missing")
+		require.NotNil(t, missing.Response)
+		assert.Equal(t, MethodNotFound, missing.Response.Err().Code)
+	})
+
+	t.Run("Transport failures", func(t *testing.T) {
+		obs := &recordingObserver{}
+		client := NewClient(&funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return nil, errDown
+		}}, WithClientObserver(obs))
+
+		require.Error(t, client.Call(ctx, "ok", nil, nil))
+		event := obs.event(t, "ok")
+		assert.Nil(t, event.Response)
+		assert.ErrorIs(t, event.Err, errDown)
+	})
+
+	t.Run("Observed once around interceptors", func(t *testing.T) {
+		obs := &recordingObserver{}
+		twice := func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				_, _ = next(ctx, req)
+				return next(ctx, req)
+			}
+		}
+		client := NewClient(&funcTransport{fn: func(ctx context.Context, payload []byte) (
+			[]byte,
+			error,
+		) {
+			return srv.HandleMessage(ctx, payload), nil
+		}}, WithClientObserver(obs), WithInterceptors(twice))
+
+		require.NoError(t, client.Call(ctx, "ok", nil, nil))
+		assert.Equal(t, []string{"ok"}, obs.started)
+		assert.Len(t, obs.finished, 1)
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		obs := &recordingObserver{}
+		client := NewClient(&funcTransport{fn: func(ctx context.Context, payload []byte) (
+			[]byte,
+			error,
+		) {
+			return srv.HandleMessage(ctx, payload), nil
+		}}, WithClientObserver(obs))
+
+		batch := client.NewBatch()
+		batch.Add("ok", nil)
+		batch.Add("ok", nil)
+		batch.AddNotification("ok", nil)
+		require.NoError(t, client.SendBatch(ctx, batch))
+		assert.Equal(t, []int{3}, obs.batches)
+	})
+}
+
+func TestObserver_Several(t *testing.T) {
+	first, second, third := &recordingObserver{}, &recordingObserver{}, &recordingObserver{}
+	srv := NewServer(WithObserver(first), WithObserver(second), WithObserver(third))
+
+	srv.HandleRequest(context.Background(), NewRequestWithID("missing", nil, 1))
+	for _, obs := range []*recordingObserver{first, second, third} {
+		assert.Equal(t, []string{"missing"}, obs.started)
+		assert.Len(t, obs.finished, 1)
+	}
+}
@@ -0,0 +1,593 @@
+// Package codegen generates Go bindings from OpenRPC documents: the types of their schemas, a
+// typed client wrapping a jsonrpc.Client, and a server interface with a function registering its
+// implementations on a jsonrpc.Server. It backs the jsonrpc-gen command.
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/jkbrsn/jsonrpc/openrpc"
+)
+
+// DefaultName is the name the generated client and server are derived from when Options.Name is
+// empty.
+const DefaultName = "Service"
+
+// Import paths of the generated code.
+const (
+	importContext = "context"
+	importTime    = "time"
+)
+
+const (
+	// omitEmpty is the json tag option of optional fields.
+	omitEmpty = ",omitempty"
+
+	// listSep separates the parameters and arguments of the generated functions.
+	listSep = ", "
+)
+
+// Options configures Generate.
+type Options struct {
+	// Package is the name of the generated package. It is required.
+	Package string
+
+	// Name prefixes the generated client and server: NameClient, NewNameClient, NameServer, and
+	// RegisterNameServer. Defaults to DefaultName.
+	Name string
+}
+
+// Generate returns the formatted Go source of the bindings of doc. Each method of doc becomes a
+// client method and a server interface method, taking its params as a NameParams struct, or as
+// individual arguments for by-position methods, and returning its result type. Every schema of the
+// document's components becomes a named type, as do inline object schemas with properties.
+func Generate(doc *openrpc.Document, opts Options) ([]byte, error) {
+	if doc == nil {
+		return nil, errors.New("document cannot be nil")
+	}
+	if !token.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("invalid package name %q", opts.Package)
+	}
+	name := opts.Name
+	if name == "" {
+		name = DefaultName
+	}
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return nil, fmt.Errorf("invalid name %q: must be an exported identifier", name)
+	}
+
+	g := newGenerator(doc)
+	if err := g.components(); err != nil {
+		return nil, err
+	}
+	for i := range doc.Methods {
+		if err := g.method(&doc.Methods[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	f := file{
+		Package: opts.Package,
+		Name:    name,
+		Title:   doc.Info.Title,
+		Imports: g.importList(),
+		Types:   g.types,
+		Methods: g.methods,
+	}
+	if f.Title == "" {
+		f.Title = "the API"
+	}
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// file is the data of fileTemplate.
+type file struct {
+	Package string
+	Name    string
+	Title   string
+	Imports []string
+	Types   []*typeDecl
+	Methods []*method
+}
+
+// typeDecl is a generated type: a struct if it has fields, otherwise defined by Underlying. Its
+// doc comment starts with its name followed by Summary.
+type typeDecl struct {
+	Name       string
+	Summary    string
+	Doc        string
+	Fields     []field
+	Underlying string
+}
+
+// field is a field of a generated struct.
+type field struct {
+	Name string
+	Type string
+	Tag  string
+	Doc  string
+}
+
+// method holds the generated code of a method, with parameter and argument lists starting with
+// a comma so that they follow the context.
+type method struct {
+	Name       string
+	GoName     string
+	Doc        string
+	Deprecated bool
+	Params     string
+	Result     string
+	Returns    string
+	Signature  string
+	ClientArgs string
+	ServerArgs string
+}
+
+// generator collects the declarations generated from a document.
+type generator struct {
+	doc     *openrpc.Document
+	names   map[string]string
+	types   []*typeDecl
+	methods []*method
+	imports map[string]bool
+}
+
+// newGenerator creates a generator for doc.
+func newGenerator(doc *openrpc.Document) *generator {
+	return &generator{
+		doc:     doc,
+		names:   make(map[string]string),
+		imports: make(map[string]bool),
+	}
+}
+
+// importList returns the sorted standard library import paths of the generated code.
+func (g *generator) importList() []string {
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// claim reserves the Go identifier name for what, failing if it is already taken.
+func (g *generator) claim(name, what string) error {
+	if other, ok := g.names[name]; ok {
+		return fmt.Errorf("%s and %s both map to the Go name %s", other, what, name)
+	}
+	g.names[name] = what
+	return nil
+}
+
+// components declares a named type for every schema of the document's components.
+func (g *generator) components() error {
+	if g.doc.Components == nil {
+		return nil
+	}
+	names := make([]string, 0, len(g.doc.Components.Schemas))
+	for name := range g.doc.Components.Schemas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		goName, err := exportedName(name)
+		if err != nil {
+			return err
+		}
+		if err := g.claim(goName, "schema "+name); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		goName, _ := exportedName(name)
+		if _, err := g.declare(goName, "schema "+name, g.doc.Components.Schemas[name]); err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// declare adds the named type declaration of schema, generated from origin.
+func (g *generator) declare(name, origin string, schema *openrpc.Schema) (string, error) {
+	decl := &typeDecl{Name: name, Summary: "is generated from " + origin + "."}
+	if schema != nil {
+		decl.Doc = schema.Description
+	}
+	if schema != nil && schema.Ref == "" && len(schema.Properties) > 0 {
+		fields, err := g.fields(name, schema)
+		if err != nil {
+			return "", err
+		}
+		decl.Fields = fields
+	} else {
+		underlying, err := g.goType(schema, name)
+		if err != nil {
+			return "", err
+		}
+		decl.Underlying = underlying
+	}
+	g.types = append(g.types, decl)
+	return name, nil
+}
+
+// fields returns the struct fields of the properties of an object schema, sorted by name.
+func (g *generator) fields(owner string, schema *openrpc.Schema) ([]field, error) {
+	props := make([]string, 0, len(schema.Properties))
+	for prop := range schema.Properties {
+		props = append(props, prop)
+	}
+	slices.Sort(props)
+
+	fields := make([]field, 0, len(props))
+	seen := make(map[string]bool, len(props))
+	for _, prop := range props {
+		name, err := exportedName(prop)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("properties of %s collide on the Go name %s", owner, name)
+		}
+		seen[name] = true
+
+		sub := schema.Properties[prop]
+		typ, err := g.goType(sub, owner+name)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", prop, err)
+		}
+		f := field{Name: name, Type: typ, Tag: prop}
+		if !slices.Contains(schema.Required, prop) {
+			f.Tag += omitEmpty
+		}
+		if sub != nil {
+			f.Doc = sub.Description
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// goType returns the Go type of schema, declaring inline object schemas with properties as a type
+// named hint.
+func (g *generator) goType(schema *openrpc.Schema, hint string) (string, error) {
+	if schema == nil {
+		return "any", nil
+	}
+	if schema.Ref != "" {
+		return g.refType(schema.Ref)
+	}
+	switch schema.Type {
+	case "boolean":
+		return "bool", nil
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "float64", nil
+	case "string":
+		return g.stringType(schema), nil
+	case "array":
+		items, err := g.goType(schema.Items, hint+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + items, nil
+	case "object":
+		return g.objectType(schema, hint)
+	case "", "null":
+		return "any", nil
+	default:
+		return "", fmt.Errorf("unsupported schema type %q", schema.Type)
+	}
+}
+
+// refType returns the Go type of a reference to a schema of the components.
+func (g *generator) refType(ref string) (string, error) {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok || g.doc.Components == nil || g.doc.Components.Schemas[name] == nil {
+		return "", fmt.Errorf("unresolved reference %q", ref)
+	}
+	return exportedName(name)
+}
+
+// stringType returns the Go type of a string schema, decoding its known formats and encodings.
+func (g *generator) stringType(schema *openrpc.Schema) string {
+	switch {
+	case schema.ContentEncoding == "base64":
+		return "[]byte"
+	case schema.Format == "date-time":
+		g.imports[importTime] = true
+		return "time.Time"
+	default:
+		return "string"
+	}
+}
+
+// objectType returns the Go type of an object schema: a struct named hint if it has properties,
+// otherwise a map.
+func (g *generator) objectType(schema *openrpc.Schema, hint string) (string, error) {
+	if len(schema.Properties) == 0 {
+		values, err := g.goType(schema.AdditionalProperties, hint+"Value")
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + values, nil
+	}
+	if err := g.claim(hint, "inline schema "+hint); err != nil {
+		return "", err
+	}
+	return g.declare(hint, "an inline schema", schema)
+}
+
+// method generates the client and server code of m.
+func (g *generator) method(m *openrpc.Method) error {
+	goName, err := exportedName(m.Name)
+	if err != nil {
+		return err
+	}
+	if err := g.claim(goName, "method "+m.Name); err != nil {
+		return err
+	}
+	g.imports[importContext] = true
+
+	out := &method{
+		Name:       m.Name,
+		GoName:     goName,
+		Doc:        firstNonEmpty(m.Summary, m.Description),
+		Deprecated: m.Deprecated,
+		Returns:    "error",
+		ClientArgs: "nil",
+	}
+	if err := g.methodParams(m, out); err != nil {
+		return fmt.Errorf("method %s: %w", m.Name, err)
+	}
+	if m.Result != nil {
+		result, err := g.goType(m.Result.Schema, goName+"Result")
+		if err != nil {
+			return fmt.Errorf("method %s: result: %w", m.Name, err)
+		}
+		out.Result = result
+		out.Returns = "(" + result + ", error)"
+	}
+	g.methods = append(g.methods, out)
+	return nil
+}
+
+// methodParams declares the params struct of m and sets the parameter and argument lists of out:
+// the struct itself, or for by-position methods its fields one by one.
+func (g *generator) methodParams(m *openrpc.Method, out *method) error {
+	if len(m.Params) == 0 {
+		return nil
+	}
+	out.Params = out.GoName + "Params"
+	if err := g.claim(out.Params, "params of "+m.Name); err != nil {
+		return err
+	}
+
+	decl := &typeDecl{Name: out.Params, Summary: "are the params of " + m.Name + "."}
+	var sig, clientArgs, serverArgs []string
+	for _, param := range m.Params {
+		name, err := exportedName(param.Name)
+		if err != nil {
+			return err
+		}
+		typ, err := g.goType(param.Schema, out.GoName+name)
+		if err != nil {
+			return fmt.Errorf("param %s: %w", param.Name, err)
+		}
+		f := field{Name: name, Type: typ, Tag: param.Name, Doc: param.Description}
+		if !param.Required && m.ParamStructure != openrpc.ByPosition {
+			f.Tag += omitEmpty
+		}
+		decl.Fields = append(decl.Fields, f)
+		arg := argName(param.Name)
+		sig = append(sig, arg+" "+typ)
+		clientArgs = append(clientArgs, arg)
+		serverArgs = append(serverArgs, "params."+name)
+	}
+	g.types = append(g.types, decl)
+
+	if m.ParamStructure == openrpc.ByPosition {
+		out.Signature = listSep + strings.Join(sig, listSep)
+		out.ClientArgs = "jsonrpc.Positional(" + strings.Join(clientArgs, listSep) + ")"
+		out.ServerArgs = listSep + strings.Join(serverArgs, listSep)
+		return nil
+	}
+	out.Signature = listSep + "params " + out.Params
+	out.ClientArgs = "params"
+	out.ServerArgs = listSep + "params"
+	return nil
+}
+
+// initialisms are the words written in upper case in Go names.
+var initialisms = map[string]bool{
+	"api": true, "http": true, "id": true, "ip": true, "json": true, "rpc": true, "uri": true,
+	"url": true, "uuid": true,
+}
+
+// words splits name on every character that is not a letter or digit.
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// exportedName converts a JSON name such as "user.get" or "eth_getBalance" into an exported Go
+// identifier such as UserGet or EthGetBalance.
+func exportedName(name string) (string, error) {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if initialisms[strings.ToLower(word)] {
+			_, _ = b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		_, _ = b.WriteString(string(runes))
+	}
+	goName := b.String()
+	if goName == "" {
+		return "", fmt.Errorf("cannot derive a Go name from %q", name)
+	}
+	if !unicode.IsLetter([]rune(goName)[0]) {
+		goName = "X" + goName
+	}
+	return goName, nil
+}
+
+// reservedArgs are the identifiers the generated methods use besides their arguments.
+var reservedArgs = map[string]bool{
+	"c": true, "context": true, "ctx": true, "jsonrpc": true, "params": true, "time": true,
+}
+
+// argName converts a JSON param name into an unexported Go identifier for an argument.
+func argName(name string) string {
+	goName, err := exportedName(name)
+	if err != nil {
+		return "arg"
+	}
+	runes := []rune(goName)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		upper-- // keep the start of the next word in "IDValue" -> "idValue"
+	}
+	arg := strings.ToLower(string(runes[:upper])) + string(runes[upper:])
+	if reservedArgs[arg] || token.IsKeyword(arg) {
+		arg += "Arg"
+	}
+	return arg
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// comment formats text as comment lines indented by indent.
+func comment(indent, text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		_, _ = b.WriteString(indent + "//")
+		if line = strings.TrimRightFunc(line, unicode.IsSpace); line != "" {
+			_, _ = b.WriteString(" " + line)
+		}
+		_, _ = b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// fileTemplate renders a file of bindings; its output is formatted by go/format.
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"comment": comment,
+}).Parse(`// Code generated by jsonrpc-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+
+	"github.com/jkbrsn/jsonrpc"
+)
+{{range .Types}}
+// {{.Name}} {{.Summary}}
+{{with .Doc}}//
+{{comment "" .}}{{end -}}
+type {{.Name}} {{if .Fields}}struct {
+{{- range .Fields}}
+{{with .Doc}}{{comment "\t" .}}{{end}}	{{.Name}} {{.Type}} ` + "`json:\"{{.Tag}}\"`" + `
+{{- end}}
+}{{else}}{{.Underlying}}{{end}}
+{{end}}
+// {{.Name}}Client calls the methods of {{.Title}} through a jsonrpc.Client.
+type {{.Name}}Client struct {
+	client *jsonrpc.Client
+}
+
+// New{{.Name}}Client returns a {{.Name}}Client sending its calls with client.
+func New{{.Name}}Client(client *jsonrpc.Client) *{{.Name}}Client {
+	return &{{.Name}}Client{client: client}
+}
+{{range .Methods}}
+// {{.GoName}} calls {{.Name}}.
+{{- with .Doc}}
+//
+{{comment "" .}}{{else}}
+{{end}}
+{{- if .Deprecated}}//
+// Deprecated: {{.Name}} is deprecated.
+{{end -}}
+func (c *{{$.Name}}Client) {{.GoName}}(ctx context.Context{{.Signature}}) {{.Returns}} {
+{{- if .Result}}
+	return jsonrpc.Call[{{.Result}}](ctx, c.client, {{printf "%q" .Name}}, {{.ClientArgs}})
+{{- else}}
+	return c.client.Call(ctx, {{printf "%q" .Name}}, {{.ClientArgs}}, nil)
+{{- end}}
+}
+{{end}}
+// {{.Name}}Server is implemented by the handlers of the methods of {{.Title}}.
+type {{.Name}}Server interface {
+{{- range $i, $m := .Methods}}
+{{- if $i}}
+{{end}}
+	// {{.GoName}} handles {{.Name}}.
+{{- with .Doc}}
+	//
+{{comment "\t" .}}{{else}}
+{{end}}
+{{- if .Deprecated}}	//
+	// Deprecated: {{.Name}} is deprecated.
+{{end -}}
+	{{.GoName}}(ctx context.Context{{.Signature}}) {{.Returns}}
+{{- end}}
+}
+
+// Register{{.Name}}Server registers the methods of impl on srv.
+func Register{{.Name}}Server(srv *jsonrpc.Server, impl {{.Name}}Server) error {
+{{- range .Methods}}
+	if err := srv.RegisterFunc({{printf "%q" .Name}}, func(ctx context.Context,
+	{{- if .Params}} req{{else}} _{{end}} *jsonrpc.Request) (any, error) {
+{{- if .Params}}
+		params, err := jsonrpc.DecodeParams[{{.Params}}](req)
+		if err != nil {
+			return nil, err
+		}
+{{- end}}
+{{- if .Result}}
+		return impl.{{.GoName}}(ctx{{.ServerArgs}})
+{{- else}}
+		return nil, impl.{{.GoName}}(ctx{{.ServerArgs}})
+{{- end}}
+	}); err != nil {
+		return err
+	}
+{{- end}}
+	return nil
+}
+`))
@@ -0,0 +1,131 @@
+package codegen
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc/openrpc"
+)
+
+// petstoreDir holds the spec and the generated bindings tested by the petstore package.
+var petstoreDir = filepath.Join("internal", "petstore")
+
+func TestGenerate(t *testing.T) {
+	t.Run("Generated petstore bindings are up to date", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(petstoreDir, "petstore.json"))
+		require.NoError(t, err)
+		var doc openrpc.Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+
+		src, err := Generate(&doc, Options{Package: "petstore", Name: "Petstore"})
+		require.NoError(t, err)
+		want, err := os.ReadFile(filepath.Join(petstoreDir, "petstore.go"))
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(src), "run go generate in %s", petstoreDir)
+	})
+
+	t.Run("Default name and minimal document", func(t *testing.T) {
+		doc := &openrpc.Document{Methods: []openrpc.Method{{Name: "ping"}}}
+		src, err := Generate(doc, Options{Package: "api"})
+		require.NoError(t, err)
+
+		file, err := parser.ParseFile(token.NewFileSet(), "api.go", src, parser.ParseComments)
+		require.NoError(t, err)
+		assert.Equal(t, "api", file.Name.Name)
+		assert.Contains(t, string(src), "type ServiceClient struct")
+		assert.Contains(t, string(src), "func RegisterServiceServer(")
+		assert.Contains(t, string(src), "Ping(ctx context.Context) error")
+	})
+
+	t.Run("Either params are passed as a struct", func(t *testing.T) {
+		doc := &openrpc.Document{Methods: []openrpc.Method{{
+			Name: "sum",
+			Params: []openrpc.ContentDescriptor{
+				{Name: "a", Required: true, Schema: &openrpc.Schema{Type: "number"}},
+				{Name: "b", Schema: &openrpc.Schema{Type: "number"}},
+			},
+			Result:         &openrpc.ContentDescriptor{Name: "sum"},
+			ParamStructure: openrpc.Either,
+		}}}
+		src, err := Generate(doc, Options{Package: "api"})
+		require.NoError(t, err)
+		assert.Contains(t, string(src), "Sum(ctx context.Context, params SumParams) (any, error)")
+		assert.Contains(t, string(src), "B float64 `json:\"b,omitempty\"`")
+	})
+
+	t.Run("Invalid documents and options fail", func(t *testing.T) {
+		method := func(name string, schema *openrpc.Schema) openrpc.Method {
+			return openrpc.Method{Name: name, Result: &openrpc.ContentDescriptor{Schema: schema}}
+		}
+		ref := &openrpc.Schema{Ref: "#/components/schemas/Missing"}
+		collide := []openrpc.Method{{Name: "user.get"}, {Name: "user_get"}}
+
+		tests := map[string]struct {
+			doc  *openrpc.Document
+			opts Options
+		}{
+			"nil document":    {opts: Options{Package: "api"}},
+			"missing package": {doc: &openrpc.Document{}},
+			"unexported name": {doc: &openrpc.Document{}, opts: Options{Package: "api", Name: "x"}},
+			"unresolved reference": {
+				doc:  &openrpc.Document{Methods: []openrpc.Method{method("get", ref)}},
+				opts: Options{Package: "api"},
+			},
+			"unsupported type": {
+				doc: &openrpc.Document{Methods: []openrpc.Method{
+					method("get", &openrpc.Schema{Type: "tuple"}),
+				}},
+				opts: Options{Package: "api"},
+			},
+			"colliding names": {
+				doc:  &openrpc.Document{Methods: collide},
+				opts: Options{Package: "api"},
+			},
+			"unnamed method": {
+				doc:  &openrpc.Document{Methods: []openrpc.Method{{Name: "."}}},
+				opts: Options{Package: "api"},
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := Generate(tt.doc, tt.opts)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestNames(t *testing.T) {
+	exported := map[string]string{
+		"user.get":       "UserGet",
+		"eth_getBalance": "EthGetBalance",
+		"user_id":        "UserID",
+		"2fa":            "X2fa",
+	}
+	for in, want := range exported {
+		got, err := exportedName(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, in)
+	}
+
+	args := map[string]string{
+		"name":      "name",
+		"user_id":   "userID",
+		"id":        "id",
+		"idValue":   "idValue",
+		"type":      "typeArg",
+		"ctx":       "ctxArg",
+		"params":    "paramsArg",
+		"from-addr": "fromAddr",
+	}
+	for in, want := range args {
+		assert.Equal(t, want, argName(in), in)
+	}
+}
@@ -0,0 +1,5 @@
+// Package petstore holds the bindings jsonrpc-gen generates from petstore.json, compiled and
+// exercised by the tests of the codegen package.
+package petstore
+
+//go:generate go run ../../../../cmd/jsonrpc-gen -spec petstore.json -name Petstore -out petstore.go
@@ -0,0 +1,156 @@
+// Code generated by jsonrpc-gen. DO NOT EDIT.
+
+package petstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Pet is generated from schema Pet.
+//
+// A pet of the store.
+type Pet struct {
+	Added time.Time `json:"added"`
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Tags  []string  `json:"tags,omitempty"`
+}
+
+// PetAddParams are the params of pet.add.
+type PetAddParams struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// PetGetParams are the params of pet.get.
+type PetGetParams struct {
+	ID int64 `json:"id"`
+}
+
+// PetRemoveParams are the params of pet.remove.
+type PetRemoveParams struct {
+	ID int64 `json:"id"`
+}
+
+// StoreStatsResult is generated from an inline schema.
+type StoreStatsResult struct {
+	// Count is the number of pets.
+	Count int64            `json:"count"`
+	Tags  map[string]int64 `json:"tags,omitempty"`
+}
+
+// PetstoreClient calls the methods of Pet Store through a jsonrpc.Client.
+type PetstoreClient struct {
+	client *jsonrpc.Client
+}
+
+// NewPetstoreClient returns a PetstoreClient sending its calls with client.
+func NewPetstoreClient(client *jsonrpc.Client) *PetstoreClient {
+	return &PetstoreClient{client: client}
+}
+
+// PetAdd calls pet.add.
+//
+// Adds a pet to the store.
+func (c *PetstoreClient) PetAdd(ctx context.Context, params PetAddParams) (Pet, error) {
+	return jsonrpc.Call[Pet](ctx, c.client, "pet.add", params)
+}
+
+// PetGet calls pet.get.
+//
+// Returns a pet by ID.
+func (c *PetstoreClient) PetGet(ctx context.Context, id int64) (Pet, error) {
+	return jsonrpc.Call[Pet](ctx, c.client, "pet.get", jsonrpc.Positional(id))
+}
+
+// PetList calls pet.list.
+func (c *PetstoreClient) PetList(ctx context.Context) ([]Pet, error) {
+	return jsonrpc.Call[[]Pet](ctx, c.client, "pet.list", nil)
+}
+
+// PetRemove calls pet.remove.
+//
+// Removes a pet from the store.
+// Removing an unknown pet is not an error.
+//
+// Deprecated: pet.remove is deprecated.
+func (c *PetstoreClient) PetRemove(ctx context.Context, id int64) error {
+	return c.client.Call(ctx, "pet.remove", jsonrpc.Positional(id), nil)
+}
+
+// StoreStats calls store.stats.
+func (c *PetstoreClient) StoreStats(ctx context.Context) (StoreStatsResult, error) {
+	return jsonrpc.Call[StoreStatsResult](ctx, c.client, "store.stats", nil)
+}
+
+// PetstoreServer is implemented by the handlers of the methods of Pet Store.
+type PetstoreServer interface {
+	// PetAdd handles pet.add.
+	//
+	// Adds a pet to the store.
+	PetAdd(ctx context.Context, params PetAddParams) (Pet, error)
+
+	// PetGet handles pet.get.
+	//
+	// Returns a pet by ID.
+	PetGet(ctx context.Context, id int64) (Pet, error)
+
+	// PetList handles pet.list.
+	PetList(ctx context.Context) ([]Pet, error)
+
+	// PetRemove handles pet.remove.
+	//
+	// Removes a pet from the store.
+	// Removing an unknown pet is not an error.
+	//
+	// Deprecated: pet.remove is deprecated.
+	PetRemove(ctx context.Context, id int64) error
+
+	// StoreStats handles store.stats.
+	StoreStats(ctx context.Context) (StoreStatsResult, error)
+}
+
+// RegisterPetstoreServer registers the methods of impl on srv.
+func RegisterPetstoreServer(srv *jsonrpc.Server, impl PetstoreServer) error {
+	if err := srv.RegisterFunc("pet.add", func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		params, err := jsonrpc.DecodeParams[PetAddParams](req)
+		if err != nil {
+			return nil, err
+		}
+		return impl.PetAdd(ctx, params)
+	}); err != nil {
+		return err
+	}
+	if err := srv.RegisterFunc("pet.get", func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		params, err := jsonrpc.DecodeParams[PetGetParams](req)
+		if err != nil {
+			return nil, err
+		}
+		return impl.PetGet(ctx, params.ID)
+	}); err != nil {
+		return err
+	}
+	if err := srv.RegisterFunc("pet.list", func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+		return impl.PetList(ctx)
+	}); err != nil {
+		return err
+	}
+	if err := srv.RegisterFunc("pet.remove", func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		params, err := jsonrpc.DecodeParams[PetRemoveParams](req)
+		if err != nil {
+			return nil, err
+		}
+		return nil, impl.PetRemove(ctx, params.ID)
+	}); err != nil {
+		return err
+	}
+	if err := srv.RegisterFunc("store.stats", func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+		return impl.StoreStats(ctx)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
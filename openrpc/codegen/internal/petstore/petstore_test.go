@@ -0,0 +1,134 @@
+package petstore
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// errNotFound is returned by store for unknown pets.
+var errNotFound = &jsonrpc.Error{Code: 404, Message: "pet not found"}
+
+// store implements PetstoreServer in memory.
+type store struct {
+	mu   sync.Mutex
+	pets []Pet
+}
+
+func (s *store) PetAdd(_ context.Context, params PetAddParams) (Pet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pet := Pet{
+		ID:    int64(len(s.pets) + 1),
+		Name:  params.Name,
+		Tags:  params.Tags,
+		Added: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+	}
+	s.pets = append(s.pets, pet)
+	return pet, nil
+}
+
+func (s *store) PetGet(_ context.Context, id int64) (Pet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.pets, func(p Pet) bool { return p.ID == id })
+	if i < 0 {
+		return Pet{}, errNotFound
+	}
+	return s.pets[i], nil
+}
+
+func (s *store) PetList(context.Context) ([]Pet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.pets), nil
+}
+
+func (s *store) PetRemove(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pets = slices.DeleteFunc(s.pets, func(p Pet) bool { return p.ID == id })
+	return nil
+}
+
+func (s *store) StoreStats(context.Context) (StoreStatsResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := StoreStatsResult{Count: int64(len(s.pets)), Tags: make(map[string]int64)}
+	for _, pet := range s.pets {
+		for _, tag := range pet.Tags {
+			stats.Tags[tag]++
+		}
+	}
+	return stats, nil
+}
+
+// newPetstore serves a store over HTTP and returns a generated client calling it.
+func newPetstore(t *testing.T) *PetstoreClient {
+	t.Helper()
+	srv := jsonrpc.NewServer()
+	require.NoError(t, RegisterPetstoreServer(srv, &store{}))
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	client := jsonrpc.NewClient(jsonrpc.NewHTTPTransport(ts.URL))
+	t.Cleanup(func() { _ = client.Close() })
+	return NewPetstoreClient(client)
+}
+
+func TestPetstore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Calls round trip through the generated bindings", func(t *testing.T) {
+		client := newPetstore(t)
+
+		added, err := client.PetAdd(ctx, PetAddParams{Name: "Rex", Tags: []string{"dog"}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), added.ID)
+		_, err = client.PetAdd(ctx, PetAddParams{Name: "Tom", Tags: []string{"cat"}})
+		require.NoError(t, err)
+
+		pet, err := client.PetGet(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Rex", pet.Name)
+		assert.True(t, added.Added.Equal(pet.Added))
+
+		stats, err := client.StoreStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, StoreStatsResult{
+			Count: 2,
+			Tags:  map[string]int64{"dog": 1, "cat": 1},
+		}, stats)
+
+		require.NoError(t, client.PetRemove(ctx, 1))
+		pets, err := client.PetList(ctx)
+		require.NoError(t, err)
+		require.Len(t, pets, 1)
+		assert.Equal(t, "Tom", pets[0].Name)
+	})
+
+	t.Run("Handler errors reach the client", func(t *testing.T) {
+		client := newPetstore(t)
+
+		_, err := client.PetGet(ctx, 42)
+		var rpcErr *jsonrpc.Error
+		require.True(t, errors.As(err, &rpcErr))
+		assert.Equal(t, errNotFound.Code, rpcErr.Code)
+	})
+
+	t.Run("Invalid params are rejected", func(t *testing.T) {
+		client := newPetstore(t)
+
+		err := client.client.Call(ctx, "pet.get", jsonrpc.Positional("one"), nil)
+		require.ErrorIs(t, err, jsonrpc.ErrInvalidParams)
+	})
+}
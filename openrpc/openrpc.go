@@ -0,0 +1,55 @@
+// Package openrpc holds the types of OpenRPC documents, which describe JSON-RPC APIs as specified
+// at https://spec.open-rpc.org, and builds the JSON Schemas of Go types for them.
+package openrpc
+
+// Version is the version of the OpenRPC specification the documents follow.
+const Version = "1.3.2"
+
+// Param structures of a Method, telling how its params are passed.
+const (
+	ByName     = "by-name"
+	ByPosition = "by-position"
+	Either     = "either"
+)
+
+// Document is an OpenRPC document.
+type Document struct {
+	OpenRPC    string      `json:"openrpc"`
+	Info       Info        `json:"info"`
+	Methods    []Method    `json:"methods"`
+	Components *Components `json:"components,omitempty"`
+}
+
+// Info describes the API of a Document.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Method describes a JSON-RPC method.
+type Method struct {
+	Name           string              `json:"name"`
+	Summary        string              `json:"summary,omitempty"`
+	Description    string              `json:"description,omitempty"`
+	Params         []ContentDescriptor `json:"params"`
+	Result         *ContentDescriptor  `json:"result,omitempty"`
+	Errors         []Error             `json:"errors,omitempty"`
+	ParamStructure string              `json:"paramStructure,omitempty"`
+	Deprecated     bool                `json:"deprecated,omitempty"`
+}
+
+// ContentDescriptor describes a param or the result of a Method.
+type ContentDescriptor struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Error describes an error a Method may return.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
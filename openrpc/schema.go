@@ -0,0 +1,231 @@
+package openrpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schemaRefPrefix is the prefix of references to the schemas of Components.
+const schemaRefPrefix = "#/components/schemas/"
+
+// JSON Schema types.
+const (
+	typeBoolean = "boolean"
+	typeInteger = "integer"
+	typeNumber  = "number"
+	typeString  = "string"
+	typeArray   = "array"
+	typeObject  = "object"
+)
+
+// Schema is a JSON Schema, limited to the keywords describing Go types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Components holds the schemas that the schemas of a Document refer to.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Field is a struct field as encoded in JSON.
+type Field struct {
+	// Name is the name of the field in JSON.
+	Name string
+
+	// Description is the value of the field's description tag.
+	Description string
+
+	// Required is false for fields tagged omitempty or omitzero, and for pointers.
+	Required bool
+
+	// Type is the Go type of the field.
+	Type reflect.Type
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Reflector builds the schemas of Go types, following the rules of encoding/json. Named struct
+// types are defined once in its Components and referred to, so that recursive types terminate.
+// A Reflector is not safe for concurrent use.
+type Reflector struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// NewReflector creates a Reflector with no schemas defined.
+func NewReflector() *Reflector {
+	return &Reflector{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// Components returns the schemas defined so far, or nil if there are none.
+func (r *Reflector) Components() *Components {
+	if len(r.schemas) == 0 {
+		return nil
+	}
+	return &Components{Schemas: r.schemas}
+}
+
+// Reflect returns the schema of values of type t. A nil t yields the schema accepting anything.
+func (r *Reflector) Reflect(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		return r.Reflect(t.Elem())
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: typeString, Format: "date-time"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType),
+		reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType), reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: typeString}
+	default:
+	}
+	return r.reflectKind(t)
+}
+
+// reflectKind returns the schema of the values of t according to its kind.
+func (r *Reflector) reflectKind(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: typeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return &Schema{Type: typeInteger}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: typeNumber}
+	case reflect.String:
+		return &Schema{Type: typeString}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: typeString, ContentEncoding: "base64"}
+		}
+		return &Schema{Type: typeArray, Items: r.Reflect(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: typeObject, AdditionalProperties: r.Reflect(t.Elem())}
+	case reflect.Struct:
+		return r.reflectStruct(t)
+	default:
+		// Interfaces, and kinds encoding/json rejects
+		return &Schema{}
+	}
+}
+
+// reflectStruct returns the schema of a struct type, or a reference to it for named types.
+func (r *Reflector) reflectStruct(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return r.objectSchema(t)
+	}
+	if name, ok := r.names[t]; ok {
+		return &Schema{Ref: schemaRefPrefix + name}
+	}
+
+	name := t.Name()
+	for i := 2; r.schemas[name] != nil; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+	r.names[t] = name
+	r.schemas[name] = &Schema{} // Placeholder for recursive references
+	*r.schemas[name] = *r.objectSchema(t)
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+// objectSchema returns the object schema of the fields of a struct type.
+func (r *Reflector) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: typeObject, Properties: make(map[string]*Schema)}
+	for _, field := range Fields(t) {
+		prop := r.Reflect(field.Type)
+		if field.Description != "" {
+			if prop.Ref != "" {
+				// A reference ignores its siblings
+				prop = &Schema{Ref: prop.Ref}
+			}
+			prop.Description = field.Description
+		}
+		schema.Properties[field.Name] = prop
+		if field.Required {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+	return schema
+}
+
+// Fields returns the fields of struct type t as encoded in JSON, in order, including the fields
+// of embedded structs without a name tag. Pointers to structs are dereferenced.
+func Fields(t reflect.Type) []Field {
+	if t.Kind() == reflect.Pointer {
+		return Fields(t.Elem())
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []Field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, Fields(embedded)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, Field{
+			Name:        name,
+			Description: sf.Tag.Get("description"),
+			Required:    requiredField(sf, opts),
+			Type:        sf.Type,
+		})
+	}
+	return fields
+}
+
+// requiredField reports whether a field with the given json tag options is always encoded.
+func requiredField(sf reflect.StructField, opts string) bool {
+	if sf.Type.Kind() == reflect.Pointer {
+		return false
+	}
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package openrpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type base struct {
+	ID int `json:"id"`
+}
+
+type node struct {
+	base
+	Name     string            `json:"name" description:"Display name"`
+	Parent   *node             `json:"parent"`
+	Children []node            `json:"children,omitempty"`
+	Labels   map[string]string `json:"labels,omitzero"`
+	Created  time.Time         `json:"created"`
+	Raw      json.RawMessage   `json:"raw"`
+	Blob     []byte            `json:"blob"`
+	Skipped  string            `json:"-"`
+	Untagged float64
+	hidden   bool
+}
+
+func TestReflector(t *testing.T) {
+	t.Run("Scalars and containers", func(t *testing.T) {
+		r := NewReflector()
+		assert.Equal(t, &Schema{Type: "boolean"}, r.Reflect(reflect.TypeFor[bool]()))
+		assert.Equal(t, &Schema{Type: "integer"}, r.Reflect(reflect.TypeFor[*uint8]()))
+		assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "number"}},
+			r.Reflect(reflect.TypeFor[[3]float32]()))
+		assert.Equal(t, &Schema{}, r.Reflect(reflect.TypeFor[any]()))
+		assert.Equal(t, &Schema{}, r.Reflect(nil))
+		assert.Nil(t, r.Components(), "no named structs were reflected")
+	})
+
+	t.Run("Named structs are referenced", func(t *testing.T) {
+		r := NewReflector()
+		schema := r.Reflect(reflect.TypeFor[node]())
+		assert.Equal(t, "#/components/schemas/node", schema.Ref)
+
+		components := r.Components()
+		require.NotNil(t, components)
+		def := components.Schemas["node"]
+		require.NotNil(t, def)
+		assert.Equal(t, "object", def.Type)
+		assert.ElementsMatch(t,
+			[]string{"id", "name", "children", "labels", "created", "raw", "blob", "Untagged",
+				"parent"},
+			keys(def.Properties))
+		assert.Equal(t, []string{"id", "name", "created", "raw", "blob", "Untagged"}, def.Required)
+		assert.Equal(t, "#/components/schemas/node", def.Properties["parent"].Ref,
+			"recursive types terminate")
+		assert.Equal(t, "Display name", def.Properties["name"].Description)
+		assert.Equal(t, "date-time", def.Properties["created"].Format)
+		assert.Equal(t, &Schema{}, def.Properties["raw"])
+		assert.Equal(t, "base64", def.Properties["blob"].ContentEncoding)
+		assert.Equal(t, "string", def.Properties["labels"].AdditionalProperties.Type)
+	})
+
+	t.Run("Anonymous structs are inlined", func(t *testing.T) {
+		r := NewReflector()
+		schema := r.Reflect(reflect.TypeOf(struct {
+			A string `json:"a"`
+		}{}))
+		assert.Equal(t, "object", schema.Type)
+		assert.Contains(t, schema.Properties, "a")
+	})
+}
+
+func TestFields(t *testing.T) {
+	assert.Nil(t, Fields(reflect.TypeFor[int]()))
+	fields := Fields(reflect.TypeFor[*base]())
+	require.Len(t, fields, 1)
+	assert.Equal(t, Field{Name: "id", Required: true, Type: reflect.TypeFor[int]()}, fields[0])
+}
+
+// keys returns the keys of m.
+func keys(m map[string]*Schema) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package jsonrpc
+
+import "sync"
+
+// WithOrderedReplies makes the server write the replies to the messages arriving on each stream
+// in the order the messages arrived, as some protocols require, while their handlers still run
+// concurrently. A reply completed early is held until the replies to all earlier messages are
+// written, so a slow call delays the replies after it on the same connection. Messages holding
+// only notifications get no reply and hold back nothing. HTTP requests, answered one at a time,
+// are not affected.
+func WithOrderedReplies() ServerOption {
+	return func(s *Server) {
+		s.orderedReplies = true
+	}
+}
+
+// replyQueue writes the replies to the messages of a connection in the order the messages
+// arrived.
+type replyQueue struct {
+	write func(reply []byte)
+
+	mu     sync.Mutex
+	issued uint64
+	next   uint64
+	held   map[uint64][]byte
+}
+
+// newReplyQueue returns a queue writing replies with write.
+func newReplyQueue(write func(reply []byte)) *replyQueue {
+	return &replyQueue{write: write, held: make(map[uint64][]byte)}
+}
+
+// reserve returns the sequence number of the reply to the next message to arrive.
+func (q *replyQueue) reserve() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seq := q.issued
+	q.issued++
+	return seq
+}
+
+// complete records the reply with sequence number seq, nil for none, and writes the replies
+// whose turn has come. Writes happen under the lock, so that they are never reordered.
+func (q *replyQueue) complete(seq uint64, reply []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.held[seq] = reply
+	for {
+		ready, ok := q.held[q.next]
+		if !ok {
+			return
+		}
+		delete(q.held, q.next)
+		q.next++
+		if ready != nil {
+			q.write(ready)
+		}
+	}
+}
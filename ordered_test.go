@@ -0,0 +1,78 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_OrderedReplies(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	handled := make(chan struct{}, 2)
+	srv := NewServer(WithOrderedReplies())
+	gated := func(ctx context.Context, _ *Request) (any, error) {
+		select {
+		case <-release:
+			return "first", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	require.NoError(t, srv.RegisterFunc("gated", gated))
+	record := func(context.Context, *Request) (any, error) {
+		handled <- struct{}{}
+		return "recorded", nil
+	}
+	require.NoError(t, srv.RegisterFunc("record", record))
+	clientEnd, serverEnd := newStreamPair()
+	go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+	defer clientEnd.Close()
+
+	send := func(msg string) {
+		require.NoError(t, clientEnd.WriteMessage(ctx, []byte(msg)))
+	}
+	send(`{"jsonrpc":"2.0","method":"gated","id":1}`)
+	send(`{"jsonrpc":"2.0","method":"record","params":["notified"]}`)
+	send(`{"jsonrpc":"2.0","method":"record","params":["called"],"id":2}`)
+
+	for range 2 {
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Fatal("handlers do not run concurrently")
+		}
+	}
+	close(release)
+
+	var ids []string
+	for range 2 {
+		msg, err := clientEnd.ReadMessage(ctx)
+		require.NoError(t, err)
+		var resp struct{ ID json.RawMessage }
+		require.NoError(t, json.Unmarshal(msg, &resp))
+		ids = append(ids, string(resp.ID))
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+func TestReplyQueue(t *testing.T) {
+	var written []string
+	q := newReplyQueue(func(reply []byte) { written = append(written, string(reply)) })
+	seqs := make([]uint64, 4)
+	for i := range seqs {
+		seqs[i] = q.reserve()
+	}
+
+	q.complete(seqs[2], []byte("c"))
+	q.complete(seqs[1], nil)
+	assert.Empty(t, written, "replies wait for earlier ones")
+	q.complete(seqs[0], []byte("a"))
+	assert.Equal(t, []string{"a", "c"}, written)
+	q.complete(seqs[3], []byte("d"))
+	assert.Equal(t, []string{"a", "c", "d"}, written)
+}
@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Dedup returns server middleware dropping the notifications whose dedup ID is among the last
+// size IDs it saw, so that the repeats of at-least-once delivery are handled once. The dedup ID
+// is removed from the params of the notifications it passes on, so that handlers see the params
+// as enqueued. Requests, and notifications without a dedup ID, go through as they are.
+//
+// The IDs seen are kept in memory: repeats arriving after as many other notifications as size,
+// or after a restart, are not detected.
+func Dedup(size int, opts ...Option) jsonrpc.Middleware {
+	cfg := newConfig(opts)
+	seen := newIDWindow(size)
+	return func(next jsonrpc.Handler) jsonrpc.Handler {
+		return jsonrpc.HandlerFunc(func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+			if !req.IsNotification() {
+				return next.ServeRPC(ctx, req)
+			}
+			id, params, ok := splitDedupID(req.Params, cfg.field)
+			if !ok {
+				return next.ServeRPC(ctx, req)
+			}
+			if !seen.add(id) {
+				return nil, nil
+			}
+			stripped := *req
+			stripped.Params = params
+			return next.ServeRPC(ctx, &stripped)
+		})
+	}
+}
+
+// splitDedupID returns the dedup ID held in the member field of params and the params without it,
+// reporting whether params held one.
+func splitDedupID(params any, field string) (string, any, bool) {
+	switch p := params.(type) {
+	case map[string]any:
+		id, ok := p[field].(string)
+		if !ok {
+			return "", nil, false
+		}
+		rest := make(map[string]any, len(p))
+		for key, value := range p {
+			if key != field {
+				rest[key] = value
+			}
+		}
+		return id, rest, true
+	case json.RawMessage:
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(p, &members); err != nil {
+			return "", nil, false
+		}
+		var id string
+		if err := json.Unmarshal(members[field], &id); err != nil || id == "" {
+			return "", nil, false
+		}
+		delete(members, field)
+		rest, err := json.Marshal(members)
+		if err != nil {
+			return "", nil, false
+		}
+		return id, json.RawMessage(rest), true
+	default:
+		return "", nil, false
+	}
+}
+
+// idWindow remembers the last IDs added to it.
+type idWindow struct {
+	mu   sync.Mutex
+	ids  map[string]struct{}
+	ring []string
+	next int
+}
+
+// newIDWindow returns a window of size IDs, at least one.
+func newIDWindow(size int) *idWindow {
+	return &idWindow{ids: make(map[string]struct{}), ring: make([]string, max(size, 1))}
+}
+
+// add adds id, reporting false if it is already in the window.
+func (w *idWindow) add(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.ids[id]; ok {
+		return false
+	}
+	if evicted := w.ring[w.next]; evicted != "" {
+		delete(w.ids, evicted)
+	}
+	w.ring[w.next] = id
+	w.next = (w.next + 1) % len(w.ring)
+	w.ids[id] = struct{}{}
+	return true
+}
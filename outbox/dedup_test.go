@@ -0,0 +1,68 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Drops repeats and strips the dedup ID", func(t *testing.T) {
+		srv, box := newInboxServer(t, Dedup(2))
+		for _, msg := range []string{
+			`{"jsonrpc":"2.0","method":"event","params":{"n":1,"dedup_id":"a"}}`,
+			`{"jsonrpc":"2.0","method":"event","params":{"n":1,"dedup_id":"a"}}`,
+			`{"jsonrpc":"2.0","method":"event","params":{"n":2,"dedup_id":"b"}}`,
+			`{"jsonrpc":"2.0","method":"event","params":{"n":3}}`,
+			`{"jsonrpc":"2.0","method":"event","params":{"n":3}}`,
+		} {
+			assert.Nil(t, srv.HandleMessage(ctx, []byte(msg)))
+		}
+		assert.Equal(t, []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":3}`}, box.received())
+	})
+
+	t.Run("Forgets IDs beyond its size", func(t *testing.T) {
+		srv, box := newInboxServer(t, Dedup(1, WithDedupField("key")))
+		for _, key := range []string{"a", "b", "a"} {
+			_ = srv.HandleMessage(ctx,
+				[]byte(`{"jsonrpc":"2.0","method":"event","params":{"key":"`+key+`"}}`))
+		}
+		assert.Len(t, box.received(), 3)
+	})
+
+	t.Run("Requests go through", func(t *testing.T) {
+		srv, box := newInboxServer(t, Dedup(8))
+		msg := `{"jsonrpc":"2.0","id":1,"method":"event","params":{"dedup_id":"a"}}`
+		_ = srv.HandleMessage(ctx, []byte(msg))
+		_ = srv.HandleMessage(ctx, []byte(msg))
+		assert.Equal(t, []string{`{"dedup_id":"a"}`, `{"dedup_id":"a"}`}, box.received())
+	})
+
+	t.Run("End to end with a queue", func(t *testing.T) {
+		srv, box := newInboxServer(t, Dedup(8))
+		store := NewMemoryStore()
+		msg := message("repeat")
+		require.NoError(t, store.Append(msg))
+		require.NoError(t, store.Append(msg))
+		q, err := New(jsonrpc.NewClient(&flakyTransport{srv: srv}), store)
+		require.NoError(t, err)
+		defer func() { _ = q.Close() }()
+		require.NoError(t, q.Flush(ctx))
+		assert.Equal(t, []string{`{}`}, box.received())
+	})
+
+	t.Run("Raw params", func(t *testing.T) {
+		id, params, ok := splitDedupID(json.RawMessage(`{"a":1,"dedup_id":"x"}`), "dedup_id")
+		require.True(t, ok)
+		assert.Equal(t, "x", id)
+		assert.JSONEq(t, `{"a":1}`, string(params.(json.RawMessage)))
+		_, _, ok = splitDedupID(json.RawMessage(`[1]`), "dedup_id")
+		assert.False(t, ok)
+	})
+}
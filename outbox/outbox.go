@@ -0,0 +1,314 @@
+// Package outbox queues the notifications of jsonrpc clients in durable storage and sends them
+// in the background, retrying through disconnections and restarts, for at-least-once delivery.
+//
+// A Queue stores every notification before sending it, and removes it from its Store once the
+// client has sent it. Notifications failing to be sent, such as while a reconnecting client is
+// down, are retried in order until they go through. MemoryStore keeps the queue for the life of
+// the process; FileStore also keeps it through restarts.
+//
+// At-least-once delivery means a notification may arrive more than once, such as when the process
+// stops between sending it and removing it from its store. Every queued notification therefore
+// carries a unique dedup ID as a member of its params, which receivers drop the repeats of with
+// the Dedup middleware.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+const (
+	// DefaultDedupField is the params member carrying the dedup IDs of queued notifications.
+	DefaultDedupField = "dedup_id"
+
+	// defaultRetryInterval is the wait before resending a notification that failed to be sent.
+	defaultRetryInterval = time.Second
+
+	// idBytes is the number of random bytes of a dedup ID.
+	idBytes = 16
+)
+
+var (
+	// ErrClosed is returned by Queue.Enqueue once the queue is closed.
+	ErrClosed = errors.New("outbox: queue closed")
+
+	// errParamsNotObject is returned for params that cannot carry a dedup ID.
+	errParamsNotObject = errors.New("outbox: params must be nil or encode as a JSON object")
+)
+
+// Message is a queued notification.
+type Message struct {
+	// ID is the dedup ID of the notification, also carried in its params.
+	ID string `json:"id"`
+
+	// Method is the method of the notification.
+	Method string `json:"method"`
+
+	// Params are the encoded params of the notification, dedup ID included.
+	Params json.RawMessage `json:"params"`
+}
+
+// Store keeps the messages of a Queue until they are sent. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Append adds a message at the end of the queue. Once it returns, the message must survive
+	// whatever the store is meant to survive.
+	Append(msg Message) error
+
+	// Ack removes a sent message from the queue.
+	Ack(id string) error
+
+	// Pending returns the messages not yet acknowledged, in the order they were appended.
+	Pending() ([]Message, error)
+}
+
+// config holds the options of Queue and Dedup.
+type config struct {
+	field   string
+	retry   time.Duration
+	onError func(error)
+}
+
+// Option configures New and Dedup.
+type Option func(*config)
+
+// WithDedupField sets the params member carrying the dedup IDs. Defaults to DefaultDedupField.
+// Queues and the Dedup middleware of their receivers must use the same field.
+func WithDedupField(name string) Option {
+	return func(c *config) {
+		c.field = name
+	}
+}
+
+// WithRetryInterval sets the wait before resending a notification that failed to be sent.
+// Defaults to one second.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.retry = interval
+	}
+}
+
+// WithErrorHandler sets a function called with the errors of sending notifications and of
+// acknowledging them in the store, which are otherwise dropped.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+// newConfig returns the configuration set by opts.
+func newConfig(opts []Option) *config {
+	cfg := &config{field: DefaultDedupField, retry: defaultRetryInterval}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Queue sends notifications through a client in the background, in the order they were
+// enqueued, keeping them in a Store until they are sent. A Queue is safe for concurrent use.
+type Queue struct {
+	client *jsonrpc.Client
+	store  Store
+	cfg    *config
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	pending []Message
+	changed chan struct{} // Closed and replaced whenever a message is sent
+	closed  bool
+}
+
+// New returns a queue sending notifications through client, resuming with the messages left
+// pending in store, such as by a previous run of the process.
+func New(client *jsonrpc.Client, store Store, opts ...Option) (*Queue, error) {
+	pending, err := store.Pending()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		client:  client,
+		store:   store,
+		cfg:     newConfig(opts),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		pending: pending,
+		changed: make(chan struct{}),
+	}
+	go q.run()
+	return q, nil
+}
+
+// Enqueue stores a notification for method with params under a new dedup ID, which it returns,
+// and has it sent in the background. Params must be nil or encode as a JSON object, which gets
+// the dedup ID as an extra member. Once Enqueue returns, the notification is as durable as the
+// store.
+func (q *Queue) Enqueue(method string, params any) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	encoded, err := withDedupID(params, q.cfg.field, id)
+	if err != nil {
+		return "", err
+	}
+	msg := Message{ID: id, Method: method, Params: encoded}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", ErrClosed
+	}
+	if err := q.store.Append(msg); err != nil {
+		return "", err
+	}
+	q.pending = append(q.pending, msg)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+// Len returns the number of notifications not yet sent.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Flush waits until every notification enqueued so far has been sent, or ctx is done.
+func (q *Queue) Flush(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		empty, changed := len(q.pending) == 0, q.changed
+		q.mu.Unlock()
+		if empty {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.done:
+			return ErrClosed
+		}
+	}
+}
+
+// Close stops sending, leaving the notifications not yet sent in the store, and waits for the
+// notification being sent, if any. Closing the client and the store is left to the caller.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cancel()
+	<-q.done
+	return nil
+}
+
+// run sends the pending messages in order until the queue is closed.
+func (q *Queue) run() {
+	defer close(q.done)
+	for {
+		msg, ok := q.head()
+		if !ok {
+			select {
+			case <-q.wake:
+				continue
+			case <-q.ctx.Done():
+				return
+			}
+		}
+
+		if err := q.client.Notify(q.ctx, msg.Method, msg.Params); err != nil {
+			q.report(err)
+			select {
+			case <-time.After(q.cfg.retry):
+				continue
+			case <-q.ctx.Done():
+				return
+			}
+		}
+		q.ack(msg.ID)
+	}
+}
+
+// head returns the next message to send, if any.
+func (q *Queue) head() (Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return Message{}, false
+	}
+	return q.pending[0], true
+}
+
+// ack removes the sent message with the given ID, at the head of the queue. A failure to remove
+// it from the store only causes it to be sent again after a restart, so it is reported and
+// otherwise ignored.
+func (q *Queue) ack(id string) {
+	if err := q.store.Ack(id); err != nil {
+		q.report(err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending[0] = Message{}
+	q.pending = q.pending[1:]
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// report hands err to the error handler, if any.
+func (q *Queue) report(err error) {
+	if q.cfg.onError != nil {
+		q.cfg.onError(err)
+	}
+}
+
+// newID returns a random dedup ID.
+func newID() (string, error) {
+	buf := make([]byte, idBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// withDedupID returns params encoded as a JSON object with id added as the member field.
+func withDedupID(params any, field, id string) (json.RawMessage, error) {
+	members := make(map[string]json.RawMessage)
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &members); err != nil {
+			return nil, errParamsNotObject
+		}
+		if members == nil {
+			// Params encoding as null
+			members = make(map[string]json.RawMessage)
+		}
+	}
+	encodedID, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	members[field] = encodedID
+	return json.Marshal(members)
+}
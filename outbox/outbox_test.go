@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyTransport hands payloads to a server in process, failing while down is set.
+type flakyTransport struct {
+	srv  *jsonrpc.Server
+	down atomic.Bool
+}
+
+func (t *flakyTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if t.down.Load() {
+		return nil, jsonrpc.ErrConnectionLost
+	}
+	return t.srv.HandleMessage(ctx, payload), nil
+}
+
+func (*flakyTransport) Close() error {
+	return nil
+}
+
+// inbox records the params of the "event" notifications a server receives.
+type inbox struct {
+	mu     sync.Mutex
+	params []string
+}
+
+// newInboxServer returns a server recording its "event" notifications to the returned inbox,
+// behind middleware.
+func newInboxServer(t *testing.T, middleware ...jsonrpc.Middleware) (*jsonrpc.Server, *inbox) {
+	t.Helper()
+	box := &inbox{}
+	srv := jsonrpc.NewServer()
+	srv.Use(middleware...)
+	event := func(_ context.Context, req *jsonrpc.Request) (any, error) {
+		data, err := json.Marshal(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		box.mu.Lock()
+		defer box.mu.Unlock()
+		box.params = append(box.params, string(data))
+		return nil, nil
+	}
+	require.NoError(t, srv.RegisterFunc("event", event))
+	return srv, box
+}
+
+// received returns the params received so far.
+func (b *inbox) received() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.params...)
+}
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Sends notifications in order with dedup IDs", func(t *testing.T) {
+		srv, box := newInboxServer(t)
+		q, err := New(jsonrpc.NewClient(&flakyTransport{srv: srv}), NewMemoryStore())
+		require.NoError(t, err)
+		defer func() { _ = q.Close() }()
+
+		id, err := q.Enqueue("event", map[string]int{"n": 1})
+		require.NoError(t, err)
+		_, err = q.Enqueue("event", nil)
+		require.NoError(t, err)
+		require.NoError(t, q.Flush(ctx))
+
+		got := box.received()
+		require.Len(t, got, 2)
+		assert.JSONEq(t, `{"n":1,"dedup_id":"`+id+`"}`, got[0])
+		assert.Contains(t, got[1], `"dedup_id":`)
+		assert.Zero(t, q.Len())
+	})
+
+	t.Run("Retries until the connection is back", func(t *testing.T) {
+		srv, box := newInboxServer(t)
+		transport := &flakyTransport{srv: srv}
+		transport.down.Store(true)
+		var failures atomic.Int32
+		store := NewMemoryStore()
+		q, err := New(jsonrpc.NewClient(transport), store,
+			WithRetryInterval(time.Millisecond),
+			WithErrorHandler(func(err error) {
+				assert.ErrorIs(t, err, jsonrpc.ErrConnectionLost)
+				failures.Add(1)
+			}),
+		)
+		require.NoError(t, err)
+		defer func() { _ = q.Close() }()
+
+		for n := range 3 {
+			_, err := q.Enqueue("event", map[string]int{"n": n})
+			require.NoError(t, err)
+		}
+		require.Eventually(t, func() bool { return failures.Load() >= 3 },
+			time.Second, time.Millisecond)
+		assert.Empty(t, box.received())
+		pending, err := store.Pending()
+		require.NoError(t, err)
+		assert.Len(t, pending, 3)
+
+		transport.down.Store(false)
+		require.NoError(t, q.Flush(ctx))
+		got := box.received()
+		require.Len(t, got, 3)
+		for n, params := range got {
+			assert.Contains(t, params, `"n":`+strconv.Itoa(n))
+		}
+		pending, err = store.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Resumes pending messages", func(t *testing.T) {
+		srv, box := newInboxServer(t)
+		store := NewMemoryStore()
+		require.NoError(t, store.Append(Message{
+			ID: "left", Method: "event", Params: json.RawMessage(`{"dedup_id":"left"}`),
+		}))
+		q, err := New(jsonrpc.NewClient(&flakyTransport{srv: srv}), store)
+		require.NoError(t, err)
+		defer func() { _ = q.Close() }()
+		require.NoError(t, q.Flush(ctx))
+		assert.Equal(t, []string{`{"dedup_id":"left"}`}, box.received())
+	})
+
+	t.Run("Rejects params that are not objects", func(t *testing.T) {
+		srv, _ := newInboxServer(t)
+		q, err := New(jsonrpc.NewClient(&flakyTransport{srv: srv}), NewMemoryStore(),
+			WithDedupField("key"))
+		require.NoError(t, err)
+		defer func() { _ = q.Close() }()
+		_, err = q.Enqueue("event", []int{1})
+		assert.ErrorIs(t, err, errParamsNotObject)
+		_, err = q.Enqueue("event", json.RawMessage(`null`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Close stops sending", func(t *testing.T) {
+		srv, _ := newInboxServer(t)
+		transport := &flakyTransport{srv: srv}
+		transport.down.Store(true)
+		store := NewMemoryStore()
+		q, err := New(jsonrpc.NewClient(transport), store, WithRetryInterval(time.Hour))
+		require.NoError(t, err)
+		_, err = q.Enqueue("event", nil)
+		require.NoError(t, err)
+
+		flushed := make(chan error)
+		go func() { flushed <- q.Flush(ctx) }()
+		require.NoError(t, q.Close())
+		assert.ErrorIs(t, <-flushed, ErrClosed)
+		_, err = q.Enqueue("event", nil)
+		assert.ErrorIs(t, err, ErrClosed)
+		pending, err := store.Pending()
+		require.NoError(t, err)
+		assert.Len(t, pending, 1, "unsent notifications stay in the store")
+
+		failing := errors.New("disk full")
+		_, err = New(jsonrpc.NewClient(transport), failingStore{err: failing})
+		assert.ErrorIs(t, err, failing)
+	})
+}
+
+// failingStore is a Store failing every operation.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) Append(Message) error        { return s.err }
+func (s failingStore) Ack(string) error            { return s.err }
+func (s failingStore) Pending() ([]Message, error) { return nil, s.err }
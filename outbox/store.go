@@ -0,0 +1,254 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// filePerm is the permission of store files created by OpenFileStore.
+const filePerm = 0o600
+
+// Operations of the records of a FileStore.
+const (
+	opAppend = "append"
+	opAck    = "ack"
+)
+
+// messageList is the ordered list of the messages of a store.
+type messageList struct {
+	messages []Message
+}
+
+// add appends msg.
+func (l *messageList) add(msg Message) {
+	l.messages = append(l.messages, msg)
+}
+
+// remove removes the message with the given ID, reporting whether there was one. Messages are
+// mostly acknowledged in order, so the list is searched from its head.
+func (l *messageList) remove(id string) bool {
+	for i, msg := range l.messages {
+		if msg.ID == id {
+			l.messages = append(l.messages[:i], l.messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// list returns a copy of the messages.
+func (l *messageList) list() []Message {
+	return append([]Message(nil), l.messages...)
+}
+
+// MemoryStore is a Store keeping messages in memory, so that they survive disconnections but
+// not restarts.
+type MemoryStore struct {
+	mu   sync.Mutex
+	list messageList
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Append implements Store.
+func (s *MemoryStore) Append(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list.add(msg)
+	return nil
+}
+
+// Ack implements Store.
+func (s *MemoryStore) Ack(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list.remove(id)
+	return nil
+}
+
+// Pending implements Store.
+func (s *MemoryStore) Pending() ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.list(), nil
+}
+
+// record is a line of a FileStore.
+type record struct {
+	Op      string   `json:"op"`
+	Message *Message `json:"message,omitempty"`
+	ID      string   `json:"id,omitempty"`
+}
+
+// FileStore is a Store keeping messages in a file, so that they survive restarts. The file is a
+// log of NDJSON records, appended to as messages are added and acknowledged, and truncated
+// whenever no message is left. Appends are synced to disk before returning; acknowledgments are
+// not, as losing one only causes its message to be sent again.
+type FileStore struct {
+	mu   sync.Mutex
+	file *os.File
+	list messageList
+}
+
+// OpenFileStore opens the store in the file at path, creating it if needed, with the messages
+// left pending in it. The file is compacted to these messages, through a temporary file next to
+// it. A record cut short at the end of the file, as by a crash in the middle of an append, is
+// dropped.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{}
+	if err := s.load(path); err != nil {
+		return nil, err
+	}
+	if err := s.compact(path); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, filePerm)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// Append implements Store.
+func (s *FileStore) Append(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(record{Op: opAppend, Message: &msg}); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.list.add(msg)
+	return nil
+}
+
+// Ack implements Store.
+func (s *FileStore) Ack(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.list.remove(id) {
+		return nil
+	}
+	if len(s.list.messages) == 0 {
+		return s.truncate()
+	}
+	return s.write(record{Op: opAck, ID: id})
+}
+
+// Pending implements Store.
+func (s *FileStore) Pending() ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.list(), nil
+}
+
+// Close closes the file of the store.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// load reads the records of the file at path, if it exists.
+func (s *FileStore) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// An incomplete last line is an interrupted append
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.apply(line); err != nil {
+			return fmt.Errorf("outbox: reading store record: %w", err)
+		}
+	}
+}
+
+// apply applies a record of the file to the list of messages.
+func (s *FileStore) apply(line []byte) error {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil
+	}
+	var rec record
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return err
+	}
+	switch {
+	case rec.Op == opAppend && rec.Message != nil:
+		s.list.add(*rec.Message)
+	case rec.Op == opAck:
+		s.list.remove(rec.ID)
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+	return nil
+}
+
+// compact replaces the file at path with one holding the pending messages only, written to a
+// temporary file first so that a crash leaves either file whole.
+func (s *FileStore) compact(path string) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePerm)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	for _, msg := range s.list.messages {
+		if err = s.write(record{Op: opAppend, Message: &msg}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// truncate empties the file.
+func (s *FileStore) truncate() error {
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// write appends rec to the file.
+func (s *FileStore) write(rec record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
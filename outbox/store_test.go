@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message returns a message with the given ID.
+func message(id string) Message {
+	return Message{ID: id, Method: "event", Params: json.RawMessage(`{"dedup_id":"` + id + `"}`)}
+}
+
+// ids returns the IDs of msgs.
+func ids(msgs []Message) []string {
+	out := make([]string, len(msgs))
+	for i, msg := range msgs {
+		out[i] = msg.ID
+	}
+	return out
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Append(message(id)))
+	}
+	require.NoError(t, store.Ack("b"))
+	require.NoError(t, store.Ack("unknown"))
+	pending, err := store.Pending()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, ids(pending))
+}
+
+func TestFileStore(t *testing.T) {
+	t.Run("Keeps pending messages across reopens", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.ndjson")
+		store, err := OpenFileStore(path)
+		require.NoError(t, err)
+		for _, id := range []string{"a", "b", "c"} {
+			require.NoError(t, store.Append(message(id)))
+		}
+		require.NoError(t, store.Ack("a"))
+		require.NoError(t, store.Close())
+
+		store, err = OpenFileStore(path)
+		require.NoError(t, err)
+		pending, err := store.Pending()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "c"}, ids(pending))
+		assert.Equal(t, message("b"), pending[0])
+
+		require.NoError(t, store.Append(message("d")))
+		require.NoError(t, store.Ack("c"))
+		require.NoError(t, store.Close())
+		store, err = OpenFileStore(path)
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		pending, err = store.Pending()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "d"}, ids(pending))
+	})
+
+	t.Run("Empties the file once everything is acknowledged", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.ndjson")
+		store, err := OpenFileStore(path)
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		require.NoError(t, store.Append(message("a")))
+		require.NoError(t, store.Ack("a"))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+		require.NoError(t, store.Append(message("b")))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"id":"b"`)
+	})
+
+	t.Run("Drops an interrupted append", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.ndjson")
+		content := `{"op":"append","message":{"id":"a","method":"event","params":{}}}` + "\n" +
+			`{"op":"append","message":{"id":"b","met`
+		require.NoError(t, os.WriteFile(path, []byte(content), filePerm))
+		store, err := OpenFileStore(path)
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		pending, err := store.Pending()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, ids(pending))
+	})
+
+	t.Run("Rejects corrupt files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.ndjson")
+		require.NoError(t, os.WriteFile(path, []byte("garbage\n{}\n"), filePerm))
+		_, err := OpenFileStore(path)
+		assert.ErrorContains(t, err, "outbox: reading store record")
+	})
+}
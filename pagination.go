@@ -0,0 +1,161 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// Members of the params of list methods set by Paginate.
+const (
+	paramCursor = "cursor"
+	paramLimit  = "limit"
+)
+
+// errParamsNotObject is returned by Paginate for params that cannot carry a cursor.
+var errParamsNotObject = errors.New("pagination params must be nil or encode as a JSON object")
+
+// PageParams are the pagination members of the params of list methods, embedded in their params
+// struct:
+//
+//	type ListUsersParams struct {
+//	    jsonrpc.PageParams
+//	    Role string `json:"role,omitempty"`
+//	}
+type PageParams struct {
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string `json:"cursor,omitempty"`
+
+	// Limit is the maximum number of items the caller wants in the page, or zero for the default
+	// of the method.
+	Limit int `json:"limit,omitempty"`
+}
+
+// PageLimit returns the number of items to put in the page: Limit, or defaultLimit if it is not
+// positive, capped at maxLimit if maxLimit is positive.
+func (p PageParams) PageLimit(defaultLimit, maxLimit int) int {
+	limit := p.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if maxLimit > 0 {
+		limit = min(limit, maxLimit)
+	}
+	return limit
+}
+
+// Page is the result of list methods: a page of items, and the cursor of the next page, empty
+// on the last page. Cursors are opaque to callers.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Paginate returns an iterator over the pages of the list method, called with params, nil or
+// encoding as a JSON object, extended with the limit, if positive, and the cursor of the page.
+// Params are encoded with the codec of the calls, as by Call.
+// Iteration stops after the last page, or after yielding the first error, such as of a server
+// returning the cursor it was called with. Pages hold their items undecoded; see the Paginate
+// function for pages of a given type:
+//
+//	for page, err := range client.Paginate(ctx, "user.list", filter, 100) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    ...
+//	}
+func (c *Client) Paginate(
+	ctx context.Context,
+	method string,
+	params any,
+	limit int,
+	opts ...CallOption,
+) iter.Seq2[Page[json.RawMessage], error] {
+	return Paginate[json.RawMessage](ctx, c, method, params, limit, opts...)
+}
+
+// Paginate is like Client.Paginate, but decodes the items of the pages into values of type T.
+func Paginate[T any](
+	ctx context.Context,
+	client *Client,
+	method string,
+	params any,
+	limit int,
+	opts ...CallOption,
+) iter.Seq2[Page[T], error] {
+	return func(yield func(Page[T], error) bool) {
+		codec := client.callOptions(opts).jsonCodec()
+		members, err := pageMembers(codec, params, limit)
+		if err != nil {
+			yield(Page[T]{}, err)
+			return
+		}
+		cursor := ""
+		for {
+			page, err := fetchPage[T](ctx, client, codec, method, members, cursor, opts)
+			if err == nil && page.NextCursor != "" && page.NextCursor == cursor {
+				err = fmt.Errorf("%s returned the cursor it was called with", method)
+			}
+			if err != nil {
+				yield(Page[T]{}, err)
+				return
+			}
+			if !yield(page, nil) {
+				return
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// fetchPage calls method for the page at cursor.
+func fetchPage[T any](
+	ctx context.Context,
+	client *Client,
+	codec Codec,
+	method string,
+	members map[string]json.RawMessage,
+	cursor string,
+	opts []CallOption,
+) (Page[T], error) {
+	if cursor != "" {
+		encoded, err := codec.Marshal(cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		members[paramCursor] = encoded
+	}
+	return Call[Page[T]](ctx, client, method, members, opts...)
+}
+
+// pageMembers returns the members of params encoded with codec, with the limit if positive.
+func pageMembers(codec Codec, params any, limit int) (map[string]json.RawMessage, error) {
+	members := make(map[string]json.RawMessage)
+	if params != nil {
+		data, err := codec.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := codec.Unmarshal(data, &members); err != nil {
+			return nil, errParamsNotObject
+		}
+		if members == nil {
+			// Params encoding as null
+			members = make(map[string]json.RawMessage)
+		}
+		delete(members, paramCursor)
+	}
+	if limit > 0 {
+		encoded, err := codec.Marshal(limit)
+		if err != nil {
+			return nil, err
+		}
+		members[paramLimit] = encoded
+	}
+	return members, nil
+}
@@ -0,0 +1,201 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listParams struct {
+	PageParams
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// newListClient returns a client of a server whose "list" method pages through total numbered
+// items, recording the params of each call.
+func newListClient(t *testing.T, total int, calls *[]listParams) *Client {
+	t.Helper()
+	srv := NewServer()
+	require.NoError(t, srv.Register("list", HandlerFunc(
+		func(_ context.Context, req *Request) (any, error) {
+			params, err := DecodeParams[listParams](req)
+			if err != nil {
+				return nil, err
+			}
+			*calls = append(*calls, params)
+			start := 0
+			if params.Cursor != "" {
+				if start, err = strconv.Atoi(params.Cursor); err != nil {
+					return nil, err
+				}
+			}
+			end := min(start+params.PageLimit(2, 10), total)
+			page := Page[string]{Items: []string{}}
+			for i := start; i < end; i++ {
+				page.Items = append(page.Items, params.Prefix+strconv.Itoa(i))
+			}
+			if end < total {
+				page.NextCursor = strconv.Itoa(end)
+			}
+			return page, nil
+		},
+	)))
+	return NewClient(&funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}})
+}
+
+func TestPaginate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Iterates over all pages", func(t *testing.T) {
+		var calls []listParams
+		client := newListClient(t, 5, &calls)
+
+		var items []string
+		for page, err := range Paginate[string](ctx, client, "list", map[string]any{
+			"prefix": "u",
+		}, 2) {
+			require.NoError(t, err)
+			items = append(items, page.Items...)
+		}
+		assert.Equal(t, []string{"u0", "u1", "u2", "u3", "u4"}, items)
+		require.Len(t, calls, 3)
+		assert.Equal(t, listParams{PageParams{Limit: 2}, "u"}, calls[0])
+		assert.Equal(t, listParams{PageParams{Cursor: "4", Limit: 2}, "u"}, calls[2])
+	})
+
+	t.Run("Client method yields raw items", func(t *testing.T) {
+		var calls []listParams
+		client := newListClient(t, 3, &calls)
+
+		var pages []Page[json.RawMessage]
+		for page, err := range client.Paginate(ctx, "list", nil, 0) {
+			require.NoError(t, err)
+			pages = append(pages, page)
+		}
+		require.Len(t, pages, 2)
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`"0"`), json.RawMessage(`"1"`)},
+			pages[0].Items)
+		assert.Empty(t, pages[1].NextCursor)
+	})
+
+	t.Run("Breaking stops fetching", func(t *testing.T) {
+		var calls []listParams
+		client := newListClient(t, 10, &calls)
+
+		for _, err := range client.Paginate(ctx, "list", nil, 1) {
+			require.NoError(t, err)
+			break
+		}
+		assert.Len(t, calls, 1)
+	})
+
+	t.Run("Cursor of the caller is replaced", func(t *testing.T) {
+		var calls []listParams
+		client := newListClient(t, 1, &calls)
+
+		params := listParams{PageParams: PageParams{Cursor: "9"}}
+		for _, err := range client.Paginate(ctx, "list", params, 0) {
+			require.NoError(t, err)
+		}
+		require.Len(t, calls, 1)
+		assert.Empty(t, calls[0].Cursor)
+	})
+
+	t.Run("Errors end the iteration", func(t *testing.T) {
+		var calls []listParams
+		client := newListClient(t, 1, &calls)
+
+		var errs []error
+		for _, err := range client.Paginate(ctx, "missing", nil, 0) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], ErrMethodNotFound)
+	})
+
+	t.Run("Params not an object", func(t *testing.T) {
+		client := newListClient(t, 1, new([]listParams))
+
+		for _, err := range client.Paginate(ctx, "list", []int{1}, 0) {
+			assert.ErrorIs(t, err, errParamsNotObject)
+		}
+	})
+
+	t.Run("Repeated cursor", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterFunc("loop", func(context.Context, *Request) (any, error) {
+			return Page[int]{Items: []int{1}, NextCursor: "same"}, nil
+		}))
+		client := NewClient(&funcTransport{fn: func(ctx context.Context, p []byte) ([]byte, error) {
+			return srv.HandleMessage(ctx, p), nil
+		}})
+
+		var errs []error
+		for _, err := range client.Paginate(ctx, "loop", nil, 0) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 2)
+		assert.NoError(t, errs[0])
+		assert.Error(t, errs[1])
+	})
+
+	t.Run("Params use the codec of the client", func(t *testing.T) {
+		var sent []*Request
+		transport := &funcTransport{fn: func(_ context.Context, p []byte) ([]byte, error) {
+			req, err := DecodeRequest(p)
+			if err != nil {
+				return nil, err
+			}
+			sent = append(sent, req)
+			resp, err := NewResponse(req.ID, Page[int]{Items: []int{}})
+			if err != nil {
+				return nil, err
+			}
+			return resp.MarshalJSON()
+		}}
+		codec := NewTimeCodec(TimeEncoding{Time: TimeUnixMilli})
+		client := NewClient(transport, WithClientCodec(codec))
+
+		since := time.UnixMilli(1700000000123)
+		for _, err := range client.Paginate(ctx, "list", map[string]any{"since": since}, 5) {
+			require.NoError(t, err)
+		}
+		require.Len(t, sent, 1)
+		assert.Equal(t, map[string]any{"since": float64(1700000000123), "limit": float64(5)},
+			sent[0].Params)
+
+		seconds := WithCallCodec(NewTimeCodec(TimeEncoding{Time: TimeUnix}))
+		for _, err := range client.Paginate(ctx, "list", map[string]any{"since": since}, 0,
+			seconds) {
+			require.NoError(t, err)
+		}
+		require.Len(t, sent, 2)
+		assert.Equal(t, map[string]any{"since": 1700000000.123}, sent[1].Params,
+			"the call codec overrides the client's")
+	})
+}
+
+func TestPageParams_PageLimit(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, 20, PageParams{}.PageLimit(20, 100))
+	})
+
+	t.Run("Requested", func(t *testing.T) {
+		assert.Equal(t, 5, PageParams{Limit: 5}.PageLimit(20, 100))
+	})
+
+	t.Run("Capped", func(t *testing.T) {
+		assert.Equal(t, 100, PageParams{Limit: 500}.PageLimit(20, 100))
+	})
+
+	t.Run("No cap", func(t *testing.T) {
+		assert.Equal(t, 500, PageParams{Limit: 500}.PageLimit(20, 0))
+	})
+}
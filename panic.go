@@ -0,0 +1,62 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// CorrelationIDKey is the member of the data of internal errors answering a panicking handler
+// holding the correlation ID of the panic, under which it is logged.
+const CorrelationIDKey = "correlationId"
+
+// PanicError is the error of a call whose handler, middleware, or result encoding panicked. The
+// server recovers the panic rather than crashing the connection, and answers the call with an
+// internal error whose data holds only the correlation ID, under CorrelationIDKey, so that the
+// caller can quote it without the panic value and stack leaking out. The PanicError itself, with
+// the value and stack, is what the server's observers and loggers receive, and what the handler
+// set with WithPanicHandler is called with.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+
+	// CorrelationID identifies the panic in both the logs and the error sent to the caller.
+	CorrelationID string
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic (correlation id %s): %v", e.CorrelationID, e.Value)
+}
+
+// Unwrap returns the internal error sent to the caller.
+func (e *PanicError) Unwrap() error {
+	return &Error{
+		Code:    ServerSideException,
+		Message: msgInternalError,
+		Data:    map[string]any{CorrelationIDKey: e.CorrelationID},
+	}
+}
+
+// WithPanicHandler calls fn with the recovered panic of every handler, middleware, or result
+// encoding that panics, such as to report it to an error tracker. It is called synchronously,
+// before the call is answered. Panics are recovered whether it is set or not and, like other
+// errors, reach the observers and loggers of the server, WithLogger logging their stack.
+func WithPanicHandler(fn func(ctx context.Context, req *Request, err *PanicError)) ServerOption {
+	return func(s *Server) {
+		s.panicHandler = fn
+	}
+}
+
+// recovered converts the value of a recovered panic of the call of req into a PanicError,
+// reporting it to the panic handler, if any.
+func (s *Server) recovered(ctx context.Context, req *Request, value any) *PanicError {
+	err := &PanicError{Value: value, Stack: debug.Stack(), CorrelationID: newUUID()}
+	if s.panicHandler != nil {
+		s.panicHandler(ctx, req, err)
+	}
+	return err
+}
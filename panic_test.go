@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panicMarshaler panics when encoded.
+type panicMarshaler struct{}
+
+func (panicMarshaler) MarshalJSON() ([]byte, error) {
+	panic("cannot encode")
+}
+
+// panicLog collects the panics reported to a panic handler.
+type panicLog struct {
+	mu     sync.Mutex
+	panics []*PanicError
+}
+
+// handler returns the panic handler appending to the log.
+func (l *panicLog) handler() func(context.Context, *Request, *PanicError) {
+	return func(_ context.Context, _ *Request, err *PanicError) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.panics = append(l.panics, err)
+	}
+}
+
+// last returns the last panic reported.
+func (l *panicLog) last(t *testing.T) *PanicError {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	require.NotEmpty(t, l.panics)
+	return l.panics[len(l.panics)-1]
+}
+
+// assertPanicResponse checks that resp is the internal error answering the panic err.
+func assertPanicResponse(t *testing.T, resp *Response, err *PanicError) {
+	t.Helper()
+	require.NotNil(t, resp.Err())
+	assert.Equal(t, ServerSideException, resp.Err().Code)
+	assert.Equal(t, msgInternalError, resp.Err().Message)
+	assert.Equal(t, map[string]any{CorrelationIDKey: err.CorrelationID}, resp.Err().Data)
+}
+
+func TestServer_PanicRecovery(t *testing.T) {
+	ctx := context.Background()
+	boom := func(context.Context, *Request) (any, error) { panic("boom") }
+
+	t.Run("Handler panics are answered with a correlation ID", func(t *testing.T) {
+		log := &panicLog{}
+		srv := NewServer(WithPanicHandler(log.handler()))
+		require.NoError(t, srv.RegisterFunc("boom", boom))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("boom", nil, int64(1)))
+		err := log.last(t)
+		assert.Equal(t, "boom", err.Value)
+		assert.Contains(t, string(err.Stack), "panic_test.go")
+		assert.NotEmpty(t, err.CorrelationID)
+		assert.Contains(t, err.Error(), err.CorrelationID)
+		assertPanicResponse(t, resp, err)
+		assert.ErrorIs(t, err, ErrInternal)
+	})
+
+	t.Run("Middleware and result encoding panics", func(t *testing.T) {
+		log := &panicLog{}
+		srv := NewServer(WithPanicHandler(log.handler()))
+		require.NoError(t, srv.RegisterFunc("unencodable",
+			func(context.Context, *Request) (any, error) { return panicMarshaler{}, nil }))
+		require.NoError(t, srv.RegisterFunc("guarded",
+			func(context.Context, *Request) (any, error) { return "ok", nil }))
+		srv.Use(func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+				if req.Method == "guarded" {
+					panic("middleware")
+				}
+				return next.ServeRPC(ctx, req)
+			})
+		})
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("unencodable", nil, int64(1)))
+		assertPanicResponse(t, resp, log.last(t))
+
+		resp = srv.HandleRequest(ctx, NewRequestWithID("guarded", nil, int64(2)))
+		assertPanicResponse(t, resp, log.last(t))
+		assert.Equal(t, "middleware", log.last(t).Value)
+	})
+
+	t.Run("Handlers run under a timeout", func(t *testing.T) {
+		srv := NewServer(WithRequestTimeout(time.Second))
+		require.NoError(t, srv.RegisterFunc("boom", boom))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("boom", nil, int64(1)))
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, ServerSideException, resp.Err().Code)
+	})
+
+	t.Run("Streams keep serving", func(t *testing.T) {
+		srv := newTestServer(t)
+		require.NoError(t, srv.RegisterFunc("boom", boom))
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		assert.ErrorIs(t, client.Call(ctx, "boom", nil, nil), ErrInternal)
+		require.NoError(t, client.Notify(ctx, "boom", nil))
+		var sum int
+		require.NoError(t, client.Call(ctx, "sum", []int{1, 2}, &sum))
+		assert.Equal(t, 3, sum)
+	})
+
+	t.Run("Logged with the stack", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+		log := &panicLog{}
+		srv := NewServer(WithLogger(logger), WithPanicHandler(log.handler()))
+		require.NoError(t, srv.RegisterFunc("boom", boom))
+
+		srv.HandleRequest(ctx, NewRequestWithID("boom", nil, int64(1)))
+		line := buf.String()
+		assert.Contains(t, line, "level=ERROR")
+		assert.Contains(t, line, "correlation_id="+log.last(t).CorrelationID)
+		assert.Contains(t, line, "stack=")
+		assert.Contains(t, line, "code=-32603")
+	})
+}
@@ -0,0 +1,133 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonUnmarshalerType is the reflect type of the json.Unmarshaler interface.
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// Positional returns its arguments as positional (array) params, encoding an empty argument list
+// as [] rather than omitting the params:
+//
+//	req := jsonrpc.NewRequest("subtract", jsonrpc.Positional(42, 23))
+func Positional(args ...any) []any {
+	if args == nil {
+		return []any{}
+	}
+	return args
+}
+
+// BindParams decodes the request's params into dst like UnmarshalParams, but additionally binds
+// positional (array) params to a struct by order: the n-th element is stored in the n-th exported
+// field not tagged `json:"-"`. Missing trailing elements leave their fields unchanged, and extra
+// elements are an error. Structs implementing json.Unmarshaler decode themselves.
+//
+// This lets one handler accept both {"a": 1, "b": 2} and [1, 2] as params.
+//
+// The bound value is then checked against the validation rules of its struct tags, so that
+// handlers can assume sane inputs:
+//
+//	type TransferArgs struct {
+//		To     string `json:"to" jsonrpc:"required"`
+//		Amount int64  `json:"amount" validate:"required,min=1,max=1000000"`
+//		Memo   string `json:"memo" validate:"max=140"`
+//	}
+//
+// The rules are:
+//
+//   - required, in the jsonrpc or validate tag: the field must not be the zero value. Use a
+//     pointer for fields whose zero value is valid but must be sent.
+//   - min=n and max=n, in the validate tag: numbers must be at least or at most n, strings must
+//     be at least or at most n characters long, and slices and maps must have at least or at
+//     most n elements. They are skipped for nil pointers.
+//
+// Nested structs, and structs held by pointers, slices, and arrays, are checked as well, and the
+// error names the first offending field by its JSON path, such as "items[0].id". Service method
+// arguments are checked the same way.
+func (r *Request) BindParams(dst any) error {
+	if err := r.bindParams(dst); err != nil {
+		return err
+	}
+	return validateTags(dst)
+}
+
+// bindParams decodes the request's params into dst, binding positional params by order.
+func (r *Request) bindParams(dst any) error {
+	params, err := expandRawParams(r.Params)
+	if err != nil {
+		return err
+	}
+	values, ok := params.([]any)
+	if !ok {
+		return r.UnmarshalParams(dst)
+	}
+
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct ||
+		ptr.Type().Implements(jsonUnmarshalerType) {
+		return r.UnmarshalParams(dst)
+	}
+
+	target := ptr.Elem()
+	fields := positionalFields(target.Type())
+	if len(values) > len(fields) {
+		return fmt.Errorf("too many params: got %d, want at most %d", len(values), len(fields))
+	}
+	for i, value := range values {
+		if err := convertValue(r.paramsCodec(), value, target.Field(fields[i])); err != nil {
+			return fmt.Errorf("invalid param at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// positionalFields returns the indices of the struct fields that positional params bind to.
+func positionalFields(typ reflect.Type) []int {
+	fields := make([]int, 0, typ.NumField())
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+// isValidParams reports whether params encodes as a JSON array or object, as the JSON-RPC 2.0
+// spec requires. Besides []any and map[string]any, this accepts other slices and arrays, maps
+// keyed by strings, structs, non-nil pointers to these, and raw JSON holding an array or object.
+func isValidParams(params any) bool {
+	switch p := params.(type) {
+	case nil, []any, map[string]any:
+		return true
+	case json.RawMessage:
+		return isStructuredJSON(p)
+	}
+
+	v := reflect.ValueOf(params)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		// Byte slices encode as base64 strings
+		return v.Type().Elem().Kind() != reflect.Uint8
+	case reflect.Array, reflect.Struct:
+		return true
+	case reflect.Map:
+		return v.Type().Key().Kind() == reflect.String
+	default:
+		return false
+	}
+}
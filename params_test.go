@@ -0,0 +1,124 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositional(t *testing.T) {
+	t.Run("Arguments", func(t *testing.T) {
+		assert.Equal(t, []any{42, "a"}, Positional(42, "a"))
+	})
+
+	t.Run("No arguments encode as empty array", func(t *testing.T) {
+		data, err := NewRequest("m", Positional()).MarshalJSON()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"params":[]`)
+	})
+}
+
+func TestRequest_BindParams(t *testing.T) {
+	type args struct {
+		From    string `json:"from"`
+		skipped int
+		Ignored string `json:"-"`
+		Amount  int    `json:"amount"`
+		Memo    string `json:"memo,omitempty"`
+	}
+
+	t.Run("Positional into struct by order", func(t *testing.T) {
+		var got args
+		require.NoError(t, NewRequest("m", []any{"ann", 5}).BindParams(&got))
+		assert.Equal(t, args{From: "ann", Amount: 5}, got)
+	})
+
+	t.Run("Named into struct", func(t *testing.T) {
+		var got args
+		req := NewRequest("m", map[string]any{"from": "ann", "amount": 5, "memo": "x"})
+		require.NoError(t, req.BindParams(&got))
+		assert.Equal(t, args{From: "ann", Amount: 5, Memo: "x"}, got)
+	})
+
+	t.Run("Decoded request", func(t *testing.T) {
+		for _, msg := range []string{
+			`{"jsonrpc":"2.0","id":1,"method":"m","params":["ann",5,"x"]}`,
+			`{"jsonrpc":"2.0","id":1,"method":"m","params":{"from":"ann","amount":5,"memo":"x"}}`,
+		} {
+			req, err := DecodeRequest([]byte(msg))
+			require.NoError(t, err)
+			var got args
+			require.NoError(t, req.BindParams(&got))
+			assert.Equal(t, args{From: "ann", Amount: 5, Memo: "x"}, got)
+		}
+	})
+
+	t.Run("Too many positional params", func(t *testing.T) {
+		var got args
+		err := NewRequest("m", []any{"ann", 5, "x", true}).BindParams(&got)
+		assert.ErrorContains(t, err, "too many params")
+	})
+
+	t.Run("Positional type mismatch", func(t *testing.T) {
+		var got args
+		err := NewRequest("m", []any{"ann", "five"}).BindParams(&got)
+		assert.ErrorContains(t, err, "index 1")
+	})
+
+	t.Run("Array into slice", func(t *testing.T) {
+		var got []int
+		require.NoError(t, NewRequest("m", []any{1, 2}).BindParams(&got))
+		assert.Equal(t, []int{1, 2}, got)
+	})
+
+	t.Run("Struct with custom unmarshaler", func(t *testing.T) {
+		var got rawArgs
+		require.NoError(t, NewRequest("m", []any{1, 2}).BindParams(&got))
+		assert.JSONEq(t, `[1,2]`, string(got.raw))
+	})
+}
+
+// rawArgs keeps its params as raw JSON.
+type rawArgs struct {
+	raw json.RawMessage
+}
+
+func (a *rawArgs) UnmarshalJSON(data []byte) error {
+	a.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func TestIsValidParams(t *testing.T) {
+	type point struct{ X, Y int }
+
+	cases := []struct {
+		name   string
+		params any
+		valid  bool
+	}{
+		{"Nil", nil, true},
+		{"Array", []any{1}, true},
+		{"Object", map[string]any{"a": 1}, true},
+		{"Typed slice", []string{"a"}, true},
+		{"Array value", [2]int{1, 2}, true},
+		{"String keyed map", map[string]int{"a": 1}, true},
+		{"Struct", point{}, true},
+		{"Struct pointer", &point{}, true},
+		{"Raw array", json.RawMessage(` [1]`), true},
+		{"Raw object", json.RawMessage(`{}`), true},
+		{"Raw scalar", json.RawMessage(`1`), false},
+		{"Nil pointer", (*point)(nil), false},
+		{"Byte slice", []byte("ab"), false},
+		{"Int keyed map", map[int]int{1: 1}, false},
+		{"String", "a", false},
+		{"Number", 1, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, isValidParams(tc.params))
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Kinds of malformed messages, matched with errors.Is against the *MessageError returned by
+// ParseMessage and the decoding functions.
+var (
+	// ErrEmptyMessage is returned for empty or blank input.
+	ErrEmptyMessage = errors.New(errEmptyData)
+
+	// ErrTruncated is returned for JSON ending before its last value is complete.
+	ErrTruncated = errors.New("truncated JSON")
+
+	// ErrMalformedJSON is returned for input that is not valid JSON.
+	ErrMalformedJSON = errors.New("malformed JSON")
+
+	// ErrUnexpectedShape is returned for valid JSON of another shape than a JSON-RPC message,
+	// such as a scalar or an array where an object is expected, an empty batch, or a batch mixing
+	// requests and responses.
+	ErrUnexpectedShape = errors.New("unexpected message shape")
+
+	// ErrInvalidField is returned for members with a missing or wrongly typed value, such as an
+	// id that is an object.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// noIndex is the MessageError index of errors in a message that is not a batch.
+const noIndex = -1
+
+// Names of the members of JSON-RPC messages.
+const (
+	fieldJSONRPC = "jsonrpc"
+	fieldID      = "id"
+	fieldMethod  = "method"
+	fieldParams  = "params"
+	fieldResult  = "result"
+	fieldError   = "error"
+)
+
+// MessageError describes why input is not a valid JSON-RPC message.
+type MessageError struct {
+	// Kind is the class of the error, such as ErrTruncated or ErrInvalidField, which the error
+	// unwraps to.
+	Kind error
+
+	// Index is the position of the malformed member in a batch, or -1 if the error is not in a
+	// batch member.
+	Index int
+
+	// Field is the name of the invalid member, such as "id", for ErrInvalidField errors.
+	Field string
+
+	// Offset is the byte offset of the error in the input for ErrMalformedJSON and ErrTruncated
+	// errors, and 0 otherwise.
+	Offset int64
+
+	// Detail describes the error. It defaults to the message of Kind.
+	Detail string
+}
+
+// Error implements error.
+func (e *MessageError) Error() string {
+	detail := e.Detail
+	if detail == "" {
+		detail = e.Kind.Error()
+	}
+	if e.Index >= 0 {
+		return fmt.Sprintf("invalid message at index %d: %s", e.Index, detail)
+	}
+	return detail
+}
+
+// Unwrap returns the kind of the error.
+func (e *MessageError) Unwrap() error {
+	return e.Kind
+}
+
+// emptyMessage returns the ErrEmptyMessage error.
+func emptyMessage() *MessageError {
+	return &MessageError{Kind: ErrEmptyMessage, Index: noIndex}
+}
+
+// invalidField returns the ErrInvalidField error of a member.
+func invalidField(field, detail string) *MessageError {
+	return &MessageError{Kind: ErrInvalidField, Index: noIndex, Field: field, Detail: detail}
+}
+
+// unexpectedShape returns the ErrUnexpectedShape error of the member at index.
+func unexpectedShape(index int, detail string) *MessageError {
+	return &MessageError{Kind: ErrUnexpectedShape, Index: index, Detail: detail}
+}
+
+// Message is a parsed JSON-RPC message: a request or notification, a response, or a batch of
+// either.
+type Message struct {
+	// Batch is true if the message is a batch, even of a single member.
+	Batch bool
+
+	// Requests holds the requests and notifications of a request message.
+	Requests []*Request
+
+	// Responses holds the responses of a response message.
+	Responses []*Response
+}
+
+// ParseMessage parses data as any JSON-RPC message, telling requests from responses by their
+// members. It is meant as the entry point for fuzzing, as with go test -fuzz: it never panics,
+// and every error it returns is a *MessageError describing what is malformed and where.
+func ParseMessage(data []byte) (msg *Message, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			msg, err = nil, &MessageError{
+				Kind:   ErrMalformedJSON,
+				Index:  noIndex,
+				Detail: fmt.Sprintf("unparsable message: %v", p),
+			}
+		}
+	}()
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, emptyMessage()
+	}
+	if err := checkSyntax(trimmed); err != nil {
+		return nil, err
+	}
+
+	switch trimmed[0] {
+	case '{':
+		msg = &Message{}
+		return msg, msg.add(trimmed, noIndex)
+	case '[':
+		return parseBatchMessage(trimmed)
+	default:
+		return nil, unexpectedShape(noIndex, "message must be an object or an array")
+	}
+}
+
+// checkSyntax returns an error if data is not a single valid JSON value.
+func checkSyntax(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var value json.RawMessage
+	err := dec.Decode(&value)
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == nil:
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &MessageError{Kind: ErrTruncated, Index: noIndex, Offset: int64(len(data))}
+	case errors.As(err, &syntaxErr):
+		return &MessageError{
+			Kind:   ErrMalformedJSON,
+			Index:  noIndex,
+			Offset: syntaxErr.Offset,
+			Detail: fmt.Sprintf("malformed JSON at offset %d: %v", syntaxErr.Offset, err),
+		}
+	default:
+		return &MessageError{Kind: ErrMalformedJSON, Index: noIndex, Detail: err.Error()}
+	}
+	if offset := dec.InputOffset(); offset != int64(len(data)) {
+		return &MessageError{
+			Kind:   ErrMalformedJSON,
+			Index:  noIndex,
+			Offset: offset,
+			Detail: fmt.Sprintf("unexpected data after the message at offset %d", offset),
+		}
+	}
+	return nil
+}
+
+// parseBatchMessage parses a batch, whose members must all be requests or all be responses.
+func parseBatchMessage(data []byte) (*Message, error) {
+	var members []json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, &MessageError{Kind: ErrMalformedJSON, Index: noIndex, Detail: err.Error()}
+	}
+	if len(members) == 0 {
+		return nil, unexpectedShape(noIndex, "batch must contain at least one message")
+	}
+
+	msg := &Message{Batch: true}
+	for i, member := range members {
+		if err := msg.add(member, i); err != nil {
+			return nil, err
+		}
+		if len(msg.Requests) > 0 && len(msg.Responses) > 0 {
+			return nil, unexpectedShape(i, "batch must not mix requests and responses")
+		}
+	}
+	return msg, nil
+}
+
+// add parses a single message, the member at index of a batch, and adds it to m.
+func (m *Message) add(data []byte, index int) error {
+	if data[0] != '{' {
+		return unexpectedShape(index, "message must be an object")
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return &MessageError{Kind: ErrMalformedJSON, Index: index, Detail: err.Error()}
+	}
+
+	_, isRequest := fields[fieldMethod]
+	_, hasResult := fields[fieldResult]
+	_, hasError := fields[fieldError]
+	switch {
+	case isRequest:
+		err := checkFieldTypes(fields, fieldJSONRPC, fieldMethod, fieldID, fieldParams)
+		if err != nil {
+			return atIndex(err, index)
+		}
+		req, err := DecodeRequest(data)
+		if err != nil {
+			return atIndex(err, index)
+		}
+		m.Requests = append(m.Requests, req)
+	case hasResult || hasError:
+		if err := checkFieldTypes(fields, fieldJSONRPC, fieldID); err != nil {
+			return atIndex(err, index)
+		}
+		resp, err := DecodeResponse(data)
+		if err != nil {
+			return atIndex(err, index)
+		}
+		m.Responses = append(m.Responses, resp)
+	default:
+		return unexpectedShape(index, "message has neither a method nor a result or an error")
+	}
+	return nil
+}
+
+// jsonKinds are the JSON value kinds each member may hold, by their first byte: '"' for strings,
+// '0' for numbers, 'n' for null, '[' for arrays, and '{' for objects.
+var jsonKinds = map[string]string{
+	fieldJSONRPC: `"`,
+	fieldMethod:  `"`,
+	fieldID:      `"0n`,
+	fieldParams:  `[{n`,
+}
+
+// checkFieldTypes checks that the named members, where present, hold values of their kinds.
+func checkFieldTypes(fields map[string]json.RawMessage, names ...string) error {
+	for _, name := range names {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if !bytes.ContainsRune([]byte(jsonKinds[name]), rune(jsonKind(raw))) {
+			return invalidField(name, fmt.Sprintf("%s field has an invalid type: %s", name, raw))
+		}
+	}
+	return nil
+}
+
+// jsonKind returns the kind of a raw JSON value as used by jsonKinds.
+func jsonKind(raw json.RawMessage) byte {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return 0
+	}
+	switch c := trimmed[0]; c {
+	case '"', '[', '{', 'n', 't', 'f':
+		return c
+	default:
+		return '0'
+	}
+}
+
+// atIndex returns err located at the member at index of a batch, as a *MessageError.
+func atIndex(err error, index int) error {
+	var msgErr *MessageError
+	if errors.As(err, &msgErr) {
+		located := *msgErr
+		located.Index = index
+		return &located
+	}
+	return &MessageError{Kind: ErrInvalidField, Index: index, Detail: err.Error()}
+}
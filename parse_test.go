@@ -0,0 +1,129 @@
+package jsonrpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessage(t *testing.T) {
+	t.Run("Valid messages", func(t *testing.T) {
+		msg, err := ParseMessage([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))
+		require.NoError(t, err)
+		assert.False(t, msg.Batch)
+		require.Len(t, msg.Requests, 1)
+		assert.Equal(t, "sum", msg.Requests[0].Method)
+
+		msg, err = ParseMessage([]byte(` [{"jsonrpc":"2.0","result":3,"id":1},
+			{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":2}] `))
+		require.NoError(t, err)
+		assert.True(t, msg.Batch)
+		require.Len(t, msg.Responses, 2)
+		assert.Equal(t, MethodNotFound, msg.Responses[1].Err().Code)
+
+		msg, err = ParseMessage([]byte(`[{"jsonrpc":"2.0","method":"notify"}]`))
+		require.NoError(t, err)
+		assert.Nil(t, msg.Requests[0].ID)
+	})
+
+	tests := []struct {
+		name  string
+		data  string
+		kind  error
+		index int
+		field string
+	}{
+		{"empty", "  ", ErrEmptyMessage, -1, ""},
+		{"truncated object", `{"jsonrpc":"2.0","method":"a"`, ErrTruncated, -1, ""},
+		{"truncated batch", `[{"jsonrpc":"2.0","method":"a"},`, ErrTruncated, -1, ""},
+		{"invalid JSON", `{"jsonrpc":}`, ErrMalformedJSON, -1, ""},
+		{"trailing data", `{"jsonrpc":"2.0","method":"a"} {}`, ErrMalformedJSON, -1, ""},
+		{"scalar", `42`, ErrUnexpectedShape, -1, ""},
+		{"empty batch", `[]`, ErrUnexpectedShape, -1, ""},
+		{"array member", `[{"jsonrpc":"2.0","method":"a"},[1]]`, ErrUnexpectedShape, 1, ""},
+		{"neither", `{"jsonrpc":"2.0","id":1}`, ErrUnexpectedShape, -1, ""},
+		{
+			"mixed batch",
+			`[{"jsonrpc":"2.0","method":"a"},{"jsonrpc":"2.0","result":1,"id":1}]`,
+			ErrUnexpectedShape, 1, "",
+		},
+		{"object id", `{"jsonrpc":"2.0","method":"a","id":{}}`, ErrInvalidField, -1, fieldID},
+		{"boolean id", `[{"jsonrpc":"2.0","result":1,"id":true}]`, ErrInvalidField, 0, fieldID},
+		{"numeric method", `{"jsonrpc":"2.0","method":5}`, ErrInvalidField, -1, fieldMethod},
+		{"empty method", `{"jsonrpc":"2.0","method":""}`, ErrInvalidField, -1, fieldMethod},
+		{"scalar params", `{"jsonrpc":"2.0","method":"a","params":1}`, ErrInvalidField, -1,
+			fieldParams},
+		{"wrong version", `{"jsonrpc":"1.0","method":"a"}`, ErrInvalidField, -1, fieldJSONRPC},
+		{"numeric version", `{"jsonrpc":2,"result":1,"id":1}`, ErrInvalidField, -1, fieldJSONRPC},
+		{"result and error", `{"jsonrpc":"2.0","result":1,"error":{},"id":1}`,
+			ErrUnexpectedShape, -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMessage([]byte(tt.data))
+			require.ErrorIs(t, err, tt.kind)
+			var msgErr *MessageError
+			require.True(t, errors.As(err, &msgErr))
+			assert.Equal(t, tt.index, msgErr.Index)
+			assert.Equal(t, tt.field, msgErr.Field)
+		})
+	}
+
+	t.Run("Offsets of syntax errors", func(t *testing.T) {
+		_, err := ParseMessage([]byte(`{"jsonrpc" "2.0"}`))
+		var msgErr *MessageError
+		require.True(t, errors.As(err, &msgErr))
+		assert.Equal(t, int64(12), msgErr.Offset)
+	})
+}
+
+func TestDecode_StructuredErrors(t *testing.T) {
+	_, err := DecodeRequest(nil)
+	require.ErrorIs(t, err, ErrEmptyMessage)
+	assert.EqualError(t, err, "empty data")
+
+	_, err = DecodeRequest([]byte(`{"jsonrpc":"2.0","method":"a","id":[1]}`))
+	var msgErr *MessageError
+	require.True(t, errors.As(err, &msgErr))
+	assert.Equal(t, fieldID, msgErr.Field)
+
+	_, err = DecodeBatchRequest([]byte(`[{"jsonrpc":"2.0","method":""}]`))
+	require.ErrorIs(t, err, ErrInvalidField)
+
+	_, err = DecodeResponse([]byte(`{"jsonrpc":"2.0","id":1}`))
+	require.ErrorIs(t, err, ErrUnexpectedShape)
+}
+
+func FuzzParseMessage(f *testing.F) {
+	seeds := []string{
+		`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`,
+		`{"jsonrpc":"2.0","method":"notify","params":{"a":"b"}}`,
+		`[{"jsonrpc":"2.0","method":"a","id":"x"},{"jsonrpc":"2.0","method":"b"}]`,
+		`{"jsonrpc":"2.0","result":{"nested":[1,2,{"x":null}]},"id":1}`,
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		`{"jsonrpc":"2.0","method":"a","id":1.5}`,
+		`{"jsonrpc":"2.0","method":"a","id":12345678901234567890}`,
+		`[`, `{`, `[]`, `null`, `"x"`, `{"id":{}}`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := ParseMessage(data)
+		if err != nil {
+			var msgErr *MessageError
+			if !errors.As(err, &msgErr) {
+				t.Fatalf("error %v is not a *MessageError", err)
+			}
+			return
+		}
+		if len(msg.Requests)+len(msg.Responses) == 0 {
+			t.Fatal("parsed message has no members")
+		}
+		if !msg.Batch && len(msg.Requests)+len(msg.Responses) != 1 {
+			t.Fatal("single message with several members")
+		}
+	})
+}
@@ -0,0 +1,24 @@
+package jsonrpc
+
+import "encoding/json"
+
+// WithRawPassthrough makes the server write results returned as json.RawMessage byte for byte,
+// without validating or re-encoding them with the codec, such as for gateways relaying the
+// RawResult of an upstream's response untouched so that signatures over it still verify. The
+// handler vouches for a raw result being valid JSON, as it is not checked. Replies are still
+// rewritten by WithCanonicalJSON.
+//
+// Responses keep their raw results as received, and MarshalJSON, AppendJSON, and WriteTo copy
+// them verbatim, so that proxies and clients decoding into a json.RawMessage relay them intact
+// in any case.
+func WithRawPassthrough() ServerOption {
+	return func(s *Server) {
+		s.rawPassthrough = true
+	}
+}
+
+// rawResult returns the result a handler returned as json.RawMessage, if any.
+func rawResult(result any) (json.RawMessage, bool) {
+	raw, ok := result.(json.RawMessage)
+	return raw, ok && len(raw) > 0
+}
@@ -0,0 +1,72 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedResult is a result whose exact bytes matter, holding whitespace, an unsorted object,
+// and characters that encoders commonly escape.
+const signedResult = `{ "z": 1.50,  "a": "<b>&</b>", "sig": "c2ln" }`
+
+func TestServer_RawPassthrough(t *testing.T) {
+	SetCodec(StdCodec)
+	defer SetCodec(nil)
+	ctx := context.Background()
+	relay := func(context.Context, *Request) (any, error) {
+		return json.RawMessage(signedResult), nil
+	}
+	msg := []byte(`{"jsonrpc":"2.0","method":"relay","id":1}`)
+
+	t.Run("Raw results are written verbatim", func(t *testing.T) {
+		srv := NewServer(WithRawPassthrough())
+		require.NoError(t, srv.RegisterFunc("relay", relay))
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":`+signedResult+`}`,
+			string(srv.HandleMessage(ctx, msg)))
+	})
+
+	t.Run("Raw results are re-encoded without it", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterFunc("relay", relay))
+		assert.NotContains(t, string(srv.HandleMessage(ctx, msg)), signedResult)
+	})
+
+	t.Run("Through a proxy to a client", func(t *testing.T) {
+		srv := NewServer(WithRawPassthrough())
+		require.NoError(t, srv.RegisterFunc("relay", relay))
+		upstream, _ := newUpstream(srv)
+		proxy := NewProxy(upstream)
+		roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+			return proxy.HandleMessage(ctx, payload), nil
+		}
+		client := NewClient(&funcTransport{fn: roundTrip})
+		defer client.Close()
+
+		var result json.RawMessage
+		require.NoError(t, client.Call(ctx, "relay", nil, &result))
+		assert.Equal(t, signedResult, string(result))
+	})
+}
+
+func TestResponse_MarshalJSON_RawResult(t *testing.T) {
+	SetCodec(StdCodec)
+	defer SetCodec(nil)
+
+	resp, err := DecodeResponse([]byte(`{"jsonrpc":"2.0","result":` + signedResult + `,"id":7}`))
+	require.NoError(t, err)
+	assert.Equal(t, signedResult, string(resp.RawResult()))
+
+	out, err := resp.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"result":`+signedResult+`}`, string(out))
+
+	raw, err := NewResponseFromRaw("a", json.RawMessage(signedResult))
+	require.NoError(t, err)
+	out, err = raw.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"a","result":`+signedResult+`}`, string(out))
+}
@@ -0,0 +1,98 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Peer is one end of a persistent connection on which both sides serve calls and issue them, as
+// symmetric protocols such as the Language Server Protocol or the Chrome DevTools Protocol
+// require. Both ends are typically peers, although the other end may equally be a stream client
+// with WithServer, or a server calling back through ClientFromContext.
+//
+// A Peer is a stream Client, whose calls, notifications, batches, and subscriptions go to the
+// remote end, serving the requests and notifications the remote end sends with its Server.
+// Handlers may be registered at any time, including after the connection is up, and may call the
+// remote end, even while it is waiting for them.
+type Peer struct {
+	*Client
+
+	server *Server
+	ended  chan struct{}
+}
+
+// NewPeer starts serving stream with srv, or with a new Server if srv is nil, and returns the
+// peer through which to call the remote end. The client options apply to the outgoing calls.
+// Like a stream client, the peer runs until Close is called or reading from the stream fails.
+//
+// While connected, the peer is listed by the Conns of its server and reached by Broadcast, as
+// with ServeStream, and handlers can use ConnFromContext and ClientFromContext.
+func NewPeer(stream Stream, srv *Server, opts ...ClientOption) *Peer {
+	if srv == nil {
+		return NewServer().newPeer(context.Background(), stream, opts...)
+	}
+	return srv.newPeer(context.Background(), stream, opts...)
+}
+
+// newPeer serves stream until the client shuts down. Handlers' contexts derive from ctx.
+func (s *Server) newPeer(ctx context.Context, stream Stream, opts ...ClientOption) *Peer {
+	boundStream(stream, s.limits.maxMessageSize)
+	conn := s.newConn()
+	if md, ok := IncomingMetadata(ctx); ok {
+		conn.remote = md.Get(PeerMetadataKey)
+	}
+	clientOpts := append([]ClientOption{
+		WithServer(s),
+		withBaseContext(contextWithConn(ctx, conn)),
+	}, s.connKeepalive(conn)...)
+	clientOpts = append(clientOpts, opts...)
+	p := &Peer{
+		Client: NewStreamClient(stream, clientOpts...),
+		server: s,
+		ended:  make(chan struct{}),
+	}
+
+	conn.client = p.Client
+	s.trackConn(conn)
+	go conn.run(p.Client)
+	go func() {
+		<-p.Client.Done()
+		_ = p.Client.Close()
+		s.untrackConn(conn)
+		conn.close()
+		conn.session.close()
+		close(p.ended)
+	}()
+	return p
+}
+
+// Server returns the server handling the requests of the remote end.
+func (p *Peer) Server() *Server {
+	return p.server
+}
+
+// Register registers a handler for requests of the remote end, like Server.Register.
+func (p *Peer) Register(method string, handler Handler) error {
+	return p.server.Register(method, handler)
+}
+
+// RegisterFunc registers a function handling requests of the remote end, like
+// Server.RegisterFunc.
+func (p *Peer) RegisterFunc(
+	method string,
+	fn func(ctx context.Context, req *Request) (any, error),
+) error {
+	return p.server.RegisterFunc(method, fn)
+}
+
+// Wait blocks until the connection has ended and its stream is closed. It returns nil when the
+// peer was closed with Close or the remote end closed the stream, and the read error otherwise.
+func (p *Peer) Wait() error {
+	<-p.ended
+	cause := p.terminalErr()
+	if cause == ErrClientClosed || errors.Is(cause, io.EOF) {
+		return nil
+	}
+	return cause
+}
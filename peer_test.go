@@ -0,0 +1,124 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPeerPair returns two connected peers, closed at the end of the test.
+func newPeerPair(t *testing.T) (a, b *Peer) {
+	t.Helper()
+	left, right := newStreamPair()
+	a, b = NewPeer(left, nil), NewPeer(right, nil)
+	t.Cleanup(func() {
+		_ = a.Close()
+		_ = b.Close()
+	})
+	return a, b
+}
+
+func TestPeer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Both ends serve and call", func(t *testing.T) {
+		editor, lsp := newPeerPair(t)
+		require.NoError(t, editor.RegisterFunc("window/showMessage",
+			func(_ context.Context, req *Request) (any, error) {
+				var params []string
+				if err := req.UnmarshalParams(&params); err != nil {
+					return nil, err
+				}
+				return "shown " + params[0], nil
+			}))
+		require.NoError(t, lsp.RegisterFunc("textDocument/hover",
+			func(context.Context, *Request) (any, error) { return "docs", nil }))
+
+		hover, err := Call[string](ctx, editor.Client, "textDocument/hover", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "docs", hover)
+
+		shown, err := Call[string](ctx, lsp.Client, "window/showMessage", []string{"hi"})
+		require.NoError(t, err)
+		assert.Equal(t, "shown hi", shown)
+	})
+
+	t.Run("Handlers call back while the caller waits", func(t *testing.T) {
+		a, b := newPeerPair(t)
+		require.NoError(t, a.RegisterFunc("config", func(context.Context, *Request) (any, error) {
+			return 42, nil
+		}))
+		work := func(ctx context.Context, _ *Request) (any, error) {
+			caller, ok := ClientFromContext(ctx)
+			if !ok {
+				return nil, errors.New("no client in context")
+			}
+			n, err := Call[int](ctx, caller, "config", nil)
+			return n + 1, err
+		}
+		require.NoError(t, b.RegisterFunc("work", work))
+
+		n, err := Call[int](ctx, a.Client, "work", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 43, n)
+	})
+
+	t.Run("Unknown methods are answered with method not found", func(t *testing.T) {
+		a, _ := newPeerPair(t)
+		err := a.Call(ctx, "missing", nil, nil)
+		assert.ErrorIs(t, err, ErrMethodNotFound)
+	})
+
+	t.Run("Notifications reach the remote handlers", func(t *testing.T) {
+		a, b := newPeerPair(t)
+		got := make(chan string, 1)
+		logger := func(_ context.Context, req *Request) (any, error) {
+			var params []string
+			_ = req.UnmarshalParams(&params)
+			got <- params[0]
+			return nil, nil
+		}
+		require.NoError(t, b.RegisterFunc("log", logger))
+
+		require.NoError(t, a.Notify(ctx, "log", []string{"hello"}))
+		select {
+		case msg := <-got:
+			assert.Equal(t, "hello", msg)
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+	})
+
+	t.Run("Listed by the server while connected", func(t *testing.T) {
+		srv := NewServer()
+		left, right := newStreamPair()
+		a := NewPeer(left, srv)
+		b := NewPeer(right, nil)
+		defer b.Close()
+		assert.Same(t, srv, a.Server())
+		assert.Len(t, srv.Conns(), 1)
+
+		require.NoError(t, a.Close())
+		require.NoError(t, a.Wait())
+		assert.Empty(t, srv.Conns())
+	})
+
+	t.Run("Wait returns once the remote end closes", func(t *testing.T) {
+		a, b := newPeerPair(t)
+		require.NoError(t, b.Close())
+
+		done := make(chan error, 1)
+		go func() { done <- a.Wait() }()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Wait did not return")
+		}
+		assert.ErrorIs(t, a.Call(ctx, "any", nil, nil), ErrClientClosed)
+	})
+}
@@ -45,35 +45,39 @@ var (
 	currentProfile = ProfileDefault
 
 	// sonicAPI is the configured sonic API instance used for all JSON operations.
-	sonicAPI sonic.API = sonic.ConfigDefault
+	sonicAPI = profileConfigs[ProfileDefault]
 
 	// profileMutex protects profile changes.
 	profileMutex sync.RWMutex
 
-	// profileConfigs stores pre-configured sonic API instances for each profile.
-	profileConfigs = map[PerformanceProfile]sonic.API{
-		ProfileDefault: sonic.ConfigDefault,
+	// profileSettings stores the sonic configuration of each profile.
+	profileSettings = map[PerformanceProfile]sonic.Config{
+		ProfileDefault: {},
 
-		ProfileCompatible: sonic.Config{
+		ProfileCompatible: {
 			EscapeHTML:       true, // encoding/json compatibility
 			SortMapKeys:      true, // encoding/json compatibility
 			CompactMarshaler: true, // No whitespace
 			CopyString:       true, // Safety over speed
 			ValidateString:   true, // Validate UTF-8
-		}.Froze(),
+		},
 
-		ProfileBalanced: sonic.Config{
+		ProfileBalanced: {
 			EscapeHTML:       false, // JSON-RPC doesn't contain HTML
 			SortMapKeys:      false, // Determinism not required
 			CompactMarshaler: true,  // No whitespace
 			NoNullSliceOrMap: true,  // Cleaner JSON output
 			CopyString:       true,  // Safety over speed
 			ValidateString:   true,  // Validate UTF-8
-		}.Froze(),
+		},
 
-		ProfileFast: sonic.ConfigFastest,
+		// Matches sonic.ConfigFastest
+		ProfileFast: {
+			NoValidateJSONMarshaler: true,
+			NoValidateJSONSkip:      true,
+		},
 
-		ProfileAggressive: sonic.Config{
+		ProfileAggressive: {
 			CopyString:              false, // Zero-copy (unsafe with buffer reuse)
 			NoNullSliceOrMap:        true,  // Cleaner JSON
 			NoValidateJSONMarshaler: true,  // Skip validation
@@ -82,12 +86,40 @@ var (
 			SortMapKeys:             false, // No sorting
 			CompactMarshaler:        true,  // No whitespace
 			ValidateString:          false, // No UTF-8 validation
-		}.Froze(),
+		},
 	}
+
+	// profileConfigs stores pre-configured sonic API instances for each profile.
+	profileConfigs = frozeProfiles(NumbersAsFloat64)
+
+	// numberProfileConfigs stores the sonic API instances of each profile decoding numbers as
+	// json.Number.
+	numberProfileConfigs = frozeProfiles(NumbersAsJSONNumber)
 )
 
+// frozeProfiles returns the sonic API instances of all profiles, decoding numbers as set by mode.
+func frozeProfiles(mode NumberDecoding) map[PerformanceProfile]sonic.API {
+	apis := make(map[PerformanceProfile]sonic.API, len(profileSettings))
+	for profile, cfg := range profileSettings {
+		cfg.UseNumber = mode == NumbersAsJSONNumber
+		apis[profile] = cfg.Froze()
+	}
+	return apis
+}
+
+// profileAPI returns the sonic API instance of profile, decoding numbers as set by mode.
+func profileAPI(profile PerformanceProfile, mode NumberDecoding) (sonic.API, bool) {
+	if mode == NumbersAsJSONNumber {
+		api, ok := numberProfileConfigs[profile]
+		return api, ok
+	}
+	api, ok := profileConfigs[profile]
+	return api, ok
+}
+
 // SetPerformanceProfile configures the JSON encoding/decoding behavior for all operations.
-// This function is thread-safe and affects all subsequent JSON operations in the package.
+// This function is thread-safe and affects all subsequent JSON operations in the package, unless
+// another codec was set with SetCodec.
 //
 // The available profiles are:
 //
@@ -105,8 +137,8 @@ func SetPerformanceProfile(profile PerformanceProfile) {
 	profileMutex.Lock()
 	defer profileMutex.Unlock()
 
-	if cfg, ok := profileConfigs[profile]; ok {
-		sonicAPI = cfg
+	if api, ok := profileAPI(profile, numberDecoding); ok {
+		sonicAPI = api
 		currentProfile = profile
 	}
 }
@@ -117,10 +149,3 @@ func GetPerformanceProfile() PerformanceProfile {
 	defer profileMutex.RUnlock()
 	return currentProfile
 }
-
-// getSonicAPI returns the current sonic API instance.
-func getSonicAPI() sonic.API {
-	profileMutex.RLock()
-	defer profileMutex.RUnlock()
-	return sonicAPI
-}
@@ -0,0 +1,398 @@
+// Package plugin runs plugins as subprocesses speaking JSON-RPC over their standard input and
+// output, in the manner of hashicorp/go-plugin but with a standard wire format, so that plugins
+// can be written in any language.
+//
+// A host starts a plugin with Start, which spawns the process and performs a handshake in which
+// the plugin picks the highest protocol version both ends speak and lists its methods. The host
+// then calls the plugin through the returned Host, a stream client, and may serve calls from the
+// plugin with WithHostServer. With WithRestart, a plugin that crashes is spawned again, with a
+// new handshake, while calls made meanwhile fail with jsonrpc.ErrConnectionLost.
+//
+// A plugin serves its methods with Serve, from its main function. Its standard output carries
+// the messages, so it must log to standard error only.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// HandshakeMethod is the method the host calls on a plugin once spawned, before any other.
+const HandshakeMethod = "plugin.handshake"
+
+// DefaultVersion is the protocol version spoken by hosts and plugins not listing any.
+const DefaultVersion = 1
+
+const (
+	// defaultHandshakeTimeout bounds the handshake of a spawned plugin.
+	defaultHandshakeTimeout = 10 * time.Second
+
+	// killGrace is how long a closed plugin has to exit after its input ends, before it is killed.
+	killGrace = 2 * time.Second
+
+	// handshakeID is the ID of the handshake request, the first on the stream.
+	handshakeID int64 = 0
+)
+
+// errHandshakeFormat formats the errors of a failed handshake.
+const errHandshakeFormat = "plugin: handshake: %w"
+
+// ErrIncompatible is returned by Start when the host and the plugin speak no common protocol
+// version.
+var ErrIncompatible = errors.New("plugin: no common protocol version")
+
+// HandshakeParams are the params of the handshake request.
+type HandshakeParams struct {
+	// Versions lists the protocol versions the host speaks.
+	Versions []int `json:"versions"`
+}
+
+// HandshakeResult is the result of the handshake request.
+type HandshakeResult struct {
+	// Version is the protocol version chosen by the plugin.
+	Version int `json:"version"`
+
+	// Methods lists the methods the plugin serves, the handshake excepted.
+	Methods []string `json:"methods"`
+}
+
+// versionKey is the session key under which a plugin keeps the negotiated protocol version.
+const versionKey = "plugin.version"
+
+// Serve serves srv to the host over the process's standard input and output until the input
+// ends, as it does once the host closes the plugin, or ctx is done. Versions lists the protocol
+// versions the plugin speaks, DefaultVersion if none; in the handshake, the plugin picks the
+// highest one the host also speaks.
+func Serve(ctx context.Context, srv *jsonrpc.Server, versions ...int) error {
+	return serve(ctx, srv, jsonrpc.NewStdioStream(jsonrpc.HeaderFraming), versions)
+}
+
+// serve registers the handshake with srv and serves stream.
+func serve(ctx context.Context, srv *jsonrpc.Server, stream jsonrpc.Stream, versions []int) error {
+	speaks := versions
+	if len(speaks) == 0 {
+		speaks = []int{DefaultVersion}
+	}
+	if err := srv.RegisterFunc(HandshakeMethod, answerHandshake(srv, speaks)); err != nil {
+		return err
+	}
+	return srv.ServeStream(ctx, stream)
+}
+
+// answerHandshake returns the handler of the handshake of a plugin serving srv, speaking
+// versions.
+func answerHandshake(
+	srv *jsonrpc.Server,
+	versions []int,
+) func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+	return func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		var params HandshakeParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, jsonrpc.ErrInvalidParams.WithData(err.Error())
+		}
+		version, ok := negotiate(versions, params.Versions)
+		if !ok {
+			return nil, jsonrpc.ErrInvalidParams.WithData(ErrIncompatible.Error())
+		}
+		if session, ok := jsonrpc.SessionFromContext(ctx); ok {
+			session.Set(versionKey, version)
+		}
+		methods := slices.DeleteFunc(srv.Methods(), func(method string) bool {
+			return method == HandshakeMethod
+		})
+		return HandshakeResult{Version: version, Methods: methods}, nil
+	}
+}
+
+// negotiate returns the highest version in both ours and theirs.
+func negotiate(ours, theirs []int) (int, bool) {
+	version, ok := 0, false
+	for _, v := range ours {
+		if (!ok || v > version) && slices.Contains(theirs, v) {
+			version, ok = v, true
+		}
+	}
+	return version, ok
+}
+
+// Version returns the protocol version negotiated with the host, from the context of a handler
+// of the plugin. It returns false outside handlers or before the handshake.
+func Version(ctx context.Context) (int, bool) {
+	session, ok := jsonrpc.SessionFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	version, ok := session.Get(versionKey)
+	if !ok {
+		return 0, false
+	}
+	v, ok := version.(int)
+	return v, ok
+}
+
+// Option configures Start.
+type Option func(*config)
+
+// config holds the settings of a host.
+type config struct {
+	versions         []int
+	restart          *jsonrpc.ReconnectPolicy
+	server           *jsonrpc.Server
+	stderr           io.Writer
+	handshakeTimeout time.Duration
+	clientOptions    []jsonrpc.ClientOption
+}
+
+// newConfig returns the settings resulting from opts.
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		versions:         []int{DefaultVersion},
+		stderr:           os.Stderr,
+		handshakeTimeout: defaultHandshakeTimeout,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithVersions sets the protocol versions the host speaks. Defaults to DefaultVersion.
+func WithVersions(versions ...int) Option {
+	return func(c *config) {
+		c.versions = versions
+	}
+}
+
+// WithRestart makes the host spawn the plugin again when it crashes or its output ends, backing
+// off between attempts according to policy, as with jsonrpc.WithReconnect. Without it, the host
+// shuts down with the plugin.
+func WithRestart(policy jsonrpc.ReconnectPolicy) Option {
+	return func(c *config) {
+		c.restart = &policy
+	}
+}
+
+// WithHostServer serves the calls and notifications the plugin sends to the host with srv.
+func WithHostServer(srv *jsonrpc.Server) Option {
+	return func(c *config) {
+		c.server = srv
+	}
+}
+
+// WithStderr sets where the standard error of the plugin goes, unless the command sets it.
+// Defaults to the host's standard error.
+func WithStderr(w io.Writer) Option {
+	return func(c *config) {
+		c.stderr = w
+	}
+}
+
+// WithHandshakeTimeout bounds the wait for the handshake of a spawned plugin. Defaults to 10s.
+func WithHandshakeTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.handshakeTimeout = d
+	}
+}
+
+// WithClientOptions sets options of the client calling the plugin, such as interceptors.
+func WithClientOptions(opts ...jsonrpc.ClientOption) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, opts...)
+	}
+}
+
+// Command returns a command function for Start running the named program with args.
+func Command(name string, args ...string) func() *exec.Cmd {
+	return func() *exec.Cmd {
+		return exec.Command(name, args...)
+	}
+}
+
+// Host is the host's end of a running plugin: a stream client calling the plugin's methods.
+// Closing it ends the plugin's input and kills the plugin if it has not exited shortly after.
+type Host struct {
+	*jsonrpc.Client
+
+	current atomic.Pointer[instance]
+}
+
+// instance describes a spawned plugin process past its handshake.
+type instance struct {
+	pid    int
+	result HandshakeResult
+}
+
+// Start spawns the plugin run by the command that command returns, called again for every
+// restart, and performs the handshake within ctx. It returns ErrIncompatible if the plugin
+// speaks none of the host's versions. The process is not bound to ctx; it runs until the host is
+// closed.
+func Start(ctx context.Context, command func() *exec.Cmd, opts ...Option) (*Host, error) {
+	cfg := newConfig(opts)
+	h := &Host{}
+	stream, err := h.launch(ctx, command, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts := slices.Clone(cfg.clientOptions)
+	if cfg.server != nil {
+		clientOpts = append(clientOpts, jsonrpc.WithServer(cfg.server))
+	}
+	if cfg.restart != nil {
+		dial := func(ctx context.Context) (jsonrpc.Stream, error) {
+			return h.launch(ctx, command, cfg)
+		}
+		clientOpts = append(clientOpts, jsonrpc.WithReconnect(dial, *cfg.restart))
+	}
+	h.Client = jsonrpc.NewStreamClient(stream, clientOpts...)
+	return h, nil
+}
+
+// Version returns the protocol version negotiated with the running plugin.
+func (h *Host) Version() int {
+	return h.current.Load().result.Version
+}
+
+// Methods returns the methods served by the running plugin, as listed in its handshake.
+func (h *Host) Methods() []string {
+	return slices.Clone(h.current.Load().result.Methods)
+}
+
+// Pid returns the process ID of the running plugin, which changes with every restart.
+func (h *Host) Pid() int {
+	return h.current.Load().pid
+}
+
+// launch spawns the plugin and performs the handshake, returning the stream to the plugin.
+func (h *Host) launch(
+	ctx context.Context,
+	command func() *exec.Cmd,
+	cfg *config,
+) (jsonrpc.Stream, error) {
+	cmd := command()
+	if cmd.Stderr == nil {
+		cmd.Stderr = cfg.stderr
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+
+	proc := &process{
+		Stream: jsonrpc.NewFramedStream(stdout, stdin, jsonrpc.HeaderFraming),
+		cmd:    cmd,
+	}
+	hsCtx, cancel := context.WithTimeout(ctx, cfg.handshakeTimeout)
+	defer cancel()
+	result, err := handshake(hsCtx, proc, cfg.versions)
+	if err != nil {
+		_ = proc.Close()
+		return nil, err
+	}
+	h.current.Store(&instance{pid: cmd.Process.Pid, result: *result})
+	return proc, nil
+}
+
+// handshake sends the handshake request on stream and reads its response, the first message from
+// the plugin. A read outlasting ctx is left to end with the stream, which the caller closes.
+func handshake(
+	ctx context.Context,
+	stream jsonrpc.Stream,
+	versions []int,
+) (*HandshakeResult, error) {
+	params := HandshakeParams{Versions: versions}
+	msg, err := jsonrpc.NewRequestWithID(HandshakeMethod, params, handshakeID).MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.WriteMessage(ctx, msg); err != nil {
+		return nil, fmt.Errorf(errHandshakeFormat, err)
+	}
+
+	type reply struct {
+		msg []byte
+		err error
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		msg, err := stream.ReadMessage(ctx)
+		replies <- reply{msg: msg, err: err}
+	}()
+	var r reply
+	select {
+	case r = <-replies:
+	case <-ctx.Done():
+		return nil, fmt.Errorf(errHandshakeFormat, ctx.Err())
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf(errHandshakeFormat, r.err)
+	}
+
+	resp, err := jsonrpc.DecodeResponse(r.msg)
+	if err != nil {
+		return nil, fmt.Errorf(errHandshakeFormat, err)
+	}
+	if rpcErr := resp.Err(); rpcErr != nil {
+		return nil, handshakeError(rpcErr)
+	}
+	var result HandshakeResult
+	if err := resp.UnmarshalResult(&result); err != nil {
+		return nil, fmt.Errorf(errHandshakeFormat, err)
+	}
+	if !slices.Contains(versions, result.Version) {
+		return nil, fmt.Errorf("%w: plugin chose version %d", ErrIncompatible, result.Version)
+	}
+	return &result, nil
+}
+
+// handshakeError returns the error for a handshake the plugin rejected.
+func handshakeError(rpcErr *jsonrpc.Error) error {
+	var detail string
+	if rpcErr.Code == jsonrpc.InvalidParams && rpcErr.UnmarshalData(&detail) == nil &&
+		detail == ErrIncompatible.Error() {
+		return ErrIncompatible
+	}
+	return fmt.Errorf(errHandshakeFormat, rpcErr)
+}
+
+// process is the stream to a plugin process, which closing ends.
+type process struct {
+	jsonrpc.Stream
+
+	cmd *exec.Cmd
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Close closes the plugin's input and output and waits for it to exit, killing it if it has not
+// within killGrace.
+func (p *process) Close() error {
+	p.closeOnce.Do(func() {
+		p.closeErr = p.Stream.Close()
+		timer := time.AfterFunc(killGrace, func() {
+			_ = p.cmd.Process.Kill()
+		})
+		defer timer.Stop()
+		// The exit status of a plugin being closed, or having crashed, is of no interest.
+		_ = p.cmd.Wait()
+	})
+	return p.closeErr
+}
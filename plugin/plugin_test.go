@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// helperEnv makes the test binary run as the test plugin, speaking the versions it lists.
+const helperEnv = "JSONRPC_TEST_PLUGIN_VERSIONS"
+
+// TestMain runs the test binary as the test plugin when started by helperCommand.
+func TestMain(m *testing.M) {
+	if versions, ok := os.LookupEnv(helperEnv); ok {
+		runHelper(versions)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runHelper serves the methods of the test plugin.
+func runHelper(versions string) {
+	var vs []int
+	for field := range strings.SplitSeq(versions, ",") {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			os.Exit(2)
+		}
+		vs = append(vs, v)
+	}
+
+	srv := jsonrpc.NewServer()
+	_ = srv.RegisterFunc("echo", func(_ context.Context, req *jsonrpc.Request) (any, error) {
+		var params []string
+		if err := req.UnmarshalParams(&params); err != nil || len(params) != 1 {
+			return nil, jsonrpc.ErrInvalidParams
+		}
+		return params[0], nil
+	})
+	_ = srv.RegisterFunc("version", func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+		v, _ := Version(ctx)
+		return v, nil
+	})
+	_ = srv.RegisterFunc("crash", func(context.Context, *jsonrpc.Request) (any, error) {
+		os.Exit(1)
+		return nil, nil
+	})
+	_ = srv.RegisterFunc("ask", func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+		host, _ := jsonrpc.ClientFromContext(ctx)
+		var answer string
+		err := host.Call(ctx, "host.answer", nil, &answer)
+		return answer, err
+	})
+	if err := Serve(context.Background(), srv, vs...); err != nil {
+		os.Exit(1)
+	}
+}
+
+// helperCommand returns a command running the test binary as a plugin speaking versions.
+func helperCommand(versions string) func() *exec.Cmd {
+	return func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		cmd.Env = append(os.Environ(), helperEnv+"="+versions)
+		return cmd
+	}
+}
+
+// startHelper starts the test plugin and closes it at the end of the test.
+func startHelper(t *testing.T, versions string, opts ...Option) *Host {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	h, err := Start(ctx, helperCommand(versions), opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = h.Close() })
+	return h
+}
+
+func TestStart(t *testing.T) {
+	t.Run("negotiates the highest common version", func(t *testing.T) {
+		h := startHelper(t, "1,2,3", WithVersions(2, 3, 4))
+		assert.Equal(t, 3, h.Version())
+		assert.ElementsMatch(t, []string{"ask", "crash", "echo", "version"}, h.Methods())
+
+		var v int
+		require.NoError(t, h.Call(context.Background(), "version", nil, &v))
+		assert.Equal(t, 3, v)
+
+		var echoed string
+		require.NoError(t, h.Call(context.Background(), "echo", []string{"hello"}, &echoed))
+		assert.Equal(t, "hello", echoed)
+	})
+
+	t.Run("defaults to the default version", func(t *testing.T) {
+		h := startHelper(t, strconv.Itoa(DefaultVersion))
+		assert.Equal(t, DefaultVersion, h.Version())
+	})
+
+	t.Run("rejects a plugin without a common version", func(t *testing.T) {
+		_, err := Start(context.Background(), helperCommand("1"), WithVersions(2))
+		assert.ErrorIs(t, err, ErrIncompatible)
+	})
+
+	t.Run("fails for a missing program", func(t *testing.T) {
+		_, err := Start(context.Background(), Command("./no-such-plugin"))
+		assert.Error(t, err)
+	})
+
+	t.Run("fails for a program not answering the handshake", func(t *testing.T) {
+		_, err := Start(context.Background(), Command("sleep", "10"),
+			WithHandshakeTimeout(50*time.Millisecond))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestHost(t *testing.T) {
+	t.Run("serves calls from the plugin", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("host.answer",
+			func(context.Context, *jsonrpc.Request) (any, error) {
+				return "42", nil
+			}))
+		h := startHelper(t, "1", WithHostServer(srv))
+
+		var answer string
+		require.NoError(t, h.Call(context.Background(), "ask", nil, &answer))
+		assert.Equal(t, "42", answer)
+	})
+
+	t.Run("restarts a crashed plugin", func(t *testing.T) {
+		events := make(chan jsonrpc.ReconnectEvent, 10)
+		h := startHelper(t, "1", WithRestart(jsonrpc.ReconnectPolicy{
+			InitialBackoff: 10 * time.Millisecond,
+			OnReconnect:    func(event jsonrpc.ReconnectEvent) { events <- event },
+		}))
+		pid := h.Pid()
+
+		err := h.Call(context.Background(), "crash", nil, nil)
+		require.Error(t, err)
+
+		select {
+		case event := <-events:
+			require.NoError(t, event.Err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("plugin was not restarted")
+		}
+		assert.NotEqual(t, pid, h.Pid())
+
+		var echoed string
+		require.NoError(t, h.Call(context.Background(), "echo", []string{"again"}, &echoed))
+		assert.Equal(t, "again", echoed)
+	})
+
+	t.Run("shuts down with a crashed plugin without restart", func(t *testing.T) {
+		h := startHelper(t, "1")
+		err := h.Call(context.Background(), "crash", nil, nil)
+		require.Error(t, err)
+
+		select {
+		case <-h.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("host did not shut down")
+		}
+	})
+
+	t.Run("close stops the plugin", func(t *testing.T) {
+		h := startHelper(t, "1")
+		proc, err := os.FindProcess(h.Pid())
+		require.NoError(t, err)
+
+		require.NoError(t, h.Close())
+		err = h.Call(context.Background(), "echo", []string{"late"}, nil)
+		assert.ErrorIs(t, err, jsonrpc.ErrClientClosed)
+		_, err = proc.Wait()
+		assert.Error(t, err, "the plugin has been waited for")
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name         string
+		ours, theirs []int
+		want         int
+		ok           bool
+	}{
+		{name: "highest common", ours: []int{1, 2, 3}, theirs: []int{3, 2}, want: 3, ok: true},
+		{name: "unordered", ours: []int{3, 1, 2}, theirs: []int{1, 2}, want: 2, ok: true},
+		{name: "none common", ours: []int{1}, theirs: []int{2}},
+		{name: "none offered", ours: []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := negotiate(tt.ours, tt.theirs)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
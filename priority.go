@@ -0,0 +1,78 @@
+package jsonrpc
+
+import (
+	"context"
+)
+
+// Priority orders the requests waiting for a slot under a concurrency limit: requests of higher
+// priority are dispatched first, and those of equal priority in arrival order. Any value may be
+// used; requests without a priority have PriorityNormal.
+type Priority int
+
+const (
+	// PriorityLow is for expensive or background requests, dispatched after the others.
+	PriorityLow Priority = -1
+
+	// PriorityNormal is the priority of requests without one.
+	PriorityNormal Priority = 0
+
+	// PriorityHigh is for cheap or urgent requests, such as health checks.
+	PriorityHigh Priority = 1
+)
+
+// priorityContextKey is the context key under which the priority of a request is stored.
+type priorityContextKey struct{}
+
+// WithPriority returns a copy of ctx carrying priority for the requests handled with it, such as
+// from an HTTP middleware setting the priority of each request before Server.ServeHTTP:
+//
+//	ctx := jsonrpc.WithPriority(r.Context(), jsonrpc.PriorityHigh)
+//	srv.ServeHTTP(w, r.WithContext(ctx))
+//
+// Handlers read the priority of their request with PriorityFromContext.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// PriorityFromContext returns the priority carried by ctx, and false if it carries none.
+func PriorityFromContext(ctx context.Context) (Priority, bool) {
+	priority, ok := ctx.Value(priorityContextKey{}).(Priority)
+	return priority, ok
+}
+
+// WithMethodPriority sets the priority of the requests of method, taking precedence over the
+// priority carried by their context, so that a server under load dispatches health checks and
+// cheap reads before expensive methods. Priorities only apply to requests waiting under
+// WithConcurrencyLimit or WithMethodConcurrencyLimit.
+func WithMethodPriority(method string, priority Priority) ServerOption {
+	return func(s *Server) {
+		if s.methodPriorities == nil {
+			s.methodPriorities = make(map[string]Priority)
+		}
+		s.methodPriorities[method] = priority
+	}
+}
+
+// WithProxyMethodPriority sets the priority of the calls of method forwarded by a proxy under
+// WithProxyConcurrencyLimit, taking precedence over the priority carried by their context. A
+// batch headed for one upstream has the highest priority of its members.
+func WithProxyMethodPriority(method string, priority Priority) ProxyOption {
+	return func(p *Proxy) {
+		if p.priorities == nil {
+			p.priorities = make(map[string]Priority)
+		}
+		p.priorities[method] = priority
+	}
+}
+
+// priorityOf returns the priority of req: the one set for its method, else the one carried by
+// ctx, else PriorityNormal.
+func priorityOf(ctx context.Context, methods map[string]Priority, req *Request) Priority {
+	if priority, ok := methods[req.Method]; ok {
+		return priority
+	}
+	if priority, ok := PriorityFromContext(ctx); ok {
+		return priority
+	}
+	return PriorityNormal
+}
@@ -0,0 +1,180 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync queues for a slot of l in the background, returning the channel its result is
+// sent on once it is queued.
+func acquireAsync(t *testing.T, l *limiter, priority Priority) <-chan error {
+	t.Helper()
+	out := make(chan error, 1)
+	queued := queuedWith(l, priority)
+	go func() { out <- l.acquire(context.Background(), priority) }()
+	require.Eventually(t, func() bool {
+		return queuedWith(l, priority) > queued
+	}, time.Second, time.Millisecond)
+	return out
+}
+
+// queuedWith returns the number of requests of the given priority waiting on l.
+func queuedWith(l *limiter, priority Priority) int {
+	n := 0
+	for _, p := range queuedPriorities(l) {
+		if p == priority {
+			n++
+		}
+	}
+	return n
+}
+
+// queuedPriorities returns the priorities of the requests waiting on l, in queue order.
+func queuedPriorities(l *limiter) []Priority {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	priorities := make([]Priority, len(l.waiters))
+	for i, w := range l.waiters {
+		priorities[i] = w.priority
+	}
+	return priorities
+}
+
+func TestLimiter_Priority(t *testing.T) {
+	t.Run("Dispatches by priority, then arrival", func(t *testing.T) {
+		l := newLimiter(ConcurrencyLimit{MaxConcurrent: 1})
+		require.NoError(t, l.acquire(context.Background(), PriorityNormal))
+
+		low := acquireAsync(t, l, PriorityLow)
+		normal := acquireAsync(t, l, PriorityNormal)
+		high := acquireAsync(t, l, PriorityHigh)
+		later := acquireAsync(t, l, PriorityNormal)
+		assert.Equal(t,
+			[]Priority{PriorityHigh, PriorityNormal, PriorityNormal, PriorityLow},
+			queuedPriorities(l))
+
+		for _, next := range []<-chan error{high, normal, later, low} {
+			l.release()
+			select {
+			case err := <-next:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("slot not handed over in priority order")
+			}
+		}
+	})
+
+	t.Run("Full queue makes room for higher priorities", func(t *testing.T) {
+		l := newLimiter(ConcurrencyLimit{MaxConcurrent: 1, MaxQueue: 2})
+		require.NoError(t, l.acquire(context.Background(), PriorityNormal))
+
+		_ = acquireAsync(t, l, PriorityNormal)
+		newest := acquireAsync(t, l, PriorityNormal)
+		assert.ErrorIs(t, l.acquire(context.Background(), PriorityNormal), ErrServerBusy)
+		assert.ErrorIs(t, l.acquire(context.Background(), PriorityLow), ErrServerBusy)
+
+		_ = acquireAsync(t, l, PriorityHigh)
+		assert.ErrorIs(t, <-newest, ErrServerBusy)
+		assert.Equal(t, []Priority{PriorityHigh, PriorityNormal}, queuedPriorities(l))
+	})
+
+	t.Run("Shedding drops the oldest of the lowest priority", func(t *testing.T) {
+		l := newLimiter(ConcurrencyLimit{MaxConcurrent: 1, MaxQueue: 3, Overload: OverloadShed})
+		require.NoError(t, l.acquire(context.Background(), PriorityNormal))
+
+		_ = acquireAsync(t, l, PriorityHigh)
+		oldest := acquireAsync(t, l, PriorityLow)
+		_ = acquireAsync(t, l, PriorityLow)
+		go func() { _ = l.acquire(context.Background(), PriorityLow) }()
+		assert.ErrorIs(t, <-oldest, ErrServerBusy)
+
+		_ = acquireAsync(t, l, PriorityHigh)
+		assert.Equal(t, []Priority{PriorityHigh, PriorityHigh, PriorityLow}, queuedPriorities(l))
+
+		shed := newLimiter(ConcurrencyLimit{MaxConcurrent: 1, MaxQueue: 1, Overload: OverloadShed})
+		require.NoError(t, shed.acquire(context.Background(), PriorityNormal))
+		_ = acquireAsync(t, shed, PriorityHigh)
+		assert.ErrorIs(t, shed.acquire(context.Background(), PriorityLow), ErrServerBusy)
+	})
+}
+
+func TestServer_Priority(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(_ context.Context, req *Request) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, req.Method)
+		return nil, nil
+	}
+
+	g := newGate()
+	srv := NewServer(
+		WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1}),
+		WithMethodPriority("health", PriorityHigh),
+	)
+	require.NoError(t, srv.Register("block", g))
+	require.NoError(t, srv.RegisterFunc("expensive", record))
+	require.NoError(t, srv.RegisterFunc("read", record))
+	require.NoError(t, srv.RegisterFunc("health", record))
+
+	blocked := handleAsync(ctx, srv, "block", 1)
+	g.waitStarted(t, 1)
+
+	expensive := handleAsync(WithPriority(ctx, PriorityLow), srv, "expensive", 2)
+	require.Eventually(t, func() bool { return queueLen(srv.limiter) == 1 }, time.Second,
+		time.Millisecond)
+	read := handleAsync(ctx, srv, "read", 3)
+	require.Eventually(t, func() bool { return queueLen(srv.limiter) == 2 }, time.Second,
+		time.Millisecond)
+	health := handleAsync(WithPriority(ctx, PriorityLow), srv, "health", 4)
+	require.Eventually(t, func() bool { return queueLen(srv.limiter) == 3 }, time.Second,
+		time.Millisecond)
+
+	g.open()
+	for _, ch := range []<-chan *Response{blocked, expensive, read, health} {
+		require.Nil(t, awaitResponse(t, ch).Err())
+	}
+	assert.Equal(t, []string{"health", "read", "expensive"}, order)
+}
+
+func TestProxy_Priority(t *testing.T) {
+	ctx := context.Background()
+	g := newGate()
+	upstreamSrv := newTestServer(t)
+	require.NoError(t, upstreamSrv.Register("block", g))
+	roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+		return upstreamSrv.HandleMessage(ctx, payload), nil
+	}
+	upstream := NewClient(&funcTransport{fn: roundTrip})
+	defer upstream.Close()
+
+	proxy := NewProxy(upstream,
+		WithProxyConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1, MaxQueue: 1}),
+		WithProxyMethodPriority("sum", PriorityHigh))
+
+	forward := func(msg string) <-chan []byte {
+		out := make(chan []byte, 1)
+		go func() { out <- proxy.HandleMessage(ctx, []byte(msg)) }()
+		return out
+	}
+	blocked := forward(`{"jsonrpc":"2.0","method":"block","id":1}`)
+	g.waitStarted(t, 1)
+
+	queued := forward(`{"jsonrpc":"2.0","method":"subtract","params":[3,1],"id":2}`)
+	require.Eventually(t, func() bool { return queueLen(proxy.limiter) == 1 }, time.Second,
+		time.Millisecond)
+	sum := forward(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":3}`)
+
+	assert.Contains(t, string(<-queued), `"code":-32011`, "displaced by the higher priority")
+	g.open()
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":3,"id":3}`, string(<-sum))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":"done","id":1}`, string(<-blocked))
+}
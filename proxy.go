@@ -0,0 +1,397 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// UpstreamUnavailable is the error code of responses a Proxy sends for calls it failed to forward,
+// within the range reserved for implementation-defined server errors.
+const UpstreamUnavailable = -32013
+
+// msgUpstreamUnavailable is the message of UpstreamUnavailable errors.
+const msgUpstreamUnavailable = "Upstream unavailable"
+
+// ErrUpstreamUnavailable is the error a Proxy sends for calls it failed to forward. The errors
+// actually sent carry the cause as data; match them with errors.Is.
+var ErrUpstreamUnavailable = &Error{Code: UpstreamUnavailable, Message: msgUpstreamUnavailable}
+
+// Proxy forwards the JSON-RPC messages of downstream clients to upstream servers, as the building
+// block of gateways, load balancers, and caching layers. Each upstream is a Client, so that it may
+// be reached over HTTP, a pool of endpoints, or a stream, with its own interceptors and retries.
+//
+// The proxy gives every forwarded call a fresh ID, unique among all calls it has in flight, and
+// restores the downstream ID on the response, so that downstream clients with clashing IDs can
+// share an upstream stream. The members of a batch headed for the same upstream are forwarded as
+// one batch, and the responses of all upstreams are merged back into one reply in request order.
+// Notifications are passed through and get no response. Calls that cannot be forwarded are
+// answered with ErrUpstreamUnavailable, or with the error of the upstream if it rejected a whole
+// batch. Notifications the upstreams push on streams are not relayed, except those of the
+// subscriptions relayed with WithProxySubscriptions.
+//
+// A Proxy is safe for concurrent use.
+type Proxy struct {
+	upstream   *Client
+	routes     map[string]*Client
+	router     func(req *Request) *Client
+	idGen      IDGenerator
+	cache      *responseCache
+	mirror     *mirror
+	transforms map[string]ProxyTransform
+
+	limiter    *limiter
+	priorities map[string]Priority
+	subs       *proxySubscriptions
+	admin      *adminTracker
+	adminSrv   *Server
+}
+
+// ProxyOption configures a Proxy.
+type ProxyOption func(*Proxy)
+
+// WithProxyRoute forwards the calls of method to upstream in place of the default upstream.
+func WithProxyRoute(method string, upstream *Client) ProxyOption {
+	return func(p *Proxy) {
+		p.routes[method] = upstream
+	}
+}
+
+// WithProxyRouter makes the proxy pick the upstream of every call with router, for instance by
+// hashing its params. Calls for which router returns nil go to the upstream set for their method
+// with WithProxyRoute, or to the default upstream.
+func WithProxyRouter(router func(req *Request) *Client) ProxyOption {
+	return func(p *Proxy) {
+		p.router = router
+	}
+}
+
+// WithProxyIDGenerator sets how the proxy generates the IDs of forwarded calls. Defaults to
+// NewSequentialIDGenerator.
+func WithProxyIDGenerator(gen IDGenerator) ProxyOption {
+	return func(p *Proxy) {
+		p.idGen = gen
+	}
+}
+
+// NewProxy creates a proxy forwarding calls to upstream, unless routed elsewhere by its options.
+// Closing the proxy's upstreams is left to the caller.
+func NewProxy(upstream *Client, opts ...ProxyOption) *Proxy {
+	p := &Proxy{
+		upstream: upstream,
+		routes:   make(map[string]*Client),
+		idGen:    NewSequentialIDGenerator(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// HandleMessage forwards a single encoded request or batch and returns the encoded reply, or nil
+// when the message held only notifications. Like Server.HandleMessage, it answers malformed JSON
+// with a parse error and invalid requests with invalid request errors, without forwarding them.
+func (p *Proxy) HandleMessage(ctx context.Context, data []byte) []byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || !getCodec().Valid(trimmed) {
+		return encodeResponse(parseErrorResponse())
+	}
+
+	if !isBatchJSON(trimmed) {
+		resp := p.forward(ctx, []json.RawMessage{trimmed})[0]
+		if resp == nil {
+			return nil
+		}
+		return encodeResponse(resp)
+	}
+
+	var rawMessages []json.RawMessage
+	if err := getCodec().Unmarshal(trimmed, &rawMessages); err != nil || len(rawMessages) == 0 {
+		return encodeResponse(invalidRequestResponse())
+	}
+	results := p.forward(ctx, rawMessages)
+	resps := make([]*Response, 0, len(results))
+	for _, resp := range results {
+		if resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		return nil
+	}
+	reply, err := EncodeBatchResponse(resps)
+	if err != nil {
+		return internalErrorResponse
+	}
+	return reply
+}
+
+// ServeHTTP implements http.Handler, so that a Proxy can be mounted on a mux like a Server. It
+// accepts the same requests and answers with the same status codes as Server.ServeHTTP.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := readAll(r.Body, defaultChunkSize, int(r.ContentLength))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	reply := p.HandleMessage(r.Context(), body)
+	if len(reply) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(httpStatusFor(reply))
+	_, _ = w.Write(reply)
+}
+
+// ServeStream forwards the messages arriving on a persistent stream until reading from the stream
+// fails or ctx is done, and then closes the stream. Messages are forwarded concurrently, and
+// their replies written as they complete.
+//
+// ServeStream returns nil when the peer closes the stream, ctx.Err() when ctx is done, ErrKicked
+// or ErrServerClosed once the stream is kicked or drained through the admin methods of
+// WithProxyAdmin, and the read error otherwise.
+func (p *Proxy) ServeStream(ctx context.Context, stream Stream) error {
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	defer stop()
+	defer func() { _ = stream.Close() }()
+
+	msgCtx, tracked := p.trackStream(ctx, stream)
+	defer tracked.end()
+	conn := &proxyConn{ctx: ctx, stream: stream}
+	if p.subs != nil {
+		conn = p.subs.connect(ctx, stream)
+		defer p.subs.disconnect(conn)
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		msg, err := stream.ReadMessage(ctx)
+		switch {
+		case err == nil:
+		case tracked.kicked() != nil:
+			return tracked.kicked()
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, io.EOF):
+			return nil
+		default:
+			return err
+		}
+
+		wg.Go(func() {
+			subCtx, attach := p.subs.message(msgCtx, conn)
+			defer attach()
+			if reply := p.HandleMessage(subCtx, msg); len(reply) > 0 {
+				_ = conn.write(reply)
+			}
+		})
+	}
+}
+
+// forwardGroup is the part of a message headed for one upstream.
+type forwardGroup struct {
+	upstream *Client
+	reqs     []*Request // With the IDs of the proxy
+	indexes  []int      // Positions of reqs in the message
+	ids      []any      // Downstream IDs of reqs
+	priority Priority   // Highest priority of reqs
+}
+
+// forward decodes the members of a message, forwards them grouped by upstream, and returns the
+// response to each member, nil for notifications.
+func (p *Proxy) forward(ctx context.Context, rawMessages []json.RawMessage) []*Response {
+	results := make([]*Response, len(rawMessages))
+	var slots map[int]cacheSlot
+	if p.cache != nil {
+		slots = make(map[int]cacheSlot)
+	}
+
+	var methods []string
+	if p.transforms != nil {
+		methods = make([]string, len(rawMessages))
+	}
+
+	var tracked []uint64
+	defer func() { p.admin.end(tracked...) }()
+
+	var groups []*forwardGroup
+	byUpstream := make(map[*Client]*forwardGroup)
+	for i, raw := range rawMessages {
+		req, err := DecodeRequestLazy(raw)
+		if err != nil {
+			results[i] = invalidRequestResponse()
+			continue
+		}
+		if p.local(ctx, i, req, results) {
+			continue
+		}
+		tracked = p.track(ctx, req, tracked)
+		p.mirror.send(ctx, req, p.idGen)
+		if p.cached(i, req, results, slots) {
+			continue
+		}
+		upstream := p.route(req)
+		if resp, ok := p.subs.handle(ctx, upstream, req); ok {
+			results[i] = resp
+			continue
+		}
+		priority := priorityOf(ctx, p.priorities, req)
+		if !p.rewriteParams(ctx, i, req, methods, results) {
+			continue
+		}
+		group, ok := byUpstream[upstream]
+		if !ok {
+			group = &forwardGroup{upstream: upstream, priority: priority}
+			byUpstream[upstream] = group
+			groups = append(groups, group)
+		}
+		group.add(i, req, p.idGen.NextID())
+		group.priority = max(group.priority, priority)
+	}
+
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		// Groups fill distinct results
+		wg.Go(func() { p.sendGroup(ctx, group, results) })
+	}
+	wg.Wait()
+
+	p.transformResults(ctx, methods, results)
+	for i, slot := range slots {
+		p.cache.set(slot, results[i])
+	}
+	return results
+}
+
+// cached answers the request at position index of the message from the cache, reporting whether
+// it did, and otherwise records in slots where to cache its response.
+func (p *Proxy) cached(index int, req *Request, results []*Response, slots map[int]cacheSlot) bool {
+	if p.cache == nil {
+		return false
+	}
+	slot, ok := p.cache.slot(req)
+	if !ok {
+		return false
+	}
+	if results[index] = p.cache.get(slot, req.ID); results[index] != nil {
+		return true
+	}
+	slots[index] = slot
+	return false
+}
+
+// route returns the upstream to forward req to.
+func (p *Proxy) route(req *Request) *Client {
+	if p.router != nil {
+		if upstream := p.router(req); upstream != nil {
+			return upstream
+		}
+	}
+	if upstream, ok := p.routes[req.Method]; ok {
+		return upstream
+	}
+	return p.upstream
+}
+
+// sendGroup sends group once it has a slot under the concurrency limit, if any.
+func (p *Proxy) sendGroup(ctx context.Context, group *forwardGroup, results []*Response) {
+	if p.limiter != nil {
+		if err := p.limiter.acquire(ctx, group.priority); err != nil {
+			group.fail(err, results)
+			return
+		}
+		defer p.limiter.release()
+	}
+	group.send(ctx, results)
+}
+
+// add appends the request at position index of the message, rewriting its ID to id unless it is a
+// notification.
+func (g *forwardGroup) add(index int, req *Request, id any) {
+	downstreamID := req.ID
+	if !req.IsNotification() {
+		req.ID = id
+	}
+	g.reqs = append(g.reqs, req)
+	g.indexes = append(g.indexes, index)
+	g.ids = append(g.ids, downstreamID)
+}
+
+// send forwards the group, as a batch if it has several members, and stores the responses in
+// results with their downstream IDs.
+func (g *forwardGroup) send(ctx context.Context, results []*Response) {
+	var resps []*Response
+	var err error
+	if len(g.reqs) == 1 {
+		var resp *Response
+		resp, err = g.upstream.invoke(ctx, g.reqs[0])
+		if resp != nil {
+			resps = []*Response{resp}
+		}
+	} else {
+		resps, err = g.upstream.CallBatch(ctx, g.reqs)
+	}
+
+	if err != nil {
+		g.fail(err, results)
+		return
+	}
+
+	next := 0
+	for i, req := range g.reqs {
+		if req.IsNotification() {
+			continue
+		}
+		switch {
+		case next < len(resps):
+			results[g.indexes[i]] = restoreID(resps[next], g.ids[i])
+			next++
+		default:
+			results[g.indexes[i]] = NewErrorResponse(g.ids[i], upstreamError(
+				errors.New("missing response from upstream")))
+		}
+	}
+}
+
+// fail answers every call of the group with the error to send for err.
+func (g *forwardGroup) fail(err error, results []*Response) {
+	for i, req := range g.reqs {
+		if !req.IsNotification() {
+			results[g.indexes[i]] = NewErrorResponse(g.ids[i], upstreamError(err))
+		}
+	}
+}
+
+// restoreID returns resp with the downstream ID id.
+func restoreID(resp *Response, id any) *Response {
+	restored, err := resp.WithID(id)
+	if err != nil {
+		return NewErrorResponse(id, ErrInternal)
+	}
+	return restored
+}
+
+// upstreamError returns the error to answer a call failing to be forwarded with err: the error of
+// the upstream if it answered with one, and ErrUpstreamUnavailable otherwise.
+func upstreamError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return ErrUpstreamUnavailable.WithData(err.Error())
+}
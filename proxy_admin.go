@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// adminStreamContextKey is the context key of the adminStream a proxied message arrived on.
+type adminStreamContextKey struct{}
+
+// WithProxyAdmin tracks the downstream connections and calls in flight of the proxy and serves
+// the admin methods of WithAdmin. Unless policy.Separate is set, calls in the admin namespace
+// are answered by the proxy rather than forwarded. For a proxy, admin.methods returns the
+// methods routed with WithProxyRoute, admin.rateLimits returns no counters, and admin.drain
+// refuses new calls with ErrShuttingDown, waits for the calls in flight to be answered, and then
+// closes the connections served by ServeStream, which return ErrServerClosed.
+func WithProxyAdmin(policy AdminPolicy) ProxyOption {
+	return func(p *Proxy) {
+		p.admin = newAdminTracker(policy)
+		if !policy.Separate {
+			p.adminSrv = NewServer()
+			registerAdmin(p.adminSrv, p, policy)
+		}
+	}
+}
+
+// AdminServer returns a new server, configured by opts, serving only the admin methods of p, to
+// be served on a listener of its own. Proxies created without WithProxyAdmin list no connections
+// nor calls in flight.
+func (p *Proxy) AdminServer(opts ...ServerOption) *Server {
+	admin := NewServer(opts...)
+	registerAdmin(admin, p, p.admin.adminPolicy())
+	return admin
+}
+
+// adminMethods implements adminTarget.
+func (p *Proxy) adminMethods() []string {
+	names := make([]string, 0, len(p.routes))
+	for name := range p.routes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// adminDrain implements adminTarget.
+func (p *Proxy) adminDrain(ctx context.Context) error {
+	return p.admin.drain(ctx)
+}
+
+// adminTracker implements adminTarget.
+func (p *Proxy) adminTracker() *adminTracker {
+	return p.admin
+}
+
+// local answers the request at position index of the message without forwarding it, if it is an
+// admin call or the proxy is draining, reporting whether it did.
+func (p *Proxy) local(ctx context.Context, index int, req *Request, results []*Response) bool {
+	switch {
+	case p.adminSrv != nil && strings.HasPrefix(req.Method, adminNamespace):
+		results[index] = p.adminSrv.HandleRequest(ctx, req)
+	case p.admin.isDraining():
+		if !req.IsNotification() {
+			results[index] = NewErrorResponse(req.ID, ErrShuttingDown)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// track tracks req, forwarded for the message of ctx, appending its sequence number to seqs.
+func (p *Proxy) track(ctx context.Context, req *Request, seqs []uint64) []uint64 {
+	if p.admin == nil {
+		return seqs
+	}
+	var conn string
+	if stream, ok := ctx.Value(adminStreamContextKey{}).(*adminStream); ok {
+		conn = stream.id
+	}
+	return append(seqs, p.admin.begin(conn, req))
+}
+
+// adminStream is a downstream stream of a proxy tracked by its adminTracker.
+type adminStream struct {
+	id      string
+	tracker *adminTracker
+	cancel  context.CancelCauseFunc
+
+	mu    sync.Mutex
+	cause error
+}
+
+// trackStream tracks stream, served by ServeStream within ctx, returning the context of its
+// messages, canceled once the stream is kicked. It returns ctx and nil if the proxy is not
+// tracked.
+func (p *Proxy) trackStream(ctx context.Context, stream Stream) (context.Context, *adminStream) {
+	if p.admin == nil {
+		return ctx, nil
+	}
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	tracked := &adminStream{id: newUUID(), tracker: p.admin, cancel: cancel}
+	info := AdminConn{ID: tracked.id, Connected: time.Now()}
+	if md, ok := IncomingMetadata(ctx); ok {
+		info.Remote = md.Get(PeerMetadataKey)
+	}
+	p.admin.connect(info, func(cause error) {
+		tracked.mu.Lock()
+		tracked.cause = cause
+		tracked.mu.Unlock()
+		cancel(cause)
+		_ = stream.Close()
+	})
+	return context.WithValue(streamCtx, adminStreamContextKey{}, tracked), tracked
+}
+
+// kicked returns the cause the stream was closed with, nil if it was not.
+func (s *adminStream) kicked() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cause
+}
+
+// end stops tracking the stream.
+func (s *adminStream) end() {
+	if s != nil {
+		s.tracker.disconnect(s.id)
+		s.cancel(nil)
+	}
+}
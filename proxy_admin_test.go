@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowUpstream returns a client of a server whose method slow returns once release is closed
+// or the call is canceled.
+func newSlowUpstream(t *testing.T, release <-chan struct{}) (*Client, *upstream) {
+	t.Helper()
+	srv := NewServer()
+	require.NoError(t, srv.RegisterFunc("slow", func(ctx context.Context, _ *Request) (any, error) {
+		select {
+		case <-release:
+			return true, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+	return newUpstream(srv)
+}
+
+func TestProxy_Admin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Answered by the proxy", func(t *testing.T) {
+		client, rec := newSlowUpstream(t, nil)
+		proxy := NewProxy(client, WithProxyAdmin(AdminPolicy{}), WithProxyRoute("slow", client))
+
+		var methods []string
+		require.Nil(t, adminCall(t, proxy, AdminMethodsMethod, "", &methods))
+		assert.Equal(t, []string{"slow"}, methods)
+		assert.Empty(t, rec.sent(), "admin calls are not forwarded")
+	})
+
+	t.Run("Connections, calls in flight, and kick", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		client, _ := newSlowUpstream(t, release)
+		proxy := NewProxy(client, WithProxyAdmin(AdminPolicy{Authorize: allowAdmin}))
+		clientEnd, serverEnd := newStreamPair()
+		connCtx := NewIncomingContext(ctx, MetadataPairs(PeerMetadataKey, "10.0.0.2:5000"))
+		served := make(chan error, 1)
+		go func() { served <- proxy.ServeStream(connCtx, serverEnd) }()
+		require.NoError(t, clientEnd.WriteMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","method":"slow","id":3}`)))
+
+		var calls []AdminCall
+		require.Eventually(t, func() bool {
+			require.Nil(t, adminCall(t, proxy, AdminInFlightMethod, "", &calls))
+			return len(calls) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, "slow", calls[0].Method)
+		assert.EqualValues(t, 3, calls[0].ID)
+
+		var conns []AdminConn
+		require.Nil(t, adminCall(t, proxy, AdminConnectionsMethod, "", &conns))
+		require.Len(t, conns, 1)
+		assert.Equal(t, "10.0.0.2:5000", conns[0].Remote)
+		assert.Equal(t, conns[0].ID, calls[0].Conn)
+
+		require.Nil(t, adminCall(t, proxy, AdminKickMethod, `["`+conns[0].ID+`"]`, nil))
+		assert.ErrorIs(t, <-served, ErrKicked)
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		release := make(chan struct{})
+		client, _ := newSlowUpstream(t, release)
+		policy := AdminPolicy{Separate: true, Authorize: allowAdmin}
+		proxy := NewProxy(client, WithProxyAdmin(policy))
+		admin := proxy.AdminServer()
+		rpcErr := adminCall(t, proxy, AdminMethodsMethod, "", nil)
+		require.NotNil(t, rpcErr, "separate admin calls are forwarded")
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+
+		_, serverEnd := newStreamPair()
+		served := make(chan error, 1)
+		go func() { served <- proxy.ServeStream(ctx, serverEnd) }()
+		slow := make(chan []byte, 1)
+		go func() {
+			slow <- proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"slow","id":1}`))
+		}()
+		require.Eventually(t, func() bool {
+			var calls []AdminCall
+			require.Nil(t, adminCall(t, admin, AdminInFlightMethod, "", &calls))
+			return len(calls) == 1
+		}, time.Second, time.Millisecond)
+
+		require.Nil(t, adminCall(t, admin, AdminDrainMethod, "", nil))
+		require.Eventually(t, func() bool {
+			rpcErr := adminCall(t, proxy, "slow", "", nil)
+			return rpcErr != nil && rpcErr.Code == ShuttingDown
+		}, time.Second, time.Millisecond)
+		select {
+		case err := <-served:
+			t.Fatalf("connection closed before the calls in flight ended: %v", err)
+		default:
+		}
+
+		close(release)
+		resp, err := DecodeResponse(<-slow)
+		require.NoError(t, err)
+		assert.Nil(t, resp.Err())
+		assert.ErrorIs(t, <-served, ErrServerClosed)
+	})
+}
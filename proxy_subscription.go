@@ -0,0 +1,501 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDetachTimeout is how long the subscriptions of a session outlive its connection by
+	// default.
+	defaultDetachTimeout = time.Minute
+
+	// maxDetachedNotifications caps the notifications held for a detached subscription.
+	maxDetachedNotifications = 256
+)
+
+// ProxySubscription is the state of a subscription relayed by a Proxy, as persisted in a
+// SubscriptionStore.
+type ProxySubscription struct {
+	// ID is the subscription ID the proxy gave the downstream client.
+	ID string `json:"id"`
+
+	// Session is the key of the downstream client, from ProxySubscriptionPolicy.Session.
+	Session string `json:"session"`
+
+	// Method is the subscribe method, and Params its params.
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+
+	// UpstreamID is the subscription ID of the upstream, which changes when the subscription is
+	// re-established.
+	UpstreamID any `json:"upstreamId,omitempty"`
+}
+
+// SubscriptionStore persists the subscriptions relayed by a Proxy, so that a restarted proxy can
+// re-establish them with RestoreSubscriptions. Implementations must be safe for concurrent use.
+type SubscriptionStore interface {
+	// Save stores sub, replacing the subscription with the same ID.
+	Save(sub ProxySubscription) error
+
+	// Delete removes the subscription with the given ID, if any.
+	Delete(id string) error
+
+	// Load returns the subscriptions stored.
+	Load() ([]ProxySubscription, error)
+}
+
+// ProxySubscriptionPolicy configures WithProxySubscriptions.
+type ProxySubscriptionPolicy struct {
+	// Methods maps the subscribe methods relayed to the method of their notifications, as
+	// "eth_subscribe" to "eth_subscription". Their unsubscribe methods are derived as for
+	// Client.Subscribe.
+	Methods map[string]string
+
+	// Session returns the key of the downstream client served by ServeStream with ctx, such as
+	// its authenticated principal, which must be the same on each of its connections. The
+	// subscriptions of connections without a key end with their connection.
+	Session func(ctx context.Context) string
+
+	// Store persists the subscriptions of sessions. Optional.
+	Store SubscriptionStore
+
+	// DetachTimeout is how long the subscriptions of a session stay established upstream once
+	// its connection ends, waiting for the session to connect again. Defaults to one minute.
+	DetachTimeout time.Duration
+}
+
+// WithProxySubscriptions makes the proxy relay the subscriptions of the methods of policy on the
+// streams it serves. It makes the subscribe calls on a stream upstream, with Client.Subscribe,
+// answers downstream with a subscription ID of its own, and relays the notifications of the
+// upstream under that ID. Another connection of the same session is attached to the
+// subscriptions, with the notifications received while detached, without subscribing again.
+// The subscriptions persisted in the Store of policy are re-established by
+// RestoreSubscriptions. Subscribe calls received over HTTP are forwarded like other calls.
+func WithProxySubscriptions(policy ProxySubscriptionPolicy) ProxyOption {
+	return func(p *Proxy) {
+		p.subs = newProxySubscriptions(policy)
+	}
+}
+
+// RestoreSubscriptions re-establishes upstream the subscriptions persisted in the store of
+// WithProxySubscriptions, typically once after the proxy restarts. They stay detached until a
+// connection of their session is served. Subscriptions that fail to be re-established are
+// deleted from the store, and their errors returned joined.
+func (p *Proxy) RestoreSubscriptions(ctx context.Context) error {
+	if p.subs == nil || p.subs.policy.Store == nil {
+		return nil
+	}
+	saved, err := p.subs.policy.Store.Load()
+	if err != nil {
+		return fmt.Errorf("load subscriptions: %w", err)
+	}
+	var errs []error
+	for _, rec := range saved {
+		upstream := p.route(&Request{Method: rec.Method, Params: requestParams(rec.Params)})
+		if err := p.subs.establish(ctx, upstream, rec, nil); err != nil {
+			_ = p.subs.policy.Store.Delete(rec.ID)
+			errs = append(errs, fmt.Errorf("restore subscription %s: %w", rec.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// proxyConn is a downstream connection served by Proxy.ServeStream.
+type proxyConn struct {
+	ctx     context.Context
+	stream  Stream
+	writeMu sync.Mutex
+	session string
+
+	// Guarded by proxySubscriptions.mu
+	subs map[string]*proxiedSubscription
+}
+
+// proxyMessage is a message arriving on a proxyConn, along with the subscriptions it
+// established, attached to the connection once its reply is written.
+type proxyMessage struct {
+	conn        *proxyConn
+	mu          sync.Mutex
+	established []*proxiedSubscription
+}
+
+// proxyMessageContextKey is the context key of the proxyMessage being forwarded.
+type proxyMessageContextKey struct{}
+
+// write writes msg to the connection.
+func (c *proxyConn) write(msg []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.stream.WriteMessage(c.ctx, msg)
+}
+
+// proxySubscriptions tracks the subscriptions relayed by a Proxy.
+type proxySubscriptions struct {
+	policy       ProxySubscriptionPolicy
+	unsubscribes map[string]string // Unsubscribe methods to their subscribe methods
+
+	mu   sync.Mutex
+	byID map[string]*proxiedSubscription
+}
+
+// proxiedSubscription is a subscription relayed by a Proxy.
+type proxiedSubscription struct {
+	rec      ProxySubscription
+	upstream *Subscription
+	notify   string
+
+	// Guarded by mu, held while writing notifications so that they keep their order
+	mu     sync.Mutex
+	conn   *proxyConn
+	held   [][]byte
+	expiry *time.Timer
+	ended  bool
+}
+
+// newProxySubscriptions returns the subscriptions of policy, applying its defaults.
+func newProxySubscriptions(policy ProxySubscriptionPolicy) *proxySubscriptions {
+	p := policy
+	if p.DetachTimeout <= 0 {
+		p.DetachTimeout = defaultDetachTimeout
+	}
+	s := &proxySubscriptions{
+		policy:       p,
+		unsubscribes: make(map[string]string),
+		byID:         make(map[string]*proxiedSubscription),
+	}
+	for method := range p.Methods {
+		if unsubscribe := unsubscribeMethod(method); unsubscribe != "" {
+			s.unsubscribes[unsubscribe] = method
+		}
+	}
+	return s
+}
+
+// connect returns the connection served with ctx by ServeStream and attaches it to the
+// subscriptions of its session.
+func (s *proxySubscriptions) connect(ctx context.Context, stream Stream) *proxyConn {
+	conn := &proxyConn{ctx: ctx, stream: stream, subs: make(map[string]*proxiedSubscription)}
+	if s.policy.Session != nil {
+		conn.session = s.policy.Session(ctx)
+	}
+	if conn.session == "" {
+		return conn
+	}
+
+	s.mu.Lock()
+	var detached []*proxiedSubscription
+	for _, sub := range s.byID {
+		if sub.rec.Session == conn.session {
+			conn.subs[sub.rec.ID] = sub
+			detached = append(detached, sub)
+		}
+	}
+	s.mu.Unlock()
+	for _, sub := range detached {
+		sub.attach(conn)
+	}
+	return conn
+}
+
+// message returns the context forwarding a message arriving on conn, and the function attaching
+// the subscriptions it established to conn, to call once the reply is written. Proxies without
+// subscriptions, whose s is nil, forward messages with ctx.
+func (s *proxySubscriptions) message(
+	ctx context.Context,
+	conn *proxyConn,
+) (context.Context, func()) {
+	if s == nil {
+		return ctx, func() {}
+	}
+	msg := &proxyMessage{conn: conn}
+	attach := func() {
+		msg.mu.Lock()
+		defer msg.mu.Unlock()
+		for _, sub := range msg.established {
+			sub.attach(conn)
+		}
+	}
+	return context.WithValue(ctx, proxyMessageContextKey{}, msg), attach
+}
+
+// disconnect detaches the subscriptions of conn once it has ended, ending those without a
+// session.
+func (s *proxySubscriptions) disconnect(conn *proxyConn) {
+	s.mu.Lock()
+	subs := conn.subs
+	conn.subs = nil
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		if conn.session == "" {
+			s.end(sub)
+			continue
+		}
+		sub.detach(conn, s.policy.DetachTimeout, func() { s.end(sub) })
+	}
+}
+
+// handle answers req if it subscribes or unsubscribes on a stream, reporting false otherwise, as
+// it does for proxies without subscriptions, whose s is nil.
+func (s *proxySubscriptions) handle(
+	ctx context.Context,
+	upstream *Client,
+	req *Request,
+) (*Response, bool) {
+	if s == nil {
+		return nil, false
+	}
+	msg, ok := ctx.Value(proxyMessageContextKey{}).(*proxyMessage)
+	if !ok || req.IsNotification() {
+		return nil, false
+	}
+	if _, ok := s.policy.Methods[req.Method]; ok {
+		return s.subscribe(ctx, msg, upstream, req), true
+	}
+	if _, ok := s.unsubscribes[req.Method]; ok {
+		return s.unsubscribe(msg.conn, req), true
+	}
+	return nil, false
+}
+
+// subscribe relays the subscription of req, a member of msg, to its connection.
+func (s *proxySubscriptions) subscribe(
+	ctx context.Context,
+	msg *proxyMessage,
+	upstream *Client,
+	req *Request,
+) *Response {
+	params, err := json.Marshal(req.Params)
+	if err != nil {
+		return NewErrorResponse(req.ID, ErrInvalidParams.WithData(err.Error()))
+	}
+	rec := ProxySubscription{
+		ID:      newUUID(),
+		Session: msg.conn.session,
+		Method:  req.Method,
+		Params:  params,
+	}
+	if req.Params == nil {
+		rec.Params = nil
+	}
+	if err := s.establish(ctx, upstream, rec, msg); err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return NewErrorResponse(req.ID, rpcErr)
+		}
+		return NewErrorResponse(req.ID, ErrUpstreamUnavailable.WithData(err.Error()))
+	}
+	resp, err := NewResponse(req.ID, rec.ID)
+	if err != nil {
+		return NewErrorResponse(req.ID, ErrInternal)
+	}
+	return resp
+}
+
+// establish subscribes upstream for rec and holds the notifications until the reply to msg is
+// written to its connection, or, if msg is nil, until a connection of its session is served.
+func (s *proxySubscriptions) establish(
+	ctx context.Context,
+	upstream *Client,
+	rec ProxySubscription,
+	msg *proxyMessage,
+) error {
+	results := make(chan json.RawMessage)
+	subCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	upstreamSub, err := upstream.Subscribe(subCtx, rec.Method, requestParams(rec.Params), results)
+	if err != nil {
+		cancel()
+		return err
+	}
+	sub := &proxiedSubscription{
+		rec:      rec,
+		upstream: upstreamSub,
+		notify:   s.policy.Methods[rec.Method],
+	}
+	sub.rec.UpstreamID = upstreamSub.ID()
+	if s.policy.Store != nil && rec.Session != "" {
+		if err := s.policy.Store.Save(sub.rec); err != nil {
+			cancel()
+			return fmt.Errorf("save subscription: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.byID[rec.ID] = sub
+	if msg != nil {
+		msg.conn.subs[rec.ID] = sub
+	}
+	s.mu.Unlock()
+	if msg != nil {
+		msg.mu.Lock()
+		msg.established = append(msg.established, sub)
+		msg.mu.Unlock()
+	} else {
+		sub.detach(nil, s.policy.DetachTimeout, func() { s.end(sub) })
+	}
+
+	go func() {
+		defer cancel()
+		s.relay(sub, results)
+	}()
+	return nil
+}
+
+// relay hands the notifications of the upstream to sub until the upstream subscription ends.
+func (s *proxySubscriptions) relay(sub *proxiedSubscription, results <-chan json.RawMessage) {
+	for {
+		select {
+		case result := <-results:
+			sub.deliver(result)
+		case <-sub.upstream.Err():
+			s.end(sub)
+			return
+		}
+	}
+}
+
+// unsubscribe ends the subscription of conn named by the params of req.
+func (s *proxySubscriptions) unsubscribe(conn *proxyConn, req *Request) *Response {
+	var ids []string
+	if err := req.UnmarshalParams(&ids); err != nil || len(ids) != 1 {
+		return NewErrorResponse(req.ID, ErrInvalidParams)
+	}
+	s.mu.Lock()
+	sub, ok := s.byID[ids[0]]
+	ok = ok && sub.rec.Session == conn.session && (conn.session != "" || conn.subs[ids[0]] == sub)
+	s.mu.Unlock()
+
+	resp, err := NewResponse(req.ID, ok)
+	if err != nil {
+		return NewErrorResponse(req.ID, ErrInternal)
+	}
+	if ok {
+		s.end(sub)
+	}
+	return resp
+}
+
+// end ends sub, unsubscribing upstream and deleting it from the store.
+func (s *proxySubscriptions) end(sub *proxiedSubscription) {
+	s.mu.Lock()
+	if s.byID[sub.rec.ID] != sub {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.byID, sub.rec.ID)
+	s.mu.Unlock()
+
+	sub.mu.Lock()
+	sub.ended = true
+	if sub.expiry != nil {
+		sub.expiry.Stop()
+	}
+	sub.held = nil
+	conn := sub.conn
+	sub.mu.Unlock()
+
+	if conn != nil {
+		s.mu.Lock()
+		delete(conn.subs, sub.rec.ID)
+		s.mu.Unlock()
+	}
+
+	if s.policy.Store != nil && sub.rec.Session != "" {
+		_ = s.policy.Store.Delete(sub.rec.ID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), unsubscribeTimeout)
+	defer cancel()
+	_ = sub.upstream.Unsubscribe(ctx)
+}
+
+// deliver writes the notification of result to the connection of sub, or holds it while sub is
+// detached.
+func (sub *proxiedSubscription) deliver(result json.RawMessage) {
+	msg, err := json.Marshal(subscriptionNotification{
+		JSONRPC: jsonRPCVersion,
+		Method:  sub.notify,
+		Params:  subscriptionParams{Subscription: sub.rec.ID, Result: result},
+	})
+	if err != nil {
+		return
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	switch {
+	case sub.ended:
+	case sub.conn != nil && sub.conn.write(msg) == nil:
+	default:
+		// Notifications failing to reach a connection about to end are held for the next one
+		sub.hold(msg)
+	}
+}
+
+// hold holds msg until a connection is attached, dropping the oldest notification held when
+// full.
+func (sub *proxiedSubscription) hold(msg []byte) {
+	if len(sub.held) == maxDetachedNotifications {
+		sub.held = sub.held[1:]
+	}
+	sub.held = append(sub.held, msg)
+}
+
+// attach relays the notifications of sub to conn from now on, starting with those held.
+func (sub *proxiedSubscription) attach(conn *proxyConn) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.ended {
+		return
+	}
+	if sub.expiry != nil {
+		sub.expiry.Stop()
+		sub.expiry = nil
+	}
+	sub.conn = conn
+	held := sub.held
+	sub.held = nil
+	for i, msg := range held {
+		if conn.write(msg) != nil {
+			sub.held = held[i:]
+			return
+		}
+	}
+}
+
+// detach holds the notifications of sub, attached to conn or to no connection if conn is nil,
+// and calls expire unless a connection is attached within timeout.
+func (sub *proxiedSubscription) detach(conn *proxyConn, timeout time.Duration, expire func()) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.ended || sub.conn != conn {
+		return
+	}
+	sub.conn = nil
+	sub.expiry = time.AfterFunc(timeout, expire)
+}
+
+// subscriptionNotification is a notification of a relayed subscription.
+type subscriptionNotification struct {
+	JSONRPC string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  subscriptionParams `json:"params"`
+}
+
+// subscriptionParams are the params of a subscriptionNotification.
+type subscriptionParams struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// requestParams returns raw params as the params of a request, or nil for none.
+func requestParams(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
+}
@@ -0,0 +1,247 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSessionKey is the context key of the sessions of the downstream connections of tests.
+type testSessionKey struct{}
+
+// testSubscriptionPolicy relays eth_subscribe for the sessions set under testSessionKey.
+func testSubscriptionPolicy(store SubscriptionStore) ProxySubscriptionPolicy {
+	return ProxySubscriptionPolicy{
+		Methods: map[string]string{"eth_subscribe": "eth_subscription"},
+		Session: func(ctx context.Context) string {
+			session, _ := ctx.Value(testSessionKey{}).(string)
+			return session
+		},
+		Store: store,
+	}
+}
+
+// downstream is a connection of a session served by a proxy, driven with raw messages.
+type downstream struct {
+	t      *testing.T
+	stream *pipeStream
+	served chan struct{}
+}
+
+// connectDownstream serves a new connection of session on proxy.
+func connectDownstream(t *testing.T, proxy *Proxy, session string) *downstream {
+	t.Helper()
+	clientEnd, proxyEnd := newStreamPair()
+	d := &downstream{t: t, stream: clientEnd, served: make(chan struct{})}
+	ctx := context.WithValue(context.Background(), testSessionKey{}, session)
+	go func() {
+		defer close(d.served)
+		_ = proxy.ServeStream(ctx, proxyEnd)
+	}()
+	t.Cleanup(func() { _ = clientEnd.Close() })
+	return d
+}
+
+// call sends a request and returns the raw result of its response.
+func (d *downstream) call(method string, params any) json.RawMessage {
+	d.t.Helper()
+	req := NewRequestWithID(method, params, "1")
+	msg, err := req.MarshalJSON()
+	require.NoError(d.t, err)
+	require.NoError(d.t, d.stream.WriteMessage(context.Background(), msg))
+	resp, err := DecodeResponse(d.read())
+	require.NoError(d.t, err)
+	require.Nil(d.t, resp.Err())
+	return resp.RawResult()
+}
+
+// subscribe subscribes to eth_subscribe and returns the subscription ID given by the proxy.
+func (d *downstream) subscribe() string {
+	d.t.Helper()
+	var id string
+	require.NoError(d.t, json.Unmarshal(d.call("eth_subscribe", []any{"newHeads"}), &id))
+	return id
+}
+
+// notification reads the next notification and returns its subscription ID and result.
+func (d *downstream) notification() (string, int) {
+	d.t.Helper()
+	var msg struct {
+		Method string `json:"method"`
+		Params struct {
+			Subscription string `json:"subscription"`
+			Result       int    `json:"result"`
+		} `json:"params"`
+	}
+	require.NoError(d.t, json.Unmarshal(d.read(), &msg))
+	assert.Equal(d.t, "eth_subscription", msg.Method)
+	return msg.Params.Subscription, msg.Params.Result
+}
+
+// read reads the next message.
+func (d *downstream) read() []byte {
+	d.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := d.stream.ReadMessage(ctx)
+	require.NoError(d.t, err)
+	return msg
+}
+
+// close closes the connection and waits for the proxy to stop serving it.
+func (d *downstream) close() {
+	d.t.Helper()
+	require.NoError(d.t, d.stream.Close())
+	select {
+	case <-d.served:
+	case <-time.After(time.Second):
+		d.t.Fatal("ServeStream did not return")
+	}
+}
+
+// unsubscribed waits for the upstream to receive an unsubscribe call and returns its params.
+func unsubscribed(t *testing.T, p *subscriptionPeer) []any {
+	t.Helper()
+	select {
+	case params := <-p.unsubscribed:
+		return params
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe not called")
+		return nil
+	}
+}
+
+func TestProxy_Subscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Notifications are relayed under the ID of the proxy", func(t *testing.T) {
+		peer, upstream := newSubscriptionPeer(t)
+		store := NewMemorySubscriptionStore()
+		proxy := NewProxy(upstream, WithProxySubscriptions(testSubscriptionPolicy(store)))
+		client := NewStreamClient(connectDownstream(t, proxy, "alice").stream)
+		defer client.Close()
+
+		ch := make(chan json.RawMessage)
+		sub, err := client.Subscribe(ctx, "eth_subscribe", []any{"newHeads"}, ch)
+		require.NoError(t, err)
+		assert.NotEqual(t, "0xa", sub.ID())
+		require.NoError(t, publish(ctx, <-peer.peers, "0xa", 1))
+		assert.Equal(t, 0, receive(t, ch))
+		assert.Equal(t, 1, receive(t, ch))
+
+		saved, err := store.Load()
+		require.NoError(t, err)
+		require.Len(t, saved, 1)
+		assert.Equal(t, ProxySubscription{
+			ID:         sub.ID().(string),
+			Session:    "alice",
+			Method:     "eth_subscribe",
+			Params:     json.RawMessage(`["newHeads"]`),
+			UpstreamID: "0xa",
+		}, saved[0])
+
+		require.NoError(t, sub.Unsubscribe(ctx))
+		assert.Equal(t, []any{"0xa"}, unsubscribed(t, peer))
+		assert.Eventually(t, func() bool {
+			saved, _ := store.Load()
+			return len(saved) == 0
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Sessions attach again to their subscriptions", func(t *testing.T) {
+		peer, upstream := newSubscriptionPeer(t)
+		proxy := NewProxy(upstream, WithProxySubscriptions(testSubscriptionPolicy(nil)))
+
+		first := connectDownstream(t, proxy, "alice")
+		id := first.subscribe()
+		publisher := <-peer.peers
+		gotID, value := first.notification()
+		assert.Equal(t, id, gotID)
+		assert.Equal(t, 0, value)
+		first.close()
+
+		require.NoError(t, publish(ctx, publisher, "0xa", 1))
+		second := connectDownstream(t, proxy, "alice")
+		gotID, value = second.notification()
+		assert.Equal(t, id, gotID, "notifications received while detached are held")
+		assert.Equal(t, 1, value)
+
+		require.NoError(t, publish(ctx, publisher, "0xa", 2))
+		_, value = second.notification()
+		assert.Equal(t, 2, value)
+
+		assert.Equal(t, "false", string(connectDownstream(t, proxy, "bob").call(
+			"eth_unsubscribe", []any{id})), "subscriptions of other sessions are not ended")
+		assert.Equal(t, "true", string(second.call("eth_unsubscribe", []any{id})))
+		assert.Equal(t, []any{"0xa"}, unsubscribed(t, peer))
+	})
+
+	t.Run("Subscriptions without a session end with their connection", func(t *testing.T) {
+		peer, upstream := newSubscriptionPeer(t)
+		proxy := NewProxy(upstream, WithProxySubscriptions(testSubscriptionPolicy(nil)))
+		d := connectDownstream(t, proxy, "")
+		d.subscribe()
+		d.close()
+		assert.Equal(t, []any{"0xa"}, unsubscribed(t, peer))
+	})
+
+	t.Run("Detached subscriptions expire", func(t *testing.T) {
+		peer, upstream := newSubscriptionPeer(t)
+		policy := testSubscriptionPolicy(nil)
+		policy.DetachTimeout = 10 * time.Millisecond
+		proxy := NewProxy(upstream, WithProxySubscriptions(policy))
+		d := connectDownstream(t, proxy, "alice")
+		d.subscribe()
+		d.close()
+		assert.Equal(t, []any{"0xa"}, unsubscribed(t, peer))
+	})
+
+	t.Run("Restarted proxies restore persisted subscriptions", func(t *testing.T) {
+		store := NewMemorySubscriptionStore()
+		_, upstream := newSubscriptionPeer(t)
+		proxy := NewProxy(upstream, WithProxySubscriptions(testSubscriptionPolicy(store)))
+		id := connectDownstream(t, proxy, "alice").subscribe()
+
+		restartedPeer, restartedUpstream := newSubscriptionPeer(t)
+		restarted := NewProxy(restartedUpstream,
+			WithProxySubscriptions(testSubscriptionPolicy(store)))
+		require.NoError(t, restarted.RestoreSubscriptions(ctx))
+		publisher := <-restartedPeer.peers
+
+		d := connectDownstream(t, restarted, "alice")
+		gotID, value := d.notification()
+		assert.Equal(t, id, gotID, "downstream clients keep their subscription IDs")
+		assert.Equal(t, 0, value)
+		require.NoError(t, publish(ctx, publisher, "0xa", 5))
+		_, value = d.notification()
+		assert.Equal(t, 5, value)
+	})
+
+	t.Run("Subscriptions failing to be restored are deleted", func(t *testing.T) {
+		store := NewMemorySubscriptionStore()
+		require.NoError(t, store.Save(ProxySubscription{
+			ID: "gone", Session: "alice", Method: "bogus_subscribe",
+		}))
+		_, upstream := newSubscriptionPeer(t)
+		proxy := NewProxy(upstream, WithProxySubscriptions(testSubscriptionPolicy(store)))
+		err := proxy.RestoreSubscriptions(ctx)
+		require.ErrorContains(t, err, "restore subscription gone")
+		saved, err := store.Load()
+		require.NoError(t, err)
+		assert.Empty(t, saved)
+
+		assert.NoError(t, NewProxy(upstream).RestoreSubscriptions(ctx))
+	})
+
+	t.Run("Subscribe calls outside streams are forwarded", func(t *testing.T) {
+		_, upstream := newSubscriptionPeer(t)
+		proxy := NewProxy(upstream, WithProxySubscriptions(testSubscriptionPolicy(nil)))
+		reply := proxy.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":[]}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"0xa"}`, string(reply))
+	})
+}
@@ -0,0 +1,270 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstream is a client forwarding to srv in process, recording the payloads it sends.
+type upstream struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+// newUpstream returns a client for srv and the record of the payloads it sends.
+func newUpstream(srv *Server) (*Client, *upstream) {
+	rec := &upstream{}
+	roundTrip := func(ctx context.Context, payload []byte) ([]byte, error) {
+		rec.mu.Lock()
+		rec.payloads = append(rec.payloads, string(payload))
+		rec.mu.Unlock()
+		return srv.HandleMessage(ctx, payload), nil
+	}
+	return NewClient(&funcTransport{fn: roundTrip}), rec
+}
+
+// sent returns the payloads sent so far.
+func (u *upstream) sent() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.payloads...)
+}
+
+func TestProxy_HandleMessage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Forwards a call and restores its ID", func(t *testing.T) {
+		client, rec := newUpstream(newTestServer(t))
+		proxy := NewProxy(client)
+
+		msg := `{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":"abc"}`
+		reply := proxy.HandleMessage(ctx, []byte(msg))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", resp.IDOrNil())
+		var total int
+		require.NoError(t, resp.UnmarshalResult(&total))
+		assert.Equal(t, 3, total)
+
+		sent := rec.sent()
+		require.Len(t, sent, 1)
+		assert.NotContains(t, sent[0], `"abc"`, "the upstream sees the proxy's ID")
+	})
+
+	t.Run("Merges a batch into one upstream batch", func(t *testing.T) {
+		client, rec := newUpstream(newTestServer(t))
+		proxy := NewProxy(client)
+
+		msg := `[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},` +
+			`{"jsonrpc":"2.0","method":"notify_hello"},` +
+			`{"jsonrpc":"2.0","method":"subtract","params":[5,3],"id":1}]`
+		resps, err := DecodeBatchResponse(proxy.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+
+		var first, second int
+		require.NoError(t, resps[0].UnmarshalResult(&first))
+		require.NoError(t, resps[1].UnmarshalResult(&second))
+		assert.Equal(t, 3, first)
+		assert.Equal(t, 2, second, "duplicate downstream IDs do not clash upstream")
+		assert.Equal(t, int64(1), resps[0].IDOrNil())
+		assert.Equal(t, int64(1), resps[1].IDOrNil())
+		assert.Len(t, rec.sent(), 1)
+	})
+
+	t.Run("Passes notifications through", func(t *testing.T) {
+		client, rec := newUpstream(newTestServer(t))
+		proxy := NewProxy(client)
+
+		reply := proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"notify_hello"}`))
+		assert.Nil(t, reply)
+		require.Len(t, rec.sent(), 1)
+		assert.Contains(t, rec.sent()[0], "notify_hello")
+	})
+
+	t.Run("Routes methods to their upstream", func(t *testing.T) {
+		main, mainRec := newUpstream(newTestServer(t))
+		other, otherRec := newUpstream(newTestServer(t))
+		proxy := NewProxy(main, WithProxyRoute("subtract", other))
+
+		msg := `[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},` +
+			`{"jsonrpc":"2.0","method":"subtract","params":[5,3],"id":2},` +
+			`{"jsonrpc":"2.0","method":"sum","params":[4],"id":3}]`
+		resps, err := DecodeBatchResponse(proxy.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 3)
+		for i, resp := range resps {
+			assert.Equal(t, int64(i+1), resp.IDOrNil(), "responses keep the request order")
+		}
+
+		require.Len(t, mainRec.sent(), 1)
+		require.Len(t, otherRec.sent(), 1)
+		assert.NotContains(t, mainRec.sent()[0], "subtract")
+		assert.Contains(t, otherRec.sent()[0], "subtract")
+	})
+
+	t.Run("Router takes precedence", func(t *testing.T) {
+		main, mainRec := newUpstream(newTestServer(t))
+		other, otherRec := newUpstream(newTestServer(t))
+		router := func(req *Request) *Client {
+			if req.Method == "sum" {
+				return other
+			}
+			return nil
+		}
+		proxy := NewProxy(main, WithProxyRouter(router))
+
+		proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`))
+		proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"fail","id":2}`))
+		assert.Len(t, otherRec.sent(), 1)
+		assert.Len(t, mainRec.sent(), 1)
+	})
+
+	t.Run("Answers invalid messages locally", func(t *testing.T) {
+		client, rec := newUpstream(newTestServer(t))
+		proxy := NewProxy(client)
+
+		resp, err := DecodeResponse(proxy.HandleMessage(ctx, []byte(`{"jsonrpc":`)))
+		require.NoError(t, err)
+		assert.Equal(t, ParseError, resp.Err().Code)
+
+		resp, err = DecodeResponse(proxy.HandleMessage(ctx, []byte(`[]`)))
+		require.NoError(t, err)
+		assert.Equal(t, InvalidRequest, resp.Err().Code)
+
+		msg := `[{"jsonrpc":"2.0","method":"sum","params":[1],"id":1},{"id":2}]`
+		resps, err := DecodeBatchResponse(proxy.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		assert.Nil(t, resps[0].Err())
+		assert.Equal(t, InvalidRequest, resps[1].Err().Code)
+		require.Len(t, rec.sent(), 1)
+		assert.NotContains(t, rec.sent()[0], `"id":2}`)
+	})
+
+	t.Run("Unreachable upstream", func(t *testing.T) {
+		client := NewClient(&funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return nil, errors.New("connection refused")
+		}})
+		proxy := NewProxy(client)
+
+		msg := `[{"jsonrpc":"2.0","method":"sum","id":1},{"jsonrpc":"2.0","method":"sum","id":2}]`
+		resps, err := DecodeBatchResponse(proxy.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		for i, resp := range resps {
+			require.ErrorIs(t, resp.Err(), ErrUpstreamUnavailable)
+			assert.Equal(t, "connection refused", resp.Err().Data)
+			assert.Equal(t, int64(i+1), resp.IDOrNil())
+		}
+	})
+
+	t.Run("Upstream rejecting a batch", func(t *testing.T) {
+		limited := NewServer(WithMaxBatchSize(1))
+		client, _ := newUpstream(limited)
+		proxy := NewProxy(client)
+
+		msg := `[{"jsonrpc":"2.0","method":"sum","id":1},{"jsonrpc":"2.0","method":"sum","id":2}]`
+		resps, err := DecodeBatchResponse(proxy.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		for _, resp := range resps {
+			require.ErrorIs(t, resp.Err(), ErrLimitExceeded)
+		}
+	})
+
+	t.Run("Upstream errors are passed through", func(t *testing.T) {
+		client, _ := newUpstream(newTestServer(t))
+		proxy := NewProxy(client)
+
+		reply := proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"missing","id":7}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Equal(t, MethodNotFound, resp.Err().Code)
+		assert.Equal(t, int64(7), resp.IDOrNil())
+	})
+}
+
+func TestProxy_ServeHTTP(t *testing.T) {
+	upstreamServer := httptest.NewServer(newTestServer(t))
+	defer upstreamServer.Close()
+	upstreamClient := NewClient(NewHTTPTransport(upstreamServer.URL))
+	defer func() { _ = upstreamClient.Close() }()
+
+	gateway := httptest.NewServer(NewProxy(upstreamClient))
+	defer gateway.Close()
+	client := NewClient(NewHTTPTransport(gateway.URL))
+	defer func() { _ = client.Close() }()
+
+	t.Run("Calls through the gateway", func(t *testing.T) {
+		var total int
+		require.NoError(t, client.Call(context.Background(), "sum", []int{1, 2, 3}, &total))
+		assert.Equal(t, 6, total)
+		require.NoError(t, client.Notify(context.Background(), "notify_hello", nil))
+	})
+
+	t.Run("Status codes", func(t *testing.T) {
+		post := func(body string) int {
+			resp, err := http.Post(gateway.URL, contentTypeJSON, strings.NewReader(body))
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		assert.Equal(t, http.StatusNotFound, post(`{"jsonrpc":"2.0","method":"missing","id":1}`))
+		assert.Equal(t, http.StatusNoContent, post(`{"jsonrpc":"2.0","method":"notify_hello"}`))
+
+		resp, err := http.Get(gateway.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestProxy_ServeStream(t *testing.T) {
+	// Both downstream clients share one upstream stream
+	upstreamEnd, serverEnd := newStreamPair()
+	upstreamSrv := newTestServer(t)
+	go func() { _ = upstreamSrv.ServeStream(context.Background(), serverEnd) }()
+	upstreamClient := NewStreamClient(upstreamEnd)
+	defer func() { _ = upstreamClient.Close() }()
+	proxy := NewProxy(upstreamClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var served sync.WaitGroup
+	var clients []*Client
+	for range 2 {
+		clientEnd, proxyEnd := newStreamPair()
+		served.Go(func() { _ = proxy.ServeStream(ctx, proxyEnd) })
+		clients = append(clients, NewStreamClient(clientEnd))
+	}
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Go(func() {
+			for n := range 10 {
+				var total int
+				assert.NoError(t, client.Call(ctx, "sum", []int{i, n}, &total))
+				assert.Equal(t, i+n, total)
+			}
+		})
+	}
+	wg.Wait()
+
+	cancel()
+	done := make(chan struct{})
+	go func() { served.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ServeStream did not return")
+	}
+}
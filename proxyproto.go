@@ -0,0 +1,174 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ProxyProtocol selects whether a listener expects connections to start with a PROXY protocol
+// header, by which load balancers such as HAProxy and AWS NLB pass on the address of the client
+// they accepted the connection from.
+type ProxyProtocol int
+
+const (
+	// ProxyProtocolOff treats connections as they are, without looking for a header.
+	ProxyProtocolOff ProxyProtocol = iota
+
+	// ProxyProtocolOptional reads the header of connections starting with one, and takes the
+	// other connections as they are.
+	ProxyProtocolOptional
+
+	// ProxyProtocolRequired rejects connections not starting with a header.
+	ProxyProtocolRequired
+)
+
+const (
+	// proxyV1Prefix starts the headers of version 1 of the PROXY protocol.
+	proxyV1Prefix = "PROXY "
+
+	// proxyV1MaxLength is the maximum length of a version 1 header, CRLF included.
+	proxyV1MaxLength = 107
+
+	// proxyV1Fields is the number of fields of a version 1 header carrying addresses.
+	proxyV1Fields = 6
+
+	// proxyV2HeaderLength is the length of the fixed part of a version 2 header, which ends with
+	// its command, its address family, and the length of the rest.
+	proxyV2HeaderLength  = 16
+	proxyV2CommandOffset = 12
+	proxyV2FamilyOffset  = 13
+	proxyV2LengthOffset  = 14
+
+	// Commands of version 2 headers, with the version in the high nibble.
+	proxyV2Local = 0x20
+	proxyV2Proxy = 0x21
+
+	// Address families and transports of version 2 headers.
+	proxyV2TCP4 = 0x11
+	proxyV2TCP6 = 0x21
+
+	// Sizes of the addresses of version 2 headers.
+	ipv4Length = 4
+	ipv6Length = 16
+	portLength = 2
+	portBits   = 16
+)
+
+// proxyV2Signature starts the headers of version 2 of the PROXY protocol.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// errProxyHeader is returned for connections without a valid PROXY protocol header.
+var errProxyHeader = errors.New("invalid PROXY protocol header")
+
+// readProxyHeader reads the PROXY protocol header at the start of r, of either version, and
+// returns the client address it carries. The address is nil for connections without a header,
+// allowed by ProxyProtocolOptional, and for headers not carrying one, such as the health checks
+// of load balancers. Only as many bytes as needed to rule a header out are waited for, so that
+// short messages of connections without one are not held up.
+func readProxyHeader(r *bufio.Reader, mode ProxyProtocol) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case proxyV1Prefix[0]:
+		prefix, err := r.Peek(len(proxyV1Prefix))
+		if err == nil && string(prefix) == proxyV1Prefix {
+			return readProxyV1(r)
+		}
+	case proxyV2Signature[0]:
+		if prefix, err := r.Peek(len(proxyV2Signature)); err == nil &&
+			bytes.Equal(prefix, proxyV2Signature) {
+			return readProxyV2(r)
+		}
+	default:
+	}
+	if mode == ProxyProtocolRequired {
+		return nil, errProxyHeader
+	}
+	return nil, nil
+}
+
+// readProxyV1 reads a version 1 header, a line such as "PROXY TCP4 192.0.2.1 192.0.2.2 1234 80".
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < proxyV1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	text, ok := strings.CutSuffix(string(line), "\r\n")
+	if !ok {
+		return nil, errProxyHeader
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != proxyV1Fields || fields[1] != "TCP4" && fields[1] != "TCP6" {
+		return nil, errProxyHeader
+	}
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errProxyHeader, err)
+	}
+	port, err := strconv.ParseUint(fields[4], decimal, portBits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errProxyHeader, err)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+// readProxyV2 reads a binary version 2 header.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[proxyV2LengthOffset:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	switch header[proxyV2CommandOffset] {
+	case proxyV2Local:
+		return nil, nil
+	case proxyV2Proxy:
+	default:
+		return nil, errProxyHeader
+	}
+
+	var ipLength int
+	switch header[proxyV2FamilyOffset] {
+	case proxyV2TCP4:
+		ipLength = ipv4Length
+	case proxyV2TCP6:
+		ipLength = ipv6Length
+	default:
+		// Other transports carry no address of use
+		return nil, nil
+	}
+	if len(body) < 2*ipLength+2*portLength {
+		return nil, errProxyHeader
+	}
+	addr, ok := netip.AddrFromSlice(body[:ipLength])
+	if !ok {
+		return nil, errProxyHeader
+	}
+	port := binary.BigEndian.Uint16(body[2*ipLength:])
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr.Unmap(), port)), nil
+}
@@ -0,0 +1,75 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proxyV2Header returns a version 2 header with the given command, family, and address body.
+func proxyV2Header(command, family byte, body []byte) string {
+	header := append([]byte(nil), proxyV2Signature...)
+	header = append(header, command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(body)))
+	return string(append(header, body...))
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	tcp4 := []byte{203, 0, 113, 7, 192, 0, 2, 1, 0x10, 0x92, 0, 80}
+	tcp6 := make([]byte, 36)
+	tcp6[0], tcp6[15], tcp6[33] = 0x20, 1, 0x50
+
+	tests := []struct {
+		name  string
+		input string
+		mode  ProxyProtocol
+		want  string
+		err   bool
+	}{
+		{"v1 TCP4", "PROXY TCP4 203.0.113.7 192.0.2.1 4242 80\r\n{}", ProxyProtocolRequired,
+			"203.0.113.7:4242", false},
+		{"v1 TCP6", "PROXY TCP6 2001:db8::1 2001:db8::2 4242 80\r\n{}", ProxyProtocolRequired,
+			"[2001:db8::1]:4242", false},
+		{"v1 UNKNOWN", "PROXY UNKNOWN\r\n{}", ProxyProtocolRequired, "", false},
+		{"v1 without CRLF", "PROXY TCP4 203.0.113.7 192.0.2.1 4242 80\n", ProxyProtocolRequired,
+			"", true},
+		{"v1 bad address", "PROXY TCP4 nope 192.0.2.1 4242 80\r\n", ProxyProtocolRequired, "",
+			true},
+		{"v1 bad port", "PROXY TCP4 203.0.113.7 192.0.2.1 99999 80\r\n", ProxyProtocolRequired,
+			"", true},
+		{"v2 TCP4", proxyV2Header(proxyV2Proxy, proxyV2TCP4, tcp4) + "{}", ProxyProtocolRequired,
+			"203.0.113.7:4242", false},
+		{"v2 TCP6", proxyV2Header(proxyV2Proxy, proxyV2TCP6, tcp6) + "{}", ProxyProtocolRequired,
+			"[2000::1]:80", false},
+		{"v2 LOCAL", proxyV2Header(proxyV2Local, 0, nil) + "{}", ProxyProtocolRequired, "",
+			false},
+		{"v2 short body", proxyV2Header(proxyV2Proxy, proxyV2TCP4, tcp4[:4]),
+			ProxyProtocolRequired, "", true},
+		{"No header, required", `{"jsonrpc":"2.0"}`, ProxyProtocolRequired, "", true},
+		{"No header, optional", `{"jsonrpc":"2.0"}`, ProxyProtocolOptional, "", false},
+		{"Short message, optional", "{}", ProxyProtocolOptional, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			addr, err := readProxyHeader(r, tt.mode)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Nil(t, addr)
+			} else {
+				require.NotNil(t, addr)
+				assert.Equal(t, tt.want, addr.String())
+			}
+			rest, _ := r.Peek(1)
+			assert.True(t, len(rest) == 1 && rest[0] == '{', "the message follows the header")
+		})
+	}
+}
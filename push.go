@@ -0,0 +1,227 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPushBuffer is the default number of pushed notifications queued per connection.
+const defaultPushBuffer = 64
+
+// ErrPushQueueFull is returned when a notification is pushed to a connection whose queue is full.
+var ErrPushQueueFull = errors.New("push queue is full")
+
+// connContextKey is the context key under which a served connection is stored.
+type connContextKey struct{}
+
+// OverflowPolicy selects what happens when a notification is pushed to a connection whose queue
+// is full, i.e. whose peer does not keep up.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops the notification for that connection only.
+	OverflowDrop OverflowPolicy = iota
+
+	// OverflowClose drops the notification and closes the connection.
+	OverflowClose
+)
+
+// WithPushBuffer sets how many pushed notifications are queued per connection before the
+// overflow policy applies. Defaults to 64.
+func WithPushBuffer(size int) ServerOption {
+	return func(s *Server) {
+		s.pushBuffer = size
+	}
+}
+
+// WithPushOverflow sets the policy applied when a connection's push queue is full. Defaults to
+// OverflowDrop.
+func WithPushOverflow(policy OverflowPolicy) ServerOption {
+	return func(s *Server) {
+		s.pushOverflow = policy
+	}
+}
+
+// Conn is a connection served by ServeStream, to which the server can push notifications.
+//
+// Pushed notifications are queued per connection and written by a dedicated goroutine, so
+// pushing never blocks on a slow peer; see WithPushBuffer and WithPushOverflow.
+type Conn struct {
+	client     *Client
+	queue      chan []byte
+	policy     OverflowPolicy
+	overflowed chan struct{}
+	overflow   sync.Once
+	done       chan struct{}
+	closeOnce  sync.Once
+	canonical  bool
+	session    *Session
+	id         string
+	remote     string
+	connected  time.Time
+
+	// capabilities are those agreed in the capability handshake, nil before it
+	capabilities atomic.Pointer[Capabilities]
+}
+
+// newConn creates a connection with the server's push settings.
+func (s *Server) newConn() *Conn {
+	size := s.pushBuffer
+	if size <= 0 {
+		size = defaultPushBuffer
+	}
+	return &Conn{
+		queue:      make(chan []byte, size),
+		policy:     s.pushOverflow,
+		overflowed: make(chan struct{}),
+		done:       make(chan struct{}),
+		canonical:  s.canonical,
+		session:    newSession(),
+		id:         newUUID(),
+		connected:  time.Now(),
+	}
+}
+
+// ID returns the identifier of the connection, unique among the connections of the process, as
+// listed by the admin methods of WithAdmin.
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// connID returns the ID of the connection of the request of ctx, empty outside ServeStream.
+func connID(ctx context.Context) string {
+	if conn, ok := ConnFromContext(ctx); ok {
+		return conn.id
+	}
+	return ""
+}
+
+// Notify queues a notification for the connection's peer without waiting for it to be written.
+// It returns ErrPushQueueFull if the queue is full and ErrClientClosed if the connection has
+// ended.
+func (c *Conn) Notify(method string, params any) error {
+	msg, err := NewNotification(method, params).MarshalJSON()
+	if err != nil {
+		return err
+	}
+	if c.canonical {
+		msg = canonicalReply(nil, msg)
+	}
+	return c.push(msg)
+}
+
+// Done returns a channel that is closed once the connection has ended.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// push queues an encoded notification, applying the overflow policy if the queue is full.
+func (c *Conn) push(msg []byte) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	select {
+	case c.queue <- msg:
+		return nil
+	default:
+	}
+	if c.policy == OverflowClose {
+		c.overflow.Do(func() {
+			close(c.overflowed)
+		})
+	}
+	return ErrPushQueueFull
+}
+
+// run writes queued notifications to the client's stream until the client shuts down, closing
+// the client if the queue overflows under OverflowClose.
+func (c *Conn) run(client *Client) {
+	for {
+		select {
+		case msg := <-c.queue:
+			if err := client.write(client.ctx, msg); err != nil {
+				return
+			}
+		case <-c.overflowed:
+			_ = client.Close()
+			return
+		case <-client.Done():
+			return
+		}
+	}
+}
+
+// close marks the connection as ended.
+func (c *Conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// Conns returns the connections currently served by ServeStream, in no particular order.
+func (s *Server) Conns() []*Conn {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	conns := make([]*Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Broadcast queues a notification for every connection currently served by ServeStream. The
+// notification is encoded once, and connections whose queue is full are handled according to
+// the overflow policy without affecting the others. It only fails if the notification cannot be
+// encoded.
+func (s *Server) Broadcast(method string, params any) error {
+	msg, err := NewNotification(method, params).MarshalJSON()
+	if err != nil {
+		return err
+	}
+	for _, conn := range s.Conns() {
+		_ = conn.push(msg)
+	}
+	return nil
+}
+
+// trackConn registers a connection for Conns and Broadcast.
+func (s *Server) trackConn(conn *Conn) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	if s.conns == nil {
+		s.conns = make(map[*Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.admin.connect(AdminConn{ID: conn.id, Remote: conn.remote, Connected: conn.connected},
+		func(cause error) { _ = conn.client.closeWith(cause) })
+}
+
+// untrackConn removes an ended connection.
+func (s *Server) untrackConn(conn *Conn) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	delete(s.conns, conn)
+	s.admin.disconnect(conn.id)
+}
+
+// ConnFromContext returns the connection on which the current request arrived, which can be kept
+// to push notifications to the peer later. It returns false outside handlers invoked through
+// ServeStream.
+func ConnFromContext(ctx context.Context) (*Conn, bool) {
+	conn, ok := ctx.Value(connContextKey{}).(*Conn)
+	return conn, ok
+}
+
+// contextWithConn returns a copy of ctx carrying the given connection.
+func contextWithConn(ctx context.Context, conn *Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
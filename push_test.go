@@ -0,0 +1,139 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveConn serves one end of a new stream pair on srv and returns the other end along with the
+// served connection.
+func serveConn(t *testing.T, srv *Server) (*pipeStream, *Conn) {
+	t.Helper()
+	clientEnd, serverEnd := newStreamPair()
+	known := make(map[*Conn]bool)
+	for _, conn := range srv.Conns() {
+		known[conn] = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+
+	var served *Conn
+	require.Eventually(t, func() bool {
+		for _, conn := range srv.Conns() {
+			if !known[conn] {
+				served = conn
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	return clientEnd, served
+}
+
+// readNotification reads the next message from s and decodes it as a notification.
+func readNotification(t *testing.T, s *pipeStream) *Request {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := s.ReadMessage(ctx)
+	require.NoError(t, err)
+	req, err := DecodeRequest(msg)
+	require.NoError(t, err)
+	require.True(t, req.IsNotification())
+	return req
+}
+
+func TestServer_Broadcast(t *testing.T) {
+	t.Run("Every connection receives the notification", func(t *testing.T) {
+		srv := NewServer()
+		first, _ := serveConn(t, srv)
+		second, _ := serveConn(t, srv)
+
+		require.NoError(t, srv.Broadcast("tick", []any{1}))
+		for _, end := range []*pipeStream{first, second} {
+			req := readNotification(t, end)
+			assert.Equal(t, "tick", req.Method)
+			assert.Equal(t, []any{float64(1)}, req.Params)
+		}
+	})
+
+	t.Run("Slow connection does not block the others", func(t *testing.T) {
+		srv := NewServer(WithPushBuffer(1))
+		_, slowConn := serveConn(t, srv)
+		fast, _ := serveConn(t, srv)
+
+		// Nobody reads from the slow end, so its stream and then its queue fill up
+		var full bool
+		for range 100 {
+			if err := slowConn.Notify("fill", nil); err != nil {
+				require.ErrorIs(t, err, ErrPushQueueFull)
+				full = true
+				break
+			}
+		}
+		require.True(t, full)
+
+		require.NoError(t, srv.Broadcast("tick", nil))
+		assert.Equal(t, "tick", readNotification(t, fast).Method)
+	})
+
+	t.Run("Overflow close disconnects the slow connection", func(t *testing.T) {
+		srv := NewServer(WithPushBuffer(1), WithPushOverflow(OverflowClose))
+		_, slowConn := serveConn(t, srv)
+
+		for range 100 {
+			if slowConn.Notify("fill", nil) != nil {
+				break
+			}
+		}
+		select {
+		case <-slowConn.Done():
+		case <-time.After(time.Second):
+			t.Fatal("slow connection was not closed")
+		}
+		assert.Empty(t, srv.Conns())
+		assert.ErrorIs(t, slowConn.Notify("late", nil), ErrClientClosed)
+	})
+
+	t.Run("Invalid notification", func(t *testing.T) {
+		assert.Error(t, NewServer().Broadcast("", nil))
+	})
+}
+
+func TestConnFromContext(t *testing.T) {
+	t.Run("Handlers can push later", func(t *testing.T) {
+		srv := NewServer()
+		conns := make(chan *Conn, 1)
+		watch := func(ctx context.Context, _ *Request) (any, error) {
+			conn, ok := ConnFromContext(ctx)
+			require.True(t, ok)
+			conns <- conn
+			return true, nil
+		}
+		require.NoError(t, srv.RegisterFunc("watch", watch))
+
+		clientEnd, serverEnd := newStreamPair()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+		require.NoError(t, client.Call(context.Background(), "watch", nil, nil))
+
+		conn := <-conns
+		require.NoError(t, conn.Notify("changed", map[string]any{"n": 1}))
+		assert.Len(t, srv.Conns(), 1)
+	})
+
+	t.Run("Outside ServeStream", func(t *testing.T) {
+		_, ok := ConnFromContext(context.Background())
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,172 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// QuorumPolicy configures a QuorumTransport.
+type QuorumPolicy struct {
+	// Quorum is the number of endpoints that must agree on a reply. Defaults to a majority of
+	// the endpoints.
+	Quorum int
+
+	// Equal reports whether two replies agree, such as by comparing only the results of the
+	// responses they hold. Defaults to comparing their canonical JSON, as returned by
+	// CanonicalJSON, so that replies differing only in formatting or member order agree.
+	Equal func(a, b []byte) bool
+}
+
+// QuorumVote is the outcome of a call on one endpoint of a QuorumTransport.
+type QuorumVote struct {
+	// Endpoint is the name of the endpoint.
+	Endpoint string
+
+	// Reply is the reply of the endpoint, nil if the exchange failed.
+	Reply []byte
+
+	// Err is the exchange error, if any.
+	Err error
+}
+
+// QuorumError is returned by a QuorumTransport for the calls on which too few endpoints agreed.
+type QuorumError struct {
+	// Quorum is the number of endpoints that had to agree.
+	Quorum int
+
+	// Agreed is the largest number of endpoints that agreed on a reply.
+	Agreed int
+
+	// Votes holds the outcome of the call on every endpoint that answered before the quorum was
+	// out of reach, in the order they answered.
+	Votes []QuorumVote
+}
+
+// Error implements the error interface.
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("no quorum: at most %d endpoints agreed on a reply, %d needed",
+		e.Agreed, e.Quorum)
+}
+
+// QuorumTransport is a Transport sending every call to all of its endpoints at once and
+// returning a reply only once Quorum of them agree on it, for consuming untrusted upstreams such
+// as public blockchain nodes. When the quorum is out of reach, through diverging replies or
+// failed exchanges, the call fails with a *QuorumError reporting every vote. Once the quorum is
+// reached, the exchanges still in flight are canceled.
+//
+// Replies, including JSON-RPC errors, are compared as a whole: a batch answered in different
+// orders diverges unless Equal allows for it. A QuorumTransport is safe for concurrent use.
+type QuorumTransport struct {
+	endpoints []PoolEndpoint
+	quorum    int
+	equal     func(a, b []byte) bool
+}
+
+// NewQuorumTransport creates a QuorumTransport over the given endpoints.
+func NewQuorumTransport(endpoints []PoolEndpoint, policy QuorumPolicy) *QuorumTransport {
+	q := &QuorumTransport{
+		endpoints: endpoints,
+		quorum:    policy.Quorum,
+		equal:     policy.Equal,
+	}
+	if q.quorum <= 0 {
+		q.quorum = len(endpoints)/2 + 1
+	}
+	return q
+}
+
+// RoundTrip sends payload to every endpoint, returning the first reply that Quorum endpoints
+// agree on.
+func (q *QuorumTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if len(q.endpoints) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	votes := make(chan QuorumVote, len(q.endpoints))
+	for _, ep := range q.endpoints {
+		go func() {
+			reply, err := ep.Transport.RoundTrip(callCtx, payload)
+			votes <- QuorumVote{Endpoint: ep.Name, Reply: reply, Err: err}
+		}()
+	}
+
+	tally := &quorumTally{transport: q}
+	for pending := len(q.endpoints) - 1; pending >= 0; pending-- {
+		if reply, ok := tally.add(<-votes); ok {
+			return reply, nil
+		}
+		if tally.best+pending < q.quorum {
+			break
+		}
+	}
+	return nil, &QuorumError{Quorum: q.quorum, Agreed: tally.best, Votes: tally.votes}
+}
+
+// Close closes every endpoint's transport.
+func (q *QuorumTransport) Close() error {
+	var errs []error
+	for _, ep := range q.endpoints {
+		if err := ep.Transport.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// quorumTally counts the votes of a call, grouping agreeing replies.
+type quorumTally struct {
+	transport *QuorumTransport
+	votes     []QuorumVote
+	groups    []*quorumGroup
+	best      int
+}
+
+// quorumGroup is a set of agreeing replies.
+type quorumGroup struct {
+	reply []byte
+	key   []byte
+	count int
+}
+
+// add counts a vote, returning the agreed reply once it reaches the quorum.
+func (t *quorumTally) add(vote QuorumVote) ([]byte, bool) {
+	t.votes = append(t.votes, vote)
+	if vote.Err != nil {
+		return nil, false
+	}
+	group := t.group(vote.Reply)
+	group.count++
+	t.best = max(t.best, group.count)
+	return group.reply, group.count >= t.transport.quorum
+}
+
+// group returns the group of the replies agreeing with reply, creating it if there is none.
+func (t *quorumTally) group(reply []byte) *quorumGroup {
+	var key []byte
+	if t.transport.equal == nil {
+		key = reply
+		if canonical, err := CanonicalJSON(reply); err == nil {
+			key = canonical
+		}
+	}
+	for _, group := range t.groups {
+		if t.agree(group, reply, key) {
+			return group
+		}
+	}
+	group := &quorumGroup{reply: reply, key: key}
+	t.groups = append(t.groups, group)
+	return group
+}
+
+// agree reports whether reply, of the given canonical key, agrees with the replies of group.
+func (t *quorumTally) agree(group *quorumGroup, reply, key []byte) bool {
+	if t.transport.equal != nil {
+		return t.transport.equal(group.reply, reply)
+	}
+	return bytes.Equal(group.key, key)
+}
@@ -0,0 +1,137 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedEndpoint returns a pool endpoint answering with reply, or failing with err, after delay.
+func fixedEndpoint(name, reply string, delay time.Duration, err error) PoolEndpoint {
+	return PoolEndpoint{Name: name, Transport: &funcTransport{
+		fn: func(ctx context.Context, _ []byte) ([]byte, error) {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return []byte(reply), nil
+		},
+	}}
+}
+
+func TestQuorumTransport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	payload := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+
+	t.Run("Returns the reply a majority agrees on", func(t *testing.T) {
+		q := NewQuorumTransport([]PoolEndpoint{
+			fixedEndpoint("a", `{"jsonrpc":"2.0","id":1,"result":"0x10"}`, 0, nil),
+			fixedEndpoint("b", `{"jsonrpc":"2.0","id":1,"result":"0x11"}`, 0, nil),
+			fixedEndpoint("c", `{"id":1, "jsonrpc":"2.0", "result":"0x10"}`, 0, nil),
+		}, QuorumPolicy{})
+		defer q.Close()
+
+		reply, err := q.RoundTrip(ctx, payload)
+		require.NoError(t, err)
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		var got string
+		require.NoError(t, resp.UnmarshalResult(&got))
+		assert.Equal(t, "0x10", got)
+	})
+
+	t.Run("Reports divergence", func(t *testing.T) {
+		q := NewQuorumTransport([]PoolEndpoint{
+			fixedEndpoint("a", `{"jsonrpc":"2.0","id":1,"result":"0x10"}`, 0, nil),
+			fixedEndpoint("b", `{"jsonrpc":"2.0","id":1,"result":"0x11"}`, 0, nil),
+			fixedEndpoint("c", "", 0, assert.AnError),
+		}, QuorumPolicy{})
+		defer q.Close()
+
+		_, err := q.RoundTrip(ctx, payload)
+		var quorumErr *QuorumError
+		require.ErrorAs(t, err, &quorumErr)
+		assert.Equal(t, 2, quorumErr.Quorum)
+		assert.Equal(t, 1, quorumErr.Agreed)
+		require.Len(t, quorumErr.Votes, 3)
+		names := make(map[string]QuorumVote)
+		for _, vote := range quorumErr.Votes {
+			names[vote.Endpoint] = vote
+		}
+		require.ErrorIs(t, names["c"].Err, assert.AnError)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"0x11"}`, string(names["b"].Reply))
+		assert.Contains(t, err.Error(), "at most 1 endpoints agreed")
+	})
+
+	t.Run("Returns without waiting for the others", func(t *testing.T) {
+		q := NewQuorumTransport([]PoolEndpoint{
+			fixedEndpoint("a", `{"jsonrpc":"2.0","id":1,"result":1}`, 0, nil),
+			fixedEndpoint("b", `{"jsonrpc":"2.0","id":1,"result":1}`, 0, nil),
+			fixedEndpoint("c", `{"jsonrpc":"2.0","id":1,"result":1}`, time.Minute, nil),
+		}, QuorumPolicy{})
+		defer q.Close()
+
+		start := time.Now()
+		_, err := q.RoundTrip(ctx, payload)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Gives up once the quorum is out of reach", func(t *testing.T) {
+		q := NewQuorumTransport([]PoolEndpoint{
+			fixedEndpoint("a", "", 0, assert.AnError),
+			fixedEndpoint("b", "", 0, assert.AnError),
+			fixedEndpoint("c", `{"jsonrpc":"2.0","id":1,"result":1}`, time.Minute, nil),
+		}, QuorumPolicy{Quorum: 2})
+		defer q.Close()
+
+		start := time.Now()
+		_, err := q.RoundTrip(ctx, payload)
+		var quorumErr *QuorumError
+		require.ErrorAs(t, err, &quorumErr)
+		assert.Zero(t, quorumErr.Agreed)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Compares with Equal", func(t *testing.T) {
+		sameResult := func(a, b []byte) bool {
+			ra, errA := DecodeResponse(a)
+			rb, errB := DecodeResponse(b)
+			return errA == nil && errB == nil && bytes.Equal(ra.RawResult(), rb.RawResult())
+		}
+		q := NewQuorumTransport([]PoolEndpoint{
+			fixedEndpoint("a", `{"jsonrpc":"2.0","id":1,"result":"ok"}`, 0, nil),
+			fixedEndpoint("b", `{"jsonrpc":"2.0","id":"1","result":"ok"}`, 0, nil),
+		}, QuorumPolicy{Quorum: 2, Equal: sameResult})
+		defer q.Close()
+
+		_, err := q.RoundTrip(ctx, payload)
+		require.NoError(t, err)
+	})
+
+	t.Run("No endpoints", func(t *testing.T) {
+		_, err := NewQuorumTransport(nil, QuorumPolicy{}).RoundTrip(ctx, payload)
+		require.ErrorIs(t, err, errNoEndpoints)
+	})
+
+	t.Run("Serves a client", func(t *testing.T) {
+		q := NewQuorumTransport([]PoolEndpoint{
+			fixedEndpoint("a", `{"jsonrpc":"2.0","id":1,"result":"0x10"}`, 0, nil),
+			fixedEndpoint("b", `{"jsonrpc":"2.0","id":1,"result":"0x10"}`, 0, nil),
+		}, QuorumPolicy{})
+		client := NewClient(q, WithIDGenerator(IDGeneratorFunc(func() any { return int64(1) })))
+		defer client.Close()
+
+		var got string
+		require.NoError(t, client.Call(ctx, "eth_blockNumber", nil, &got))
+		assert.Equal(t, "0x10", got)
+	})
+}
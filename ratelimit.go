@@ -0,0 +1,246 @@
+package jsonrpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// RateLimited is the error code of responses to requests turned away by a rate limit, within the
+// range reserved for implementation-defined server errors.
+const RateLimited = -32014
+
+// msgRateLimited is the message of RateLimited errors.
+const msgRateLimited = "Rate limited"
+
+// ErrRateLimited is the error sent for requests turned away by a rate limit.
+var ErrRateLimited = &Error{Code: RateLimited, Message: msgRateLimited}
+
+// minBucketSweep is the number of buckets a TokenBucket holds before dropping the idle ones.
+const minBucketSweep = 64
+
+// RateLimiter keeps separate call budgets under string keys, such as peer addresses or method
+// names. Implementations must be safe for concurrent use.
+type RateLimiter interface {
+	// Allow reports whether a call under key may proceed now, taking it from the budget if so.
+	Allow(key string) bool
+
+	// Wait blocks until a call under key may proceed and takes it from the budget, or returns
+	// ctx.Err() once ctx is done.
+	Wait(ctx context.Context, key string) error
+}
+
+// RateLimit applies a RateLimiter to calls.
+type RateLimit struct {
+	// Limiter keeps the budgets.
+	Limiter RateLimiter
+
+	// Key returns the budget a call is taken from, for instance RateLimitByPeer. Defaults to a
+	// single budget shared by all calls.
+	Key func(ctx context.Context, req *Request) string
+}
+
+// key returns the budget of req.
+func (l RateLimit) key(ctx context.Context, req *Request) string {
+	if l.Key == nil {
+		return ""
+	}
+	return l.Key(ctx, req)
+}
+
+// RateLimitByPeer is a RateLimit.Key giving each remote host its own budget, taken from the
+// PeerMetadataKey of IncomingMetadata without the port. Calls from unknown peers share one budget.
+func RateLimitByPeer(ctx context.Context, _ *Request) string {
+	md, _ := IncomingMetadata(ctx)
+	peer := md.Get(PeerMetadataKey)
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		return host
+	}
+	return peer
+}
+
+// RateLimitByMethod is a RateLimit.Key giving each method its own budget.
+func RateLimitByMethod(_ context.Context, req *Request) string {
+	return req.Method
+}
+
+// WithRateLimit turns away requests exceeding limit across all methods, answering them with
+// ErrRateLimited, or over HTTP with 429 Too Many Requests. Notifications count towards the limit
+// and are dropped when exceeding it. Several limits may be added, and requests must pass all of
+// them.
+func WithRateLimit(limit RateLimit) ServerOption {
+	return func(s *Server) {
+		s.rateLimits = append(s.rateLimits, limit)
+	}
+}
+
+// WithMethodRateLimit turns away requests to method exceeding limit, in addition to the limits
+// set with WithRateLimit.
+func WithMethodRateLimit(method string, limit RateLimit) ServerOption {
+	return func(s *Server) {
+		if s.methodRateLimits == nil {
+			s.methodRateLimits = make(map[string][]RateLimit)
+		}
+		s.methodRateLimits[method] = append(s.methodRateLimits[method], limit)
+	}
+}
+
+// WithClientRateLimit throttles the client to limit, so that it respects the quotas of upstream
+// providers: calls and notifications wait until the limit allows them or their context is done,
+// and batches until it allows each of their members. Throttling runs as an interceptor placed
+// after those added by earlier WithInterceptors options, so that retries placed before it are
+// throttled too.
+func WithClientRateLimit(limit RateLimit) ClientOption {
+	return func(c *Client) {
+		c.rateLimits = append(c.rateLimits, limit)
+		WithInterceptors(func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				if err := limit.Limiter.Wait(ctx, limit.key(ctx, req)); err != nil {
+					return nil, err
+				}
+				return next(ctx, req)
+			}
+		})(c)
+	}
+}
+
+// checkRateLimits returns ErrRateLimited if req exceeds a rate limit of the server, counting the
+// outcome for the admin methods of WithAdmin.
+func (s *Server) checkRateLimits(ctx context.Context, req *Request) error {
+	if s.admin == nil || len(s.rateLimits)+len(s.methodRateLimits[req.Method]) == 0 {
+		return s.rateLimited(ctx, req)
+	}
+	err := s.rateLimited(ctx, req)
+	method := req.Method
+	if _, ok := s.registry.Load().lookup(method); !ok {
+		method = ""
+	}
+	s.admin.countRateLimit(method, err)
+	return err
+}
+
+// rateLimited returns ErrRateLimited if req exceeds a rate limit of the server.
+func (s *Server) rateLimited(ctx context.Context, req *Request) error {
+	for _, limit := range s.rateLimits {
+		if !limit.Limiter.Allow(limit.key(ctx, req)) {
+			return ErrRateLimited
+		}
+	}
+	for _, limit := range s.methodRateLimits[req.Method] {
+		if !limit.Limiter.Allow(limit.key(ctx, req)) {
+			return ErrRateLimited
+		}
+	}
+	return nil
+}
+
+// throttleBatch waits until the client's rate limits allow every member of a batch.
+func (c *Client) throttleBatch(ctx context.Context, reqs []*Request) error {
+	for _, limit := range c.rateLimits {
+		for _, req := range reqs {
+			if err := limit.Limiter.Wait(ctx, limit.key(ctx, req)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TokenBucket is a RateLimiter giving each key a bucket of tokens, refilled at a steady rate up
+// to a burst size, a call taking one token. Buckets are created on first use, and idle buckets
+// are dropped once refilled. A TokenBucket is safe for concurrent use.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweepAt int
+}
+
+// bucket is the state of a TokenBucket key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a TokenBucket refilling rate tokens per second, up to burst tokens,
+// which is also the number of calls allowed at once after a pause. A burst below 1 means 1, and
+// a rate of zero or less never refills.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:    max(rate, 0),
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+		sweepAt: minBucketSweep,
+	}
+}
+
+// Allow implements RateLimiter.
+func (tb *TokenBucket) Allow(key string) bool {
+	return tb.take(key, time.Now()) == 0
+}
+
+// Wait implements RateLimiter.
+func (tb *TokenBucket) Wait(ctx context.Context, key string) error {
+	for {
+		wait := tb.take(key, time.Now())
+		if wait == 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take takes a token from the bucket of key if it has one, returning zero, and otherwise returns
+// how long until it has one.
+func (tb *TokenBucket) take(key string, now time.Time) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		tb.sweep(now)
+		b = &bucket{tokens: tb.burst, last: now}
+		tb.buckets[key] = b
+	}
+	tb.refill(b, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if tb.rate == 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration((1 - b.tokens) / tb.rate * float64(time.Second))
+}
+
+// refill adds the tokens accrued by b since its last use. The caller must hold tb.mu.
+func (tb *TokenBucket) refill(b *bucket, now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = min(tb.burst, b.tokens+elapsed*tb.rate)
+	b.last = now
+}
+
+// sweep drops the buckets refilled to their burst once there are many of them, since they hold
+// no more state than new buckets. The caller must hold tb.mu.
+func (tb *TokenBucket) sweep(now time.Time) {
+	if len(tb.buckets) < tb.sweepAt {
+		return
+	}
+	for key, b := range tb.buckets {
+		tb.refill(b, now)
+		if b.tokens >= tb.burst {
+			delete(tb.buckets, key)
+		}
+	}
+	tb.sweepAt = max(minBucketSweep, 2*len(tb.buckets))
+}
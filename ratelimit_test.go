@@ -0,0 +1,170 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("Allows the burst, then refills", func(t *testing.T) {
+		tb := NewTokenBucket(100, 2)
+		assert.True(t, tb.Allow("a"))
+		assert.True(t, tb.Allow("a"))
+		assert.False(t, tb.Allow("a"))
+		assert.True(t, tb.Allow("b"), "keys have their own bucket")
+
+		require.Eventually(t, func() bool { return tb.Allow("a") }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Zero rate never refills", func(t *testing.T) {
+		tb := NewTokenBucket(0, 0)
+		assert.True(t, tb.Allow(""))
+		assert.False(t, tb.Allow(""))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, tb.Wait(ctx, ""), context.DeadlineExceeded)
+	})
+
+	t.Run("Wait blocks until a token is available", func(t *testing.T) {
+		tb := NewTokenBucket(50, 1)
+		ctx := context.Background()
+		start := time.Now()
+		for range 3 {
+			require.NoError(t, tb.Wait(ctx, ""))
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("Idle buckets are dropped", func(t *testing.T) {
+		tb := NewTokenBucket(1000, 1)
+		for i := range minBucketSweep {
+			tb.Allow(strings.Repeat("k", i+1))
+		}
+		time.Sleep(5 * time.Millisecond)
+		tb.Allow("new")
+		tb.mu.Lock()
+		defer tb.mu.Unlock()
+		assert.Len(t, tb.buckets, 1)
+	})
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	ctx := NewIncomingContext(context.Background(), MetadataPairs(PeerMetadataKey, "10.0.0.1:5000"))
+	assert.Equal(t, "10.0.0.1", RateLimitByPeer(ctx, nil))
+	ctx = NewIncomingContext(context.Background(), MetadataPairs(PeerMetadataKey, "pipe"))
+	assert.Equal(t, "pipe", RateLimitByPeer(ctx, nil))
+	assert.Equal(t, "", RateLimitByPeer(context.Background(), nil))
+	assert.Equal(t, "sum", RateLimitByMethod(context.Background(), NewRequest("sum", nil)))
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Requests over the limit are rejected", func(t *testing.T) {
+		srv := newTestServer(t)
+		WithRateLimit(RateLimit{Limiter: NewTokenBucket(0, 2)})(srv)
+
+		for i := range 2 {
+			resp := srv.HandleRequest(ctx, NewRequestWithID("sum", []int{1}, i))
+			assert.Nil(t, resp.Err())
+		}
+		resp := srv.HandleRequest(ctx, NewRequestWithID("sum", []int{1}, 3))
+		require.ErrorIs(t, resp.Err(), ErrRateLimited)
+		assert.Equal(t, RateLimited, resp.Err().Code)
+	})
+
+	t.Run("Method limit applies to its method only", func(t *testing.T) {
+		srv := newTestServer(t)
+		WithMethodRateLimit("sum", RateLimit{Limiter: NewTokenBucket(0, 1)})(srv)
+
+		assert.Nil(t, srv.HandleRequest(ctx, NewRequestWithID("sum", []int{1}, 1)).Err())
+		require.ErrorIs(t, srv.HandleRequest(ctx, NewRequestWithID("sum", nil, 2)).Err(),
+			ErrRateLimited)
+		for i := range 3 {
+			resp := srv.HandleRequest(ctx, NewRequestWithID("subtract", []int{2, 1}, i))
+			assert.Nil(t, resp.Err())
+		}
+	})
+
+	t.Run("Peers have their own budget over HTTP", func(t *testing.T) {
+		srv := newTestServer(t)
+		WithRateLimit(RateLimit{Limiter: NewTokenBucket(0, 1), Key: RateLimitByPeer})(srv)
+
+		post := func(peer string) int {
+			body := `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			r.Header.Set("Content-Type", contentTypeJSON)
+			r.RemoteAddr = peer
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			return w.Code
+		}
+		assert.Equal(t, http.StatusOK, post("10.0.0.1:1000"))
+		assert.Equal(t, http.StatusTooManyRequests, post("10.0.0.1:1001"))
+		assert.Equal(t, http.StatusOK, post("10.0.0.2:1000"))
+	})
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+	var sent atomic.Int32
+	transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		sent.Add(1)
+		return srv.HandleMessage(ctx, payload), nil
+	}}
+
+	t.Run("Calls wait for the limit", func(t *testing.T) {
+		client := NewClient(transport, WithClientRateLimit(RateLimit{
+			Limiter: NewTokenBucket(50, 1),
+		}))
+		start := time.Now()
+		for range 3 {
+			require.NoError(t, client.Call(ctx, "sum", []int{1}, nil))
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("Calls give up with their context", func(t *testing.T) {
+		sent.Store(0)
+		client := NewClient(transport, WithClientRateLimit(RateLimit{
+			Limiter: NewTokenBucket(0, 1),
+		}))
+		require.NoError(t, client.Notify(ctx, "notify_hello", nil))
+
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, client.Call(short, "sum", nil, nil), context.DeadlineExceeded)
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("Batch members count towards the limit", func(t *testing.T) {
+		sent.Store(0)
+		client := NewClient(transport, WithClientRateLimit(RateLimit{
+			Limiter: NewTokenBucket(0, 2),
+		}))
+		reqs := []*Request{
+			NewRequestWithID("sum", []int{1}, int64(1)),
+			NewRequestWithID("sum", []int{2}, int64(2)),
+		}
+		_, err := client.CallBatch(ctx, reqs)
+		require.NoError(t, err)
+
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		b := client.NewBatch()
+		call := b.Add("sum", []int{1})
+		require.ErrorIs(t, client.SendBatch(short, b), context.DeadlineExceeded)
+		require.ErrorIs(t, call.Err(), context.DeadlineExceeded)
+		assert.Equal(t, int32(1), sent.Load())
+	})
+}
@@ -0,0 +1,172 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConnectionLost is returned by calls made on, or interrupted by, a dropped connection of a
+// reconnecting stream client. The call may or may not have reached the server.
+var ErrConnectionLost = errors.New("connection lost")
+
+// Dialer establishes a new Stream, such as a WebSocket connection to a fixed URL.
+type Dialer func(ctx context.Context) (Stream, error)
+
+// ReconnectPolicy configures the reconnection of stream clients enabled with WithReconnect.
+type ReconnectPolicy struct {
+	// MaxAttempts caps the dial attempts made after the connection drops, after which the client
+	// shuts down. Zero means no limit.
+	MaxAttempts int
+
+	// InitialBackoff is the wait after the first failed attempt. Defaults to 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts. Defaults to 5s.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the wait grows after each attempt. Defaults to 2.
+	Multiplier float64
+
+	// Jitter randomizes each wait by up to this fraction in either direction. Defaults to 0.2;
+	// negative values disable it.
+	Jitter float64
+
+	// OnReconnect, if set, is called after every attempt. It runs on the client's read loop,
+	// so it must not block nor make calls on the client.
+	OnReconnect func(event ReconnectEvent)
+}
+
+// ReconnectEvent describes a reconnection attempt of a stream client.
+type ReconnectEvent struct {
+	// Attempt is the number of the attempt since the connection dropped, starting at 1.
+	Attempt int
+
+	// Cause is the error that dropped the connection.
+	Cause error
+
+	// Err is the error of the attempt, nil once reconnected.
+	Err error
+}
+
+// WithReconnect makes a stream client redial with dial when reading from its stream fails,
+// instead of shutting down. Attempts back off exponentially according to policy.
+//
+// While the connection is down, calls fail with ErrConnectionLost, which WithRetry treats as
+// retryable. Once reconnected, every active subscription is renewed by repeating its subscribe
+// call, after which notifications carry on under the new subscription ID; subscriptions failing
+// to renew end with the error on their Err channel. It has no effect on clients using a
+// request/response Transport.
+func WithReconnect(dial Dialer, policy ReconnectPolicy) ClientOption {
+	return func(c *Client) {
+		c.dial = dial
+		c.reconnectPolicy = &policy
+	}
+}
+
+// streamConn is a connection of a stream client, replaced when a reconnecting client redials.
+type streamConn struct {
+	stream Stream
+
+	// lost is closed once the connection has dropped
+	lost chan struct{}
+
+	// read is when a message was last read, in Unix nanoseconds
+	read atomic.Int64
+
+	// dead holds the cause of the connection closed because its peer was declared dead
+	dead atomic.Pointer[error]
+
+	// replies orders the replies to inbound messages, for servers with WithOrderedReplies
+	replies *replyQueue
+}
+
+// newStreamConn wraps a stream as a live connection.
+func newStreamConn(stream Stream) *streamConn {
+	conn := &streamConn{stream: stream, lost: make(chan struct{})}
+	conn.touch()
+	return conn
+}
+
+// touch records that a message was read.
+func (c *streamConn) touch() {
+	c.read.Store(time.Now().UnixNano())
+}
+
+// lastRead returns when a message was last read, or when the connection was established.
+func (c *streamConn) lastRead() time.Time {
+	return time.Unix(0, c.read.Load())
+}
+
+// readErr returns the error to report for a failed read: the cause of the peer declared dead, if
+// it was, and err otherwise.
+func (c *streamConn) readErr(err error) error {
+	if cause := c.dead.Load(); cause != nil {
+		return *cause
+	}
+	return err
+}
+
+// reconnect replaces a dropped connection, reporting whether the client carries on with a new
+// one. It gives up when the client is closed meanwhile or the attempts run out, in which case
+// the client is shut down.
+func (c *Client) reconnect(conn *streamConn, cause error) bool {
+	close(conn.lost)
+	_ = conn.stream.Close()
+	c.abortChunkStreams(ErrConnectionLost)
+
+	backoff := RetryPolicy{
+		InitialBackoff: c.reconnectPolicy.InitialBackoff,
+		MaxBackoff:     c.reconnectPolicy.MaxBackoff,
+		Multiplier:     c.reconnectPolicy.Multiplier,
+		Jitter:         c.reconnectPolicy.Jitter,
+	}.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		stream, err := c.dial(c.ctx)
+		if err == nil && !c.replaceConn(stream) {
+			return false
+		}
+		if hook := c.reconnectPolicy.OnReconnect; hook != nil {
+			hook(ReconnectEvent{Attempt: attempt, Cause: cause, Err: err})
+		}
+		if err == nil {
+			c.renegotiate()
+			go c.resubscribe()
+			return true
+		}
+
+		limit := c.reconnectPolicy.MaxAttempts
+		if limit > 0 && attempt >= limit {
+			c.shutdown(fmt.Errorf("%w: %w; reconnecting failed: %w", ErrClientClosed, cause, err))
+			return false
+		}
+
+		timer := time.NewTimer(backoff.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// replaceConn makes stream the client's connection, unless the client has been closed meanwhile,
+// in which case the stream is closed instead.
+func (c *Client) replaceConn(stream Stream) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		_ = stream.Close()
+		return false
+	}
+	boundStream(stream, c.limits.maxMessageSize)
+	conn := newStreamConn(stream)
+	c.conn.Store(conn)
+	c.startKeepalive(conn)
+	return true
+}
@@ -0,0 +1,278 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reconnectPeer serves a server over a new stream pair for every dial, handing out subscription
+// IDs "0x1", "0x2", and so on.
+type reconnectPeer struct {
+	srv     *Server
+	subs    atomic.Int32
+	fail    atomic.Int32
+	reject  atomic.Bool
+	conns   chan *pipeStream
+	peers   chan *Client
+	blocked chan struct{}
+	release chan struct{}
+}
+
+// newReconnectPeer creates a peer serving echo, block, and eth_subscribe. Subscribing fails while
+// reject is set.
+func newReconnectPeer(t *testing.T) *reconnectPeer {
+	t.Helper()
+	p := &reconnectPeer{
+		srv:     NewServer(),
+		conns:   make(chan *pipeStream, 8),
+		peers:   make(chan *Client, 8),
+		blocked: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	echo := func(_ context.Context, req *Request) (any, error) {
+		return req.Params, nil
+	}
+	block := func(ctx context.Context, _ *Request) (any, error) {
+		p.blocked <- struct{}{}
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+		}
+		return nil, nil
+	}
+	subscribe := func(ctx context.Context, _ *Request) (any, error) {
+		if p.reject.Load() {
+			return nil, ErrInvalidParams
+		}
+		peer, _ := ClientFromContext(ctx)
+		p.peers <- peer
+		return fmt.Sprintf("0x%d", p.subs.Add(1)), nil
+	}
+	require.NoError(t, p.srv.RegisterFunc("echo", echo))
+	require.NoError(t, p.srv.RegisterFunc("block", block))
+	require.NoError(t, p.srv.RegisterFunc("eth_subscribe", subscribe))
+	require.NoError(t, p.srv.RegisterFunc("eth_unsubscribe", echo))
+	t.Cleanup(func() { close(p.release) })
+	return p
+}
+
+// dial connects a new stream to the peer's server, failing while fail is positive.
+func (p *reconnectPeer) dial(context.Context) (Stream, error) {
+	if p.fail.Add(-1) >= 0 {
+		return nil, assert.AnError
+	}
+	clientEnd, serverEnd := newStreamPair()
+	go func() { _ = p.srv.ServeStream(context.Background(), serverEnd) }()
+	p.conns <- serverEnd
+	return clientEnd, nil
+}
+
+// drop closes the oldest connection not dropped yet.
+func (p *reconnectPeer) drop(t *testing.T) {
+	t.Helper()
+	select {
+	case conn := <-p.conns:
+		_ = conn.Close()
+	case <-time.After(time.Second):
+		t.Fatal("no connection to drop")
+	}
+}
+
+// connect dials the peer and returns a client reconnecting with policy. Events are sent on the
+// returned channel.
+func (p *reconnectPeer) connect(
+	t *testing.T,
+	policy ReconnectPolicy,
+) (*Client, <-chan ReconnectEvent) {
+	t.Helper()
+	events := make(chan ReconnectEvent, 16)
+	policy.OnReconnect = func(event ReconnectEvent) { events <- event }
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = time.Millisecond
+	}
+
+	stream, err := p.dial(context.Background())
+	require.NoError(t, err)
+	client := NewStreamClient(stream, WithReconnect(p.dial, policy))
+	t.Cleanup(func() { _ = client.Close() })
+	return client, events
+}
+
+// nextEvent returns the next reconnection event.
+func nextEvent(t *testing.T, events <-chan ReconnectEvent) ReconnectEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("reconnect event not received")
+		return ReconnectEvent{}
+	}
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reconnects after the connection drops", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{})
+
+		p.drop(t)
+		event := nextEvent(t, events)
+		assert.Equal(t, 1, event.Attempt)
+		require.ErrorIs(t, event.Cause, io.EOF)
+		require.NoError(t, event.Err)
+
+		var result []int
+		require.NoError(t, client.Call(ctx, "echo", []int{1}, &result))
+		assert.Equal(t, []int{1}, result)
+		select {
+		case <-client.Done():
+			t.Fatal("client shut down")
+		default:
+		}
+	})
+
+	t.Run("Backs off between failed attempts", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{})
+
+		p.fail.Store(2)
+		p.drop(t)
+		for attempt := 1; attempt <= 2; attempt++ {
+			event := nextEvent(t, events)
+			assert.Equal(t, attempt, event.Attempt)
+			require.ErrorIs(t, event.Err, assert.AnError)
+		}
+		event := nextEvent(t, events)
+		assert.Equal(t, 3, event.Attempt)
+		require.NoError(t, event.Err)
+
+		require.NoError(t, client.Call(ctx, "echo", nil, nil))
+	})
+
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{MaxAttempts: 2})
+
+		p.fail.Store(5)
+		p.drop(t)
+		nextEvent(t, events)
+		nextEvent(t, events)
+
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("client not shut down")
+		}
+		err := client.Call(ctx, "echo", nil, nil)
+		require.ErrorIs(t, err, ErrClientClosed)
+		require.ErrorIs(t, err, io.EOF)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("In-flight calls fail with ErrConnectionLost", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{})
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- client.Call(ctx, "block", nil, nil) }()
+		<-p.blocked
+
+		p.drop(t)
+		select {
+		case err := <-errCh:
+			require.ErrorIs(t, err, ErrConnectionLost)
+		case <-time.After(time.Second):
+			t.Fatal("call not interrupted")
+		}
+		nextEvent(t, events)
+	})
+
+	t.Run("Close stops reconnecting", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{InitialBackoff: time.Hour})
+
+		p.fail.Store(1)
+		p.drop(t)
+		nextEvent(t, events)
+
+		require.NoError(t, client.Close())
+		require.ErrorIs(t, client.Call(ctx, "echo", nil, nil), ErrClientClosed)
+	})
+
+	t.Run("Without reconnect the client shuts down", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		stream, err := p.dial(ctx)
+		require.NoError(t, err)
+		client := NewStreamClient(stream)
+		defer client.Close()
+
+		p.drop(t)
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("client not shut down")
+		}
+	})
+}
+
+func TestClient_ReconnectResubscribes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Renews subscriptions on the new connection", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{})
+
+		ch := make(chan json.RawMessage, 4)
+		sub, err := client.Subscribe(ctx, "eth_subscribe", []any{"newHeads"}, ch)
+		require.NoError(t, err)
+		assert.Equal(t, "0x1", sub.ID())
+		require.NoError(t, publish(ctx, <-p.peers, "0x1", 1))
+		assert.Equal(t, 1, receive(t, ch))
+
+		p.drop(t)
+		nextEvent(t, events)
+
+		var peer *Client
+		select {
+		case peer = <-p.peers:
+		case <-time.After(time.Second):
+			t.Fatal("subscription not renewed")
+		}
+		require.Eventually(t, func() bool {
+			return sub.ID() == "0x2"
+		}, time.Second, time.Millisecond)
+		require.NoError(t, publish(ctx, peer, "0x2", 2))
+		assert.Equal(t, 2, receive(t, ch))
+	})
+
+	t.Run("Failed renewal ends the subscription", func(t *testing.T) {
+		p := newReconnectPeer(t)
+		client, events := p.connect(t, ReconnectPolicy{})
+
+		ch := make(chan json.RawMessage, 4)
+		sub, err := client.Subscribe(ctx, "eth_subscribe", nil, ch)
+		require.NoError(t, err)
+		<-p.peers
+
+		p.reject.Store(true)
+		p.drop(t)
+		nextEvent(t, events)
+
+		select {
+		case err := <-sub.Err():
+			require.ErrorIs(t, err, ErrInvalidParams)
+		case <-time.After(time.Second):
+			t.Fatal("subscription not ended")
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// registry is a snapshot of the methods of a server. A snapshot is never modified once
+// published: updates copy the current one and swap the copy in, so that requests are dispatched
+// without taking a lock and see either all of an update or none of it.
+type registry struct {
+	methods   map[string]Handler
+	folded    map[string]string
+	wildcards []wildcardRoute
+}
+
+// newRegistry returns an empty registry.
+func newRegistry() *registry {
+	return &registry{methods: make(map[string]Handler), folded: make(map[string]string)}
+}
+
+// clone returns a copy of r to update.
+func (r *registry) clone() *registry {
+	return &registry{
+		methods:   maps.Clone(r.methods),
+		folded:    maps.Clone(r.folded),
+		wildcards: slices.Clone(r.wildcards),
+	}
+}
+
+// add adds handler under method, a name or a wildcard pattern.
+func (r *registry) add(method string, handler Handler) {
+	if strings.HasSuffix(method, wildcard) {
+		r.addWildcard(method, handler)
+		return
+	}
+	r.methods[method] = handler
+	r.folded[strings.ToLower(method)] = method
+}
+
+// remove removes method, a name or a wildcard pattern, reporting false if it is not registered.
+func (r *registry) remove(method string) bool {
+	if strings.HasSuffix(method, wildcard) {
+		prefix := strings.TrimSuffix(method, wildcard)
+		i := slices.IndexFunc(r.wildcards, func(route wildcardRoute) bool {
+			return route.prefix == prefix
+		})
+		if i < 0 {
+			return false
+		}
+		r.wildcards = slices.Delete(r.wildcards, i, i+1)
+		return true
+	}
+	if _, ok := r.methods[method]; !ok {
+		return false
+	}
+	delete(r.methods, method)
+	if lower := strings.ToLower(method); r.folded[lower] == method {
+		delete(r.folded, lower)
+	}
+	return true
+}
+
+// RegistryUpdate is a set of changes to the methods of a running server, applied together by
+// Server.Update.
+type RegistryUpdate struct {
+	removed []string
+	added   map[string]Handler
+}
+
+// Register adds handler under method, a name or a wildcard pattern as for Server.Register.
+// Registering a method that the update also deregisters replaces its handler.
+func (u *RegistryUpdate) Register(method string, handler Handler) {
+	u.added[method] = handler
+}
+
+// Deregister removes method, a name or a wildcard pattern.
+func (u *RegistryUpdate) Deregister(method string) {
+	u.removed = append(u.removed, method)
+}
+
+// Update applies the changes made by fn to the methods of the server at once, such as to load or
+// unload the methods of a plugin, or to replace them with a new version. It fails without
+// changing anything if a method to register is invalid or already registered, or one to
+// deregister is not registered.
+//
+// Updates are safe while serving: connections stay up, requests dispatched after the update see
+// all of it, and requests dispatched before keep running with the handlers they were dispatched
+// to. Descriptions set with Describe and per-method options outlive deregistration, so that they
+// apply to a method registered again under the same name.
+func (s *Server) Update(fn func(u *RegistryUpdate)) error {
+	u := &RegistryUpdate{added: make(map[string]Handler)}
+	fn(u)
+	for method, handler := range u.added {
+		if err := validateRegistration(method, handler); err != nil {
+			return err
+		}
+		if s.isAdminMethod(method) {
+			return errors.New("method names starting with 'admin.' are reserved by WithAdmin")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.registry.Load().clone()
+	for _, method := range u.removed {
+		if strings.HasPrefix(method, reservedPrefix) {
+			return errors.New("method names starting with 'rpc.' are reserved by JSON-RPC 2.0 spec")
+		}
+		if !next.remove(method) {
+			return fmt.Errorf("method %q is not registered", method)
+		}
+	}
+	for method := range u.added {
+		if err := next.checkRegistered(method); err != nil {
+			return err
+		}
+		if !s.foldCase {
+			continue
+		}
+		if err := next.checkFolded(method); err != nil {
+			return err
+		}
+	}
+	for method, handler := range u.added {
+		next.add(method, handler)
+	}
+	s.registry.Store(next)
+	return nil
+}
+
+// Deregister removes methods, names or wildcard patterns, from the running server at once. It
+// fails without removing any if one of them is not registered. See Update.
+func (s *Server) Deregister(methods ...string) error {
+	return s.Update(func(u *RegistryUpdate) {
+		for _, method := range methods {
+			u.Deregister(method)
+		}
+	})
+}
+
+// registerBuiltin serves fn under a method name of the library, such as rpc.discover, replacing
+// any handler registered under it.
+func (s *Server) registerBuiltin(
+	method string,
+	fn func(ctx context.Context, req *Request) (any, error),
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.registry.Load().clone()
+	next.add(method, HandlerFunc(fn))
+	s.registry.Store(next)
+}
@@ -0,0 +1,102 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// constHandler returns a handler answering result.
+func constHandler(result any) Handler {
+	return HandlerFunc(func(context.Context, *Request) (any, error) {
+		return result, nil
+	})
+}
+
+func TestServer_Update(t *testing.T) {
+	ctx := context.Background()
+
+	// call calls method on srv, returning the result or the error code.
+	call := func(srv *Server, method string) any {
+		resp := srv.HandleRequest(ctx, NewRequestWithID(method, nil, int64(1)))
+		if rpcErr := resp.Err(); rpcErr != nil {
+			return rpcErr.Code
+		}
+		var result any
+		require.NoError(t, resp.UnmarshalResult(&result))
+		return result
+	}
+
+	t.Run("Loads, replaces, and unloads methods", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Update(func(u *RegistryUpdate) {
+			u.Register("plugin.a", constHandler("a1"))
+			u.Register("plugin.*", constHandler("any"))
+		}))
+		assert.Equal(t, "a1", call(srv, "plugin.a"))
+		assert.Equal(t, "any", call(srv, "plugin.b"))
+
+		require.NoError(t, srv.Update(func(u *RegistryUpdate) {
+			u.Deregister("plugin.a")
+			u.Register("plugin.a", constHandler("a2"))
+		}))
+		assert.Equal(t, "a2", call(srv, "plugin.a"))
+
+		require.NoError(t, srv.Deregister("plugin.a", "plugin.*"))
+		assert.EqualValues(t, MethodNotFound, call(srv, "plugin.a"))
+		assert.EqualValues(t, MethodNotFound, call(srv, "plugin.b"))
+		assert.Empty(t, srv.Methods())
+	})
+
+	t.Run("Failed updates change nothing", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("kept", constHandler("kept")))
+
+		err := srv.Update(func(u *RegistryUpdate) {
+			u.Deregister("kept")
+			u.Deregister("missing")
+		})
+		assert.ErrorContains(t, err, `method "missing" is not registered`)
+		err = srv.Update(func(u *RegistryUpdate) {
+			u.Register("new", constHandler("new"))
+			u.Register("kept", constHandler("other"))
+		})
+		assert.ErrorContains(t, err, "already registered")
+		assert.Error(t, srv.Deregister("rpc.discover"))
+
+		assert.Equal(t, []string{"kept"}, srv.Methods())
+		assert.Equal(t, "kept", call(srv, "kept"))
+	})
+
+	t.Run("Connections stay up across updates", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("v", constHandler(int64(0))))
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		var version atomic.Int64
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			for i := int64(1); i <= 50; i++ {
+				assert.NoError(t, srv.Update(func(u *RegistryUpdate) {
+					u.Deregister("v")
+					u.Register("v", constHandler(i))
+				}))
+				version.Store(i)
+			}
+		})
+		for range 50 {
+			before := version.Load()
+			var got int64
+			require.NoError(t, client.Call(ctx, "v", nil, &got))
+			assert.GreaterOrEqual(t, got, before)
+		}
+		wg.Wait()
+	})
+}
@@ -7,15 +7,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 )
 
 // Request is a struct for a JSON-RPC request. It conforms to the JSON-RPC 2.0 specification except
-// that the ID field is allowed to be fractional.
+// that the ID field is allowed to be fractional. Decoded numeric IDs are an int64, a float64, or,
+// for numbers a float64 cannot hold exactly, a json.Number; a *big.Int may be sent as the ID too.
 type Request struct {
 	JSONRPC string `json:"jsonrpc"`
 	ID      any    `json:"id,omitempty"`
 	Method  string `json:"method"`
 	Params  any    `json:"params,omitempty"`
+
+	// codec decodes the params in place of the package codec, set by servers with a codec
+	codec Codec
 }
 
 // NewRequest creates a JSON-RPC 2.0 request with an auto-generated ID.
@@ -50,12 +55,21 @@ func NewNotification(method string, params any) *Request {
 // IDString returns the ID as a string.
 func (r *Request) IDString() string {
 	switch id := r.ID.(type) {
+	case ID:
+		if id.IsNull() {
+			return ""
+		}
+		return id.String()
 	case string:
 		return id
 	case int64:
 		return fmt.Sprintf("%d", id)
 	case float64:
 		return formatFloat64ID(id)
+	case json.Number:
+		return id.String()
+	case *big.Int:
+		return id.String()
 	default:
 		return ""
 	}
@@ -75,8 +89,12 @@ func (r *Request) IsEmpty() bool {
 	return false
 }
 
-// IsNotification returns true if this is a notification (no ID expected).
+// IsNotification returns true if this is a notification, that is a request without an ID. A
+// request whose ID is null, as decoded into NullID, is not a notification and is answered.
 func (r *Request) IsNotification() bool {
+	if id, ok := r.ID.(ID); ok {
+		return id.IsAbsent()
+	}
 	return r.ID == nil
 }
 
@@ -88,7 +106,18 @@ func (r *Request) MarshalJSON() ([]byte, error) {
 	}
 
 	type alias Request // Avoid infinite recursion by using an alias
-	return getSonicAPI().Marshal((*alias)(r))
+	if id, ok := r.ID.(ID); ok && id.IsAbsent() {
+		notification := *r
+		notification.ID = nil
+		return getCodec().Marshal((*alias)(&notification))
+	}
+	return getCodec().Marshal((*alias)(r))
+}
+
+// Reset clears the request, so that it can be reused, for instance from a sync.Pool, to decode
+// another request with UnmarshalJSON.
+func (r *Request) Reset() {
+	*r = Request{}
 }
 
 // String returns a string representation of the JSON-RPC request.
@@ -97,32 +126,27 @@ func (r *Request) String() string {
 	return fmt.Sprintf("ID: %v, Method: %s", r.ID, r.Method)
 }
 
-// Validate checks if the JSON-RPC request conforms to the JSON-RPC specification.
+// Validate checks if the JSON-RPC request conforms to the JSON-RPC specification. Method names
+// starting with "rpc." are valid in requests, as they name the methods built into servers, such
+// as rpc.discover; Server.Register rejects them.
 func (r *Request) Validate() error {
 	if r == nil {
 		return errors.New("request is nil")
 	}
 	if r.JSONRPC != jsonRPCVersion {
-		return errors.New("jsonrpc field is required to be exactly \"2.0\"")
+		return errors.New(errVersion)
 	}
 	if r.Method == "" {
-		return errors.New("method field is required")
-	}
-
-	// Check for reserved "rpc." prefix (JSON-RPC 2.0 spec)
-	if len(r.Method) >= 4 && r.Method[:4] == "rpc." {
-		return errors.New("method names starting with 'rpc.' are reserved by JSON-RPC 2.0 spec")
+		return errors.New(errMethodMissing)
 	}
 
 	switch r.ID.(type) {
-	case nil, string, int64, float64:
+	case nil, string, int64, float64, json.Number, *big.Int, ID:
 	default:
 		return errors.New("id field must be a string or a number")
 	}
-	switch r.Params.(type) {
-	case nil, []any, map[string]any:
-	default:
-		return errors.New("params field must be either an array, an object, or nil")
+	if !isValidParams(r.Params) {
+		return errors.New(errParamsType)
 	}
 
 	return nil
@@ -139,17 +163,17 @@ func (r *Request) UnmarshalJSON(data []byte) error {
 	}
 
 	var aux requestAux
-	if err := getSonicAPI().Unmarshal(data, &aux); err != nil {
+	if err := getCodec().Unmarshal(data, &aux); err != nil {
 		return err
 	}
 
 	if aux.JSONRPC != jsonRPCVersion {
-		return errors.New("jsonrpc field is required to be exactly \"2.0\"")
+		return invalidField(fieldJSONRPC, errVersion)
 	}
 	r.JSONRPC = aux.JSONRPC
 
 	if aux.Method == "" {
-		return errors.New("method field is required")
+		return invalidField(fieldMethod, errMethodMissing)
 	}
 	r.Method = aux.Method
 
@@ -170,15 +194,27 @@ func (r *Request) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// unmarshalRequestID unmarshals and normalizes the ID field from raw JSON.
+// unmarshalRequestID unmarshals the ID of a request like unmarshalRawID, but decodes null into
+// NullID, so that requests with a null ID are not taken for notifications.
 func unmarshalRequestID(rawID json.RawMessage) (any, error) {
+	if isJSONNull(rawID) {
+		return NullID(), nil
+	}
+	return unmarshalRawID(rawID)
+}
+
+// unmarshalRawID unmarshals and normalizes the ID field of a request or response from raw JSON.
+func unmarshalRawID(rawID json.RawMessage) (any, error) {
 	if len(rawID) == 0 {
 		return nil, nil
 	}
+	if id, ok := parseSimpleID(rawID); ok {
+		return id, nil
+	}
 
 	var id any
-	if err := getSonicAPI().Unmarshal(rawID, &id); err != nil {
-		return nil, fmt.Errorf("invalid id field: %w", err)
+	if err := getCodec().Unmarshal(rawID, &id); err != nil {
+		return nil, invalidField(fieldID, fmt.Sprintf("invalid id field: %v", err))
 	}
 
 	// If the value is "null", id will be nil
@@ -187,22 +223,55 @@ func unmarshalRequestID(rawID json.RawMessage) (any, error) {
 	}
 
 	switch v := id.(type) {
-	case float64:
-		// JSON numbers are unmarshalled as float64, so an explicit integer check is needed
-		if v != float64(int64(v)) {
-			return v, nil
-		}
-		return int64(v), nil
+	case float64, json.Number:
+		// Decode numbers from their literal, so that IDs a float64 cannot hold keep their precision
+		return numberValue(string(bytes.TrimSpace(rawID))), nil
 	case string:
 		if v == "" {
 			return nil, nil
 		}
 		return v, nil
 	default:
-		return nil, errors.New("id field must be a string or a number")
+		return nil, invalidField(fieldID, "id field must be a string or a number")
 	}
 }
 
+// parseSimpleID decodes the common forms of IDs, integers of up to maxSimpleIDDigits digits and
+// strings without escapes, without going through the generic decoder. It returns false for other
+// IDs.
+func parseSimpleID(raw []byte) (any, bool) {
+	if n := len(raw); n >= 2 && raw[0] == '"' && raw[n-1] == '"' {
+		str := raw[1 : n-1]
+		if bytes.IndexByte(str, '\\') >= 0 || bytes.IndexByte(str, '"') >= 0 {
+			return nil, false
+		}
+		if len(str) == 0 {
+			return nil, true
+		}
+		return string(str), true
+	}
+
+	digits := raw
+	negative := len(digits) > 0 && digits[0] == '-'
+	if negative {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || len(digits) > maxSimpleIDDigits || len(digits) > 1 && digits[0] == '0' {
+		return nil, false
+	}
+	var id int64
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, false
+		}
+		id = id*decimal + int64(c-'0')
+	}
+	if negative {
+		id = -id
+	}
+	return id, true
+}
+
 // unmarshalRequestParams unmarshals and validates the params field from raw JSON.
 func unmarshalRequestParams(rawParams json.RawMessage) (any, error) {
 	if len(rawParams) == 0 {
@@ -210,8 +279,8 @@ func unmarshalRequestParams(rawParams json.RawMessage) (any, error) {
 	}
 
 	var params any
-	if err := getSonicAPI().Unmarshal(rawParams, &params); err != nil {
-		return nil, fmt.Errorf("invalid params field: %w", err)
+	if err := getCodec().Unmarshal(rawParams, &params); err != nil {
+		return nil, invalidField(fieldParams, fmt.Sprintf("invalid params field: %v", err))
 	}
 
 	// Accept only arrays or objects.
@@ -221,11 +290,11 @@ func unmarshalRequestParams(rawParams json.RawMessage) (any, error) {
 	case string:
 		// Treat empty strings as nil
 		if params != "" {
-			return nil, errors.New("params field must be either an array, an object, or nil")
+			return nil, invalidField(fieldParams, errParamsType)
 		}
 		return nil, nil
 	default:
-		return nil, errors.New("params field must be either an array, an object, or nil")
+		return nil, invalidField(fieldParams, errParamsType)
 	}
 }
 
@@ -240,20 +309,34 @@ func (r *Request) UnmarshalParams(dst any) error {
 		return errors.New("request has no params field")
 	}
 
+	codec := r.paramsCodec()
+	if raw, ok := r.Params.(json.RawMessage); ok {
+		return codec.Unmarshal(raw, dst)
+	}
+
 	// Marshal params back to JSON, then unmarshal into destination
 	// This handles the conversion from any ([]any or map[string]any) to the target type
-	paramBytes, err := getSonicAPI().Marshal(r.Params)
+	paramBytes, err := codec.Marshal(r.Params)
 	if err != nil {
 		return fmt.Errorf("failed to marshal params: %w", err)
 	}
 
-	return getSonicAPI().Unmarshal(paramBytes, dst)
+	return codec.Unmarshal(paramBytes, dst)
+}
+
+// paramsCodec returns the codec decoding the params: that of the server handling the request,
+// or the package codec.
+func (r *Request) paramsCodec() Codec {
+	if r.codec != nil {
+		return r.codec
+	}
+	return getCodec()
 }
 
 // DecodeRequest parses a JSON-RPC request from a byte slice.
 func DecodeRequest(data []byte) (*Request, error) {
 	if len(bytes.TrimSpace(data)) == 0 {
-		return nil, errors.New(errEmptyData)
+		return nil, emptyMessage()
 	}
 	req := &Request{}
 	err := req.UnmarshalJSON(data)
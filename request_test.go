@@ -100,6 +100,21 @@ func TestRequest_MarshalJSON(t *testing.T) {
 				expected: `{"jsonrpc":"2.0","id":"abc","method":"eth_getBalance",` +
 					`"params":{"address":"0x123"}}`,
 			},
+			{
+				name: "With struct Params",
+				req: &Request{JSONRPC: "2.0", Method: "eth_getBalance",
+					Params: struct {
+						Address string `json:"address"`
+					}{Address: "0x123"}, ID: "abc"},
+				expected: `{"jsonrpc":"2.0","id":"abc","method":"eth_getBalance",` +
+					`"params":{"address":"0x123"}}`,
+			},
+			{
+				name: "With typed slice Params",
+				req: &Request{JSONRPC: "2.0", Method: "sum",
+					Params: []int{1, 2}, ID: int64(1)},
+				expected: `{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2]}`,
+			},
 		}
 
 		for _, tc := range cases {
@@ -136,6 +151,10 @@ func TestRequest_MarshalJSON(t *testing.T) {
 				name: "Invalid ID type",
 				req:  &Request{JSONRPC: "2.0", Method: "testMethod", ID: []int{1, 2, 3}},
 			},
+			{
+				name: "Scalar Params",
+				req:  &Request{JSONRPC: "2.0", Method: "testMethod", Params: "0x123"},
+			},
 		}
 
 		for _, tc := range cases {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"sync"
 
 	"github.com/bytedance/sonic/ast"
@@ -39,7 +40,7 @@ type Response struct {
 
 // NewResponse creates a JSON-RPC 2.0 response with a result.
 func NewResponse(id any, result any) (*Response, error) {
-	resultBytes, err := getSonicAPI().Marshal(result)
+	resultBytes, err := getCodec().Marshal(result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal result: %w", err)
 	}
@@ -47,7 +48,7 @@ func NewResponse(id any, result any) (*Response, error) {
 	// Pre-marshal the ID to cache it for later use
 	var rawID json.RawMessage
 	if id != nil {
-		idBytes, err := getSonicAPI().Marshal(id)
+		idBytes, err := getCodec().Marshal(id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal id: %w", err)
 		}
@@ -56,17 +57,30 @@ func NewResponse(id any, result any) (*Response, error) {
 
 	return &Response{
 		jsonrpc: jsonRPCVersion,
-		id:      id,
+		id:      responseID(id),
 		rawID:   rawID,
 		result:  resultBytes,
 	}, nil
 }
 
+// responseID returns the value a response holds for id, so that responses to requests whose ID
+// is an ID hold the same values as decoded responses. The encoding of id is kept as the raw ID.
+func responseID(id any) any {
+	typed, ok := id.(ID)
+	if !ok {
+		return id
+	}
+	if typed.IsAbsent() || typed.IsNull() {
+		return nil
+	}
+	return typed.Value()
+}
+
 // NewResponseFromRaw creates a JSON-RPC 2.0 response with a raw result.
 func NewResponseFromRaw(id any, rawResult json.RawMessage) (*Response, error) {
 	var rawID json.RawMessage
 	if id != nil {
-		idBytes, err := getSonicAPI().Marshal(id)
+		idBytes, err := getCodec().Marshal(id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal id: %w", err)
 		}
@@ -75,7 +89,7 @@ func NewResponseFromRaw(id any, rawResult json.RawMessage) (*Response, error) {
 
 	return &Response{
 		jsonrpc: jsonRPCVersion,
-		id:      id,
+		id:      responseID(id),
 		rawID:   rawID,
 		result:  rawResult,
 	}, nil
@@ -85,7 +99,7 @@ func NewResponseFromRaw(id any, rawResult json.RawMessage) (*Response, error) {
 func NewErrorResponse(id any, err *Error) *Response {
 	var rawID json.RawMessage
 	if id != nil {
-		idBytes, marshalErr := getSonicAPI().Marshal(id)
+		idBytes, marshalErr := getCodec().Marshal(id)
 		if marshalErr == nil {
 			rawID = idBytes
 		}
@@ -94,7 +108,7 @@ func NewErrorResponse(id any, err *Error) *Response {
 
 	return &Response{
 		jsonrpc: jsonRPCVersion,
-		id:      id,
+		id:      responseID(id),
 		rawID:   rawID,
 		err:     err,
 	}
@@ -141,6 +155,10 @@ func (r *Response) IDString() string {
 		return fmt.Sprintf("%d", id)
 	case float64:
 		return formatFloat64ID(id)
+	case json.Number:
+		return id.String()
+	case *big.Int:
+		return id.String()
 	default:
 		return ""
 	}
@@ -163,7 +181,7 @@ func (r *Response) Validate() error {
 
 	// Normalize int to int64 for consistency
 	switch v := r.id.(type) {
-	case nil, string, int64, float64:
+	case nil, string, int64, float64, json.Number, *big.Int:
 		// Already in correct format
 	case int:
 		// Convert platform-dependent int to int64
@@ -272,7 +290,7 @@ func isEmptyResult(result json.RawMessage) bool {
 // DecodeResponse parses and returns a new Response from a byte slice.
 func DecodeResponse(data []byte) (*Response, error) {
 	if len(bytes.TrimSpace(data)) == 0 {
-		return nil, errors.New(errEmptyData)
+		return nil, emptyMessage()
 	}
 
 	resp := &Response{}
@@ -15,59 +15,16 @@ type responseParseFormat struct {
 	Error   json.RawMessage `json:"error,omitempty"`
 }
 
-// responseMarshalFormat is the wire format for marshaling JSON-RPC responses.
-type responseMarshalFormat struct {
-	JSONRPC string          `json:"jsonrpc"`
-	ID      any             `json:"id"`
-	Error   *Error          `json:"error,omitempty"`
-	Result  json.RawMessage `json:"result,omitempty"`
-}
-
-// MarshalJSON serializes the Response into a JSON-RPC 2.0 compliant byte slice.
-//
-// The method prioritizes parsed fields (id, err) over their raw counterparts (rawID, rawError)
-// when both are present. If only raw fields exist, they are used directly to avoid unnecessary
-// re-marshaling.
+// MarshalJSON serializes the Response into a JSON-RPC 2.0 compliant byte slice, like
+// AppendJSON. Raw IDs and results are copied verbatim rather than re-encoded with the
+// codec, so that the bytes of a decoded response's result, or of one created with a raw result,
+// are preserved exactly. Note that encoding/json compacts and escapes the output of MarshalJSON
+// when marshaling a value holding a Response.
 func (r *Response) MarshalJSON() ([]byte, error) {
-	err := r.Validate()
-	if err != nil {
-		return nil, err
-	}
-
-	var id any
-	if len(r.rawID) > 0 {
-		id = r.rawID
-	} else if r.id != nil {
-		id = r.id
-	} else {
-		id = nil
-	}
-
-	if len(r.rawError) > 0 && r.err == nil {
-		r.err = &Error{}
-		if err := r.err.UnmarshalJSON(r.rawError); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal JSON-RPC error: %w", err)
-		}
-	}
-	errVal := r.err
-
-	var result json.RawMessage
-	if len(r.result) > 0 {
-		result = json.RawMessage(r.result)
-	}
-
-	output := responseMarshalFormat{
-		JSONRPC: r.jsonrpc,
-		ID:      id,
-		Error:   errVal,
-		Result:  result,
-	}
-
-	marshaled, err := getSonicAPI().Marshal(output)
+	marshaled, err := r.AppendJSON(make([]byte, 0, r.Size()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal JSON-RPC response: %w", err)
 	}
-
 	return marshaled, nil
 }
 
@@ -142,7 +99,7 @@ func (r *Response) UnmarshalResult(dst any) error {
 		return errors.New("response has no result field")
 	}
 
-	return getSonicAPI().Unmarshal(r.result, dst)
+	return getCodec().Unmarshal(r.result, dst)
 }
 
 // Unmarshal deserializes the entire Response into a custom struct.
@@ -159,7 +116,7 @@ func (r *Response) Unmarshal(dst any) error {
 		return err
 	}
 
-	return getSonicAPI().Unmarshal(data, dst)
+	return getCodec().Unmarshal(data, dst)
 }
 
 // UnmarshalError deserializes the raw error bytes into the err field of the Response.
@@ -181,12 +138,12 @@ func (r *Response) UnmarshalError() error {
 // allow for any unmarshalling to occur at the caller's discretion.
 func (r *Response) parseFromBytes(data []byte) error {
 	var aux responseParseFormat
-	if err := getSonicAPI().Unmarshal(data, &aux); err != nil {
+	if err := getCodec().Unmarshal(data, &aux); err != nil {
 		return err
 	}
 
 	if aux.JSONRPC != jsonRPCVersion {
-		return fmt.Errorf("invalid JSON-RPC version: %s", aux.JSONRPC)
+		return invalidField(fieldJSONRPC, "invalid JSON-RPC version: "+aux.JSONRPC)
 	}
 	r.jsonrpc = aux.JSONRPC
 
@@ -195,10 +152,10 @@ func (r *Response) parseFromBytes(data []byte) error {
 	errorExists := len(aux.Error) > 0
 
 	if !resultExists && !errorExists {
-		return errors.New("response must contain either result or error")
+		return unexpectedShape(noIndex, "response must contain either result or error")
 	}
 	if resultExists && errorExists {
-		return errors.New("response must not contain both result and error")
+		return unexpectedShape(noIndex, "response must not contain both result and error")
 	}
 
 	// Parse and unmarshal the ID field
@@ -241,35 +198,11 @@ func (r *Response) unmarshalID() error {
 		return nil
 	}
 
-	var id any
-	if err := getSonicAPI().Unmarshal(r.rawID, &id); err != nil {
-		return fmt.Errorf("invalid id field: %w", err)
-	}
-
-	// If the value is "null", id will be nil
-	if id == nil {
-		r.id = nil
-		return nil
-	}
-
-	switch v := id.(type) {
-	case float64:
-		// JSON numbers are unmarshalled as float64, so an explicit integer check is needed
-		if v != float64(int64(v)) {
-			r.id = v
-		} else {
-			r.id = int64(v)
-		}
-	case string:
-		if v == "" {
-			r.id = nil
-		} else {
-			r.id = v
-		}
-	default:
-		return errors.New("id field must be a string or a number")
+	id, err := unmarshalRawID(r.rawID)
+	if err != nil {
+		return err
 	}
-
+	r.id = id
 	return nil
 }
 
@@ -291,7 +224,7 @@ func (r *Response) getIDBytes() ([]byte, error) {
 		return r.rawID, nil
 	}
 	if r.id != nil {
-		return getSonicAPI().Marshal(r.id)
+		return getCodec().Marshal(r.id)
 	}
 	return []byte("null"), nil
 }
@@ -313,7 +246,7 @@ func (r *Response) writeErrorField(w io.Writer, total *int64) error {
 // getErrorBytes returns the marshaled error bytes
 func (r *Response) getErrorBytes() ([]byte, error) {
 	if r.err != nil {
-		return getSonicAPI().Marshal(r.err)
+		return getCodec().Marshal(r.err)
 	}
 	return r.rawError, nil
 }
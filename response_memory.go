@@ -88,7 +88,7 @@ func (r *Response) WithID(newID any) (*Response, error) {
 
 	var rawID json.RawMessage
 	if newID != nil {
-		rawBytes, err := getSonicAPI().Marshal(newID)
+		rawBytes, err := getCodec().Marshal(newID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal id: %w", err)
 		}
@@ -107,6 +107,13 @@ func (r *Response) WithID(newID any) (*Response, error) {
 	return clone, nil
 }
 
+// Reset clears the response, so that it can be reused, for instance from a sync.Pool, to decode
+// another response with UnmarshalJSON. Unlike Free, it clears every field. The response must not
+// be in use by other goroutines.
+func (r *Response) Reset() {
+	*r = Response{}
+}
+
 // Free releases memory-retaining fields. Only use after consuming the response.
 func (r *Response) Free() {
 	if r == nil {
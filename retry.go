@@ -0,0 +1,166 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Defaults applied to the zero fields of a RetryPolicy.
+const (
+	defaultInitialBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff        = 5 * time.Second
+	defaultBackoffMultiplier = 2
+	defaultBackoffJitter     = 0.2
+)
+
+// RetryPolicy configures the automatic retries enabled with WithRetry.
+//
+// A call is retried when sending it fails, except when the client is closed or the context is
+// done, or when it draws a JSON-RPC error whose code is listed in RetryCodes or whose ErrorData
+// is marked Retryable. HTTP errors are only retried for 5xx and 429 statuses. To keep
+// non-idempotent methods from ever running twice, only requests for which Idempotent returns true
+// are retried; with a nil Idempotent, nothing is.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first. Values below 2
+	// disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts. Defaults to 5s.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the wait grows after each retry. Defaults to 2.
+	Multiplier float64
+
+	// Jitter randomizes each wait by up to this fraction in either direction, so that clients
+	// failing together do not retry in lockstep. Defaults to 0.2; negative values disable it.
+	Jitter float64
+
+	// RetryCodes lists the JSON-RPC error codes worth retrying, such as rate limit or
+	// temporarily unavailable codes of the server.
+	RetryCodes []int
+
+	// Idempotent reports whether a request may safely be sent more than once.
+	Idempotent func(req *Request) bool
+}
+
+// IdempotentMethods returns a RetryPolicy.Idempotent function allowing retries of the named
+// methods only.
+func IdempotentMethods(methods ...string) func(req *Request) bool {
+	allowed := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		allowed[method] = struct{}{}
+	}
+	return func(req *Request) bool {
+		_, ok := allowed[req.Method]
+		return ok
+	}
+}
+
+// WithRetry retries failed calls and notifications according to policy. The retries run as an
+// interceptor placed after those added by earlier WithInterceptors options, so that earlier
+// interceptors see a single call and later ones every attempt. Batches are not retried.
+func WithRetry(policy RetryPolicy) ClientOption {
+	cfg := policy.withDefaults()
+	return WithInterceptors(cfg.interceptor)
+}
+
+// withDefaults returns a copy of the policy with its zero fields set to the defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	cfg := p
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = defaultBackoffMultiplier
+	}
+	if cfg.Jitter == 0 {
+		cfg.Jitter = defaultBackoffJitter
+	}
+	return cfg
+}
+
+// interceptor retries calls to next according to the policy.
+func (p RetryPolicy) interceptor(next Invoker) Invoker {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		if p.MaxAttempts < 2 || p.Idempotent == nil || !p.Idempotent(req) ||
+			req.Validate() != nil {
+			return next(ctx, req)
+		}
+
+		for attempt := 1; ; attempt++ {
+			resp, err := next(ctx, req)
+			if attempt >= p.MaxAttempts || !p.shouldRetry(ctx, resp, err) {
+				return resp, err
+			}
+
+			timer := time.NewTimer(p.backoff(attempt))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return resp, err
+			}
+		}
+	}
+}
+
+// shouldRetry reports whether an attempt's outcome is worth retrying.
+func (p RetryPolicy) shouldRetry(ctx context.Context, resp *Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return isRetryableError(err)
+	}
+	if resp == nil {
+		return false
+	}
+	rpcErr := resp.Err()
+	if rpcErr == nil {
+		return false
+	}
+	if data, ok := rpcErr.ErrorData(); ok && data.Retryable {
+		return true
+	}
+	return slices.Contains(p.RetryCodes, rpcErr.Code)
+}
+
+// backoff returns the wait after the given attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := float64(p.InitialBackoff)
+	for range attempt - 1 {
+		wait *= p.Multiplier
+		if wait >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	wait = min(wait, float64(p.MaxBackoff))
+	if p.Jitter > 0 {
+		// Spread uniformly over [wait*(1-jitter), wait*(1+jitter))
+		wait *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(wait)
+}
+
+// isRetryableError reports whether a failure to send a call may be transient.
+func isRetryableError(err error) bool {
+	if errors.Is(err, ErrClientClosed) || errors.Is(err, errDuplicateID) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError ||
+			httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyTransport fails with the given errors, in order, before answering with result.
+func flakyTransport(attempts *atomic.Int32, failures ...error) *funcTransport {
+	return &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+		n := int(attempts.Add(1))
+		if n <= len(failures) {
+			return nil, failures[n-1]
+		}
+		req, err := DecodeRequest(payload)
+		if err != nil {
+			return nil, err
+		}
+		resp, _ := NewResponse(req.ID, "ok")
+		return resp.MarshalJSON()
+	}}
+}
+
+// fastRetry returns a policy retrying every method with negligible backoff.
+func fastRetry(maxAttempts int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		Jitter:         -1,
+		Idempotent:     func(*Request) bool { return true },
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	errTransient := errors.New("connection reset")
+
+	t.Run("Retries transport errors", func(t *testing.T) {
+		var attempts atomic.Int32
+		transport := flakyTransport(&attempts, errTransient, errTransient)
+		client := NewClient(transport, WithRetry(fastRetry(3)))
+
+		var result string
+		require.NoError(t, client.Call(context.Background(), "get", nil, &result))
+		assert.Equal(t, "ok", result)
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		var attempts atomic.Int32
+		transport := flakyTransport(&attempts, errTransient, errTransient, errTransient)
+		client := NewClient(transport, WithRetry(fastRetry(2)))
+
+		assert.ErrorIs(t, client.Call(context.Background(), "get", nil, nil), errTransient)
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("Non-idempotent methods are not retried", func(t *testing.T) {
+		var attempts atomic.Int32
+		policy := fastRetry(3)
+		policy.Idempotent = IdempotentMethods("get")
+		client := NewClient(flakyTransport(&attempts, errTransient), WithRetry(policy))
+
+		assert.Error(t, client.Call(context.Background(), "transfer", nil, nil))
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("Nothing is retried without Idempotent", func(t *testing.T) {
+		var attempts atomic.Int32
+		policy := fastRetry(3)
+		policy.Idempotent = nil
+		client := NewClient(flakyTransport(&attempts, errTransient), WithRetry(policy))
+
+		assert.Error(t, client.Call(context.Background(), "get", nil, nil))
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("Selected JSON-RPC error codes", func(t *testing.T) {
+		var attempts atomic.Int32
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			attempts.Add(1)
+			req, _ := DecodeRequest(payload)
+			return NewErrorResponse(req.ID, &Error{Code: -32005, Message: "limited"}).MarshalJSON()
+		}}
+		policy := fastRetry(3)
+		policy.RetryCodes = []int{-32005}
+		client := NewClient(transport, WithRetry(policy))
+
+		assert.True(t, IsCode(client.Call(context.Background(), "get", nil, nil), -32005))
+		assert.Equal(t, int32(3), attempts.Load())
+
+		attempts.Store(0)
+		client = NewClient(transport, WithRetry(fastRetry(3)))
+		assert.Error(t, client.Call(context.Background(), "get", nil, nil))
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("Errors marked retryable", func(t *testing.T) {
+		var attempts atomic.Int32
+		transport := &funcTransport{fn: func(_ context.Context, payload []byte) ([]byte, error) {
+			req, _ := DecodeRequest(payload)
+			data := ErrorData{Retryable: attempts.Add(1) < 2}
+			return NewErrorResponse(req.ID, NewErrorData(-32050, data)).MarshalJSON()
+		}}
+		client := NewClient(transport, WithRetry(fastRetry(5)))
+
+		assert.True(t, IsCode(client.Call(context.Background(), "get", nil, nil), -32050))
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("HTTP status codes", func(t *testing.T) {
+		for status, want := range map[int]int32{400: 1, 429: 2, 503: 2} {
+			var attempts atomic.Int32
+			transport := flakyTransport(&attempts, &HTTPError{StatusCode: status})
+			client := NewClient(transport, WithRetry(fastRetry(2)))
+			_ = client.Call(context.Background(), "get", nil, nil)
+			assert.Equal(t, want, attempts.Load(), "status %d", status)
+		}
+	})
+
+	t.Run("Context cancellation stops retrying", func(t *testing.T) {
+		var attempts atomic.Int32
+		policy := fastRetry(5)
+		policy.InitialBackoff = time.Hour
+		client := NewClient(flakyTransport(&attempts, errTransient), WithRetry(policy))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, client.Call(ctx, "get", nil, nil), errTransient)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Run("Exponential and capped", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second,
+			Jitter: -1}.withDefaults()
+		assert.Equal(t, 100*time.Millisecond, p.backoff(1))
+		assert.Equal(t, 200*time.Millisecond, p.backoff(2))
+		assert.Equal(t, 800*time.Millisecond, p.backoff(4))
+		assert.Equal(t, time.Second, p.backoff(5))
+		assert.Equal(t, time.Second, p.backoff(50))
+	})
+
+	t.Run("Jitter stays in bounds", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.5}.withDefaults()
+		for range 100 {
+			wait := p.backoff(1)
+			assert.GreaterOrEqual(t, wait, 50*time.Millisecond)
+			assert.Less(t, wait, 150*time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// wildcard is the suffix of method patterns registered with Register, matching any name with the
+// pattern's prefix.
+const wildcard = "*"
+
+// WithCaseInsensitiveMethods matches the names of requested methods to the registered ones
+// regardless of case, for peers that are loose about it. A request matched this way is handled
+// under the registered name, so that handlers, middleware, and per-method options see that name;
+// registering two names differing only in case fails. Wildcard patterns stay case-sensitive.
+func WithCaseInsensitiveMethods() ServerOption {
+	return func(s *Server) {
+		s.foldCase = true
+	}
+}
+
+// wildcardRoute routes the methods with a prefix to a handler.
+type wildcardRoute struct {
+	prefix  string
+	handler Handler
+}
+
+// addWildcard adds a route for the methods matching pattern, keeping the routes ordered from the
+// longest prefix to the shortest, so that the most specific one matches first.
+func (r *registry) addWildcard(pattern string, handler Handler) {
+	route := wildcardRoute{prefix: strings.TrimSuffix(pattern, wildcard), handler: handler}
+	i, _ := slices.BinarySearchFunc(r.wildcards, route, func(a, b wildcardRoute) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+	r.wildcards = slices.Insert(r.wildcards, i, route)
+}
+
+// hasWildcard reports whether pattern is registered.
+func (r *registry) hasWildcard(pattern string) bool {
+	prefix := strings.TrimSuffix(pattern, wildcard)
+	return slices.ContainsFunc(r.wildcards, func(route wildcardRoute) bool {
+		return route.prefix == prefix
+	})
+}
+
+// checkRegistered returns an error if method, or a pattern, is already registered.
+func (r *registry) checkRegistered(method string) error {
+	if strings.HasSuffix(method, wildcard) {
+		if r.hasWildcard(method) {
+			return fmt.Errorf("method pattern %q is already registered", method)
+		}
+		return nil
+	}
+	if _, ok := r.methods[method]; ok {
+		return fmt.Errorf("method %q is already registered", method)
+	}
+	return nil
+}
+
+// checkFolded returns an error if a name differing from method only in case is registered, for
+// servers with WithCaseInsensitiveMethods.
+func (r *registry) checkFolded(method string) error {
+	if name, ok := r.folded[strings.ToLower(method)]; ok && !strings.HasSuffix(method, wildcard) {
+		return fmt.Errorf("method %q is already registered as %q", method, name)
+	}
+	return nil
+}
+
+// lookup returns the handler of method: the one registered under its name, or else the one of
+// the most specific wildcard pattern matching it.
+func (r *registry) lookup(method string) (Handler, bool) {
+	if handler, ok := r.methods[method]; ok {
+		return handler, true
+	}
+	for _, route := range r.wildcards {
+		if strings.HasPrefix(method, route.prefix) {
+			return route.handler, true
+		}
+	}
+	return nil, false
+}
+
+// canonicalRequest returns req, or with WithCaseInsensitiveMethods a copy of it named after the
+// registered method its name matches regardless of case.
+func (s *Server) canonicalRequest(req *Request) *Request {
+	if !s.foldCase {
+		return req
+	}
+	name, ok := s.registry.Load().folded[strings.ToLower(req.Method)]
+	if !ok || name == req.Method {
+		return req
+	}
+	canonical := *req
+	canonical.Method = name
+	return &canonical
+}
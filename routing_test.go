@@ -0,0 +1,79 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoMethod is a handler returning the name of the method it was called as.
+var echoMethod = HandlerFunc(func(_ context.Context, req *Request) (any, error) {
+	return req.Method, nil
+})
+
+func TestServer_Wildcards(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer()
+	require.NoError(t, srv.Register("*", HandlerFunc(func(context.Context, *Request) (any, error) {
+		return "upstream", nil
+	})))
+	require.NoError(t, srv.Register("wallet.*", echoMethod))
+	require.NoError(t, srv.Register("wallet.keys.*", HandlerFunc(
+		func(context.Context, *Request) (any, error) { return "keys", nil })))
+	balance := func(context.Context, *Request) (any, error) { return 42, nil }
+	require.NoError(t, srv.RegisterFunc("wallet.balance", balance))
+
+	call := func(method string) any {
+		resp := srv.HandleRequest(ctx, NewRequestWithID(method, nil, 1))
+		require.Nil(t, resp.Err())
+		var result any
+		require.NoError(t, resp.UnmarshalResult(&result))
+		return result
+	}
+
+	t.Run("Routes by the most specific match", func(t *testing.T) {
+		assert.EqualValues(t, 42, call("wallet.balance"))
+		assert.Equal(t, "wallet.sign", call("wallet.sign"))
+		assert.Equal(t, "keys", call("wallet.keys.list"))
+		assert.Equal(t, "upstream", call("eth_blockNumber"))
+	})
+
+	t.Run("Patterns are not methods", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"wallet.balance"}, srv.Methods())
+	})
+
+	t.Run("Rejects invalid patterns", func(t *testing.T) {
+		assert.ErrorContains(t, srv.Register("wallet.*", echoMethod), "already registered")
+		assert.ErrorContains(t, srv.Register("a*.b", echoMethod), "wildcard before its end")
+		assert.Error(t, srv.Register("rpc.*", echoMethod))
+	})
+}
+
+func TestServer_WithCaseInsensitiveMethods(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Matches regardless of case under the registered name", func(t *testing.T) {
+		srv := NewServer(WithCaseInsensitiveMethods())
+		require.NoError(t, srv.Register("eth_getBalance", echoMethod))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("ETH_GETBALANCE", nil, 1))
+		require.Nil(t, resp.Err())
+		var name string
+		require.NoError(t, resp.UnmarshalResult(&name))
+		assert.Equal(t, "eth_getBalance", name)
+
+		err := srv.Register("ETH_getBalance", echoMethod)
+		assert.ErrorContains(t, err, `already registered as "eth_getBalance"`)
+	})
+
+	t.Run("Names are case-sensitive by default", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("eth_getBalance", echoMethod))
+		require.NoError(t, srv.Register("ETH_GETBALANCE", echoMethod))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("Eth_GetBalance", nil, 1))
+		assert.ErrorIs(t, resp.Err(), ErrMethodNotFound)
+	})
+}
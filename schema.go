@@ -0,0 +1,61 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jkbrsn/jsonrpc/jsonschema"
+)
+
+// WithMethodSchema validates the params of the calls of method against schema before calling
+// its handler, after the middleware. Params violating it are answered with ErrInvalidParams
+// whose data lists the violations, as []jsonschema.Violation:
+//
+//	{"code": -32602, "message": "Invalid params", "data": [
+//		{"path": "/age", "keyword": "minimum", "message": "must be at least 0"}
+//	]}
+//
+// Absent params are validated as null, so a schema accepting calls without params allows the
+// type null. Violations are listed in a stable order: that of the members of the params as sent
+// with WithLazyParams, and by member name for params decoded eagerly, whose order is lost.
+func WithMethodSchema(method string, schema *jsonschema.Schema) ServerOption {
+	return func(s *Server) {
+		if s.schemas == nil {
+			s.schemas = make(map[string]*jsonschema.Schema)
+		}
+		s.schemas[method] = schema
+	}
+}
+
+// validateParams wraps next to first validate the params of the requests against schema.
+func validateParams(schema *jsonschema.Schema, next Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+		params, err := schemaInput(req.Params)
+		if err == nil {
+			err = schema.Validate(params)
+		}
+		var invalid *jsonschema.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			return nil, ErrInvalidParams.WithData(invalid.Violations)
+		case err != nil:
+			return nil, ErrInvalidParams.WithData(err.Error())
+		default:
+			return next.ServeRPC(ctx, req)
+		}
+	})
+}
+
+// schemaInput returns the encoded params to validate: raw params as they are, and decoded params
+// re-encoded with sorted member names, so that their violations are always listed alike.
+func schemaInput(params any) ([]byte, error) {
+	if raw, ok := params.(json.RawMessage); ok && len(raw) > 0 {
+		return raw, nil
+	}
+	data, err := getCodec().Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return CanonicalJSON(data)
+}
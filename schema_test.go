@@ -0,0 +1,92 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc/jsonschema"
+)
+
+func TestServer_MethodSchema(t *testing.T) {
+	ctx := context.Background()
+	schema := jsonschema.MustCompile(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`)
+	newServer := func(t *testing.T, opts ...ServerOption) (*Server, *int) {
+		srv := NewServer(
+			append([]ServerOption{WithMethodSchema("user.update", schema)}, opts...)...)
+		calls := new(int)
+		require.NoError(t, srv.RegisterFunc("user.update",
+			func(context.Context, *Request) (any, error) {
+				*calls++
+				return "ok", nil
+			}))
+		return srv, calls
+	}
+
+	t.Run("Valid params reach the handler", func(t *testing.T) {
+		srv, calls := newServer(t)
+		reply := srv.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","method":"user.update","params":{"name":"a","age":3},"id":1}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":"ok","id":1}`, string(reply))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("Violations are answered with Invalid Params", func(t *testing.T) {
+		srv, calls := newServer(t)
+		reply := srv.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","method":"user.update","params":{"age":-1,"x":1},"id":1}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params",
+			"data":[
+				{"path":"/age","keyword":"minimum","message":"must be at least 0"},
+				{"path":"/x","keyword":"additionalProperties",
+					"message":"is not an allowed property"},
+				{"path":"","keyword":"required","message":"must have the property \"name\""}
+			]}}`, string(reply))
+		assert.Zero(t, *calls)
+	})
+
+	t.Run("Absent and lazy params", func(t *testing.T) {
+		srv, calls := newServer(t, WithLazyParams())
+		resp := srv.HandleRequest(ctx, NewRequestWithID("user.update", nil, int64(1)))
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, InvalidParams, resp.Err().Code)
+		var violations []jsonschema.Violation
+		require.NoError(t, resp.Err().UnmarshalData(&violations))
+		assert.Equal(t, "type", violations[0].Keyword)
+
+		reply := srv.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","method":"user.update","params":{"name":"a"},"id":2}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":"ok","id":2}`, string(reply))
+		assert.Equal(t, 1, *calls)
+
+		reply = srv.HandleMessage(ctx, []byte(
+			`{"jsonrpc":"2.0","method":"user.update","params":{"x":1,"age":-1,"name":"a"},"id":3}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		require.NoError(t, resp.Err().UnmarshalData(&violations))
+		require.Len(t, violations, 2)
+		assert.Equal(t, "/x", violations[0].Path, "raw params keep their member order")
+		assert.Equal(t, "/age", violations[1].Path)
+	})
+
+	t.Run("Other methods are not validated", func(t *testing.T) {
+		srv, _ := newServer(t)
+		require.NoError(t, srv.RegisterFunc("echo",
+			func(_ context.Context, req *Request) (any, error) { return req.Params, nil }))
+		resp := srv.HandleRequest(ctx, NewRequestWithID("echo", []any{1}, int64(1)))
+		assert.Nil(t, resp.Err())
+
+		resp = srv.HandleRequest(ctx, NewRequestWithID("missing", nil, int64(2)))
+		assert.Equal(t, MethodNotFound, resp.Err().Code)
+	})
+}
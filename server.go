@@ -0,0 +1,536 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc/jsonschema"
+)
+
+// Handler responds to a JSON-RPC request. The returned result is marshaled into the response's
+// result field. A returned error becomes the response's error: an *Error (or an error wrapping
+// one) is sent as-is, any other error is reported as an internal error.
+//
+// For notifications the result and error are discarded, as no response is sent.
+type Handler interface {
+	ServeRPC(ctx context.Context, req *Request) (any, error)
+}
+
+// HandlerFunc adapts an ordinary function into a Handler.
+type HandlerFunc func(ctx context.Context, req *Request) (any, error)
+
+// ServeRPC calls f(ctx, req).
+func (f HandlerFunc) ServeRPC(ctx context.Context, req *Request) (any, error) {
+	return f(ctx, req)
+}
+
+// Middleware wraps a Handler with behavior that runs around every dispatched method, such as
+// logging, authentication, or metrics. It receives the next handler in the chain and returns the
+// handler to call in its place.
+type Middleware func(next Handler) Handler
+
+// Standard JSON-RPC 2.0 error messages.
+const (
+	msgParseError     = "Parse error"
+	msgInvalidRequest = "Invalid Request"
+	msgMethodNotFound = "Method not found"
+	msgInvalidParams  = "Invalid params"
+	msgInternalError  = "Internal error"
+)
+
+// internalErrorResponse is the encoded reply used when a response cannot be marshaled.
+var internalErrorResponse = []byte(
+	`{"jsonrpc":"2.0","id":null,"error":{"code":-32603,"message":"Internal error"}}`,
+)
+
+// Server dispatches JSON-RPC requests to registered handlers. It decodes and validates incoming
+// messages, routes them by method name, maps handler errors to JSON-RPC errors, and fans batches
+// out to concurrently executing handlers.
+//
+// A Server is safe for concurrent use, including registering methods while serving.
+type Server struct {
+	mu         sync.RWMutex
+	registry   atomic.Pointer[registry]
+	infos      map[string]MethodInfo
+	middleware []Middleware
+
+	cancelMethod   string
+	foldCase       bool
+	strict         bool
+	v1Compat       bool
+	lazyParams     bool
+	canonical      bool
+	rawPassthrough bool
+	orderedReplies bool
+	shape          ResponseShape
+	admin          *adminTracker
+	voidResult     any
+	batchPolicy    BatchPolicy
+	encodings      []Encoding
+	compression    *compressors
+	errors         *ErrorRegistry
+	observer       Observer
+	codec          Codec
+
+	authenticator Authenticator
+	authorizer    Authorizer
+
+	// Handler timeouts
+	timeout           time.Duration
+	methodTimeouts    map[string]time.Duration
+	propagateDeadline bool
+
+	// Concurrency limits
+	limiter          *limiter
+	methodLimiters   map[string]*limiter
+	methodPriorities map[string]Priority
+	pools            []*workerPool
+	limits           messageLimits
+
+	// Params schemas
+	schemas map[string]*jsonschema.Schema
+
+	// Rate limits
+	rateLimits       []RateLimit
+	methodRateLimits map[string][]RateLimit
+
+	// Connections served by ServeStream
+	connMu       sync.Mutex
+	conns        map[*Conn]struct{}
+	pushBuffer   int
+	pushOverflow OverflowPolicy
+	keepalive    *KeepalivePolicy
+	onDeadConn   func(conn *Conn, err error)
+
+	// Health reporting
+	health       bool
+	started      time.Time
+	inFlight     atomic.Int64
+	healthChecks map[string]HealthCheck
+
+	// Graceful shutdown
+	drain          drainer
+	shutdownMethod string
+
+	panicHandler func(ctx context.Context, req *Request, err *PanicError)
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// NewServer creates a Server with no registered methods.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{started: time.Now()}
+	s.registry.Store(newRegistry())
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds a handler for the given method name. It returns an error if the name is empty,
+// uses the reserved "rpc." prefix, or is already registered.
+//
+// A name ending in "*" is a wildcard pattern, routing to handler every method starting with the
+// rest of the name that has no handler of its own, such as "wallet.*" for the methods of a
+// namespace, or "*" alone for a catch-all forwarding unknown methods upstream. Where several
+// patterns match, the longest one wins.
+func (s *Server) Register(method string, handler Handler) error {
+	return s.registerAll(map[string]Handler{method: handler})
+}
+
+// registerAll adds all handlers, or none of them if any registration is invalid.
+func (s *Server) registerAll(handlers map[string]Handler) error {
+	return s.Update(func(u *RegistryUpdate) {
+		for method, handler := range handlers {
+			u.Register(method, handler)
+		}
+	})
+}
+
+// validateRegistration checks the name and handler of a method to register.
+func validateRegistration(method string, handler Handler) error {
+	if method == "" {
+		return errors.New("method name is required")
+	}
+	if strings.HasPrefix(method, reservedPrefix) {
+		return errors.New("method names starting with 'rpc.' are reserved by JSON-RPC 2.0 spec")
+	}
+	if strings.Contains(strings.TrimSuffix(method, wildcard), wildcard) {
+		return fmt.Errorf("method pattern %q has a wildcard before its end", method)
+	}
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	return nil
+}
+
+// Use appends middleware to the chain applied around every dispatched method, including methods
+// registered before the call and unknown methods, which reach the chain with a handler returning
+// a method not found error. Middleware runs in the order added: the first added is the outermost.
+func (s *Server) Use(middleware ...Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// RegisterFunc adds a handler function for the given method name. See Register.
+func (s *Server) RegisterFunc(
+	method string,
+	fn func(ctx context.Context, req *Request) (any, error),
+) error {
+	if fn == nil {
+		return errors.New("handler cannot be nil")
+	}
+	return s.Register(method, HandlerFunc(fn))
+}
+
+// Methods returns the names of all registered methods, in no particular order.
+func (s *Server) Methods() []string {
+	methods := s.registry.Load().methods
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	return names
+}
+
+// HandleRequest dispatches a single decoded request and returns its response. It returns nil for
+// notifications, unless their failures are answered as ResponseShape.NotificationErrors sets.
+func (s *Server) HandleRequest(ctx context.Context, req *Request) *Response {
+	s.drain.enter()
+	defer s.drain.leave()
+	if s.observer == nil {
+		resp, err := s.handleRequest(ctx, req)
+		return s.notificationError(req, resp, err)
+	}
+
+	start := time.Now()
+	s.observer.CallStarted(ctx, req)
+	resp, err := s.handleRequest(ctx, req)
+	resp = s.notificationError(req, resp, err)
+	s.observer.CallFinished(ctx, CallEvent{
+		Request:  req,
+		Response: resp,
+		Err:      err,
+		Duration: time.Since(start),
+	})
+	return resp
+}
+
+// handleRequest dispatches a single decoded request and returns its response, along with the
+// handler's error, which is also reported for notifications.
+func (s *Server) handleRequest(ctx context.Context, received *Request) (*Response, error) {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	if s.admin != nil {
+		defer s.admin.end(s.admin.begin(connID(ctx), received))
+	}
+	req := s.canonicalRequest(received)
+	if s.codec != nil {
+		coded := *req
+		coded.codec = s.codec
+		req = &coded
+	}
+	if s.isShuttingDown() {
+		if req.IsNotification() {
+			return nil, ErrShuttingDown
+		}
+		return NewErrorResponse(req.ID, ErrShuttingDown), ErrShuttingDown
+	}
+
+	reqCtx := ctx
+	if s.authenticator != nil || s.authorizer != nil {
+		admitCtx, rpcErr := s.admit(ctx, req)
+		if rpcErr != nil {
+			if req.IsNotification() {
+				return nil, rpcErr
+			}
+			return NewErrorResponse(req.ID, rpcErr), rpcErr
+		}
+		reqCtx = admitCtx
+	}
+
+	if s.isCancelRequest(req) {
+		cancelInflight(reqCtx, req)
+		return nil, nil
+	}
+	if s.cancelMethod != "" && !req.IsNotification() {
+		var done func()
+		reqCtx, done = trackInflight(reqCtx, req.ID)
+		defer done()
+	}
+
+	result, err := s.invokeWithDeadline(reqCtx, req)
+	if req.IsNotification() {
+		return nil, err
+	}
+	if err != nil {
+		return NewErrorResponse(req.ID, s.toError(err)), err
+	}
+
+	resp, marshalErr := s.newResponse(reqCtx, req, result)
+	var panicErr *PanicError
+	if errors.As(marshalErr, &panicErr) {
+		return NewErrorResponse(req.ID, s.toError(panicErr)), panicErr
+	}
+	if marshalErr != nil {
+		return NewErrorResponse(req.ID, &Error{
+			Code:    ServerSideException,
+			Message: msgInternalError,
+			Data:    marshalErr.Error(),
+		}), marshalErr
+	}
+	return resp, nil
+}
+
+// HandleMessage decodes an encoded request or batch, dispatches it, and returns the encoded
+// reply. It returns nil when no reply is due, i.e. when the message holds only notifications.
+//
+// Malformed input is answered per the JSON-RPC 2.0 specification: invalid JSON yields a parse
+// error, an empty batch an invalid request error, and each invalid batch member its own invalid
+// request error.
+func (s *Server) HandleMessage(ctx context.Context, data []byte) []byte {
+	reply := s.AppendMessage(ctx, nil, data)
+	if len(reply) == 0 {
+		return nil
+	}
+	return reply
+}
+
+// AppendMessage is like HandleMessage, but appends the encoded reply to dst and returns the
+// extended buffer, leaving dst as is when no reply is due. Transports that write the reply out
+// before handling the next message can reuse one buffer across messages.
+func (s *Server) AppendMessage(ctx context.Context, dst, data []byte) []byte {
+	reply := s.appendMessage(ctx, dst, data)
+	if s.canonical {
+		reply = canonicalReply(dst, reply)
+	}
+	if s.shape.rewrites() {
+		reply = s.shape.shapeReply(dst, reply)
+	}
+	return reply
+}
+
+// appendMessage dispatches the message data and appends the encoded reply to dst.
+func (s *Server) appendMessage(ctx context.Context, dst, data []byte) []byte {
+	s.drain.enter()
+	defer s.drain.leave()
+	if err := s.limits.checkMessage(len(data)); err != nil {
+		return appendResponse(dst, NewErrorResponse(nil, err))
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || !getCodec().Valid(trimmed) {
+		return appendResponse(dst, parseErrorResponse())
+	}
+
+	msgCtx := s.withPayload(ctx, trimmed)
+	if !isBatchJSON(trimmed) {
+		resp := s.handleRaw(msgCtx, trimmed)
+		if resp == nil {
+			return dst
+		}
+		return appendResponse(dst, resp)
+	}
+
+	var rawMessages []json.RawMessage
+	if err := getCodec().Unmarshal(trimmed, &rawMessages); err != nil || len(rawMessages) == 0 {
+		return appendResponse(dst, invalidRequestResponse())
+	}
+	if err := s.limits.checkBatch(len(rawMessages)); err != nil {
+		return appendResponse(dst, NewErrorResponse(nil, err))
+	}
+
+	if s.observer != nil {
+		s.observer.BatchStarted(ctx, len(rawMessages))
+	}
+	resps := s.handleBatch(msgCtx, rawMessages)
+	if len(resps) == 0 {
+		return dst
+	}
+	reply, err := AppendBatchResponse(dst, resps)
+	if err != nil {
+		return append(dst, internalErrorResponse...)
+	}
+	return reply
+}
+
+// handleBatch dispatches the batch members, concurrently unless the batch policy is sequential,
+// and returns the responses in the order of the requests, leaving out notifications.
+func (s *Server) handleBatch(ctx context.Context, rawMessages []json.RawMessage) []*Response {
+	results := make([]*Response, len(rawMessages))
+	workers := s.batchWorkers(len(rawMessages))
+	if s.batchPolicy.Execution == BatchSequential {
+		workers = 1
+	}
+	abort := s.batchPolicy.OnError == BatchAbort
+
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := int(next.Add(1) - 1); i < len(rawMessages); i = int(next.Add(1) - 1) {
+				if abort && failed.Load() {
+					results[i] = rejectResponse(rawMessages[i], ErrBatchAborted)
+					continue
+				}
+				results[i] = s.handleRaw(ctx, rawMessages[i])
+				if results[i] != nil && results[i].Err() != nil {
+					failed.Store(true)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	resps := make([]*Response, 0, len(results))
+	for _, resp := range results {
+		if resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	return resps
+}
+
+// handleRaw decodes and dispatches a single request message.
+func (s *Server) handleRaw(ctx context.Context, raw json.RawMessage) *Response {
+	if err := s.limits.checkParamsDepth(raw); err != nil {
+		return rejectResponse(raw, err)
+	}
+
+	msg := raw
+	if s.v1Compat {
+		normalized, err := NormalizeV1(msg)
+		if err != nil {
+			return invalidRequestResponse()
+		}
+		msg = normalized
+	}
+	if s.strict {
+		if err := validateStrictRequest(msg); err != nil {
+			return NewErrorResponse(nil, &Error{
+				Code:    InvalidRequest,
+				Message: msgInvalidRequest,
+				Data:    err.Error(),
+			})
+		}
+	}
+
+	decode := DecodeRequest
+	if s.lazyParams {
+		decode = DecodeRequestLazy
+	}
+	req, err := decode(msg)
+	if err != nil {
+		return invalidRequestResponse()
+	}
+	return s.shape.echo(raw, s.HandleRequest(ctx, req))
+}
+
+// methodNotFound is the handler invoked for unregistered methods.
+var methodNotFound = HandlerFunc(func(context.Context, *Request) (any, error) {
+	return nil, &Error{Code: MethodNotFound, Message: msgMethodNotFound}
+})
+
+// invoke looks up the handler for the request's method, wraps it in the middleware chain, and
+// calls it. A panicking handler is reported as a PanicError.
+func (s *Server) invoke(ctx context.Context, req *Request) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, s.recovered(ctx, req, p)
+		}
+	}()
+
+	handler, ok := s.registry.Load().lookup(req.Method)
+	s.mu.RLock()
+	middleware := s.middleware
+	s.mu.RUnlock()
+
+	if !ok {
+		handler = methodNotFound
+	} else if schema, validated := s.schemas[req.Method]; validated {
+		handler = validateParams(schema, handler)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler.ServeRPC(ctx, req)
+}
+
+// newResponse encodes the response carrying the result of req, reporting a panic of the result's
+// encoding as a PanicError.
+func (s *Server) newResponse(
+	ctx context.Context,
+	req *Request,
+	result any,
+) (resp *Response, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			resp, err = nil, s.recovered(ctx, req, p)
+		}
+	}()
+	if result == nil && s.voidResult != nil {
+		return NewResponse(req.ID, s.voidResult)
+	}
+	if raw, ok := rawResult(result); ok && s.rawPassthrough {
+		return NewResponseFromRaw(req.ID, raw)
+	}
+	if s.codec != nil {
+		data, err := s.codec.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+		return NewResponseFromRaw(req.ID, data)
+	}
+	return NewResponse(req.ID, result)
+}
+
+// toError converts a handler error into a JSON-RPC error.
+func (s *Server) toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		if rpcErr != nil {
+			return rpcErr
+		}
+	}
+	if s.errors != nil {
+		if mapped, ok := s.errors.ToError(err); ok {
+			return mapped
+		}
+	}
+	return &Error{Code: ServerSideException, Message: err.Error()}
+}
+
+// parseErrorResponse returns the response for a message that is not valid JSON.
+func parseErrorResponse() *Response {
+	return NewErrorResponse(nil, &Error{Code: ParseError, Message: msgParseError})
+}
+
+// invalidRequestResponse returns the response for a message that is not a valid request.
+func invalidRequestResponse() *Response {
+	return NewErrorResponse(nil, &Error{Code: InvalidRequest, Message: msgInvalidRequest})
+}
+
+// encodeResponse marshals a response, falling back to a generic internal error.
+func encodeResponse(resp *Response) []byte {
+	return appendResponse(nil, resp)
+}
+
+// appendResponse appends an encoded response to dst, or an internal error response if encoding
+// fails.
+func appendResponse(dst []byte, resp *Response) []byte {
+	out, err := resp.AppendJSON(dst)
+	if err != nil {
+		return append(dst, internalErrorResponse...)
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package jsonrpc
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/bytedance/sonic/ast"
+)
+
+// Bounds of the error code range the JSON-RPC 2.0 spec reserves for server errors.
+const (
+	minServerErrorCode = -32099
+	maxServerErrorCode = -32000
+)
+
+// ServeHTTP implements http.Handler, serving JSON-RPC over HTTP so that a Server can be mounted
+// on a mux directly:
+//
+//	mux.Handle("/rpc", srv)
+//
+// Requests must use POST with a JSON content type, and the body holds a single request or a
+// batch. Handlers receive the request headers and remote address through IncomingMetadata.
+// Status codes follow the JSON-RPC over HTTP conventions: 200 for replies, 204 when the
+// message held only notifications, and for single error replies 500 for parse and server errors,
+// 400 for invalid requests, 401 for unauthenticated and 403 for unauthorized requests, 404 for
+// unknown methods, 413 for exceeded size limits, 429 for exceeded rate limits, and 503 while
+// shutting down. Batch replies
+// always use 200. Requests in an encoding added with WithEncodings are answered in it, and
+// request bodies compressed with gzip or deflate are decompressed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.drain.enter()
+	defer s.drain.leave()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	enc := s.encodingFor(contentType)
+	if enc == nil && !isJSONContentType(contentType) {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err := s.limits.checkMessage(int(r.ContentLength)); err != nil {
+		// Declared too large, so rejected without reading
+		s.writeReply(w, r, encodeResponse(NewErrorResponse(nil, err)), enc)
+		return
+	}
+	body, err := s.readBody(r)
+	if errors.Is(err, errUnsupportedEncoding) {
+		http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if enc != nil {
+		if body, err = enc.Decode(body); err != nil {
+			s.writeReply(w, r, encodeResponse(parseErrorResponse()), enc)
+			return
+		}
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	reply := s.AppendMessage(httpContext(r), *buf, body)
+	*buf = reply
+	if len(reply) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeReply(w, r, reply, enc)
+}
+
+// writeReply writes an encoded reply with the HTTP status matching it, transcoded into enc
+// unless it is nil, and compressed if negotiated.
+func (s *Server) writeReply(w http.ResponseWriter, r *http.Request, reply []byte, enc Encoding) {
+	out, contentType := reply, contentTypeJSON
+	if enc != nil {
+		encoded, err := enc.Encode(reply)
+		if err != nil {
+			http.Error(w, "failed to encode reply", http.StatusInternalServerError)
+			return
+		}
+		out, contentType = encoded, enc.ContentType()
+	}
+	out = s.compression.compressReply(w, r, out)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(httpStatusFor(reply))
+	_, _ = w.Write(out)
+}
+
+// readBody reads the request body, decompressing it. Under a message size limit, reading stops
+// one byte past the limit, enough for AppendMessage to reject the message.
+func (s *Server) readBody(r *http.Request) ([]byte, error) {
+	body, err := decompress(r.Header.Get("Content-Encoding"), r.Body)
+	if err != nil {
+		return nil, err
+	}
+	expectedSize := int(r.ContentLength)
+	if body != r.Body {
+		// The length is that of the compressed body
+		expectedSize = 0
+	}
+
+	limit := s.limits.maxMessageSize
+	if limit == 0 {
+		return readAll(body, defaultChunkSize, expectedSize)
+	}
+	return readAll(io.LimitReader(body, int64(limit)+1), defaultChunkSize, expectedSize)
+}
+
+// isJSONContentType reports whether the Content-Type header value names a JSON-RPC media type.
+func isJSONContentType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case contentTypeJSON, "application/json-rpc", "application/jsonrequest":
+		return true
+	default:
+		return false
+	}
+}
+
+// httpStatusFor returns the HTTP status code for an encoded reply.
+func httpStatusFor(reply []byte) int {
+	if isBatchJSON(reply) {
+		return http.StatusOK
+	}
+
+	node, err := ast.NewSearcher(string(reply)).GetByPath("error", "code")
+	if err != nil {
+		return http.StatusOK
+	}
+	code, err := node.Int64()
+	if err != nil {
+		return http.StatusOK
+	}
+
+	switch {
+	case code == InvalidRequest:
+		return http.StatusBadRequest
+	case code == Unauthenticated:
+		return http.StatusUnauthorized
+	case code == PermissionDenied:
+		return http.StatusForbidden
+	case code == MethodNotFound:
+		return http.StatusNotFound
+	case code == LimitExceeded:
+		return http.StatusRequestEntityTooLarge
+	case code == RateLimited:
+		return http.StatusTooManyRequests
+	case code == ShuttingDown:
+		return http.StatusServiceUnavailable
+	case code == ParseError, code == InvalidParams, code == ServerSideException:
+		return http.StatusInternalServerError
+	case code >= minServerErrorCode && code <= maxServerErrorCode:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusOK
+	}
+}
@@ -0,0 +1,148 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_ServeHTTP(t *testing.T) {
+	srv := newTestServer(t)
+	boom := func(context.Context, *Request) (any, error) { panic("boom") }
+	require.NoError(t, srv.RegisterFunc("boom", boom))
+
+	// post sends body to srv and returns the recorded response
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		reply  string
+	}{
+		{
+			name:   "Result",
+			body:   `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			status: http.StatusOK,
+			reply:  `{"jsonrpc":"2.0","result":19,"id":1}`,
+		},
+		{
+			name:   "Notification",
+			body:   `{"jsonrpc":"2.0","method":"notify_hello","params":[7]}`,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Parse error",
+			body:   `{"jsonrpc":"2.0","method":"foobar`,
+			status: http.StatusInternalServerError,
+			reply:  `{"jsonrpc":"2.0","error":` + parseErrJSON + `,"id":null}`,
+		},
+		{
+			name:   "Invalid request",
+			body:   `{"jsonrpc":"2.0","method":1,"params":"bar"}`,
+			status: http.StatusBadRequest,
+			reply:  `{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null}`,
+		},
+		{
+			name:   "Method not found",
+			body:   `{"jsonrpc":"2.0","method":"foobar","id":"1"}`,
+			status: http.StatusNotFound,
+			reply:  `{"jsonrpc":"2.0","error":` + methodMissingJSON + `,"id":"1"}`,
+		},
+		{
+			name:   "Batch",
+			body:   `[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":"1"},{"foo":"boo"}]`,
+			status: http.StatusOK,
+			reply: `[{"jsonrpc":"2.0","result":3,"id":"1"},` +
+				`{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post("application/json", tt.body)
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.reply == "" {
+				assert.Empty(t, rec.Body.String())
+				return
+			}
+			assert.Equal(t, contentTypeJSON, rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.reply, rec.Body.String())
+		})
+	}
+
+	t.Run("Panic is an internal error", func(t *testing.T) {
+		rec := post("application/json", `{"jsonrpc":"2.0","method":"boom","id":2}`)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		resp, err := DecodeResponse(rec.Body.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, ServerSideException, resp.Err().Code)
+		assert.Equal(t, "Internal error", resp.Err().Message)
+		assert.Contains(t, resp.Err().Data, CorrelationIDKey)
+	})
+
+	t.Run("Content type parameters are accepted", func(t *testing.T) {
+		body := `{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`
+		rec := post("application/json; charset=utf-8", body)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		rec := post("text/plain", `{"jsonrpc":"2.0","method":"sum","id":1}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+		rec = post("", `{"jsonrpc":"2.0","method":"sum","id":1}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("Only POST is allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rpc", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+	})
+
+	t.Run("With HTTP transport", func(t *testing.T) {
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		client := NewClient(NewHTTPTransport(ts.URL))
+		defer client.Close()
+
+		var result int
+		require.NoError(t, client.Call(context.Background(), "sum", []any{1, 2, 3}, &result))
+		assert.Equal(t, 6, result)
+
+		err := client.Call(context.Background(), "missing", nil, nil)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+
+		require.NoError(t, client.Notify(context.Background(), "notify_hello", nil))
+	})
+}
+
+func TestHTTPStatusFor(t *testing.T) {
+	status := func(code int) int {
+		reply := encodeResponse(NewErrorResponse(int64(1), &Error{Code: code, Message: "x"}))
+		return httpStatusFor(reply)
+	}
+	assert.Equal(t, http.StatusInternalServerError, status(InvalidParams))
+	assert.Equal(t, http.StatusInternalServerError, status(-32000))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status(LimitExceeded))
+	assert.Equal(t, http.StatusTooManyRequests, status(RateLimited))
+	assert.Equal(t, http.StatusUnauthorized, status(Unauthenticated))
+	assert.Equal(t, http.StatusForbidden, status(PermissionDenied))
+	assert.Equal(t, http.StatusOK, status(1234))
+}
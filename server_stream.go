@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// clientContextKey is the context key under which a stream's Client is stored.
+type clientContextKey struct{}
+
+// emptyServer answers inbound requests on stream clients configured without a server.
+var emptyServer = NewServer()
+
+// ServeStream serves requests arriving on a persistent stream until reading from the stream fails
+// or ctx is done, and then closes the stream. Requests are handled concurrently.
+//
+// Handlers can issue calls and notifications back to the remote peer over the same stream with
+// the Client returned by ClientFromContext. While served, the stream is also listed by Conns and
+// reached by Broadcast, and handlers can keep the Conn returned by ConnFromContext to push
+// notifications later.
+//
+// ServeStream returns nil when the peer closes the stream, ctx.Err() when ctx is done,
+// ErrServerClosed once Shutdown is called, ErrKicked once the stream is kicked through the admin
+// methods of WithAdmin, and the read error otherwise.
+func (s *Server) ServeStream(ctx context.Context, stream Stream) error {
+	if s.isShuttingDown() {
+		_ = stream.Close()
+		return ErrServerClosed
+	}
+	peer := s.newPeer(ctx, stream)
+
+	select {
+	case <-ctx.Done():
+		_ = peer.Close()
+		<-peer.ended
+		return ctx.Err()
+	case <-peer.ended:
+		cause := peer.terminalErr()
+		if errors.Is(cause, io.EOF) {
+			return nil
+		}
+		return cause
+	}
+}
+
+// ClientFromContext returns the Client for the stream on which the current request arrived, which
+// can be used to call back to the remote peer. It returns false outside handlers invoked for
+// stream requests.
+func ClientFromContext(ctx context.Context) (*Client, bool) {
+	client, ok := ctx.Value(clientContextKey{}).(*Client)
+	return client, ok
+}
+
+// contextWithClient returns a copy of ctx carrying the given client.
+func contextWithClient(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
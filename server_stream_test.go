@@ -0,0 +1,124 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_ServeStream(t *testing.T) {
+	t.Run("Bidirectional calls", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+
+		srv := NewServer()
+		greet := func(ctx context.Context, _ *Request) (any, error) {
+			peer, ok := ClientFromContext(ctx)
+			if !ok {
+				return nil, &Error{Code: ServerSideException, Message: "no peer"}
+			}
+			var name string
+			if err := peer.Call(ctx, "client.name", nil, &name); err != nil {
+				return nil, err
+			}
+			return "hello " + name, nil
+		}
+		require.NoError(t, srv.RegisterFunc("greet", greet))
+
+		clientSrv := NewServer()
+		name := func(_ context.Context, _ *Request) (any, error) { return "ann", nil }
+		require.NoError(t, clientSrv.RegisterFunc("client.name", name))
+
+		serveErr := make(chan error, 1)
+		go func() { serveErr <- srv.ServeStream(context.Background(), serverEnd) }()
+
+		client := NewStreamClient(clientEnd, WithServer(clientSrv))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var greeting string
+				assert.NoError(t, client.Call(context.Background(), "greet", nil, &greeting))
+				assert.Equal(t, "hello ann", greeting)
+			}()
+		}
+		wg.Wait()
+
+		require.NoError(t, client.Close())
+		select {
+		case err := <-serveErr:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("ServeStream did not return after peer closed")
+		}
+	})
+
+	t.Run("Inbound requests without a server get method not found", func(t *testing.T) {
+		clientEnd, serverEnd := newStreamPair()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		req := `{"jsonrpc":"2.0","id":"srv-1","method":"client.anything"}`
+		require.NoError(t, serverEnd.WriteMessage(context.Background(), []byte(req)))
+
+		reply, err := serverEnd.ReadMessage(context.Background())
+		require.NoError(t, err)
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Equal(t, "srv-1", resp.IDOrNil())
+		assert.Equal(t, MethodNotFound, resp.Err().Code)
+	})
+
+	t.Run("Context cancellation stops serving", func(t *testing.T) {
+		_, serverEnd := newStreamPair()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		serveErr := make(chan error, 1)
+		go func() { serveErr <- NewServer().ServeStream(ctx, serverEnd) }()
+		cancel()
+
+		select {
+		case err := <-serveErr:
+			require.ErrorIs(t, err, context.Canceled)
+		case <-time.After(time.Second):
+			t.Fatal("ServeStream did not return after cancellation")
+		}
+	})
+
+	t.Run("Handler context derives from serve context", func(t *testing.T) {
+		type key struct{}
+		clientEnd, serverEnd := newStreamPair()
+
+		srv := NewServer()
+		value := func(ctx context.Context, _ *Request) (any, error) { return ctx.Value(key{}), nil }
+		require.NoError(t, srv.RegisterFunc("value", value))
+
+		ctx := context.WithValue(context.Background(), key{}, "from-serve")
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		var got string
+		require.NoError(t, client.Call(context.Background(), "value", nil, &got))
+		assert.Equal(t, "from-serve", got)
+	})
+}
+
+func TestClientFromContext(t *testing.T) {
+	_, ok := ClientFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestIsRequestMessage(t *testing.T) {
+	assert.True(t, isRequestMessage([]byte(`{"jsonrpc":"2.0","method":"a","id":1}`)))
+	assert.True(t, isRequestMessage([]byte(`[{"jsonrpc":"2.0","method":"a"}]`)))
+	assert.False(t, isRequestMessage([]byte(`{"jsonrpc":"2.0","result":1,"id":1}`)))
+	assert.False(t, isRequestMessage([]byte(`[{"jsonrpc":"2.0","result":1,"id":1}]`)))
+	assert.False(t, isRequestMessage([]byte(`not json`)))
+}
@@ -0,0 +1,295 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Encoded standard errors, for expected replies.
+const (
+	parseErrJSON      = `{"code":-32700,"message":"Parse error"}`
+	invalidReqJSON    = `{"code":-32600,"message":"Invalid Request"}`
+	methodMissingJSON = `{"code":-32601,"message":"Method not found"}`
+)
+
+// newTestServer returns a server with the methods used by the JSON-RPC 2.0 spec examples.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv := NewServer()
+	subtract := func(_ context.Context, req *Request) (any, error) {
+		var params []int
+		if err := req.UnmarshalParams(&params); err != nil || len(params) != 2 {
+			return nil, &Error{Code: InvalidParams, Message: "Invalid params"}
+		}
+		return params[0] - params[1], nil
+	}
+	sum := func(_ context.Context, req *Request) (any, error) {
+		var params []int
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		total := 0
+		for _, p := range params {
+			total += p
+		}
+		return total, nil
+	}
+	noop := func(_ context.Context, _ *Request) (any, error) { return nil, nil }
+	fail := func(_ context.Context, _ *Request) (any, error) {
+		return nil, errors.New("something broke")
+	}
+
+	require.NoError(t, srv.RegisterFunc("subtract", subtract))
+	require.NoError(t, srv.RegisterFunc("sum", sum))
+	require.NoError(t, srv.RegisterFunc("notify_hello", noop))
+	require.NoError(t, srv.RegisterFunc("fail", fail))
+	return srv
+}
+
+func TestServer_Register(t *testing.T) {
+	handler := HandlerFunc(func(_ context.Context, _ *Request) (any, error) { return nil, nil })
+
+	t.Run("Valid registration", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("user.get", handler))
+		assert.Equal(t, []string{"user.get"}, srv.Methods())
+	})
+
+	t.Run("Invalid registrations", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Register("dup", handler))
+
+		cases := []struct {
+			name    string
+			method  string
+			handler Handler
+			errMsg  string
+		}{
+			{"Empty name", "", handler, "required"},
+			{"Reserved prefix", "rpc.discover", handler, "reserved"},
+			{"Nil handler", "x", nil, "nil"},
+			{"Duplicate", "dup", handler, "already registered"},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := srv.Register(tc.method, tc.handler)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+
+	t.Run("Nil func", func(t *testing.T) {
+		require.Error(t, NewServer().RegisterFunc("x", nil))
+	})
+}
+
+func TestServer_HandleMessage(t *testing.T) {
+	srv := newTestServer(t)
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Positional params",
+			input:    `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			expected: `{"jsonrpc":"2.0","result":19,"id":1}`,
+		},
+		{
+			name:     "Non-existent method",
+			input:    `{"jsonrpc":"2.0","method":"foobar","id":"1"}`,
+			expected: `{"jsonrpc":"2.0","error":` + methodMissingJSON + `,"id":"1"}`,
+		},
+		{
+			name:     "Invalid JSON",
+			input:    `{"jsonrpc":"2.0","method":"foobar,"params":"bar","baz]`,
+			expected: `{"jsonrpc":"2.0","error":` + parseErrJSON + `,"id":null}`,
+		},
+		{
+			name:     "Invalid request object",
+			input:    `{"jsonrpc":"2.0","method":1,"params":"bar"}`,
+			expected: `{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null}`,
+		},
+		{
+			name: "Batch with invalid JSON",
+			input: `[{"jsonrpc":"2.0","method":"sum","params":[1,2,4],"id":"1"},` +
+				`{"jsonrpc":"2.0","method"]`,
+			expected: `{"jsonrpc":"2.0","error":` + parseErrJSON + `,"id":null}`,
+		},
+		{
+			name:     "Empty batch",
+			input:    `[]`,
+			expected: `{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null}`,
+		},
+		{
+			name:  "Invalid batch of non-objects",
+			input: `[1,2]`,
+			expected: `[{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null},` +
+				`{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null}]`,
+		},
+		{
+			name: "Mixed batch",
+			input: `[{"jsonrpc":"2.0","method":"sum","params":[1,2,4],"id":"1"},` +
+				`{"jsonrpc":"2.0","method":"notify_hello","params":[7]},` +
+				`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":"2"},` +
+				`{"foo":"boo"},` +
+				`{"jsonrpc":"2.0","method":"foo.get","params":{"name":"myself"},"id":"5"}]`,
+			expected: `[{"jsonrpc":"2.0","result":7,"id":"1"},` +
+				`{"jsonrpc":"2.0","result":19,"id":"2"},` +
+				`{"jsonrpc":"2.0","error":` + invalidReqJSON + `,"id":null},` +
+				`{"jsonrpc":"2.0","error":` + methodMissingJSON + `,"id":"5"}]`,
+		},
+		{
+			name:  "Plain errors become internal errors",
+			input: `{"jsonrpc":"2.0","method":"fail","id":3}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"something broke"},` +
+				`"id":3}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := srv.HandleMessage(context.Background(), []byte(tc.input))
+			assert.JSONEq(t, tc.expected, string(reply))
+		})
+	}
+
+	t.Run("Notifications get no reply", func(t *testing.T) {
+		inputs := []string{
+			`{"jsonrpc":"2.0","method":"notify_hello","params":[7]}`,
+			`{"jsonrpc":"2.0","method":"unknown"}`,
+			`[{"jsonrpc":"2.0","method":"notify_hello"},{"jsonrpc":"2.0","method":"fail"}]`,
+		}
+		for _, input := range inputs {
+			assert.Nil(t, srv.HandleMessage(context.Background(), []byte(input)), input)
+		}
+	})
+}
+
+func TestServer_HandleRequest(t *testing.T) {
+	srv := NewServer()
+	var calls atomic.Int32
+	count := func(_ context.Context, _ *Request) (any, error) {
+		calls.Add(1)
+		return map[string]any{"ok": true}, nil
+	}
+	wrapped := func(_ context.Context, _ *Request) (any, error) {
+		return nil, errors.Join(errors.New("context"), &Error{Code: InvalidParams, Message: "bad"})
+	}
+	unmarshalable := func(_ context.Context, _ *Request) (any, error) {
+		return make(chan int), nil
+	}
+	require.NoError(t, srv.RegisterFunc("count", count))
+	require.NoError(t, srv.RegisterFunc("wrapped", wrapped))
+	require.NoError(t, srv.RegisterFunc("unmarshalable", unmarshalable))
+
+	t.Run("Result response", func(t *testing.T) {
+		resp := srv.HandleRequest(context.Background(), NewRequestWithID("count", nil, int64(1)))
+		require.NotNil(t, resp)
+		assert.JSONEq(t, `{"ok":true}`, string(resp.RawResult()))
+	})
+
+	t.Run("Notification runs handler but returns nil", func(t *testing.T) {
+		before := calls.Load()
+		assert.Nil(t, srv.HandleRequest(context.Background(), NewNotification("count", nil)))
+		assert.Equal(t, before+1, calls.Load())
+	})
+
+	t.Run("Wrapped *Error is preserved", func(t *testing.T) {
+		req := NewRequestWithID("wrapped", nil, int64(2))
+		resp := srv.HandleRequest(context.Background(), req)
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, InvalidParams, resp.Err().Code)
+	})
+
+	t.Run("Unmarshalable result is an internal error", func(t *testing.T) {
+		req := NewRequestWithID("unmarshalable", nil, "x")
+		resp := srv.HandleRequest(context.Background(), req)
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, ServerSideException, resp.Err().Code)
+	})
+}
+
+func TestServer_Use(t *testing.T) {
+	// tag returns middleware recording its name before and after calling next
+	tag := func(name string, trace *[]string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, req *Request) (any, error) {
+				*trace = append(*trace, name+">")
+				result, err := next.ServeRPC(ctx, req)
+				*trace = append(*trace, "<"+name)
+				return result, err
+			})
+		}
+	}
+
+	t.Run("Runs in order around the handler", func(t *testing.T) {
+		var trace []string
+		srv := NewServer()
+		handler := func(_ context.Context, _ *Request) (any, error) {
+			trace = append(trace, "handler")
+			return "ok", nil
+		}
+		require.NoError(t, srv.RegisterFunc("m", handler))
+		srv.Use(tag("a", &trace))
+		srv.Use(tag("b", &trace))
+
+		resp := srv.HandleRequest(context.Background(), NewRequestWithID("m", nil, int64(1)))
+		require.NotNil(t, resp)
+		assert.Nil(t, resp.Err())
+		assert.Equal(t, []string{"a>", "b>", "handler", "<b", "<a"}, trace)
+	})
+
+	t.Run("Wraps unknown methods", func(t *testing.T) {
+		var trace []string
+		srv := NewServer()
+		srv.Use(tag("a", &trace))
+
+		resp := srv.HandleRequest(context.Background(), NewRequestWithID("missing", nil, int64(1)))
+		assert.Equal(t, MethodNotFound, resp.Err().Code)
+		assert.Equal(t, []string{"a>", "<a"}, trace)
+	})
+
+	t.Run("Can short-circuit", func(t *testing.T) {
+		srv := newTestServer(t)
+		deny := func(Handler) Handler {
+			return HandlerFunc(func(context.Context, *Request) (any, error) {
+				return nil, &Error{Code: -32001, Message: "Unauthorized"}
+			})
+		}
+		srv.Use(deny)
+
+		reply := srv.HandleMessage(context.Background(),
+			[]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))
+		expected := `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"Unauthorized"}}`
+		assert.JSONEq(t, expected, string(reply))
+	})
+}
+
+func TestServer_WithClient(t *testing.T) {
+	srv := newTestServer(t)
+	transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}}
+	client := NewClient(transport)
+
+	var result int
+	require.NoError(t, client.Call(context.Background(), "subtract", []any{5, 3}, &result))
+	assert.Equal(t, 2, result)
+
+	resps, err := client.CallBatch(context.Background(), []*Request{
+		NewRequestWithID("sum", []any{1, 2}, int64(10)),
+		NewRequestWithID("missing", nil, int64(11)),
+	})
+	require.NoError(t, err)
+	require.Len(t, resps, 2)
+	assert.Nil(t, resps[0].Err())
+	assert.Equal(t, MethodNotFound, resps[1].Err().Code)
+}
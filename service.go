@@ -0,0 +1,213 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// serviceMethod is a Handler invoking an exported method of a registered service through
+// reflection.
+type serviceMethod struct {
+	receiver  reflect.Value
+	fn        reflect.Method
+	hasCtx    bool
+	argTypes  []reflect.Type
+	hasResult bool
+	hasError  bool
+}
+
+// RegisterService exposes the exported methods of receiver as JSON-RPC methods named
+// "namespace.methodName", where methodName is the Go method name with its first letter lowered.
+// With an empty namespace the methods are registered under methodName alone.
+//
+// A method is exposed when its signature has the form
+//
+//	func (t *T) Name([ctx context.Context,] [arg1 A1, arg2 A2, ...]) [(R,)] [error]
+//
+// that is, an optional leading context, any number of arguments, and at most one result
+// optionally followed by an error. Other exported methods are skipped.
+//
+// Params are bound to the arguments as follows: a params array is bound positionally, with
+// missing trailing arguments left at their zero value; a params object is unmarshaled into the
+// single argument of a one-argument method; absent params leave all arguments at their zero value.
+// Params that cannot be bound are answered with an invalid params error.
+func (s *Server) RegisterService(namespace string, receiver any) error {
+	if receiver == nil {
+		return errors.New("service receiver cannot be nil")
+	}
+
+	rv := reflect.ValueOf(receiver)
+	rt := rv.Type()
+
+	handlers := make(map[string]Handler)
+	for i := range rt.NumMethod() {
+		method := rt.Method(i)
+		sm, ok := newServiceMethod(rv, method)
+		if !ok {
+			continue
+		}
+		name := lowerFirst(method.Name)
+		if namespace != "" {
+			name = namespace + "." + name
+		}
+		handlers[name] = sm
+	}
+
+	if len(handlers) == 0 {
+		return fmt.Errorf("type %s has no exported methods of suitable signature", rt)
+	}
+
+	return s.registerAll(handlers)
+}
+
+// newServiceMethod inspects a method's signature and returns a handler for it, or false if the
+// signature is unsuitable.
+func newServiceMethod(receiver reflect.Value, method reflect.Method) (*serviceMethod, bool) {
+	if !method.IsExported() {
+		return nil, false
+	}
+	mt := method.Type
+	sm := &serviceMethod{receiver: receiver, fn: method}
+
+	// Input 0 is the receiver
+	first := 1
+	if mt.NumIn() > first && mt.In(first) == contextType {
+		sm.hasCtx = true
+		first++
+	}
+	for i := first; i < mt.NumIn(); i++ {
+		sm.argTypes = append(sm.argTypes, mt.In(i))
+	}
+
+	switch mt.NumOut() {
+	case 0:
+	case 1:
+		if mt.Out(0) == errorType {
+			sm.hasError = true
+		} else {
+			sm.hasResult = true
+		}
+	case 2:
+		if mt.Out(0) == errorType || mt.Out(1) != errorType {
+			return nil, false
+		}
+		sm.hasResult = true
+		sm.hasError = true
+	default:
+		return nil, false
+	}
+
+	return sm, true
+}
+
+// ServeRPC binds the request params to the method arguments, calls the method, and returns its
+// result and error.
+func (m *serviceMethod) ServeRPC(ctx context.Context, req *Request) (any, error) {
+	args, err := m.bindArgs(req.paramsCodec(), req.Params)
+	if err == nil {
+		err = validateArgs(args)
+	}
+	if err != nil {
+		return nil, &Error{Code: InvalidParams, Message: msgInvalidParams, Data: err.Error()}
+	}
+
+	in := make([]reflect.Value, 0, len(args)+2)
+	in = append(in, m.receiver)
+	if m.hasCtx {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	in = append(in, args...)
+
+	out := m.fn.Func.Call(in)
+
+	var result any
+	if m.hasResult {
+		result = out[0].Interface()
+	}
+	if m.hasError {
+		if err, ok := out[len(out)-1].Interface().(error); ok && err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// bindArgs converts decoded params into argument values for the method.
+func (m *serviceMethod) bindArgs(codec Codec, params any) ([]reflect.Value, error) {
+	args := make([]reflect.Value, len(m.argTypes))
+	for i, typ := range m.argTypes {
+		args[i] = reflect.New(typ).Elem()
+	}
+
+	expanded, err := expandRawParams(params)
+	if err != nil {
+		return nil, err
+	}
+	switch p := expanded.(type) {
+	case nil:
+		return args, nil
+	case []any:
+		if len(p) > len(m.argTypes) {
+			return nil, fmt.Errorf("too many params: got %d, want at most %d", len(p), len(args))
+		}
+		for i, value := range p {
+			if err := convertValue(codec, value, args[i]); err != nil {
+				return nil, fmt.Errorf("invalid param at index %d: %w", i, err)
+			}
+		}
+		return args, nil
+	case map[string]any, json.RawMessage:
+		if len(m.argTypes) != 1 {
+			return nil, fmt.Errorf("named params require exactly one argument, method takes %d",
+				len(m.argTypes))
+		}
+		if err := convertValue(codec, p, args[0]); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+		return args, nil
+	default:
+		return nil, errors.New("params must be an array or an object")
+	}
+}
+
+// validateArgs checks the bound arguments against the validation rules of their struct tags.
+func validateArgs(args []reflect.Value) error {
+	for _, arg := range args {
+		if err := validateValue(arg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertValue stores a decoded JSON value into dst, which must be settable, by re-encoding it
+// with codec. Raw JSON is decoded directly.
+func convertValue(codec Codec, value any, dst reflect.Value) error {
+	if raw, ok := value.(json.RawMessage); ok {
+		return codec.Unmarshal(raw, dst.Addr().Interface())
+	}
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, dst.Addr().Interface())
+}
+
+// lowerFirst returns s with its first rune in lower case.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,171 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userArgs struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type userService struct {
+	lastCtx context.Context
+}
+
+func (s *userService) Get(ctx context.Context, args userArgs) (map[string]any, error) {
+	s.lastCtx = ctx
+	if args.ID == 0 {
+		return nil, errors.New("user not found")
+	}
+	return map[string]any{"id": args.ID, "name": args.Name}, nil
+}
+
+func (*userService) Add(a, b int) int {
+	return a + b
+}
+
+func (*userService) Reset() error {
+	return nil
+}
+
+func (*userService) Fail() error {
+	return &Error{Code: -32001, Message: "custom"}
+}
+
+func (*userService) Ping() {}
+
+// Unsuitable signatures are skipped
+func (*userService) Pair() (int, int) { return 1, 2 }
+
+func (*userService) helper() {}
+
+func TestServer_RegisterService(t *testing.T) {
+	t.Run("Methods are exposed with namespace", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterService("user", &userService{}))
+
+		methods := srv.Methods()
+		sort.Strings(methods)
+		assert.Equal(t,
+			[]string{"user.add", "user.fail", "user.get", "user.ping", "user.reset"}, methods)
+	})
+
+	t.Run("Empty namespace", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterService("", &userService{}))
+		assert.Contains(t, srv.Methods(), "add")
+	})
+
+	t.Run("Nil receiver", func(t *testing.T) {
+		require.Error(t, NewServer().RegisterService("x", nil))
+	})
+
+	t.Run("No suitable methods", func(t *testing.T) {
+		err := NewServer().RegisterService("x", struct{}{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no exported methods")
+	})
+
+	t.Run("Registration is all or nothing", func(t *testing.T) {
+		srv := NewServer()
+		noop := func(_ context.Context, _ *Request) (any, error) { return nil, nil }
+		require.NoError(t, srv.RegisterFunc("user.add", noop))
+		require.Error(t, srv.RegisterService("user", &userService{}))
+		assert.Equal(t, []string{"user.add"}, srv.Methods())
+	})
+}
+
+func TestServiceMethod_ServeRPC(t *testing.T) {
+	svc := &userService{}
+	srv := NewServer()
+	require.NoError(t, srv.RegisterService("user", svc))
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Named params into struct",
+			input:    `{"jsonrpc":"2.0","id":1,"method":"user.get","params":{"id":7,"name":"Ann"}}`,
+			expected: `{"jsonrpc":"2.0","id":1,"result":{"id":7,"name":"Ann"}}`,
+		},
+		{
+			name:     "Positional params into struct",
+			input:    `{"jsonrpc":"2.0","id":1,"method":"user.get","params":[{"id":7}]}`,
+			expected: `{"jsonrpc":"2.0","id":1,"result":{"id":7,"name":""}}`,
+		},
+		{
+			name:     "Positional params into multiple arguments",
+			input:    `{"jsonrpc":"2.0","id":2,"method":"user.add","params":[40,2]}`,
+			expected: `{"jsonrpc":"2.0","id":2,"result":42}`,
+		},
+		{
+			name:     "Missing trailing arguments are zero",
+			input:    `{"jsonrpc":"2.0","id":3,"method":"user.add","params":[40]}`,
+			expected: `{"jsonrpc":"2.0","id":3,"result":40}`,
+		},
+		{
+			name:     "Error-only method",
+			input:    `{"jsonrpc":"2.0","id":4,"method":"user.reset"}`,
+			expected: `{"jsonrpc":"2.0","id":4,"result":null}`,
+		},
+		{
+			name:     "No return values",
+			input:    `{"jsonrpc":"2.0","id":5,"method":"user.ping"}`,
+			expected: `{"jsonrpc":"2.0","id":5,"result":null}`,
+		},
+		{
+			name:     "Returned *Error is preserved",
+			input:    `{"jsonrpc":"2.0","id":6,"method":"user.fail"}`,
+			expected: `{"jsonrpc":"2.0","id":6,"error":{"code":-32001,"message":"custom"}}`,
+		},
+		{
+			name:     "Plain error",
+			input:    `{"jsonrpc":"2.0","id":7,"method":"user.get","params":{"id":0}}`,
+			expected: `{"jsonrpc":"2.0","id":7,"error":{"code":-32603,"message":"user not found"}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := srv.HandleMessage(context.Background(), []byte(tc.input))
+			assert.JSONEq(t, tc.expected, string(reply))
+		})
+	}
+
+	t.Run("Context is passed through", func(t *testing.T) {
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "v")
+		req := NewRequestWithID("user.get", map[string]any{"id": 1}, int64(1))
+		require.NotNil(t, srv.HandleRequest(ctx, req))
+		assert.Equal(t, "v", svc.lastCtx.Value(key{}))
+	})
+
+	t.Run("Invalid params", func(t *testing.T) {
+		inputs := []string{
+			`{"jsonrpc":"2.0","id":1,"method":"user.add","params":[1,2,3]}`,
+			`{"jsonrpc":"2.0","id":1,"method":"user.add","params":{"a":1}}`,
+			`{"jsonrpc":"2.0","id":1,"method":"user.add","params":["x"]}`,
+		}
+		for _, input := range inputs {
+			resp, err := DecodeResponse(srv.HandleMessage(context.Background(), []byte(input)))
+			require.NoError(t, err)
+			require.NotNil(t, resp.Err(), input)
+			assert.Equal(t, InvalidParams, resp.Err().Code, input)
+		}
+	})
+}
+
+func TestLowerFirst(t *testing.T) {
+	assert.Equal(t, "getUser", lowerFirst("GetUser"))
+	assert.Equal(t, "x", lowerFirst("X"))
+	assert.Equal(t, "", lowerFirst(""))
+}
@@ -0,0 +1,105 @@
+package jsonrpc
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Session is the state of a connection served by ServeStream or a Peer, shared by the handlers
+// of its requests, such as the authenticated principal, the subscriptions of the peer, or
+// counters. It lives as long as the connection, whose end runs the cleanup functions registered
+// with OnClose. A Session is safe for concurrent use.
+type Session struct {
+	mu       sync.Mutex
+	values   map[any]any
+	counters map[any]int64
+	cleanups []func()
+	closed   bool
+}
+
+// newSession returns an empty session.
+func newSession() *Session {
+	return &Session{values: make(map[any]any), counters: make(map[any]int64)}
+}
+
+// SessionFromContext returns the session of the connection on which the current request
+// arrived. It returns false outside handlers invoked for stream requests, such as over HTTP.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return conn.session, true
+}
+
+// Session returns the session of the connection.
+func (c *Conn) Session() *Session {
+	return c.session
+}
+
+// Get returns the value stored under key, and false if there is none.
+func (s *Session) Get(key any) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Set stores value under key, replacing the value stored before. Keys are compared like map
+// keys, so packages should use keys of unexported types, as for context values.
+func (s *Session) Set(key, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Add adds delta to the counter under key, which starts at zero, and returns its new value.
+// Counters are kept apart from the values set with Set.
+func (s *Session) Add(key any, delta int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[key] += delta
+	return s.counters[key]
+}
+
+// Counter returns the value of the counter under key.
+func (s *Session) Counter(key any) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[key]
+}
+
+// OnClose registers fn to run once the connection has ended, such as to cancel the peer's
+// subscriptions or release resources held on its behalf. Cleanup functions run in the reverse
+// order of their registration, after the connection is closed and no longer listed by Conns. If
+// the connection has already ended, fn runs right away.
+func (s *Session) OnClose(fn func()) {
+	s.mu.Lock()
+	if !s.closed {
+		s.cleanups = append(s.cleanups, fn)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	fn()
+}
+
+// close runs the cleanup functions.
+func (s *Session) close() {
+	s.mu.Lock()
+	s.closed = true
+	cleanups := s.cleanups
+	s.cleanups = nil
+	s.mu.Unlock()
+	for _, fn := range slices.Backward(cleanups) {
+		fn()
+	}
+}
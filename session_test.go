@@ -0,0 +1,94 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionUserKey is the session key of the user of a connection under test.
+type sessionUserKey struct{}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	closed := make(chan string, 2)
+	srv := NewServer()
+	login := func(ctx context.Context, req *Request) (any, error) {
+		session, _ := SessionFromContext(ctx)
+		var params []string
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		session.Set(sessionUserKey{}, params[0])
+		session.OnClose(func() { closed <- "second" })
+		session.OnClose(func() { closed <- "first" })
+		return nil, nil
+	}
+	whoami := func(ctx context.Context, _ *Request) (any, error) {
+		session, _ := SessionFromContext(ctx)
+		user, _ := session.Get(sessionUserKey{})
+		return map[string]any{"user": user, "calls": session.Add("calls", 1)}, nil
+	}
+	require.NoError(t, srv.RegisterFunc("login", login))
+	require.NoError(t, srv.RegisterFunc("whoami", whoami))
+
+	// connect serves a new connection, returning the client calling over it.
+	connect := func() *Client {
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		return NewStreamClient(clientEnd)
+	}
+	type identity struct {
+		User  *string
+		Calls int64
+	}
+
+	t.Run("State is scoped to the connection", func(t *testing.T) {
+		alice, other := connect(), connect()
+		defer other.Close()
+		require.NoError(t, alice.Call(ctx, "login", []string{"alice"}, nil))
+
+		var got identity
+		require.NoError(t, alice.Call(ctx, "whoami", nil, &got))
+		require.NoError(t, alice.Call(ctx, "whoami", nil, &got))
+		require.NotNil(t, got.User)
+		assert.Equal(t, "alice", *got.User)
+		assert.Equal(t, int64(2), got.Calls)
+
+		require.NoError(t, other.Call(ctx, "whoami", nil, &got))
+		assert.Nil(t, got.User)
+		assert.Equal(t, int64(1), got.Calls)
+
+		require.NoError(t, alice.Close())
+		for _, want := range []string{"first", "second"} {
+			select {
+			case got := <-closed:
+				assert.Equal(t, want, got)
+			case <-time.After(time.Second):
+				t.Fatal("cleanup not run on disconnect")
+			}
+		}
+	})
+
+	t.Run("Values, counters, and late cleanups", func(t *testing.T) {
+		session := newSession()
+		session.Set("k", 1)
+		session.Delete("k")
+		_, ok := session.Get("k")
+		assert.False(t, ok)
+		assert.Zero(t, session.Counter("calls"))
+
+		session.close()
+		ran := false
+		session.OnClose(func() { ran = true })
+		assert.True(t, ran, "cleanups registered late run right away")
+	})
+
+	t.Run("No session outside streams", func(t *testing.T) {
+		_, ok := SessionFromContext(ctx)
+		assert.False(t, ok)
+	})
+}
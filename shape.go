@@ -0,0 +1,169 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/bytedance/sonic/ast"
+)
+
+// IDEcho selects how a server echoes the IDs of requests in its responses.
+type IDEcho int
+
+const (
+	// IDEchoDecoded writes IDs encoded anew from their decoded values, so that equal IDs are
+	// written alike: a string ID spelled with escapes is written unescaped and a number ID in a
+	// canonical form. This is the default.
+	IDEchoDecoded IDEcho = iota
+
+	// IDEchoExact writes IDs byte for byte as they appeared in the requests, for clients
+	// matching responses by comparing the raw text of IDs.
+	IDEchoExact
+)
+
+// defaultFieldOrder is the order of the members of responses written by AppendJSON.
+var defaultFieldOrder = []string{"jsonrpc", "id", "result", "error"}
+
+// ResponseShape configures WithResponseShape. The zero value writes responses as the JSON-RPC
+// 2.0 specification requires.
+type ResponseShape struct {
+	// IDEcho selects how the IDs of requests are echoed. Defaults to IDEchoDecoded.
+	IDEcho IDEcho
+
+	// NullMembers writes both result and error in every response, the one not set as null, as
+	// JSON-RPC 1.0 clients expect.
+	NullMembers bool
+
+	// NotificationErrors answers notifications whose handler fails with an error response of
+	// null id. The specification forbids replying to notifications, but some clients wait for
+	// a reply to every message.
+	NotificationErrors bool
+
+	// FieldOrder lists the members of responses in the order they are written, such as
+	// {"id", "jsonrpc", "result", "error"} for clients parsing responses positionally. Members
+	// not listed follow in their default order of jsonrpc, id, result, and error. Empty keeps
+	// the default order.
+	FieldOrder []string
+}
+
+// WithResponseShape makes the server shape its responses as shape describes, to interoperate
+// with clients that depart from the specification. Reordering and null members apply to the
+// encoded replies of messages, after WithCanonicalJSON, whose key order they override.
+func WithResponseShape(shape ResponseShape) ServerOption {
+	return func(s *Server) {
+		s.shape = shape
+		s.shape.FieldOrder = slices.Clone(shape.FieldOrder)
+	}
+}
+
+// rewrites reports whether the encoded replies are rewritten.
+func (shape *ResponseShape) rewrites() bool {
+	return shape.NullMembers || len(shape.FieldOrder) > 0
+}
+
+// echo returns resp with the ID of the request message raw as it was written, or resp as is if
+// exact echo is off or the ID cannot be found.
+func (shape *ResponseShape) echo(raw []byte, resp *Response) *Response {
+	if shape.IDEcho != IDEchoExact || resp == nil || resp.id == nil {
+		return resp
+	}
+	node, err := ast.NewSearcher(string(raw)).GetByPath("id")
+	if err != nil || !node.Exists() {
+		return resp
+	}
+	id, err := node.Raw()
+	if err != nil {
+		return resp
+	}
+	return &Response{
+		jsonrpc:  resp.jsonrpc,
+		id:       resp.id,
+		rawID:    json.RawMessage(id),
+		err:      resp.err,
+		rawError: resp.rawError,
+		result:   resp.result,
+	}
+}
+
+// notificationError returns resp, or, for a notification req that failed with err, the error
+// response due under NotificationErrors.
+func (s *Server) notificationError(req *Request, resp *Response, err error) *Response {
+	if resp != nil || err == nil || !s.shape.NotificationErrors || !req.IsNotification() {
+		return resp
+	}
+	return NewErrorResponse(nil, s.toError(err))
+}
+
+// shapeReply replaces the reply appended to dst with its shaped form, leaving it as is if it
+// cannot be parsed.
+func (shape *ResponseShape) shapeReply(dst, reply []byte) []byte {
+	if len(reply) == len(dst) {
+		return reply
+	}
+	msg := slices.Clone(reply[len(dst):])
+	shaped := reply[:len(dst)]
+	if !isBatchJSON(msg) {
+		out, err := shape.appendShaped(shaped, msg)
+		if err != nil {
+			return append(shaped, msg...)
+		}
+		return out
+	}
+
+	var members []json.RawMessage
+	if err := getCodec().Unmarshal(msg, &members); err != nil {
+		return append(shaped, msg...)
+	}
+	out := append(shaped, '[')
+	for i, member := range members {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		var err error
+		if out, err = shape.appendShaped(out, member); err != nil {
+			return append(shaped, msg...)
+		}
+	}
+	return append(out, ']')
+}
+
+// appendShaped appends the response msg to dst with its members ordered and completed.
+func (shape *ResponseShape) appendShaped(dst, msg []byte) ([]byte, error) {
+	var members map[string]json.RawMessage
+	if err := getCodec().Unmarshal(msg, &members); err != nil {
+		return dst, err
+	}
+	if shape.NullMembers {
+		for _, key := range []string{"result", "error"} {
+			if _, ok := members[key]; !ok {
+				members[key] = json.RawMessage("null")
+			}
+		}
+	}
+
+	order := append(slices.Clone(shape.FieldOrder), defaultFieldOrder...)
+	var rest []string
+	for key := range members {
+		if !slices.Contains(order, key) {
+			rest = append(rest, key)
+		}
+	}
+	slices.Sort(rest)
+
+	out := append(dst, '{')
+	written := make(map[string]bool, len(members))
+	for _, key := range append(order, rest...) {
+		value, ok := members[key]
+		if !ok || written[key] {
+			continue
+		}
+		if len(written) > 0 {
+			out = append(out, ',')
+		}
+		written[key] = true
+		out = appendString(out, key)
+		out = append(out, ':')
+		out = append(out, value...)
+	}
+	return append(out, '}'), nil
+}
@@ -0,0 +1,92 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newShapedServer returns a server shaped as shape, serving echo and fail.
+func newShapedServer(t *testing.T, shape ResponseShape, opts ...ServerOption) *Server {
+	t.Helper()
+	srv := NewServer(append([]ServerOption{WithResponseShape(shape)}, opts...)...)
+	require.NoError(t, srv.RegisterFunc("echo", func(context.Context, *Request) (any, error) {
+		return "ok", nil
+	}))
+	require.NoError(t, srv.RegisterFunc("fail", func(context.Context, *Request) (any, error) {
+		return nil, errors.New("boom")
+	}))
+	return srv
+}
+
+func TestServer_ResponseShape(t *testing.T) {
+	ctx := context.Background()
+	padded := []byte(`{"jsonrpc":"2.0","method":"echo","id":"007"}`)
+	escaped := []byte(`{"jsonrpc":"2.0","method":"echo","id":"\u0030\u0037"}`)
+
+	t.Run("Default", func(t *testing.T) {
+		srv := newShapedServer(t, ResponseShape{})
+		assert.Equal(t, `{"jsonrpc":"2.0","id":"007","result":"ok"}`,
+			string(srv.HandleMessage(ctx, padded)))
+		assert.Equal(t, `{"jsonrpc":"2.0","id":"07","result":"ok"}`,
+			string(srv.HandleMessage(ctx, escaped)))
+		assert.Nil(t, srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"fail"}`)))
+	})
+
+	t.Run("Exact ID echo", func(t *testing.T) {
+		srv := newShapedServer(t, ResponseShape{IDEcho: IDEchoExact})
+		assert.Equal(t, `{"jsonrpc":"2.0","id":"\u0030\u0037","result":"ok"}`,
+			string(srv.HandleMessage(ctx, escaped)))
+
+		reply := srv.HandleMessage(ctx, []byte(`[{"jsonrpc":"2.0","method":"echo","id":1.50},`+
+			`{"jsonrpc":"2.0","method":"nope","id":"0042"}]`))
+		assert.Equal(t, `[{"jsonrpc":"2.0","id":1.50,"result":"ok"},`+
+			`{"jsonrpc":"2.0","id":"0042","error":{"code":-32601,"message":"Method not found"}}]`,
+			string(reply))
+	})
+
+	t.Run("Null members", func(t *testing.T) {
+		srv := newShapedServer(t, ResponseShape{NullMembers: true})
+		reply := srv.HandleMessage(ctx, []byte(`[{"jsonrpc":"2.0","method":"echo","id":1},`+
+			`{"jsonrpc":"2.0","method":"nope","id":2}]`))
+		assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":"ok","error":null},`+
+			`{"jsonrpc":"2.0","id":2,"result":null,`+
+			`"error":{"code":-32601,"message":"Method not found"}}]`, string(reply))
+	})
+
+	t.Run("Notification errors", func(t *testing.T) {
+		srv := newShapedServer(t, ResponseShape{NotificationErrors: true})
+		reply := srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"fail"}`))
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		assert.Nil(t, resp.IDOrNil())
+		require.Error(t, resp.Err())
+		assert.Contains(t, string(reply), `"id":null`)
+
+		assert.Nil(t, srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"echo"}`)),
+			"successful notifications get no reply")
+		reply = srv.HandleMessage(ctx, []byte(`[{"jsonrpc":"2.0","method":"echo"},`+
+			`{"jsonrpc":"2.0","method":"nope"}]`))
+		resps, err := DecodeBatchResponse(reply)
+		require.NoError(t, err)
+		require.Len(t, resps, 1)
+		assert.Equal(t, MethodNotFound, resps[0].Err().Code)
+	})
+
+	t.Run("Field order", func(t *testing.T) {
+		srv := newShapedServer(t, ResponseShape{
+			FieldOrder: []string{"id", "result", "error"},
+		}, WithCanonicalJSON())
+		assert.Equal(t, `{"id":"007","result":"ok","jsonrpc":"2.0"}`,
+			string(srv.HandleMessage(ctx, padded)))
+
+		buf := []byte("prefix")
+		reply := srv.AppendMessage(ctx, buf, []byte(`{"jsonrpc":"2.0","method":"nope","id":1}`))
+		assert.Equal(t,
+			`prefix{"id":1,"error":{"code":-32601,"message":"Method not found"},"jsonrpc":"2.0"}`,
+			string(reply))
+	})
+}
@@ -0,0 +1,168 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// ShuttingDown is the error code of responses to requests refused by a server shutting down,
+// within the range reserved for implementation-defined server errors.
+const ShuttingDown = -32017
+
+// msgShuttingDown is the message of ShuttingDown errors.
+const msgShuttingDown = "Server shutting down"
+
+// ErrShuttingDown is the error sent for requests arriving once Shutdown has been called.
+var ErrShuttingDown = &Error{Code: ShuttingDown, Message: msgShuttingDown}
+
+// ErrServerClosed is returned by Serve and ServeStream once Shutdown has been called.
+var ErrServerClosed = errors.New("server closed")
+
+// WithShutdownNotification makes Shutdown send a notification of method, without params, to
+// every connection before closing it, so that peers can tell a shutdown from a dropped
+// connection. WebSocket connections are closed with a normal closure frame regardless.
+func WithShutdownNotification(method string) ServerOption {
+	return func(s *Server) {
+		s.shutdownMethod = method
+	}
+}
+
+// drainer tracks the messages a server is handling, from their arrival until their reply is
+// written, so that shutting down can wait for them.
+type drainer struct {
+	active    atomic.Int64
+	closing   atomic.Bool
+	idleOnce  sync.Once
+	idle      chan struct{}
+	initOnce  sync.Once
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+}
+
+// init creates the channel closed once idle.
+func (d *drainer) init() {
+	d.initOnce.Do(func() {
+		d.idle = make(chan struct{})
+	})
+}
+
+// enter records the arrival of a message. Messages keep being admitted while shutting down, to be
+// answered with ErrShuttingDown.
+func (d *drainer) enter() {
+	d.active.Add(1)
+}
+
+// leave records that a message is done.
+func (d *drainer) leave() {
+	if d.active.Add(-1) == 0 && d.closing.Load() {
+		d.signalIdle()
+	}
+}
+
+// signalIdle wakes up the shutdown waiting for the messages to be done.
+func (d *drainer) signalIdle() {
+	d.init()
+	d.idleOnce.Do(func() {
+		close(d.idle)
+	})
+}
+
+// close marks the drainer as shutting down, returning the channel closed once no message is
+// being handled.
+func (d *drainer) close() <-chan struct{} {
+	d.init()
+	d.closing.Store(true)
+	if d.active.Load() == 0 {
+		d.signalIdle()
+	}
+	return d.idle
+}
+
+// track registers a listener of Serve, to be closed on shutdown. It returns false if the server
+// is already shutting down.
+func (d *drainer) track(l net.Listener) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closing.Load() {
+		return false
+	}
+	if d.listeners == nil {
+		d.listeners = make(map[net.Listener]struct{})
+	}
+	d.listeners[l] = struct{}{}
+	return true
+}
+
+// untrack removes a listener of Serve.
+func (d *drainer) untrack(l net.Listener) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.listeners, l)
+}
+
+// closeListeners closes the listeners of Serve.
+func (d *drainer) closeListeners() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for l := range d.listeners {
+		_ = l.Close()
+	}
+}
+
+// Shutdown gracefully shuts the server down. It stops Serve from accepting connections and
+// refuses new requests with ErrShuttingDown, waits for the messages being handled to be answered,
+// including the replies written to their connection or HTTP response, and then closes the
+// connections served by ServeStream, after sending the notification set with
+// WithShutdownNotification, if any.
+//
+// If ctx is done before the messages are answered, the connections are closed anyway, which
+// cancels the contexts of their handlers, and Shutdown returns ctx.Err(). It otherwise returns
+// once every connection has ended. The server cannot be restarted. For HTTP, shut the
+// http.Server down as well; requests it still accepts are refused.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.drain.mu.Lock()
+	idle := s.drain.close()
+	s.drain.mu.Unlock()
+	s.drain.closeListeners()
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	conns := s.Conns()
+	for _, conn := range conns {
+		conn.shutdown(ctx, s.shutdownMethod)
+	}
+	for _, conn := range conns {
+		select {
+		case <-conn.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// isShuttingDown reports whether Shutdown has been called.
+func (s *Server) isShuttingDown() bool {
+	return s.drain.closing.Load()
+}
+
+// shutdown closes the connection, after notifying its peer of method unless it is empty.
+func (c *Conn) shutdown(ctx context.Context, method string) {
+	if method != "" {
+		if msg, err := NewNotification(method, nil).MarshalJSON(); err == nil {
+			_ = c.client.write(ctx, msg)
+		}
+	}
+	_ = c.client.closeWith(ErrServerClosed)
+}
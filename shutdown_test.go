@@ -0,0 +1,213 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLog is a Stream recording the messages written to it.
+type writeLog struct {
+	Stream
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (w *writeLog) WriteMessage(ctx context.Context, msg []byte) error {
+	w.mu.Lock()
+	w.msgs = append(w.msgs, string(msg))
+	w.mu.Unlock()
+	return w.Stream.WriteMessage(ctx, msg)
+}
+
+// written returns the messages written so far.
+func (w *writeLog) written() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.msgs)
+}
+
+// shutdownAsync calls Shutdown in the background, returning the channel its error is sent on.
+func shutdownAsync(ctx context.Context, srv *Server) <-chan error {
+	out := make(chan error, 1)
+	go func() { out <- srv.Shutdown(ctx) }()
+	return out
+}
+
+// awaitErr returns the error sent on ch.
+func awaitErr(t *testing.T, ch <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("no error received")
+		return nil
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Drains in-flight calls on streams", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithShutdownNotification("server.bye"))
+		require.NoError(t, srv.Register("block", g))
+		require.NoError(t, srv.Register("quick", g))
+
+		clientEnd, serverEnd := newStreamPair()
+		log := &writeLog{Stream: serverEnd}
+		served := make(chan error, 1)
+		go func() { served <- srv.ServeStream(ctx, log) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		inflight := make(chan error, 1)
+		go func() { inflight <- client.Call(ctx, "block", nil, nil) }()
+		g.waitStarted(t, 1)
+
+		shutdown := shutdownAsync(ctx, srv)
+		require.Eventually(t, srv.isShuttingDown, time.Second, time.Millisecond)
+
+		err := client.Call(ctx, "quick", nil, nil)
+		require.ErrorIs(t, err, ErrShuttingDown)
+		assert.True(t, IsCode(err, ShuttingDown))
+
+		g.open()
+		require.NoError(t, awaitErr(t, inflight), "the in-flight call is answered")
+		require.NoError(t, awaitErr(t, shutdown))
+		assert.ErrorIs(t, awaitErr(t, served), ErrServerClosed)
+
+		written := log.written()
+		require.NotEmpty(t, written)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","method":"server.bye"}`, written[len(written)-1])
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("connection not closed")
+		}
+		assert.Empty(t, srv.Conns())
+	})
+
+	t.Run("Gives up at the deadline", func(t *testing.T) {
+		canceled := make(chan struct{})
+		stuck := HandlerFunc(func(ctx context.Context, _ *Request) (any, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		})
+		srv := NewServer()
+		require.NoError(t, srv.Register("stuck", stuck))
+
+		clientEnd, serverEnd := newStreamPair()
+		go func() { _ = srv.ServeStream(ctx, serverEnd) }()
+		client := NewStreamClient(clientEnd)
+		defer client.Close()
+
+		inflight := make(chan error, 1)
+		go func() { inflight <- client.Call(ctx, "stuck", nil, nil) }()
+		require.Eventually(t, func() bool {
+			return srv.drain.active.Load() > 0
+		}, time.Second, time.Millisecond)
+
+		short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, srv.Shutdown(short), context.DeadlineExceeded)
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("handler context not canceled")
+		}
+		assert.Error(t, awaitErr(t, inflight))
+	})
+
+	t.Run("Waits for HTTP replies", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer()
+		require.NoError(t, srv.Register("block", g))
+
+		post := func(body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+			req.Header.Set("Content-Type", contentTypeJSON)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			return rec
+		}
+
+		replied := make(chan *httptest.ResponseRecorder, 1)
+		go func() { replied <- post(`{"jsonrpc":"2.0","method":"block","id":1}`) }()
+		g.waitStarted(t, 1)
+
+		shutdown := shutdownAsync(ctx, srv)
+		require.Eventually(t, srv.isShuttingDown, time.Second, time.Millisecond)
+		select {
+		case <-shutdown:
+			t.Fatal("Shutdown returned before the reply was written")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		refused := post(`{"jsonrpc":"2.0","method":"block","id":2}`)
+		assert.Equal(t, http.StatusServiceUnavailable, refused.Code)
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32017,"message":"Server shutting down"},"id":2}`,
+			refused.Body.String())
+
+		g.open()
+		require.NoError(t, awaitErr(t, shutdown))
+		rec := <-replied
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":"done","id":1}`, rec.Body.String())
+	})
+
+	t.Run("Stops Serve", func(t *testing.T) {
+		srv := newTestServer(t)
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(ctx, l) }()
+
+		stream, err := DialConn(ctx, "tcp", l.Addr().String())
+		require.NoError(t, err)
+		client := NewStreamClient(stream)
+		defer client.Close()
+		var sum int
+		require.NoError(t, client.Call(ctx, "sum", []int{1, 2}, &sum))
+
+		require.NoError(t, srv.Shutdown(ctx))
+		assert.ErrorIs(t, awaitErr(t, served), ErrServerClosed)
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("connection not closed")
+		}
+
+		_, err = net.Dial("tcp", l.Addr().String())
+		assert.Error(t, err, "the listener is closed")
+	})
+
+	t.Run("Refuses to serve once shut down", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.Shutdown(ctx))
+
+		_, serverEnd := newStreamPair()
+		assert.ErrorIs(t, srv.ServeStream(ctx, serverEnd), ErrServerClosed)
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		assert.ErrorIs(t, srv.Serve(ctx, l), ErrServerClosed)
+
+		reply := srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"a","id":1}`))
+		assert.Contains(t, string(reply), `"code":-32017`)
+		assert.Nil(t, srv.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"a"}`)))
+	})
+}
@@ -0,0 +1,103 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlowCall describes a call that took longer than its threshold.
+type SlowCall struct {
+	// Request is the request or notification sent.
+	Request *Request
+
+	// Response is the response received, nil for notifications and calls that got none.
+	Response *Response
+
+	// Err is the error the call failed with, if any.
+	Err error
+
+	// Duration is how long the call took, including retries and the interceptors added after
+	// WithClientSlowCalls.
+	Duration time.Duration
+
+	// Threshold is the threshold the call exceeded.
+	Threshold time.Duration
+
+	// Endpoint is the name of the PoolTransport endpoint that answered the call. It is empty when
+	// no endpoint did or the client does not send through a PoolTransport.
+	Endpoint string
+}
+
+// SlowCallPolicy configures WithClientSlowCalls.
+type SlowCallPolicy struct {
+	// Threshold is the duration beyond which calls to methods without a threshold of their own
+	// are slow. Zero only reports calls to the methods in Methods.
+	Threshold time.Duration
+
+	// Methods maps methods to their thresholds, overriding Threshold.
+	Methods map[string]time.Duration
+
+	// OnSlowCall is called with the context of every slow call, and so with its metadata and
+	// trace, once the call completes. It runs on the goroutine of the call, and should not block.
+	OnSlowCall func(ctx context.Context, call SlowCall)
+}
+
+// WithClientSlowCalls reports the calls and notifications of the client that take longer than
+// the threshold of their method to the OnSlowCall callback of policy, with the endpoint that
+// answered them, so that latency regressions can be alerted on without instrumenting every call
+// site. Like the other interceptors, it does not see batches.
+func WithClientSlowCalls(policy SlowCallPolicy) ClientOption {
+	return WithInterceptors(func(next Invoker) Invoker {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			threshold, ok := policy.Methods[req.Method]
+			if !ok {
+				threshold = policy.Threshold
+			}
+			if threshold <= 0 || policy.OnSlowCall == nil {
+				return next(ctx, req)
+			}
+
+			endpoint := &endpointRecord{}
+			start := time.Now()
+			resp, err := next(context.WithValue(ctx, endpointContextKey{}, endpoint), req)
+			if elapsed := time.Since(start); elapsed > threshold {
+				policy.OnSlowCall(ctx, SlowCall{
+					Request:   req,
+					Response:  resp,
+					Err:       err,
+					Duration:  elapsed,
+					Threshold: threshold,
+					Endpoint:  endpoint.get(),
+				})
+			}
+			return resp, err
+		}
+	})
+}
+
+// endpointContextKey is the context key of the endpointRecord of a call.
+type endpointContextKey struct{}
+
+// endpointRecord holds the name of the endpoint that answered a call, set by the PoolTransport
+// that sent it.
+type endpointRecord struct {
+	mu   sync.Mutex
+	name string
+}
+
+// recordEndpoint records name as the endpoint that answered the call of ctx, if it is tracked.
+func recordEndpoint(ctx context.Context, name string) {
+	if record, ok := ctx.Value(endpointContextKey{}).(*endpointRecord); ok {
+		record.mu.Lock()
+		record.name = name
+		record.mu.Unlock()
+	}
+}
+
+// get returns the recorded endpoint name.
+func (r *endpointRecord) get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.name
+}
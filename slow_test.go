@@ -0,0 +1,88 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowCallKey is the context key of the test values handed to OnSlowCall.
+type slowCallKey struct{}
+
+func TestWithClientSlowCalls(t *testing.T) {
+	srv := newTestServer(t)
+	delayed := func(delay time.Duration) Transport {
+		return &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+			time.Sleep(delay)
+			return srv.HandleMessage(ctx, payload), nil
+		}}
+	}
+
+	t.Run("Reports calls beyond their threshold", func(t *testing.T) {
+		var mu sync.Mutex
+		var calls []SlowCall
+		var values []any
+		client := NewClient(delayed(20*time.Millisecond), WithClientSlowCalls(SlowCallPolicy{
+			Threshold: 5 * time.Millisecond,
+			Methods:   map[string]time.Duration{"subtract": time.Minute},
+			OnSlowCall: func(ctx context.Context, call SlowCall) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, call)
+				values = append(values, ctx.Value(slowCallKey{}))
+			},
+		}))
+		ctx := context.WithValue(context.Background(), slowCallKey{}, "trace")
+
+		var sum int
+		require.NoError(t, client.Call(ctx, "sum", []int{1, 2}, &sum))
+		var diff int
+		require.NoError(t, client.Call(ctx, "subtract", []int{3, 1}, &diff))
+		require.NoError(t, client.Notify(ctx, "notify_hello", nil))
+
+		require.Len(t, calls, 2)
+		assert.Equal(t, "sum", calls[0].Request.Method)
+		assert.GreaterOrEqual(t, calls[0].Duration, 20*time.Millisecond)
+		assert.Equal(t, 5*time.Millisecond, calls[0].Threshold)
+		assert.JSONEq(t, "3", string(calls[0].Response.RawResult()))
+		assert.Empty(t, calls[0].Endpoint)
+		assert.Equal(t, "notify_hello", calls[1].Request.Method)
+		assert.Nil(t, calls[1].Response)
+		assert.Equal(t, []any{"trace", "trace"}, values)
+	})
+
+	t.Run("Fast calls and methods without threshold", func(t *testing.T) {
+		reported := 0
+		client := NewClient(delayed(0), WithClientSlowCalls(SlowCallPolicy{
+			Methods:    map[string]time.Duration{"sum": time.Minute},
+			OnSlowCall: func(context.Context, SlowCall) { reported++ },
+		}))
+		require.NoError(t, client.Call(context.Background(), "sum", []int{1}, nil))
+		require.NoError(t, client.Call(context.Background(), "subtract", []int{1, 1}, nil))
+		assert.Zero(t, reported)
+	})
+
+	t.Run("Reports the pool endpoint", func(t *testing.T) {
+		down := func(context.Context, []byte) ([]byte, error) {
+			return nil, errors.New("connection refused")
+		}
+		pool := NewPoolTransport([]PoolEndpoint{
+			{Name: "down", Transport: &funcTransport{fn: down}},
+			{Name: "slow", Transport: delayed(10 * time.Millisecond)},
+		})
+		defer func() { _ = pool.Close() }()
+		var got SlowCall
+		client := NewClient(pool, WithClientSlowCalls(SlowCallPolicy{
+			Threshold:  time.Millisecond,
+			OnSlowCall: func(_ context.Context, call SlowCall) { got = call },
+		}))
+		require.NoError(t, client.Call(context.Background(), "sum", []int{1}, nil))
+		assert.Equal(t, "slow", got.Endpoint)
+		assert.NoError(t, got.Err)
+	})
+}
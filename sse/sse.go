@@ -0,0 +1,537 @@
+// Package sse provides an HTTP transport for the jsonrpc package for networks whose proxies and
+// firewalls break WebSockets. A client opens a server-sent events (SSE) channel with a GET
+// request and POSTs its messages; the server streams back replies, notifications, and its own
+// calls to the client as events over the channel, so that both peers may issue requests as over
+// any jsonrpc.Stream.
+//
+// The first event, of type endpoint, holds the URL to which the client POSTs the messages of its
+// session. Every other event, of type message, holds one JSON-RPC message (single or batch), as
+// do POST bodies, which are answered with 202 Accepted.
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// defaultReadLimit is the default maximum size in bytes of a single incoming message.
+const defaultReadLimit = 32 * 1024 * 1024
+
+// Event types.
+const (
+	eventEndpoint = "endpoint"
+	eventMessage  = "message"
+)
+
+// Wire format details.
+const (
+	contentTypeEvents = "text/event-stream"
+	contentTypeJSON   = "application/json"
+	sessionParam      = "session"
+	sessionIDSize     = 16
+	lineOverhead      = 64
+)
+
+// errClosed reports a write to a closed stream.
+var errClosed = errors.New("sse: stream closed")
+
+// config holds the settings shared by Dial and NewHandler.
+type config struct {
+	readLimit  int
+	httpClient *http.Client
+	header     http.Header
+	heartbeat  time.Duration
+}
+
+// Option configures Dial and NewHandler.
+type Option func(*config)
+
+// WithReadLimit sets the maximum size in bytes of a single incoming message, an event for
+// clients and a POST body for handlers. Defaults to 32 MiB.
+func WithReadLimit(n int) Option {
+	return func(c *config) {
+		c.readLimit = n
+	}
+}
+
+// WithHTTPClient sets the HTTP client used by Dial for the event channel and the POSTs. It must
+// not time out requests, as the event channel stays open for the life of the stream. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.httpClient = client
+	}
+}
+
+// WithHeader adds the header key with value to the requests made by Dial, such as for
+// authentication.
+func WithHeader(key, value string) Option {
+	return func(c *config) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// WithHeartbeat makes the handler write an SSE comment to idle channels every interval, so that
+// proxies do not close them for inactivity. Zero, the default, disables heartbeats.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(c *config) {
+		c.heartbeat = interval
+	}
+}
+
+// newConfig applies opts over the defaults.
+func newConfig(opts []Option) *config {
+	cfg := &config{readLimit: defaultReadLimit, httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Stream is a jsonrpc.Stream over an SSE channel, reading the messages of its events and writing
+// messages as POSTs.
+type Stream struct {
+	cfg      *config
+	endpoint string
+	body     io.ReadCloser
+	cancel   context.CancelFunc
+	events   <-chan []byte
+	done     chan struct{}
+
+	mu      sync.Mutex
+	readErr error
+	closed  bool
+}
+
+// Dial opens an SSE channel to the endpoint at rawURL and waits for the session's POST URL. The
+// returned stream is typically passed to jsonrpc.NewStreamClient.
+func Dial(ctx context.Context, rawURL string, opts ...Option) (*Stream, error) {
+	cfg := newConfig(opts)
+	// The channel outlives ctx, which only bounds the handshake
+	streamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	addHeader(req, cfg.header)
+	req.Header.Set("Accept", contentTypeEvents)
+	resp, err := cfg.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stream := &Stream{cfg: cfg, body: resp.Body, cancel: cancel, done: make(chan struct{})}
+	if resp.StatusCode != http.StatusOK {
+		_ = stream.Close()
+		return nil, fmt.Errorf("sse: unexpected HTTP status %s", resp.Status)
+	}
+
+	events := make(chan []byte)
+	endpoint := make(chan string, 1)
+	stream.events = events
+	go stream.readEvents(newEventReader(resp.Body, cfg.readLimit), endpoint, events)
+	select {
+	case path, ok := <-endpoint:
+		if !ok {
+			err := stream.err()
+			_ = stream.Close()
+			return nil, fmt.Errorf("sse: no endpoint event: %w", err)
+		}
+		target, err := req.URL.Parse(path)
+		if err != nil {
+			_ = stream.Close()
+			return nil, fmt.Errorf("sse: invalid endpoint %q: %w", path, err)
+		}
+		stream.endpoint = target.String()
+		return stream, nil
+	case <-ctx.Done():
+		_ = stream.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// Dialer returns a jsonrpc.Dialer connecting to rawURL, for clients redialing dropped channels:
+//
+//	dial := sse.Dialer(url)
+//	stream, err := dial(ctx)
+//	client := jsonrpc.NewStreamClient(stream, jsonrpc.WithReconnect(dial, policy))
+func Dialer(rawURL string, opts ...Option) jsonrpc.Dialer {
+	return func(ctx context.Context) (jsonrpc.Stream, error) {
+		return Dial(ctx, rawURL, opts...)
+	}
+}
+
+// readEvents sends the endpoint of the first endpoint event, then the data of the message events
+// read until the channel fails, and records the failure.
+func (s *Stream) readEvents(reader *eventReader, endpoint chan<- string, events chan<- []byte) {
+	defer close(events)
+	defer close(endpoint)
+	found := false
+	for {
+		typ, data, err := reader.next()
+		if err != nil {
+			s.mu.Lock()
+			s.readErr = err
+			s.mu.Unlock()
+			return
+		}
+		switch {
+		case typ == eventEndpoint && !found:
+			found = true
+			endpoint <- string(data)
+		case typ == eventMessage && found:
+			select {
+			case events <- data:
+			case <-s.done:
+				return
+			}
+		default:
+			// Unknown events are ignored, as the SSE specification requires
+		}
+	}
+}
+
+// err returns the error that ended the channel, io.EOF once the stream is closed.
+func (s *Stream) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.readErr == nil {
+		return io.EOF
+	}
+	return s.readErr
+}
+
+// ReadMessage returns the message of the next event. The end of the channel is reported as
+// io.EOF.
+func (s *Stream) ReadMessage(ctx context.Context) ([]byte, error) {
+	select {
+	case msg, ok := <-s.events:
+		if !ok {
+			return nil, s.err()
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WriteMessage POSTs msg to the session's endpoint.
+func (s *Stream) WriteMessage(ctx context.Context, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	addHeader(req, s.cfg.header)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	resp, err := s.cfg.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sse: unexpected HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+// Close closes the event channel, ending the session. Closing a closed stream is not an error.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	closed := s.closed
+	s.closed = true
+	s.mu.Unlock()
+	if closed {
+		return nil
+	}
+	close(s.done)
+	s.cancel()
+	_ = s.body.Close()
+	return nil
+}
+
+// addHeader adds the values of header to the header of req.
+func addHeader(req *http.Request, header http.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+}
+
+// eventReader parses a stream of server-sent events.
+type eventReader struct {
+	scanner *bufio.Scanner
+}
+
+// newEventReader returns a reader of the events of r, whose lines hold at most limit bytes of
+// data.
+func newEventReader(r io.Reader, limit int) *eventReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, limit+lineOverhead)
+	return &eventReader{scanner: scanner}
+}
+
+// next returns the type and data of the next event, the lines of the data joined with newlines.
+func (r *eventReader) next() (typ string, data []byte, err error) {
+	var buf []byte
+	hasData := false
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if hasData {
+				return cmp.Or(typ, eventMessage), buf, nil
+			}
+			typ = ""
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			typ = value
+		case "data":
+			if hasData {
+				buf = append(buf, '\n')
+			}
+			buf, hasData = append(buf, value...), true
+		default:
+			// Comments, IDs, and retry hints carry nothing for JSON-RPC
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	return "", nil, io.EOF
+}
+
+// writeEvent writes an event of type typ holding data to w, one data line per line of data.
+func writeEvent(w io.Writer, typ string, data []byte) error {
+	buf := make([]byte, 0, len(data)+lineOverhead)
+	buf = append(buf, "event: "...)
+	buf = append(buf, typ...)
+	buf = append(buf, '\n')
+	for line := range bytes.SplitSeq(data, []byte("\n")) {
+		buf = append(buf, "data: "...)
+		buf = append(buf, bytes.TrimSuffix(line, []byte("\r"))...)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() string {
+	id := make([]byte, sessionIDSize)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// handler serves SSE sessions with a jsonrpc.Server.
+type handler struct {
+	srv *jsonrpc.Server
+	cfg *config
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// NewHandler returns an http.Handler serving each SSE channel opened with a GET request as a
+// stream of srv until the channel closes, and routing POSTs to the session named by their
+// session query parameter. Handlers can call back to the connected client through
+// jsonrpc.ClientFromContext, and receive the headers of the GET request through
+// jsonrpc.IncomingMetadata.
+func NewHandler(srv *jsonrpc.Server, opts ...Option) http.Handler {
+	return &handler{srv: srv, cfg: newConfig(opts), sessions: make(map[string]*session)}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.serveEvents(w, r)
+	case http.MethodPost:
+		h.servePost(w, r)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveEvents serves a new session over the event channel of r until either peer ends it.
+func (h *handler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	id := newSessionID()
+	sess := &session{
+		w:        w,
+		flusher:  flusher,
+		incoming: make(chan []byte),
+		done:     make(chan struct{}),
+		ended:    r.Context().Done(),
+	}
+	h.mu.Lock()
+	h.sessions[id] = sess
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+	}()
+
+	header := w.Header()
+	header.Set("Content-Type", contentTypeEvents)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	endpoint := (&url.URL{Path: r.URL.Path, RawQuery: sessionParam + "=" + id}).String()
+	if err := sess.write(eventEndpoint, []byte(endpoint)); err != nil {
+		return
+	}
+	if h.cfg.heartbeat > 0 {
+		go sess.beat(h.cfg.heartbeat)
+	}
+
+	ctx := jsonrpc.NewIncomingContext(r.Context(), jsonrpc.MetadataFromHTTP(r))
+	ctx = jsonrpc.WithConnectionState(ctx, r.TLS)
+	_ = h.srv.ServeStream(ctx, sess)
+	sess.end()
+}
+
+// servePost hands the message in the body of r to the session it names.
+func (h *handler) servePost(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	sess, ok := h.sessions[r.URL.Query().Get(sessionParam)]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(h.cfg.readLimit)+1))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > h.cfg.readLimit {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	select {
+	case sess.incoming <- body:
+		w.WriteHeader(http.StatusAccepted)
+	case <-sess.done:
+		http.Error(w, "unknown session", http.StatusNotFound)
+	case <-sess.ended:
+		http.Error(w, "unknown session", http.StatusNotFound)
+	case <-r.Context().Done():
+	}
+}
+
+// session is the jsonrpc.Stream of a served SSE channel, reading the messages POSTed to it and
+// writing messages as events.
+type session struct {
+	w        http.ResponseWriter
+	flusher  http.Flusher
+	incoming chan []byte
+	ended    <-chan struct{}
+
+	mu       sync.Mutex
+	done     chan struct{}
+	finished bool
+}
+
+// ReadMessage returns the next message POSTed to the session. The end of the channel is
+// reported as io.EOF.
+func (s *session) ReadMessage(ctx context.Context) ([]byte, error) {
+	select {
+	case msg := <-s.incoming:
+		return msg, nil
+	case <-s.done:
+		return nil, io.EOF
+	case <-s.ended:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WriteMessage writes msg as a message event.
+func (s *session) WriteMessage(_ context.Context, msg []byte) error {
+	return s.write(eventMessage, msg)
+}
+
+// write writes an event of type typ holding data and flushes it.
+func (s *session) write(typ string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return errClosed
+	}
+	if err := writeEvent(s.w, typ, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// beat writes a comment every interval until the session ends.
+func (s *session) beat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if !s.finished {
+				_, _ = io.WriteString(s.w, ":\n\n")
+				s.flusher.Flush()
+			}
+			s.mu.Unlock()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close ends the session, closing its event channel.
+func (s *session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	return nil
+}
+
+// end closes the session and stops writes to it, as the response writer is invalid once the
+// handler returns.
+func (s *session) end() {
+	_ = s.Close()
+	s.mu.Lock()
+	s.finished = true
+	s.mu.Unlock()
+}
@@ -0,0 +1,255 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// newTestServer starts an HTTP server serving SSE sessions with srv and returns its URL.
+func newTestServer(t *testing.T, srv *jsonrpc.Server, opts ...Option) string {
+	t.Helper()
+	ts := httptest.NewServer(NewHandler(srv, opts...))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/rpc"
+}
+
+// echo returns the request params as the result.
+func echo(_ context.Context, req *jsonrpc.Request) (any, error) {
+	return req.Params, nil
+}
+
+func TestSSE(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Call", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+
+		stream, err := Dial(ctx, newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		var got []string
+		require.NoError(t, client.Call(ctx, "echo", []any{"a", "b"}, &got))
+		assert.Equal(t, []string{"a", "b"}, got)
+
+		require.NoError(t, client.Call(ctx, "echo", []any{"line\nbreak"}, &got))
+		assert.Equal(t, []string{"line\nbreak"}, got)
+	})
+
+	t.Run("Server notifies client", func(t *testing.T) {
+		received := make(chan string, 1)
+		srv := jsonrpc.NewServer()
+		subscribe := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			conn, _ := jsonrpc.ConnFromContext(ctx)
+			go func() { _ = conn.Notify("event", []any{"tick"}) }()
+			return true, nil
+		}
+		require.NoError(t, srv.RegisterFunc("subscribe", subscribe))
+
+		clientSrv := jsonrpc.NewServer()
+		event := func(_ context.Context, req *jsonrpc.Request) (any, error) {
+			var params []string
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			received <- params[0]
+			return nil, nil
+		}
+		require.NoError(t, clientSrv.RegisterFunc("event", event))
+
+		stream, err := Dial(ctx, newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream, jsonrpc.WithServer(clientSrv))
+		defer client.Close()
+
+		require.NoError(t, client.Call(ctx, "subscribe", nil, nil))
+		select {
+		case got := <-received:
+			assert.Equal(t, "tick", got)
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+	})
+
+	t.Run("Server calls back to client", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		greet := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, _ := jsonrpc.ClientFromContext(ctx)
+			var name string
+			if err := peer.Call(ctx, "name", nil, &name); err != nil {
+				return nil, err
+			}
+			return "hello " + name, nil
+		}
+		require.NoError(t, srv.RegisterFunc("greet", greet))
+		clientSrv := jsonrpc.NewServer()
+		require.NoError(t, clientSrv.RegisterFunc("name",
+			func(context.Context, *jsonrpc.Request) (any, error) { return "ann", nil }))
+
+		stream, err := Dial(ctx, newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream, jsonrpc.WithServer(clientSrv))
+		defer client.Close()
+
+		var greeting string
+		require.NoError(t, client.Call(ctx, "greet", nil, &greeting))
+		assert.Equal(t, "hello ann", greeting)
+	})
+
+	t.Run("Headers reach handlers as metadata", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		token := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			md, _ := jsonrpc.IncomingMetadata(ctx)
+			return md.Get("Authorization"), nil
+		}
+		require.NoError(t, srv.RegisterFunc("token", token))
+
+		stream, err := Dial(ctx, newTestServer(t, srv), WithHeader("Authorization", "Bearer x"))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		var got string
+		require.NoError(t, client.Call(ctx, "token", nil, &got))
+		assert.Equal(t, "Bearer x", got)
+	})
+
+	t.Run("Closing the channel ends the session", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		url := newTestServer(t, srv)
+		stream, err := Dial(ctx, url)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return len(srv.Conns()) == 1 }, time.Second,
+			10*time.Millisecond)
+
+		require.NoError(t, stream.Close())
+		require.NoError(t, stream.Close())
+		require.Eventually(t, func() bool { return len(srv.Conns()) == 0 }, time.Second,
+			10*time.Millisecond)
+		assert.Error(t, stream.WriteMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"m"}`)),
+			"the session is gone")
+		_, err = stream.ReadMessage(ctx)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("Server close ends the client", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		stop := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, _ := jsonrpc.ClientFromContext(ctx)
+			go func() { _ = peer.Close() }()
+			return nil, nil
+		}
+		require.NoError(t, srv.RegisterFunc("stop", stop))
+
+		stream, err := Dial(ctx, newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		_ = client.Call(ctx, "stop", nil, nil)
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("client did not shut down after the server closed the channel")
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	srv := jsonrpc.NewServer()
+	require.NoError(t, srv.RegisterFunc("echo", echo))
+
+	t.Run("Rejects other methods", func(t *testing.T) {
+		resp, err := http.Post(newTestServer(t, srv)+"?session=x", "application/json",
+			strings.NewReader(`{}`))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "unknown session")
+
+		req, err := http.NewRequest(http.MethodPut, newTestServer(t, srv), nil)
+		require.NoError(t, err)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("Wire format", func(t *testing.T) {
+		url := newTestServer(t, srv, WithHeartbeat(10*time.Millisecond), WithReadLimit(64))
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		lines := bufio.NewReader(resp.Body)
+		readLine := func() string {
+			line, err := lines.ReadString('\n')
+			require.NoError(t, err)
+			return strings.TrimSuffix(line, "\n")
+		}
+		assert.Equal(t, "event: endpoint", readLine())
+		endpoint := strings.TrimPrefix(readLine(), "data: ")
+		assert.True(t, strings.HasPrefix(endpoint, "/rpc?session="), endpoint)
+		assert.Empty(t, readLine())
+
+		post := func(body string) int {
+			resp, err := http.Post(strings.TrimSuffix(url, "/rpc")+endpoint, "application/json",
+				strings.NewReader(body))
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(strings.Repeat(" ", 65)))
+		assert.Equal(t, http.StatusAccepted,
+			post(`{"jsonrpc":"2.0","method":"echo","params":["a"],"id":1}`))
+
+		var events []string
+		for len(events) < 3 {
+			events = append(events, readLine())
+		}
+		for events[0] == ":" || events[0] == "" {
+			events = append(events[1:], readLine())
+		}
+		assert.Equal(t, []string{
+			"event: message",
+			`data: {"jsonrpc":"2.0","id":1,"result":["a"]}`,
+			"",
+		}, events)
+	})
+}
+
+func TestEventReader(t *testing.T) {
+	input := ": comment\n\nevent: endpoint\ndata: /a\n\nid: 1\ndata: [1,\ndata:2]\r\n\n" +
+		"event: other\ndata: x\n\n"
+	reader := newEventReader(strings.NewReader(input), defaultReadLimit)
+
+	typ, data, err := reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, "endpoint", typ)
+	assert.Equal(t, "/a", string(data))
+
+	typ, data, err = reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, "message", typ)
+	assert.Equal(t, "[1,\n2]", string(data))
+
+	typ, _, err = reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, "other", typ)
+
+	_, _, err = reader.next()
+	assert.ErrorIs(t, err, io.EOF)
+}
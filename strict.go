@@ -0,0 +1,206 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Members allowed at the top level of strictly validated requests and responses.
+var (
+	requestMembers  = []string{"jsonrpc", "id", "method", "params"}
+	responseMembers = []string{"jsonrpc", "id", "result", "error"}
+)
+
+// WithStrictValidation makes the server reject incoming requests that the lenient decoder would
+// accept but that do not strictly follow the JSON-RPC 2.0 specification, answering them with an
+// invalid request error whose data describes the violation. See ValidateStrict for the rules.
+func WithStrictValidation() ServerOption {
+	return func(s *Server) {
+		s.strict = true
+	}
+}
+
+// WithStrictResponses makes the client reject replies that do not strictly follow the JSON-RPC
+// 2.0 specification, as described by ValidateStrict. Calls over a Transport fail with the
+// violation; stream clients drop such responses like other malformed messages.
+func WithStrictResponses() ClientOption {
+	return func(c *Client) {
+		c.strict = true
+	}
+}
+
+// ValidateStrict checks that an encoded request, response, or batch strictly follows the
+// JSON-RPC 2.0 specification. Beyond what decoding checks, it rejects:
+//
+//   - a missing or different jsonrpc member, where the version must be exactly "2.0"
+//   - numeric IDs with a fractional part or an exponent
+//   - responses without an id member, or with both or neither of result and error
+//   - error objects without an integer code and a string message
+//   - params that are neither an array nor an object, including empty strings
+//   - unknown top-level members
+//
+// A message is checked as a request when it has a method member, and as a response otherwise.
+// The returned error is an *Error with the code the specification mandates: ParseError for
+// invalid JSON, and InvalidRequest, with the violation as data, for anything else.
+func ValidateStrict(msg []byte) error {
+	trimmed := bytes.TrimSpace(msg)
+	if len(trimmed) == 0 || !getCodec().Valid(trimmed) {
+		return &Error{Code: ParseError, Message: msgParseError}
+	}
+	if err := validateStrict(trimmed); err != nil {
+		return &Error{Code: InvalidRequest, Message: msgInvalidRequest, Data: err.Error()}
+	}
+	return nil
+}
+
+// validateStrict checks a valid JSON message or batch, returning the first violation found.
+func validateStrict(msg []byte) error {
+	if !isBatchJSON(msg) {
+		return validateStrictMessage(msg)
+	}
+
+	var members []json.RawMessage
+	if err := getCodec().Unmarshal(msg, &members); err != nil {
+		return fmt.Errorf("invalid batch: %w", err)
+	}
+	if len(members) == 0 {
+		return errors.New("batch must not be empty")
+	}
+	for i, member := range members {
+		if err := validateStrictMessage(member); err != nil {
+			return fmt.Errorf("batch member %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateStrictMessage checks a single request or response.
+func validateStrictMessage(msg []byte) error {
+	if isRequestMessage(msg) {
+		return validateStrictRequest(msg)
+	}
+	return validateStrictResponse(msg)
+}
+
+// validateStrictRequest checks a single request or notification.
+func validateStrictRequest(msg []byte) error {
+	members, err := strictMembers(msg, requestMembers)
+	if err != nil {
+		return err
+	}
+
+	var method string
+	if err := getCodec().Unmarshal(members["method"], &method); err != nil || method == "" {
+		return errors.New("method member must be a non-empty string")
+	}
+	if rawID, ok := members["id"]; ok {
+		if err := validateStrictID(rawID); err != nil {
+			return err
+		}
+	}
+	if rawParams, ok := members["params"]; ok && !isStructuredJSON(rawParams) {
+		return errors.New("params member must be an array or an object")
+	}
+	return nil
+}
+
+// validateStrictResponse checks a single response.
+func validateStrictResponse(msg []byte) error {
+	members, err := strictMembers(msg, responseMembers)
+	if err != nil {
+		return err
+	}
+
+	rawID, ok := members["id"]
+	if !ok {
+		return errors.New("id member is required in responses")
+	}
+	if err := validateStrictID(rawID); err != nil {
+		return err
+	}
+
+	_, hasResult := members["result"]
+	rawError, hasError := members["error"]
+	switch {
+	case hasResult && hasError:
+		return errors.New("response must not contain both result and error")
+	case !hasResult && !hasError:
+		return errors.New("response must contain either result or error")
+	case hasError:
+		return validateStrictError(rawError)
+	default:
+		return nil
+	}
+}
+
+// validateStrictError checks the error object of a response.
+func validateStrictError(raw json.RawMessage) error {
+	var members map[string]json.RawMessage
+	if err := getCodec().Unmarshal(raw, &members); err != nil || members == nil {
+		return errors.New("error member must be an object")
+	}
+	if !isJSONInteger(members["code"]) {
+		return errors.New("error code must be an integer")
+	}
+	var message string
+	if err := getCodec().Unmarshal(members["message"], &message); err != nil {
+		return errors.New("error message must be a string")
+	}
+	return nil
+}
+
+// strictMembers decodes the top-level members of a message, checking that each is allowed and
+// that the jsonrpc member is exactly "2.0".
+func strictMembers(msg []byte, allowed []string) (map[string]json.RawMessage, error) {
+	var members map[string]json.RawMessage
+	if err := getCodec().Unmarshal(msg, &members); err != nil || members == nil {
+		return nil, errors.New("message must be an object")
+	}
+	for name := range members {
+		if !slices.Contains(allowed, name) {
+			return nil, fmt.Errorf("unknown member %q", name)
+		}
+	}
+
+	var version string
+	if err := getCodec().Unmarshal(members["jsonrpc"], &version); err != nil ||
+		version != jsonRPCVersion {
+		return nil, errors.New("jsonrpc member must be exactly \"2.0\"")
+	}
+	return members, nil
+}
+
+// validateStrictID checks that an id member is a string, an integer, or null.
+func validateStrictID(raw json.RawMessage) error {
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case len(trimmed) == 0:
+		return errors.New("id member must be a string, a number, or null")
+	case trimmed[0] == '"', string(trimmed) == "null", isJSONInteger(trimmed):
+		return nil
+	case isJSONNumber(trimmed):
+		return errors.New("id member must not contain fractional parts")
+	default:
+		return errors.New("id member must be a string, a number, or null")
+	}
+}
+
+// isJSONNumber reports whether raw holds a JSON number.
+func isJSONNumber(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && (trimmed[0] == '-' || trimmed[0] >= '0' && trimmed[0] <= '9')
+}
+
+// isJSONInteger reports whether raw holds a JSON number without fraction or exponent.
+func isJSONInteger(raw json.RawMessage) bool {
+	return isJSONNumber(raw) && !bytes.ContainsAny(raw, ".eE")
+}
+
+// isStructuredJSON reports whether raw holds a JSON array or object.
+func isStructuredJSON(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{')
+}
@@ -0,0 +1,135 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStrict(t *testing.T) {
+	t.Run("Valid messages", func(t *testing.T) {
+		for _, msg := range []string{
+			`{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2]}`,
+			`{"jsonrpc":"2.0","id":"a","method":"sum","params":{"a":1}}`,
+			`{"jsonrpc":"2.0","method":"notify"}`,
+			`{"jsonrpc":"2.0","id":1,"result":null}`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+			`[{"jsonrpc":"2.0","id":1,"method":"sum"},{"jsonrpc":"2.0","id":2,"result":3}]`,
+		} {
+			assert.NoError(t, ValidateStrict([]byte(msg)), msg)
+		}
+	})
+
+	t.Run("Invalid JSON is a parse error", func(t *testing.T) {
+		var rpcErr *Error
+		require.ErrorAs(t, ValidateStrict([]byte(`{"jsonrpc":`)), &rpcErr)
+		assert.Equal(t, ParseError, rpcErr.Code)
+	})
+
+	cases := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{"Missing version", `{"id":1,"method":"sum"}`, `exactly "2.0"`},
+		{"Wrong version", `{"jsonrpc":"1.0","id":1,"result":1}`, `exactly "2.0"`},
+		{"Fractional request ID", `{"jsonrpc":"2.0","id":1.5,"method":"sum"}`, "fractional"},
+		{"Exponent response ID", `{"jsonrpc":"2.0","id":1e3,"result":1}`, "fractional"},
+		{"Object ID", `{"jsonrpc":"2.0","id":{},"method":"sum"}`, "id member"},
+		{"Unknown request member", `{"jsonrpc":"2.0","id":1,"method":"sum","x":1}`, `"x"`},
+		{"Unknown response member", `{"jsonrpc":"2.0","id":1,"result":1,"x":1}`, `"x"`},
+		{"Empty method", `{"jsonrpc":"2.0","id":1,"method":""}`, "method member"},
+		{"Scalar params", `{"jsonrpc":"2.0","id":1,"method":"sum","params":""}`, "params"},
+		{
+			"Result and error",
+			`{"jsonrpc":"2.0","id":1,"result":1,"error":{"code":1,"message":"x"}}`,
+			"both result and error",
+		},
+		{"Neither result nor error", `{"jsonrpc":"2.0","id":1}`, "either result or error"},
+		{"Response without ID", `{"jsonrpc":"2.0","result":1}`, "id member is required"},
+		{
+			"Fractional error code",
+			`{"jsonrpc":"2.0","id":1,"error":{"code":1.5,"message":"x"}}`,
+			"error code",
+		},
+		{"Error without message", `{"jsonrpc":"2.0","id":1,"error":{"code":1}}`, "error message"},
+		{"Empty batch", `[]`, "empty"},
+		{"Invalid batch member", `[{"jsonrpc":"2.0","id":1,"result":1},1]`, "batch member 1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStrict([]byte(tc.msg))
+			var rpcErr *Error
+			require.ErrorAs(t, err, &rpcErr)
+			assert.Equal(t, InvalidRequest, rpcErr.Code)
+			assert.Contains(t, rpcErr.Data, tc.want)
+		})
+	}
+}
+
+func TestServer_StrictValidation(t *testing.T) {
+	newServer := func(t *testing.T, opts ...ServerOption) *Server {
+		srv := NewServer(opts...)
+		echo := func(_ context.Context, req *Request) (any, error) {
+			return req.Params.([]any)[0], nil
+		}
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+		return srv
+	}
+	msg := []byte(`{"jsonrpc":"2.0","id":1.5,"method":"echo","params":["hi"],"extra":true}`)
+
+	t.Run("Lenient by default", func(t *testing.T) {
+		reply := newServer(t).HandleMessage(context.Background(), msg)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1.5,"result":"hi"}`, string(reply))
+	})
+
+	t.Run("Strict rejects with invalid request", func(t *testing.T) {
+		reply := newServer(t, WithStrictValidation()).HandleMessage(context.Background(), msg)
+		resp, err := DecodeResponse(reply)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, InvalidRequest, resp.Err().Code)
+		assert.Nil(t, resp.IDOrNil())
+	})
+
+	t.Run("Strict accepts valid requests", func(t *testing.T) {
+		srv := newServer(t, WithStrictValidation())
+		reply := srv.HandleMessage(context.Background(),
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":["hi"]}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"hi"}`, string(reply))
+	})
+}
+
+func TestClient_StrictResponses(t *testing.T) {
+	reply := func(body string) *funcTransport {
+		return &funcTransport{fn: func(context.Context, []byte) ([]byte, error) {
+			return []byte(body), nil
+		}}
+	}
+	malformed := `{"jsonrpc":"2.0","id":1,"result":3,"extra":true}`
+
+	t.Run("Lenient by default", func(t *testing.T) {
+		var result int
+		client := NewClient(reply(malformed))
+		require.NoError(t, client.Call(context.Background(), "m", nil, &result))
+		assert.Equal(t, 3, result)
+	})
+
+	t.Run("Strict rejects malformed replies", func(t *testing.T) {
+		client := NewClient(reply(malformed), WithStrictResponses())
+		err := client.Call(context.Background(), "m", nil, nil)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, InvalidRequest, rpcErr.Code)
+		assert.ErrorContains(t, err, "invalid response")
+	})
+
+	t.Run("Strict accepts valid replies", func(t *testing.T) {
+		client := NewClient(reply(`{"jsonrpc":"2.0","id":1,"result":3}`), WithStrictResponses())
+		var result int
+		require.NoError(t, client.Call(context.Background(), "m", nil, &result))
+		assert.Equal(t, 3, result)
+	})
+}
@@ -0,0 +1,400 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic/ast"
+)
+
+const (
+	// unsubscribeTimeout bounds the unsubscribe call made when a subscription's context ends.
+	unsubscribeTimeout = 10 * time.Second
+
+	// maxEarlyNotifications caps the notifications held for a subscription whose subscribe call
+	// has not completed yet.
+	maxEarlyNotifications = 64
+)
+
+// errSubscriptionsUnsupported is returned by Subscribe on clients without a stream.
+var errSubscriptionsUnsupported = errors.New("subscriptions require a stream client")
+
+// Subscription is a stream of server-initiated notifications created by Client.Subscribe.
+//
+// Notifications follow the convention used by eth_subscribe and similar APIs: the params are an
+// object holding the subscription ID under "subscription" and the payload under "result".
+type Subscription struct {
+	client    *Client
+	method    string
+	subscribe string
+	params    any
+	ch        chan<- json.RawMessage
+
+	// Guarded by client.subMu, as resubscribing replaces them
+	id  any
+	key string
+
+	mu     sync.Mutex
+	queue  []json.RawMessage
+	endErr error
+	wake   chan struct{}
+	quit   chan struct{}
+	errCh  chan error
+	unsubs sync.Once
+}
+
+// SubscribeOption configures a subscription created by Client.Subscribe.
+type SubscribeOption func(*Subscription)
+
+// WithUnsubscribeMethod sets the method called to cancel the subscription. By default it is
+// derived from the subscribe method by replacing a trailing "subscribe" with "unsubscribe", as in
+// eth_subscribe/eth_unsubscribe or accountSubscribe/accountUnsubscribe.
+func WithUnsubscribeMethod(method string) SubscribeOption {
+	return func(s *Subscription) {
+		s.method = method
+	}
+}
+
+// Subscribe calls method with params, expecting the server to return a subscription ID, and
+// forwards the "result" of every matching notification to ch. Notifications are queued without
+// bound so that a slow receiver never stalls the connection.
+//
+// The subscription lasts until ctx is done or Unsubscribe is called, in both cases unsubscribing
+// on the server, or until the client shuts down, in which case the cause is sent on Err. Clients
+// created WithReconnect renew the subscription on every new connection.
+// Subscribe is only supported by stream clients.
+func (c *Client) Subscribe(
+	ctx context.Context,
+	method string,
+	params any,
+	ch chan<- json.RawMessage,
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	if !c.isStream() {
+		return nil, errSubscriptionsUnsupported
+	}
+
+	sub := &Subscription{
+		client:    c,
+		method:    unsubscribeMethod(method),
+		subscribe: method,
+		params:    params,
+		ch:        ch,
+		wake:      make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		errCh:     make(chan error, 1),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	if sub.method == "" {
+		return nil, fmt.Errorf("cannot derive unsubscribe method from %q", method)
+	}
+
+	c.beginSubscribe()
+	defer c.endSubscribe()
+
+	id, key, err := sub.call(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub.id = id
+	sub.key = key
+
+	if err := c.addSubscription(sub); err != nil {
+		return nil, err
+	}
+	go sub.run(ctx)
+	return sub, nil
+}
+
+// call makes the subscribe call and returns the subscription ID and its key.
+func (s *Subscription) call(ctx context.Context) (any, string, error) {
+	var id any
+	if err := s.client.Call(ctx, s.subscribe, s.params, &id); err != nil {
+		return nil, "", err
+	}
+	key := idKey(id)
+	if key == "" {
+		return nil, "", fmt.Errorf("invalid subscription id: %v", id)
+	}
+	return id, key, nil
+}
+
+// ID returns the subscription ID assigned by the server, which changes when the subscription is
+// renewed after a reconnection.
+func (s *Subscription) ID() any {
+	s.client.subMu.Lock()
+	defer s.client.subMu.Unlock()
+	return s.id
+}
+
+// Err returns a channel that receives the error ending the subscription when the client shuts
+// down or renewing the subscription after a reconnection fails. The channel is closed once the
+// subscription has ended, without a value when it ended through Unsubscribe or its context.
+func (s *Subscription) Err() <-chan error {
+	return s.errCh
+}
+
+// Unsubscribe stops forwarding notifications and cancels the subscription on the server. Only the
+// first call contacts the server; later calls return nil.
+func (s *Subscription) Unsubscribe(ctx context.Context) error {
+	var err error
+	s.unsubs.Do(func() {
+		id := s.client.removeSubscription(s)
+		close(s.quit)
+		err = s.client.Call(ctx, s.method, []any{id}, nil)
+	})
+	return err
+}
+
+// end stops the subscription with err, without contacting the server.
+func (s *Subscription) end(err error) {
+	s.unsubs.Do(func() {
+		s.client.removeSubscription(s)
+		s.mu.Lock()
+		s.endErr = err
+		s.mu.Unlock()
+		close(s.quit)
+	})
+}
+
+// push queues a notification payload for delivery.
+func (s *Subscription) push(result json.RawMessage) {
+	s.mu.Lock()
+	s.queue = append(s.queue, result)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain removes and returns the queued payloads.
+func (s *Subscription) drain() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.queue
+	s.queue = nil
+	return queue
+}
+
+// run forwards queued payloads to the receiver until the subscription ends.
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.errCh)
+
+	var pending []json.RawMessage
+	for {
+		if len(pending) == 0 {
+			pending = s.drain()
+		}
+		var out chan<- json.RawMessage
+		var next json.RawMessage
+		if len(pending) > 0 {
+			out, next = s.ch, pending[0]
+		}
+
+		select {
+		case out <- next:
+			pending = pending[1:]
+		case <-s.wake:
+		case <-s.quit:
+			s.mu.Lock()
+			err := s.endErr
+			s.mu.Unlock()
+			if err != nil {
+				s.errCh <- err
+			}
+			return
+		case <-ctx.Done():
+			unsubCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unsubscribeTimeout)
+			_ = s.Unsubscribe(unsubCtx)
+			cancel()
+			return
+		case <-s.client.done:
+			s.client.removeSubscription(s)
+			s.errCh <- s.client.terminalErr()
+			return
+		}
+	}
+}
+
+// beginSubscribe marks a subscribe call as in flight, so that notifications arriving before its
+// response are held rather than dropped.
+func (c *Client) beginSubscribe() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	c.subscribing++
+}
+
+// endSubscribe marks a subscribe call as complete, discarding held notifications once no
+// subscribe calls remain in flight.
+func (c *Client) endSubscribe() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	c.subscribing--
+	if c.subscribing == 0 {
+		clear(c.early)
+	}
+}
+
+// addSubscription registers sub and hands it any notifications that arrived ahead of it.
+func (c *Client) addSubscription(sub *Subscription) error {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if _, ok := c.subs[sub.key]; ok {
+		return fmt.Errorf("duplicate subscription id: %v", sub.id)
+	}
+	c.subs[sub.key] = sub
+	for _, result := range c.early[sub.key] {
+		sub.push(result)
+	}
+	delete(c.early, sub.key)
+	return nil
+}
+
+// removeSubscription stops routing notifications to sub and returns its current ID.
+func (c *Client) removeSubscription(sub *Subscription) any {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if c.subs[sub.key] == sub {
+		delete(c.subs, sub.key)
+	}
+	return sub.id
+}
+
+// resubscribe renews every active subscription after a reconnection. Subscriptions whose renewal
+// is interrupted by another disconnection are left for the next reconnection to renew.
+func (c *Client) resubscribe() {
+	c.subMu.Lock()
+	subs := slices.Collect(maps.Values(c.subs))
+	c.subMu.Unlock()
+
+	for _, sub := range subs {
+		err := c.renewSubscription(sub)
+		if err != nil && !errors.Is(err, ErrConnectionLost) {
+			sub.end(fmt.Errorf("resubscribe: %w", err))
+		}
+	}
+}
+
+// renewSubscription repeats the subscribe call of sub and routes notifications for the new ID to
+// it. A subscription ended meanwhile is cancelled again on the server.
+func (c *Client) renewSubscription(sub *Subscription) error {
+	c.beginSubscribe()
+	defer c.endSubscribe()
+
+	id, key, err := sub.call(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if c.subs[sub.key] != sub {
+		go func() {
+			ctx, cancel := context.WithTimeout(c.ctx, unsubscribeTimeout)
+			defer cancel()
+			_ = c.Call(ctx, sub.method, []any{id}, nil)
+		}()
+		return nil
+	}
+	if other, ok := c.subs[key]; ok && other != sub {
+		return fmt.Errorf("duplicate subscription id: %v", id)
+	}
+	delete(c.subs, sub.key)
+	sub.id, sub.key = id, key
+	c.subs[key] = sub
+	for _, result := range c.early[key] {
+		sub.push(result)
+	}
+	delete(c.early, key)
+	return nil
+}
+
+// routeNotification delivers a subscription notification to its subscription. It reports false
+// for messages that are not notifications for a known or pending subscription.
+func (c *Client) routeNotification(msg []byte) bool {
+	key, result, ok := parseSubscriptionNotification(msg)
+	if !ok {
+		return false
+	}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if sub, ok := c.subs[key]; ok {
+		sub.push(result)
+		return true
+	}
+	if c.subscribing > 0 && len(c.early[key]) < maxEarlyNotifications {
+		c.early[key] = append(c.early[key], result)
+		return true
+	}
+	return false
+}
+
+// parseSubscriptionNotification extracts the subscription key and raw result from a single
+// notification whose params hold "subscription" and "result" members.
+func parseSubscriptionNotification(msg []byte) (string, json.RawMessage, bool) {
+	if isBatchJSON(msg) {
+		return "", nil, false
+	}
+	searcher := ast.NewSearcher(string(msg))
+	if node, err := searcher.GetByPath("id"); err == nil && node.Exists() {
+		return "", nil, false
+	}
+
+	subNode, err := searcher.GetByPath("params", "subscription")
+	if err != nil {
+		return "", nil, false
+	}
+	rawSub, err := subNode.Raw()
+	if err != nil {
+		return "", nil, false
+	}
+	var id any
+	if err := getCodec().Unmarshal([]byte(rawSub), &id); err != nil {
+		return "", nil, false
+	}
+	key := idKey(id)
+	if key == "" {
+		return "", nil, false
+	}
+
+	resultNode, err := searcher.GetByPath("params", "result")
+	if err != nil {
+		return "", nil, false
+	}
+	rawResult, err := resultNode.Raw()
+	if err != nil {
+		return "", nil, false
+	}
+	return key, json.RawMessage(rawResult), true
+}
+
+// unsubscribeMethod derives the unsubscribe method name from a subscribe method name, returning
+// "" when the name does not end in "subscribe".
+func unsubscribeMethod(method string) string {
+	if prefix, ok := strings.CutSuffix(method, "Subscribe"); ok {
+		return prefix + "Unsubscribe"
+	}
+	if prefix, ok := strings.CutSuffix(method, "subscribe"); ok {
+		return prefix + "unsubscribe"
+	}
+	return ""
+}
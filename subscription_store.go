@@ -0,0 +1,143 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// subscriptionFilePerm is the permission of the files written by FileSubscriptionStore.
+const subscriptionFilePerm = 0o600
+
+// MemorySubscriptionStore is a SubscriptionStore keeping subscriptions in memory, so that they
+// survive a new Proxy in the same process but not restarts.
+type MemorySubscriptionStore struct {
+	mu   sync.Mutex
+	subs map[string]ProxySubscription
+}
+
+// NewMemorySubscriptionStore returns an empty MemorySubscriptionStore.
+func NewMemorySubscriptionStore() *MemorySubscriptionStore {
+	return &MemorySubscriptionStore{subs: make(map[string]ProxySubscription)}
+}
+
+// Save implements SubscriptionStore.
+func (s *MemorySubscriptionStore) Save(sub ProxySubscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+	return nil
+}
+
+// Delete implements SubscriptionStore.
+func (s *MemorySubscriptionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, id)
+	return nil
+}
+
+// Load implements SubscriptionStore, returning the subscriptions ordered by ID.
+func (s *MemorySubscriptionStore) Load() ([]ProxySubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedSubscriptions(s.subs), nil
+}
+
+// FileSubscriptionStore is a SubscriptionStore keeping subscriptions in a JSON file, rewritten
+// whole on every change through a temporary file, so that a crash leaves either version whole.
+type FileSubscriptionStore struct {
+	path string
+	mu   sync.Mutex
+	subs map[string]ProxySubscription
+}
+
+// OpenFileSubscriptionStore returns a store of the subscriptions in the file at path, which is
+// created on the first change if it does not exist.
+func OpenFileSubscriptionStore(path string) (*FileSubscriptionStore, error) {
+	s := &FileSubscriptionStore{path: path, subs: make(map[string]ProxySubscription)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subs []ProxySubscription
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &subs); err != nil {
+			return nil, err
+		}
+	}
+	for _, sub := range subs {
+		s.subs[sub.ID] = sub
+	}
+	return s, nil
+}
+
+// Save implements SubscriptionStore.
+func (s *FileSubscriptionStore) Save(sub ProxySubscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, had := s.subs[sub.ID]
+	s.subs[sub.ID] = sub
+	if err := s.write(); err != nil {
+		if had {
+			s.subs[sub.ID] = previous
+		} else {
+			delete(s.subs, sub.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete implements SubscriptionStore.
+func (s *FileSubscriptionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, had := s.subs[id]
+	if !had {
+		return nil
+	}
+	delete(s.subs, id)
+	if err := s.write(); err != nil {
+		s.subs[id] = previous
+		return err
+	}
+	return nil
+}
+
+// Load implements SubscriptionStore, returning the subscriptions ordered by ID.
+func (s *FileSubscriptionStore) Load() ([]ProxySubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedSubscriptions(s.subs), nil
+}
+
+// write replaces the file with the subscriptions of the store.
+func (s *FileSubscriptionStore) write() error {
+	data, err := json.MarshalIndent(sortedSubscriptions(s.subs), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), subscriptionFilePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// sortedSubscriptions returns the subscriptions of subs ordered by ID.
+func sortedSubscriptions(subs map[string]ProxySubscription) []ProxySubscription {
+	sorted := make([]ProxySubscription, 0, len(subs))
+	for _, id := range slices.Sorted(maps.Keys(subs)) {
+		sorted = append(sorted, subs[id])
+	}
+	return sorted
+}
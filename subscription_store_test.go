@@ -0,0 +1,80 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionStores(t *testing.T) {
+	b := ProxySubscription{ID: "b", Session: "s", Method: "eth_subscribe"}
+	a := ProxySubscription{
+		ID:      "a",
+		Session: "s",
+		Method:  "eth_subscribe",
+		Params:  json.RawMessage(`["logs"]`),
+	}
+
+	for name, open := range map[string]func(t *testing.T, path string) SubscriptionStore{
+		"Memory": func(*testing.T, string) SubscriptionStore {
+			return NewMemorySubscriptionStore()
+		},
+		"File": func(t *testing.T, path string) SubscriptionStore {
+			store, err := OpenFileSubscriptionStore(path)
+			require.NoError(t, err)
+			return store
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := open(t, filepath.Join(t.TempDir(), "subs.json"))
+			require.NoError(t, store.Save(b))
+			require.NoError(t, store.Save(a))
+			a.UpstreamID = "0x2"
+			require.NoError(t, store.Save(a))
+			require.NoError(t, store.Delete("missing"))
+
+			saved, err := store.Load()
+			require.NoError(t, err)
+			require.Len(t, saved, 2)
+			assert.Equal(t, "a", saved[0].ID)
+			assert.Equal(t, "0x2", saved[0].UpstreamID)
+
+			require.NoError(t, store.Delete("b"))
+			saved, err = store.Load()
+			require.NoError(t, err)
+			assert.Len(t, saved, 1)
+		})
+	}
+
+	t.Run("File stores survive restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "subs.json")
+		store, err := OpenFileSubscriptionStore(path)
+		require.NoError(t, err)
+		require.NoError(t, store.Save(a))
+
+		reopened, err := OpenFileSubscriptionStore(path)
+		require.NoError(t, err)
+		saved, err := reopened.Load()
+		require.NoError(t, err)
+		require.Len(t, saved, 1)
+		assert.JSONEq(t, `["logs"]`, string(saved[0].Params))
+	})
+
+	t.Run("Invalid files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "subs.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+		_, err := OpenFileSubscriptionStore(path)
+		require.Error(t, err)
+
+		store, err := OpenFileSubscriptionStore(filepath.Join(t.TempDir(), "missing", "subs.json"))
+		require.NoError(t, err)
+		require.Error(t, store.Save(a), "the directory does not exist")
+		saved, err := store.Load()
+		require.NoError(t, err)
+		assert.Empty(t, saved, "failed saves are rolled back")
+	})
+}
@@ -0,0 +1,214 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subscriptionPeer is the server side of a subscription test, publishing on subscription "0xa".
+type subscriptionPeer struct {
+	peers        chan *Client
+	unsubscribed chan []any
+}
+
+// newSubscriptionPeer serves eth_subscribe and eth_unsubscribe on one end of a stream pair and
+// returns a stream client on the other end. The subscribe handler publishes one notification
+// before replying.
+func newSubscriptionPeer(t *testing.T, opts ...ClientOption) (*subscriptionPeer, *Client) {
+	t.Helper()
+	p := &subscriptionPeer{
+		peers:        make(chan *Client, 1),
+		unsubscribed: make(chan []any, 1),
+	}
+
+	srv := NewServer()
+	subscribe := func(ctx context.Context, _ *Request) (any, error) {
+		peer, _ := ClientFromContext(ctx)
+		if err := publish(ctx, peer, "0xa", 0); err != nil {
+			return nil, err
+		}
+		p.peers <- peer
+		return "0xa", nil
+	}
+	unsubscribe := func(_ context.Context, req *Request) (any, error) {
+		var params []any
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		p.unsubscribed <- params
+		return true, nil
+	}
+	require.NoError(t, srv.RegisterFunc("eth_subscribe", subscribe))
+	require.NoError(t, srv.RegisterFunc("eth_unsubscribe", unsubscribe))
+
+	clientEnd, serverEnd := newStreamPair()
+	go func() { _ = srv.ServeStream(context.Background(), serverEnd) }()
+
+	client := NewStreamClient(clientEnd, opts...)
+	t.Cleanup(func() { _ = client.Close() })
+	return p, client
+}
+
+// publish sends a subscription notification carrying value.
+func publish(ctx context.Context, peer *Client, sub string, value int) error {
+	params := map[string]any{"subscription": sub, "result": value}
+	return peer.Notify(ctx, "eth_subscription", params)
+}
+
+// receive reads the next payload from ch as an int.
+func receive(t *testing.T, ch <-chan json.RawMessage) int {
+	t.Helper()
+	select {
+	case raw := <-ch:
+		var v int
+		require.NoError(t, json.Unmarshal(raw, &v))
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+		return 0
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	t.Run("Forwards notifications in order", func(t *testing.T) {
+		p, client := newSubscriptionPeer(t)
+
+		ch := make(chan json.RawMessage)
+		sub, err := client.Subscribe(context.Background(), "eth_subscribe", []any{"newHeads"}, ch)
+		require.NoError(t, err)
+		assert.Equal(t, "0xa", sub.ID())
+
+		peer := <-p.peers
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, publish(context.Background(), peer, "0xa", i))
+		}
+		for i := 0; i <= 3; i++ {
+			assert.Equal(t, i, receive(t, ch))
+		}
+	})
+
+	t.Run("Context cancellation unsubscribes", func(t *testing.T) {
+		p, client := newSubscriptionPeer(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan json.RawMessage, 1)
+		sub, err := client.Subscribe(ctx, "eth_subscribe", nil, ch)
+		require.NoError(t, err)
+		cancel()
+
+		select {
+		case params := <-p.unsubscribed:
+			assert.Equal(t, []any{"0xa"}, params)
+		case <-time.After(time.Second):
+			t.Fatal("unsubscribe not called")
+		}
+		_, open := <-sub.Err()
+		assert.False(t, open)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		p, client := newSubscriptionPeer(t)
+
+		ch := make(chan json.RawMessage, 1)
+		sub, err := client.Subscribe(context.Background(), "eth_subscribe", nil, ch)
+		require.NoError(t, err)
+		require.NoError(t, sub.Unsubscribe(context.Background()))
+		require.NoError(t, sub.Unsubscribe(context.Background()))
+
+		assert.Equal(t, []any{"0xa"}, <-p.unsubscribed)
+		_, open := <-sub.Err()
+		assert.False(t, open)
+	})
+
+	t.Run("Client shutdown is reported on Err", func(t *testing.T) {
+		_, client := newSubscriptionPeer(t)
+
+		ch := make(chan json.RawMessage, 1)
+		sub, err := client.Subscribe(context.Background(), "eth_subscribe", nil, ch)
+		require.NoError(t, err)
+		require.NoError(t, client.Close())
+
+		select {
+		case err := <-sub.Err():
+			require.ErrorIs(t, err, ErrClientClosed)
+		case <-time.After(time.Second):
+			t.Fatal("subscription did not end")
+		}
+	})
+
+	t.Run("Unknown subscriptions reach the client server", func(t *testing.T) {
+		received := make(chan string, 1)
+		clientSrv := NewServer()
+		handle := func(_ context.Context, req *Request) (any, error) {
+			received <- req.Method
+			return nil, nil
+		}
+		require.NoError(t, clientSrv.RegisterFunc("eth_subscription", handle))
+		p, client := newSubscriptionPeer(t, WithServer(clientSrv))
+
+		ch := make(chan json.RawMessage, 1)
+		_, err := client.Subscribe(context.Background(), "eth_subscribe", nil, ch)
+		require.NoError(t, err)
+
+		require.NoError(t, publish(context.Background(), <-p.peers, "0xb", 1))
+		select {
+		case method := <-received:
+			assert.Equal(t, "eth_subscription", method)
+		case <-time.After(time.Second):
+			t.Fatal("notification not handled by the client server")
+		}
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		_, client := newSubscriptionPeer(t)
+
+		ch := make(chan json.RawMessage, 1)
+		_, err := client.Subscribe(context.Background(), "bogus_subscribe", nil, ch)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+	})
+
+	t.Run("Requires a stream client", func(t *testing.T) {
+		client := NewClient(&funcTransport{})
+		ch := make(chan json.RawMessage, 1)
+		_, err := client.Subscribe(context.Background(), "eth_subscribe", nil, ch)
+		require.ErrorIs(t, err, errSubscriptionsUnsupported)
+	})
+
+	t.Run("Underivable unsubscribe method", func(t *testing.T) {
+		_, client := newSubscriptionPeer(t)
+		ch := make(chan json.RawMessage, 1)
+		_, err := client.Subscribe(context.Background(), "watch", nil, ch)
+		require.Error(t, err)
+	})
+}
+
+func TestUnsubscribeMethod(t *testing.T) {
+	assert.Equal(t, "eth_unsubscribe", unsubscribeMethod("eth_subscribe"))
+	assert.Equal(t, "accountUnsubscribe", unsubscribeMethod("accountSubscribe"))
+	assert.Equal(t, "", unsubscribeMethod("watch"))
+}
+
+func TestParseSubscriptionNotification(t *testing.T) {
+	msg := `{"jsonrpc":"2.0","method":"s","params":{"subscription":7,"result":{"a":1}}}`
+	key, result, ok := parseSubscriptionNotification([]byte(msg))
+	require.True(t, ok)
+	assert.Equal(t, idKey(int64(7)), key)
+	assert.JSONEq(t, `{"a":1}`, string(result))
+
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"s","params":{"subscription":7,"result":1}}`,
+		`{"jsonrpc":"2.0","method":"s","params":{"result":1}}`,
+		`{"jsonrpc":"2.0","method":"s","params":[7,1]}`,
+		`[{"jsonrpc":"2.0","method":"s","params":{"subscription":7,"result":1}}]`,
+	} {
+		_, _, ok := parseSubscriptionNotification([]byte(msg))
+		assert.False(t, ok, msg)
+	}
+}
@@ -0,0 +1,144 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+)
+
+// tenantContextKey is the context key of the tenant a request was routed to.
+type tenantContextKey struct{}
+
+// TenantRouter is an http.Handler hosting several independent servers behind one listener, each
+// serving a tenant with its own methods, middleware, authentication, and limits. By default a
+// request goes to the tenant registered under the longest prefix of its URL path, in whole
+// segments, so that "/tenantA" also serves "/tenantA/rpc"; with WithTenantHeader, it goes to the
+// tenant named by a header instead. Requests matching no tenant go to the default server, if one
+// is set, and are answered with 404 otherwise.
+//
+// Tenants may be added and removed while serving. A TenantRouter is safe for concurrent use.
+type TenantRouter struct {
+	header   string
+	fallback *Server
+
+	mu      sync.RWMutex
+	tenants map[string]*Server
+}
+
+// TenantOption configures a TenantRouter.
+type TenantOption func(*TenantRouter)
+
+// WithTenantHeader routes requests by the value of the named header, such as "X-Tenant", rather
+// than by URL path.
+func WithTenantHeader(name string) TenantOption {
+	return func(r *TenantRouter) {
+		r.header = name
+	}
+}
+
+// WithDefaultTenant sets the server of the requests matching no tenant.
+func WithDefaultTenant(srv *Server) TenantOption {
+	return func(r *TenantRouter) {
+		r.fallback = srv
+	}
+}
+
+// NewTenantRouter creates a TenantRouter without tenants.
+func NewTenantRouter(opts ...TenantOption) *TenantRouter {
+	r := &TenantRouter{tenants: make(map[string]*Server)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Handle makes srv serve tenant, replacing any server already serving it. Tenants are URL paths,
+// such as "/v1", or header values with WithTenantHeader.
+func (r *TenantRouter) Handle(tenant string, srv *Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants[r.key(tenant)] = srv
+}
+
+// Remove stops serving tenant, reporting whether it was served.
+func (r *TenantRouter) Remove(tenant string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.key(tenant)
+	_, ok := r.tenants[key]
+	delete(r.tenants, key)
+	return ok
+}
+
+// Server returns the server of tenant, if it is served.
+func (r *TenantRouter) Server(tenant string) (*Server, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	srv, ok := r.tenants[r.key(tenant)]
+	return srv, ok
+}
+
+// Tenants returns the served tenants, sorted.
+func (r *TenantRouter) Tenants() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenants := make([]string, 0, len(r.tenants))
+	for tenant := range r.tenants {
+		tenants = append(tenants, tenant)
+	}
+	slices.Sort(tenants)
+	return tenants
+}
+
+// ServeHTTP routes the request to the server of its tenant, whose name is then available to
+// handlers through TenantFromContext.
+func (r *TenantRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	tenant, srv := r.route(req)
+	if srv == nil {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+	if tenant == "" {
+		srv.ServeHTTP(w, req)
+		return
+	}
+	srv.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), tenantContextKey{}, tenant)))
+}
+
+// route returns the tenant of req and its server, or the default server with an empty tenant.
+func (r *TenantRouter) route(req *http.Request) (string, *Server) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.header != "" {
+		tenant := req.Header.Get(r.header)
+		if srv, ok := r.tenants[tenant]; ok && tenant != "" {
+			return tenant, srv
+		}
+		return "", r.fallback
+	}
+
+	for prefix := r.key(req.URL.Path); ; prefix = path.Dir(prefix) {
+		if srv, ok := r.tenants[prefix]; ok {
+			return prefix, srv
+		}
+		if prefix == "/" {
+			return "", r.fallback
+		}
+	}
+}
+
+// key returns the key tenant is stored under: header values as they are, and paths cleaned.
+func (r *TenantRouter) key(tenant string) string {
+	if r.header != "" {
+		return tenant
+	}
+	return path.Clean("/" + tenant)
+}
+
+// TenantFromContext returns the tenant a TenantRouter routed the request to, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok
+}
@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTenantServer returns a server answering the whoami method with its name and the tenant of
+// the call.
+func newTenantServer(t *testing.T, name string) *Server {
+	t.Helper()
+	srv := NewServer()
+	whoami := func(ctx context.Context, _ *Request) (any, error) {
+		tenant, _ := TenantFromContext(ctx)
+		return name + ":" + tenant, nil
+	}
+	require.NoError(t, srv.RegisterFunc("whoami", whoami))
+	return srv
+}
+
+// postWhoami posts a whoami call to the handler at target, returning the status and body.
+func postWhoami(h http.Handler, target string, header http.Header) (int, string) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"whoami"}`
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestTenantRouter(t *testing.T) {
+	t.Run("Routes by path", func(t *testing.T) {
+		router := NewTenantRouter()
+		router.Handle("/v1", newTenantServer(t, "one"))
+		router.Handle("tenantA/", newTenantServer(t, "a"))
+
+		cases := map[string]string{
+			"/v1":           "one:/v1",
+			"/v1/":          "one:/v1",
+			"/tenantA/rpc":  "a:/tenantA",
+			"/tenantA//x/y": "a:/tenantA",
+		}
+		for target, want := range cases {
+			code, body := postWhoami(router, target, nil)
+			assert.Equal(t, http.StatusOK, code, target)
+			assert.Contains(t, body, `"result":"`+want+`"`, target)
+		}
+
+		code, _ := postWhoami(router, "/v2", nil)
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = postWhoami(router, "/tenantAB", nil)
+		assert.Equal(t, http.StatusNotFound, code, "prefixes match whole segments")
+		assert.Equal(t, []string{"/tenantA", "/v1"}, router.Tenants())
+	})
+
+	t.Run("Routes by header", func(t *testing.T) {
+		router := NewTenantRouter(WithTenantHeader("X-Tenant"),
+			WithDefaultTenant(newTenantServer(t, "default")))
+		router.Handle("acme", newTenantServer(t, "acme"))
+
+		_, body := postWhoami(router, "/", http.Header{"X-Tenant": {"acme"}})
+		assert.Contains(t, body, `"result":"acme:acme"`)
+		_, body = postWhoami(router, "/acme", http.Header{"X-Tenant": {"other"}})
+		assert.Contains(t, body, `"result":"default:"`)
+		_, body = postWhoami(router, "/", nil)
+		assert.Contains(t, body, `"result":"default:"`)
+	})
+
+	t.Run("Tenants are isolated", func(t *testing.T) {
+		router := NewTenantRouter()
+		router.Handle("/a", newTenantServer(t, "a"))
+		router.Handle("/b", NewServer())
+
+		_, body := postWhoami(router, "/b", nil)
+		assert.Contains(t, body, `"code":-32601`)
+	})
+
+	t.Run("Tenants change while serving", func(t *testing.T) {
+		router := NewTenantRouter()
+		first := newTenantServer(t, "first")
+		router.Handle("/a", first)
+		srv, ok := router.Server("/a/")
+		require.True(t, ok)
+		assert.Same(t, first, srv)
+
+		router.Handle("/a", newTenantServer(t, "second"))
+		_, body := postWhoami(router, "/a", nil)
+		assert.Contains(t, body, `"result":"second:/a"`)
+
+		assert.True(t, router.Remove("/a"))
+		assert.False(t, router.Remove("/a"))
+		code, _ := postWhoami(router, "/a", nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Root tenant catches every path", func(t *testing.T) {
+		router := NewTenantRouter()
+		router.Handle("/", newTenantServer(t, "root"))
+		_, body := postWhoami(router, "/any/path", nil)
+		assert.Contains(t, body, `"result":"root:/"`)
+	})
+}
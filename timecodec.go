@@ -0,0 +1,712 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeFormat selects the JSON encoding of time.Time values.
+type TimeFormat int
+
+const (
+	// TimeRFC3339 encodes times as RFC 3339 strings with nanoseconds, as encoding/json does.
+	TimeRFC3339 TimeFormat = iota
+	// TimeUnix encodes times as seconds since the Unix epoch, with a decimal fraction for times
+	// with sub-second precision.
+	TimeUnix
+	// TimeUnixMilli encodes times as whole milliseconds since the Unix epoch, as JavaScript's
+	// Date.now does.
+	TimeUnixMilli
+)
+
+// DurationFormat selects the JSON encoding of time.Duration values.
+type DurationFormat int
+
+const (
+	// DurationNanoseconds encodes durations as integer nanoseconds, as encoding/json does.
+	DurationNanoseconds DurationFormat = iota
+	// DurationSeconds encodes durations as seconds, with a decimal fraction if needed.
+	DurationSeconds
+	// DurationMilliseconds encodes durations as milliseconds, with a decimal fraction if needed.
+	DurationMilliseconds
+	// DurationISO8601 encodes durations as ISO 8601 strings in hours, minutes and seconds, such
+	// as "PT1H30M" or "-PT0.5S".
+	DurationISO8601
+)
+
+// TimeEncoding is the encoding of the times and durations of a codec returned by NewTimeCodec.
+// The zero value encodes them as encoding/json does.
+type TimeEncoding struct {
+	Time     TimeFormat
+	Duration DurationFormat
+}
+
+const (
+	// nanoDigits is the number of decimal digits of nanoseconds in a second.
+	nanoDigits = 9
+	// hoursPerDay and daysPerWeek convert the date components of ISO 8601 durations.
+	hoursPerDay = 24
+	daysPerWeek = 7
+
+	// minus is the sign of negative numbers and durations.
+	minus = "-"
+
+	// msgInvalidISODuration is the format of the errors of malformed ISO 8601 durations.
+	msgInvalidISODuration = "invalid ISO 8601 duration %q"
+)
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+	// timeTypesCache maps types to whether they hold times, and jsonFieldsCache maps struct
+	// types to their fields.
+	timeTypesCache  sync.Map
+	jsonFieldsCache sync.Map
+)
+
+// timeCodec implements Codec with encoding/json, rewriting times and durations.
+type timeCodec struct {
+	enc TimeEncoding
+}
+
+// NewTimeCodec returns a Codec backed by encoding/json that encodes the time.Time and
+// time.Duration values found in params and results with enc, such as for peers expecting Unix
+// timestamps or ISO 8601 durations. It is selected per client with WithClientCodec, per server
+// with WithServerCodec, or per call with WithCallCodec.
+//
+// Decoding accepts any of the encodings regardless of enc: times as RFC 3339 strings or Unix
+// timestamps, in milliseconds under TimeUnixMilli and in seconds otherwise, and durations as
+// numbers in the unit of enc, in seconds for DurationISO8601, or as ISO 8601 or Go duration
+// strings. Values are found through the static types of struct fields, slices and maps, and
+// through the dynamic values of interfaces when encoding; types with their own JSON or text
+// encoding are left to it.
+func NewTimeCodec(enc TimeEncoding) Codec {
+	return &timeCodec{enc: enc}
+}
+
+// Marshal implements Codec.
+func (c *timeCodec) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || c.enc == (TimeEncoding{}) || !holdsTime(reflect.TypeOf(v)) {
+		return data, err
+	}
+	tree, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.encodeNode(tree, reflect.ValueOf(v)))
+}
+
+// Unmarshal implements Codec.
+func (c *timeCodec) Unmarshal(data []byte, v any) error {
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Kind() != reflect.Pointer || !holdsTime(typ.Elem()) {
+		return unmarshalStd(data, v)
+	}
+	tree, err := decodeTree(data)
+	if err != nil {
+		// Let unmarshalStd describe the syntax error
+		return unmarshalStd(data, v)
+	}
+	tree, err = c.decodeNode(tree, typ.Elem())
+	if err != nil {
+		return err
+	}
+	normalized, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return unmarshalStd(normalized, v)
+}
+
+// Valid implements Codec.
+func (*timeCodec) Valid(data []byte) bool {
+	return json.Valid(data)
+}
+
+// decodeTree decodes data into generic values, keeping numbers as json.Number.
+func decodeTree(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// encodeNode returns node, the encoding of value, with the times and durations of value
+// re-encoded.
+func (c *timeCodec) encodeNode(node any, value reflect.Value) any {
+	v := value
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return node
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || !v.CanInterface() {
+		return node
+	}
+	switch typ := v.Type(); {
+	case typ == timeType:
+		return c.encodeTime(v.Interface().(time.Time))
+	case typ == durationType:
+		return c.encodeDuration(time.Duration(v.Int()))
+	case customJSON(typ):
+		return node
+	default:
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		c.encodeStruct(node, v)
+	case reflect.Slice, reflect.Array:
+		if elems, ok := node.([]any); ok {
+			for i := range min(len(elems), v.Len()) {
+				elems[i] = c.encodeNode(elems[i], v.Index(i))
+			}
+		}
+	case reflect.Map:
+		c.encodeMap(node, v)
+	default:
+	}
+	return node
+}
+
+// encodeStruct re-encodes the members of node, the encoding of the struct v.
+func (c *timeCodec) encodeStruct(node any, v reflect.Value) {
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return
+	}
+	for _, field := range jsonFields(v.Type()) {
+		child, ok := obj[field.name]
+		if !ok {
+			continue
+		}
+		if fv, ok := fieldValue(v, field.index); ok {
+			obj[field.name] = c.encodeNode(child, fv)
+		}
+	}
+}
+
+// encodeMap re-encodes the members of node, the encoding of the map v.
+func (c *timeCodec) encodeMap(node any, v reflect.Value) {
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return
+	}
+	iter := v.MapRange()
+	for iter.Next() {
+		key, ok := mapKey(iter.Key())
+		if !ok {
+			continue
+		}
+		if child, ok := obj[key]; ok {
+			obj[key] = c.encodeNode(child, iter.Value())
+		}
+	}
+}
+
+// decodeNode returns node with its times and durations, as found through the target type,
+// converted to the encodings of encoding/json.
+func (c *timeCodec) decodeNode(node any, target reflect.Type) (any, error) {
+	typ := target
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if node == nil {
+		return nil, nil
+	}
+	switch {
+	case typ == timeType:
+		return c.decodeTime(node)
+	case typ == durationType:
+		return c.decodeDuration(node)
+	case customJSON(typ):
+		return node, nil
+	default:
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		return node, c.decodeStruct(node, typ)
+	case reflect.Slice, reflect.Array:
+		elems, ok := node.([]any)
+		if !ok {
+			return node, nil
+		}
+		for i, elem := range elems {
+			decoded, err := c.decodeNode(elem, typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = decoded
+		}
+		return elems, nil
+	case reflect.Map:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return node, nil
+		}
+		for key, child := range obj {
+			decoded, err := c.decodeNode(child, typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = decoded
+		}
+		return obj, nil
+	default:
+		return node, nil
+	}
+}
+
+// decodeStruct converts the members of node matching the fields of the struct type typ, by name
+// or case-insensitively as encoding/json does.
+func (c *timeCodec) decodeStruct(node any, typ reflect.Type) error {
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return nil
+	}
+	fields := jsonFields(typ)
+	for key, child := range obj {
+		index := slices.IndexFunc(fields, func(f jsonField) bool { return f.name == key })
+		if index < 0 {
+			index = slices.IndexFunc(fields, func(f jsonField) bool {
+				return strings.EqualFold(f.name, key)
+			})
+		}
+		if index < 0 {
+			continue
+		}
+		decoded, err := c.decodeNode(child, fields[index].typ)
+		if err != nil {
+			return err
+		}
+		obj[key] = decoded
+	}
+	return nil
+}
+
+// encodeTime returns the encoding of t.
+func (c *timeCodec) encodeTime(t time.Time) any {
+	switch c.enc.Time {
+	case TimeUnix:
+		return json.Number(formatUnix(t.Unix(), int64(t.Nanosecond())))
+	case TimeUnixMilli:
+		return json.Number(strconv.FormatInt(t.UnixMilli(), decimal))
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// decodeTime converts a Unix timestamp to an RFC 3339 string. Strings are left for time.Time
+// to parse.
+func (c *timeCodec) decodeTime(node any) (any, error) {
+	num, ok := node.(json.Number)
+	if !ok {
+		return node, nil
+	}
+	if c.enc.Time == TimeUnixMilli {
+		ms, frac, err := parseFixed(string(num), unitDigits(time.Millisecond))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %s: %w", num, err)
+		}
+		return time.UnixMilli(ms).Add(time.Duration(frac)).Format(time.RFC3339Nano), nil
+	}
+	sec, nsec, err := parseFixed(string(num), nanoDigits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %s: %w", num, err)
+	}
+	return time.Unix(sec, nsec).Format(time.RFC3339Nano), nil
+}
+
+// encodeDuration returns the encoding of d.
+func (c *timeCodec) encodeDuration(d time.Duration) any {
+	switch c.enc.Duration {
+	case DurationSeconds:
+		return json.Number(formatUnits(d, time.Second))
+	case DurationMilliseconds:
+		return json.Number(formatUnits(d, time.Millisecond))
+	case DurationISO8601:
+		return formatISODuration(d)
+	default:
+		return json.Number(strconv.FormatInt(int64(d), decimal))
+	}
+}
+
+// decodeDuration converts a duration to integer nanoseconds.
+func (c *timeCodec) decodeDuration(node any) (any, error) {
+	var d time.Duration
+	var err error
+	switch value := node.(type) {
+	case string:
+		if strings.HasPrefix(strings.TrimPrefix(value, minus), "P") {
+			d, err = parseISODuration(value)
+		} else {
+			d, err = time.ParseDuration(value)
+		}
+	case json.Number:
+		d, err = parseUnits(string(value), c.durationUnit())
+	default:
+		return node, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Number(strconv.FormatInt(int64(d), decimal)), nil
+}
+
+// durationUnit returns the unit of durations encoded as numbers.
+func (c *timeCodec) durationUnit() time.Duration {
+	switch c.enc.Duration {
+	case DurationSeconds, DurationISO8601:
+		return time.Second
+	case DurationMilliseconds:
+		return time.Millisecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+// formatUnix returns the decimal seconds of the Unix time sec and nsec, with nsec in [0, 1e9).
+func formatUnix(sec, nsec int64) string {
+	if nsec == 0 {
+		return strconv.FormatInt(sec, decimal)
+	}
+	if sec < 0 {
+		// -1.5 is -2 seconds and 5e8 nanoseconds
+		return minus + strconv.FormatInt(-sec-1, decimal) +
+			fraction(uint64(int64(time.Second)-nsec), nanoDigits)
+	}
+	return strconv.FormatInt(sec, decimal) + fraction(uint64(nsec), nanoDigits)
+}
+
+// formatUnits returns d as a decimal number of unit.
+func formatUnits(d, unit time.Duration) string {
+	sign := ""
+	n := uint64(d)
+	if d < 0 {
+		sign, n = minus, -n
+	}
+	u := uint64(unit)
+	s := sign + strconv.FormatUint(n/u, decimal)
+	if rem := n % u; rem != 0 {
+		s += fraction(rem, unitDigits(unit))
+	}
+	return s
+}
+
+// formatISODuration returns d as an ISO 8601 duration in hours, minutes and seconds.
+func formatISODuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var b []byte
+	n := uint64(d)
+	if d < 0 {
+		b = append(b, '-')
+		n = -n
+	}
+	b = append(b, "PT"...)
+	for _, part := range []struct {
+		unit       time.Duration
+		designator byte
+	}{{time.Hour, 'H'}, {time.Minute, 'M'}} {
+		if count := n / uint64(part.unit); count > 0 {
+			b = strconv.AppendUint(b, count, decimal)
+			b = append(b, part.designator)
+		}
+		n %= uint64(part.unit)
+	}
+	if n > 0 {
+		b = append(b, formatUnits(time.Duration(n), time.Second)...)
+		b = append(b, 'S')
+	}
+	return string(b)
+}
+
+// fraction returns the decimal fraction of n in units of 10^-digits, without trailing zeros.
+func fraction(n uint64, digits int) string {
+	s := strconv.FormatUint(n, decimal)
+	s = strings.Repeat("0", digits-len(s)) + s
+	return "." + strings.TrimRight(s, "0")
+}
+
+// unitDigits returns the number of decimal digits of nanoseconds in unit, a power of ten.
+func unitDigits(unit time.Duration) int {
+	return len(strconv.FormatInt(int64(unit), decimal)) - 1
+}
+
+// parseUnits parses a decimal number of unit.
+func parseUnits(s string, unit time.Duration) (time.Duration, error) {
+	whole, frac, err := parseFixed(s, unitDigits(unit))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %s: %w", s, err)
+	}
+	if whole > math.MaxInt64/int64(unit) || whole < math.MinInt64/int64(unit) {
+		return 0, fmt.Errorf("invalid duration %s: out of range", s)
+	}
+	return time.Duration(whole)*unit + time.Duration(frac), nil
+}
+
+// parseFixed parses the decimal number s into its integer part and its fraction in units of
+// 10^-digits, both carrying the sign of s. Digits beyond the precision are truncated.
+func parseFixed(s string, digits int) (whole, frac int64, err error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		w := math.Trunc(f)
+		return int64(w), int64(math.Round((f - w) * math.Pow10(digits))), nil
+	}
+	unsigned, neg := strings.CutPrefix(s, minus)
+	intPart, fracPart, _ := strings.Cut(unsigned, ".")
+	if whole, err = strconv.ParseInt(intPart, decimal, 64); err != nil {
+		return 0, 0, err
+	}
+	if digits > 0 {
+		fracPart = fracPart[:min(len(fracPart), digits)]
+		fracPart += strings.Repeat("0", digits-len(fracPart))
+		if frac, err = strconv.ParseInt(fracPart, decimal, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	if neg {
+		whole, frac = -whole, -frac
+	}
+	return whole, frac, nil
+}
+
+// parseISODuration parses an ISO 8601 duration in weeks, days, hours, minutes and seconds, with
+// an optional sign. Years and months, which have no fixed length, are rejected.
+func parseISODuration(s string) (time.Duration, error) {
+	rest, neg := strings.CutPrefix(s, minus)
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf(msgInvalidISODuration, s)
+	}
+	var d time.Duration
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' && !inTime {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		i := strings.IndexAny(rest, "WDHMS")
+		if i <= 0 {
+			return 0, fmt.Errorf(msgInvalidISODuration, s)
+		}
+		unit, ok := isoUnit(rest[i], inTime)
+		if !ok {
+			return 0, fmt.Errorf(msgInvalidISODuration, s)
+		}
+		whole, frac, err := parseFixed(rest[:i], nanoDigits)
+		if err != nil {
+			return 0, fmt.Errorf(msgInvalidISODuration, s)
+		}
+		d += time.Duration(whole)*unit + time.Duration(frac)*(unit/time.Second)
+		rest = rest[i+1:]
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// isoUnit returns the length of the ISO 8601 duration designator c, in the date part or, when
+// inTime, the time part of a duration.
+func isoUnit(c byte, inTime bool) (time.Duration, bool) {
+	switch {
+	case !inTime && c == 'W':
+		return daysPerWeek * hoursPerDay * time.Hour, true
+	case !inTime && c == 'D':
+		return hoursPerDay * time.Hour, true
+	case inTime && c == 'H':
+		return time.Hour, true
+	case inTime && c == 'M':
+		return time.Minute, true
+	case inTime && c == 'S':
+		return time.Second, true
+	default:
+		return 0, false
+	}
+}
+
+// jsonField is a struct field as encoding/json names it.
+type jsonField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// jsonFields returns the fields of the struct type typ that encoding/json encodes, with the
+// fields of embedded structs promoted. Fields encoded as strings with the ",string" option are
+// omitted.
+func jsonFields(typ reflect.Type) []jsonField {
+	if cached, ok := jsonFieldsCache.Load(typ); ok {
+		if fields, ok := cached.([]jsonField); ok {
+			return fields
+		}
+	}
+	fields := appendJSONFields(nil, typ, nil, map[reflect.Type]bool{})
+	jsonFieldsCache.Store(typ, fields)
+	return fields
+}
+
+// appendJSONFields appends the fields of typ, found at index in the outer struct, to fields.
+// Shallower fields take precedence over promoted fields of the same name.
+func appendJSONFields(
+	outer []jsonField,
+	typ reflect.Type,
+	index []int,
+	visited map[reflect.Type]bool,
+) []jsonField {
+	if visited[typ] {
+		return outer
+	}
+	fields := outer
+	visited[typ] = true
+	var embedded []reflect.StructField
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() || slices.Contains(strings.Split(opts, ","), "string") {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if !slices.ContainsFunc(fields, func(f jsonField) bool { return f.name == name }) {
+			fields = append(fields, jsonField{
+				name:  name,
+				index: append(slices.Clone(index), i),
+				typ:   field.Type,
+			})
+		}
+	}
+	for _, field := range embedded {
+		fields = appendJSONFields(fields, derefType(field.Type),
+			append(slices.Clone(index), field.Index...), visited)
+	}
+	return fields
+}
+
+// derefType returns the element type of pointer types, and other types as they are.
+func derefType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Pointer {
+		return typ.Elem()
+	}
+	return typ
+}
+
+// fieldValue returns the field of the struct v at index, following embedded pointers, or false
+// if one of them is nil.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	field := v
+	for _, i := range index {
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				return reflect.Value{}, false
+			}
+			field = field.Elem()
+		}
+		field = field.Field(i)
+	}
+	return field, true
+}
+
+// mapKey returns the JSON member name of a map key of string or integer kind.
+func mapKey(key reflect.Value) (string, bool) {
+	switch key.Kind() {
+	case reflect.String:
+		return key.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), decimal), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), decimal), true
+	default:
+		return "", false
+	}
+}
+
+// holdsTime reports whether values of typ may hold times or durations the codec rewrites.
+// Interfaces may hold any value.
+func holdsTime(typ reflect.Type) bool {
+	if typ == nil {
+		return false
+	}
+	if cached, ok := timeTypesCache.Load(typ); ok {
+		if holds, ok := cached.(bool); ok {
+			return holds
+		}
+	}
+	holds := scanTime(typ, map[reflect.Type]bool{})
+	timeTypesCache.Store(typ, holds)
+	return holds
+}
+
+// scanTime reports whether typ holds times or durations, skipping the types in seen.
+func scanTime(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	if typ == timeType || typ == durationType {
+		return true
+	}
+	if seen[typ] || customJSON(typ) {
+		return false
+	}
+	seen[typ] = true
+	switch typ.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return scanTime(typ.Elem(), seen)
+	case reflect.Struct:
+		return slices.ContainsFunc(jsonFields(typ), func(f jsonField) bool {
+			return scanTime(f.typ, seen)
+		})
+	default:
+		return false
+	}
+}
+
+// customJSON reports whether typ has its own JSON or text encoding.
+func customJSON(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Interface {
+		return false
+	}
+	ptr := reflect.PointerTo(typ)
+	for _, iface := range []reflect.Type{
+		jsonMarshalerType, jsonUnmarshalerType, textMarshalerType, textUnmarshalerType,
+	} {
+		if typ.Implements(iface) || ptr.Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timedEvent holds times and durations in the shapes the time codec walks.
+type timedEvent struct {
+	Name    string                   `json:"name"`
+	At      time.Time                `json:"at"`
+	Timeout time.Duration            `json:"timeout"`
+	Retries []time.Duration          `json:"retries,omitempty"`
+	Expiry  *time.Time               `json:"expiry,omitempty"`
+	Windows map[string]time.Duration `json:"windows,omitempty"`
+	Quoted  time.Duration            `json:"quoted,string,omitempty"`
+	timedMeta
+}
+
+// timedMeta is embedded in timedEvent to check promoted fields.
+type timedMeta struct {
+	Created time.Time `json:"created"`
+}
+
+func TestTimeCodec(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500_000_000, time.UTC)
+
+	t.Run("Unix seconds and seconds", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{Time: TimeUnix, Duration: DurationSeconds})
+		event := timedEvent{
+			Name:      "deploy",
+			At:        at,
+			Timeout:   1500 * time.Millisecond,
+			Retries:   []time.Duration{time.Second, time.Minute},
+			Expiry:    &at,
+			Windows:   map[string]time.Duration{"grace": 250 * time.Millisecond},
+			Quoted:    time.Second,
+			timedMeta: timedMeta{Created: at.Truncate(time.Second)},
+		}
+		data, err := codec.Marshal(event)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"name": "deploy",
+			"at": 1709296200.5,
+			"timeout": 1.5,
+			"retries": [1, 60],
+			"expiry": 1709296200.5,
+			"windows": {"grace": 0.25},
+			"quoted": "1000000000",
+			"created": 1709296200
+		}`, string(data))
+
+		var decoded timedEvent
+		require.NoError(t, codec.Unmarshal(data, &decoded))
+		assert.True(t, event.At.Equal(decoded.At))
+		assert.True(t, event.Expiry.Equal(*decoded.Expiry))
+		assert.True(t, event.Created.Equal(decoded.Created))
+		assert.Equal(t, event.Timeout, decoded.Timeout)
+		assert.Equal(t, event.Retries, decoded.Retries)
+		assert.Equal(t, event.Windows, decoded.Windows)
+		assert.Equal(t, event.Quoted, decoded.Quoted)
+	})
+
+	t.Run("Unix milliseconds and milliseconds", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{Time: TimeUnixMilli, Duration: DurationMilliseconds})
+		data, err := codec.Marshal(timedEvent{At: at, Timeout: 1500 * time.Microsecond})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"","at":1709296200500,"timeout":1.5,"created":-62135596800000}`,
+			string(data))
+
+		var decoded timedEvent
+		require.NoError(t, codec.Unmarshal(data, &decoded))
+		assert.True(t, at.Equal(decoded.At))
+		assert.Equal(t, 1500*time.Microsecond, decoded.Timeout)
+	})
+
+	t.Run("ISO 8601 durations", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{Duration: DurationISO8601})
+		for d, want := range map[time.Duration]string{
+			0:                        `"PT0S"`,
+			90 * time.Minute:         `"PT1H30M"`,
+			-1500 * time.Millisecond: `"-PT1.5S"`,
+			26*time.Hour + 3*time.Minute + 4500*time.Millisecond: `"PT26H3M4.5S"`,
+		} {
+			data, err := codec.Marshal(d)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(data))
+
+			var decoded time.Duration
+			require.NoError(t, codec.Unmarshal(data, &decoded))
+			assert.Equal(t, d, decoded)
+		}
+
+		var d time.Duration
+		require.NoError(t, codec.Unmarshal([]byte(`"P1W2DT0.5S"`), &d))
+		assert.Equal(t, 9*24*time.Hour+500*time.Millisecond, d)
+		require.Error(t, codec.Unmarshal([]byte(`"P1M"`), &d), "months have no fixed length")
+		assert.Error(t, codec.Unmarshal([]byte(`"PT1X"`), &d))
+	})
+
+	t.Run("Decoding accepts every encoding", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{})
+		var event timedEvent
+		require.NoError(t, codec.Unmarshal(
+			[]byte(`{"AT":1709296200.5,"timeout":"1m30s","retries":["PT2S",3]}`), &event))
+		assert.True(t, at.Equal(event.At), "keys match case-insensitively")
+		assert.Equal(t, 90*time.Second, event.Timeout)
+		assert.Equal(t, []time.Duration{2 * time.Second, 3}, event.Retries)
+
+		var times []time.Time
+		require.NoError(t, codec.Unmarshal([]byte(`["2024-03-01T12:30:00.5Z",-1.5]`), &times))
+		assert.True(t, at.Equal(times[0]))
+		assert.True(t, time.Unix(-2, 500_000_000).Equal(times[1]))
+
+		assert.Error(t, codec.Unmarshal([]byte(`{"timeout":"soon"}`), &event))
+	})
+
+	t.Run("Interfaces are encoded by their values", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{Time: TimeUnix, Duration: DurationSeconds})
+		data, err := codec.Marshal([]any{at.Truncate(time.Second), 2 * time.Second, "x", 3})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[1709296200, 2, "x", 3]`, string(data))
+
+		data, err = codec.Marshal(map[string]any{"n": uint64(12345678901234567890)})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"n":12345678901234567890}`, string(data), "numbers are kept exact")
+	})
+
+	t.Run("Negative Unix times", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{Time: TimeUnix})
+		data, err := codec.Marshal(time.Unix(-2, 500_000_000))
+		require.NoError(t, err)
+		assert.Equal(t, "-1.5", string(data))
+
+		data, err = codec.Marshal(time.Unix(-1, 500_000_000))
+		require.NoError(t, err)
+		assert.Equal(t, "-0.5", string(data))
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		codec := NewTimeCodec(TimeEncoding{Time: TimeUnix})
+		var event timedEvent
+		require.Error(t, codec.Unmarshal([]byte(`{"at":`), &event))
+		assert.False(t, codec.Valid([]byte(`{"at":`)))
+	})
+}
+
+func TestClientAndServerCodecs(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	type scheduleParams struct {
+		At    time.Time     `json:"at"`
+		Delay time.Duration `json:"delay"`
+	}
+	server := NewServer(WithServerCodec(
+		NewTimeCodec(TimeEncoding{Time: TimeUnixMilli, Duration: DurationMilliseconds})))
+	require.NoError(t, server.RegisterFunc("schedule", func(
+		_ context.Context,
+		req *Request,
+	) (any, error) {
+		params, err := DecodeParams[scheduleParams](req)
+		if err != nil {
+			return nil, err
+		}
+		return params.At.Add(params.Delay), nil
+	}))
+
+	var wire []string
+	transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		wire = append(wire, string(payload))
+		resp := server.HandleMessage(ctx, payload)
+		wire = append(wire, string(resp))
+		return resp, nil
+	}}
+	client := NewClient(transport, WithClientCodec(
+		NewTimeCodec(TimeEncoding{Time: TimeUnixMilli, Duration: DurationMilliseconds})))
+
+	got, err := Call[time.Time](ctx, client, "schedule",
+		scheduleParams{At: at, Delay: 2 * time.Second})
+	require.NoError(t, err)
+	assert.True(t, at.Add(2*time.Second).Equal(got))
+	require.Len(t, wire, 2)
+	assert.Contains(t, wire[0], `"params":{"at":1709296200000,"delay":2000}`)
+	assert.Contains(t, wire[1], `"result":1709296202000`)
+
+	t.Run("Call codecs override the client codec", func(t *testing.T) {
+		wire = nil
+		ms, err := Call[int64](ctx, client, "schedule", scheduleParams{At: at},
+			WithCallCodec(StdCodec))
+		require.NoError(t, err)
+		assert.Equal(t, at.UnixMilli(), ms)
+		assert.Contains(t, wire[0], `"at":"2024-03-01T12:30:00Z"`, "the server accepts either")
+	})
+
+	t.Run("Positional params", func(t *testing.T) {
+		require.NoError(t, server.RegisterFunc("delay", func(
+			_ context.Context,
+			req *Request,
+		) (any, error) {
+			var params struct {
+				Delay time.Duration
+			}
+			if err := req.BindParams(&params); err != nil {
+				return nil, err
+			}
+			return params.Delay, nil
+		}))
+		got, err := Call[time.Duration](ctx, client, "delay", []any{1500})
+		require.NoError(t, err)
+		assert.Equal(t, 1500*time.Millisecond, got)
+	})
+}
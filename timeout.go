@@ -0,0 +1,87 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RequestTimeout is the error code of responses to requests whose handler overran its timeout,
+// within the range reserved for implementation-defined server errors.
+const RequestTimeout = -32010
+
+// msgRequestTimeout is the message of RequestTimeout errors.
+const msgRequestTimeout = "Request timeout"
+
+// ErrRequestTimeout is the error sent for requests whose handler overran its timeout.
+var ErrRequestTimeout = &Error{Code: RequestTimeout, Message: msgRequestTimeout}
+
+// errHandlerTimeout is the cause of handler contexts canceled by a timeout.
+var errHandlerTimeout = errors.New("handler timeout")
+
+// WithRequestTimeout bounds the execution of every method, including its middleware. Once the
+// timeout elapses, the handler's context is canceled and the request is answered with
+// ErrRequestTimeout without waiting for the handler to return. Handlers should watch their
+// context, as one that ignores it keeps running in the background. Disabled by default.
+func WithRequestTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+// WithMethodTimeout sets the timeout of method in place of the one set with WithRequestTimeout.
+// A timeout of zero exempts the method, such as a long-running subscription, from the timeout.
+func WithMethodTimeout(method string, timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if s.methodTimeouts == nil {
+			s.methodTimeouts = make(map[string]time.Duration)
+		}
+		s.methodTimeouts[method] = timeout
+	}
+}
+
+// timeoutFor returns the timeout applying to method, zero if none.
+func (s *Server) timeoutFor(method string) time.Duration {
+	if timeout, ok := s.methodTimeouts[method]; ok {
+		return timeout
+	}
+	return s.timeout
+}
+
+// invokeWithTimeout dispatches the request under the timeout of its method, answering with
+// ErrRequestTimeout as soon as it elapses.
+func (s *Server) invokeWithTimeout(ctx context.Context, req *Request) (any, error) {
+	timeout := s.timeoutFor(req.Method)
+	if timeout <= 0 {
+		return s.invokeLimited(ctx, req)
+	}
+
+	handlerCtx, cancel := context.WithTimeoutCause(ctx, timeout, errHandlerTimeout)
+	defer cancel()
+
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := s.invokeLimited(handlerCtx, req)
+		done <- outcome{result: result, err: err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-handlerCtx.Done():
+		if context.Cause(handlerCtx) == errHandlerTimeout {
+			return nil, ErrRequestTimeout
+		}
+		// Cancellation by the caller leaves the handler to wind down as it would without timeout
+		out = <-done
+	}
+	if out.err != nil && context.Cause(handlerCtx) == errHandlerTimeout &&
+		errors.Is(out.err, context.DeadlineExceeded) {
+		return nil, ErrRequestTimeout
+	}
+	return out.result, out.err
+}
@@ -0,0 +1,152 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sleepHandler waits for d or until its context is done, sending the cause on exited if set.
+func sleepHandler(d time.Duration, exited chan<- error) HandlerFunc {
+	return func(ctx context.Context, _ *Request) (any, error) {
+		select {
+		case <-time.After(d):
+			return "done", nil
+		case <-ctx.Done():
+			if exited != nil {
+				exited <- context.Cause(ctx)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestServer_RequestTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Overrunning handler times out", func(t *testing.T) {
+		exited := make(chan error, 1)
+		srv := NewServer(WithRequestTimeout(10 * time.Millisecond))
+		require.NoError(t, srv.Register("slow", sleepHandler(time.Hour, exited)))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("slow", nil, 1))
+		require.NotNil(t, resp)
+		require.ErrorIs(t, resp.Err(), ErrRequestTimeout)
+		assert.Equal(t, RequestTimeout, resp.Err().Code)
+		assert.Equal(t, msgRequestTimeout, resp.Err().Message)
+
+		select {
+		case cause := <-exited:
+			require.ErrorIs(t, cause, errHandlerTimeout)
+		case <-time.After(time.Second):
+			t.Fatal("handler context not canceled")
+		}
+	})
+
+	t.Run("Does not wait for handlers ignoring their context", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		stuck := HandlerFunc(func(context.Context, *Request) (any, error) {
+			<-release
+			return nil, nil
+		})
+		srv := NewServer(WithRequestTimeout(10 * time.Millisecond))
+		require.NoError(t, srv.Register("stuck", stuck))
+
+		start := time.Now()
+		resp := srv.HandleRequest(ctx, NewRequestWithID("stuck", nil, 1))
+		require.ErrorIs(t, resp.Err(), ErrRequestTimeout)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Fast handler is unaffected", func(t *testing.T) {
+		srv := NewServer(WithRequestTimeout(time.Second))
+		require.NoError(t, srv.Register("fast", sleepHandler(0, nil)))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("fast", nil, 1))
+		assert.Nil(t, resp.Err())
+		var result string
+		require.NoError(t, resp.UnmarshalResult(&result))
+		assert.Equal(t, "done", result)
+	})
+
+	t.Run("Handler errors are kept", func(t *testing.T) {
+		failing := HandlerFunc(func(context.Context, *Request) (any, error) {
+			return nil, ErrInvalidParams
+		})
+		srv := NewServer(WithRequestTimeout(time.Second))
+		require.NoError(t, srv.Register("fail", failing))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("fail", nil, 1))
+		require.ErrorIs(t, resp.Err(), ErrInvalidParams)
+	})
+
+	t.Run("Method timeout overrides the global one", func(t *testing.T) {
+		srv := NewServer(
+			WithRequestTimeout(time.Hour),
+			WithMethodTimeout("slow", 10*time.Millisecond),
+			WithMethodTimeout("exempt", 0),
+		)
+		require.NoError(t, srv.Register("slow", sleepHandler(time.Hour, nil)))
+		require.NoError(t, srv.Register("exempt", sleepHandler(20*time.Millisecond, nil)))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("slow", nil, 1))
+		require.ErrorIs(t, resp.Err(), ErrRequestTimeout)
+		assert.Equal(t, time.Duration(0), srv.timeoutFor("exempt"))
+		assert.Equal(t, time.Hour, srv.timeoutFor("other"))
+	})
+
+	t.Run("Method timeout without a global one", func(t *testing.T) {
+		srv := NewServer(WithMethodTimeout("slow", 10*time.Millisecond))
+		require.NoError(t, srv.Register("slow", sleepHandler(time.Hour, nil)))
+		require.NoError(t, srv.Register("other", sleepHandler(20*time.Millisecond, nil)))
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("slow", nil, 1))
+		require.ErrorIs(t, resp.Err(), ErrRequestTimeout)
+		resp = srv.HandleRequest(ctx, NewRequestWithID("other", nil, 2))
+		assert.Nil(t, resp.Err())
+	})
+
+	t.Run("Caller cancellation is not a timeout", func(t *testing.T) {
+		srv := NewServer(WithRequestTimeout(time.Hour))
+		require.NoError(t, srv.Register("slow", sleepHandler(time.Hour, nil)))
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		resp := srv.HandleRequest(canceled, NewRequestWithID("slow", nil, 1))
+		require.Error(t, resp.Err())
+		assert.False(t, errors.Is(resp.Err(), ErrRequestTimeout))
+	})
+
+	t.Run("Applies to each batch member", func(t *testing.T) {
+		srv := NewServer(WithRequestTimeout(10 * time.Millisecond))
+		require.NoError(t, srv.Register("slow", sleepHandler(time.Hour, nil)))
+		require.NoError(t, srv.Register("fast", sleepHandler(0, nil)))
+
+		msg := `[{"jsonrpc":"2.0","method":"slow","id":1},{"jsonrpc":"2.0","method":"fast","id":2}]`
+		resps, err := DecodeBatchResponse(srv.HandleMessage(ctx, []byte(msg)))
+		require.NoError(t, err)
+		require.Len(t, resps, 2)
+		require.ErrorIs(t, resps[0].Err(), ErrRequestTimeout)
+		assert.Nil(t, resps[1].Err())
+	})
+
+	t.Run("Clients match the timeout error", func(t *testing.T) {
+		srv := NewServer(WithRequestTimeout(10 * time.Millisecond))
+		require.NoError(t, srv.Register("slow", sleepHandler(time.Hour, nil)))
+		client := NewClient(&funcTransport{fn: func(ctx context.Context, payload []byte) (
+			[]byte,
+			error,
+		) {
+			return srv.HandleMessage(ctx, payload), nil
+		}})
+
+		err := client.Call(ctx, "slow", nil, nil)
+		require.ErrorIs(t, err, ErrRequestTimeout)
+		assert.True(t, IsCode(err, RequestTimeout))
+	})
+}
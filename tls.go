@@ -0,0 +1,170 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSOptions describes one side of a TLS setup, built into a *tls.Config by NewClientTLSConfig or
+// NewServerTLSConfig, from files or from values already loaded.
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM files of the certificate presented to the peer: the
+	// certificate of a server, or the client certificate of mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// Certificates are presented to the peer in addition to the one of CertFile and KeyFile.
+	Certificates []tls.Certificate
+
+	// CAFile is a PEM bundle of the certificate authorities trusted to sign the certificates of
+	// the peer, added to CAs.
+	CAFile string
+
+	// CAs are the certificate authorities trusted to sign the certificates of the peer. On
+	// clients they replace the system roots; on servers they enable mutual TLS, which then
+	// requires every client to present a certificate they signed.
+	CAs *x509.CertPool
+
+	// ServerName is the name clients send as SNI and verify the certificate of the server
+	// against, for servers reached by address or under another name. Defaults to the host
+	// dialed.
+	ServerName string
+
+	// MinVersion is the minimum TLS version accepted. Defaults to TLS 1.2.
+	MinVersion uint16
+}
+
+// NewClientTLSConfig returns the configuration of a TLS client for opts, for WithTLSConfig and
+// WithHTTPTLSConfig.
+func NewClientTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootCAs = cfg.ClientCAs
+	cfg.ClientCAs = nil
+	cfg.ServerName = opts.ServerName
+	return cfg, nil
+}
+
+// NewServerTLSConfig returns the configuration of a TLS server for opts, for Listen with
+// WithTLSConfig or the TLSConfig of an http.Server. It requires a certificate, and requires and
+// verifies client certificates when opts has certificate authorities.
+func NewServerTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Certificates) == 0 {
+		return nil, errNoCertificates
+	}
+	if cfg.ClientCAs != nil {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// config returns the configuration common to clients and servers, with the trusted authorities
+// in ClientCAs.
+func (o TLSOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		Certificates: append([]tls.Certificate(nil), o.Certificates...),
+		ClientCAs:    o.CAs,
+		MinVersion:   o.MinVersion,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.ClientCAs == nil {
+			cfg.ClientCAs = x509.NewCertPool()
+		} else {
+			cfg.ClientCAs = cfg.ClientCAs.Clone()
+		}
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+	}
+	return cfg, nil
+}
+
+// WithHTTPTLSConfig makes the transport send requests over TLS with cfg, such as a configuration
+// of NewClientTLSConfig presenting a client certificate, through a clone of
+// http.DefaultTransport. It replaces the client set by an earlier WithHTTPClient.
+func WithHTTPTLSConfig(cfg *tls.Config) HTTPOption {
+	return func(t *HTTPTransport) {
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			transport = &http.Transport{}
+		}
+		transport = transport.Clone()
+		transport.TLSClientConfig = cfg
+		t.client = &http.Client{Transport: transport}
+	}
+}
+
+// connectionStateContextKey is the context key of the TLS connection state of a call.
+type connectionStateContextKey struct{}
+
+// WithConnectionState returns a copy of ctx carrying state as the TLS connection state of the
+// calls handled with it, or ctx itself if state is nil. Servers attach it to the calls arriving
+// over TLS, on HTTP and on listeners of Serve; custom transports attach it with this function.
+func WithConnectionState(ctx context.Context, state *tls.ConnectionState) context.Context {
+	if state == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, connectionStateContextKey{}, state)
+}
+
+// ConnectionStateFromContext returns the TLS connection state of the current call, and false if
+// it did not arrive over TLS.
+func ConnectionStateFromContext(ctx context.Context) (*tls.ConnectionState, bool) {
+	state, ok := ctx.Value(connectionStateContextKey{}).(*tls.ConnectionState)
+	return state, ok
+}
+
+// PeerCertificate returns the client certificate of the current call, if the server verified one
+// with mutual TLS. Unverified certificates, sent to servers not requiring them, are not returned.
+func PeerCertificate(ctx context.Context) (*x509.Certificate, bool) {
+	state, ok := ConnectionStateFromContext(ctx)
+	if !ok || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return state.VerifiedChains[0][0], true
+}
+
+// CertificateAuthenticator returns an Authenticator accepting the requests made with a verified
+// client certificate for which verify returns a Principal, such as one named after its subject
+// common name, for authorization by ACL. Requests without one get ErrUnauthenticated.
+func CertificateAuthenticator(
+	verify func(ctx context.Context, cert *x509.Certificate) (*Principal, error),
+) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, _ *Request) (*Principal, error) {
+		cert, ok := PeerCertificate(ctx)
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		return verify(ctx, cert)
+	})
+}
+
+// httpContext returns the context of the calls of an HTTP request: with its metadata, and its
+// TLS connection state if it arrived over TLS.
+func httpContext(r *http.Request) context.Context {
+	return WithConnectionState(NewIncomingContext(r.Context(), MetadataFromHTTP(r)), r.TLS)
+}
@@ -0,0 +1,176 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPKI holds the PEM files of a certificate authority, of a server certificate it signed for
+// 127.0.0.1 and rpc.internal, and of a client certificate it signed for "alice".
+type testPKI struct {
+	caFile, serverCert, serverKey, clientCert, clientKey string
+}
+
+// newTestPKI writes a fresh testPKI to a temporary directory.
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	writePEM := func(name, kind string, der []byte) string {
+		path := filepath.Join(dir, name)
+		data := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		return path
+	}
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			DNSNames:     []string{"rpc.internal"},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		return writePEM(name+".crt", "CERTIFICATE", der),
+			writePEM(name+".key", "PRIVATE KEY", keyDER)
+	}
+
+	pki := testPKI{caFile: writePEM("ca.crt", "CERTIFICATE", caDER)}
+	pki.serverCert, pki.serverKey = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	pki.clientCert, pki.clientKey = issue("alice", 3, x509.ExtKeyUsageClientAuth)
+	return pki
+}
+
+// newMTLSServer returns a server authenticating callers by the common name of their client
+// certificate, with a "whoami" method returning it.
+func newMTLSServer(t *testing.T) *Server {
+	t.Helper()
+	srv := NewServer(WithAuthenticator(CertificateAuthenticator(
+		func(_ context.Context, cert *x509.Certificate) (*Principal, error) {
+			return &Principal{ID: cert.Subject.CommonName}, nil
+		})))
+	whoami := func(ctx context.Context, _ *Request) (any, error) {
+		principal, _ := PrincipalFromContext(ctx)
+		return principal.ID, nil
+	}
+	require.NoError(t, srv.RegisterFunc("whoami", whoami))
+	return srv
+}
+
+func TestTLSConfigs(t *testing.T) {
+	pki := newTestPKI(t)
+	serverCfg, err := NewServerTLSConfig(TLSOptions{
+		CertFile: pki.serverCert, KeyFile: pki.serverKey, CAFile: pki.caFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, serverCfg.ClientAuth)
+	assert.Equal(t, uint16(tls.VersionTLS12), serverCfg.MinVersion)
+	clientCfg, err := NewClientTLSConfig(TLSOptions{
+		CertFile: pki.clientCert, KeyFile: pki.clientKey, CAFile: pki.caFile,
+		ServerName: "rpc.internal", MinVersion: tls.VersionTLS13,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), clientCfg.MinVersion)
+
+	t.Run("Mutual TLS over a listener", func(t *testing.T) {
+		l, err := Listen("tcp", "127.0.0.1:0", WithTLSConfig(serverCfg))
+		require.NoError(t, err)
+		serveListener(t, newMTLSServer(t), l)
+
+		stream, err := DialConn(context.Background(), "tcp", l.Addr().String(),
+			WithTLSConfig(clientCfg))
+		require.NoError(t, err)
+		client := NewStreamClient(stream)
+		defer func() { _ = client.Close() }()
+		var who string
+		require.NoError(t, client.Call(context.Background(), "whoami", nil, &who))
+		assert.Equal(t, "alice", who)
+	})
+
+	t.Run("Mutual TLS over HTTP", func(t *testing.T) {
+		ts := httptest.NewUnstartedServer(newMTLSServer(t))
+		ts.TLS = serverCfg
+		ts.StartTLS()
+		defer ts.Close()
+
+		client := NewClient(NewHTTPTransport(ts.URL, WithHTTPTLSConfig(clientCfg)))
+		var who string
+		require.NoError(t, client.Call(context.Background(), "whoami", nil, &who))
+		assert.Equal(t, "alice", who)
+	})
+
+	t.Run("Clients without a certificate are turned away", func(t *testing.T) {
+		anonymous, err := NewClientTLSConfig(TLSOptions{CAFile: pki.caFile})
+		require.NoError(t, err)
+		optional := serverCfg.Clone()
+		optional.ClientAuth = tls.VerifyClientCertIfGiven
+		ts := httptest.NewUnstartedServer(newMTLSServer(t))
+		ts.TLS = optional
+		ts.StartTLS()
+		defer ts.Close()
+
+		client := NewClient(NewHTTPTransport(ts.URL, WithHTTPTLSConfig(anonymous)))
+		err = client.Call(context.Background(), "whoami", nil, nil)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, Unauthenticated, rpcErr.Code)
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := NewServerTLSConfig(TLSOptions{CAFile: pki.caFile})
+		assert.ErrorIs(t, err, errNoCertificates)
+		_, err = NewClientTLSConfig(TLSOptions{CAFile: pki.serverKey})
+		assert.ErrorContains(t, err, "no certificates found")
+		_, err = NewClientTLSConfig(TLSOptions{CertFile: pki.clientCert})
+		assert.Error(t, err)
+	})
+}
+
+func TestPeerCertificate(t *testing.T) {
+	_, ok := PeerCertificate(context.Background())
+	assert.False(t, ok)
+	ctx := WithConnectionState(context.Background(), &tls.ConnectionState{})
+	_, ok = ConnectionStateFromContext(ctx)
+	assert.True(t, ok)
+	_, ok = PeerCertificate(ctx)
+	assert.False(t, ok, "unverified")
+	assert.Equal(t, context.Background(), WithConnectionState(context.Background(), nil))
+}
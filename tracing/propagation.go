@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Carrier holds propagated fields. Its method set is that of OpenTelemetry's TextMapCarrier, so
+// that MetadataCarrier can be handed to OpenTelemetry propagators.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+	Keys() []string
+}
+
+// Propagator injects the trace context of a context into a carrier, and extracts it back.
+type Propagator interface {
+	// Inject writes the trace context of ctx to carrier.
+	Inject(ctx context.Context, carrier Carrier)
+
+	// Extract returns a copy of ctx carrying the trace context read from carrier.
+	Extract(ctx context.Context, carrier Carrier) context.Context
+}
+
+// MetadataCarrier adapts jsonrpc.Metadata to Carrier.
+type MetadataCarrier jsonrpc.Metadata
+
+// Get implements Carrier.
+func (c MetadataCarrier) Get(key string) string {
+	return jsonrpc.Metadata(c).Get(key)
+}
+
+// Set implements Carrier.
+func (c MetadataCarrier) Set(key, value string) {
+	jsonrpc.Metadata(c).Set(key, value)
+}
+
+// Keys implements Carrier.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,53 @@
+package tracing
+
+import "context"
+
+// SpanKind is the role of a span in a call.
+type SpanKind int
+
+const (
+	// SpanKindClient is the kind of spans of outgoing calls.
+	SpanKindClient SpanKind = iota + 1
+
+	// SpanKindServer is the kind of spans of dispatched handlers.
+	SpanKindServer
+)
+
+// StatusCode is the status of a span. Spans of successful calls keep the unset status.
+type StatusCode int
+
+const (
+	// StatusUnset is the default status of spans.
+	StatusUnset StatusCode = iota
+
+	// StatusError marks spans of calls that failed.
+	StatusError
+)
+
+// Attribute is a key/value pair set on a span. Values are strings or ints.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer starts spans. An OpenTelemetry adapter wraps a trace.Tracer, converting the kind and
+// attributes to their OpenTelemetry counterparts.
+type Tracer interface {
+	// Start starts a span, returning it along with a context carrying it.
+	Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (
+		context.Context,
+		Span,
+	)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs ...Attribute)
+
+	// SetStatus sets the status of the span, with a description for errors.
+	SetStatus(code StatusCode, description string)
+
+	// End completes the span.
+	End()
+}
@@ -0,0 +1,130 @@
+// Package tracing instruments jsonrpc servers and clients with tracing spans: a client span per
+// outgoing call, and a server span per dispatched handler, with the trace context propagated
+// through call metadata, which the HTTP transport sends as headers. Spans go to a Tracer, so that
+// any tracing system, such as OpenTelemetry, can be plugged in without becoming a dependency of
+// this module. Attributes follow the OpenTelemetry semantic conventions for JSON-RPC.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// Attribute keys set on spans.
+const (
+	AttrSystem       = "rpc.system"
+	AttrMethod       = "rpc.method"
+	AttrVersion      = "rpc.jsonrpc.version"
+	AttrRequestID    = "rpc.jsonrpc.request_id"
+	AttrErrorCode    = "rpc.jsonrpc.error_code"
+	AttrErrorMessage = "rpc.jsonrpc.error_message"
+)
+
+// system is the value of AttrSystem.
+const system = "jsonrpc"
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// config holds the instrumentation settings.
+type config struct {
+	propagator Propagator
+}
+
+// WithPropagator propagates the trace context with p: clients inject it into the outgoing
+// metadata of calls, and servers extract it from their incoming metadata. Without a propagator,
+// server spans only have a parent if the context passed to the server carries one.
+func WithPropagator(p Propagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// newConfig applies opts.
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Interceptor returns a client interceptor starting a span per call. Added first with
+// jsonrpc.WithInterceptors, retried calls share one span.
+func Interceptor(tracer Tracer, opts ...Option) jsonrpc.Interceptor {
+	cfg := newConfig(opts)
+	return func(next jsonrpc.Invoker) jsonrpc.Invoker {
+		return func(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+			ctx, span := tracer.Start(ctx, req.Method, SpanKindClient, requestAttributes(req)...)
+			defer span.End()
+
+			if cfg.propagator != nil {
+				md, _ := jsonrpc.OutgoingMetadata(ctx)
+				md = md.Copy()
+				cfg.propagator.Inject(ctx, MetadataCarrier(md))
+				ctx = jsonrpc.NewOutgoingContext(ctx, md)
+			}
+
+			resp, err := next(ctx, req)
+			switch {
+			case resp != nil && resp.Err() != nil:
+				setError(span, resp.Err())
+			case err != nil:
+				setError(span, err)
+			default:
+			}
+			return resp, err
+		}
+	}
+}
+
+// Middleware returns a server middleware starting a span per dispatched handler, as a child of
+// the propagated trace context, if any.
+func Middleware(tracer Tracer, opts ...Option) jsonrpc.Middleware {
+	cfg := newConfig(opts)
+	return func(next jsonrpc.Handler) jsonrpc.Handler {
+		return jsonrpc.HandlerFunc(func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+			if md, ok := jsonrpc.IncomingMetadata(ctx); ok && cfg.propagator != nil {
+				ctx = cfg.propagator.Extract(ctx, MetadataCarrier(md))
+			}
+			ctx, span := tracer.Start(ctx, req.Method, SpanKindServer, requestAttributes(req)...)
+			defer span.End()
+
+			result, err := next.ServeRPC(ctx, req)
+			if err != nil {
+				setError(span, err)
+			}
+			return result, err
+		})
+	}
+}
+
+// requestAttributes returns the attributes describing req.
+func requestAttributes(req *jsonrpc.Request) []Attribute {
+	attrs := []Attribute{
+		{Key: AttrSystem, Value: system},
+		{Key: AttrMethod, Value: req.Method},
+		{Key: AttrVersion, Value: req.JSONRPC},
+	}
+	if !req.IsNotification() {
+		attrs = append(attrs, Attribute{Key: AttrRequestID, Value: fmt.Sprint(req.ID)})
+	}
+	return attrs
+}
+
+// setError marks span as failed with err, recording its JSON-RPC error code if it has one.
+func setError(span Span, err error) {
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) {
+		span.SetStatus(StatusError, err.Error())
+		return
+	}
+	span.SetAttributes(
+		Attribute{Key: AttrErrorCode, Value: rpcErr.Code},
+		Attribute{Key: AttrErrorMessage, Value: rpcErr.Message},
+	)
+	span.SetStatus(StatusError, rpcErr.Message)
+}
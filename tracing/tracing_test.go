@@ -0,0 +1,224 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// traceHeader is the metadata key testPropagator propagates span IDs under.
+const traceHeader = "x-test-trace"
+
+// testSpan is a span recorded by testTracer.
+type testSpan struct {
+	id          int
+	parent      int
+	name        string
+	kind        SpanKind
+	attrs       map[string]any
+	status      StatusCode
+	description string
+	ended       bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *testSpan) SetStatus(code StatusCode, description string) {
+	s.status, s.description = code, description
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+// spanKey is the context key of the current span ID.
+type spanKey struct{}
+
+// testTracer records the spans it starts, parenting them to the span ID of the context.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (
+	context.Context,
+	Span,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, _ := ctx.Value(spanKey{}).(int)
+	span := &testSpan{
+		id:     len(t.spans) + 1,
+		parent: parent,
+		name:   name,
+		kind:   kind,
+		attrs:  make(map[string]any),
+	}
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span.id), span
+}
+
+// span returns the span of kind, failing if there is not exactly one.
+func (t *testTracer) span(tb testing.TB, kind SpanKind) *testSpan {
+	tb.Helper()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var found []*testSpan
+	for _, span := range t.spans {
+		if span.kind == kind {
+			found = append(found, span)
+		}
+	}
+	require.Len(tb, found, 1)
+	return found[0]
+}
+
+// testPropagator propagates the current span ID under traceHeader.
+type testPropagator struct{}
+
+func (testPropagator) Inject(ctx context.Context, carrier Carrier) {
+	if id, ok := ctx.Value(spanKey{}).(int); ok {
+		carrier.Set(traceHeader, strconv.Itoa(id))
+	}
+}
+
+func (testPropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	id, err := strconv.Atoi(carrier.Get(traceHeader))
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, id)
+}
+
+// newServer creates a server traced with tracer, with an "ok" and a "fail" method.
+func newServer(t *testing.T, tracer Tracer) *jsonrpc.Server {
+	t.Helper()
+	srv := jsonrpc.NewServer()
+	srv.Use(Middleware(tracer, WithPropagator(testPropagator{})))
+	ok := jsonrpc.HandlerFunc(func(context.Context, *jsonrpc.Request) (any, error) {
+		return "fine", nil
+	})
+	fail := jsonrpc.HandlerFunc(func(context.Context, *jsonrpc.Request) (any, error) {
+		return nil, jsonrpc.ErrInvalidParams
+	})
+	require.NoError(t, srv.Register("ok", ok))
+	require.NoError(t, srv.Register("fail", fail))
+	return srv
+}
+
+func TestPropagation(t *testing.T) {
+	tracer := &testTracer{}
+	httpSrv := httptest.NewServer(newServer(t, tracer))
+	defer httpSrv.Close()
+
+	client := jsonrpc.NewClient(
+		jsonrpc.NewHTTPTransport(httpSrv.URL),
+		jsonrpc.WithInterceptors(Interceptor(tracer, WithPropagator(testPropagator{}))),
+	)
+	defer func() { _ = client.Close() }()
+
+	ctx := jsonrpc.AppendToOutgoingContext(context.Background(), "x-other", "kept")
+	require.NoError(t, client.Call(ctx, "ok", nil, nil))
+
+	clientSpan := tracer.span(t, SpanKindClient)
+	serverSpan := tracer.span(t, SpanKindServer)
+	assert.Equal(t, clientSpan.id, serverSpan.parent, "server span is a child of the client span")
+	assert.True(t, clientSpan.ended)
+	assert.True(t, serverSpan.ended)
+
+	md, _ := jsonrpc.OutgoingMetadata(ctx)
+	assert.Empty(t, md.Get(traceHeader), "the caller's metadata is not modified")
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Successful calls", func(t *testing.T) {
+		tracer := &testTracer{}
+		srv := newServer(t, tracer)
+
+		srv.HandleRequest(ctx, jsonrpc.NewRequestWithID("ok", nil, 7))
+		span := tracer.span(t, SpanKindServer)
+		assert.Equal(t, "ok", span.name)
+		assert.Equal(t, map[string]any{
+			AttrSystem:    "jsonrpc",
+			AttrMethod:    "ok",
+			AttrVersion:   "2.0",
+			AttrRequestID: "7",
+		}, span.attrs)
+		assert.Equal(t, StatusUnset, span.status)
+		assert.Zero(t, span.parent)
+	})
+
+	t.Run("Errors set the status and code", func(t *testing.T) {
+		tracer := &testTracer{}
+		srv := newServer(t, tracer)
+
+		srv.HandleRequest(ctx, jsonrpc.NewRequestWithID("fail", nil, 1))
+		span := tracer.span(t, SpanKindServer)
+		assert.Equal(t, StatusError, span.status)
+		assert.Equal(t, jsonrpc.InvalidParams, span.attrs[AttrErrorCode])
+		assert.Equal(t, jsonrpc.ErrInvalidParams.Message, span.attrs[AttrErrorMessage])
+	})
+
+	t.Run("Notifications have no request ID", func(t *testing.T) {
+		tracer := &testTracer{}
+		srv := newServer(t, tracer)
+
+		srv.HandleRequest(ctx, jsonrpc.NewNotification("ok", nil))
+		assert.NotContains(t, tracer.span(t, SpanKindServer).attrs, AttrRequestID)
+	})
+}
+
+func TestInterceptor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Error responses", func(t *testing.T) {
+		tracer := &testTracer{}
+		httpSrv := httptest.NewServer(newServer(t, &testTracer{}))
+		defer httpSrv.Close()
+		client := jsonrpc.NewClient(
+			jsonrpc.NewHTTPTransport(httpSrv.URL),
+			jsonrpc.WithInterceptors(Interceptor(tracer)),
+		)
+
+		require.Error(t, client.Call(ctx, "missing", nil, nil))
+		span := tracer.span(t, SpanKindClient)
+		assert.Equal(t, StatusError, span.status)
+		assert.Equal(t, jsonrpc.MethodNotFound, span.attrs[AttrErrorCode])
+	})
+
+	t.Run("Transport failures", func(t *testing.T) {
+		tracer := &testTracer{}
+		errDown := errors.New("down")
+		failing := func(jsonrpc.Invoker) jsonrpc.Invoker {
+			return func(context.Context, *jsonrpc.Request) (*jsonrpc.Response, error) {
+				return nil, errDown
+			}
+		}
+		client := jsonrpc.NewClient(
+			jsonrpc.NewHTTPTransport("http://127.0.0.1:0"),
+			jsonrpc.WithInterceptors(Interceptor(tracer), failing),
+		)
+
+		require.ErrorIs(t, client.Call(ctx, "ok", nil, nil), errDown)
+		span := tracer.span(t, SpanKindClient)
+		assert.Equal(t, StatusError, span.status)
+		assert.Equal(t, "down", span.description)
+		assert.NotContains(t, span.attrs, AttrErrorCode)
+	})
+}
@@ -0,0 +1,120 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// RawTransform rewrites the raw JSON of params or a result, such as to redact fields or to
+// translate naming conventions between downstream clients and an upstream. It may modify raw in
+// place and return it. Params are nil when the request has none, and returning nil params omits
+// them.
+type RawTransform func(ctx context.Context, raw json.RawMessage) (json.RawMessage, error)
+
+// ProxyTransform holds the rewrites a proxy applies to the calls of a method. Either may be nil.
+type ProxyTransform struct {
+	// Params rewrites the params of requests and notifications before they are forwarded. A
+	// call whose params fail to be rewritten is not forwarded, and is answered with the error
+	// if it is an *Error, and with ErrInvalidParams otherwise.
+	Params RawTransform
+
+	// Result rewrites the results of successful responses before they are relayed. A response
+	// whose result fails to be rewritten is replaced with the error if it is an *Error, and with
+	// ErrInternal otherwise.
+	Result RawTransform
+}
+
+// WithProxyTransform rewrites the params of the calls of method on their way upstream and the
+// results on their way back, working on raw JSON so that neither is decoded unless the transform
+// does it. Params are rewritten after any cache lookup and routing, which see the downstream
+// params, and responses cached by WithProxyCache hold the rewritten results. Error responses are
+// relayed as they are.
+func WithProxyTransform(method string, transform ProxyTransform) ProxyOption {
+	return func(p *Proxy) {
+		if p.transforms == nil {
+			p.transforms = make(map[string]ProxyTransform)
+		}
+		p.transforms[method] = transform
+	}
+}
+
+// transformParams rewrites the params of req with the transform of its method, if any, returning
+// the error to answer req with if they fail to be rewritten.
+func (p *Proxy) transformParams(ctx context.Context, req *Request) *Error {
+	transform, ok := p.transforms[req.Method]
+	if !ok || transform.Params == nil {
+		return nil
+	}
+	var raw json.RawMessage
+	if params, ok := req.Params.(json.RawMessage); ok {
+		raw = params
+	}
+	params, err := transform.Params(ctx, raw)
+	if err != nil {
+		return transformError(err, ErrInvalidParams)
+	}
+	req.Params = nil
+	if params != nil {
+		req.Params = params
+	}
+	return nil
+}
+
+// rewriteParams rewrites the params of req, the member at position i of a message, if methods
+// holds the methods of the members to transform, recording its method. It reports whether req is
+// to be forwarded, storing the response to it in results otherwise.
+func (p *Proxy) rewriteParams(
+	ctx context.Context, i int, req *Request, methods []string, results []*Response,
+) bool {
+	if methods == nil {
+		return true
+	}
+	if err := p.transformParams(ctx, req); err != nil {
+		if !req.IsNotification() {
+			results[i] = NewErrorResponse(req.ID, err)
+		}
+		return false
+	}
+	methods[i] = req.Method
+	return true
+}
+
+// transformResults rewrites the results of the forwarded calls, whose methods are given by
+// position, leaving the positions without a method as they are.
+func (p *Proxy) transformResults(ctx context.Context, methods []string, results []*Response) {
+	for i, method := range methods {
+		if method != "" {
+			results[i] = p.transformResult(ctx, method, results[i])
+		}
+	}
+}
+
+// transformResult returns resp, the response to a call of method, with its result rewritten by
+// the transform of the method, if any.
+func (p *Proxy) transformResult(ctx context.Context, method string, resp *Response) *Response {
+	transform, ok := p.transforms[method]
+	if !ok || transform.Result == nil || resp == nil || resp.Err() != nil {
+		return resp
+	}
+	id := resp.IDOrNil()
+	result, err := transform.Result(ctx, resp.RawResult())
+	if err != nil {
+		return NewErrorResponse(id, transformError(err, ErrInternal))
+	}
+	transformed, err := NewResponseFromRaw(id, result)
+	if err != nil {
+		return NewErrorResponse(id, ErrInternal)
+	}
+	return transformed
+}
+
+// transformError returns the error to answer a call whose transform failed with err: err itself
+// if it is an *Error, and fallback with the cause as data otherwise.
+func transformError(err error, fallback *Error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return fallback.WithData(err.Error())
+}
@@ -0,0 +1,112 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProxyTransform(t *testing.T) {
+	ctx := context.Background()
+	double := func(_ context.Context, raw json.RawMessage) (json.RawMessage, error) {
+		return bytes.ReplaceAll(raw, []byte("1"), []byte("10")), nil
+	}
+	wrap := func(_ context.Context, raw json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`{"value":` + string(raw) + `}`), nil
+	}
+
+	t.Run("Rewrites params and results", func(t *testing.T) {
+		client, up := newUpstream(newTestServer(t))
+		proxy := NewProxy(client,
+			WithProxyTransform("sum", ProxyTransform{Params: double, Result: wrap}))
+
+		reply := proxy.HandleMessage(ctx, []byte(`[`+
+			`{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2]},`+
+			`{"jsonrpc":"2.0","id":2,"method":"subtract","params":[1,2]}]`))
+		assert.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"result":{"value":12}},`+
+			`{"jsonrpc":"2.0","id":2,"result":-1}]`, string(reply))
+		require.Len(t, up.sent(), 1)
+		assert.Contains(t, up.sent()[0], `"params":[10,2]`)
+		assert.Contains(t, up.sent()[0], `"method":"subtract","params":[1,2]`)
+	})
+
+	t.Run("Nil params are omitted", func(t *testing.T) {
+		client, up := newUpstream(newTestServer(t))
+		var seen json.RawMessage
+		drop := func(_ context.Context, raw json.RawMessage) (json.RawMessage, error) {
+			seen = raw
+			return nil, nil
+		}
+		proxy := NewProxy(client,
+			WithProxyTransform("notify_hello", ProxyTransform{Params: drop}))
+		reply := proxy.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"notify_hello","params":[1,2]}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":null}`, string(reply))
+		assert.JSONEq(t, `[1,2]`, string(seen))
+		assert.NotContains(t, up.sent()[0], "params")
+	})
+
+	t.Run("Failed params transforms are not forwarded", func(t *testing.T) {
+		client, up := newUpstream(newTestServer(t))
+		reject := func(context.Context, json.RawMessage) (json.RawMessage, error) {
+			return nil, errors.New("bad params")
+		}
+		custom := func(context.Context, json.RawMessage) (json.RawMessage, error) {
+			return nil, &Error{Code: 4, Message: "nope"}
+		}
+		proxy := NewProxy(client,
+			WithProxyTransform("sum", ProxyTransform{Params: reject}),
+			WithProxyTransform("subtract", ProxyTransform{Params: custom}),
+		)
+		reply := proxy.HandleMessage(ctx, []byte(`[`+
+			`{"jsonrpc":"2.0","id":1,"method":"sum","params":[1]},`+
+			`{"jsonrpc":"2.0","id":2,"method":"subtract","params":[1]},`+
+			`{"jsonrpc":"2.0","method":"sum","params":[1]}]`))
+		assert.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"error":`+
+			`{"code":-32602,"message":"Invalid params","data":"bad params"}},`+
+			`{"jsonrpc":"2.0","id":2,"error":{"code":4,"message":"nope"}}]`, string(reply))
+		assert.Empty(t, up.sent())
+	})
+
+	t.Run("Error responses and failed result transforms", func(t *testing.T) {
+		client, _ := newUpstream(newTestServer(t))
+		called := false
+		failing := func(context.Context, json.RawMessage) (json.RawMessage, error) {
+			called = true
+			return nil, errors.New("broken")
+		}
+		proxy := NewProxy(client,
+			WithProxyTransform("fail", ProxyTransform{Result: failing}),
+			WithProxyTransform("sum", ProxyTransform{Result: failing}),
+		)
+		reply := proxy.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"fail"}`))
+		assert.NotContains(t, string(reply), "broken")
+		assert.False(t, called, "error responses are relayed as they are")
+
+		reply = proxy.HandleMessage(ctx,
+			[]byte(`{"jsonrpc":"2.0","id":2,"method":"sum","params":[1]}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"error":`+
+			`{"code":-32603,"message":"Internal error","data":"broken"}}`, string(reply))
+	})
+
+	t.Run("Cache holds rewritten results", func(t *testing.T) {
+		client, up := newUpstream(newTestServer(t))
+		proxy := NewProxy(client,
+			WithProxyCache(CachePolicy{TTLs: map[string]time.Duration{"sum": time.Minute}}),
+			WithProxyTransform("sum", ProxyTransform{Result: wrap}),
+		)
+		for id := range 2 {
+			reply := proxy.HandleMessage(ctx, []byte(
+				`{"jsonrpc":"2.0","id":`+strconv.Itoa(id)+`,"method":"sum","params":[1,2]}`))
+			assert.Contains(t, string(reply), `"result":{"value":3}`)
+		}
+		assert.Len(t, up.sent(), 1)
+	})
+}
@@ -0,0 +1,27 @@
+package jsonrpc
+
+import (
+	"context"
+)
+
+// Transport carries encoded JSON-RPC messages between a Client and a server using a
+// request/response exchange, such as an HTTP POST.
+//
+// RoundTrip sends a single encoded request, notification, or batch and returns the encoded reply.
+// A nil or empty reply is valid when the payload consists only of notifications.
+type Transport interface {
+	RoundTrip(ctx context.Context, payload []byte) ([]byte, error)
+	Close() error
+}
+
+// Stream is a persistent, message-framed, full-duplex connection such as a WebSocket, a stdio
+// pipe, or a TCP socket. Each call to ReadMessage returns exactly one encoded JSON-RPC message
+// (single or batch), and each call to WriteMessage writes exactly one.
+//
+// Implementations must allow ReadMessage and WriteMessage to be called concurrently with each
+// other, but callers never issue concurrent calls to the same method of a Stream.
+type Stream interface {
+	ReadMessage(ctx context.Context) ([]byte, error)
+	WriteMessage(ctx context.Context, msg []byte) error
+	Close() error
+}
@@ -0,0 +1,280 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Framing selects how messages are delimited on a byte stream.
+type Framing int
+
+const (
+	// HeaderFraming precedes each message with a Content-Length header block, as in the Language
+	// Server Protocol base protocol: "Content-Length: <n>\r\n\r\n<message>".
+	HeaderFraming Framing = iota
+
+	// LineFraming writes each message on its own line, i.e. newline-delimited JSON.
+	LineFraming
+)
+
+// contentLengthHeader is the header carrying the message size in HeaderFraming.
+const contentLengthHeader = "Content-Length"
+
+// defaultMaxFrameSize is the default maximum size in bytes of a message read from a framed
+// stream, matching the read limits of the ws and sse transports.
+const defaultMaxFrameSize = 32 * 1024 * 1024
+
+// errMissingContentLength is returned when a header block does not declare the message size.
+var errMissingContentLength = errors.New("missing Content-Length header")
+
+// framedStream is a Stream over a byte-oriented reader and writer.
+type framedStream struct {
+	r       *bufio.Reader
+	w       io.Writer
+	framing Framing
+	closers []io.Closer
+	maxSize int
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewFramedStream creates a Stream reading messages from r and writing them to w, delimited
+// according to framing. Closing the stream closes r and w if they implement io.Closer.
+//
+// Reads cannot be interrupted by a context once started; they end when a message arrives or the
+// reader is closed or fails. Messages larger than 32 MiB fail the read with ErrLimitExceeded,
+// after which the stream cannot be read any further.
+func NewFramedStream(r io.Reader, w io.Writer, framing Framing) Stream {
+	return newFramedStream(r, w, framing)
+}
+
+// newFramedStream creates the framed stream returned by NewFramedStream.
+func newFramedStream(r io.Reader, w io.Writer, framing Framing) *framedStream {
+	s := &framedStream{
+		r:       bufio.NewReader(r),
+		w:       w,
+		framing: framing,
+		maxSize: defaultMaxFrameSize,
+	}
+	if c, ok := r.(io.Closer); ok {
+		s.closers = append(s.closers, c)
+	}
+	if c, ok := w.(io.Closer); ok && !sameValue(r, w) {
+		s.closers = append(s.closers, c)
+	}
+	return s
+}
+
+// limit sets the maximum size of the messages read to n bytes, if positive.
+func (s *framedStream) limit(n int) {
+	if n > 0 {
+		s.maxSize = n
+	}
+}
+
+// bound lowers the maximum size of the messages read to n bytes, if positive.
+func (s *framedStream) bound(n int) {
+	if n > 0 && n < s.maxSize {
+		s.maxSize = n
+	}
+}
+
+// boundStream lowers the maximum size of the messages read from stream to n bytes, if positive
+// and stream was created by NewFramedStream or NewConnStream, so that messages exceeding a
+// message size limit are rejected before they are buffered.
+func boundStream(stream Stream, n int) {
+	switch s := stream.(type) {
+	case *framedStream:
+		s.bound(n)
+	case *connStream:
+		boundStream(s.Stream, n)
+	default:
+	}
+}
+
+// NewStdioStream creates a Stream over the process's standard input and output, for serving or
+// calling JSON-RPC across a subprocess boundary or from editor tooling.
+func NewStdioStream(framing Framing) Stream {
+	return NewFramedStream(os.Stdin, os.Stdout, framing)
+}
+
+// ReadMessage reads the next message. It returns io.EOF when the reader ends between messages.
+func (s *framedStream) ReadMessage(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.framing == LineFraming {
+		return s.readLine()
+	}
+	return s.readHeaderFramed()
+}
+
+// WriteMessage writes a single framed message.
+func (s *framedStream) WriteMessage(ctx context.Context, msg []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	frame, err := s.frame(msg)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(frame)
+	return err
+}
+
+// frame returns msg with the framing applied.
+func (s *framedStream) frame(msg []byte) ([]byte, error) {
+	if s.framing != LineFraming {
+		frame := fmt.Appendf(nil, "%s: %d\r\n\r\n", contentLengthHeader, len(msg))
+		return append(frame, msg...), nil
+	}
+
+	line := msg
+	// A message must not span lines, so pretty-printed input is compacted first
+	if bytes.ContainsAny(msg, "\r\n") {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, msg); err != nil {
+			return nil, fmt.Errorf("failed to compact message: %w", err)
+		}
+		line = buf.Bytes()
+	}
+	frame := make([]byte, 0, len(line)+1)
+	return append(append(frame, line...), '\n'), nil
+}
+
+// Close closes the underlying reader and writer.
+func (s *framedStream) Close() error {
+	s.closeOnce.Do(func() {
+		for _, c := range s.closers {
+			if err := c.Close(); err != nil && s.closeErr == nil {
+				s.closeErr = err
+			}
+		}
+	})
+	return s.closeErr
+}
+
+// readLine reads the next non-empty line.
+func (s *framedStream) readLine() ([]byte, error) {
+	for {
+		line, err := s.readBoundedLine()
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 {
+			// A final message without a trailing newline is still a message
+			return trimmed, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readBoundedLine reads up to and including the next newline, or up to the end of the reader. It
+// fails with ErrLimitExceeded once the line, without its line ending, exceeds the maximum size.
+func (s *framedStream) readBoundedLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		line = append(line, chunk...)
+		// A line ending may be split across chunks, so one byte of it is tolerated until it ends
+		if errors.Is(err, bufio.ErrBufferFull) {
+			if len(line) > s.maxSize+1 {
+				return nil, s.tooLarge()
+			}
+			continue
+		}
+		if len(bytes.TrimRight(line, "\r\n")) > s.maxSize {
+			return nil, s.tooLarge()
+		}
+		return line, err
+	}
+}
+
+// tooLarge returns the error of a message exceeding the maximum size.
+func (s *framedStream) tooLarge() error {
+	return limitError("message size exceeds %d bytes", s.maxSize)
+}
+
+// readHeaderFramed reads a header block followed by a message of the declared length.
+func (s *framedStream) readHeaderFramed() ([]byte, error) {
+	length, err := s.readHeaders()
+	if err != nil {
+		return nil, err
+	}
+	if length > s.maxSize {
+		// Rejected before allocating, as the stream cannot be resynchronized past it anyway
+		return nil, s.tooLarge()
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(s.r, msg); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readHeaders reads a header block up to the blank line ending it and returns the declared
+// Content-Length. Other headers are ignored.
+func (s *framedStream) readHeaders() (int, error) {
+	length := -1
+	started := false
+	for {
+		raw, err := s.readBoundedLine()
+		if err != nil {
+			if started && errors.Is(err, io.EOF) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		line := strings.TrimRight(string(raw), "\r\n")
+		if line == "" && !started {
+			// Tolerate blank lines between messages
+			continue
+		}
+		if line == "" {
+			break
+		}
+		started = true
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return 0, fmt.Errorf("malformed header line: %q", line)
+		}
+		if !strings.EqualFold(strings.TrimSpace(name), contentLengthHeader) {
+			continue
+		}
+		length, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || length < 0 {
+			return 0, fmt.Errorf("invalid %s: %q", contentLengthHeader, value)
+		}
+	}
+	if length < 0 {
+		return 0, errMissingContentLength
+	}
+	return length, nil
+}
+
+// sameValue reports whether a and b hold the same comparable value, such as one net.Conn passed
+// as both reader and writer.
+func sameValue(a, b any) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,168 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeRecorder is an io.ReadWriteCloser over a buffer that counts Close calls.
+type closeRecorder struct {
+	bytes.Buffer
+	closes int
+}
+
+func (c *closeRecorder) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestFramedStream_Header(t *testing.T) {
+	t.Run("Write", func(t *testing.T) {
+		var out bytes.Buffer
+		s := NewFramedStream(strings.NewReader(""), &out, HeaderFraming)
+		require.NoError(t, s.WriteMessage(context.Background(), []byte(`{"a":1}`)))
+		assert.Equal(t, "Content-Length: 7\r\n\r\n{\"a\":1}", out.String())
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		in := "Content-Length: 7\r\nContent-Type: application/vscode-jsonrpc\r\n\r\n" +
+			`{"a":1}` + "\r\ncontent-length: 2\n\n[]"
+		s := NewFramedStream(strings.NewReader(in), io.Discard, HeaderFraming)
+
+		msg, err := s.ReadMessage(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(msg))
+
+		msg, err = s.ReadMessage(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, `[]`, string(msg))
+
+		_, err = s.ReadMessage(context.Background())
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("Malformed input", func(t *testing.T) {
+		tests := map[string]string{
+			"Missing length":   "Content-Type: x\r\n\r\n{}",
+			"Invalid length":   "Content-Length: -3\r\n\r\n{}",
+			"Malformed header": "garbage\r\n\r\n{}",
+			"Truncated body":   "Content-Length: 10\r\n\r\n{}",
+			"Truncated header": "Content-Length: 2\r\n",
+		}
+		for name, in := range tests {
+			t.Run(name, func(t *testing.T) {
+				s := NewFramedStream(strings.NewReader(in), io.Discard, HeaderFraming)
+				_, err := s.ReadMessage(context.Background())
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, io.EOF)
+			})
+		}
+	})
+}
+
+func TestFramedStream_Line(t *testing.T) {
+	t.Run("Write compacts multi-line messages", func(t *testing.T) {
+		var out bytes.Buffer
+		s := NewFramedStream(strings.NewReader(""), &out, LineFraming)
+		require.NoError(t, s.WriteMessage(context.Background(), []byte(`{"a":1}`)))
+		require.NoError(t, s.WriteMessage(context.Background(), []byte("{\n  \"b\": 2\n}")))
+		assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n", out.String())
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		in := "{\"a\":1}\r\n\n[1,2]\n{\"last\":true}"
+		s := NewFramedStream(strings.NewReader(in), io.Discard, LineFraming)
+
+		for _, want := range []string{`{"a":1}`, `[1,2]`, `{"last":true}`} {
+			msg, err := s.ReadMessage(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, want, string(msg))
+		}
+		_, err := s.ReadMessage(context.Background())
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestFramedStream_MaxSize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Declared length is checked before reading", func(t *testing.T) {
+		in := "Content-Length: 1073741824\r\n\r\n{}"
+		s := NewFramedStream(strings.NewReader(in), io.Discard, HeaderFraming)
+		_, err := s.ReadMessage(ctx)
+		require.ErrorIs(t, err, ErrLimitExceeded, "rejected at the default limit")
+	})
+
+	t.Run("Lines are read up to the limit", func(t *testing.T) {
+		long := `"` + strings.Repeat("x", 8192) + `"`
+		in := "[1,2]\r\n" + long + "\n"
+		s := newFramedStream(strings.NewReader(in), io.Discard, LineFraming)
+		s.limit(len("[1,2]"))
+
+		msg, err := s.ReadMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, `[1,2]`, string(msg), "the line ending does not count")
+		_, err = s.ReadMessage(ctx)
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	})
+
+	t.Run("Header lines are bounded", func(t *testing.T) {
+		in := "X-Padding: " + strings.Repeat("x", 8192) + "\r\nContent-Length: 2\r\n\r\n{}"
+		s := newFramedStream(strings.NewReader(in), io.Discard, HeaderFraming)
+		s.limit(len(in) / 2)
+		_, err := s.ReadMessage(ctx)
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	})
+}
+
+func TestFramedStream_Close(t *testing.T) {
+	t.Run("Closes reader and writer once", func(t *testing.T) {
+		r, w := &closeRecorder{}, &closeRecorder{}
+		s := NewFramedStream(r, w, LineFraming)
+		require.NoError(t, s.Close())
+		require.NoError(t, s.Close())
+		assert.Equal(t, 1, r.closes)
+		assert.Equal(t, 1, w.closes)
+	})
+
+	t.Run("Shared reader and writer is closed once", func(t *testing.T) {
+		rw := &closeRecorder{}
+		require.NoError(t, NewFramedStream(rw, rw, LineFraming).Close())
+		assert.Equal(t, 1, rw.closes)
+	})
+}
+
+func TestFramedStream_ServeStream(t *testing.T) {
+	for name, framing := range map[string]Framing{"Header": HeaderFraming, "Line": LineFraming} {
+		t.Run(name, func(t *testing.T) {
+			clientR, serverW := io.Pipe()
+			serverR, clientW := io.Pipe()
+
+			serveErr := make(chan error, 1)
+			go func() {
+				stream := NewFramedStream(serverR, serverW, framing)
+				serveErr <- newTestServer(t).ServeStream(context.Background(), stream)
+			}()
+
+			client := NewStreamClient(NewFramedStream(clientR, clientW, framing))
+			var result int
+			require.NoError(t, client.Call(context.Background(), "sum", []any{1, 2, 3}, &result))
+			assert.Equal(t, 6, result)
+
+			require.NoError(t, client.Close())
+			select {
+			case err := <-serveErr:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("ServeStream did not return after the client closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+const (
+	// contentTypeJSON is the media type used for JSON-RPC over HTTP.
+	contentTypeJSON = "application/json"
+
+	// maxHTTPErrorBody caps how much of a non-JSON error body is kept in an HTTPError.
+	maxHTTPErrorBody = 1024
+)
+
+// HTTPError is returned by HTTPTransport when the server answers with a non-2xx status code and
+// a body that is not a JSON-RPC message.
+type HTTPError struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("http status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Body)
+}
+
+// HTTPTransport is a Transport that sends each payload as the body of an HTTP POST request. The
+// outgoing metadata of the call context is sent as request headers.
+type HTTPTransport struct {
+	url         string
+	client      *http.Client
+	header      http.Header
+	encoding    Encoding
+	compression *compressors
+}
+
+// HTTPOption configures an HTTPTransport.
+type HTTPOption func(*HTTPTransport)
+
+// WithHTTPClient sets the *http.Client used to send requests. Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(t *HTTPTransport) {
+		if client != nil {
+			t.client = client
+		}
+	}
+}
+
+// WithHTTPHeader adds a header sent with every request.
+func WithHTTPHeader(key, value string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.header.Add(key, value)
+	}
+}
+
+// NewHTTPTransport creates an HTTPTransport posting to the given URL.
+func NewHTTPTransport(url string, opts ...HTTPOption) *HTTPTransport {
+	t := &HTTPTransport{
+		url:    url,
+		client: http.DefaultClient,
+		header: make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip posts payload to the transport's URL and returns the response body. An empty body,
+// as sent by servers answering a notification with 204 No Content, yields a nil reply.
+func (t *HTTPTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	req, err := t.newRequest(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := t.readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON-RPC servers may pair error responses with 4xx/5xx statuses, so a JSON body wins
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return body, nil
+		}
+		if len(body) > maxHTTPErrorBody {
+			body = body[:maxHTTPErrorBody]
+		}
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	return body, nil
+}
+
+// newRequest creates the HTTP request carrying payload, in the transport's encoding and
+// compressed if configured.
+func (t *HTTPTransport) newRequest(ctx context.Context, payload []byte) (*http.Request, error) {
+	body, contentType := payload, contentTypeJSON
+	if t.encoding != nil {
+		encoded, err := t.encoding.Encode(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode http request: %w", err)
+		}
+		body, contentType = encoded, t.encoding.ContentType()
+	}
+	var contentEncoding string
+	if t.compression != nil && len(body) >= t.compression.minSize {
+		compressed, err := t.compression.gzip.compress(body)
+		if err != nil {
+			return nil, err
+		}
+		body, contentEncoding = compressed, codingGzip
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	setHTTPHeaders(ctx, req.Header)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	if t.compression != nil {
+		req.Header.Set("Accept-Encoding", codingGzip+", "+codingDeflate)
+	}
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	return req, nil
+}
+
+// readBody reads the response body, decompressing it and transcoding it into JSON if it is in
+// the transport's encoding.
+func (t *HTTPTransport) readBody(resp *http.Response) ([]byte, error) {
+	reader, err := decompress(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read http response: %w", err)
+	}
+	expectedSize := int(resp.ContentLength)
+	if reader != resp.Body {
+		expectedSize = 0
+	}
+	body, err := readAll(reader, defaultChunkSize, expectedSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read http response: %w", err)
+	}
+	if t.encoding == nil || len(body) == 0 {
+		return body, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != t.encoding.ContentType() {
+		return body, nil
+	}
+	decoded, err := t.encoding.Decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode http response: %w", err)
+	}
+	return decoded, nil
+}
+
+// Close releases idle connections held by the underlying HTTP client.
+func (t *HTTPTransport) Close() error {
+	if t.client == nil {
+		return errors.New("transport has no http client")
+	}
+	t.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,176 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// contentTypeStream is the media type of the bodies of HTTP streams, holding JSON-RPC messages
+// in HeaderFraming.
+const contentTypeStream = "application/jsonrpc-stream"
+
+// defaultHTTP2Client is the HTTP/2 client shared by transports and streams not given one.
+var defaultHTTP2Client = sync.OnceValue(func() *http.Client {
+	return NewHTTP2Client(nil)
+})
+
+// NewHTTP2Client returns an *http.Client speaking only HTTP/2: over TLS to https URLs, and
+// unencrypted with prior knowledge (h2c) to http URLs. Concurrent requests to a server are
+// multiplexed over a single connection as HTTP/2 streams, each with its own flow control, so
+// that many calls in flight do not need many connections. config tunes HTTP/2, such as the
+// flow control windows of MaxReceiveBufferPerConnection and MaxReceiveBufferPerStream; nil uses
+// the defaults.
+func NewHTTP2Client(config *http.HTTP2Config) *http.Client {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{
+		Protocols:         protocols,
+		HTTP2:             config,
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+// NewHTTP2Transport creates an HTTPTransport posting to the given URL over HTTP/2, as with a
+// client of NewHTTP2Client, for clients with many concurrent calls to a single upstream. The
+// server must speak HTTP/2, which http.Server does over TLS, and unencrypted once the
+// UnencryptedHTTP2 protocol is set in its Protocols.
+func NewHTTP2Transport(url string, opts ...HTTPOption) *HTTPTransport {
+	return NewHTTPTransport(url, append([]HTTPOption{WithHTTPClient(defaultHTTP2Client())},
+		opts...)...)
+}
+
+// httpStream is a Stream over the bodies of a long-lived HTTP request and its response.
+type httpStream struct {
+	*framedStream
+	cancel context.CancelFunc
+}
+
+// DialHTTPStream opens a long-lived POST to the endpoint at url served by ServeHTTPStream,
+// whose request body carries the messages to the server and whose response body the messages
+// from it, in HeaderFraming. Over HTTP/2 the stream is one of the multiplexed streams of the
+// connection, so that a client can keep several open to one server. The returned stream is
+// typically passed to NewStreamClient. Of the options, only WithHTTPClient and WithHTTPHeader
+// apply; the client defaults to one of NewHTTP2Client, as full-duplex requests need HTTP/2
+// through most proxies. The outgoing metadata of ctx is sent as request headers.
+func DialHTTPStream(ctx context.Context, url string, opts ...HTTPOption) (Stream, error) {
+	t := NewHTTPTransport(url, append([]HTTPOption{WithHTTPClient(defaultHTTP2Client())},
+		opts...)...)
+	reader, writer := io.Pipe()
+	// The stream outlives ctx, which only bounds opening it
+	streamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodPost, url, reader)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	setHTTPHeaders(ctx, req.Header)
+	req.Header.Set("Content-Type", contentTypeStream)
+	req.Header.Set("Accept", contentTypeStream)
+
+	type dialed struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan dialed, 1)
+	go func() {
+		//nolint:bodyclose // the response body is owned by the stream
+		resp, err := t.client.Do(req)
+		done <- dialed{resp, err}
+	}()
+	var result dialed
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		cancel()
+		return nil, result.err
+	}
+	if result.resp.StatusCode != http.StatusOK {
+		_ = result.resp.Body.Close()
+		cancel()
+		return nil, &HTTPError{StatusCode: result.resp.StatusCode}
+	}
+	return &httpStream{
+		framedStream: newFramedStream(result.resp.Body, writer, HeaderFraming),
+		cancel:       cancel,
+	}, nil
+}
+
+// Close ends the request body and the response, closing the stream.
+func (s *httpStream) Close() error {
+	err := s.framedStream.Close()
+	s.cancel()
+	return err
+}
+
+// flushWriter flushes every write to an HTTP response, until the handler ends.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// Write writes p to the response and flushes it.
+func (w *flushWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ended {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.rc.Flush()
+}
+
+// end stops writes, as the response is invalid once the handler returns, while writers of the
+// stream may still be running.
+func (w *flushWriter) end() {
+	w.mu.Lock()
+	w.ended = true
+	w.mu.Unlock()
+}
+
+// ServeHTTPStream serves the long-lived POST of a client of DialHTTPStream as a stream with
+// ServeStream until either peer ends it, so that handlers can call back to the client and push
+// notifications to it. It can be mounted on a mux directly:
+//
+//	mux.HandleFunc("/rpc/stream", srv.ServeHTTPStream)
+//
+// Streams need HTTP/2, or full-duplex HTTP/1.1 between the client and the server. Handlers
+// receive the request headers and remote address through IncomingMetadata. Under a message size
+// limit, a message declared larger ends the stream.
+func (s *Server) ServeHTTPStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rc := http.NewResponseController(w)
+	// HTTP/2 is full duplex already; HTTP/1.1 needs it enabled, if supported
+	_ = rc.EnableFullDuplex()
+	w.Header().Set("Content-Type", contentTypeStream)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	writer := &flushWriter{w: w, rc: rc}
+	defer writer.end()
+	stream := newFramedStream(r.Body, writer, HeaderFraming)
+	stream.limit(s.limits.maxMessageSize)
+	_ = s.ServeStream(httpContext(r), stream)
+}
@@ -0,0 +1,175 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// h2cServer serves handler over HTTP/1.1 and unencrypted HTTP/2, counting the connections it
+// accepts and recording the protocol of the last request.
+type h2cServer struct {
+	*httptest.Server
+	conns atomic.Int32
+	proto atomic.Int32
+}
+
+// newH2CServer starts an h2cServer for handler.
+func newH2CServer(t *testing.T, handler http.Handler) *h2cServer {
+	t.Helper()
+	hs := &h2cServer{}
+	hs.Server = httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			hs.proto.Store(int32(r.ProtoMajor))
+			handler.ServeHTTP(w, r)
+		}))
+	hs.Config.Protocols = new(http.Protocols)
+	hs.Config.Protocols.SetHTTP1(true)
+	hs.Config.Protocols.SetUnencryptedHTTP2(true)
+	hs.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			hs.conns.Add(1)
+		}
+	}
+	hs.Start()
+	t.Cleanup(hs.Close)
+	return hs
+}
+
+func TestHTTP2Transport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Multiplexes calls over one connection", func(t *testing.T) {
+		hs := newH2CServer(t, newTestServer(t))
+		client := NewClient(NewHTTP2Transport(hs.URL,
+			WithHTTPClient(NewHTTP2Client(&http.HTTP2Config{MaxReceiveBufferPerStream: 1 << 16}))))
+		defer client.Close()
+
+		var total int
+		require.NoError(t, client.Call(ctx, "sum", []int{1, 2}, &total))
+		assert.Equal(t, int32(2), hs.proto.Load())
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Go(func() {
+				var got int
+				assert.NoError(t, client.Call(ctx, "sum", []int{i, 1}, &got))
+				assert.Equal(t, i+1, got)
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), hs.conns.Load())
+	})
+
+	t.Run("Over TLS", func(t *testing.T) {
+		var proto atomic.Int32
+		srv := newTestServer(t)
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				proto.Store(int32(r.ProtoMajor))
+				srv.ServeHTTP(w, r)
+			}))
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		defer ts.Close()
+
+		httpClient := NewHTTP2Client(nil)
+		tlsConfig := ts.Client().Transport.(*http.Transport).TLSClientConfig
+		httpClient.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+		client := NewClient(NewHTTP2Transport(ts.URL, WithHTTPClient(httpClient)))
+		defer client.Close()
+
+		var total int
+		require.NoError(t, client.Call(ctx, "sum", []int{2, 3}, &total))
+		assert.Equal(t, 5, total)
+		assert.Equal(t, int32(2), proto.Load())
+	})
+}
+
+func TestHTTPStream(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Calls and pushes over one POST", func(t *testing.T) {
+		srv := newTestServer(t)
+		conns := make(chan *Conn, 1)
+		watch := func(ctx context.Context, _ *Request) (any, error) {
+			conn, _ := ConnFromContext(ctx)
+			conns <- conn
+			return true, nil
+		}
+		require.NoError(t, srv.RegisterFunc("watch", watch))
+		hs := newH2CServer(t, http.HandlerFunc(srv.ServeHTTPStream))
+
+		received := make(chan string, 1)
+		clientSrv := NewServer()
+		event := func(_ context.Context, req *Request) (any, error) {
+			var params []string
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			received <- params[0]
+			return nil, nil
+		}
+		require.NoError(t, clientSrv.RegisterFunc("event", event))
+
+		stream, err := DialHTTPStream(ctx, hs.URL, WithHTTPHeader("X-Token", "t"))
+		require.NoError(t, err)
+		client := NewStreamClient(stream, WithServer(clientSrv))
+		defer client.Close()
+
+		var total int
+		require.NoError(t, client.Call(ctx, "sum", []int{1, 2, 3}, &total))
+		assert.Equal(t, 6, total)
+		assert.Equal(t, int32(2), hs.proto.Load())
+
+		require.NoError(t, client.Call(ctx, "watch", nil, nil))
+		require.NoError(t, (<-conns).Notify("event", []string{"tick"}))
+		select {
+		case got := <-received:
+			assert.Equal(t, "tick", got)
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+
+		require.NoError(t, client.Close())
+		require.Eventually(t, func() bool { return len(srv.Conns()) == 0 }, time.Second,
+			10*time.Millisecond)
+	})
+
+	t.Run("Oversized messages end the stream", func(t *testing.T) {
+		srv := NewServer(WithMaxMessageSize(64))
+		hs := newH2CServer(t, http.HandlerFunc(srv.ServeHTTPStream))
+		stream, err := DialHTTPStream(ctx, hs.URL)
+		require.NoError(t, err)
+		client := NewStreamClient(stream)
+		defer client.Close()
+
+		_ = client.Notify(ctx, "m", []string{string(make([]byte, 100))})
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("stream not ended")
+		}
+	})
+
+	t.Run("Rejects other methods", func(t *testing.T) {
+		hs := newH2CServer(t, http.HandlerFunc(NewServer().ServeHTTPStream))
+		resp, err := http.Get(hs.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+		_, err = DialHTTPStream(ctx, newH2CServer(t, http.NotFoundHandler()).URL)
+		var httpErr *HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	})
+}
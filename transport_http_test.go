@@ -0,0 +1,94 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTransport_RoundTrip(t *testing.T) {
+	t.Run("Posts JSON and returns the body", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+			body, _ := io.ReadAll(r.Body)
+			req, err := DecodeRequest(body)
+			require.NoError(t, err)
+			resp, _ := NewResponse(req.ID, "pong")
+			_, _ = resp.WriteTo(w)
+		}))
+		defer srv.Close()
+
+		client := NewClient(NewHTTPTransport(srv.URL, WithHTTPHeader("X-Api-Key", "secret")))
+		defer client.Close()
+
+		var result string
+		require.NoError(t, client.Call(context.Background(), "ping", nil, &result))
+		assert.Equal(t, "pong", result)
+	})
+
+	t.Run("No content yields nil reply", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		reply, err := NewHTTPTransport(srv.URL).RoundTrip(context.Background(), []byte(`{}`))
+		require.NoError(t, err)
+		assert.Nil(t, reply)
+	})
+
+	t.Run("Non-2xx with JSON body is returned", func(t *testing.T) {
+		body := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(body))
+		}))
+		defer srv.Close()
+
+		reply, err := NewHTTPTransport(srv.URL).RoundTrip(context.Background(), []byte(`{`))
+		require.NoError(t, err)
+		assert.JSONEq(t, body, string(reply))
+	})
+
+	t.Run("Non-2xx without JSON body is an HTTPError", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		_, err := NewHTTPTransport(srv.URL).RoundTrip(context.Background(), []byte(`{}`))
+		var httpErr *HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+		assert.Contains(t, httpErr.Error(), "bad gateway")
+	})
+
+	t.Run("Custom http client is used", func(t *testing.T) {
+		called := false
+		offline := roundTripperFunc(func(_ *http.Request) (*http.Response, error) {
+			called = true
+			return nil, errors.New("offline")
+		})
+		httpClient := &http.Client{Transport: offline}
+
+		_, err := NewHTTPTransport("http://example.invalid", WithHTTPClient(httpClient)).
+			RoundTrip(context.Background(), []byte(`{}`))
+		require.Error(t, err)
+		assert.True(t, called)
+	})
+}
+
+// roundTripperFunc adapts a function into an http.RoundTripper, for tests.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
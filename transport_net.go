@@ -0,0 +1,228 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// errNoCertificates is returned by Listen for TLS configurations without certificates.
+var errNoCertificates = errors.New(
+	"tls: neither Certificates, GetCertificate, nor GetConfigForClient set in Config")
+
+// connConfig holds the settings shared by DialConn, Listen, and NewConnStream.
+type connConfig struct {
+	framing      Framing
+	maxFrameSize int
+	tlsConfig    *tls.Config
+	keepAlive    *net.KeepAliveConfig
+	policy       *ListenerPolicy
+}
+
+// ConnOption configures connection-based streams.
+type ConnOption func(*connConfig)
+
+// WithFraming sets how messages are delimited on the connection. Defaults to LineFraming.
+func WithFraming(framing Framing) ConnOption {
+	return func(c *connConfig) {
+		c.framing = framing
+	}
+}
+
+// WithMaxFrameSize sets the maximum size in bytes of a single incoming message. A larger message
+// fails the read with ErrLimitExceeded before it is buffered, ending the stream. Defaults to
+// 32 MiB; non-positive values keep the default.
+func WithMaxFrameSize(n int) ConnOption {
+	return func(c *connConfig) {
+		c.maxFrameSize = n
+	}
+}
+
+// WithTLSConfig enables TLS with the given configuration. Listen requires it to hold at least one
+// certificate; DialConn uses it as the client configuration.
+func WithTLSConfig(cfg *tls.Config) ConnOption {
+	return func(c *connConfig) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithTCPKeepAlive sets the TCP keepalive probes of TCP connections, which let the operating
+// system detect dead peers and half-open connections even while no message is exchanged. Without
+// it, connections keep the defaults of the net package. Unlike WithKeepalive, it does not close
+// the stream client itself: reads fail once the system gives up on the connection.
+func WithTCPKeepAlive(cfg net.KeepAliveConfig) ConnOption {
+	return func(c *connConfig) {
+		c.keepAlive = &cfg
+	}
+}
+
+// newConnConfig applies opts over the defaults.
+func newConnConfig(opts []ConnOption) *connConfig {
+	cfg := &connConfig{framing: LineFraming}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// connStream is a framed Stream over a net.Conn. Unlike a plain framed stream, its reads are
+// interrupted when the read context is done.
+type connStream struct {
+	Stream
+	conn net.Conn
+}
+
+// NewConnStream creates a Stream over an established connection, such as a TCP or Unix socket.
+// Messages are newline-delimited unless WithFraming says otherwise; TLS options are ignored, as
+// the connection is already established.
+func NewConnStream(conn net.Conn, opts ...ConnOption) Stream {
+	cfg := newConnConfig(opts)
+	if cfg.keepAlive != nil {
+		setKeepAlive(conn, *cfg.keepAlive)
+	}
+	stream := newFramedStream(conn, conn, cfg.framing)
+	stream.limit(cfg.maxFrameSize)
+	return &connStream{Stream: stream, conn: conn}
+}
+
+// setKeepAlive applies the keepalive settings to conn if it is a TCP connection, possibly wrapped
+// in TLS.
+func setKeepAlive(conn net.Conn, cfg net.KeepAliveConfig) {
+	raw := conn
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		raw = tlsConn.NetConn()
+	}
+	if tcpConn, ok := raw.(*net.TCPConn); ok {
+		_ = tcpConn.SetKeepAliveConfig(cfg)
+	}
+}
+
+// ReadMessage reads the next message, returning ctx.Err() if ctx is done first.
+func (s *connStream) ReadMessage(ctx context.Context) ([]byte, error) {
+	// Unblock the read by expiring the deadline when ctx is done
+	expired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = s.conn.SetReadDeadline(time.Now())
+		close(expired)
+	})
+
+	msg, err := s.Stream.ReadMessage(ctx)
+	if !stop() {
+		// Clear the expired deadline so that reads with another context still work
+		<-expired
+		_ = s.conn.SetReadDeadline(time.Time{})
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+	}
+	return msg, err
+}
+
+// DialConn connects to address on the named network ("tcp", "unix", ...) and returns a Stream
+// over the connection, typically passed to NewStreamClient.
+func DialConn(ctx context.Context, network, address string, opts ...ConnOption) (Stream, error) {
+	cfg := newConnConfig(opts)
+
+	var conn net.Conn
+	var err error
+	if cfg.tlsConfig != nil {
+		dialer := &tls.Dialer{Config: cfg.tlsConfig}
+		conn, err = dialer.DialContext(ctx, network, address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewConnStream(conn, opts...), nil
+}
+
+// Listen announces on the local network address, enforcing the WithListenerPolicy policy, if any,
+// and wrapping accepted connections in TLS when WithTLSConfig is given. The listener is typically
+// passed to Server.Serve.
+func Listen(network, address string, opts ...ConnOption) (net.Listener, error) {
+	cfg := newConnConfig(opts)
+	if cfg.tlsConfig != nil && len(cfg.tlsConfig.Certificates) == 0 &&
+		cfg.tlsConfig.GetCertificate == nil && cfg.tlsConfig.GetConfigForClient == nil {
+		return nil, errNoCertificates
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.policy != nil {
+		l = NewPolicyListener(l, *cfg.policy)
+	}
+	if cfg.tlsConfig != nil {
+		l = tls.NewListener(l, cfg.tlsConfig)
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l and serves each one with ServeStream in its own goroutine, with
+// handlers able to call back to the connected peer through ClientFromContext and to read its
+// address under PeerMetadataKey in IncomingMetadata, and for TLS connections its certificate
+// with PeerCertificate. Messages are newline-delimited unless
+// WithFraming says otherwise.
+//
+// Serve returns when accepting fails, returning nil if l was closed, or when ctx is done. In the
+// latter case it closes l and every connection it served, waits for them to finish, and returns
+// ctx.Err(). Shutdown closes l too, and Serve then returns ErrServerClosed once the connections
+// are closed.
+func (s *Server) Serve(ctx context.Context, l net.Listener, opts ...ConnOption) error {
+	if !s.drain.track(l) {
+		_ = l.Close()
+		return ErrServerClosed
+	}
+	defer s.drain.untrack(l)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = l.Close()
+	})
+	defer stop()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if s.isShuttingDown() {
+				// Let Shutdown close the connections once drained
+				wg.Wait()
+				return ErrServerClosed
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		wg.Go(func() { s.serveConn(connCtx, conn, opts) })
+	}
+}
+
+// serveConn serves an accepted connection, completing its TLS handshake first, if it is one, so
+// that handlers find its connection state with ConnectionStateFromContext.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn, opts []ConnOption) {
+	connCtx := NewIncomingContext(ctx, MetadataPairs(PeerMetadataKey, conn.RemoteAddr().String()))
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return
+		}
+		state := tlsConn.ConnectionState()
+		connCtx = WithConnectionState(connCtx, &state)
+	}
+	_ = s.ServeStream(connCtx, NewConnStream(conn, opts...))
+}
@@ -0,0 +1,188 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveListener runs srv on l until the test ends.
+func serveListener(t *testing.T, srv *Server, l net.Listener, opts ...ConnOption) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = srv.Serve(ctx, l, opts...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// selfSignedTLS returns server and client TLS configurations trusting a fresh certificate for
+// 127.0.0.1.
+func selfSignedTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	serverCfg := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+	clientCfg := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return serverCfg, clientCfg
+}
+
+func TestServer_Serve(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		address func(t *testing.T) string
+		opts    []ConnOption
+	}{
+		{
+			name:    "TCP",
+			network: "tcp",
+			address: func(*testing.T) string { return "127.0.0.1:0" },
+		},
+		{
+			name:    "Unix",
+			network: "unix",
+			address: func(t *testing.T) string { return filepath.Join(t.TempDir(), "rpc.sock") },
+		},
+		{
+			name:    "TCP with header framing",
+			network: "tcp",
+			address: func(*testing.T) string { return "127.0.0.1:0" },
+			opts:    []ConnOption{WithFraming(HeaderFraming)},
+		},
+		{
+			name:    "TCP with keepalive probes",
+			network: "tcp",
+			address: func(*testing.T) string { return "127.0.0.1:0" },
+			opts: []ConnOption{WithTCPKeepAlive(net.KeepAliveConfig{
+				Enable:   true,
+				Idle:     time.Second,
+				Interval: time.Second,
+				Count:    3,
+			})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Listen(tt.network, tt.address(t))
+			require.NoError(t, err)
+			serveListener(t, newTestServer(t), l, tt.opts...)
+
+			stream, err := DialConn(context.Background(), tt.network, l.Addr().String(), tt.opts...)
+			require.NoError(t, err)
+			client := NewStreamClient(stream)
+			defer client.Close()
+
+			var result int
+			require.NoError(t, client.Call(context.Background(), "sum", []any{1, 2, 3}, &result))
+			assert.Equal(t, 6, result)
+		})
+	}
+
+	t.Run("TLS", func(t *testing.T) {
+		serverCfg, clientCfg := selfSignedTLS(t)
+		l, err := Listen("tcp", "127.0.0.1:0", WithTLSConfig(serverCfg))
+		require.NoError(t, err)
+		serveListener(t, newTestServer(t), l)
+
+		addr := l.Addr().String()
+		stream, err := DialConn(context.Background(), "tcp", addr, WithTLSConfig(clientCfg))
+		require.NoError(t, err)
+		client := NewStreamClient(stream)
+		defer client.Close()
+
+		var result int
+		require.NoError(t, client.Call(context.Background(), "subtract", []any{5, 3}, &result))
+		assert.Equal(t, 2, result)
+	})
+
+	t.Run("Cancellation closes listener and connections", func(t *testing.T) {
+		l, err := Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		serveErr := make(chan error, 1)
+		go func() { serveErr <- newTestServer(t).Serve(ctx, l) }()
+
+		stream, err := DialConn(context.Background(), "tcp", l.Addr().String())
+		require.NoError(t, err)
+		client := NewStreamClient(stream)
+		defer client.Close()
+		require.NoError(t, client.Call(context.Background(), "sum", []any{1}, nil))
+
+		cancel()
+		select {
+		case err := <-serveErr:
+			require.ErrorIs(t, err, context.Canceled)
+		case <-time.After(time.Second):
+			t.Fatal("Serve did not return after cancellation")
+		}
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("connection was not closed")
+		}
+	})
+}
+
+func TestConnStream_ReadMessage(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	s := NewConnStream(a)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := s.ReadMessage(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The stream stays usable with another context
+	go func() { _, _ = b.Write([]byte("{\"a\":1}\n")) }()
+	msg, err := s.ReadMessage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(msg))
+}
+
+func TestConnStream_MaxFrameSize(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	s := NewConnStream(a, WithMaxFrameSize(4))
+	defer s.Close()
+
+	go func() { _, _ = b.Write([]byte("{\"a\":1}\n")) }()
+	_, err := s.ReadMessage(context.Background())
+	require.ErrorIs(t, err, ErrLimitExceeded)
+}
@@ -0,0 +1,381 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for PoolTransport.
+const (
+	defaultMaxFailures      = 3
+	defaultEjectionCooldown = 30 * time.Second
+
+	// latencySmoothing is the weight of the latest sample in an endpoint's latency average.
+	latencySmoothing = 0.3
+)
+
+// errNoEndpoints is returned by a PoolTransport without endpoints.
+var errNoEndpoints = errors.New("pool has no endpoints")
+
+// Balancing selects how a PoolTransport spreads calls over its healthy endpoints.
+type Balancing int
+
+const (
+	// Failover sends every call to the first healthy endpoint, in the order given, moving on to
+	// the next one only when it fails.
+	Failover Balancing = iota
+
+	// RoundRobin rotates calls over the healthy endpoints.
+	RoundRobin
+
+	// LeastLatency sends calls to the healthy endpoint with the lowest average latency.
+	LeastLatency
+)
+
+// PoolEndpoint is an upstream endpoint of a PoolTransport.
+type PoolEndpoint struct {
+	// Name identifies the endpoint in EndpointStatus, typically its URL.
+	Name string
+
+	// Transport carries calls to the endpoint.
+	Transport Transport
+}
+
+// EndpointStatus is a snapshot of the state of a PoolTransport endpoint.
+type EndpointStatus struct {
+	Name     string
+	Healthy  bool
+	Failures int
+	Latency  time.Duration
+}
+
+// PoolTransport is a Transport spreading calls over several upstream endpoints, such as the
+// nodes of an RPC provider. Endpoints failing several times in a row are ejected for a cooldown
+// period, and a failed call moves on to the next endpoint, so that one unhealthy node does not
+// fail the call.
+//
+// Only failures to exchange the payload, such as connection errors, timeouts, and 5xx or 429
+// HTTP statuses, are failed over; replies, including JSON-RPC errors, are returned as-is. A call
+// may thus reach a second endpoint when the first failed after receiving it.
+// A PoolTransport is safe for concurrent use.
+type PoolTransport struct {
+	endpoints []*poolEndpoint
+	next      atomic.Uint64
+
+	balancing       Balancing
+	maxFailures     int
+	cooldown        time.Duration
+	endpointTimeout time.Duration
+	healthMethod    string
+	healthInterval  time.Duration
+	httpOpts        []HTTPOption
+	hedging         HedgePolicy
+
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// poolEndpoint is an endpoint along with its health state.
+type poolEndpoint struct {
+	name      string
+	transport Transport
+
+	mu        sync.Mutex
+	failures  int
+	downUntil time.Time
+	latency   time.Duration
+}
+
+// PoolOption configures a PoolTransport.
+type PoolOption func(*PoolTransport)
+
+// WithBalancing sets how calls are spread over healthy endpoints. Defaults to Failover.
+func WithBalancing(balancing Balancing) PoolOption {
+	return func(p *PoolTransport) {
+		p.balancing = balancing
+	}
+}
+
+// WithEjection sets how many consecutive failures eject an endpoint and for how long it is
+// skipped afterwards, after which it gets calls again. Defaults to 3 failures and 30 seconds.
+func WithEjection(maxFailures int, cooldown time.Duration) PoolOption {
+	return func(p *PoolTransport) {
+		p.maxFailures = maxFailures
+		p.cooldown = cooldown
+	}
+}
+
+// WithEndpointTimeout bounds each attempt on an endpoint, so that a hanging endpoint fails over
+// to the next one before the call's own deadline. Disabled by default.
+func WithEndpointTimeout(timeout time.Duration) PoolOption {
+	return func(p *PoolTransport) {
+		p.endpointTimeout = timeout
+	}
+}
+
+// WithHealthCheck calls method on every endpoint at the given interval. Checks failing to get an
+// answer count as failed calls towards ejection, while any answer, even a JSON-RPC error, restores
+// an ejected endpoint.
+func WithHealthCheck(method string, interval time.Duration) PoolOption {
+	return func(p *PoolTransport) {
+		p.healthMethod = method
+		p.healthInterval = interval
+	}
+}
+
+// WithPoolHTTPOptions sets the options of the HTTP transports created by NewHTTPPool, such as a
+// shared *http.Client tuned for connection pooling.
+func WithPoolHTTPOptions(opts ...HTTPOption) PoolOption {
+	return func(p *PoolTransport) {
+		p.httpOpts = append(p.httpOpts, opts...)
+	}
+}
+
+// NewPoolTransport creates a PoolTransport over the given endpoints.
+func NewPoolTransport(endpoints []PoolEndpoint, opts ...PoolOption) *PoolTransport {
+	p := newPool(opts)
+	for _, ep := range endpoints {
+		p.endpoints = append(p.endpoints, &poolEndpoint{name: ep.Name, transport: ep.Transport})
+	}
+	p.start()
+	return p
+}
+
+// NewHTTPPool creates a PoolTransport posting to the given URLs, each through an HTTPTransport.
+func NewHTTPPool(urls []string, opts ...PoolOption) *PoolTransport {
+	p := newPool(opts)
+	for _, url := range urls {
+		p.endpoints = append(p.endpoints, &poolEndpoint{
+			name:      url,
+			transport: NewHTTPTransport(url, p.httpOpts...),
+		})
+	}
+	p.start()
+	return p
+}
+
+// newPool creates a pool without endpoints, applying opts over the defaults.
+func newPool(opts []PoolOption) *PoolTransport {
+	p := &PoolTransport{
+		maxFailures: defaultMaxFailures,
+		cooldown:    defaultEjectionCooldown,
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// start launches the health checker, if configured.
+func (p *PoolTransport) start() {
+	if p.healthMethod == "" || p.healthInterval <= 0 {
+		return
+	}
+	p.wg.Go(p.healthLoop)
+}
+
+// RoundTrip sends payload to an endpoint chosen by the balancing policy, failing over to the
+// other endpoints in turn. Ejected endpoints are only tried when no healthy one is left. Calls
+// matched by the WithHedging policy are hedged over the endpoints in the same order.
+func (p *PoolTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	if len(p.endpoints) == 0 {
+		return nil, errNoEndpoints
+	}
+	if p.shouldHedge(payload) {
+		return p.hedge(ctx, payload)
+	}
+
+	var errs []error
+	for _, ep := range p.candidates() {
+		reply, err := p.attempt(ctx, ep, payload)
+		if err == nil {
+			return reply, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil || !isRetryableError(err) {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// attempt sends payload to one endpoint and records the outcome.
+func (p *PoolTransport) attempt(ctx context.Context, ep *poolEndpoint, payload []byte) (
+	[]byte,
+	error,
+) {
+	attemptCtx := ctx
+	if p.endpointTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, p.endpointTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	reply, err := ep.transport.RoundTrip(attemptCtx, payload)
+	switch {
+	case err == nil:
+		ep.succeed(time.Since(start))
+		recordEndpoint(ctx, ep.name)
+	case ctx.Err() == nil && isRetryableError(err):
+		// Failures of the caller's context or request say nothing about the endpoint
+		ep.fail(p.maxFailures, p.cooldown)
+	default:
+	}
+	return reply, err
+}
+
+// candidates returns the endpoints in the order to try them: healthy endpoints ordered by the
+// balancing policy, followed by ejected ones.
+func (p *PoolTransport) candidates() []*poolEndpoint {
+	now := time.Now()
+	healthy := make([]*poolEndpoint, 0, len(p.endpoints))
+	var ejected []*poolEndpoint
+	for _, ep := range p.endpoints {
+		if ep.isHealthy(now) {
+			healthy = append(healthy, ep)
+		} else {
+			ejected = append(ejected, ep)
+		}
+	}
+
+	switch p.balancing {
+	case RoundRobin:
+		if n := len(healthy); n > 1 {
+			shift := int(p.next.Add(1) % uint64(n))
+			healthy = append(healthy[shift:], healthy[:shift]...)
+		}
+	case LeastLatency:
+		slices.SortStableFunc(healthy, func(a, b *poolEndpoint) int {
+			return int(a.averageLatency() - b.averageLatency())
+		})
+	default:
+	}
+	return append(healthy, ejected...)
+}
+
+// Endpoints returns the current state of every endpoint, in the order given at creation.
+func (p *PoolTransport) Endpoints() []EndpointStatus {
+	now := time.Now()
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		ep.mu.Lock()
+		statuses[i] = EndpointStatus{
+			Name:     ep.name,
+			Healthy:  !now.Before(ep.downUntil),
+			Failures: ep.failures,
+			Latency:  ep.latency,
+		}
+		ep.mu.Unlock()
+	}
+	return statuses
+}
+
+// Close stops the health checker and closes every endpoint's transport.
+func (p *PoolTransport) Close() error {
+	var errs []error
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		p.wg.Wait()
+		for _, ep := range p.endpoints {
+			if err := ep.transport.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// healthLoop checks every endpoint at the health check interval until the pool is closed.
+func (p *PoolTransport) healthLoop() {
+	ticker := time.NewTicker(p.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+// checkHealth calls the health check method on every endpoint concurrently.
+func (p *PoolTransport) checkHealth() {
+	payload, err := NewRequestWithID(p.healthMethod, nil, "health").MarshalJSON()
+	if err != nil {
+		return
+	}
+
+	timeout := p.healthInterval
+	if p.endpointTimeout > 0 {
+		timeout = min(timeout, p.endpointTimeout)
+	}
+
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			start := time.Now()
+			reply, err := ep.transport.RoundTrip(ctx, payload)
+			if err == nil {
+				_, _, err = DecodeResponseOrBatch(reply)
+			}
+			if err != nil {
+				ep.fail(p.maxFailures, p.cooldown)
+				return
+			}
+			ep.succeed(time.Since(start))
+		})
+	}
+	wg.Wait()
+}
+
+// isHealthy reports whether the endpoint is not ejected at time now.
+func (ep *poolEndpoint) isHealthy(now time.Time) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return !now.Before(ep.downUntil)
+}
+
+// averageLatency returns the endpoint's smoothed latency, zero if it has not answered yet.
+func (ep *poolEndpoint) averageLatency() time.Duration {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.latency
+}
+
+// succeed records a successful exchange taking the given time.
+func (ep *poolEndpoint) succeed(latency time.Duration) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	ep.failures = 0
+	ep.downUntil = time.Time{}
+	if ep.latency == 0 {
+		ep.latency = latency
+		return
+	}
+	ep.latency = time.Duration(latencySmoothing*float64(latency) +
+		(1-latencySmoothing)*float64(ep.latency))
+}
+
+// fail records a failed exchange, ejecting the endpoint after maxFailures in a row.
+func (ep *poolEndpoint) fail(maxFailures int, cooldown time.Duration) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	ep.failures++
+	if ep.failures >= maxFailures {
+		ep.downUntil = time.Now().Add(cooldown)
+	}
+}
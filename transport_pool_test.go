@@ -0,0 +1,286 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEndpoint returns a pool endpoint answering with its name, or failing with err, and
+// counting its calls.
+func countingEndpoint(name string, calls *atomic.Int32, err error) PoolEndpoint {
+	return PoolEndpoint{Name: name, Transport: &funcTransport{
+		fn: func(context.Context, []byte) ([]byte, error) {
+			calls.Add(1)
+			if err != nil {
+				return nil, err
+			}
+			return []byte(name), nil
+		},
+	}}
+}
+
+func TestPoolTransport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Failover prefers the first endpoint", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, nil),
+			countingEndpoint("b", &b, nil),
+		})
+		defer pool.Close()
+
+		for range 3 {
+			reply, err := pool.RoundTrip(ctx, []byte(`{}`))
+			require.NoError(t, err)
+			assert.Equal(t, "a", string(reply))
+		}
+		assert.Equal(t, int32(3), a.Load())
+		assert.Zero(t, b.Load())
+	})
+
+	t.Run("Fails over on transport errors", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, assert.AnError),
+			countingEndpoint("b", &b, nil),
+		})
+		defer pool.Close()
+
+		reply, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(reply))
+		assert.Equal(t, 1, pool.Endpoints()[0].Failures)
+	})
+
+	t.Run("Does not fail over on client errors", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, &HTTPError{StatusCode: http.StatusBadRequest}),
+			countingEndpoint("b", &b, nil),
+		})
+		defer pool.Close()
+
+		_, err := pool.RoundTrip(ctx, []byte(`{}`))
+		var httpErr *HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Zero(t, b.Load())
+		assert.Zero(t, pool.Endpoints()[0].Failures)
+	})
+
+	t.Run("All failing joins the errors", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, assert.AnError),
+			countingEndpoint("b", &b, assert.AnError),
+		})
+		defer pool.Close()
+
+		_, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, int32(1), a.Load())
+		assert.Equal(t, int32(1), b.Load())
+	})
+
+	t.Run("No endpoints", func(t *testing.T) {
+		pool := NewPoolTransport(nil)
+		defer pool.Close()
+
+		_, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.ErrorIs(t, err, errNoEndpoints)
+	})
+
+	t.Run("Round robin rotates endpoints", func(t *testing.T) {
+		var a, b, c atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, nil),
+			countingEndpoint("b", &b, nil),
+			countingEndpoint("c", &c, nil),
+		}, WithBalancing(RoundRobin))
+		defer pool.Close()
+
+		for range 6 {
+			_, err := pool.RoundTrip(ctx, []byte(`{}`))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), a.Load())
+		assert.Equal(t, int32(2), b.Load())
+		assert.Equal(t, int32(2), c.Load())
+	})
+
+	t.Run("Least latency prefers the fastest endpoint", func(t *testing.T) {
+		slow := PoolEndpoint{Name: "slow", Transport: &funcTransport{
+			fn: func(context.Context, []byte) ([]byte, error) {
+				return []byte("slow"), nil
+			},
+		}}
+		var fast atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{slow, countingEndpoint("fast", &fast, nil)},
+			WithBalancing(LeastLatency))
+		defer pool.Close()
+
+		pool.endpoints[0].succeed(20 * time.Millisecond)
+		pool.endpoints[1].succeed(time.Millisecond)
+
+		reply, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, "fast", string(reply))
+	})
+
+	t.Run("Endpoint timeout fails over", func(t *testing.T) {
+		hanging := PoolEndpoint{Name: "hanging", Transport: &funcTransport{
+			fn: func(ctx context.Context, _ []byte) ([]byte, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		}}
+		var b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{hanging, countingEndpoint("b", &b, nil)},
+			WithEndpointTimeout(10*time.Millisecond))
+		defer pool.Close()
+
+		reply, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(reply))
+	})
+
+	t.Run("Canceled context does not fail over", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, context.Canceled),
+			countingEndpoint("b", &b, nil),
+		})
+		defer pool.Close()
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := pool.RoundTrip(canceled, []byte(`{}`))
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, b.Load())
+		assert.Zero(t, pool.Endpoints()[0].Failures)
+	})
+}
+
+func TestPoolTransport_Ejection(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Ejects after consecutive failures", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, assert.AnError),
+			countingEndpoint("b", &b, nil),
+		}, WithEjection(2, time.Hour))
+		defer pool.Close()
+
+		for range 4 {
+			_, err := pool.RoundTrip(ctx, []byte(`{}`))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), a.Load())
+		assert.Equal(t, int32(4), b.Load())
+		assert.False(t, pool.Endpoints()[0].Healthy)
+		assert.True(t, pool.Endpoints()[1].Healthy)
+	})
+
+	t.Run("Ejected endpoints are tried when none is healthy", func(t *testing.T) {
+		var a, b atomic.Int32
+		var failing atomic.Bool
+		failing.Store(true)
+		flaky := PoolEndpoint{Name: "a", Transport: &funcTransport{
+			fn: func(context.Context, []byte) ([]byte, error) {
+				a.Add(1)
+				if failing.Load() {
+					return nil, assert.AnError
+				}
+				return []byte("a"), nil
+			},
+		}}
+		pool := NewPoolTransport([]PoolEndpoint{flaky, countingEndpoint("b", &b, assert.AnError)},
+			WithEjection(1, time.Hour))
+		defer pool.Close()
+
+		_, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.Error(t, err)
+
+		failing.Store(false)
+		reply, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(reply))
+		assert.True(t, pool.Endpoints()[0].Healthy, "success restores the endpoint")
+	})
+
+	t.Run("Cooldown expiry restores the endpoint", func(t *testing.T) {
+		var a, b atomic.Int32
+		pool := NewPoolTransport([]PoolEndpoint{
+			countingEndpoint("a", &a, assert.AnError),
+			countingEndpoint("b", &b, nil),
+		}, WithEjection(1, 10*time.Millisecond))
+		defer pool.Close()
+
+		_, err := pool.RoundTrip(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		assert.False(t, pool.Endpoints()[0].Healthy)
+
+		assert.Eventually(t, func() bool {
+			return pool.Endpoints()[0].Healthy
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestPoolTransport_HealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	srv := NewServer()
+	health := HandlerFunc(func(context.Context, *Request) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, srv.Register("health", health))
+	up := httptest.NewServer(srv)
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthy.Load() {
+			srv.ServeHTTP(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	pool := NewHTTPPool([]string{down.URL, up.URL},
+		WithHealthCheck("health", 10*time.Millisecond), WithEjection(2, time.Hour))
+	defer pool.Close()
+
+	assert.Eventually(t, func() bool {
+		return !pool.Endpoints()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+	assert.True(t, pool.Endpoints()[1].Healthy)
+	assert.Equal(t, down.URL, pool.Endpoints()[0].Name)
+
+	client := NewClient(pool)
+	var result string
+	require.NoError(t, client.Call(context.Background(), "health", nil, &result))
+	assert.Equal(t, "ok", result)
+
+	healthy.Store(true)
+	assert.Eventually(t, func() bool {
+		return pool.Endpoints()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestPoolTransport_Close(t *testing.T) {
+	a := &funcTransport{}
+	b := &funcTransport{}
+	pool := NewPoolTransport([]PoolEndpoint{{Name: "a", Transport: a}, {Name: "b", Transport: b}},
+		WithHealthCheck("health", time.Hour))
+
+	require.NoError(t, pool.Close())
+	require.NoError(t, pool.Close())
+	assert.True(t, a.closed)
+	assert.True(t, b.closed)
+}
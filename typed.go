@@ -0,0 +1,72 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+)
+
+// DecodeParams decodes the request's params into a value of type T with Request.BindParams, so
+// struct types accept both named and positional params. On failure it returns an *Error with the
+// InvalidParams code, which a handler can return as-is:
+//
+//	args, err := jsonrpc.DecodeParams[GetUserArgs](req)
+//	if err != nil {
+//	    return nil, err
+//	}
+func DecodeParams[T any](req *Request) (T, error) {
+	var params T
+	if err := req.BindParams(&params); err != nil {
+		return params, &Error{Code: InvalidParams, Message: msgInvalidParams, Data: err.Error()}
+	}
+	return params, nil
+}
+
+// DecodeResult unmarshals the response's result into a value of type T. If the response holds a
+// JSON-RPC error, it is returned as an *Error instead.
+func DecodeResult[T any](resp *Response) (T, error) {
+	var result T
+	if rpcErr := resp.Err(); rpcErr != nil {
+		return result, rpcErr
+	}
+	if err := resp.UnmarshalResult(&result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// DecodeErrorData extracts the data of the *Error that err is or wraps into a value of type T,
+// such as an application-defined error detail struct:
+//
+//	details, err := jsonrpc.DecodeErrorData[RevertDetails](callErr)
+//
+// It fails if err holds no *Error, or the error carries no data or data of another shape.
+func DecodeErrorData[T any](err error) (T, error) {
+	var data T
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		return data, errors.New("error is not a JSON-RPC error")
+	}
+	if unmarshalErr := rpcErr.UnmarshalData(&data); unmarshalErr != nil {
+		return data, unmarshalErr
+	}
+	return data, nil
+}
+
+// Call invokes method on the client and returns the result unmarshaled into a value of type R.
+// Errors and options are as for Client.Call, with JSON-RPC errors returned as an *Error:
+//
+//	balance, err := jsonrpc.Call[int64](ctx, client, "getBalance", []any{"alice"},
+//		jsonrpc.WithCallTimeout(time.Second))
+func Call[R any](
+	ctx context.Context,
+	client *Client,
+	method string,
+	params any,
+	opts ...CallOption,
+) (R, error) {
+	var result R
+	if err := client.Call(ctx, method, params, &result, opts...); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeParams(t *testing.T) {
+	type args struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	t.Run("Object into struct", func(t *testing.T) {
+		req := NewRequest("m", map[string]any{"name": "ann", "age": 30})
+		got, err := DecodeParams[args](req)
+		require.NoError(t, err)
+		assert.Equal(t, args{Name: "ann", Age: 30}, got)
+	})
+
+	t.Run("Array into struct", func(t *testing.T) {
+		got, err := DecodeParams[args](NewRequest("m", []any{"ann", 30}))
+		require.NoError(t, err)
+		assert.Equal(t, args{Name: "ann", Age: 30}, got)
+	})
+
+	t.Run("Array into slice", func(t *testing.T) {
+		got, err := DecodeParams[[]int](NewRequest("m", []any{1, 2}))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, got)
+	})
+
+	t.Run("Mismatch is invalid params", func(t *testing.T) {
+		_, err := DecodeParams[args](NewRequest("m", []any{1, 2}))
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+		assert.NotEmpty(t, rpcErr.Data)
+	})
+
+	t.Run("Missing params is invalid params", func(t *testing.T) {
+		_, err := DecodeParams[args](NewRequest("m", nil))
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+	})
+}
+
+func TestDecodeResult(t *testing.T) {
+	t.Run("Result", func(t *testing.T) {
+		resp, err := NewResponse(int64(1), map[string]any{"height": 7})
+		require.NoError(t, err)
+		got, err := DecodeResult[map[string]int](resp)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"height": 7}, got)
+	})
+
+	t.Run("Error response", func(t *testing.T) {
+		resp := NewErrorResponse(int64(1), &Error{Code: MethodNotFound, Message: msgMethodNotFound})
+		_, err := DecodeResult[int](resp)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, MethodNotFound, rpcErr.Code)
+	})
+
+	t.Run("Type mismatch", func(t *testing.T) {
+		resp, err := NewResponse(int64(1), "text")
+		require.NoError(t, err)
+		_, err = DecodeResult[int](resp)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeErrorData(t *testing.T) {
+	type revert struct {
+		Reason string `json:"reason"`
+	}
+
+	t.Run("Wrapped error", func(t *testing.T) {
+		err := fmt.Errorf("call: %w", &Error{Code: 3, Message: "reverted",
+			Data: map[string]any{"reason": "insufficient funds"}})
+		got, decodeErr := DecodeErrorData[revert](err)
+		require.NoError(t, decodeErr)
+		assert.Equal(t, revert{Reason: "insufficient funds"}, got)
+	})
+
+	t.Run("Not a JSON-RPC error", func(t *testing.T) {
+		_, err := DecodeErrorData[revert](errors.New("plain"))
+		assert.Error(t, err)
+	})
+}
+
+func TestCall(t *testing.T) {
+	srv := newTestServer(t)
+	transport := &funcTransport{fn: func(ctx context.Context, payload []byte) ([]byte, error) {
+		return srv.HandleMessage(ctx, payload), nil
+	}}
+	client := NewClient(transport)
+
+	sum, err := Call[int](context.Background(), client, "sum", []any{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, 6, sum)
+
+	_, err = Call[int](context.Background(), client, "missing", nil)
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, MethodNotFound, rpcErr.Code)
+}
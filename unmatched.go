@@ -0,0 +1,140 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnmatchedResponse is matched by the errors reporting responses that a client received but
+// could not hand to any call.
+var ErrUnmatchedResponse = errors.New("unmatched response")
+
+// recentResponseIDs is the number of recently answered IDs a stream client remembers, to tell
+// replayed responses from responses to unknown IDs.
+const recentResponseIDs = 256
+
+// UnmatchedResponseError reports a response that matched no call: its ID was never sent or its
+// call gave up, or its call was already answered.
+type UnmatchedResponseError struct {
+	// Response is the unmatched response.
+	Response *Response
+
+	// Duplicate is true if the call with the response's ID was already answered, as when a
+	// server replays a response.
+	Duplicate bool
+}
+
+// Error implements error.
+func (e *UnmatchedResponseError) Error() string {
+	if e.Duplicate {
+		return fmt.Sprintf("%v: duplicate response for request id %v",
+			ErrUnmatchedResponse, e.Response.IDOrNil())
+	}
+	return fmt.Sprintf("%v: response for unknown request id %v",
+		ErrUnmatchedResponse, e.Response.IDOrNil())
+}
+
+// Unwrap returns ErrUnmatchedResponse.
+func (*UnmatchedResponseError) Unwrap() error {
+	return ErrUnmatchedResponse
+}
+
+// WithUnmatchedResponseHandler routes the responses that match no call to handler instead of
+// dropping them, which is the default. Calls are answered by the first response carrying their
+// ID; later ones are duplicates and unmatched too, so that a server replaying responses does not
+// disturb the calls.
+//
+// Handlers are called in the order the responses arrive, from the goroutine receiving them: the
+// read loop of stream clients, the calling goroutine otherwise. They must not block nor make
+// calls on the client.
+func WithUnmatchedResponseHandler(handler func(resp *Response)) ClientOption {
+	return func(c *Client) {
+		c.unmatchedHandler = handler
+	}
+}
+
+// WithUnmatchedErrorHandler reports the responses that match no call to handler as an
+// *UnmatchedResponseError, telling duplicates apart from responses to unknown IDs. It is called
+// like the handler of WithUnmatchedResponseHandler, before it if both are set.
+func WithUnmatchedErrorHandler(handler func(err error)) ClientOption {
+	return func(c *Client) {
+		c.unmatchedErr = handler
+	}
+}
+
+// tracksUnmatched reports whether the client reports unmatched responses.
+func (c *Client) tracksUnmatched() bool {
+	return c.unmatchedErr != nil || c.unmatchedHandler != nil
+}
+
+// reportUnmatched hands a response that matched no call to the configured handlers.
+func (c *Client) reportUnmatched(resp *Response, duplicate bool) {
+	if c.unmatchedErr != nil {
+		c.unmatchedErr(&UnmatchedResponseError{Response: resp, Duplicate: duplicate})
+	}
+	if c.unmatchedHandler != nil {
+		c.unmatchedHandler(resp)
+	}
+}
+
+// matchReplies maps the responses of an exchange to the keys of the calls they answer. The
+// first response carrying a key answers its call; the others are reported as unmatched.
+func (c *Client) matchReplies(resps []*Response, keys []string) map[string]*Response {
+	replies := make(map[string]*Response, len(resps))
+
+	// A single call answered with a null ID (e.g. a parse error) still belongs to that call
+	if len(keys) == 1 && len(resps) == 1 && resps[0].IDOrNil() == nil {
+		replies[keys[0]] = resps[0]
+		return replies
+	}
+
+	expected := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		expected[key] = struct{}{}
+	}
+	for _, resp := range resps {
+		key := idKey(resp.IDOrNil())
+		if _, ok := expected[key]; !ok {
+			c.reportUnmatched(resp, false)
+			continue
+		}
+		if _, ok := replies[key]; ok {
+			c.reportUnmatched(resp, true)
+			continue
+		}
+		replies[key] = resp
+	}
+	return replies
+}
+
+// recentIDs remembers the last keys added, up to a fixed number.
+type recentIDs struct {
+	ring []string
+	set  map[string]struct{}
+	next int
+}
+
+// add remembers key, forgetting the oldest key once full.
+func (r *recentIDs) add(key string) {
+	if r.contains(key) {
+		return
+	}
+	if r.set == nil {
+		r.ring = make([]string, 0, recentResponseIDs)
+		r.set = make(map[string]struct{}, recentResponseIDs)
+	}
+	if len(r.ring) < recentResponseIDs {
+		r.ring = append(r.ring, key)
+	} else {
+		delete(r.set, r.ring[r.next])
+		r.ring[r.next] = key
+		r.next = (r.next + 1) % recentResponseIDs
+	}
+	r.set[key] = struct{}{}
+}
+
+// contains reports whether key is remembered.
+func (r *recentIDs) contains(key string) bool {
+	_, ok := r.set[key]
+	return ok
+}
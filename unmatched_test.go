@@ -0,0 +1,163 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unmatchedLog collects the unmatched responses and errors reported to a client.
+type unmatchedLog struct {
+	mu    sync.Mutex
+	resps []*Response
+	errs  []error
+}
+
+// options returns the client options reporting to the log.
+func (l *unmatchedLog) options() []ClientOption {
+	return []ClientOption{
+		WithUnmatchedResponseHandler(func(resp *Response) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.resps = append(l.resps, resp)
+		}),
+		WithUnmatchedErrorHandler(func(err error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.errs = append(l.errs, err)
+		}),
+	}
+}
+
+// ids returns the IDs of the unmatched responses, in the order reported.
+func (l *unmatchedLog) ids() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ids := make([]string, len(l.resps))
+	for i, resp := range l.resps {
+		ids[i] = resp.IDString()
+	}
+	return ids
+}
+
+// duplicates returns whether each reported error is for a duplicate, in the order reported.
+func (l *unmatchedLog) duplicates(t *testing.T) []bool {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dups := make([]bool, len(l.errs))
+	for i, err := range l.errs {
+		require.ErrorIs(t, err, ErrUnmatchedResponse)
+		var unmatched *UnmatchedResponseError
+		require.ErrorAs(t, err, &unmatched)
+		dups[i] = unmatched.Duplicate
+	}
+	return dups
+}
+
+func TestClient_UnmatchedResponses(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Transport replies with replayed and unknown responses", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(`[
+				{"jsonrpc":"2.0","result":"first","id":1},
+				{"jsonrpc":"2.0","result":"stale","id":99},
+				{"jsonrpc":"2.0","result":"replayed","id":1}
+			]`), nil
+		}}
+		log := &unmatchedLog{}
+		client := NewClient(transport, log.options()...)
+		defer client.Close()
+
+		resps, err := client.CallBatch(ctx, []*Request{NewRequestWithID("a", nil, int64(1))})
+		require.NoError(t, err)
+		require.Len(t, resps, 1)
+		assert.JSONEq(t, `"first"`, string(resps[0].RawResult()))
+
+		assert.Equal(t, []string{"99", "1"}, log.ids())
+		assert.Equal(t, []bool{false, true}, log.duplicates(t))
+	})
+
+	t.Run("Dropped by default", func(t *testing.T) {
+		transport := &funcTransport{fn: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(`[
+				{"jsonrpc":"2.0","result":1,"id":1},
+				{"jsonrpc":"2.0","result":2,"id":1}
+			]`), nil
+		}}
+		client := NewClient(transport)
+		defer client.Close()
+
+		resps, err := client.CallBatch(ctx, []*Request{NewRequestWithID("a", nil, int64(1))})
+		require.NoError(t, err)
+		assert.JSONEq(t, "1", string(resps[0].RawResult()))
+	})
+
+	t.Run("Stream keeps working through replays", func(t *testing.T) {
+		local, remote := newStreamPair()
+		log := &unmatchedLog{}
+		client := NewStreamClient(local, log.options()...)
+		defer client.Close()
+
+		go func() {
+			for {
+				msg, err := remote.ReadMessage(ctx)
+				if err != nil {
+					return
+				}
+				req, err := DecodeRequest(msg)
+				if err != nil {
+					return
+				}
+				reply := fmt.Sprintf(`{"jsonrpc":"2.0","result":"ok","id":%s}`, req.IDString())
+				for range 2 {
+					_ = remote.WriteMessage(ctx, []byte(reply))
+				}
+				_ = remote.WriteMessage(ctx, []byte(`{"jsonrpc":"2.0","result":"x","id":"nope"}`))
+			}
+		}()
+
+		for range 3 {
+			var result string
+			require.NoError(t, client.Call(ctx, "a", nil, &result))
+			assert.Equal(t, "ok", result)
+		}
+		require.Eventually(t, func() bool {
+			return len(log.ids()) == 6
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"1", "nope", "2", "nope", "3", "nope"}, log.ids())
+		assert.Equal(t, []bool{true, false, true, false, true, false}, log.duplicates(t))
+
+		select {
+		case <-client.Done():
+			t.Fatal("client shut down")
+		default:
+		}
+	})
+
+	t.Run("Error describes the response", func(t *testing.T) {
+		resp, err := NewResponse(7, "x")
+		require.NoError(t, err)
+		assert.Equal(t, "unmatched response: duplicate response for request id 7",
+			(&UnmatchedResponseError{Response: resp, Duplicate: true}).Error())
+		assert.True(t, errors.Is(&UnmatchedResponseError{Response: resp}, ErrUnmatchedResponse))
+	})
+}
+
+func TestRecentIDs(t *testing.T) {
+	var recent recentIDs
+	for i := range recentResponseIDs + 1 {
+		recent.add(fmt.Sprint(i))
+	}
+	assert.False(t, recent.contains("0"), "the oldest key is forgotten")
+	assert.True(t, recent.contains("1"))
+	assert.True(t, recent.contains(fmt.Sprint(recentResponseIDs)))
+	assert.Len(t, recent.set, recentResponseIDs)
+}
@@ -2,8 +2,10 @@ package jsonrpc
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
+	"math/big"
 	"math/rand/v2"
 	"strconv"
 	"strings"
@@ -12,8 +14,8 @@ import (
 
 // revive:disable:add-constant makes sense here
 
-// bufferPool is a sync.Pool for reusing byte buffers during stream reading. Purpose is to reduce
-// GC pressure in high-throughput scenarios by reusing buffers.
+// bufferPool is a sync.Pool for reusing byte buffers during stream reading and reply encoding.
+// Purpose is to reduce GC pressure in high-throughput scenarios by reusing buffers.
 var bufferPool = sync.Pool{
 	New: func() any {
 		// Pre-allocate 16KB buffers (typical response size)
@@ -32,9 +34,10 @@ func getBuffer() *[]byte {
 	return buf
 }
 
-// putBuffer returns a buffer to the pool after clearing it
+// putBuffer returns a buffer to the pool after clearing it. Buffers grown past 1MB are dropped
+// instead, so that an occasional huge message does not stay pinned in memory.
 func putBuffer(buf *[]byte) {
-	if buf == nil {
+	if buf == nil || cap(*buf) > 1024*1024 {
 		return
 	}
 	// Reset the buffer but keep capacity for reuse
@@ -62,6 +65,41 @@ func formatFloat64ID(id float64) string {
 	return str
 }
 
+// idKey returns a key identifying a JSON-RPC ID, used to correlate responses with requests.
+// Numbers and strings are kept apart so that the ID 1 never matches the ID "1".
+func idKey(id any) string {
+	switch v := id.(type) {
+	case string:
+		return "s:" + v
+	case int64:
+		return "n:" + strconv.FormatInt(v, 10)
+	case int:
+		return "n:" + strconv.Itoa(v)
+	case float64:
+		if v == float64(int64(v)) {
+			return "n:" + strconv.FormatInt(int64(v), 10)
+		}
+		return "n:" + formatFloat64ID(v)
+	case json.Number:
+		if n := numberValue(v.String()); n != any(v) {
+			return idKey(n)
+		}
+		return "n:" + v.String()
+	case *big.Int:
+		if v.IsInt64() {
+			return "n:" + strconv.FormatInt(v.Int64(), 10)
+		}
+		return "n:" + v.String()
+	case ID:
+		if v.Kind() == IDString || v.Kind() == IDNumber {
+			return idKey(v.Value())
+		}
+		return ""
+	default:
+		return ""
+	}
+}
+
 // RandomJSONRPCID returns a randomly generated value appropriate for a JSON-RPC ID field.
 // Returns an int64 in the range [0, 2147483647] (int32 range) for compatibility.
 func RandomJSONRPCID() int64 {
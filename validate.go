@@ -0,0 +1,189 @@
+package jsonrpc
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tag keys of validation rules.
+const (
+	tagJSONRPC  = "jsonrpc"
+	tagValidate = "validate"
+)
+
+// Validation rules.
+const (
+	ruleRequired = "required"
+	ruleMin      = "min"
+	ruleMax      = "max"
+)
+
+// validateTags checks the value dst points to against the validation rules of its struct tags.
+func validateTags(dst any) error {
+	return validateValue(reflect.ValueOf(dst), "")
+}
+
+// validateValue checks the structs held by v, at the JSON path path.
+func validateValue(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return validateValue(v.Elem(), path)
+	case reflect.Struct:
+		return validateStruct(v, path)
+	case reflect.Slice, reflect.Array:
+		if !holdsStructs(v.Type().Elem()) {
+			return nil
+		}
+		for i := range v.Len() {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// holdsStructs reports whether typ is a struct or a pointer to one.
+func holdsStructs(typ reflect.Type) bool {
+	elem := typ
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// validateStruct checks the fields of the struct v, at the JSON path path, against their rules.
+func validateStruct(v reflect.Value, path string) error {
+	typ := v.Type()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		// Embedded structs promote their fields even when unexported, as in encoding/json
+		if !field.IsExported() && !(field.Anonymous && holdsStructs(field.Type)) {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fieldPath := path
+		if name != "" || !field.Anonymous {
+			fieldPath = joinPath(path, cmp.Or(name, field.Name))
+		}
+		if err := checkRules(field, v.Field(i), fieldPath); err != nil {
+			return err
+		}
+		if err := validateValue(v.Field(i), fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinPath returns the JSON path of the member name of the value at path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// checkRules checks the value v of field, at the JSON path path, against the rules of its tags.
+func checkRules(field reflect.StructField, v reflect.Value, path string) error {
+	for _, key := range []string{tagJSONRPC, tagValidate} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		for rule := range strings.SplitSeq(tag, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			if key == tagJSONRPC && name != ruleRequired {
+				return fmt.Errorf("invalid %s tag of field %s: unknown option %q", key, path, name)
+			}
+			violation, err := checkRule(v, name, arg)
+			if err != nil {
+				return fmt.Errorf("invalid %s tag of field %s: %w", key, path, err)
+			}
+			if violation != "" {
+				return fmt.Errorf("param %q %s", path, violation)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRule checks v against the rule name with argument arg, returning how v violates it, or
+// an error if the rule is malformed.
+func checkRule(v reflect.Value, name, arg string) (violation string, err error) {
+	switch name {
+	case ruleRequired:
+		if v.IsZero() {
+			return "is required", nil
+		}
+		return "", nil
+	case ruleMin, ruleMax:
+		value := v
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return "", nil
+			}
+			value = value.Elem()
+		}
+		return checkBound(value, name, arg)
+	case "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("unknown rule %q", name)
+	}
+}
+
+// checkBound checks v against the min or max rule with bound arg.
+func checkBound(v reflect.Value, name, arg string) (violation string, err error) {
+	order, what, err := compareBound(v, arg)
+	if err != nil {
+		return "", fmt.Errorf("%s=%s: %w", name, arg, err)
+	}
+	switch {
+	case name == ruleMin && order < 0:
+		return fmt.Sprintf(what, "at least", arg), nil
+	case name == ruleMax && order > 0:
+		return fmt.Sprintf(what, "at most", arg), nil
+	default:
+		return "", nil
+	}
+}
+
+// compareBound compares the number, length, or size of v with bound, returning the format of
+// the violation of a bound by v.
+func compareBound(v reflect.Value, bound string) (order int, what string, err error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(bound, decimal, 64)
+		return cmp.Compare(v.Int(), n), "must be %s %s", err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		n, err := strconv.ParseUint(bound, decimal, 64)
+		return cmp.Compare(v.Uint(), n), "must be %s %s", err
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(bound, 64)
+		return cmp.Compare(v.Float(), f), "must be %s %s", err
+	case reflect.String:
+		n, err := strconv.Atoi(bound)
+		return cmp.Compare(utf8.RuneCountInString(v.String()), n),
+			"must be %s %s characters long", err
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, err := strconv.Atoi(bound)
+		return cmp.Compare(v.Len(), n), "must have %s %s elements", err
+	default:
+		return 0, "", errors.New("rule does not apply to " + v.Kind().String())
+	}
+}
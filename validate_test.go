@@ -0,0 +1,124 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type paymentArgs struct {
+	To     string   `json:"to" jsonrpc:"required"`
+	Amount int64    `json:"amount" validate:"required,min=1,max=1000"`
+	Memo   string   `json:"memo,omitempty" validate:"max=5"`
+	Fee    *float64 `json:"fee,omitempty" validate:"min=0.5"`
+	Tags   []string `json:"tags,omitempty" validate:"max=2"`
+	Legs   []struct {
+		Hop uint `json:"hop" validate:"max=3"`
+	} `json:"legs,omitempty"`
+}
+
+type auditedArgs struct {
+	paymentArgs
+	Reason string `validate:"min=2"`
+}
+
+func TestRequest_BindParams_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		params any
+		want   string
+	}{
+		{"Valid named params", map[string]any{"to": "bob", "amount": 5}, ""},
+		{"Valid positional params", []any{"bob", 5, "hi", 1.5}, ""},
+		{"Required", map[string]any{"amount": 5}, `param "to" is required`},
+		{"Required in validate tag", []any{"bob"}, `param "amount" is required`},
+		{"Minimum", []any{"bob", -1}, `param "amount" must be at least 1`},
+		{"Maximum", []any{"bob", 1001}, `param "amount" must be at most 1000`},
+		{"String length", []any{"bob", 1, "héllo!"}, `param "memo" must be at most 5 characters`},
+		{"Pointer", []any{"bob", 1, "", 0.25}, `param "fee" must be at least 0.5`},
+		{"Slice length", map[string]any{"to": "bob", "amount": 1, "tags": []string{"a", "b", "c"}},
+			`param "tags" must have at most 2 elements`},
+		{"Nested structs", map[string]any{"to": "bob", "amount": 1,
+			"legs": []any{map[string]any{"hop": 1}, map[string]any{"hop": 4}}},
+			`param "legs[1].hop" must be at most 3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args paymentArgs
+			err := NewRequest("transfer", tt.params).BindParams(&args)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	t.Run("Embedded structs", func(t *testing.T) {
+		var args auditedArgs
+		err := NewRequest("audit", map[string]any{"amount": 1, "Reason": "ok"}).BindParams(&args)
+		assert.EqualError(t, err, `param "to" is required`)
+
+		err = NewRequest("audit", map[string]any{"to": "a", "amount": 1, "Reason": "x"}).
+			BindParams(&args)
+		assert.EqualError(t, err, `param "Reason" must be at least 2 characters long`)
+	})
+
+	t.Run("Malformed tags", func(t *testing.T) {
+		var unknown struct {
+			A int `json:"a" validate:"positive"`
+		}
+		err := NewRequest("m", map[string]any{"a": 1}).BindParams(&unknown)
+		assert.EqualError(t, err, `invalid validate tag of field a: unknown rule "positive"`)
+
+		var bound struct {
+			A int `json:"a" validate:"min=x"`
+		}
+		assert.ErrorContains(t, NewRequest("m", []any{1}).BindParams(&bound), "min=x")
+
+		var option struct {
+			A int `json:"a" jsonrpc:"min=1"`
+		}
+		assert.ErrorContains(t, NewRequest("m", []any{1}).BindParams(&option), "unknown option")
+
+		var kind struct {
+			A bool `json:"a" validate:"min=1"`
+		}
+		assert.ErrorContains(t, NewRequest("m", []any{true}).BindParams(&kind), "bool")
+	})
+}
+
+type paymentService struct{}
+
+func (paymentService) Send(_ context.Context, args paymentArgs) (int64, error) {
+	return args.Amount, nil
+}
+
+func TestValidation_Handlers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("DecodeParams", func(t *testing.T) {
+		_, err := DecodeParams[paymentArgs](NewRequest("transfer", []any{"bob", 0}))
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+		assert.Equal(t, `param "amount" is required`, rpcErr.Data)
+	})
+
+	t.Run("Service methods", func(t *testing.T) {
+		srv := NewServer()
+		require.NoError(t, srv.RegisterService("transfers", paymentService{}))
+
+		resp := srv.HandleRequest(ctx,
+			NewRequestWithID("transfers.send", []any{map[string]any{"to": "bob"}}, int64(1)))
+		require.NotNil(t, resp.Err())
+		assert.Equal(t, InvalidParams, resp.Err().Code)
+		assert.Equal(t, `param "amount" is required`, resp.Err().Data)
+
+		resp = srv.HandleRequest(ctx, NewRequestWithID("transfers.send",
+			map[string]any{"to": "bob", "amount": 7}, int64(2)))
+		require.Nil(t, resp.Err())
+	})
+}
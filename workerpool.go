@@ -0,0 +1,139 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// WorkerPool is a budget of handler slots and memory dedicated to a class of requests, such as
+// methods tracing transactions or requests with large params, so that they cannot starve the
+// cheap calls sharing the server. Requests are classified by method and by the size of their
+// encoded params.
+type WorkerPool struct {
+	// Methods are the methods whose requests the pool executes.
+	Methods []string
+
+	// MinParamsSize, if positive, also routes to the pool the requests of any method whose
+	// encoded params take at least that many bytes.
+	MinParamsSize int
+
+	// Limit bounds the handlers executing at once in the pool, like WithConcurrencyLimit does
+	// for the requests of no pool.
+	Limit ConcurrencyLimit
+
+	// MaxBytes, if positive, caps the total size of the encoded params of the requests executing
+	// at once in the pool, as a proxy for the memory their handlers use. A request over budget is
+	// answered with ErrServerBusy, unless the pool has no other request executing, so that a
+	// single request larger than the budget still runs.
+	MaxBytes int
+}
+
+// WithWorkerPool adds a worker pool to the server. Requests are routed to the first pool, in the
+// order they were added, listing their method or with params large enough, and take a slot of
+// that pool instead of one under WithConcurrencyLimit. Method concurrency limits and rate limits
+// apply on top.
+//
+// Sizes are known for free under WithLazyParams; otherwise params are encoded again to be
+// measured, for the pools that need their size.
+func WithWorkerPool(pool WorkerPool) ServerOption {
+	return func(s *Server) {
+		s.pools = append(s.pools, newWorkerPool(pool))
+	}
+}
+
+// workerPool enforces a WorkerPool.
+type workerPool struct {
+	pool    WorkerPool
+	methods map[string]struct{}
+	limiter *limiter
+
+	mu     sync.Mutex
+	bytes  int // Params bytes of the requests executing
+	active int // Requests executing
+}
+
+// newWorkerPool creates the workerPool of pool.
+func newWorkerPool(pool WorkerPool) *workerPool {
+	p := &workerPool{
+		pool:    pool,
+		methods: make(map[string]struct{}, len(pool.Methods)),
+		limiter: newLimiter(pool.Limit),
+	}
+	for _, method := range pool.Methods {
+		p.methods[method] = struct{}{}
+	}
+	return p
+}
+
+// acquire takes a slot of the pool and reserves size bytes of its budget for a request of the
+// given priority. A nil error means both must be given back with release.
+func (p *workerPool) acquire(ctx context.Context, priority Priority, size int) error {
+	if p.limiter != nil {
+		if err := p.limiter.acquire(ctx, priority); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pool.MaxBytes > 0 && p.active > 0 && p.bytes+size > p.pool.MaxBytes {
+		if p.limiter != nil {
+			p.limiter.release()
+		}
+		return ErrServerBusy
+	}
+	p.bytes += size
+	p.active++
+	return nil
+}
+
+// release gives back the slot and the size bytes taken by acquire.
+func (p *workerPool) release(size int) {
+	p.mu.Lock()
+	p.bytes -= size
+	p.active--
+	p.mu.Unlock()
+	if p.limiter != nil {
+		p.limiter.release()
+	}
+}
+
+// workerPool returns the pool executing req, if any, and the size of its encoded params, which is
+// only measured when a pool needs it.
+func (s *Server) workerPool(req *Request) (*workerPool, int) {
+	size := -1
+	for _, p := range s.pools {
+		if _, ok := p.methods[req.Method]; !ok {
+			if p.pool.MinParamsSize <= 0 {
+				continue
+			}
+			if size < 0 {
+				size = paramsSize(req)
+			}
+			if size < p.pool.MinParamsSize {
+				continue
+			}
+		}
+		if size < 0 && p.pool.MaxBytes > 0 {
+			size = paramsSize(req)
+		}
+		return p, max(size, 0)
+	}
+	return nil, 0
+}
+
+// paramsSize returns the size of the encoded params of req, or zero if they cannot be encoded.
+func paramsSize(req *Request) int {
+	if req.Params == nil {
+		return 0
+	}
+	if raw, ok := req.Params.(json.RawMessage); ok {
+		return len(raw)
+	}
+	data, err := getCodec().Marshal(req.Params)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_WorkerPool(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Heavy method does not starve cheap calls", func(t *testing.T) {
+		heavy, cheap := newGate(), newGate()
+		srv := NewServer(
+			WithConcurrencyLimit(ConcurrencyLimit{MaxConcurrent: 1, Overload: OverloadReject}),
+			WithWorkerPool(WorkerPool{
+				Methods: []string{"debug_traceTransaction"},
+				Limit:   ConcurrencyLimit{MaxConcurrent: 1, Overload: OverloadReject},
+			}),
+		)
+		require.NoError(t, srv.Register("debug_traceTransaction", heavy))
+		require.NoError(t, srv.Register("eth_blockNumber", cheap))
+
+		trace := handleAsync(ctx, srv, "debug_traceTransaction", 1)
+		heavy.waitStarted(t, 1)
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("debug_traceTransaction", nil, 2))
+		require.ErrorIs(t, resp.Err(), ErrServerBusy)
+
+		block := handleAsync(ctx, srv, "eth_blockNumber", 3)
+		cheap.waitStarted(t, 1)
+
+		heavy.open()
+		cheap.open()
+		assert.Nil(t, awaitResponse(t, trace).Err())
+		assert.Nil(t, awaitResponse(t, block).Err())
+	})
+
+	t.Run("Routes by params size", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(WithWorkerPool(WorkerPool{
+			MinParamsSize: 100,
+			Limit:         ConcurrencyLimit{MaxConcurrent: 1, Overload: OverloadReject},
+		}))
+		require.NoError(t, srv.Register("block", g))
+
+		large := []any{strings.Repeat("x", 100)}
+		first := make(chan *Response, 1)
+		go func() { first <- srv.HandleRequest(ctx, NewRequestWithID("block", large, 1)) }()
+		g.waitStarted(t, 1)
+
+		resp := srv.HandleRequest(ctx, NewRequestWithID("block", large, 2))
+		require.ErrorIs(t, resp.Err(), ErrServerBusy)
+
+		small := handleAsync(ctx, srv, "block", 3)
+		g.waitStarted(t, 1)
+
+		g.open()
+		assert.Nil(t, awaitResponse(t, first).Err())
+		assert.Nil(t, awaitResponse(t, small).Err())
+	})
+
+	t.Run("Memory budget", func(t *testing.T) {
+		g := newGate()
+		srv := NewServer(
+			WithLazyParams(),
+			WithWorkerPool(WorkerPool{Methods: []string{"block"}, MaxBytes: 64}),
+		)
+		require.NoError(t, srv.Register("block", g))
+
+		handle := func(id, size int) <-chan []byte {
+			msg := []byte(fmt.Sprintf(
+				`{"jsonrpc":"2.0","id":%d,"method":"block","params":["%s"]}`,
+				id, strings.Repeat("x", size),
+			))
+			out := make(chan []byte, 1)
+			go func() { out <- srv.HandleMessage(ctx, msg) }()
+			return out
+		}
+
+		// A request over budget runs while the pool is otherwise idle
+		first := handle(1, 100)
+		g.waitStarted(t, 1)
+
+		reply := <-handle(2, 1)
+		assert.Contains(t, string(reply), `"code":-32011`)
+
+		g.open()
+		assert.Contains(t, string(<-first), `"result":"done"`)
+		assert.Contains(t, string(<-handle(3, 1)), `"result":"done"`)
+	})
+}
+
+func TestParamsSize(t *testing.T) {
+	t.Run("Raw params", func(t *testing.T) {
+		req := NewRequest("m", json.RawMessage(`[1,2]`))
+		assert.Equal(t, 5, paramsSize(req))
+	})
+
+	t.Run("Decoded params", func(t *testing.T) {
+		req := NewRequest("m", map[string]any{"a": 1})
+		assert.Equal(t, len(`{"a":1}`), paramsSize(req))
+	})
+
+	t.Run("No params", func(t *testing.T) {
+		assert.Zero(t, paramsSize(NewRequest("m", nil)))
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !js
+
+package ws
+
+import "github.com/coder/websocket"
+
+// dialCompression enables compression of messages of at least threshold bytes in the dial
+// options, keeping any compression mode they set.
+func dialCompression(dial *websocket.DialOptions, threshold int) {
+	if dial.CompressionMode == websocket.CompressionDisabled {
+		dial.CompressionMode = websocket.CompressionNoContextTakeover
+	}
+	dial.CompressionThreshold = threshold
+}
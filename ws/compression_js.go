@@ -0,0 +1,7 @@
+package ws
+
+import "github.com/coder/websocket"
+
+// dialCompression leaves the dial options unchanged, as browsers negotiate compression of their
+// WebSocket connections on their own.
+func dialCompression(*websocket.DialOptions, int) {}
@@ -0,0 +1,232 @@
+// Package ws provides a WebSocket transport for the jsonrpc package. Each WebSocket message
+// carries exactly one JSON-RPC message (single or batch), and both peers may issue requests over
+// the same connection.
+//
+// Under GOOS=js GOARCH=wasm, Dial and Dialer connect through the browser's WebSocket API, so
+// that browser-deployed clients reuse the same API; handlers of NewHandler reject every
+// connection there.
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/coder/websocket"
+
+	"github.com/jkbrsn/jsonrpc"
+)
+
+// defaultReadLimit is the default maximum size in bytes of a single incoming message.
+const defaultReadLimit = 32 * 1024 * 1024
+
+// Stream is a jsonrpc.Stream over a WebSocket connection.
+type Stream struct {
+	conn     *websocket.Conn
+	encoding jsonrpc.Encoding
+}
+
+// NewStream wraps an established WebSocket connection. Of the options, only WithEncoding
+// applies.
+func NewStream(conn *websocket.Conn, opts ...Option) *Stream {
+	return &Stream{conn: conn, encoding: newConfig(opts).encoding}
+}
+
+// Conn returns the underlying WebSocket connection.
+func (s *Stream) Conn() *websocket.Conn {
+	return s.conn
+}
+
+// ReadMessage reads the next WebSocket message. A normal closure by the peer is reported as
+// io.EOF. Binary messages are transcoded from the stream's encoding, if any; those failing to
+// decode are passed on as they are, to be answered with a parse error.
+func (s *Stream) ReadMessage(ctx context.Context) ([]byte, error) {
+	typ, data, err := s.conn.Read(ctx)
+	if err != nil {
+		switch websocket.CloseStatus(err) {
+		case websocket.StatusNormalClosure, websocket.StatusGoingAway:
+			return nil, io.EOF
+		default:
+			return nil, err
+		}
+	}
+	if typ == websocket.MessageBinary && s.encoding != nil {
+		if msg, err := s.encoding.Decode(data); err == nil {
+			return msg, nil
+		}
+	}
+	return data, nil
+}
+
+// WriteMessage writes msg as a single text message, or as a binary message in the stream's
+// encoding if it has one.
+func (s *Stream) WriteMessage(ctx context.Context, msg []byte) error {
+	if s.encoding == nil {
+		return s.conn.Write(ctx, websocket.MessageText, msg)
+	}
+	data, err := s.encoding.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return s.conn.Write(ctx, websocket.MessageBinary, data)
+}
+
+// Ping sends a ping frame and waits for the peer's pong, implementing jsonrpc.Pinger for
+// keepalives. Pongs are only processed while messages are being read, as clients and served
+// streams do.
+func (s *Stream) Ping(ctx context.Context) error {
+	return s.conn.Ping(ctx)
+}
+
+// Close performs the WebSocket closing handshake with a normal closure status. Closing a
+// connection that is already closed is not an error.
+func (s *Stream) Close() error {
+	err := s.conn.Close(websocket.StatusNormalClosure, "")
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// config holds the settings shared by Dial and NewHandler.
+type config struct {
+	readLimit     int64
+	dialOptions   *websocket.DialOptions
+	acceptOptions *websocket.AcceptOptions
+	encoding      jsonrpc.Encoding
+	compress      bool
+	threshold     int
+}
+
+// Option configures Dial and NewHandler.
+type Option func(*config)
+
+// WithReadLimit sets the maximum size in bytes of a single incoming message. Defaults to 32 MiB.
+func WithReadLimit(n int64) Option {
+	return func(c *config) {
+		c.readLimit = n
+	}
+}
+
+// WithDialOptions sets the options used by Dial to establish the connection.
+func WithDialOptions(opts *websocket.DialOptions) Option {
+	return func(c *config) {
+		c.dialOptions = opts
+	}
+}
+
+// WithAcceptOptions sets the options used by the handler to accept connections.
+func WithAcceptOptions(opts *websocket.AcceptOptions) Option {
+	return func(c *config) {
+		c.acceptOptions = opts
+	}
+}
+
+// WithEncoding makes the connection carry messages in enc, such as MessagePack or CBOR, as binary
+// WebSocket messages. Both peers must use the same encoding; text messages are still read as
+// JSON.
+func WithEncoding(enc jsonrpc.Encoding) Option {
+	return func(c *config) {
+		c.encoding = enc
+	}
+}
+
+// WithCompression negotiates the permessage-deflate extension, compressing messages of at least
+// threshold bytes, as small messages cost more to compress than they save. Zero uses the
+// library default of 512 bytes. Each message is compressed on its own, without keeping a
+// sliding window across messages, which bounds the memory held per connection; set a
+// CompressionMode in the dial or accept options to trade memory for better ratios instead. In
+// browsers, under GOOS=js, compression of dialed connections is left to the browser.
+func WithCompression(threshold int) Option {
+	return func(c *config) {
+		c.compress, c.threshold = true, threshold
+	}
+}
+
+// newConfig applies opts over the defaults.
+func newConfig(opts []Option) *config {
+	cfg := &config{readLimit: defaultReadLimit}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.compress {
+		cfg.applyCompression()
+	}
+	return cfg
+}
+
+// applyCompression enables compression in copies of the dial and accept options, keeping any
+// compression mode they set.
+func (c *config) applyCompression() {
+	dial := websocket.DialOptions{}
+	if c.dialOptions != nil {
+		dial = *c.dialOptions
+	}
+	dialCompression(&dial, c.threshold)
+	c.dialOptions = &dial
+
+	accept := websocket.AcceptOptions{}
+	if c.acceptOptions != nil {
+		accept = *c.acceptOptions
+	}
+	if accept.CompressionMode == websocket.CompressionDisabled {
+		accept.CompressionMode = websocket.CompressionNoContextTakeover
+	}
+	accept.CompressionThreshold = c.threshold
+	c.acceptOptions = &accept
+}
+
+// Dial connects to the WebSocket endpoint at url. The returned stream is typically passed to
+// jsonrpc.NewStreamClient.
+func Dial(ctx context.Context, url string, opts ...Option) (*Stream, error) {
+	cfg := newConfig(opts)
+
+	//nolint:bodyclose // the response body is owned by the WebSocket connection
+	conn, _, err := websocket.Dial(ctx, url, cfg.dialOptions)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(cfg.readLimit)
+	return &Stream{conn: conn, encoding: cfg.encoding}, nil
+}
+
+// Dialer returns a jsonrpc.Dialer connecting to url, for clients redialing dropped connections:
+//
+//	dial := ws.Dialer(url)
+//	stream, err := dial(ctx)
+//	client := jsonrpc.NewStreamClient(stream, jsonrpc.WithReconnect(dial, policy))
+func Dialer(url string, opts ...Option) jsonrpc.Dialer {
+	return func(ctx context.Context) (jsonrpc.Stream, error) {
+		return Dial(ctx, url, opts...)
+	}
+}
+
+// handler upgrades HTTP requests to WebSocket connections served by a jsonrpc.Server.
+type handler struct {
+	srv *jsonrpc.Server
+	cfg *config
+}
+
+// NewHandler returns an http.Handler that upgrades each request to a WebSocket connection and
+// serves it with srv until the connection closes. Handlers can call back to the connected client
+// through jsonrpc.ClientFromContext.
+func NewHandler(srv *jsonrpc.Server, opts ...Option) http.Handler {
+	return &handler{srv: srv, cfg: newConfig(opts)}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, h.cfg.acceptOptions)
+	if err != nil {
+		// Accept has already replied with an appropriate HTTP error
+		return
+	}
+	conn.SetReadLimit(h.cfg.readLimit)
+
+	ctx := jsonrpc.NewIncomingContext(r.Context(), jsonrpc.MetadataFromHTTP(r))
+	ctx = jsonrpc.WithConnectionState(ctx, r.TLS)
+	_ = h.srv.ServeStream(ctx, &Stream{conn: conn, encoding: h.cfg.encoding})
+}
@@ -0,0 +1,345 @@
+//go:build !js
+
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jkbrsn/jsonrpc"
+	"github.com/jkbrsn/jsonrpc/msgpack"
+)
+
+// newTestServer starts an HTTP server upgrading every request to a WebSocket served by srv and
+// returns its ws:// URL.
+func newTestServer(t *testing.T, srv *jsonrpc.Server, opts ...Option) string {
+	t.Helper()
+	ts := httptest.NewServer(NewHandler(srv, opts...))
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// echo returns the request params as the result.
+func echo(_ context.Context, req *jsonrpc.Request) (any, error) {
+	return req.Params, nil
+}
+
+func TestWebSocket(t *testing.T) {
+	t.Run("Call", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+
+		stream, err := Dial(context.Background(), newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		var got []string
+		require.NoError(t, client.Call(context.Background(), "echo", []any{"a", "b"}, &got))
+		assert.Equal(t, []string{"a", "b"}, got)
+	})
+
+	t.Run("With encoding", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+
+		enc := WithEncoding(msgpack.Encoding{})
+		stream, err := Dial(context.Background(), newTestServer(t, srv, enc), enc)
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		var got map[string]any
+		params := map[string]any{"n": 1.5, "s": "x"}
+		require.NoError(t, client.Call(context.Background(), "echo", params, &got))
+		assert.Equal(t, params, got)
+	})
+
+	t.Run("With compression", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+		extensions := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			md, _ := jsonrpc.IncomingMetadata(ctx)
+			return md.Get("Sec-Websocket-Extensions"), nil
+		}
+		require.NoError(t, srv.RegisterFunc("extensions", extensions))
+
+		compress := WithCompression(64)
+		stream, err := Dial(context.Background(), newTestServer(t, srv, compress), compress)
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		var negotiated string
+		require.NoError(t, client.Call(context.Background(), "extensions", nil, &negotiated))
+		assert.Contains(t, negotiated, "permessage-deflate")
+
+		var got []string
+		params := []string{strings.Repeat("z", 4096)}
+		require.NoError(t, client.Call(context.Background(), "echo", params, &got))
+		assert.Equal(t, params, got)
+	})
+
+	t.Run("Handshake headers reach handlers as metadata", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		token := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			md, _ := jsonrpc.IncomingMetadata(ctx)
+			return md.Get("Authorization"), nil
+		}
+		require.NoError(t, srv.RegisterFunc("token", token))
+
+		header := http.Header{"Authorization": {"Bearer x"}}
+		dialOpts := WithDialOptions(&websocket.DialOptions{HTTPHeader: header})
+		stream, err := Dial(context.Background(), newTestServer(t, srv), dialOpts)
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		var got string
+		require.NoError(t, client.Call(context.Background(), "token", nil, &got))
+		assert.Equal(t, "Bearer x", got)
+	})
+
+	t.Run("Server calls back to client", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		greet := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, ok := jsonrpc.ClientFromContext(ctx)
+			if !ok {
+				return nil, &jsonrpc.Error{Code: jsonrpc.ServerSideException, Message: "no peer"}
+			}
+			var name string
+			if err := peer.Call(ctx, "client.name", nil, &name); err != nil {
+				return nil, err
+			}
+			return "hello " + name, nil
+		}
+		require.NoError(t, srv.RegisterFunc("greet", greet))
+
+		clientSrv := jsonrpc.NewServer()
+		name := func(_ context.Context, _ *jsonrpc.Request) (any, error) { return "ann", nil }
+		require.NoError(t, clientSrv.RegisterFunc("client.name", name))
+
+		stream, err := Dial(context.Background(), newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream, jsonrpc.WithServer(clientSrv))
+		defer client.Close()
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				var greeting string
+				assert.NoError(t, client.Call(context.Background(), "greet", nil, &greeting))
+				assert.Equal(t, "hello ann", greeting)
+			})
+		}
+		wg.Wait()
+	})
+
+	t.Run("Server notifies client", func(t *testing.T) {
+		received := make(chan string, 1)
+		srv := jsonrpc.NewServer()
+		subscribe := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, _ := jsonrpc.ClientFromContext(ctx)
+			return true, peer.Notify(ctx, "event", []any{"tick"})
+		}
+		require.NoError(t, srv.RegisterFunc("subscribe", subscribe))
+
+		clientSrv := jsonrpc.NewServer()
+		event := func(_ context.Context, req *jsonrpc.Request) (any, error) {
+			var params []string
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			received <- params[0]
+			return nil, nil
+		}
+		require.NoError(t, clientSrv.RegisterFunc("event", event))
+
+		stream, err := Dial(context.Background(), newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream, jsonrpc.WithServer(clientSrv))
+		defer client.Close()
+
+		require.NoError(t, client.Call(context.Background(), "subscribe", nil, nil))
+		select {
+		case got := <-received:
+			assert.Equal(t, "tick", got)
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+	})
+
+	t.Run("Peer close ends the client", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		stop := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, _ := jsonrpc.ClientFromContext(ctx)
+			go func() { _ = peer.Close() }()
+			return nil, nil
+		}
+		require.NoError(t, srv.RegisterFunc("stop", stop))
+
+		stream, err := Dial(context.Background(), newTestServer(t, srv))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		_ = client.Call(context.Background(), "stop", nil, nil)
+		select {
+		case <-client.Done():
+		case <-time.After(time.Second):
+			t.Fatal("client did not shut down after the server closed the connection")
+		}
+		err = client.Call(context.Background(), "stop", nil, nil)
+		require.ErrorIs(t, err, jsonrpc.ErrClientClosed)
+	})
+
+	t.Run("Reconnects and resubscribes", func(t *testing.T) {
+		var subs atomic.Int32
+		srv := jsonrpc.NewServer()
+		subscribe := func(context.Context, *jsonrpc.Request) (any, error) {
+			return fmt.Sprintf("0x%d", subs.Add(1)), nil
+		}
+		publish := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, _ := jsonrpc.ClientFromContext(ctx)
+			params := map[string]any{"subscription": fmt.Sprintf("0x%d", subs.Load()), "result": 1}
+			return nil, peer.Notify(ctx, "eth_subscription", params)
+		}
+		stop := func(ctx context.Context, _ *jsonrpc.Request) (any, error) {
+			peer, _ := jsonrpc.ClientFromContext(ctx)
+			go func() { _ = peer.Close() }()
+			return nil, nil
+		}
+		require.NoError(t, srv.RegisterFunc("eth_subscribe", subscribe))
+		require.NoError(t, srv.RegisterFunc("publish", publish))
+		require.NoError(t, srv.RegisterFunc("stop", stop))
+
+		reconnected := make(chan struct{}, 1)
+		policy := jsonrpc.ReconnectPolicy{
+			InitialBackoff: time.Millisecond,
+			OnReconnect: func(event jsonrpc.ReconnectEvent) {
+				if event.Err == nil {
+					reconnected <- struct{}{}
+				}
+			},
+		}
+		dial := Dialer(newTestServer(t, srv))
+		stream, err := dial(context.Background())
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream, jsonrpc.WithReconnect(dial, policy))
+		defer client.Close()
+
+		ch := make(chan json.RawMessage, 1)
+		sub, err := client.Subscribe(context.Background(), "eth_subscribe", nil, ch)
+		require.NoError(t, err)
+
+		require.NoError(t, client.Notify(context.Background(), "stop", nil))
+		select {
+		case <-reconnected:
+		case <-time.After(time.Second):
+			t.Fatal("client did not reconnect")
+		}
+		require.Eventually(t, func() bool {
+			return sub.ID() == "0x2"
+		}, time.Second, time.Millisecond)
+
+		require.NoError(t, client.Call(context.Background(), "publish", nil, nil))
+		select {
+		case got := <-ch:
+			assert.JSONEq(t, "1", string(got))
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+	})
+
+	t.Run("Keepalive pings", func(t *testing.T) {
+		dead := make(chan *jsonrpc.Conn, 1)
+		srv := jsonrpc.NewServer(jsonrpc.WithConnKeepalive(jsonrpc.KeepalivePolicy{
+			Interval: 10 * time.Millisecond,
+			Timeout:  50 * time.Millisecond,
+		}, func(conn *jsonrpc.Conn, _ error) { dead <- conn }))
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+		url := newTestServer(t, srv)
+
+		// A client reading its messages answers the pings
+		stream, err := Dial(context.Background(), url)
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, client.Call(context.Background(), "echo", nil, nil))
+		assert.Empty(t, dead)
+
+		// One that stops reading does not
+		conn, _, err := websocket.Dial(context.Background(), url, nil)
+		require.NoError(t, err)
+		defer conn.CloseNow()
+		select {
+		case <-dead:
+		case <-time.After(time.Second):
+			t.Fatal("unresponsive peer not detected")
+		}
+	})
+
+	t.Run("Read limit", func(t *testing.T) {
+		srv := jsonrpc.NewServer()
+		require.NoError(t, srv.RegisterFunc("echo", echo))
+
+		stream, err := Dial(context.Background(), newTestServer(t, srv, WithReadLimit(64)))
+		require.NoError(t, err)
+		client := jsonrpc.NewStreamClient(stream)
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err = client.Call(ctx, "echo", []any{strings.Repeat("x", 128)}, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("Rejects non-WebSocket requests", func(t *testing.T) {
+		ts := httptest.NewServer(NewHandler(jsonrpc.NewServer()))
+		defer ts.Close()
+
+		resp, err := http.Get(ts.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.GreaterOrEqual(t, resp.StatusCode, http.StatusBadRequest)
+	})
+}
+
+func TestStream_ReadMessage(t *testing.T) {
+	t.Run("Normal closure reads as EOF", func(t *testing.T) {
+		accepted := make(chan *Stream, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := websocket.Accept(w, r, nil)
+			if err != nil {
+				return
+			}
+			accepted <- NewStream(conn)
+			<-r.Context().Done()
+		}))
+		defer ts.Close()
+
+		stream, err := Dial(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http"))
+		require.NoError(t, err)
+
+		serverEnd := <-accepted
+		go func() { _ = serverEnd.Close() }()
+
+		_, err = stream.ReadMessage(context.Background())
+		require.ErrorIs(t, err, io.EOF)
+		require.NoError(t, stream.Close())
+	})
+}